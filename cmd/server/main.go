@@ -13,11 +13,19 @@ import (
 
 	"github.com/robert-malhotra/asf-stac-proxy/internal/api"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/asf"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/auth"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cache"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/cmr"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/download"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/ratelimit"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/redis"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/translate"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
 )
 
 func main() {
@@ -44,39 +52,216 @@ func run() error {
 	)
 
 	// Load collection definitions
-	collections, err := config.LoadCollections("./collections")
+	collections, err := config.LoadCollections(cfg.Collections.Dir)
 	if err != nil {
-		logger.Warn("failed to load collections from ./collections, using empty registry", "error", err)
+		logger.Warn("failed to load collections, using empty registry", "dir", cfg.Collections.Dir, "error", err)
 		collections = config.NewCollectionRegistry()
 	}
 	logger.Info("loaded collections", "count", collections.Count())
 
+	// Reload collection definitions on SIGHUP and when the directory changes
+	reloader := config.NewCollectionReloader(collections, cfg.Collections.Dir, logger)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if cfg.Collections.WatchInterval > 0 {
+		go reloader.Watch(watchCtx, cfg.Collections.WatchInterval)
+	}
+
 	// Create translator
 	translator := translate.NewTranslator(cfg, collections, logger)
 
-	// Create cursor store for server-side pagination cursor storage
-	// TTL: 1 hour (cursors expire after this time)
-	// Cleanup interval: 5 minutes (check for expired cursors)
-	cursorStore := stac.NewMemoryCursorStore(1*time.Hour, 5*time.Minute)
-	defer cursorStore.Stop()
-	logger.Info("initialized cursor store", "ttl", "1h", "cleanup_interval", "5m")
-
-	// Create search backend based on configuration
-	var searchBackend backend.SearchBackend
-	switch cfg.Backend.Type {
-	case "cmr":
-		cmrClient := cmr.NewClient(cfg.CMR.BaseURL, cfg.CMR.Provider, cfg.CMR.Timeout).WithLogger(logger)
-		searchBackend = cmr.NewCMRBackend(cmrClient, collections, cfg, logger)
-		logger.Info("using CMR backend", "base_url", cfg.CMR.BaseURL, "provider", cfg.CMR.Provider)
+	// Create the keyring sealing pagination cursors, so clients can neither
+	// read nor alter them
+	var keyring *stac.CursorKeyring
+	if cfg.Cursor.Secret != "" {
+		previous := make([][]byte, len(cfg.Cursor.PreviousSecrets))
+		for i, secret := range cfg.Cursor.PreviousSecrets {
+			previous[i] = []byte(secret)
+		}
+		keyring, err = stac.NewCursorKeyring([]byte(cfg.Cursor.Secret), previous, cfg.Cursor.KeyRotatedAt, cfg.Cursor.KeyGrace)
+		if err != nil {
+			return fmt.Errorf("failed to create cursor keyring: %w", err)
+		}
+	} else {
+		keyring = stac.NewRandomCursorKeyring()
+		logger.Warn("CURSOR_SECRET is not set, cursors are sealed with a random key valid only on this instance until it restarts")
+	}
+	// Cursors expire after the TTL, and those kept inline are size-bounded
+	keyring.WithLimits(cfg.Cursor.TTL, cfg.Cursor.MaxBytes)
+
+	// Create cursor store for server-side pagination cursor storage. Replicas
+	// behind a load balancer need the signed or redis store to share cursors;
+	// signed uses none, keeping long cursors inline and sealed by the keyring
+	var cursorStore stac.CursorStore
+	switch cfg.Cursor.Store {
+	case "signed":
+	case "redis":
+		redisClient := redis.NewClient(cfg.Cursor.Redis.Addr).
+			WithPassword(cfg.Cursor.Redis.Password).
+			WithDB(cfg.Cursor.Redis.DB)
+		defer redisClient.Close()
+		cursorStore = stac.NewKVCursorStore(redisClient, cfg.Cursor.Redis.KeyPrefix, cfg.Cursor.TTL, cfg.Cursor.MaxBytes)
 	default:
-		asfClient := asf.NewClient(cfg.ASF.BaseURL, cfg.ASF.Timeout).WithLogger(logger)
-		searchBackend = backend.NewASFBackend(asfClient, collections, translator, cfg, logger)
-		logger.Info("using ASF backend", "base_url", cfg.ASF.BaseURL)
+		// Cleanup interval: 5 minutes (check for expired cursors)
+		memoryStore := stac.NewMemoryCursorStore(cfg.Cursor.TTL, 5*time.Minute)
+		defer memoryStore.Stop()
+		cursorStore = memoryStore
+	}
+	logger.Info("initialized cursor store", "store", cfg.Cursor.Store, "ttl", cfg.Cursor.TTL)
+
+	// Create Prometheus metrics, served on /metrics
+	var appMetrics *metrics.Metrics
+	if cfg.Features.EnableMetrics {
+		appMetrics = metrics.New()
+		if stats, ok := cursorStore.(stac.CursorStoreStats); ok {
+			appMetrics.RegisterCursorStore(stats.Stats)
+		}
+	}
+
+	// Create the tracer, if a trace exporter is configured
+	var tracer *tracing.Tracer
+	exporter, err := tracing.NewExporter(cfg.Tracing.Exporter, cfg.Tracing.Endpoint, cfg.Tracing.Headers, cfg.Tracing.ServiceName, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	if exporter != nil {
+		tracer = tracing.NewTracer(cfg.Tracing.ServiceName, exporter).WithSampleRatio(cfg.Tracing.SampleRatio)
+		logger.Info("enabled tracing", "exporter", cfg.Tracing.Exporter, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
+
+	// Create the upstream response cache, shared by the ASF and CMR clients
+	var responseCache *cache.ReadThrough
+	cacheTTLs := cache.TTLs{Search: cfg.Cache.SearchTTL, Item: cfg.Cache.ItemTTL}
+	if cfg.Cache.Enabled {
+		responseCache = cache.NewReadThrough(cache.NewLRU(cfg.Cache.MaxBytes), cfg.Cache.MaxLoadTime)
+		logger.Info("initialized response cache",
+			"max_bytes", cfg.Cache.MaxBytes,
+			"search_ttl", cfg.Cache.SearchTTL,
+			"item_ttl", cfg.Cache.ItemTTL,
+		)
+	}
+
+	// Transient upstream failures are retried with backoff
+	retryPolicy := upstream.RetryPolicy{
+		MaxAttempts: cfg.Retry.MaxAttempts,
+		BaseDelay:   cfg.Retry.BaseDelay,
+		MaxDelay:    cfg.Retry.MaxDelay,
+	}
+
+	// Cap the upstream calls in flight across the ASF and CMR clients
+	var upstreamSlots *ratelimit.Semaphore
+	if cfg.RateLimit.UpstreamConcurrency > 0 {
+		upstreamSlots = ratelimit.NewSemaphore(cfg.RateLimit.UpstreamConcurrency, cfg.RateLimit.UpstreamWait)
+		logger.Info("capped upstream concurrency",
+			"max_in_flight", cfg.RateLimit.UpstreamConcurrency,
+			"max_wait", cfg.RateLimit.UpstreamWait,
+		)
+	}
+
+	// Create search backends based on configuration
+	newBackend := func(backendType string) backend.SearchBackend {
+		switch backendType {
+		case "cmr":
+			cmrClient := cmr.NewClient(cfg.CMR.BaseURL, cfg.CMR.Provider, cfg.CMR.Timeout).WithLogger(logger).
+				WithRetryPolicy(retryPolicy).
+				WithMetrics(appMetrics).
+				WithCache(responseCache, cacheTTLs).
+				WithConcurrencyLimit(upstreamSlots)
+			logger.Info("using CMR backend", "base_url", cfg.CMR.BaseURL, "provider", cfg.CMR.Provider)
+			return cmr.NewCMRBackend(cmrClient, collections, cfg, logger).WithMetrics(appMetrics)
+		default:
+			asfClient := asf.NewClient(cfg.ASF.BaseURL, cfg.ASF.Timeout).WithLogger(logger).
+				WithRetryPolicy(retryPolicy).
+				WithMetrics(appMetrics).
+				WithCache(responseCache, cacheTTLs).
+				WithConcurrencyLimit(upstreamSlots)
+			logger.Info("using ASF backend", "base_url", cfg.ASF.BaseURL)
+			return backend.NewASFBackend(asfClient, collections, translator, cfg, logger).WithMetrics(appMetrics)
+		}
+	}
+
+	searchBackend := newBackend(cfg.Backend.Type)
+	if cfg.Backend.Failover != "" {
+		// Route requests to the secondary while the primary's breaker is open
+		breakerCfg := backend.BreakerConfig{
+			Window:      cfg.Backend.Breaker.Window,
+			MinRequests: cfg.Backend.Breaker.MinRequests,
+			FailureRate: cfg.Backend.Breaker.FailureRate,
+			OpenTimeout: cfg.Backend.Breaker.OpenTimeout,
+		}
+		searchBackend = backend.NewFailover(searchBackend, newBackend(cfg.Backend.Failover), breakerCfg, logger)
+		logger.Info("enabled backend failover", "primary", cfg.Backend.Type, "secondary", cfg.Backend.Failover)
 	}
 
 	// Create handlers with backend and cursor store
 	handlers := api.NewHandlers(cfg, searchBackend, translator, collections, logger).
-		WithCursorStore(cursorStore)
+		WithCursorStore(cursorStore).
+		WithCursorKeyring(keyring).
+		WithCache(responseCache).
+		WithMetrics(appMetrics).
+		WithTracer(tracer)
+
+	// Authenticate clients, if API keys or a JWKS are configured
+	if cfg.Auth.Enabled() {
+		authenticator := auth.NewAuthenticator(cfg.Auth.APIKeyHeader).WithRequired(cfg.Auth.Required)
+		if cfg.Auth.APIKeysFile != "" {
+			keys, err := auth.LoadAPIKeys(cfg.Auth.APIKeysFile)
+			if err != nil {
+				return err
+			}
+			authenticator.WithAPIKeys(keys)
+			logger.Info("loaded API keys", "file", cfg.Auth.APIKeysFile, "count", len(keys))
+		}
+		var keySet *auth.KeySet
+		if cfg.Auth.JWKSFile != "" {
+			keySet = auth.NewFileKeySet(cfg.Auth.JWKSFile, cfg.Auth.JWKSRefresh)
+			if err := keySet.Load(context.Background()); err != nil {
+				return err
+			}
+		} else if cfg.Auth.JWKSURL != "" {
+			keySet = auth.NewURLKeySet(cfg.Auth.JWKSURL, &http.Client{Timeout: 10 * time.Second}, cfg.Auth.JWKSRefresh)
+			if err := keySet.Load(context.Background()); err != nil {
+				// The identity provider may be back by the first token
+				logger.Warn("failed to load JWKS, retrying on demand", "error", err)
+			}
+		}
+		if keySet != nil {
+			authenticator.WithJWT(auth.NewJWTVerifier(keySet, auth.JWTConfig{
+				Issuer:           cfg.Auth.Issuer,
+				Audience:         cfg.Auth.Audience,
+				CollectionsClaim: cfg.Auth.CollectionsClaim,
+				ClaimPrefix:      cfg.Auth.ClaimPrefix,
+				ClockSkew:        cfg.Auth.ClockSkew,
+			}))
+		}
+		handlers.WithAuthenticator(authenticator)
+		logger.Info("enabled authentication", "required", cfg.Auth.Required, "jwt", keySet != nil)
+	}
+
+	// Stream assets through Earthdata Login, if enabled
+	if cfg.Download.Enabled {
+		downloads := download.NewProxy(cfg.Download.Timeout).
+			WithToken(cfg.Download.EarthdataToken).
+			WithTokenHosts(cfg.Download.TokenHosts).
+			WithLoginHost(cfg.Download.LoginHost).
+			WithLogger(logger)
+		handlers.WithDownloads(downloads)
+		logger.Info("enabled asset downloads", "server_token", downloads.HasToken(), "rewrite_hrefs", cfg.Download.RewriteHrefs)
+	}
+
+	// Throttle each client, if enabled
+	if cfg.RateLimit.Enabled {
+		routeLimits, err := cfg.RateLimit.RouteLimits()
+		if err != nil {
+			return err
+		}
+		rateLimits := api.NewRateLimits(cfg.RateLimit.Rate, cfg.RateLimit.Burst)
+		for pattern, limit := range routeLimits {
+			rateLimits.WithRoute(pattern, limit.Rate, limit.Burst)
+		}
+		handlers.WithRateLimits(rateLimits)
+		logger.Info("enabled rate limiting", "rate", cfg.RateLimit.Rate, "burst", cfg.RateLimit.Burst, "routes", len(routeLimits))
+	}
 
 	// Create router
 	router := api.NewRouter(handlers, logger)
@@ -99,15 +284,24 @@ func run() error {
 		}
 	}()
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal, reloading collections on SIGHUP
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 
-	select {
-	case err := <-serverErr:
-		return fmt.Errorf("server error: %w", err)
-	case sig := <-quit:
-		logger.Info("received shutdown signal", "signal", sig)
+wait:
+	for {
+		select {
+		case err := <-serverErr:
+			return fmt.Errorf("server error: %w", err)
+		case <-hup:
+			logger.Info("received SIGHUP, reloading collections")
+			reloader.Reload()
+		case sig := <-quit:
+			logger.Info("received shutdown signal", "signal", sig)
+			break wait
+		}
 	}
 
 	// Graceful shutdown
@@ -119,6 +313,11 @@ func run() error {
 		return fmt.Errorf("server shutdown error: %w", err)
 	}
 
+	// Export the spans still buffered
+	if err := tracer.Shutdown(ctx); err != nil {
+		logger.Warn("failed to flush traces", "error", err)
+	}
+
 	logger.Info("server stopped")
 	return nil
 }
@@ -0,0 +1,345 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	intstac "github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/translate"
+)
+
+// maxAggregationScanPages bounds the pages of MaxLimit items scanned to
+// compute aggregations the backend cannot count. Aggregations over more items
+// are computed from the newest ones and the response is marked partial.
+const maxAggregationScanPages = 10
+
+// maxCountedBuckets bounds the upstream count requests made for one
+// frequency distribution. Distributions with more buckets are scanned.
+const maxCountedBuckets = 36
+
+// Aggregations lists the aggregations available.
+// GET /aggregations
+// GET /collections/{collectionId}/aggregations
+func (h *Handlers) Aggregations(w http.ResponseWriter, r *http.Request) {
+	collectionID := chi.URLParam(r, "collectionId")
+	if collectionID != "" && !h.collectionVisible(r.Context(), collectionID) {
+		WriteNotFound(w, fmt.Sprintf("collection %q not found", collectionID))
+		return
+	}
+
+	result := intstac.NewAggregationCollection()
+	for _, def := range intstac.SupportedAggregations {
+		result.Aggregations = append(result.Aggregations, &intstac.Aggregation{
+			Name:     def.Name,
+			DataType: def.DataType,
+		})
+	}
+
+	h.addAggregationLinks(result, collectionID, "/aggregations")
+	WriteJSON(w, http.StatusOK, result)
+}
+
+// Aggregate computes aggregations over the items matching a search.
+// GET/POST /aggregate
+// GET/POST /collections/{collectionId}/aggregate
+//
+// Aggregations are computed from upstream counts when the backend can count
+// (CMR hits) and the whole filter is pushed down: one count per term or
+// datetime bucket, for terms whose values are listed in the collection
+// summaries. Everything else is computed by scanning the matching items, at
+// most maxAggregationScanPages pages of them.
+func (h *Handlers) Aggregate(w http.ResponseWriter, r *http.Request) {
+	collectionID := chi.URLParam(r, "collectionId")
+	if collectionID == "" && !h.cfg.Features.EnableSearch {
+		WriteError(w, http.StatusNotImplemented, "NotImplemented", "search endpoint is disabled")
+		return
+	}
+	if collectionID != "" && !h.collectionVisible(r.Context(), collectionID) {
+		WriteNotFound(w, fmt.Sprintf("collection %q not found", collectionID))
+		return
+	}
+
+	var aggReq *intstac.AggregationRequest
+	var err error
+	if r.Method == http.MethodPost {
+		aggReq, err = intstac.ParseAggregationRequestBody(r.Body)
+		defer r.Body.Close()
+	} else {
+		aggReq, err = intstac.ParseAggregationRequest(r)
+	}
+	if err != nil {
+		WriteInvalidParameter(w, fmt.Sprintf("invalid aggregation request: %v", err))
+		return
+	}
+
+	for _, collID := range aggReq.Collections {
+		if !h.collectionVisible(r.Context(), collID) {
+			WriteNotFound(w, fmt.Sprintf("collection %q not found", collID))
+			return
+		}
+	}
+
+	// Aggregations cover all matching items, so paging and ordering do not apply
+	aggReq.Cursor = ""
+	aggReq.Sortby = nil
+	aggReq.Limit = h.cfg.Features.MaxLimit
+
+	backendParams, plan, err := h.buildBackendParams(aggReq.SearchRequest, collectionID)
+	if err != nil {
+		WriteInvalidParameter(w, fmt.Sprintf("invalid filter: %v", err))
+		return
+	}
+	if !h.restrictToVisible(r.Context(), backendParams, plan) {
+		writeNoVisibleCollections(w, r)
+		return
+	}
+
+	result, err := h.aggregate(r.Context(), aggReq, backendParams, plan)
+	if err != nil {
+		h.logger.Error("backend aggregation failed",
+			slog.String("collection_id", collectionID),
+			slog.String("backend", h.backend.Name()),
+			slog.String("error", err.Error()),
+		)
+		if errors.Is(err, translate.ErrCollectionNotFound) {
+			WriteNotFound(w, "one or more collections not found")
+		} else {
+			WriteUpstreamFailure(w, err, "upstream search service error")
+		}
+		return
+	}
+
+	h.addAggregationLinks(result, collectionID, "/aggregate")
+	WriteJSON(w, http.StatusOK, result)
+}
+
+// aggregate computes the requested aggregations, counting upstream what the
+// backend can count and scanning for the rest.
+func (h *Handlers) aggregate(ctx context.Context, req *intstac.AggregationRequest, params *backend.SearchParams, plan *filterPlan) (*intstac.AggregationCollection, error) {
+	result := intstac.NewAggregationCollection()
+	result.Aggregations = make([]*intstac.Aggregation, len(req.Aggregations))
+
+	counter, canCount := h.backend.(backend.Counter)
+	canCount = canCount && !plan.hasResidual()
+
+	var scanned []int
+	for i, name := range req.Aggregations {
+		def, _ := intstac.LookupAggregation(name)
+		if canCount {
+			agg, err := h.countAggregation(ctx, counter, req, def, params)
+			switch {
+			case errors.Is(err, backend.ErrNotSupported):
+				// A failover backend routed to one that cannot count
+				canCount = false
+			case err != nil:
+				return nil, err
+			case agg != nil:
+				result.Aggregations[i] = agg
+				continue
+			}
+		}
+		scanned = append(scanned, i)
+	}
+	if len(scanned) == 0 {
+		return result, nil
+	}
+
+	aggregator := intstac.NewAggregator(req)
+	complete, totalCount, err := h.scanItems(ctx, params, plan, maxAggregationScanPages, aggregator.Add)
+	if err != nil {
+		return nil, err
+	}
+	result.Partial = !complete
+	for _, i := range scanned {
+		result.Aggregations[i] = aggregator.Result(req.Aggregations[i])
+		// An exact upstream count beats the count of a partial scan
+		if def, _ := intstac.LookupAggregation(req.Aggregations[i]); def.Kind == intstac.AggregationCount && !complete && totalCount != nil {
+			result.Aggregations[i] = intstac.CountAggregation(def, *totalCount)
+		}
+	}
+	return result, nil
+}
+
+// countAggregation computes an aggregation from upstream counts. It returns
+// nil when the aggregation cannot be counted and must be scanned.
+func (h *Handlers) countAggregation(ctx context.Context, counter backend.Counter, req *intstac.AggregationRequest, def intstac.AggregationDefinition, params *backend.SearchParams) (*intstac.Aggregation, error) {
+	switch def.Kind {
+	case intstac.AggregationCount:
+		count, err := counter.Count(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return intstac.CountAggregation(def, count), nil
+
+	case intstac.AggregationTerms:
+		// Each value is counted by pushing it down in place of any value
+		// the search itself pushed down, so that property must be free
+		if _, ok := backend.CapabilitiesOf(h.backend)[def.Property]; !ok || pushedDown(def.Property, params) {
+			return nil, nil
+		}
+		values := h.summaryValues(def.Property, params.Collections)
+		if len(values) == 0 || len(values) > maxCountedBuckets {
+			return nil, nil
+		}
+		buckets := []*intstac.Bucket{}
+		for _, value := range values {
+			bucketParams := *params
+			if !applyPushdown(def.Property, []any{value}, h.collections, &bucketParams) {
+				return nil, nil
+			}
+			count, err := counter.Count(ctx, &bucketParams)
+			if err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				buckets = append(buckets, &intstac.Bucket{Key: value, DataType: intstac.BucketDataString, Frequency: count})
+			}
+		}
+		return intstac.FrequencyAggregation(def, buckets), nil
+
+	case intstac.AggregationDatetime:
+		start := h.ascendingScanStart(params)
+		end := time.Now().UTC()
+		if params.End != nil {
+			end = *params.End
+		}
+		var bounds []time.Time
+		for t := intstac.TruncateToInterval(start, req.DatetimeFrequencyInterval); t.Before(end); t = intstac.NextInterval(t, req.DatetimeFrequencyInterval) {
+			if len(bounds) == maxCountedBuckets {
+				return nil, nil
+			}
+			bounds = append(bounds, t)
+		}
+		buckets := []*intstac.Bucket{}
+		for _, t := range bounds {
+			bucketStart := t
+			if bucketStart.Before(start) {
+				bucketStart = start
+			}
+			bucketEnd := intstac.NextInterval(t, req.DatetimeFrequencyInterval)
+			if bucketEnd.After(end) {
+				bucketEnd = end
+			}
+			bucketParams := *params
+			bucketParams.Start = &bucketStart
+			bucketParams.End = &bucketEnd
+			count, err := counter.Count(ctx, &bucketParams)
+			if err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				buckets = append(buckets, &intstac.Bucket{
+					Key:       t.Format(time.RFC3339),
+					DataType:  intstac.BucketDataDatetime,
+					Frequency: count,
+				})
+			}
+		}
+		return intstac.FrequencyAggregation(def, buckets), nil
+	}
+	return nil, nil
+}
+
+// scanItems passes every item matching the search to add, fetching pages of
+// MaxLimit items the way a client follows next links. It reports false when
+// maxPages were scanned before the last page, along with the upstream match
+// count of the first page, when exact.
+func (h *Handlers) scanItems(ctx context.Context, params *backend.SearchParams, plan *filterPlan, maxPages int, add func(*intstac.Item)) (bool, *int, error) {
+	limit := h.cfg.Features.MaxLimit
+	var cursor *intstac.Cursor
+	var totalCount *int
+
+	for pages := 0; pages < maxPages; pages++ {
+		h.setBackendLimit(params, plan, limit, cursor)
+		page, err := h.fetchPage(ctx, params, plan, limit, cursor)
+		if err != nil {
+			return false, nil, err
+		}
+		if pages == 0 {
+			totalCount = page.TotalCount
+		}
+		for _, item := range page.Features {
+			add(item)
+		}
+		if !page.BackendHasMoreData {
+			return true, totalCount, nil
+		}
+
+		if h.backend.SupportsPagination() {
+			if page.NextCursor == "" {
+				return true, totalCount, nil
+			}
+			params.Cursor = page.NextCursor
+			continue
+		}
+		next := intstac.NextCursorInOrder(extractItemTimeInfos(page.Consumed), cursor, false)
+		if next == nil {
+			return true, totalCount, nil
+		}
+		params.End = intstac.ApplyCursorToDatetime(next, params.End)
+		cursor = next
+	}
+	return false, totalCount, nil
+}
+
+// pushedDown reports whether params already constrain a property upstream
+func pushedDown(property string, params *backend.SearchParams) bool {
+	switch property {
+	case "platform":
+		return len(params.Platform) > 0
+	case "sar:instrument_mode":
+		return len(params.BeamMode) > 0
+	case "sat:orbit_state":
+		return params.FlightDirection != ""
+	case "sat:relative_orbit":
+		return len(params.RelativeOrbit) > 0
+	}
+	return false
+}
+
+// summaryValues returns the distinct string values of a property listed in
+// the summaries of the given collections, or of all collections
+func (h *Handlers) summaryValues(property string, collectionIDs []string) []string {
+	collections := h.collections.All()
+	if len(collectionIDs) > 0 {
+		collections = collections[:0:0]
+		for _, id := range collectionIDs {
+			if coll := h.collections.Get(id); coll != nil {
+				collections = append(collections, coll)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, coll := range collections {
+		values, ok := coll.Summaries[property].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, v := range values {
+			if s, ok := v.(string); ok && !seen[s] {
+				seen[s] = true
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
+
+// addAggregationLinks adds the self and root links of an aggregation response
+func (h *Handlers) addAggregationLinks(result *intstac.AggregationCollection, collectionID, endpoint string) {
+	baseURL := h.cfg.STAC.BaseURL
+	self := baseURL + endpoint
+	if collectionID != "" {
+		self = fmt.Sprintf("%s/collections/%s%s", baseURL, collectionID, endpoint)
+	}
+	result.AddLink("self", self, "application/json")
+	result.AddLink("root", baseURL+"/", "application/json")
+}
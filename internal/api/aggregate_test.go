@@ -0,0 +1,310 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	gostac "github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+)
+
+// countingBackend is a timeWindowBackend that also counts matches upstream,
+// like CMR through its hits count
+type countingBackend struct {
+	timeWindowBackend
+	countCalls []backend.SearchParams
+}
+
+func (m *countingBackend) Count(ctx context.Context, params *backend.SearchParams) (int, error) {
+	m.countCalls = append(m.countCalls, *params)
+
+	count := 0
+	for _, item := range m.items {
+		start, _ := time.Parse(time.RFC3339, item.Properties["start_datetime"].(string))
+		if params.End != nil && !start.Before(*params.End) {
+			continue
+		}
+		if params.Start != nil && start.Before(*params.Start) {
+			continue
+		}
+		if len(params.Platform) > 0 && !strings.EqualFold(params.Platform[0], item.Properties["platform"].(string)) {
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// createAggregationTestItems returns 30 items, one a day from 2024-01-17
+// back into December, alternating platforms and cycling three orbits
+func createAggregationTestItems() []*gostac.Item {
+	baseTime := time.Date(2024, 1, 17, 12, 0, 0, 0, time.UTC)
+	items := make([]*gostac.Item, 30)
+	for i := range items {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", i), baseTime.AddDate(0, 0, -i))
+		items[i].Properties["platform"] = "sentinel-1a"
+		if i%2 == 1 {
+			items[i].Properties["platform"] = "sentinel-1b"
+		}
+		items[i].Properties["sat:relative_orbit"] = 10 + i%3
+	}
+	return items
+}
+
+func createAggregationTestHandlers(b backend.SearchBackend, maxLimit int) *Handlers {
+	cfg := createTestConfig()
+	cfg.Features.MaxLimit = maxLimit
+	cfg.Features.EnableSearch = true
+
+	collections := config.NewCollectionRegistry()
+	_ = collections.Add(&config.CollectionConfig{
+		ID:          "sentinel-1",
+		ASFDatasets: []string{"SENTINEL-1"},
+		Summaries: map[string]interface{}{
+			"platform": []interface{}{"sentinel-1a", "sentinel-1b"},
+		},
+	})
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	return NewHandlers(cfg, b, nil, collections, logger)
+}
+
+func serveAggregate(t *testing.T, h *Handlers, req *http.Request) *stac.AggregationCollection {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Aggregate(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result stac.AggregationCollection
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return &result
+}
+
+// aggregationBuckets returns the buckets of an aggregation as key -> frequency
+func aggregationBuckets(t *testing.T, result *stac.AggregationCollection, name string) map[string]int {
+	t.Helper()
+	for _, agg := range result.Aggregations {
+		if agg.Name == name {
+			buckets := make(map[string]int)
+			for _, b := range agg.Buckets {
+				buckets[fmt.Sprint(b.Key)] = b.Frequency
+			}
+			return buckets
+		}
+	}
+	t.Fatalf("aggregation %s missing from response", name)
+	return nil
+}
+
+func aggregationValue(t *testing.T, result *stac.AggregationCollection, name string) any {
+	t.Helper()
+	for _, agg := range result.Aggregations {
+		if agg.Name == name {
+			return agg.Value
+		}
+	}
+	t.Fatalf("aggregation %s missing from response", name)
+	return nil
+}
+
+func TestHandlers_Aggregate_ScansNonCountingBackend(t *testing.T) {
+	// Test that on an ASF-like backend aggregations are computed by scanning
+	// every matching item across several time windows
+
+	mock := &timeWindowBackend{mockBackend{items: createAggregationTestItems()}}
+	handlers := createAggregationTestHandlers(mock, 6)
+
+	req := httptest.NewRequest(http.MethodGet, "/aggregate?collections=sentinel-1&aggregations="+
+		"total_count,platform_frequency,sat_relative_orbit_frequency,datetime_frequency", nil)
+	result := serveAggregate(t, handlers, req)
+
+	if result.Partial {
+		t.Error("Expected a complete scan")
+	}
+	if v := aggregationValue(t, result, "total_count"); v != float64(30) {
+		t.Errorf("Expected total_count 30, got %v", v)
+	}
+	if got := aggregationBuckets(t, result, "platform_frequency"); got["sentinel-1a"] != 15 || got["sentinel-1b"] != 15 {
+		t.Errorf("Unexpected platform buckets %v", got)
+	}
+	if got := aggregationBuckets(t, result, "sat_relative_orbit_frequency"); got["10"] != 10 || got["11"] != 10 || got["12"] != 10 {
+		t.Errorf("Unexpected relative orbit buckets %v", got)
+	}
+	// 17 days in January, 13 in December
+	want := map[string]int{"2024-01-01T00:00:00Z": 17, "2023-12-01T00:00:00Z": 13}
+	if got := aggregationBuckets(t, result, "datetime_frequency"); len(got) != 2 ||
+		got["2024-01-01T00:00:00Z"] != want["2024-01-01T00:00:00Z"] || got["2023-12-01T00:00:00Z"] != want["2023-12-01T00:00:00Z"] {
+		t.Errorf("Expected datetime buckets %v, got %v", want, got)
+	}
+	if len(mock.searchCalls) < 5 {
+		t.Errorf("Expected the scan to span several batches, got %d", len(mock.searchCalls))
+	}
+}
+
+// notCountingBackend offers Count but cannot serve it, like a failover
+// backend routed to a backend without counts
+type notCountingBackend struct {
+	timeWindowBackend
+}
+
+func (m *notCountingBackend) Count(ctx context.Context, params *backend.SearchParams) (int, error) {
+	return 0, backend.ErrNotSupported
+}
+
+func TestHandlers_Aggregate_ScansWhenCountNotSupported(t *testing.T) {
+	// Test that aggregations fall back to scanning when counting turns out
+	// not to be supported
+
+	mock := &notCountingBackend{timeWindowBackend{mockBackend{items: createAggregationTestItems()}}}
+	handlers := createAggregationTestHandlers(mock, 6)
+
+	req := httptest.NewRequest(http.MethodGet, "/aggregate?collections=sentinel-1&aggregations=total_count,platform_frequency", nil)
+	result := serveAggregate(t, handlers, req)
+
+	if v := aggregationValue(t, result, "total_count"); v != float64(30) {
+		t.Errorf("Expected total_count 30, got %v", v)
+	}
+	if got := aggregationBuckets(t, result, "platform_frequency"); got["sentinel-1a"] != 15 || got["sentinel-1b"] != 15 {
+		t.Errorf("Unexpected platform buckets %v", got)
+	}
+}
+
+func TestHandlers_Aggregate_BoundedScanIsPartial(t *testing.T) {
+	// Test that a scan stopping at maxAggregationScanPages marks the response partial
+
+	mock := &timeWindowBackend{mockBackend{items: createAggregationTestItems()}}
+	handlers := createAggregationTestHandlers(mock, 2)
+
+	req := httptest.NewRequest(http.MethodGet, "/aggregate?aggregations=total_count,platform_frequency", nil)
+	result := serveAggregate(t, handlers, req)
+
+	if !result.Partial {
+		t.Error("Expected the response to be marked partial")
+	}
+	// The count covers only the scanned items, none of them twice
+	total, _ := aggregationValue(t, result, "total_count").(float64)
+	if total < maxAggregationScanPages || total >= 30 {
+		t.Errorf("Expected total_count of the scanned items, got %v", total)
+	}
+	platforms := aggregationBuckets(t, result, "platform_frequency")
+	if float64(platforms["sentinel-1a"]+platforms["sentinel-1b"]) != total {
+		t.Errorf("Expected platform buckets %v to add up to %v", platforms, total)
+	}
+	if len(mock.searchCalls) != maxAggregationScanPages {
+		t.Errorf("Expected %d upstream requests, got %d", maxAggregationScanPages, len(mock.searchCalls))
+	}
+}
+
+func TestHandlers_Aggregate_CountsUpstream(t *testing.T) {
+	// Test that a counting backend answers total_count, platform terms listed
+	// in the summaries and short histograms without any search, while
+	// relative orbits, which have no summary, are still scanned
+
+	mock := &countingBackend{timeWindowBackend: timeWindowBackend{mockBackend{items: createAggregationTestItems()}}}
+	handlers := createAggregationTestHandlers(mock, 250)
+
+	req := httptest.NewRequest(http.MethodGet, "/collections/sentinel-1/aggregate?aggregations=total_count,platform_frequency,datetime_frequency"+
+		"&datetime=2023-12-01T00:00:00Z/2024-02-01T00:00:00Z", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("collectionId", "sentinel-1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	result := serveAggregate(t, handlers, req)
+
+	if len(mock.searchCalls) != 0 {
+		t.Errorf("Expected no searches, got %d", len(mock.searchCalls))
+	}
+	// One count for the total, one per platform and one per month
+	if len(mock.countCalls) != 5 {
+		t.Errorf("Expected 5 count requests, got %d", len(mock.countCalls))
+	}
+	if v := aggregationValue(t, result, "total_count"); v != float64(30) {
+		t.Errorf("Expected total_count 30, got %v", v)
+	}
+	if got := aggregationBuckets(t, result, "platform_frequency"); got["sentinel-1a"] != 15 || got["sentinel-1b"] != 15 {
+		t.Errorf("Unexpected platform buckets %v", got)
+	}
+	if got := aggregationBuckets(t, result, "datetime_frequency"); got["2024-01-01T00:00:00Z"] != 17 || got["2023-12-01T00:00:00Z"] != 13 {
+		t.Errorf("Unexpected datetime buckets %v", got)
+	}
+	if result.Links[0].Href != "http://test.example.com/collections/sentinel-1/aggregate" {
+		t.Errorf("Unexpected self link %s", result.Links[0].Href)
+	}
+
+	// Relative orbits are not listed in the summaries, so they are scanned
+	mock.countCalls = nil
+	req = httptest.NewRequest(http.MethodPost, "/aggregate", strings.NewReader(
+		`{"collections": ["sentinel-1"], "aggregations": ["sat_relative_orbit_frequency"]}`))
+	result = serveAggregate(t, handlers, req)
+
+	if len(mock.countCalls) != 0 || len(mock.searchCalls) == 0 {
+		t.Errorf("Expected a scan, got %d counts and %d searches", len(mock.countCalls), len(mock.searchCalls))
+	}
+	if got := aggregationBuckets(t, result, "sat_relative_orbit_frequency"); got["10"] != 10 {
+		t.Errorf("Unexpected relative orbit buckets %v", got)
+	}
+}
+
+func TestHandlers_Aggregate_InvalidRequests(t *testing.T) {
+	handlers := createAggregationTestHandlers(&timeWindowBackend{}, 250)
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"unknown aggregation", "/aggregate?aggregations=cloud_cover_frequency", http.StatusBadRequest},
+		{"unknown interval", "/aggregate?datetime_frequency_interval=fortnight", http.StatusBadRequest},
+		{"geohash precision too high", "/aggregate?centroid_geohash_grid_frequency_precision=13", http.StatusBadRequest},
+		{"unknown collection", "/aggregate?collections=missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handlers.Aggregate(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_Aggregations(t *testing.T) {
+	handlers := createAggregationTestHandlers(&timeWindowBackend{}, 250)
+
+	w := httptest.NewRecorder()
+	handlers.Aggregations(w, httptest.NewRequest(http.MethodGet, "/aggregations", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var result stac.AggregationCollection
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if result.Type != "AggregationCollection" {
+		t.Errorf("Expected type AggregationCollection, got %s", result.Type)
+	}
+	if len(result.Aggregations) != len(stac.SupportedAggregations) {
+		t.Errorf("Expected %d aggregations, got %d", len(stac.SupportedAggregations), len(result.Aggregations))
+	}
+	for _, agg := range result.Aggregations {
+		if agg.Buckets != nil || agg.Value != nil {
+			t.Errorf("Expected aggregation %s to carry no results", agg.Name)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	intstac "github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+)
+
+// initialAscendingSpan is the first time window scanned for ascending order.
+// Windows widen while sparse and are halved when they overflow a batch.
+const initialAscendingSpan = 24 * time.Hour
+
+// maxAscendingScanBatches bounds the upstream requests made to fill one page
+// in ascending order on a backend that returns the newest results first.
+const maxAscendingScanBatches = 20
+
+// earliestAcquisition is the scan start when neither the request nor the
+// collections give one: the launch of SEASAT, the oldest mission ASF holds.
+var earliestAcquisition = time.Date(1978, 6, 28, 0, 0, 0, 0, time.UTC)
+
+// ascendingStartOrder orders a complete time window oldest first
+var ascendingStartOrder = []intstac.SortbyItem{{Field: "start_datetime", Direction: string(intstac.SortAsc)}}
+
+// scanAscending fills a page in ascending start time order from a backend that
+// returns the newest results first. It walks forward from params.Start in time
+// windows small enough to be fetched whole: a full batch means the window may
+// hold older items than those returned, so the window is halved and fetched
+// again. The items of a complete window are ordered oldest first and consumed
+// until the page is full. When maxAscendingScanBatches is reached first, the
+// page is returned with a Resume cursor at the end of the last window scanned.
+func (h *Handlers) scanAscending(ctx context.Context, params *backend.SearchParams, plan *filterPlan, limit int, cursor *intstac.Cursor) (*searchPage, error) {
+	page := &searchPage{Features: []*intstac.Item{}}
+	batchSize := h.cfg.Features.MaxLimit
+
+	end := params.End
+	stop := time.Now().UTC()
+	if end != nil {
+		stop = *end
+	}
+
+	lo := h.ascendingScanStart(params)
+	span := initialAscendingSpan
+	// Consecutive windows share their boundary instant, so items starting
+	// then are fetched twice
+	consumed := make(map[string]bool)
+	for batch := 0; batch < maxAscendingScanBatches; batch++ {
+		if !lo.Before(stop) {
+			return page, nil
+		}
+
+		// Window ends fall on whole seconds, so that Resume cursors are exact
+		hi := lo.Truncate(time.Second).Add(span)
+		last := !hi.Before(stop)
+		windowStart, windowEnd := lo, hi
+		params.Start = &windowStart
+		params.End = &windowEnd
+		if last {
+			params.End = end
+		}
+		params.Limit = batchSize
+
+		result, err := h.backend.Search(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Items) >= batchSize {
+			if span <= time.Second {
+				return nil, fmt.Errorf("more than %d items start within one second of %s; cannot order them oldest first",
+					batchSize, lo.Format(time.RFC3339))
+			}
+			span = max((span / 2).Truncate(time.Second), time.Second)
+			continue
+		}
+
+		items := intstac.FilterSeenItems(result.Items, func(item *intstac.Item) string {
+			return item.Id
+		}, cursor)
+		intstac.SortItems(items, ascendingStartOrder)
+
+		for _, item := range items {
+			if consumed[item.Id] {
+				continue
+			}
+			if len(page.Features) >= limit {
+				page.BackendHasMoreData = true
+				return page, nil
+			}
+			consumed[item.Id] = true
+			page.Consumed = append(page.Consumed, item)
+			if plan.matches(item) {
+				page.Features = append(page.Features, item)
+			}
+		}
+
+		if last {
+			return page, nil
+		}
+		lo = hi
+		if len(page.Features) >= limit {
+			page.BackendHasMoreData = true
+			return page, nil
+		}
+		if len(result.Items) < batchSize/4 {
+			span *= 2
+		}
+	}
+
+	page.BackendHasMoreData = true
+	page.Resume = &intstac.Cursor{
+		StartTime: lo.Format(time.RFC3339),
+		Direction: "next",
+		Ascending: true,
+	}
+	return page, nil
+}
+
+// ascendingScanStart returns where an ascending scan begins: the requested
+// start time, or else the earliest temporal extent of the searched collections.
+func (h *Handlers) ascendingScanStart(params *backend.SearchParams) time.Time {
+	if params.Start != nil {
+		return *params.Start
+	}
+
+	collections := h.collections.All()
+	if len(params.Collections) > 0 {
+		collections = collections[:0:0]
+		for _, id := range params.Collections {
+			if coll := h.collections.Get(id); coll != nil {
+				collections = append(collections, coll)
+			}
+		}
+	}
+
+	var earliest *time.Time
+	for _, coll := range collections {
+		if start, ok := extentStart(coll); ok && (earliest == nil || start.Before(*earliest)) {
+			earliest = &start
+		}
+	}
+	if earliest == nil {
+		return earliestAcquisition
+	}
+	return *earliest
+}
+
+// extentStart returns the start of a collection's temporal extent
+func extentStart(coll *config.CollectionConfig) (time.Time, bool) {
+	var earliest time.Time
+	found := false
+	for _, interval := range coll.Extent.Temporal.Interval {
+		if len(interval) == 0 {
+			continue
+		}
+		s, ok := interval[0].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			continue
+		}
+		if !found || t.Before(earliest) {
+			earliest, found = t, true
+		}
+	}
+	return earliest, found
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/auth"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cql2"
+)
+
+// Authenticate creates a middleware that authenticates requests with the
+// authenticator a and stores the principal in the request context. Requests
+// with invalid credentials get a 401 Unauthorized, as do requests without any
+// when a requires them.
+func Authenticate(a *auth.Authenticator, logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if operationalRoutes[routePatternOf(r)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqID := GetRequestID(r.Context())
+			p, err := a.Authenticate(r)
+			if err != nil {
+				logger.Warn("authentication failed",
+					slog.String("request_id", reqID),
+					slog.String("error", err.Error()),
+				)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				WriteErrorWithRequestID(w, http.StatusUnauthorized, ErrCodeUnauthorized, "invalid credentials", reqID)
+				return
+			}
+			if p == nil {
+				if a.Required() {
+					w.Header().Set("WWW-Authenticate", "Bearer")
+					WriteErrorWithRequestID(w, http.StatusUnauthorized, ErrCodeUnauthorized, "authentication required", reqID)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+		})
+	}
+}
+
+// collectionVisible reports whether a collection exists and the client may
+// access it. Restricted collections the client was not granted are treated as
+// missing, so their existence is not disclosed.
+func (h *Handlers) collectionVisible(ctx context.Context, id string) bool {
+	coll := h.collections.Get(id)
+	return coll != nil && (!coll.Restricted || auth.FromContext(ctx).Allows(id))
+}
+
+// visibleCollections returns the collections the client may access, sorted
+// by ID.
+func (h *Handlers) visibleCollections(ctx context.Context) []*config.CollectionConfig {
+	p := auth.FromContext(ctx)
+	var visible []*config.CollectionConfig
+	for _, coll := range h.collections.All() {
+		if !coll.Restricted || p.Allows(coll.ID) {
+			visible = append(visible, coll)
+		}
+	}
+	slices.SortFunc(visible, func(a, b *config.CollectionConfig) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return visible
+}
+
+// restrictToVisible limits a search to the collections the client may access.
+// A search of no collection in particular is narrowed to the visible ones
+// when some are hidden, and items looked up by ID, which the ASF API finds
+// whatever their collection, are filtered by collection. It returns false
+// when the client may access no collection at all.
+func (h *Handlers) restrictToVisible(ctx context.Context, params *backend.SearchParams, plan *filterPlan) bool {
+	visible := h.visibleCollections(ctx)
+	if len(visible) == h.collections.Count() {
+		return true
+	}
+	if len(visible) == 0 {
+		return false
+	}
+
+	if len(params.Collections) == 0 {
+		for _, coll := range visible {
+			params.Collections = append(params.Collections, coll.ID)
+		}
+	}
+	if len(params.IDs) > 0 {
+		allowed := make(map[string]bool, len(params.Collections))
+		for _, id := range params.Collections {
+			allowed[id] = true
+		}
+		plan.restrictCollections(allowed)
+	}
+	return true
+}
+
+// writeNoVisibleCollections answers a search by a client that may access no
+// collection: a 401 inviting an anonymous client to authenticate, or a 403.
+func writeNoVisibleCollections(w http.ResponseWriter, r *http.Request) {
+	if auth.FromContext(r.Context()) == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		WriteError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "authentication required")
+		return
+	}
+	WriteError(w, http.StatusForbidden, ErrCodeForbidden, "no collections are available to these credentials")
+}
+
+// restrictCollections makes the plan reject the items of collections not in
+// allowed, on top of any residual filter
+func (p *filterPlan) restrictCollections(allowed map[string]bool) {
+	residual := p.residual
+	p.residual = func(get cql2.PropertyGetter) bool {
+		collection, _ := get("collection")
+		id, _ := collection.(string)
+		return allowed[id] && (residual == nil || residual(get))
+	}
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gostac "github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/auth"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/download"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
+)
+
+// stackingMock is a mockBackend whose stacks hold just the item, looked up by
+// ID alone like the real backends look granules up by name
+type stackingMock struct {
+	*mockBackend
+}
+
+func (m stackingMock) Stack(ctx context.Context, collection, itemID string) (*backend.SearchResult, error) {
+	item, err := m.GetItem(ctx, collection, itemID)
+	if err != nil {
+		return nil, err
+	}
+	return &backend.SearchResult{Items: []*gostac.Item{item}}, nil
+}
+
+// newAuthTestRouter serves a public sentinel-1 collection and a restricted
+// nisar one, with API keys "nisar-key" granting nisar and "other-key" granting
+// only collections not served here
+func newAuthTestRouter(t *testing.T, required bool) (http.Handler, *mockBackend) {
+	t.Helper()
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	keys := `[
+		{"subject": "nisar-team", "key": "nisar-key", "collections": ["nisar*"]},
+		{"subject": "other-team", "key": "other-key", "collections": ["opera-*"]}
+	]`
+	if err := os.WriteFile(keysFile, []byte(keys), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	apiKeys, err := auth.LoadAPIKeys(keysFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collections := createTestCollections()
+	_ = collections.Add(&config.CollectionConfig{
+		ID:          "nisar",
+		Title:       "NISAR",
+		Description: "Restricted collection",
+		License:     "proprietary",
+		ASFDatasets: []string{"NISAR"},
+		Restricted:  true,
+	})
+
+	restricted := createTestItem("nisar-item", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	restricted.Collection = "nisar"
+	restricted.Assets["data"] = &gostac.Asset{Href: "http://127.0.0.1:1/nisar-item.h5", Roles: []string{"data"}}
+	mock := &mockBackend{items: []*gostac.Item{
+		createTestItem("item-1", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		restricted,
+	}}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	cfg.Features.EnableQueryables = true
+	cfg.Download.Enabled = true
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandlers(cfg, stackingMock{mock}, nil, collections, logger).
+		WithAuthenticator(auth.NewAuthenticator("X-API-Key").WithAPIKeys(apiKeys).WithRequired(required)).
+		WithDownloads(download.NewProxy(time.Second))
+	return NewRouter(h, logger), mock
+}
+
+func apiKeyHeader(key string) http.Header {
+	return http.Header{"X-Api-Key": []string{key}}
+}
+
+func TestAuth_RestrictedCollectionsHidden(t *testing.T) {
+	router, _ := newAuthTestRouter(t, false)
+
+	targets := []string{
+		"/collections/nisar",
+		"/collections/nisar/items",
+		"/collections/nisar/items/nisar-item",
+		"/collections/nisar/queryables",
+		"/collections/nisar/aggregations",
+		"/search?collections=nisar",
+	}
+	for _, key := range []string{"", "other-key"} {
+		var header http.Header
+		if key != "" {
+			header = apiKeyHeader(key)
+		}
+
+		w := serveFrom(router, http.MethodGet, "/collections", "192.0.2.1:1234", header)
+		if ids := collectionIDs(t, w.Body.Bytes()); !reflect.DeepEqual(ids, []string{"sentinel-1"}) {
+			t.Errorf("key %q: expected only sentinel-1 to be listed, got %v", key, ids)
+		}
+		for _, target := range targets {
+			if w := serveFrom(router, http.MethodGet, target, "192.0.2.1:1234", header); w.Code != http.StatusNotFound {
+				t.Errorf("key %q: expected %s to be 404, got %d", key, target, w.Code)
+			}
+		}
+	}
+}
+
+func TestAuth_GrantedCollectionsVisible(t *testing.T) {
+	router, _ := newAuthTestRouter(t, false)
+	header := apiKeyHeader("nisar-key")
+
+	w := serveFrom(router, http.MethodGet, "/collections", "192.0.2.1:1234", header)
+	if ids := collectionIDs(t, w.Body.Bytes()); !reflect.DeepEqual(ids, []string{"nisar", "sentinel-1"}) {
+		t.Errorf("Expected both collections to be listed, got %v", ids)
+	}
+	for _, target := range []string{
+		"/collections/nisar",
+		"/collections/nisar/items",
+		"/collections/nisar/items/nisar-item",
+		"/collections/nisar/queryables",
+		"/search?collections=nisar",
+	} {
+		if w := serveFrom(router, http.MethodGet, target, "192.0.2.1:1234", header); w.Code != http.StatusOK {
+			t.Errorf("Expected %s to be 200, got %d: %s", target, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAuth_RestrictedItemsNotServedThroughOtherCollections(t *testing.T) {
+	router, _ := newAuthTestRouter(t, false)
+
+	// The backend finds items by ID whatever the collection in the URL, so a
+	// restricted item must not be reachable through a public collection,
+	// even by a client granted it
+	targets := []string{
+		"/collections/sentinel-1/items/nisar-item",
+		"/collections/sentinel-1/items/nisar-item/stack",
+		"/download/sentinel-1/nisar-item/data",
+	}
+	for _, key := range []string{"", "nisar-key"} {
+		var header http.Header
+		if key != "" {
+			header = apiKeyHeader(key)
+		}
+		for _, target := range targets {
+			w := serveFrom(router, http.MethodGet, target, "192.0.2.1:1234", header)
+			if w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "nisar-item.h5") {
+				t.Errorf("key %q: expected %s to be 404, got %d: %s", key, target, w.Code, w.Body.String())
+			}
+		}
+	}
+
+	w := serveFrom(router, http.MethodGet, "/collections/nisar/items/nisar-item/stack", "192.0.2.1:1234", apiKeyHeader("nisar-key"))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the stack through its own collection to be 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuth_SearchNarrowedToVisible(t *testing.T) {
+	router, mock := newAuthTestRouter(t, false)
+
+	w := serveFrom(router, http.MethodGet, "/search", "192.0.2.1:1234", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if got := mock.searchCalls[len(mock.searchCalls)-1].Collections; !reflect.DeepEqual(got, []string{"sentinel-1"}) {
+		t.Errorf("Expected the search to be narrowed to sentinel-1, got %v", got)
+	}
+
+	// Items looked up by ID are filtered by collection, as the backend finds
+	// them whatever their collection
+	w = serveFrom(router, http.MethodGet, "/search?ids=item-1,nisar-item", "192.0.2.1:1234", nil)
+	var fc struct {
+		Features []gostac.Item `json:"features"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &fc); err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 1 || fc.Features[0].Id != "item-1" {
+		t.Errorf("Expected only item-1, got %+v", fc.Features)
+	}
+
+	// A granted client searches everything, as without authentication
+	serveFrom(router, http.MethodGet, "/search", "192.0.2.1:1234", apiKeyHeader("nisar-key"))
+	if got := mock.searchCalls[len(mock.searchCalls)-1].Collections; len(got) != 0 {
+		t.Errorf("Expected an unrestricted search, got collections %v", got)
+	}
+}
+
+func TestAuth_InvalidCredentials(t *testing.T) {
+	router, _ := newAuthTestRouter(t, false)
+
+	tests := []struct {
+		name   string
+		header http.Header
+	}{
+		{"unknown API key", apiKeyHeader("wrong-key")},
+		{"bearer token without JWKS", http.Header{"Authorization": []string{"Bearer abc.def.ghi"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveFrom(router, http.MethodGet, "/collections", "192.0.2.1:1234", tt.header)
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected status 401, got %d", w.Code)
+			}
+			if !strings.Contains(w.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+				t.Errorf("Unexpected WWW-Authenticate %q", w.Header().Get("WWW-Authenticate"))
+			}
+			var stacErr STACError
+			if err := json.NewDecoder(w.Body).Decode(&stacErr); err != nil || stacErr.Code != ErrCodeUnauthorized {
+				t.Errorf("Expected a %s STAC error, got %+v (%v)", ErrCodeUnauthorized, stacErr, err)
+			}
+		})
+	}
+}
+
+func TestAuth_Required(t *testing.T) {
+	router, _ := newAuthTestRouter(t, true)
+
+	if w := serveFrom(router, http.MethodGet, "/collections", "192.0.2.1:1234", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an anonymous request to be 401, got %d", w.Code)
+	}
+	if w := serveFrom(router, http.MethodGet, "/collections", "192.0.2.1:1234", apiKeyHeader("other-key")); w.Code != http.StatusOK {
+		t.Errorf("Expected an authenticated request to be 200, got %d", w.Code)
+	}
+	if w := serveFrom(router, http.MethodGet, "/health", "192.0.2.1:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected /health to need no credentials, got %d", w.Code)
+	}
+}
+
+func TestAuth_MetricsRequireCredentials(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandlers(createTestConfig(), &mockBackend{}, nil, createTestCollections(), logger).
+		WithMetrics(metrics.New()).
+		WithAuthenticator(auth.NewAuthenticator("X-API-Key").WithRequired(true))
+	router := NewRouter(h, logger)
+
+	if w := serveFrom(router, http.MethodGet, "/metrics", "192.0.2.1:1234", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected anonymous /metrics to be 401, got %d", w.Code)
+	}
+	if w := serveFrom(router, http.MethodGet, "/health", "192.0.2.1:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected /health to need no credentials, got %d", w.Code)
+	}
+}
+
+func collectionIDs(t *testing.T, body []byte) []string {
+	t.Helper()
+	var resp struct {
+		Collections []struct {
+			ID string `json:"id"`
+		} `json:"collections"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("Failed to decode collections: %v", err)
+	}
+	ids := make([]string, 0, len(resp.Collections))
+	for _, c := range resp.Collections {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/download"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
+)
+
+// downloadPathPrefix is the path of the download route, whose responses
+// stream as is, without compression
+const downloadPathPrefix = "/download/"
+
+// copiedDownloadHeaders are the upstream response headers passed on to clients
+var copiedDownloadHeaders = []string{
+	"Content-Type",
+	"Content-Length",
+	"Content-Range",
+	"Content-Encoding",
+	"Content-Disposition",
+	"Accept-Ranges",
+	"ETag",
+	"Last-Modified",
+}
+
+// Download streams an item asset from upstream, logging in to Earthdata with
+// the client's token or the server-side one. Range and conditional requests
+// are passed through.
+// GET, HEAD /download/{collectionId}/{itemId}/{asset}
+func (h *Handlers) Download(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "Handlers.Download")
+	defer span.End()
+
+	collectionID := chi.URLParam(r, "collectionId")
+	itemID := chi.URLParam(r, "itemId")
+	assetKey := chi.URLParam(r, "asset")
+
+	// Verify collection exists and is visible to the client
+	if !h.collectionVisible(ctx, collectionID) {
+		WriteNotFound(w, fmt.Sprintf("collection %q not found", collectionID))
+		return
+	}
+
+	// Resolve the asset from the item, so only the hrefs of its metadata
+	// can be fetched
+	item, err := h.backend.GetItem(ctx, collectionID, itemID)
+	if err != nil {
+		h.logger.Error("failed to fetch item",
+			slog.String("collection_id", collectionID),
+			slog.String("item_id", itemID),
+			slog.String("backend", h.backend.Name()),
+			slog.String("error", err.Error()),
+		)
+
+		if strings.Contains(err.Error(), "not found") {
+			WriteNotFound(w, fmt.Sprintf("item %q not found", itemID))
+		} else {
+			WriteUpstreamFailure(w, err, "upstream service error")
+		}
+		return
+	}
+	// Backends look granules up by name, so an item of another collection,
+	// possibly one hidden from the client, is not found in this one
+	if item.Collection != collectionID {
+		WriteNotFound(w, fmt.Sprintf("item %q not found", itemID))
+		return
+	}
+	asset := item.Assets[assetKey]
+	if asset == nil || asset.Href == "" {
+		WriteNotFound(w, fmt.Sprintf("asset %q not found", assetKey))
+		return
+	}
+
+	resp, err := h.downloads.Fetch(ctx, r.Method, asset.Href, h.earthdataToken(r), r.Header)
+	if err != nil {
+		h.logger.Warn("asset download failed",
+			slog.String("collection_id", collectionID),
+			slog.String("item_id", itemID),
+			slog.String("asset", assetKey),
+			slog.String("error", err.Error()),
+		)
+
+		switch {
+		case errors.Is(err, download.ErrUnauthorized):
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Earthdata Login"`)
+			WriteError(w, http.StatusUnauthorized, ErrCodeUnauthorized,
+				fmt.Sprintf("asset requires an Earthdata Login token in the %s header", h.cfg.Download.TokenHeader))
+		case errors.Is(err, download.ErrNotFound):
+			WriteNotFound(w, fmt.Sprintf("asset %q not found upstream", assetKey))
+		default:
+			WriteUpstreamFailure(w, err, "asset download failed")
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	header := w.Header()
+	for _, name := range copiedDownloadHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			header.Set(name, value)
+		} else {
+			header.Del(name)
+		}
+	}
+	if header.Get("Content-Disposition") == "" {
+		if name := path.Base(resp.Request.URL.Path); name != "/" && name != "." {
+			header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		}
+	}
+
+	// Large assets take longer than the server's write timeout to stream
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Debug("failed to clear the write deadline", slog.String("error", err.Error()))
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+	n, err := io.Copy(w, resp.Body)
+	span.SetAttribute("download.bytes", n)
+	if err != nil {
+		h.logger.Warn("asset download interrupted",
+			slog.String("asset", assetKey),
+			slog.Int64("bytes", n),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// earthdataToken returns the Earthdata token supplied by the client, if any.
+// Without authentication of its own, the proxy also passes on the bearer
+// token of the Authorization header.
+func (h *Handlers) earthdataToken(r *http.Request) string {
+	if token := r.Header.Get(h.cfg.Download.TokenHeader); token != "" {
+		if scheme, rest, ok := strings.Cut(token, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			token = rest
+		}
+		return strings.TrimSpace(token)
+	}
+	if h.auth == nil {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// rewriteAssetHrefs points the assets of items at the download route, when
+// the download proxy is enabled with href rewriting.
+func (h *Handlers) rewriteAssetHrefs(items ...*stac.Item) {
+	if h.downloads == nil || !h.cfg.Download.RewriteHrefs {
+		return
+	}
+	for _, item := range items {
+		for key, asset := range item.Assets {
+			if asset == nil || asset.Href == "" {
+				continue
+			}
+			asset.Href = h.downloadURL(item.Collection, item.Id, key)
+		}
+	}
+}
+
+// downloadURL returns the download route of an asset
+func (h *Handlers) downloadURL(collectionID, itemID, assetKey string) string {
+	return h.cfg.STAC.BaseURL + downloadPathPrefix + url.PathEscape(collectionID) + "/" +
+		url.PathEscape(itemID) + "/" + url.PathEscape(assetKey)
+}
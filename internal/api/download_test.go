@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gostac "github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/download"
+)
+
+var downloadTestContent = []byte(`{"granule": "S1A_IW_GRDH_1SDV_20240101"}`)
+
+// assetBackend returns a fresh copy of an item with a data asset at href on
+// every call, as the real backends translate items anew
+type assetBackend struct {
+	mockBackend
+	href string
+}
+
+func (b *assetBackend) item() *gostac.Item {
+	item := createTestItem("item-1", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	item.Assets["data"] = &gostac.Asset{Href: b.href, Type: "application/json", Roles: []string{"data"}}
+	return item
+}
+
+func (b *assetBackend) GetItem(ctx context.Context, collection, itemID string) (*gostac.Item, error) {
+	if itemID != "item-1" {
+		return nil, fmt.Errorf("item not found: %s", itemID)
+	}
+	return b.item(), nil
+}
+
+func (b *assetBackend) Search(ctx context.Context, params *backend.SearchParams) (*backend.SearchResult, error) {
+	return &backend.SearchResult{Items: []*gostac.Item{b.item()}}, nil
+}
+
+// newDownloadTestRouter serves an asset over HTTPS that requires the bearer
+// token "edl-token", like a datapool endpoint
+func newDownloadTestRouter(t *testing.T, serverToken string, rewrite bool) http.Handler {
+	t.Helper()
+	assets := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer edl-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeContent(w, r, "granule.json", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), bytes.NewReader(downloadTestContent))
+	}))
+	t.Cleanup(assets.Close)
+
+	cfg := createTestConfig()
+	cfg.Download.Enabled = true
+	cfg.Download.TokenHeader = "X-Earthdata-Token"
+	cfg.Download.RewriteHrefs = rewrite
+	proxy := download.NewProxy(5 * time.Second).
+		WithToken(serverToken).
+		WithTokenHosts([]string{"127.0.0.1"}).
+		WithTLSConfig(assets.Client().Transport.(*http.Transport).TLSClientConfig)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandlers(cfg, &assetBackend{href: assets.URL + "/products/granule.json"}, nil, createTestCollections(), logger).
+		WithDownloads(proxy)
+	return NewRouter(h, logger)
+}
+
+func TestDownload_StreamsAsset(t *testing.T) {
+	router := newDownloadTestRouter(t, "", false)
+	target := "/download/sentinel-1/item-1/data"
+
+	// The client's token is passed on, in the dedicated header or, as the
+	// proxy does not authenticate clients itself, as a bearer token
+	for _, header := range []http.Header{
+		{"X-Earthdata-Token": []string{"edl-token"}, "Accept-Encoding": []string{"gzip"}},
+		{"Authorization": []string{"Bearer edl-token"}},
+	} {
+		w := serveFrom(router, http.MethodGet, target, "192.0.2.1:1234", header)
+		if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), downloadTestContent) {
+			t.Fatalf("Expected the asset, got status %d: %s", w.Code, w.Body.String())
+		}
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Expected the asset to stream uncompressed, got %q", w.Header().Get("Content-Encoding"))
+		}
+		if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="granule.json"` {
+			t.Errorf("Unexpected Content-Disposition %q", got)
+		}
+	}
+
+	header := http.Header{"X-Earthdata-Token": []string{"Bearer edl-token"}, "Range": []string{"bytes=0-9"}}
+	w := serveFrom(router, http.MethodGet, target, "192.0.2.1:1234", header)
+	if w.Code != http.StatusPartialContent || w.Body.String() != string(downloadTestContent[:10]) {
+		t.Errorf("Expected the first 10 bytes, got status %d: %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); !strings.HasPrefix(got, "bytes 0-9/") {
+		t.Errorf("Unexpected Content-Range %q", got)
+	}
+
+	w = serveFrom(router, http.MethodHead, target, "192.0.2.1:1234", http.Header{"X-Earthdata-Token": []string{"edl-token"}})
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("Expected asset headers only, got status %d, headers %v", w.Code, w.Header())
+	}
+}
+
+func TestDownload_ServerToken(t *testing.T) {
+	router := newDownloadTestRouter(t, "edl-token", false)
+
+	w := serveFrom(router, http.MethodGet, "/download/sentinel-1/item-1/data", "192.0.2.1:1234", nil)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), downloadTestContent) {
+		t.Errorf("Expected the asset, got status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDownload_Errors(t *testing.T) {
+	router := newDownloadTestRouter(t, "", false)
+
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		want   int
+	}{
+		{"no token", "/download/sentinel-1/item-1/data", "", http.StatusUnauthorized},
+		{"rejected token", "/download/sentinel-1/item-1/data", "expired", http.StatusUnauthorized},
+		{"unknown asset", "/download/sentinel-1/item-1/metadata", "edl-token", http.StatusNotFound},
+		{"unknown item", "/download/sentinel-1/item-2/data", "edl-token", http.StatusNotFound},
+		{"unknown collection", "/download/nisar/item-1/data", "edl-token", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveFrom(router, http.MethodGet, tt.target, "192.0.2.1:1234", http.Header{"X-Earthdata-Token": []string{tt.token}})
+			if w.Code != tt.want {
+				t.Fatalf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			var stacErr STACError
+			if err := json.NewDecoder(w.Body).Decode(&stacErr); err != nil || stacErr.Code == "" {
+				t.Errorf("Expected a STAC error, got %+v (%v)", stacErr, err)
+			}
+		})
+	}
+}
+
+func TestDownload_RewritesHrefs(t *testing.T) {
+	want := "http://test.example.com/download/sentinel-1/item-1/data"
+
+	router := newDownloadTestRouter(t, "", true)
+	w := serveFrom(router, http.MethodGet, "/collections/sentinel-1/items/item-1", "192.0.2.1:1234", nil)
+	var item gostac.Item
+	if err := json.Unmarshal(w.Body.Bytes(), &item); err != nil {
+		t.Fatal(err)
+	}
+	if got := item.Assets["data"].Href; got != want {
+		t.Errorf("Expected the item's asset at %s, got %s", want, got)
+	}
+
+	w = serveFrom(router, http.MethodGet, "/collections/sentinel-1/items", "192.0.2.1:1234", nil)
+	var items struct {
+		Features []gostac.Item `json:"features"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items.Features) != 1 || items.Features[0].Assets["data"].Href != want {
+		t.Errorf("Expected the items' asset at %s, got %+v", want, items.Features)
+	}
+
+	// The rewritten href is served
+	w = serveFrom(router, http.MethodGet, strings.TrimPrefix(want, "http://test.example.com"), "192.0.2.1:1234",
+		http.Header{"X-Earthdata-Token": []string{"edl-token"}})
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the rewritten href to be served, got %d", w.Code)
+	}
+
+	// Without rewriting, hrefs point upstream
+	router = newDownloadTestRouter(t, "", false)
+	w = serveFrom(router, http.MethodGet, "/collections/sentinel-1/items/item-1", "192.0.2.1:1234", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &item); err != nil {
+		t.Fatal(err)
+	}
+	if got := item.Assets["data"].Href; !strings.HasSuffix(got, "/products/granule.json") {
+		t.Errorf("Expected the upstream href, got %s", got)
+	}
+}
+
+func TestDownload_DisabledByDefault(t *testing.T) {
+	router := newRateLimitedRouter(NewRateLimits(100, 100))
+	if w := serveFrom(router, http.MethodGet, "/download/sentinel-1/item-1/data", "192.0.2.1:1234", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,511 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/planetlabs/go-ogc/filter"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cql2"
+	intstac "github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/pkg/geojson"
+)
+
+// filterPlan is a CQL2 filter split into the predicates applied upstream through
+// backend.SearchParams and a residual that is evaluated against translated items.
+type filterPlan struct {
+	// residual is evaluated in the proxy; nil when the whole filter was pushed down
+	residual cql2.Predicate
+	// residualExpr is the CQL2-JSON form of the residual, kept for logging
+	residualExpr any
+}
+
+// matches reports whether an item satisfies the residual filter.
+func (p *filterPlan) matches(item *intstac.Item) bool {
+	if p == nil || p.residual == nil {
+		return true
+	}
+	return p.residual(itemPropertyGetter(item))
+}
+
+// hasResidual reports whether some predicates must be evaluated in the proxy.
+func (p *filterPlan) hasResidual() bool {
+	return p != nil && p.residual != nil
+}
+
+// itemPropertyGetter exposes an item to the CQL2 evaluator. The top-level id,
+// collection and geometry are available alongside the item properties. Polarizations
+// are presented in the ASF "VV+VH" form used for pushdown, so a predicate gives
+// the same answer whether it runs upstream or in the proxy.
+func itemPropertyGetter(item *intstac.Item) cql2.PropertyGetter {
+	return func(name string) (any, bool) {
+		switch name {
+		case "id":
+			return item.Id, item.Id != ""
+		case "collection":
+			return item.Collection, item.Collection != ""
+		case "geometry":
+			return item.Geometry, item.Geometry != nil
+		}
+		value, ok := item.Properties[name]
+		if !ok || value == nil {
+			return nil, false
+		}
+		if name == "sar:polarizations" {
+			switch pols := value.(type) {
+			case []string:
+				return strings.Join(pols, "+"), true
+			case []any:
+				strs := make([]string, 0, len(pols))
+				for _, p := range pols {
+					if s, ok := p.(string); ok {
+						strs = append(strs, s)
+					}
+				}
+				return strings.Join(strs, "+"), true
+			}
+		}
+		return value, true
+	}
+}
+
+// planFilter validates a CQL2-JSON filter and pushes down every top-level
+// conjunct the backend can apply exactly. Everything else - comparisons other
+// than equality, NOT, OR across properties, LIKE, IS NULL, spatial, most
+// temporal predicates and BETWEEN on properties without range pushdown -
+// becomes the residual evaluated in the proxy. Pushing down a conjunct of an AND never
+// changes the result, so the combination is equivalent to the original filter.
+// Platform names are spelled as the collections define them.
+func planFilter(expr any, caps backend.FilterCapabilities, collections *config.CollectionRegistry, params *backend.SearchParams) (*filterPlan, error) {
+	if expr == nil {
+		return &filterPlan{}, nil
+	}
+
+	if err := validateFilterSyntax(expr); err != nil {
+		return nil, err
+	}
+	// Compile the full expression first so unsupported operators are reported
+	// even when they appear next to predicates that could be pushed down
+	if _, err := cql2.Compile(expr); err != nil {
+		return nil, err
+	}
+
+	pushed := make(map[string]bool)
+	var residual []any
+	for _, conjunct := range cql2.Conjuncts(expr) {
+		if pushdownConjunct(conjunct, caps, collections, params, pushed) {
+			continue
+		}
+		// Temporal predicates narrow the upstream time range; only a plain
+		// t_intersects on datetime is applied there exactly
+		if narrowToWindow(conjunct, params) {
+			continue
+		}
+		// Spatial predicates narrow the upstream search to their envelope and
+		// are then tested exactly against each footprint
+		narrowToEnvelope(conjunct, params)
+		residual = append(residual, conjunct)
+	}
+
+	if len(residual) == 0 {
+		return &filterPlan{}, nil
+	}
+
+	residualExpr := cql2.And(residual...)
+	predicate, err := cql2.Compile(residualExpr)
+	if err != nil {
+		return nil, err
+	}
+	return &filterPlan{residual: predicate, residualExpr: residualExpr}, nil
+}
+
+// validateFilterSyntax checks that the filter is a well-formed CQL2-JSON
+// document using the go-ogc parser.
+func validateFilterSyntax(expr any) error {
+	if _, ok := expr.(bool); ok {
+		return nil
+	}
+	filterJSON, err := json.Marshal(expr)
+	if err != nil {
+		return fmt.Errorf("failed to encode filter: %w", err)
+	}
+	var f filter.Filter
+	if err := json.Unmarshal(filterJSON, &f); err != nil {
+		return err
+	}
+	if f.Expression == nil {
+		return fmt.Errorf("filter has no expression")
+	}
+	return nil
+}
+
+// pushdownConjunct applies a single conjunct to params if the backend can
+// evaluate it exactly, and reports whether it did. Each property is pushed
+// down at most once; further predicates on it stay in the residual.
+func pushdownConjunct(expr any, caps backend.FilterCapabilities, collections *config.CollectionRegistry, params *backend.SearchParams, pushed map[string]bool) bool {
+	property, values, ok := equalityValues(expr)
+	if !ok || pushed[property] {
+		return false
+	}
+
+	switch caps[property] {
+	case backend.PushdownMulti:
+	case backend.PushdownSingle:
+		if len(values) != 1 {
+			return false
+		}
+	default:
+		return false
+	}
+
+	if !applyPushdown(property, values, collections, params) {
+		return false
+	}
+	pushed[property] = true
+	return true
+}
+
+// valueRange is the range of a BETWEEN predicate, one of the values
+// returned by equalityValues
+type valueRange struct {
+	low, high float64
+}
+
+// equalityValues recognizes predicates that restrict a single property to a
+// set of literal values: property = value, property IN (values), property
+// BETWEEN low AND high as a valueRange, and ORs of those on the same property.
+func equalityValues(expr any) (string, []any, bool) {
+	name, args, ok := cql2.Op(expr)
+	if !ok {
+		return "", nil, false
+	}
+
+	switch name {
+	case "=":
+		if len(args) != 2 {
+			return "", nil, false
+		}
+		if prop, ok := cql2.PropertyName(args[0]); ok && isLiteral(args[1]) {
+			return prop, []any{args[1]}, true
+		}
+		if prop, ok := cql2.PropertyName(args[1]); ok && isLiteral(args[0]) {
+			return prop, []any{args[0]}, true
+		}
+
+	case "in":
+		if len(args) != 2 {
+			return "", nil, false
+		}
+		prop, ok := cql2.PropertyName(args[0])
+		if !ok {
+			return "", nil, false
+		}
+		list, ok := args[1].([]any)
+		if !ok || len(list) == 0 {
+			return "", nil, false
+		}
+		for _, v := range list {
+			if !isLiteral(v) {
+				return "", nil, false
+			}
+		}
+		return prop, list, true
+
+	case "between":
+		if len(args) != 3 {
+			return "", nil, false
+		}
+		prop, ok := cql2.PropertyName(args[0])
+		if !ok {
+			return "", nil, false
+		}
+		low, okLow := args[1].(float64)
+		high, okHigh := args[2].(float64)
+		if !okLow || !okHigh || low > high {
+			return "", nil, false
+		}
+		return prop, []any{valueRange{low, high}}, true
+
+	case "or":
+		var property string
+		var values []any
+		for _, arg := range args {
+			prop, vals, ok := equalityValues(arg)
+			if !ok || (property != "" && prop != property) {
+				return "", nil, false
+			}
+			property = prop
+			values = append(values, vals...)
+		}
+		if property != "" {
+			return property, values, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// narrowToEnvelope restricts the upstream search to the bounding box of the
+// literal geometry in s_intersects, s_within or s_contains on the item
+// geometry. Each of these implies that the footprint intersects the literal,
+// and so its envelope. An existing bbox is intersected with the envelope; a
+// request geometry is left as it is.
+func narrowToEnvelope(expr any, params *backend.SearchParams) {
+	name, args, ok := cql2.Op(expr)
+	if !ok || len(args) != 2 {
+		return
+	}
+	switch name {
+	case "s_intersects", "s_within", "s_contains":
+	default:
+		return
+	}
+
+	literal := args[1]
+	if prop, ok := cql2.PropertyName(args[0]); !ok || prop != "geometry" {
+		if prop, ok := cql2.PropertyName(args[1]); !ok || prop != "geometry" {
+			return
+		}
+		literal = args[0]
+	}
+
+	geom, err := cql2.GeometryLiteral(literal)
+	if err != nil {
+		return
+	}
+	envelope, err := geojson.ComputeBBox(geom)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case len(params.Intersects) > 0:
+	case len(params.BBox) == 0:
+		params.BBox = envelope
+	case len(params.BBox) == 4:
+		narrowed := []float64{
+			math.Max(params.BBox[0], envelope[0]),
+			math.Max(params.BBox[1], envelope[1]),
+			math.Min(params.BBox[2], envelope[2]),
+			math.Min(params.BBox[3], envelope[3]),
+		}
+		if narrowed[0] <= narrowed[2] && narrowed[1] <= narrowed[3] {
+			params.BBox = narrowed
+		}
+	}
+}
+
+// narrowToWindow restricts the upstream time range for a temporal predicate
+// comparing the item datetime with a literal, and reports whether the range
+// now applies the predicate exactly. The upstream APIs match items whose
+// acquisition period intersects Start..End, so that holds for t_intersects
+// when no other time range is set. Every other relation except t_disjoint
+// implies an intersection with a window derived from the literal.
+func narrowToWindow(expr any, params *backend.SearchParams) bool {
+	name, args, ok := cql2.Op(expr)
+	if !ok || len(args) != 2 || !cql2.IsTemporalOp(name) {
+		return false
+	}
+
+	literal := args[1]
+	if prop, ok := cql2.PropertyName(args[0]); !ok || prop != "datetime" {
+		if prop, ok := cql2.PropertyName(args[1]); !ok || prop != "datetime" {
+			return false
+		}
+		literal = args[0]
+		name = cql2.ConverseTemporalOp(name)
+	}
+
+	start, end, err := cql2.TemporalLiteral(literal)
+	if err != nil {
+		return false
+	}
+
+	switch name {
+	case "t_disjoint":
+		return false
+	case "t_before":
+		// The item ends before the literal starts
+		start, end = nil, start
+	case "t_after":
+		// The item starts after the literal ends
+		start, end = end, nil
+	case "t_meets":
+		end = start
+	case "t_metby":
+		start = end
+	}
+	if start == nil && end == nil {
+		return false
+	}
+
+	if name == "t_intersects" && params.Start == nil && params.End == nil {
+		params.Start, params.End = start, end
+		return true
+	}
+
+	if params.Start != nil && (start == nil || params.Start.After(*start)) {
+		start = params.Start
+	}
+	if params.End != nil && (end == nil || params.End.Before(*end)) {
+		end = params.End
+	}
+	if start != nil && end != nil && start.After(*end) {
+		// Nothing can match; leave the range alone and let the residual
+		// reject every item
+		return false
+	}
+	params.Start, params.End = start, end
+	return false
+}
+
+func isLiteral(v any) bool {
+	switch v.(type) {
+	case string, float64:
+		return true
+	}
+	return false
+}
+
+// applyPushdown sets the SearchParams field for a property. It returns false,
+// leaving params untouched, when a value has the wrong type for the field.
+func applyPushdown(property string, values []any, collections *config.CollectionRegistry, params *backend.SearchParams) bool {
+	switch property {
+	case "platform":
+		strs, ok := stringValues(values)
+		if !ok {
+			return false
+		}
+		for i, v := range strs {
+			strs[i] = normalizePlatformForASF(v, collections)
+		}
+		params.Platform = strs
+	case "sar:instrument_mode":
+		strs, ok := stringValues(values)
+		if !ok {
+			return false
+		}
+		params.BeamMode = strs
+	case "sar:polarizations":
+		strs, ok := stringValues(values)
+		if !ok {
+			return false
+		}
+		params.Polarization = strs
+	case "sar:product_type":
+		strs, ok := stringValues(values)
+		if !ok {
+			return false
+		}
+		params.ProcessingLevel = strs
+	case "sat:orbit_state":
+		strs, ok := stringValues(values)
+		if !ok || len(strs) != 1 {
+			return false
+		}
+		params.FlightDirection = strs[0]
+	case "sat:relative_orbit":
+		ints, ok := intValues(values)
+		// ASF filters relative orbits and paths with one parameter, whose
+		// values are alternatives, so only one of them is pushed down
+		if !ok || len(params.Path) > 0 {
+			return false
+		}
+		params.RelativeOrbit = ints
+	case "sat:absolute_orbit":
+		ints, ok := intValues(values)
+		if !ok {
+			return false
+		}
+		params.AbsoluteOrbit = ints
+	case "s1:burst_id":
+		strs, ok := stringValues(values)
+		if !ok {
+			return false
+		}
+		params.BurstID = strs
+	case "s1:subswath":
+		strs, ok := stringValues(values)
+		if !ok {
+			return false
+		}
+		params.Subswath = strs
+	case "asf:frame":
+		ranges, ok := rangeValues(values, true)
+		if !ok {
+			return false
+		}
+		params.Frame = ranges
+	case "asf:path":
+		ranges, ok := rangeValues(values, true)
+		if !ok || len(params.RelativeOrbit) > 0 {
+			return false
+		}
+		params.Path = ranges
+	case "view:off_nadir":
+		ranges, ok := rangeValues(values, false)
+		if !ok {
+			return false
+		}
+		params.OffNadirAngle = ranges
+	default:
+		return false
+	}
+	return true
+}
+
+func stringValues(values []any) ([]string, bool) {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+func intValues(values []any) ([]int, bool) {
+	out := make([]int, 0, len(values))
+	for _, v := range values {
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) {
+			return nil, false
+		}
+		out = append(out, int(f))
+	}
+	return out, true
+}
+
+// rangeValues converts numbers and BETWEEN ranges to backend ranges. Ranges
+// of integer properties are narrowed to the integers they contain, and
+// non-integer values of them rejected.
+func rangeValues(values []any, integer bool) ([]backend.Range, bool) {
+	out := make([]backend.Range, 0, len(values))
+	for _, v := range values {
+		var r backend.Range
+		switch v := v.(type) {
+		case float64:
+			if integer && v != math.Trunc(v) {
+				return nil, false
+			}
+			r = backend.Range{Min: v, Max: v}
+		case valueRange:
+			r = backend.Range{Min: v.low, Max: v.high}
+			if integer {
+				r = backend.Range{Min: math.Ceil(v.low), Max: math.Floor(v.high)}
+			}
+			if r.Min > r.Max {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+		out = append(out, r)
+	}
+	return out, true
+}
@@ -0,0 +1,450 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	gostac "github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cql2"
+)
+
+func cmrTestCapabilities() backend.FilterCapabilities {
+	return backend.FilterCapabilities{
+		"sar:instrument_mode": backend.PushdownSingle,
+		"sar:polarizations":   backend.PushdownSingle,
+		"sar:product_type":    backend.PushdownSingle,
+		"sat:orbit_state":     backend.PushdownSingle,
+		"sat:relative_orbit":  backend.PushdownSingle,
+		"s1:burst_id":         backend.PushdownSingle,
+		"s1:subswath":         backend.PushdownSingle,
+		"asf:frame":           backend.PushdownSingle,
+		"asf:path":            backend.PushdownSingle,
+		"view:off_nadir":      backend.PushdownSingle,
+	}
+}
+
+func TestPlanFilter_Pushdown(t *testing.T) {
+	collections, err := config.LoadCollections("../../collections")
+	if err != nil {
+		t.Fatalf("LoadCollections error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		filter       string
+		caps         backend.FilterCapabilities
+		want         backend.SearchParams
+		wantResidual bool
+	}{
+		{
+			name:   "equality pushed down",
+			filter: "platform = 'sentinel-1a' AND sar:instrument_mode = 'IW'",
+			caps:   backend.DefaultFilterCapabilities(),
+			want:   backend.SearchParams{Platform: []string{"Sentinel-1A"}, BeamMode: []string{"IW"}},
+		},
+		{
+			name:   "in and same-property or pushed down",
+			filter: "sar:polarizations IN ('VV', 'VV+VH') AND (sat:relative_orbit = 1 OR sat:relative_orbit = 2)",
+			caps:   backend.DefaultFilterCapabilities(),
+			want:   backend.SearchParams{Polarization: []string{"VV", "VV+VH"}, RelativeOrbit: []int{1, 2}},
+		},
+		{
+			name:         "not stays residual",
+			filter:       "NOT (platform = 'sentinel-1a')",
+			caps:         backend.DefaultFilterCapabilities(),
+			wantResidual: true,
+		},
+		{
+			name:         "mixed-property or stays residual",
+			filter:       "platform = 'sentinel-1a' OR sar:instrument_mode = 'IW'",
+			caps:         backend.DefaultFilterCapabilities(),
+			wantResidual: true,
+		},
+		{
+			name:         "inequality stays residual next to pushed predicate",
+			filter:       "sar:instrument_mode = 'IW' AND sat:relative_orbit <> 10",
+			caps:         backend.DefaultFilterCapabilities(),
+			want:         backend.SearchParams{BeamMode: []string{"IW"}},
+			wantResidual: true,
+		},
+		{
+			name:         "second predicate on a property stays residual",
+			filter:       "sar:product_type = 'GRD_HD' AND sar:product_type IN ('GRD_HD', 'SLC')",
+			caps:         backend.DefaultFilterCapabilities(),
+			want:         backend.SearchParams{ProcessingLevel: []string{"GRD_HD"}},
+			wantResidual: true,
+		},
+		{
+			name:         "processing level is evaluated in the proxy",
+			filter:       "processing:level = 'L1'",
+			caps:         backend.DefaultFilterCapabilities(),
+			wantResidual: true,
+		},
+		{
+			name:         "non-integer orbit stays residual",
+			filter:       "sat:relative_orbit = 1.5",
+			caps:         backend.DefaultFilterCapabilities(),
+			wantResidual: true,
+		},
+		{
+			name:   "single value pushed down to CMR",
+			filter: "sar:instrument_mode IN ('IW')",
+			caps:   cmrTestCapabilities(),
+			want:   backend.SearchParams{BeamMode: []string{"IW"}},
+		},
+		{
+			name:         "multiple values stay residual on CMR",
+			filter:       "sar:instrument_mode IN ('IW', 'EW')",
+			caps:         cmrTestCapabilities(),
+			wantResidual: true,
+		},
+		{
+			name:         "platform stays residual on CMR",
+			filter:       "platform = 'sentinel-1a'",
+			caps:         cmrTestCapabilities(),
+			wantResidual: true,
+		},
+		{
+			name:   "burst IDs pushed down",
+			filter: "s1:burst_id IN ('064_136231_IW2', '064_136232_IW2')",
+			caps:   backend.DefaultFilterCapabilities(),
+			want:   backend.SearchParams{BurstID: []string{"064_136231_IW2", "064_136232_IW2"}},
+		},
+		{
+			name:         "subswath stays residual on ASF",
+			filter:       "s1:burst_id = '064_136231_IW2' AND s1:subswath = 'IW2'",
+			caps:         backend.DefaultFilterCapabilities(),
+			want:         backend.SearchParams{BurstID: []string{"064_136231_IW2"}},
+			wantResidual: true,
+		},
+		{
+			name:   "burst ID and subswath pushed down to CMR",
+			filter: "s1:burst_id = '064_136231_IW2' AND s1:subswath = 'IW2'",
+			caps:   cmrTestCapabilities(),
+			want:   backend.SearchParams{BurstID: []string{"064_136231_IW2"}, Subswath: []string{"IW2"}},
+		},
+		{
+			name:   "frame range pushed down",
+			filter: "asf:frame BETWEEN 100 AND 150",
+			caps:   backend.DefaultFilterCapabilities(),
+			want:   backend.SearchParams{Frame: []backend.Range{{Min: 100, Max: 150}}},
+		},
+		{
+			name:   "path values and ranges pushed down",
+			filter: "asf:path IN (422, 424) OR asf:path BETWEEN 430 AND 432",
+			caps:   backend.DefaultFilterCapabilities(),
+			want:   backend.SearchParams{Path: []backend.Range{{Min: 422, Max: 422}, {Min: 424, Max: 424}, {Min: 430, Max: 432}}},
+		},
+		{
+			name:   "integer range narrowed to its integers",
+			filter: "asf:frame BETWEEN 99.5 AND 150.5",
+			caps:   backend.DefaultFilterCapabilities(),
+			want:   backend.SearchParams{Frame: []backend.Range{{Min: 100, Max: 150}}},
+		},
+		{
+			name:         "fractional frame stays residual",
+			filter:       "asf:frame = 100.5",
+			caps:         backend.DefaultFilterCapabilities(),
+			wantResidual: true,
+		},
+		{
+			name:   "off-nadir range pushed down",
+			filter: "view:off_nadir BETWEEN 21.5 AND 34.3",
+			caps:   backend.DefaultFilterCapabilities(),
+			want:   backend.SearchParams{OffNadirAngle: []backend.Range{{Min: 21.5, Max: 34.3}}},
+		},
+		{
+			name:         "path with relative orbit stays residual on ASF",
+			filter:       "sat:relative_orbit = 64 AND asf:path = 422",
+			caps:         backend.DefaultFilterCapabilities(),
+			want:         backend.SearchParams{RelativeOrbit: []int{64}},
+			wantResidual: true,
+		},
+		{
+			name:         "relative orbit with path stays residual on ASF",
+			filter:       "asf:path BETWEEN 60 AND 70 AND sat:relative_orbit = 64",
+			caps:         backend.DefaultFilterCapabilities(),
+			want:         backend.SearchParams{Path: []backend.Range{{Min: 60, Max: 70}}},
+			wantResidual: true,
+		},
+		{
+			name:         "path with relative orbit stays residual on CMR",
+			filter:       "sat:relative_orbit = 64 AND asf:path BETWEEN 60 AND 70",
+			caps:         cmrTestCapabilities(),
+			want:         backend.SearchParams{RelativeOrbit: []int{64}},
+			wantResidual: true,
+		},
+		{
+			name:   "frame range pushed down to CMR",
+			filter: "asf:frame BETWEEN 100 AND 150 AND asf:path = 422",
+			caps:   cmrTestCapabilities(),
+			want:   backend.SearchParams{Frame: []backend.Range{{Min: 100, Max: 150}}, Path: []backend.Range{{Min: 422, Max: 422}}},
+		},
+		{
+			name:         "several frames stay residual on CMR",
+			filter:       "asf:frame IN (100, 150)",
+			caps:         cmrTestCapabilities(),
+			wantResidual: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := cql2.ParseText(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseText error: %v", err)
+			}
+
+			var params backend.SearchParams
+			plan, err := planFilter(expr, tt.caps, collections, &params)
+			if err != nil {
+				t.Fatalf("planFilter error: %v", err)
+			}
+
+			if !reflect.DeepEqual(params, tt.want) {
+				t.Errorf("pushed params = %+v, want %+v", params, tt.want)
+			}
+			if plan.hasResidual() != tt.wantResidual {
+				t.Errorf("hasResidual = %v, want %v (residual %v)", plan.hasResidual(), tt.wantResidual, plan.residualExpr)
+			}
+		})
+	}
+}
+
+func TestPlanFilter_ResidualMatchesItems(t *testing.T) {
+	expr, err := cql2.ParseText("NOT (platform = 'sentinel-1a') AND sar:polarizations = 'VV+VH'")
+	if err != nil {
+		t.Fatalf("ParseText error: %v", err)
+	}
+
+	var params backend.SearchParams
+	plan, err := planFilter(expr, backend.DefaultFilterCapabilities(), nil, &params)
+	if err != nil {
+		t.Fatalf("planFilter error: %v", err)
+	}
+	if !reflect.DeepEqual(params.Polarization, []string{"VV+VH"}) {
+		t.Errorf("expected polarization pushed down, got %v", params.Polarization)
+	}
+
+	item := func(platform string) *gostac.Item {
+		item := createTestItem("item", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+		item.Properties["platform"] = platform
+		item.Properties["sar:polarizations"] = []any{"VV", "VH"}
+		return item
+	}
+
+	if plan.matches(item("sentinel-1a")) {
+		t.Error("expected sentinel-1a item to be excluded")
+	}
+	if !plan.matches(item("sentinel-1b")) {
+		t.Error("expected sentinel-1b item to match")
+	}
+}
+
+func TestPlanFilter_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr any
+	}{
+		{
+			name: "unknown operator",
+			expr: map[string]any{"op": "regex", "args": []any{map[string]any{"property": "id"}, "x"}},
+		},
+		{
+			name: "malformed expression",
+			expr: map[string]any{"args": []any{"x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params backend.SearchParams
+			if _, err := planFilter(tt.expr, backend.DefaultFilterCapabilities(), nil, &params); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestPlanFilter_SpatialEnvelope(t *testing.T) {
+	tests := []struct {
+		name       string
+		filter     string
+		bbox       []float64
+		intersects []byte
+		wantBBox   []float64
+	}{
+		{
+			name:     "envelope of polygon pushed as bbox",
+			filter:   "S_CONTAINS(geometry, POLYGON((1 2, 5 2, 3 8, 1 2)))",
+			wantBBox: []float64{1, 2, 5, 8},
+		},
+		{
+			name:     "geometry as second argument",
+			filter:   "S_WITHIN(BBOX(-10, -5, 10, 5), geometry)",
+			wantBBox: []float64{-10, -5, 10, 5},
+		},
+		{
+			name:     "intersected with request bbox",
+			filter:   "S_INTERSECTS(geometry, BBOX(0, 0, 10, 10))",
+			bbox:     []float64{5, -5, 20, 5},
+			wantBBox: []float64{5, 0, 10, 5},
+		},
+		{
+			name:     "request bbox kept when envelopes do not overlap",
+			filter:   "S_INTERSECTS(geometry, BBOX(0, 0, 1, 1))",
+			bbox:     []float64{5, 5, 6, 6},
+			wantBBox: []float64{5, 5, 6, 6},
+		},
+		{
+			name:       "request geometry left alone",
+			filter:     "S_INTERSECTS(geometry, BBOX(0, 0, 1, 1))",
+			intersects: []byte(`{"type":"Point","coordinates":[0.5,0.5]}`),
+		},
+		{
+			name:   "disjoint is not pushed",
+			filter: "S_DISJOINT(geometry, BBOX(0, 0, 1, 1))",
+		},
+		{
+			name:   "spatial predicate under OR is not pushed",
+			filter: "S_INTERSECTS(geometry, BBOX(0, 0, 1, 1)) OR platform = 'sentinel-1a'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := cql2.ParseText(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseText error: %v", err)
+			}
+
+			params := backend.SearchParams{BBox: tt.bbox, Intersects: tt.intersects}
+			plan, err := planFilter(expr, backend.DefaultFilterCapabilities(), nil, &params)
+			if err != nil {
+				t.Fatalf("planFilter error: %v", err)
+			}
+
+			if !reflect.DeepEqual(params.BBox, tt.wantBBox) {
+				t.Errorf("BBox = %v, want %v", params.BBox, tt.wantBBox)
+			}
+			// The exact test always runs in the proxy
+			if !plan.hasResidual() {
+				t.Error("expected spatial predicate to remain in the residual")
+			}
+		})
+	}
+}
+
+func TestPlanFilter_TemporalWindow(t *testing.T) {
+	ts := func(s string) *time.Time {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &parsed
+	}
+
+	tests := []struct {
+		name         string
+		filter       string
+		start, end   *time.Time
+		wantStart    *time.Time
+		wantEnd      *time.Time
+		wantResidual bool
+	}{
+		{
+			name:      "t_intersects is applied upstream exactly",
+			filter:    "T_INTERSECTS(datetime, INTERVAL('2023-01-01T00:00:00Z', '2023-02-01T00:00:00Z'))",
+			wantStart: ts("2023-01-01T00:00:00Z"),
+			wantEnd:   ts("2023-02-01T00:00:00Z"),
+		},
+		{
+			name:      "t_intersects with an open end",
+			filter:    "T_INTERSECTS(datetime, INTERVAL('2023-01-01T00:00:00Z', '..'))",
+			wantStart: ts("2023-01-01T00:00:00Z"),
+		},
+		{
+			name:         "t_during narrows and is evaluated in the proxy",
+			filter:       "T_DURING(datetime, INTERVAL('2023-01-01T00:00:00Z', '2023-02-01T00:00:00Z'))",
+			wantStart:    ts("2023-01-01T00:00:00Z"),
+			wantEnd:      ts("2023-02-01T00:00:00Z"),
+			wantResidual: true,
+		},
+		{
+			name:         "t_before bounds the end",
+			filter:       "T_BEFORE(datetime, TIMESTAMP('2023-01-01T00:00:00Z'))",
+			wantEnd:      ts("2023-01-01T00:00:00Z"),
+			wantResidual: true,
+		},
+		{
+			name:         "t_after with datetime second bounds the end",
+			filter:       "T_AFTER(TIMESTAMP('2023-01-01T00:00:00Z'), datetime)",
+			wantEnd:      ts("2023-01-01T00:00:00Z"),
+			wantResidual: true,
+		},
+		{
+			name:         "t_after bounds the start",
+			filter:       "T_AFTER(datetime, INTERVAL('2022-12-01T00:00:00Z', '2023-01-01T00:00:00Z'))",
+			wantStart:    ts("2023-01-01T00:00:00Z"),
+			wantResidual: true,
+		},
+		{
+			name:         "intersected with the request datetime",
+			filter:       "T_INTERSECTS(datetime, INTERVAL('2023-01-01T00:00:00Z', '2023-02-01T00:00:00Z'))",
+			start:        ts("2023-01-15T00:00:00Z"),
+			end:          ts("2023-03-01T00:00:00Z"),
+			wantStart:    ts("2023-01-15T00:00:00Z"),
+			wantEnd:      ts("2023-02-01T00:00:00Z"),
+			wantResidual: true,
+		},
+		{
+			name:         "request datetime kept when windows do not overlap",
+			filter:       "T_INTERSECTS(datetime, INTERVAL('2023-01-01T00:00:00Z', '2023-02-01T00:00:00Z'))",
+			start:        ts("2024-01-01T00:00:00Z"),
+			wantStart:    ts("2024-01-01T00:00:00Z"),
+			wantResidual: true,
+		},
+		{
+			name:         "t_disjoint is not pushed",
+			filter:       "T_DISJOINT(datetime, INTERVAL('2023-01-01T00:00:00Z', '2023-02-01T00:00:00Z'))",
+			wantResidual: true,
+		},
+		{
+			name:         "other properties are not pushed",
+			filter:       "T_INTERSECTS(start_datetime, INTERVAL('2023-01-01T00:00:00Z', '2023-02-01T00:00:00Z'))",
+			wantResidual: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := cql2.ParseText(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseText error: %v", err)
+			}
+
+			params := backend.SearchParams{Start: tt.start, End: tt.end}
+			plan, err := planFilter(expr, backend.DefaultFilterCapabilities(), nil, &params)
+			if err != nil {
+				t.Fatalf("planFilter error: %v", err)
+			}
+
+			if !timeEqual(params.Start, tt.wantStart) || !timeEqual(params.End, tt.wantEnd) {
+				t.Errorf("range = %v..%v, want %v..%v", params.Start, params.End, tt.wantStart, tt.wantEnd)
+			}
+			if plan.hasResidual() != tt.wantResidual {
+				t.Errorf("hasResidual = %v, want %v", plan.hasResidual(), tt.wantResidual)
+			}
+		})
+	}
+}
+
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
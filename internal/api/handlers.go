@@ -1,7 +1,7 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -11,11 +11,15 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/planetlabs/go-ogc/filter"
 	"github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/auth"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cache"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/download"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
 	intstac "github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/translate"
 )
 
@@ -26,6 +30,13 @@ type Handlers struct {
 	translator  *translate.Translator
 	collections *config.CollectionRegistry
 	cursorStore intstac.CursorStore
+	keyring     *intstac.CursorKeyring
+	cache       *cache.ReadThrough
+	metrics     *metrics.Metrics
+	tracer      *tracing.Tracer
+	rateLimits  *RateLimits
+	auth        *auth.Authenticator
+	downloads   *download.Proxy
 	logger      *slog.Logger
 }
 
@@ -52,6 +63,51 @@ func (h *Handlers) WithCursorStore(store intstac.CursorStore) *Handlers {
 	return h
 }
 
+// WithCursorKeyring sets the keyring sealing the cursors handed to clients.
+// Without one, cursors are plain base64 JSON that clients could alter.
+func (h *Handlers) WithCursorKeyring(keyring *intstac.CursorKeyring) *Handlers {
+	h.keyring = keyring
+	return h
+}
+
+// WithCache sets the upstream response cache whose counters are reported by Health.
+func (h *Handlers) WithCache(rt *cache.ReadThrough) *Handlers {
+	h.cache = rt
+	return h
+}
+
+// WithMetrics sets the metrics recorded by the handlers and served on /metrics.
+func (h *Handlers) WithMetrics(m *metrics.Metrics) *Handlers {
+	h.metrics = m
+	return h
+}
+
+// WithTracer sets the tracer that starts a span for each request.
+func (h *Handlers) WithTracer(t *tracing.Tracer) *Handlers {
+	h.tracer = t
+	return h
+}
+
+// WithAuthenticator authenticates requests with a. Without one, every client
+// is anonymous and restricted collections are hidden from all.
+func (h *Handlers) WithAuthenticator(a *auth.Authenticator) *Handlers {
+	h.auth = a
+	return h
+}
+
+// WithDownloads enables the asset download route, which streams assets
+// through p.
+func (h *Handlers) WithDownloads(p *download.Proxy) *Handlers {
+	h.downloads = p
+	return h
+}
+
+// WithRateLimits sets the limits throttling each client's requests.
+func (h *Handlers) WithRateLimits(limits *RateLimits) *Handlers {
+	h.rateLimits = limits
+	return h
+}
+
 // LandingPage returns the STAC API landing page (root catalog).
 // GET /
 func (h *Handlers) LandingPage(w http.ResponseWriter, r *http.Request) {
@@ -85,9 +141,11 @@ func (h *Handlers) LandingPage(w http.ResponseWriter, r *http.Request) {
 			Type:   "application/geo+json",
 			Method: "POST",
 		})
+		landing.AddLink("aggregate", baseURL+"/aggregate", "application/json")
+		landing.AddLink("aggregations", baseURL+"/aggregations", "application/json")
 	}
 
-	landing.AddLink("service-desc", baseURL+"/api", "application/vnd.oai.openapi+json;version=3.0")
+	landing.AddLink("service-desc", baseURL+"/api", OpenAPIMediaType)
 	landing.AddLink("service-doc", baseURL+"/api.html", "text/html")
 
 	WriteJSON(w, http.StatusOK, landing)
@@ -108,8 +166,8 @@ func (h *Handlers) Conformance(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) Collections(w http.ResponseWriter, r *http.Request) {
 	baseURL := h.cfg.STAC.BaseURL
 
-	// Get all collections from registry
-	collectionConfigs := h.collections.All()
+	// Get the collections the client may access from the registry
+	collectionConfigs := h.visibleCollections(r.Context())
 	h.logger.Info("building collections response", "count", len(collectionConfigs))
 	collections := make([]*stac.Collection, 0, len(collectionConfigs))
 
@@ -147,7 +205,7 @@ func (h *Handlers) Collection(w http.ResponseWriter, r *http.Request) {
 
 	// Get collection from registry
 	collectionConfig := h.collections.Get(collectionID)
-	if collectionConfig == nil {
+	if collectionConfig == nil || !h.collectionVisible(r.Context(), collectionID) {
 		WriteNotFound(w, fmt.Sprintf("collection %q not found", collectionID))
 		return
 	}
@@ -161,14 +219,17 @@ func (h *Handlers) Collection(w http.ResponseWriter, r *http.Request) {
 // Items returns items from a specific collection.
 // GET /collections/{collectionId}/items
 func (h *Handlers) Items(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "Handlers.Items")
+	defer span.End()
+
 	collectionID := chi.URLParam(r, "collectionId")
 	if collectionID == "" {
 		WriteBadRequest(w, "collection ID is required")
 		return
 	}
 
-	// Verify collection exists
-	if !h.collections.Has(collectionID) {
+	// Verify collection exists and is visible to the client
+	if !h.collectionVisible(ctx, collectionID) {
 		WriteNotFound(w, fmt.Sprintf("collection %q not found", collectionID))
 		return
 	}
@@ -190,11 +251,14 @@ func (h *Handlers) Items(w http.ResponseWriter, r *http.Request) {
 		searchReq.Limit = h.cfg.Features.MaxLimit
 	}
 
+	// Cursors are bound to the search they page through
+	fingerprint := searchReq.Fingerprint(collectionID)
+
 	// Decode current cursor if present (needed for filtering duplicates on ASF backend)
 	var currentCursor *intstac.Cursor
-	if searchReq.Cursor != "" && !h.backend.SupportsPagination() {
+	if searchReq.Cursor != "" {
 		var cursorErr error
-		currentCursor, cursorErr = intstac.DecodeCursorWithStore(searchReq.Cursor, h.cursorStore)
+		currentCursor, cursorErr = h.decodeCursor(searchReq, collectionID)
 		if cursorErr != nil {
 			h.logger.Warn("failed to decode cursor",
 				slog.String("error", cursorErr.Error()),
@@ -205,56 +269,40 @@ func (h *Handlers) Items(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Build backend search params
-	// For ASF backend with cursor, over-fetch to compensate for SeenIDs filtering
-	backendParams := h.buildBackendParams(searchReq, collectionID)
-	backendLimit := searchReq.Limit
-	if !h.backend.SupportsPagination() && currentCursor != nil && len(currentCursor.SeenIDs) > 0 {
-		// Request extra items to compensate for filtering
-		backendLimit = searchReq.Limit + len(currentCursor.SeenIDs)
-		if backendLimit > h.cfg.Features.MaxLimit {
-			backendLimit = h.cfg.Features.MaxLimit
-		}
-		backendParams.Limit = backendLimit
+	// Build backend search params, pushing down the parts of the filter the backend can apply
+	backendParams, plan, err := h.buildBackendParams(searchReq, collectionID)
+	if err != nil {
+		WriteInvalidParameter(w, fmt.Sprintf("invalid filter: %v", err))
+		return
 	}
+	h.restrictToVisible(ctx, backendParams, plan)
+	h.setBackendLimit(backendParams, plan, searchReq.Limit, currentCursor)
 
 	// Execute search against backend
-	ctx := r.Context()
-	result, err := h.backend.Search(ctx, backendParams)
+	page, err := h.fetchPage(ctx, backendParams, plan, searchReq.Limit, currentCursor)
 	if err != nil {
 		h.logger.Error("backend search failed",
 			slog.String("collection_id", collectionID),
 			slog.String("backend", h.backend.Name()),
 			slog.String("error", err.Error()),
 		)
-		WriteUpstreamError(w, "upstream search service error")
+		WriteUpstreamFailure(w, err, "upstream search service error")
 		return
 	}
 
-	// Track if backend returned a full page (used for pagination decision)
-	backendReturnedFullPage := len(result.Items) >= backendLimit
+	// Apply the secondary sort keys within the page
+	intstac.SortItems(page.Features, searchReq.Sortby)
 
-	// Build STAC ItemCollection from backend results
-	itemCollection := intstac.NewItemCollection(result.Items)
-
-	// Filter out items that were already returned in the previous page (for ASF backend)
-	if !h.backend.SupportsPagination() && currentCursor != nil {
-		itemCollection.Features = intstac.FilterSeenItems(itemCollection.Features, func(item *intstac.Item) string {
-			return item.Id
-		}, currentCursor)
-	}
-
-	// Trim to requested limit if we over-fetched
-	if len(itemCollection.Features) > searchReq.Limit {
-		itemCollection.Features = itemCollection.Features[:searchReq.Limit]
-	}
+	// Build STAC ItemCollection from the filtered page
+	h.rewriteAssetHrefs(page.Features...)
+	itemCollection := intstac.NewItemCollection(page.Features)
 
 	// Set context with pagination metadata
 	// For ASF backend: only report TotalCount on first page - subsequent pages have
 	// modified queries that return remaining count, not total count
 	// For CMR backend: always report TotalCount since it uses native pagination
 	limit := searchReq.Limit
-	totalCount := result.TotalCount
+	totalCount := page.TotalCount
 	if !h.backend.SupportsPagination() && currentCursor != nil {
 		totalCount = nil // Don't report inaccurate count on paginated ASF requests
 	}
@@ -269,27 +317,49 @@ func (h *Handlers) Items(w http.ResponseWriter, r *http.Request) {
 	itemCollection.AddLink("collection", fmt.Sprintf("%s/collections/%s", baseURL, collectionID), "application/json")
 
 	// Build pagination links based on backend type
-	if h.backend.SupportsPagination() && result.NextCursor != "" {
-		// CMR-style: use the cursor from the backend directly
-		nextURL := buildNextURLWithCursor(selfURL, r.URL.Query(), result.NextCursor, searchReq.Limit)
-		itemCollection.Links = append(itemCollection.Links, &stac.Link{
-			Rel:  "next",
-			Href: nextURL,
-			Type: "application/geo+json",
-		})
-	} else if !h.backend.SupportsPagination() && len(itemCollection.Features) > 0 {
-		// ASF-style: build cursor from item timestamps
+	if h.backend.SupportsPagination() && page.NextCursor != "" && page.BackendHasMoreData {
+		// CMR-style: wrap the backend's search-after token in a proxy cursor
+		nextURL, err := h.cursorNextURL(selfURL, r.URL.Query(), &intstac.Cursor{
+			Direction:   "next",
+			SearchAfter: page.NextCursor,
+		}, searchReq.Limit, fingerprint)
+		if err != nil {
+			h.logger.Error("failed to encode cursor", slog.String("error", err.Error()))
+		} else {
+			itemCollection.Links = append(itemCollection.Links, &stac.Link{
+				Rel:  "next",
+				Href: nextURL,
+				Type: "application/geo+json",
+			})
+		}
+	} else if page.Resume != nil {
+		// The scan stopped between time windows before filling the page
+		nextURL, err := h.cursorNextURL(selfURL, r.URL.Query(), page.Resume, searchReq.Limit, fingerprint)
+		if err != nil {
+			h.logger.Error("failed to encode cursor", slog.String("error", err.Error()))
+		} else {
+			itemCollection.Links = append(itemCollection.Links, &stac.Link{
+				Rel:  "next",
+				Href: nextURL,
+				Type: "application/geo+json",
+			})
+		}
+	} else if !h.backend.SupportsPagination() && len(page.Consumed) > 0 {
+		// ASF-style: build cursor from the timestamps of the items this page covers
 		// Generate next link if backend returned a full page (more data likely exists)
-		items := extractItemTimeInfos(itemCollection.Features)
+		items := extractItemTimeInfos(page.Consumed)
 		paginationInfo := intstac.CursorPaginationInfo{
 			BaseURL:            selfURL,
 			Limit:              searchReq.Limit,
 			ReturnedCount:      len(itemCollection.Features),
-			BackendHasMoreData: backendReturnedFullPage,
+			BackendHasMoreData: page.BackendHasMoreData,
 			QueryParams:        r.URL.Query(),
 			Items:              items,
 			CurrentCursor:      currentCursor,
 			CursorStore:        h.cursorStore,
+			Keyring:            h.keyring,
+			Query:              fingerprint,
+			Ascending:          intstac.SortsAscending(searchReq.Sortby),
 		}
 		paginationLinks := intstac.BuildCursorPaginationLinks(paginationInfo)
 		for _, link := range paginationLinks {
@@ -297,12 +367,28 @@ func (h *Handlers) Items(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	WriteGeoJSON(w, http.StatusOK, itemCollection)
+	h.metrics.ObservePage("items", len(page.Features))
+	span.SetAttribute("page.items", len(page.Features))
+
+	// Reduce the items to the requested fields
+	response, err := itemCollection.WithFields(searchReq.Fields)
+	if err != nil {
+		h.logger.Error("failed to apply fields", slog.String("error", err.Error()))
+		WriteInternalError(w, "failed to apply fields")
+		return
+	}
+
+	_, encodeSpan := tracing.Start(ctx, "encode response")
+	WriteGeoJSON(w, http.StatusOK, response)
+	encodeSpan.End()
 }
 
 // Item returns a single item by ID from a collection.
 // GET /collections/{collectionId}/items/{itemId}
 func (h *Handlers) Item(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "Handlers.Item")
+	defer span.End()
+
 	collectionID := chi.URLParam(r, "collectionId")
 	itemID := chi.URLParam(r, "itemId")
 
@@ -316,14 +402,13 @@ func (h *Handlers) Item(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify collection exists
-	if !h.collections.Has(collectionID) {
+	// Verify collection exists and is visible to the client
+	if !h.collectionVisible(ctx, collectionID) {
 		WriteNotFound(w, fmt.Sprintf("collection %q not found", collectionID))
 		return
 	}
 
 	// Fetch item from backend
-	ctx := r.Context()
 	item, err := h.backend.GetItem(ctx, collectionID, itemID)
 	if err != nil {
 		h.logger.Error("failed to fetch item",
@@ -336,10 +421,16 @@ func (h *Handlers) Item(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(err.Error(), "not found") {
 			WriteNotFound(w, fmt.Sprintf("item %q not found", itemID))
 		} else {
-			WriteUpstreamError(w, "upstream service error")
+			WriteUpstreamFailure(w, err, "upstream service error")
 		}
 		return
 	}
+	// Backends look granules up by name, so an item of another collection,
+	// possibly one hidden from the client, is not found in this one
+	if item.Collection != collectionID {
+		WriteNotFound(w, fmt.Sprintf("item %q not found", itemID))
+		return
+	}
 
 	// Add links
 	baseURL := h.cfg.STAC.BaseURL
@@ -366,12 +457,19 @@ func (h *Handlers) Item(w http.ResponseWriter, r *http.Request) {
 		},
 	)
 
+	h.rewriteAssetHrefs(item)
+
+	_, encodeSpan := tracing.Start(ctx, "encode response")
 	WriteGeoJSON(w, http.StatusOK, item)
+	encodeSpan.End()
 }
 
 // Search performs a cross-collection search.
 // GET/POST /search
 func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "Handlers.Search")
+	defer span.End()
+
 	if !h.cfg.Features.EnableSearch {
 		WriteError(w, http.StatusNotImplemented, "NotImplemented", "search endpoint is disabled")
 		return
@@ -406,11 +504,14 @@ func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 		searchReq.Limit = h.cfg.Features.MaxLimit
 	}
 
+	// Cursors are bound to the search they page through
+	fingerprint := searchReq.Fingerprint("")
+
 	// Decode current cursor if present (needed for filtering duplicates on ASF backend)
 	var currentCursor *intstac.Cursor
-	if searchReq.Cursor != "" && !h.backend.SupportsPagination() {
+	if searchReq.Cursor != "" {
 		var cursorErr error
-		currentCursor, cursorErr = intstac.DecodeCursorWithStore(searchReq.Cursor, h.cursorStore)
+		currentCursor, cursorErr = h.decodeCursor(searchReq, "")
 		if cursorErr != nil {
 			h.logger.Warn("failed to decode cursor",
 				slog.String("error", cursorErr.Error()),
@@ -421,29 +522,28 @@ func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Build backend search params
-	// For ASF backend with cursor, over-fetch to compensate for SeenIDs filtering
-	backendParams := h.buildBackendParams(searchReq, "")
-	backendLimit := searchReq.Limit
-	if !h.backend.SupportsPagination() && currentCursor != nil && len(currentCursor.SeenIDs) > 0 {
-		backendLimit = searchReq.Limit + len(currentCursor.SeenIDs)
-		if backendLimit > h.cfg.Features.MaxLimit {
-			backendLimit = h.cfg.Features.MaxLimit
-		}
-		backendParams.Limit = backendLimit
+	// Build backend search params, pushing down the parts of the filter the backend can apply
+	backendParams, plan, err := h.buildBackendParams(searchReq, "")
+	if err != nil {
+		WriteInvalidParameter(w, fmt.Sprintf("invalid filter: %v", err))
+		return
 	}
 
-	// Validate collections exist
+	// Validate collections exist and are visible to the client
 	for _, collID := range searchReq.Collections {
-		if !h.collections.Has(collID) {
+		if !h.collectionVisible(ctx, collID) {
 			WriteNotFound(w, fmt.Sprintf("collection %q not found", collID))
 			return
 		}
 	}
+	if !h.restrictToVisible(ctx, backendParams, plan) {
+		writeNoVisibleCollections(w, r)
+		return
+	}
+	h.setBackendLimit(backendParams, plan, searchReq.Limit, currentCursor)
 
 	// Execute search against backend
-	ctx := r.Context()
-	result, err := h.backend.Search(ctx, backendParams)
+	page, err := h.fetchPage(ctx, backendParams, plan, searchReq.Limit, currentCursor)
 	if err != nil {
 		h.logger.Error("backend search failed",
 			slog.String("backend", h.backend.Name()),
@@ -453,35 +553,24 @@ func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, translate.ErrCollectionNotFound) {
 			WriteNotFound(w, "one or more collections not found")
 		} else {
-			WriteUpstreamError(w, "upstream search service error")
+			WriteUpstreamFailure(w, err, "upstream search service error")
 		}
 		return
 	}
 
-	// Track if backend returned a full page (used for pagination decision)
-	backendReturnedFullPage := len(result.Items) >= backendLimit
-
-	// Build STAC ItemCollection from backend results
-	itemCollection := intstac.NewItemCollection(result.Items)
-
-	// Filter out items that were already returned in the previous page (for ASF backend)
-	if !h.backend.SupportsPagination() && currentCursor != nil {
-		itemCollection.Features = intstac.FilterSeenItems(itemCollection.Features, func(item *intstac.Item) string {
-			return item.Id
-		}, currentCursor)
-	}
+	// Apply the secondary sort keys within the page
+	intstac.SortItems(page.Features, searchReq.Sortby)
 
-	// Trim to requested limit if we over-fetched
-	if len(itemCollection.Features) > searchReq.Limit {
-		itemCollection.Features = itemCollection.Features[:searchReq.Limit]
-	}
+	// Build STAC ItemCollection from the filtered page
+	h.rewriteAssetHrefs(page.Features...)
+	itemCollection := intstac.NewItemCollection(page.Features)
 
 	// Set context with pagination metadata
 	// For ASF backend: only report TotalCount on first page - subsequent pages have
 	// modified queries that return remaining count, not total count
 	// For CMR backend: always report TotalCount since it uses native pagination
 	limit := searchReq.Limit
-	totalCount := result.TotalCount
+	totalCount := page.TotalCount
 	if !h.backend.SupportsPagination() && currentCursor != nil {
 		totalCount = nil // Don't report inaccurate count on paginated ASF requests
 	}
@@ -504,26 +593,48 @@ func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build pagination links based on backend type
-	if h.backend.SupportsPagination() && result.NextCursor != "" {
-		// CMR-style: use the cursor from the backend directly
-		nextURL := buildNextURLWithCursor(searchURL, queryParams, result.NextCursor, searchReq.Limit)
-		itemCollection.Links = append(itemCollection.Links, &stac.Link{
-			Rel:  "next",
-			Href: nextURL,
-			Type: "application/geo+json",
-		})
-	} else if !h.backend.SupportsPagination() && len(itemCollection.Features) > 0 {
-		// ASF-style: build cursor from item timestamps
-		items := extractItemTimeInfos(itemCollection.Features)
+	if h.backend.SupportsPagination() && page.NextCursor != "" && page.BackendHasMoreData {
+		// CMR-style: wrap the backend's search-after token in a proxy cursor
+		nextURL, err := h.cursorNextURL(searchURL, queryParams, &intstac.Cursor{
+			Direction:   "next",
+			SearchAfter: page.NextCursor,
+		}, searchReq.Limit, fingerprint)
+		if err != nil {
+			h.logger.Error("failed to encode cursor", slog.String("error", err.Error()))
+		} else {
+			itemCollection.Links = append(itemCollection.Links, &stac.Link{
+				Rel:  "next",
+				Href: nextURL,
+				Type: "application/geo+json",
+			})
+		}
+	} else if page.Resume != nil {
+		// The scan stopped between time windows before filling the page
+		nextURL, err := h.cursorNextURL(searchURL, queryParams, page.Resume, searchReq.Limit, fingerprint)
+		if err != nil {
+			h.logger.Error("failed to encode cursor", slog.String("error", err.Error()))
+		} else {
+			itemCollection.Links = append(itemCollection.Links, &stac.Link{
+				Rel:  "next",
+				Href: nextURL,
+				Type: "application/geo+json",
+			})
+		}
+	} else if !h.backend.SupportsPagination() && len(page.Consumed) > 0 {
+		// ASF-style: build cursor from the timestamps of the items this page covers
+		items := extractItemTimeInfos(page.Consumed)
 		paginationInfo := intstac.CursorPaginationInfo{
 			BaseURL:            searchURL,
 			Limit:              searchReq.Limit,
 			ReturnedCount:      len(itemCollection.Features),
-			BackendHasMoreData: backendReturnedFullPage,
+			BackendHasMoreData: page.BackendHasMoreData,
 			QueryParams:        queryParams,
 			Items:              items,
 			CurrentCursor:      currentCursor,
 			CursorStore:        h.cursorStore,
+			Keyring:            h.keyring,
+			Query:              fingerprint,
+			Ascending:          intstac.SortsAscending(searchReq.Sortby),
 		}
 		paginationLinks := intstac.BuildCursorPaginationLinks(paginationInfo)
 		for _, link := range paginationLinks {
@@ -531,15 +642,35 @@ func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	WriteGeoJSON(w, http.StatusOK, itemCollection)
+	h.metrics.ObservePage("search", len(page.Features))
+	span.SetAttribute("page.items", len(page.Features))
+
+	// Reduce the items to the requested fields
+	response, err := itemCollection.WithFields(searchReq.Fields)
+	if err != nil {
+		h.logger.Error("failed to apply fields", slog.String("error", err.Error()))
+		WriteInternalError(w, "failed to apply fields")
+		return
+	}
+
+	_, encodeSpan := tracing.Start(ctx, "encode response")
+	WriteGeoJSON(w, http.StatusOK, response)
+	encodeSpan.End()
 }
 
 // Health returns the health status of the service.
 // GET /health
 func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 	// TODO: Could add ASF API connectivity check here
-	response := map[string]string{
-		"status": "ok",
+	response := map[string]any{
+		"status":  "ok",
+		"backend": h.backend.Name(),
+	}
+	if reporter, ok := h.backend.(backend.StatusReporter); ok {
+		response["backend_status"] = reporter.Status()
+	}
+	if h.cache != nil {
+		response["cache"] = h.cache.Stats()
 	}
 
 	WriteJSON(w, http.StatusOK, response)
@@ -610,6 +741,16 @@ func (h *Handlers) buildSTACCollection(cfg *config.CollectionConfig, baseURL str
 			Type:  "application/geo+json",
 			Title: "Items",
 		},
+		&stac.Link{
+			Rel:  "aggregate",
+			Href: fmt.Sprintf("%s/collections/%s/aggregate", baseURL, cfg.ID),
+			Type: "application/json",
+		},
+		&stac.Link{
+			Rel:  "aggregations",
+			Href: fmt.Sprintf("%s/collections/%s/aggregations", baseURL, cfg.ID),
+			Type: "application/json",
+		},
 	)
 
 	return collection
@@ -667,7 +808,9 @@ func extractItemTimeInfos(features []*intstac.Item) []intstac.ItemTimeInfo {
 }
 
 // buildBackendParams converts a STAC SearchRequest to backend.SearchParams.
-func (h *Handlers) buildBackendParams(req *intstac.SearchRequest, collectionID string) *backend.SearchParams {
+// The CQL2 filter is split by planFilter: predicates the backend can apply
+// exactly are set on the params, and the returned plan holds the rest.
+func (h *Handlers) buildBackendParams(req *intstac.SearchRequest, collectionID string) (*backend.SearchParams, *filterPlan, error) {
 	params := &backend.SearchParams{
 		Limit: req.Limit,
 	}
@@ -699,173 +842,224 @@ func (h *Handlers) buildBackendParams(req *intstac.SearchRequest, collectionID s
 		}
 	}
 
-	// Handle cursor - for CMR backend, pass it directly
-	// For ASF backend, the cursor is decoded and used to modify End time
+	// Plan the CQL2 filter: push down what the backend can express exactly.
+	// This runs before the cursor narrows the time range, so temporal
+	// predicates see only the range the client asked for.
+	plan, err := planFilter(req.Filter, backend.CapabilitiesOf(h.backend), h.collections, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if plan.hasResidual() {
+		h.logger.Debug("evaluating filter predicates in proxy",
+			slog.Any("residual", plan.residualExpr))
+	}
+
+	// Handle cursor - for CMR backend, pass the wrapped search-after token
+	// For ASF backend, the cursor is decoded and used to modify End time, or
+	// Start time when results are ordered oldest first
 	if req.Cursor != "" {
-		if h.backend.SupportsPagination() {
-			params.Cursor = req.Cursor
-		} else {
-			// ASF backend: decode cursor and apply to datetime
-			cursor, err := intstac.DecodeCursorWithStore(req.Cursor, h.cursorStore)
-			if err == nil && cursor != nil {
+		cursor, err := h.decodeCursor(req, collectionID)
+		if err == nil && cursor != nil {
+			if h.backend.SupportsPagination() {
+				params.Cursor = cursor.SearchAfter
+			} else if cursor.Ascending {
+				params.Start = intstac.ApplyCursorToStart(cursor, params.Start)
+			} else {
+				// ASF backend: apply the cursor to datetime
 				params.End = intstac.ApplyCursorToDatetime(cursor, params.End)
 			}
 		}
 	}
 
-	// Set sort
+	// Set sort. Only the first key is sent upstream; the others are applied
+	// within each page by the proxy
 	if len(req.Sortby) > 0 {
 		params.SortField = req.Sortby[0].Field
 		params.SortDirection = req.Sortby[0].Direction
 	}
 
-	// Parse CQL2 filter for SAR-specific parameters
-	if req.Filter != nil {
-		h.extractFilterParams(req.Filter, params)
-	}
-
-	return params
+	return params, plan, nil
 }
 
-// extractFilterParams extracts SAR-specific parameters from CQL2 filter using go-ogc library.
-func (h *Handlers) extractFilterParams(filterData interface{}, params *backend.SearchParams) {
-	// Marshal the filter back to JSON so we can use go-ogc to parse it
-	filterJSON, err := json.Marshal(filterData)
-	if err != nil {
-		h.logger.Debug("extractFilterParams: failed to marshal filter", slog.String("error", err.Error()))
+// setBackendLimit sets the number of items requested from the backend per batch.
+// A paginating backend is asked for exactly limit items. With a residual
+// filter, full batches are fetched since items will be discarded. Otherwise,
+// with a cursor on a non-paginating backend, it over-fetches to compensate
+// for items removed by SeenIDs filtering.
+func (h *Handlers) setBackendLimit(params *backend.SearchParams, plan *filterPlan, limit int, currentCursor *intstac.Cursor) {
+	backendLimit := limit
+	if h.backend.SupportsPagination() {
+		// Native cursors resume after the last item fetched, so every item
+		// in a batch must fit on this page
+		params.Limit = backendLimit
 		return
 	}
-
-	// Parse using go-ogc filter library
-	var f filter.Filter
-	if err := json.Unmarshal(filterJSON, &f); err != nil {
-		h.logger.Debug("extractFilterParams: failed to parse CQL2 filter", slog.String("error", err.Error()))
-		return
-	}
-
-	if f.Expression == nil {
-		h.logger.Debug("extractFilterParams: filter has no expression")
-		return
+	if plan.hasResidual() {
+		backendLimit = h.cfg.Features.MaxLimit
+	} else if currentCursor != nil && len(currentCursor.SeenIDs) > 0 {
+		backendLimit = limit + len(currentCursor.SeenIDs)
+		if backendLimit > h.cfg.Features.MaxLimit {
+			backendLimit = h.cfg.Features.MaxLimit
+		}
 	}
+	params.Limit = backendLimit
+}
 
-	// Extract property values from the parsed filter
-	propertyValues := make(map[string][]string)
-	extractPropertiesFromExpression(f.Expression, propertyValues)
+// maxResidualScanBatches bounds the upstream requests made to fill one page
+// when part of the filter is evaluated in the proxy.
+const maxResidualScanBatches = 10
+
+// searchPage is one page of search results after cursor and residual filtering.
+type searchPage struct {
+	// Features are the matching items, at most the requested limit
+	Features []*intstac.Item
+	// Consumed are the upstream items this page covers, matching or not.
+	// The next cursor continues after them.
+	Consumed []*intstac.Item
+	// BackendHasMoreData is true when the last upstream batch was full
+	BackendHasMoreData bool
+	// TotalCount is the upstream match count; nil when unknown or inexact
+	TotalCount *int
+	// NextCursor is the backend's native pagination cursor, if any
+	NextCursor string
+	// Resume, when set, is the cursor for the next page instead of one
+	// derived from Consumed
+	Resume *intstac.Cursor
+}
 
-	h.logger.Debug("extractFilterParams: extracted properties", slog.Any("properties", propertyValues))
+// fetchPage searches the backend and fills a page of up to limit items.
+// Items returned on the previous page are dropped and the residual filter is
+// applied. When the residual rejects items on a non-paginating backend, the
+// time window is advanced past the consumed items and further batches are
+// fetched until the page is full, the backend has no more data, or
+// maxResidualScanBatches is reached. On a paginating backend the next batch
+// instead continues from the backend's cursor. Ascending order on a backend
+// that cannot sort ascending is handled by scanAscending.
+func (h *Handlers) fetchPage(ctx context.Context, params *backend.SearchParams, plan *filterPlan, limit int, currentCursor *intstac.Cursor) (*searchPage, error) {
+	ascending := params.SortDirection == string(intstac.SortAsc)
+	if ascending && !h.backend.SupportsPagination() && !backend.SupportsAscendingSort(h.backend) {
+		return h.scanAscending(ctx, params, plan, limit, currentCursor)
+	}
+
+	page := &searchPage{Features: []*intstac.Item{}}
+	cursor := currentCursor
+
+	for batch := 0; ; batch++ {
+		result, err := h.backend.Search(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if batch == 0 {
+			page.TotalCount = result.TotalCount
+		}
+		page.NextCursor = result.NextCursor
+		page.BackendHasMoreData = len(result.Items) >= params.Limit
+
+		// Filter out items that were already returned in the previous page (for ASF backend)
+		items := result.Items
+		if !h.backend.SupportsPagination() && cursor != nil {
+			items = intstac.FilterSeenItems(items, func(item *intstac.Item) string {
+				return item.Id
+			}, cursor)
+		}
 
-	// Map extracted properties to backend params
-	if vals, ok := propertyValues["sar:polarizations"]; ok {
-		params.Polarization = vals
-	}
-	if vals, ok := propertyValues["sar:instrument_mode"]; ok {
-		params.BeamMode = vals
-	}
-	if vals, ok := propertyValues["sat:orbit_state"]; ok && len(vals) > 0 {
-		params.FlightDirection = vals[0]
-	}
-	if vals, ok := propertyValues["processing:level"]; ok {
-		params.ProcessingLevel = vals
-	}
-	// sar:product_type maps to processing level (e.g., SLC, GRD, RAW)
-	if vals, ok := propertyValues["sar:product_type"]; ok {
-		params.ProcessingLevel = vals
-	}
-	// platform filter (e.g., "sentinel-1a" -> "Sentinel-1A")
-	if vals, ok := propertyValues["platform"]; ok {
-		normalized := make([]string, len(vals))
-		for i, v := range vals {
-			normalized[i] = normalizePlatformForASF(v)
+		for _, item := range items {
+			if len(page.Features) >= limit {
+				break
+			}
+			page.Consumed = append(page.Consumed, item)
+			if plan.matches(item) {
+				page.Features = append(page.Features, item)
+			}
 		}
-		params.Platform = normalized
-	}
 
-	h.logger.Debug("extractFilterParams: final params",
-		slog.Any("processingLevel", params.ProcessingLevel),
-		slog.Any("platform", params.Platform))
-}
+		if !plan.hasResidual() || len(page.Features) >= limit ||
+			!page.BackendHasMoreData || batch+1 >= maxResidualScanBatches {
+			break
+		}
 
-// extractPropertiesFromExpression recursively extracts property-value pairs from a CQL2 expression.
-func extractPropertiesFromExpression(expr filter.Expression, result map[string][]string) {
-	switch e := expr.(type) {
-	case *filter.And:
-		for _, arg := range e.Args {
-			extractPropertiesFromExpression(arg, result)
-		}
-	case *filter.Or:
-		for _, arg := range e.Args {
-			extractPropertiesFromExpression(arg, result)
-		}
-	case *filter.Not:
-		extractPropertiesFromExpression(e.Arg, result)
-	case *filter.Comparison:
-		// Handle equality comparisons: property = value
-		if e.Name == filter.Equals || e.Name == "eq" {
-			propName := getPropertyName(e.Left)
-			if propName != "" {
-				if val := getStringValue(e.Right); val != "" {
-					result[propName] = append(result[propName], val)
-				}
+		if h.backend.SupportsPagination() {
+			// Continue after the last item fetched, asking only for the
+			// items still missing so that none are skipped
+			if page.NextCursor == "" {
+				break
 			}
+			params.Cursor = page.NextCursor
+			params.Limit = limit - len(page.Features)
+			continue
 		}
-	case *filter.In:
-		// Handle "in" operator: property IN [value1, value2, ...]
-		propName := getPropertyName(e.Item)
-		if propName != "" {
-			for _, item := range e.List {
-				if val := getStringValue(item); val != "" {
-					result[propName] = append(result[propName], val)
-				}
-			}
+
+		// Continue below the oldest item consumed so far, or above the
+		// newest when ascending
+		next := intstac.NextCursorInOrder(extractItemTimeInfos(page.Consumed), cursor, ascending)
+		if next == nil {
+			break
+		}
+		if ascending {
+			params.Start = intstac.ApplyCursorToStart(next, params.Start)
+		} else {
+			params.End = intstac.ApplyCursorToDatetime(next, params.End)
 		}
+		cursor = next
 	}
-}
 
-// getPropertyName extracts the property name from a scalar expression.
-func getPropertyName(expr filter.ScalarExpression) string {
-	if prop, ok := expr.(*filter.Property); ok {
-		return prop.Name
+	// The upstream count includes items rejected by the residual filter
+	if plan.hasResidual() {
+		page.TotalCount = nil
 	}
-	return ""
-}
 
-// getStringValue extracts a string value from a scalar expression.
-func getStringValue(expr filter.ScalarExpression) string {
-	if str, ok := expr.(*filter.String); ok {
-		return str.Value
-	}
-	return ""
+	return page, nil
 }
 
 // normalizePlatformForASF converts STAC lowercase platform names to ASF format.
 // STAC items display lowercase platforms (e.g., "sentinel-1a"), but ASF API
-// expects specific capitalization (e.g., "Sentinel-1A").
-func normalizePlatformForASF(platform string) string {
-	lower := strings.ToLower(platform)
-
-	// Known platform mappings (STAC lowercase -> ASF format)
-	platformMap := map[string]string{
-		"sentinel-1a": "Sentinel-1A",
-		"sentinel-1b": "Sentinel-1B",
-		"sentinel-1c": "Sentinel-1C",
-		"alos":        "ALOS",
-		"ers-1":       "ERS-1",
-		"ers-2":       "ERS-2",
-		"jers-1":      "JERS-1",
-		"radarsat-1":  "RADARSAT-1",
-		"seasat":      "SEASAT",
-		"uavsar":      "UAVSAR",
-		"airsar":      "AIRSAR",
-	}
-
-	if asfName, ok := platformMap[lower]; ok {
-		return asfName
-	}
-
-	// If not in map, return original value
+// expects the names the collections define for them (e.g., "Sentinel-1A").
+func normalizePlatformForASF(platform string, collections *config.CollectionRegistry) string {
+	if p := collections.Platform("", platform); p != nil {
+		for _, name := range p.Names {
+			if strings.EqualFold(name, platform) {
+				return name
+			}
+		}
+	}
+
+	// If no collection defines the platform, return original value
 	return platform
 }
 
+// decodeCursor decodes the pagination cursor of a search and checks that it
+// was issued for the same search, for the backend's pagination mode, a native
+// search-after token or a time window, and for a time window that it walks in
+// the requested order.
+func (h *Handlers) decodeCursor(req *intstac.SearchRequest, collectionID string) (*intstac.Cursor, error) {
+	cursor, err := intstac.DecodeCursorSealed(req.Cursor, h.keyring, h.cursorStore)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Query != "" && cursor.Query != req.Fingerprint(collectionID) {
+		return nil, fmt.Errorf("cursor was issued for a different query")
+	}
+	ascending := intstac.SortsAscending(req.Sortby)
+	if (cursor.SearchAfter != "") != h.backend.SupportsPagination() {
+		return nil, fmt.Errorf("cursor was issued for a different pagination mode")
+	}
+	if cursor.SearchAfter == "" && cursor.Ascending != ascending {
+		return nil, fmt.Errorf("cursor was issued for a different sort order")
+	}
+	return cursor, nil
+}
+
+// cursorNextURL builds a next link carrying the given cursor, bound to the
+// search with the given fingerprint.
+func (h *Handlers) cursorNextURL(baseURL string, params url.Values, cursor *intstac.Cursor, limit int, query string) (string, error) {
+	cursor.Query = query
+	encoded, err := intstac.EncodeCursorSealed(cursor, h.keyring, h.cursorStore)
+	if err != nil {
+		return "", err
+	}
+	return buildNextURLWithCursor(baseURL, params, encoded, limit), nil
+}
+
 // buildNextURLWithCursor constructs a URL with a cursor parameter for pagination.
 func buildNextURLWithCursor(baseURL string, params url.Values, cursor string, limit int) string {
 	newParams := url.Values{}
@@ -3,7 +3,9 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -17,8 +19,11 @@ import (
 	gostac "github.com/planetlabs/go-stac"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/translate"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
 )
 
 // mockBackend is a test backend that returns configurable results
@@ -107,6 +112,7 @@ func createTestCollections() *config.CollectionRegistry {
 		Description: "Test collection",
 		License:     "proprietary",
 		ASFDatasets: []string{"SENTINEL-1"},
+		Platforms:   []config.PlatformConfig{{Names: []string{"Sentinel-1A", "Sentinel-1B", "Sentinel-1C"}}},
 		Extent: config.Extent{
 			Spatial: config.SpatialExtent{
 				BBox: [][]float64{{-180, -90, 180, 90}},
@@ -787,6 +793,10 @@ func TestHandlers_Search_PlatformFilterInOperator(t *testing.T) {
 
 func TestNormalizePlatformForASF(t *testing.T) {
 	// Unit test for the normalizePlatformForASF helper function
+	collections, err := config.LoadCollections("../../collections")
+	if err != nil {
+		t.Fatalf("LoadCollections error: %v", err)
+	}
 
 	tests := []struct {
 		input    string
@@ -803,17 +813,42 @@ func TestNormalizePlatformForASF(t *testing.T) {
 		{"ers-2", "ERS-2"},
 		{"radarsat-1", "RADARSAT-1"},
 		{"uavsar", "UAVSAR"},
+		{"nisar", "NISAR"},
+		{"sir-c", "SIR-C"},
+		{"seasat 1", "SEASAT 1"},
 		{"unknown-platform", "unknown-platform"}, // Unknown platforms pass through unchanged
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.input, func(t *testing.T) {
-			result := normalizePlatformForASF(tt.input)
+			result := normalizePlatformForASF(tt.input, collections)
 			if result != tt.expected {
 				t.Errorf("normalizePlatformForASF(%q) = %q, want %q", tt.input, result, tt.expected)
 			}
 		})
 	}
+
+	// The Sentinel-1 platforms of OPERA products are those of Sentinel-1
+	// itself, so only the OPERA collections may define them
+	opera := config.NewCollectionRegistry()
+	for _, id := range []string{"opera-rtc-s1", "opera-cslc-s1", "opera-disp-s1"} {
+		if err := opera.Add(collections.Get(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for input, expected := range map[string]string{
+		"sentinel-1a": "Sentinel-1A",
+		"sentinel-1b": "Sentinel-1B",
+		"sentinel-1c": "Sentinel-1C",
+	} {
+		if result := normalizePlatformForASF(input, opera); result != expected {
+			t.Errorf("normalizePlatformForASF(%q) with OPERA collections = %q, want %q", input, result, expected)
+		}
+	}
+
+	if got := normalizePlatformForASF("sentinel-1a", nil); got != "sentinel-1a" {
+		t.Errorf("expected platforms passed through without collections, got %q", got)
+	}
 }
 
 func TestHandlers_Items_OverFetchCappedAtMaxLimit(t *testing.T) {
@@ -875,3 +910,1358 @@ func TestHandlers_Items_OverFetchCappedAtMaxLimit(t *testing.T) {
 		t.Errorf("Expected backend limit to be capped at MaxLimit (250), got %d", mock.searchCalls[0].Limit)
 	}
 }
+
+func TestHandlers_Search_CQL2TextFilter(t *testing.T) {
+	// Test that CQL2-Text filters on GET produce the same backend params as the JSON form
+
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	items := []*gostac.Item{createTestItem("item-000", baseTime)}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	textFilter := "sar:instrument_mode='IW' AND sat:orbit_state='ascending' AND platform IN ('sentinel-1a', 'sentinel-1b')"
+	jsonFilter := `{"op":"and","args":[
+		{"op":"=","args":[{"property":"sar:instrument_mode"},"IW"]},
+		{"op":"=","args":[{"property":"sat:orbit_state"},"ascending"]},
+		{"op":"in","args":[{"property":"platform"},["sentinel-1a","sentinel-1b"]]}]}`
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"explicit filter-lang", "filter=" + url.QueryEscape(textFilter) + "&filter-lang=cql2-text"},
+		{"auto-detected", "filter=" + url.QueryEscape(textFilter)},
+		{"json form", "filter=" + url.QueryEscape(jsonFilter) + "&filter-lang=cql2-json"},
+	}
+
+	var calls []backend.SearchParams
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockBackend{items: items}
+			handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+			req := httptest.NewRequest("GET", "/search?collections=sentinel-1&"+tc.query, nil)
+			w := httptest.NewRecorder()
+			handlers.Search(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if len(mock.searchCalls) != 1 {
+				t.Fatalf("Expected 1 search call, got %d", len(mock.searchCalls))
+			}
+
+			call := mock.searchCalls[0]
+			if len(call.BeamMode) != 1 || call.BeamMode[0] != "IW" {
+				t.Errorf("Expected BeamMode [IW], got %v", call.BeamMode)
+			}
+			if call.FlightDirection != "ascending" {
+				t.Errorf("Expected FlightDirection ascending, got %q", call.FlightDirection)
+			}
+			if len(call.Platform) != 2 {
+				t.Errorf("Expected 2 platforms, got %v", call.Platform)
+			}
+			calls = append(calls, call)
+		})
+	}
+
+	// All encodings must yield identical backend parameters
+	for i := 1; i < len(calls); i++ {
+		if fmt.Sprint(calls[i]) != fmt.Sprint(calls[0]) {
+			t.Errorf("Backend params differ between encodings:\n%+v\n%+v", calls[0], calls[i])
+		}
+	}
+}
+
+func TestHandlers_Items_CQL2TextFilter(t *testing.T) {
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	mock := &mockBackend{items: []*gostac.Item{createTestItem("item-000", baseTime)}}
+
+	cfg := createTestConfig()
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+	handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+	r := chi.NewRouter()
+	r.Get("/collections/{collectionId}/items", handlers.Items)
+
+	filter := url.QueryEscape("sar:polarizations = 'VV+VH'")
+	req := httptest.NewRequest("GET", "/collections/sentinel-1/items?filter-lang=cql2-text&filter="+filter, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(mock.searchCalls) != 1 {
+		t.Fatalf("Expected 1 search call, got %d", len(mock.searchCalls))
+	}
+	if pol := mock.searchCalls[0].Polarization; len(pol) != 1 || pol[0] != "VV+VH" {
+		t.Errorf("Expected Polarization [VV+VH], got %v", pol)
+	}
+}
+
+func TestHandlers_Search_InvalidFilterRejected(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{
+			name:   "cql2-text syntax error",
+			method: "GET",
+			target: "/search?filter=" + url.QueryEscape("sar:instrument_mode = 'IW' AND") + "&filter-lang=cql2-text",
+		},
+		{
+			name:   "auto-detected cql2-text syntax error",
+			method: "GET",
+			target: "/search?filter=" + url.QueryEscape("platform sentinel-1a"),
+		},
+		{
+			name:   "malformed cql2-json",
+			method: "GET",
+			target: "/search?filter=" + url.QueryEscape(`{"op":"="`) + "&filter-lang=cql2-json",
+		},
+		{
+			name:   "unsupported filter-lang",
+			method: "GET",
+			target: "/search?filter=" + url.QueryEscape("platform = 'x'") + "&filter-lang=ecql",
+		},
+		{
+			name:   "cql2-text syntax error in POST body",
+			method: "POST",
+			target: "/search",
+			body:   `{"filter": "platform = ", "filter-lang": "cql2-text"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockBackend{}
+			handlers := NewHandlers(cfg, mock, nil, collections, logger)
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			handlers.Search(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var errResp STACError
+			if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("Failed to parse error response: %v", err)
+			}
+			if errResp.Code != ErrCodeInvalidParameter {
+				t.Errorf("Expected code %s, got %s", ErrCodeInvalidParameter, errResp.Code)
+			}
+			if len(mock.searchCalls) != 0 {
+				t.Error("Expected no backend call for an invalid filter")
+			}
+		})
+	}
+}
+
+func TestHandlers_Search_CQL2TextFilterInPOSTBody(t *testing.T) {
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	items := make([]*gostac.Item, 5)
+	for i := range items {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", i), baseTime.Add(-time.Duration(i)*time.Hour))
+	}
+	mock := &mockBackend{items: items}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+	cursorStore := stac.NewMemoryCursorStore(time.Hour, 5*time.Minute)
+	defer cursorStore.Stop()
+	handlers := NewHandlers(cfg, mock, translator, collections, logger).WithCursorStore(cursorStore)
+
+	body := `{"collections": ["sentinel-1"], "limit": 5, "filter": "sar:product_type = 'SLC'", "filter-lang": "cql2-text"}`
+	req := httptest.NewRequest("POST", "/search", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handlers.Search(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if pl := mock.searchCalls[0].ProcessingLevel; len(pl) != 1 || pl[0] != "SLC" {
+		t.Errorf("Expected ProcessingLevel [SLC], got %v", pl)
+	}
+
+	// The next link carries the filter in its normalized CQL2-JSON form
+	var result stac.ItemCollection
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	var nextHref string
+	for _, link := range result.Links {
+		if link.Rel == "next" {
+			nextHref = link.Href
+		}
+	}
+	if nextHref == "" {
+		t.Fatal("Expected next link")
+	}
+	nextURL, err := url.Parse(nextHref)
+	if err != nil {
+		t.Fatalf("Failed to parse next link: %v", err)
+	}
+	if lang := nextURL.Query().Get("filter-lang"); lang != "cql2-json" {
+		t.Errorf("Expected filter-lang cql2-json in next link, got %q", lang)
+	}
+	var filterObj map[string]interface{}
+	if err := json.Unmarshal([]byte(nextURL.Query().Get("filter")), &filterObj); err != nil {
+		t.Errorf("Expected JSON filter in next link, got %q", nextURL.Query().Get("filter"))
+	}
+}
+
+// timeWindowBackend is a test backend that behaves like ASF: results are
+// sorted newest first and limited to start times in [params.Start, params.End).
+type timeWindowBackend struct {
+	mockBackend
+}
+
+func (m *timeWindowBackend) Search(ctx context.Context, params *backend.SearchParams) (*backend.SearchResult, error) {
+	m.searchCalls = append(m.searchCalls, *params)
+
+	var items []*gostac.Item
+	for _, item := range m.items {
+		start, _ := time.Parse(time.RFC3339, item.Properties["start_datetime"].(string))
+		if params.End != nil && !start.Before(*params.End) {
+			continue
+		}
+		if params.Start != nil && start.Before(*params.Start) {
+			continue
+		}
+		items = append(items, item)
+		if len(items) >= params.Limit {
+			break
+		}
+	}
+
+	return &backend.SearchResult{Items: items}, nil
+}
+
+func TestHandlers_Search_ResidualFilterFillsPages(t *testing.T) {
+	// Test that a filter evaluated in the proxy still returns full pages and
+	// that following next links visits every matching item exactly once
+
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	items := make([]*gostac.Item, 30)
+	for i := 0; i < 30; i++ {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", i), baseTime.Add(-time.Duration(i)*time.Minute))
+		if i%2 == 0 {
+			items[i].Properties["platform"] = "sentinel-1a"
+		} else {
+			items[i].Properties["platform"] = "sentinel-1b"
+		}
+	}
+
+	mock := &timeWindowBackend{mockBackend{items: items}}
+
+	cfg := createTestConfig()
+	cfg.Features.MaxLimit = 6
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+	cursorStore := stac.NewMemoryCursorStore(time.Hour, 5*time.Minute)
+	defer cursorStore.Stop()
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger).WithCursorStore(cursorStore)
+
+	target := "/search?collections=sentinel-1&limit=5&filter-lang=cql2-text&filter=" +
+		url.QueryEscape("NOT (platform = 'sentinel-1a')")
+
+	seen := make(map[string]bool)
+	for page := 0; target != ""; page++ {
+		if page > 10 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		handlers.Search(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+
+		if _, ok := response["numberMatched"]; ok {
+			t.Error("Expected numberMatched to be omitted when the filter is evaluated in the proxy")
+		}
+
+		features := response["features"].([]interface{})
+		if len(seen) < 15 && len(features) != 5 {
+			t.Errorf("Page %d: expected a full page of 5 items, got %d", page, len(features))
+		}
+		for _, f := range features {
+			feature := f.(map[string]interface{})
+			id := feature["id"].(string)
+			props := feature["properties"].(map[string]interface{})
+			if props["platform"] == "sentinel-1a" {
+				t.Errorf("Page %d: item %s should have been filtered out", page, id)
+			}
+			if seen[id] {
+				t.Errorf("Page %d: item %s returned twice", page, id)
+			}
+			seen[id] = true
+		}
+
+		target = ""
+		for _, l := range response["links"].([]interface{}) {
+			link := l.(map[string]interface{})
+			if link["rel"] == "next" {
+				u, err := url.Parse(link["href"].(string))
+				if err != nil {
+					t.Fatalf("Invalid next link: %v", err)
+				}
+				target = "/search?" + u.RawQuery
+			}
+		}
+	}
+
+	if len(seen) != 15 {
+		t.Errorf("Expected 15 matching items across all pages, got %d", len(seen))
+	}
+	for _, call := range mock.searchCalls {
+		if call.Limit > cfg.Features.MaxLimit {
+			t.Errorf("Backend limit %d exceeds MaxLimit", call.Limit)
+		}
+		if len(call.Platform) != 0 {
+			t.Errorf("Expected platform NOT to be pushed down, got %v", call.Platform)
+		}
+	}
+}
+
+func TestHandlers_Search_SpatialFilterCoveringAOI(t *testing.T) {
+	// Test that s_contains returns only scenes whose footprint fully covers
+	// the area of interest, and that the envelope is pushed to the backend
+
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	footprints := map[string]string{
+		"covering":    `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}`,
+		"partial":     `{"type":"Polygon","coordinates":[[[4,0],[10,0],[10,10],[4,10],[4,0]]]}`,
+		"elsewhere":   `{"type":"Polygon","coordinates":[[[20,20],[30,20],[30,30],[20,30],[20,20]]]}`,
+		"also-covers": `{"type":"Polygon","coordinates":[[[1,1],[9,1],[9,9],[1,9],[1,1]]]}`,
+	}
+
+	var items []*gostac.Item
+	for _, id := range []string{"covering", "partial", "elsewhere", "also-covers"} {
+		item := createTestItem(id, baseTime)
+		item.Geometry = json.RawMessage(footprints[id])
+		items = append(items, item)
+	}
+
+	mock := &mockBackend{items: items}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+	cursorStore := stac.NewMemoryCursorStore(time.Hour, 5*time.Minute)
+	defer cursorStore.Stop()
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger).WithCursorStore(cursorStore)
+
+	body := `{
+		"collections": ["sentinel-1"],
+		"filter-lang": "cql2-text",
+		"filter": "S_CONTAINS(geometry, POLYGON((2 2, 3 2, 3 3, 2 3, 2 2)))"
+	}`
+	req := httptest.NewRequest("POST", "/search", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handlers.Search(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	var ids []string
+	for _, f := range response["features"].([]interface{}) {
+		ids = append(ids, f.(map[string]interface{})["id"].(string))
+	}
+	if strings.Join(ids, ",") != "covering,also-covers" {
+		t.Errorf("Expected covering,also-covers, got %v", ids)
+	}
+
+	if len(mock.searchCalls) == 0 {
+		t.Fatal("Expected a backend search call")
+	}
+	if bbox := mock.searchCalls[0].BBox; fmt.Sprint(bbox) != "[2 2 3 3]" {
+		t.Errorf("Expected AOI envelope [2 2 3 3] pushed as bbox, got %v", bbox)
+	}
+}
+
+func TestHandlers_Search_TemporalFilterDuring(t *testing.T) {
+	// Test that t_during selects acquisitions entirely inside the interval and
+	// that the interval narrows the backend time range
+
+	// createTestItem gives each item a one hour acquisition
+	items := []*gostac.Item{
+		createTestItem("inside", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)),
+		createTestItem("crosses-end", time.Date(2024, 1, 15, 23, 30, 0, 0, time.UTC)),
+		createTestItem("crosses-start", time.Date(2024, 1, 14, 23, 30, 0, 0, time.UTC)),
+	}
+	mock := &mockBackend{items: items}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+	cursorStore := stac.NewMemoryCursorStore(time.Hour, 5*time.Minute)
+	defer cursorStore.Stop()
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger).WithCursorStore(cursorStore)
+
+	filter := "T_DURING(datetime, INTERVAL('2024-01-15', '2024-01-15'))"
+	req := httptest.NewRequest("GET", "/search?collections=sentinel-1&filter="+url.QueryEscape(filter), nil)
+	w := httptest.NewRecorder()
+	handlers.Search(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	features := response["features"].([]interface{})
+	if len(features) != 1 || features[0].(map[string]interface{})["id"] != "inside" {
+		t.Errorf("Expected only the item acquired during the interval, got %v", features)
+	}
+
+	call := mock.searchCalls[0]
+	if call.Start == nil || !call.Start.Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected backend start 2024-01-15T00:00:00Z, got %v", call.Start)
+	}
+	if call.End == nil || call.End.Day() != 15 {
+		t.Errorf("Expected backend end on 2024-01-15, got %v", call.End)
+	}
+}
+
+// searchAfterBackend is a test backend that behaves like CMR with native
+// pagination: results continue after the offset encoded in params.Cursor.
+type searchAfterBackend struct {
+	mockBackend
+}
+
+func (m *searchAfterBackend) Search(ctx context.Context, params *backend.SearchParams) (*backend.SearchResult, error) {
+	m.searchCalls = append(m.searchCalls, *params)
+
+	offset := 0
+	if params.Cursor != "" {
+		if _, err := fmt.Sscanf(params.Cursor, "after:%d", &offset); err != nil {
+			return nil, fmt.Errorf("invalid search-after token %q", params.Cursor)
+		}
+	}
+	end := offset + params.Limit
+	if end > len(m.items) {
+		end = len(m.items)
+	}
+
+	total := len(m.items)
+	result := &backend.SearchResult{Items: m.items[offset:end], TotalCount: &total}
+	if end < len(m.items) {
+		result.NextCursor = fmt.Sprintf("after:%d", end)
+	}
+	return result, nil
+}
+
+func TestHandlers_Search_NativePagination(t *testing.T) {
+	// Test that a paginating backend's search-after token is wrapped in an
+	// opaque cursor and that the total count is reported on every page
+
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	items := make([]*gostac.Item, 12)
+	for i := range items {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", i), baseTime.Add(-time.Duration(i)*time.Minute))
+	}
+
+	mock := &searchAfterBackend{mockBackend{items: items, supportsPagination: true}}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+	target := "/search?collections=sentinel-1&limit=5"
+	var ids []string
+	for page := 0; target != ""; page++ {
+		if page > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		handlers.Search(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+
+		if matched, ok := response["numberMatched"].(float64); !ok || matched != 12 {
+			t.Errorf("Page %d: expected numberMatched 12, got %v", page, response["numberMatched"])
+		}
+		for _, f := range response["features"].([]interface{}) {
+			ids = append(ids, f.(map[string]interface{})["id"].(string))
+		}
+
+		target = ""
+		for _, l := range response["links"].([]interface{}) {
+			link := l.(map[string]interface{})
+			if link["rel"] != "next" {
+				continue
+			}
+			u, err := url.Parse(link["href"].(string))
+			if err != nil {
+				t.Fatalf("Invalid next link: %v", err)
+			}
+			encoded := u.Query().Get("cursor")
+			if strings.HasPrefix(encoded, "after:") {
+				t.Errorf("Page %d: expected the search-after token to be wrapped, got %q", page, encoded)
+			}
+			cursor, err := stac.DecodeCursor(encoded)
+			if err != nil || cursor == nil || cursor.SearchAfter == "" {
+				t.Fatalf("Page %d: next cursor %q does not carry a search-after token: %v", page, encoded, err)
+			}
+			target = "/search?" + u.RawQuery
+		}
+	}
+
+	if len(ids) != 12 {
+		t.Errorf("Expected 12 items across all pages, got %d", len(ids))
+	}
+	for i, id := range ids {
+		if want := fmt.Sprintf("item-%03d", i); id != want {
+			t.Errorf("Item %d: expected %s, got %s", i, want, id)
+		}
+	}
+
+	wantCursors := []string{"", "after:5", "after:10"}
+	if len(mock.searchCalls) != len(wantCursors) {
+		t.Fatalf("Expected %d backend calls, got %d", len(wantCursors), len(mock.searchCalls))
+	}
+	for i, call := range mock.searchCalls {
+		if call.Cursor != wantCursors[i] {
+			t.Errorf("Call %d: expected backend cursor %q, got %q", i, wantCursors[i], call.Cursor)
+		}
+		if call.End != nil {
+			t.Errorf("Call %d: expected no time window, got end %v", i, call.End)
+		}
+	}
+}
+
+func TestHandlers_Search_NativePaginationResidualFilter(t *testing.T) {
+	// Test that residual filtering on a paginating backend continues from the
+	// backend's token without skipping items
+
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	items := make([]*gostac.Item, 12)
+	for i := range items {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", i), baseTime.Add(-time.Duration(i)*time.Minute))
+		items[i].Properties["platform"] = "sentinel-1a"
+		if i%3 == 0 {
+			items[i].Properties["platform"] = "sentinel-1b"
+		}
+	}
+
+	mock := &searchAfterBackend{mockBackend{items: items, supportsPagination: true}}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+	target := "/search?collections=sentinel-1&limit=3&filter-lang=cql2-text&filter=" +
+		url.QueryEscape("NOT (platform = 'sentinel-1a')")
+
+	var ids []string
+	for page := 0; target != ""; page++ {
+		if page > 10 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		handlers.Search(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		for _, f := range response["features"].([]interface{}) {
+			ids = append(ids, f.(map[string]interface{})["id"].(string))
+		}
+
+		target = ""
+		for _, l := range response["links"].([]interface{}) {
+			link := l.(map[string]interface{})
+			if link["rel"] == "next" {
+				u, err := url.Parse(link["href"].(string))
+				if err != nil {
+					t.Fatalf("Invalid next link: %v", err)
+				}
+				target = "/search?" + u.RawQuery
+			}
+		}
+	}
+
+	want := []string{"item-000", "item-003", "item-006", "item-009"}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected items %v, got %v", want, ids)
+	}
+}
+
+func TestHandlers_Search_CursorPaginationModeMismatch(t *testing.T) {
+	// Test that a cursor issued for one pagination mode is rejected by the other
+
+	timeWindow := stac.EncodeCursor(&stac.Cursor{StartTime: "2024-01-15T12:00:00Z", Direction: "next"})
+	native := stac.EncodeCursor(&stac.Cursor{Direction: "next", SearchAfter: "after:5"})
+
+	tests := []struct {
+		name               string
+		supportsPagination bool
+		cursor             string
+	}{
+		{"time window cursor on paginating backend", true, timeWindow},
+		{"search-after cursor on time window backend", false, native},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockBackend{supportsPagination: tt.supportsPagination}
+
+			cfg := createTestConfig()
+			cfg.Features.EnableSearch = true
+			collections := createTestCollections()
+			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+			translator := translate.NewTranslator(cfg, collections, logger)
+
+			handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+			req := httptest.NewRequest("GET", "/search?collections=sentinel-1&cursor="+url.QueryEscape(tt.cursor), nil)
+			w := httptest.NewRecorder()
+			handlers.Search(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+			if len(mock.searchCalls) != 0 {
+				t.Errorf("Expected no backend calls, got %d", len(mock.searchCalls))
+			}
+		})
+	}
+}
+
+// followPages requests target and each next link in turn, returning the IDs
+// of the items on every page in order
+func followPages(t *testing.T, handler http.HandlerFunc, target string) []string {
+	t.Helper()
+
+	var ids []string
+	for page := 0; target != ""; page++ {
+		if page > 20 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		for _, f := range response["features"].([]interface{}) {
+			ids = append(ids, f.(map[string]interface{})["id"].(string))
+		}
+
+		target = ""
+		for _, l := range response["links"].([]interface{}) {
+			link := l.(map[string]interface{})
+			if link["rel"] == "next" {
+				u, err := url.Parse(link["href"].(string))
+				if err != nil {
+					t.Fatalf("Invalid next link: %v", err)
+				}
+				target = u.Path + "?" + u.RawQuery
+			}
+		}
+	}
+	return ids
+}
+
+func TestHandlers_Search_AscendingSortWalksForward(t *testing.T) {
+	// Test that sortby=+datetime on a backend returning the newest items first
+	// pages through every item oldest first, even when a time window holds
+	// more items than fit in one upstream batch
+
+	baseTime := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	// One item per hour, newest first as ASF returns them
+	items := make([]*gostac.Item, 30)
+	for i := range items {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", 29-i), baseTime.Add(time.Duration(29-i)*time.Hour))
+	}
+
+	mock := &timeWindowBackend{mockBackend{items: items}}
+
+	cfg := createTestConfig()
+	cfg.Features.MaxLimit = 6
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+	ids := followPages(t, handlers.Search,
+		"/search?collections=sentinel-1&limit=5&sortby=%2Bdatetime&datetime=2024-01-15T00:00:00Z/2024-02-01T00:00:00Z")
+
+	if len(ids) != 30 {
+		t.Fatalf("Expected 30 items across all pages, got %d: %v", len(ids), ids)
+	}
+	for i, id := range ids {
+		if want := fmt.Sprintf("item-%03d", i); id != want {
+			t.Errorf("Item %d: expected %s, got %s", i, want, id)
+		}
+	}
+	for _, call := range mock.searchCalls {
+		if call.Limit > cfg.Features.MaxLimit {
+			t.Errorf("Backend limit %d exceeds MaxLimit", call.Limit)
+		}
+	}
+}
+
+func TestHandlers_Search_AscendingSortResumesLongScan(t *testing.T) {
+	// Test that an ascending scan that runs out of upstream requests before
+	// filling the page links to a cursor where the scan stopped
+
+	baseTime := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	items := make([]*gostac.Item, 80)
+	for i := range items {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", 79-i), baseTime.Add(time.Duration(79-i)*time.Hour))
+		items[i].Properties["platform"] = "sentinel-1a"
+	}
+	// Only the newest item matches
+	items[0].Properties["platform"] = "sentinel-1b"
+
+	mock := &timeWindowBackend{mockBackend{items: items}}
+
+	cfg := createTestConfig()
+	cfg.Features.MaxLimit = 6
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+	target := "/search?collections=sentinel-1&limit=5&sortby=%2Bdatetime&datetime=2024-01-15T00:00:00Z/2024-02-01T00:00:00Z" +
+		"&filter-lang=cql2-text&filter=" + url.QueryEscape("NOT (platform = 'sentinel-1a')")
+	ids := followPages(t, handlers.Search, target)
+
+	if len(ids) != 1 || ids[0] != "item-079" {
+		t.Errorf("Expected only item-079, got %v", ids)
+	}
+	if len(mock.searchCalls) <= maxAscendingScanBatches {
+		t.Errorf("Expected the scan to continue on a second page, got %d backend calls", len(mock.searchCalls))
+	}
+}
+
+// inclusiveWindowBackend filters by start time like ASF, whose end bound is
+// inclusive, returning the newest items first
+type inclusiveWindowBackend struct {
+	mockBackend
+}
+
+func (m *inclusiveWindowBackend) Search(ctx context.Context, params *backend.SearchParams) (*backend.SearchResult, error) {
+	m.searchCalls = append(m.searchCalls, *params)
+
+	var items []*gostac.Item
+	for _, item := range m.items {
+		start, _ := time.Parse(time.RFC3339, item.Properties["start_datetime"].(string))
+		if params.End != nil && start.After(*params.End) {
+			continue
+		}
+		if params.Start != nil && start.Before(*params.Start) {
+			continue
+		}
+		items = append(items, item)
+		if len(items) >= params.Limit {
+			break
+		}
+	}
+
+	return &backend.SearchResult{Items: items}, nil
+}
+
+func TestHandlers_Search_AscendingSortWindowBoundary(t *testing.T) {
+	// Test that an item starting exactly where one scan window ends and the
+	// next begins is returned once, although both windows include it
+
+	baseTime := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	items := []*gostac.Item{
+		createTestItem("item-2", baseTime.Add(36*time.Hour)),
+		createTestItem("item-1", baseTime.Add(initialAscendingSpan)),
+		createTestItem("item-0", baseTime.Add(12*time.Hour)),
+	}
+
+	mock := &inclusiveWindowBackend{mockBackend{items: items}}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+	ids := followPages(t, handlers.Search,
+		"/search?collections=sentinel-1&limit=10&sortby=%2Bdatetime&datetime=2024-01-15T00:00:00Z/2024-01-18T00:00:00Z")
+
+	if want := "item-0,item-1,item-2"; strings.Join(ids, ",") != want {
+		t.Errorf("Expected %s, got %v", want, ids)
+	}
+}
+
+func TestHandlers_Search_SecondarySortWithinPage(t *testing.T) {
+	// Test that secondary sort keys order items sharing a start time
+
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	items := make([]*gostac.Item, 4)
+	for i, orbit := range []float64{12, 40, 7, 99} {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", i), baseTime.Add(-time.Duration(i/2)*time.Minute))
+		items[i].Properties["sat:relative_orbit"] = orbit
+	}
+
+	mock := &mockBackend{items: items}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+	ids := followPages(t, handlers.Search, "/search?collections=sentinel-1&sortby=-datetime,-sat:relative_orbit")
+
+	want := []string{"item-001", "item-000", "item-003", "item-002"}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected order %v, got %v", want, ids)
+	}
+	if len(mock.searchCalls) != 1 || mock.searchCalls[0].SortField != "datetime" || mock.searchCalls[0].SortDirection != "desc" {
+		t.Errorf("Expected the first key to be sent upstream, got %+v", mock.searchCalls)
+	}
+}
+
+func TestHandlers_Search_UnsortableFieldRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{"secondary key first", "GET", "/search?sortby=-sat:relative_orbit", ""},
+		{"unknown field", "GET", "/search?sortby=%2Bdatetime,eo:cloud_cover", ""},
+		{"POST unknown field", "POST", "/search", `{"sortby":[{"field":"datetime","direction":"asc"},{"field":"geometry","direction":"asc"}]}`},
+		{"POST invalid direction", "POST", "/search", `{"sortby":[{"field":"datetime","direction":"sideways"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockBackend{}
+
+			cfg := createTestConfig()
+			cfg.Features.EnableSearch = true
+			collections := createTestCollections()
+			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+			translator := translate.NewTranslator(cfg, collections, logger)
+
+			handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handlers.Search(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+			if len(mock.searchCalls) != 0 {
+				t.Errorf("Expected no backend calls, got %d", len(mock.searchCalls))
+			}
+		})
+	}
+}
+
+func TestHandlers_Search_CursorSortOrderMismatch(t *testing.T) {
+	// Test that a descending cursor is rejected when ascending order is requested
+
+	mock := &mockBackend{}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+	cursor := stac.EncodeCursor(&stac.Cursor{StartTime: "2024-01-15T12:00:00Z", Direction: "next"})
+	req := httptest.NewRequest("GET", "/search?sortby=%2Bdatetime&cursor="+url.QueryEscape(cursor), nil)
+	w := httptest.NewRecorder()
+	handlers.Search(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandlers_FieldsExtension(t *testing.T) {
+	// Test that fields reduce the items returned by items and search, and are
+	// kept in next links
+
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	items := make([]*gostac.Item, 3)
+	for i := range items {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", i), baseTime.Add(-time.Duration(i)*time.Minute))
+		items[i].Properties["platform"] = "sentinel-1a"
+		items[i].Assets["data"] = &gostac.Asset{Href: "https://example.com/data.zip"}
+	}
+
+	mock := &mockBackend{items: items}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger)
+
+	footprint := "id,geometry,properties.datetime,-type,-stac_version,-collection,-bbox,-assets,-links"
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		handler http.HandlerFunc
+	}{
+		{
+			name: "items",
+			request: func() *http.Request {
+				req := httptest.NewRequest("GET", "/collections/sentinel-1/items?limit=2&fields="+url.QueryEscape(footprint), nil)
+				rctx := chi.NewRouteContext()
+				rctx.URLParams.Add("collectionId", "sentinel-1")
+				return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			},
+			handler: handlers.Items,
+		},
+		{
+			name: "POST search",
+			request: func() *http.Request {
+				body := `{"collections":["sentinel-1"],"limit":2,"fields":{"include":["id","geometry","properties.datetime"],` +
+					`"exclude":["type","stac_version","collection","bbox","assets","links"]}}`
+				return httptest.NewRequest("POST", "/search", strings.NewReader(body))
+			},
+			handler: handlers.Search,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, tt.request())
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+
+			features := response["features"].([]interface{})
+			if len(features) != 2 {
+				t.Fatalf("Expected 2 features, got %d", len(features))
+			}
+			for _, f := range features {
+				feature := f.(map[string]interface{})
+				if len(feature) != 3 || feature["id"] == nil || feature["geometry"] == nil {
+					t.Errorf("Expected only id, geometry and properties, got %v", feature)
+				}
+				props, _ := feature["properties"].(map[string]interface{})
+				if len(props) != 1 || props["datetime"] == nil {
+					t.Errorf("Expected only properties.datetime, got %v", props)
+				}
+			}
+
+			next := ""
+			for _, l := range response["links"].([]interface{}) {
+				link := l.(map[string]interface{})
+				if link["rel"] == "next" {
+					next = link["href"].(string)
+				}
+			}
+			u, err := url.Parse(next)
+			if err != nil || u.Query().Get("fields") == "" {
+				t.Errorf("Expected fields in the next link, got %q", next)
+			}
+		})
+	}
+}
+
+// downBackend is a mockBackend whose upstream is unreachable
+type downBackend struct {
+	mockBackend
+	name string
+}
+
+func (m *downBackend) Name() string {
+	return m.name
+}
+
+func (m *downBackend) Search(ctx context.Context, params *backend.SearchParams) (*backend.SearchResult, error) {
+	return nil, &upstream.Error{Service: m.name, Err: errors.New("connection refused"), Retryable: true}
+}
+
+func TestHandlers_Failover_ReportsActiveBackend(t *testing.T) {
+	// Test that a search the primary fails mid-request names the secondary
+	// that served it in the X-Backend header, and that after the primary's
+	// breaker trips the secondary is also named in /health
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	items := []*gostac.Item{createTestItem("item-1", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+	failover := backend.NewFailover(&downBackend{name: "asf"}, &mockBackend{items: items}, backend.BreakerConfig{
+		Window:      2,
+		MinRequests: 1,
+		FailureRate: 1,
+		OpenTimeout: time.Minute,
+	}, logger)
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	router := NewRouter(NewHandlers(cfg, failover, nil, createTestCollections(), logger), logger)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	w := serve("/search?collections=sentinel-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(BackendHeader); got != "mock" {
+		t.Errorf("Expected the request failed over to the secondary, got %q", got)
+	}
+
+	w = serve("/search?collections=sentinel-1")
+	if got := w.Header().Get(BackendHeader); got != "mock" {
+		t.Errorf("Expected the request to be routed to the secondary, got %q", got)
+	}
+
+	var health struct {
+		Backend       string                 `json:"backend"`
+		BackendStatus backend.FailoverStatus `json:"backend_status"`
+	}
+	if err := json.Unmarshal(serve("/health").Body.Bytes(), &health); err != nil {
+		t.Fatalf("Failed to decode health: %v", err)
+	}
+	if health.Backend != "mock" || health.BackendStatus.Breakers["asf"].State != "open" {
+		t.Errorf("Unexpected health %+v", health)
+	}
+}
+
+func TestHandlers_Metrics(t *testing.T) {
+	// Test that requests are recorded by route pattern and served on /metrics
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	items := []*gostac.Item{createTestItem("item-1", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+	m := metrics.New()
+	h := NewHandlers(createTestConfig(), &mockBackend{items: items}, nil, createTestCollections(), logger).WithMetrics(m)
+	router := NewRouter(h, logger)
+
+	for _, target := range []string{"/collections/sentinel-1/items", "/collections/sentinel-1/items/item-1", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		`asf_stac_http_requests_total{route="/collections/{collectionId}/items",method="GET",status="200"} 1`,
+		`asf_stac_http_requests_total{route="/collections/{collectionId}/items/{itemId}",method="GET",status="200"} 1`,
+		`asf_stac_http_requests_total{route="unmatched",method="GET",status="404"} 1`,
+		`asf_stac_page_items_count{endpoint="items"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestHandlers_Tracing(t *testing.T) {
+	// Test that a request continues the caller's trace from its traceparent,
+	// with spans for the route, the handler and the response encoding
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	items := []*gostac.Item{createTestItem("item-1", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+	recorder := tracing.NewRecorder()
+	h := NewHandlers(createTestConfig(), &mockBackend{items: items}, nil, createTestCollections(), logger).
+		WithTracer(tracing.NewTracer("test", recorder))
+	router := NewRouter(h, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/collections/sentinel-1/items", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	server, ok := recorder.Find("GET /collections/{collectionId}/items")
+	if !ok {
+		t.Fatalf("Expected a server span named after the route, got %+v", recorder.Spans())
+	}
+	if server.Context.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || server.Parent.String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the caller's trace to be continued, got %+v", server.Context)
+	}
+	if server.Attributes["http.response.status_code"] != http.StatusOK {
+		t.Errorf("Unexpected server span attributes %v", server.Attributes)
+	}
+
+	handler, ok := recorder.Find("Handlers.Items")
+	if !ok || handler.Parent != server.Context.SpanID || handler.Attributes["page.items"] != 1 {
+		t.Errorf("Unexpected handler span %+v", handler)
+	}
+	if encode, ok := recorder.Find("encode response"); !ok || encode.Parent != handler.Context.SpanID {
+		t.Errorf("Unexpected encode span %+v", encode)
+	}
+}
+
+// nextLinkCursor returns the cursor parameter of the next link in a response
+func nextLinkCursor(t *testing.T, body []byte) string {
+	t.Helper()
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	for _, l := range response["links"].([]interface{}) {
+		link := l.(map[string]interface{})
+		if link["rel"] == "next" {
+			u, err := url.Parse(link["href"].(string))
+			if err != nil {
+				t.Fatalf("Invalid next link: %v", err)
+			}
+			return u.Query().Get("cursor")
+		}
+	}
+	t.Fatal("Expected a next link")
+	return ""
+}
+
+func TestHandlers_Search_SealedCursors(t *testing.T) {
+	// Test that cursors sealed with a keyring page through every item, and
+	// that altered, forged or replayed cursors are rejected
+
+	baseTime := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	items := make([]*gostac.Item, 20)
+	for i := range items {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", 19-i), baseTime.Add(time.Duration(19-i)*time.Hour))
+	}
+
+	mock := &timeWindowBackend{mockBackend{items: items}}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	keyring, err := stac.NewCursorKeyring([]byte("0123456789abcdef0123456789abcdef"), nil, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("NewCursorKeyring() error = %v", err)
+	}
+	handlers := NewHandlers(cfg, mock, translator, collections, logger).WithCursorKeyring(keyring)
+
+	query := "/search?collections=sentinel-1&bbox=-150,60,-140,70&limit=5"
+	if ids := followPages(t, handlers.Search, query); len(ids) != 20 {
+		t.Fatalf("Expected 20 items across pages, got %d: %v", len(ids), ids)
+	}
+
+	req := httptest.NewRequest("GET", query, nil)
+	w := httptest.NewRecorder()
+	handlers.Search(w, req)
+	cursor := nextLinkCursor(t, w.Body.Bytes())
+
+	// The cursor is opaque: it does not decode as plain JSON
+	if _, err := stac.DecodeCursor(cursor); err == nil {
+		t.Errorf("Expected a sealed cursor, got a plain one: %s", cursor)
+	}
+
+	// Change a character in the middle of the sealed cursor
+	altered := []byte(cursor)
+	if altered[len(altered)/2] == 'A' {
+		altered[len(altered)/2] = 'B'
+	} else {
+		altered[len(altered)/2] = 'A'
+	}
+
+	// A hand-made cursor jumping the time window
+	forged := stac.EncodeCursor(&stac.Cursor{StartTime: "2024-01-15T03:00:00Z", Direction: "next"})
+
+	tests := []struct {
+		name      string
+		target    string
+		wantError string
+	}{
+		{"altered", query + "&cursor=" + url.QueryEscape(string(altered)), "invalid"},
+		{"forged", query + "&cursor=" + url.QueryEscape(forged), "invalid"},
+		{"replayed with another bbox", "/search?collections=sentinel-1&bbox=-10,60,0,70&limit=5&cursor=" + url.QueryEscape(cursor), "different query"},
+		{"replayed with a filter", query + "&filter=" + url.QueryEscape("platform = 'sentinel-1b'") + "&cursor=" + url.QueryEscape(cursor), "different query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := len(mock.searchCalls)
+
+			req := httptest.NewRequest("GET", tt.target, nil)
+			w := httptest.NewRecorder()
+			handlers.Search(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantError) {
+				t.Errorf("Expected error mentioning %q, got %s", tt.wantError, w.Body.String())
+			}
+			if len(mock.searchCalls) != calls {
+				t.Errorf("Expected no backend calls, got %d", len(mock.searchCalls)-calls)
+			}
+		})
+	}
+}
+
+func TestHandlers_Search_CursorFromPostFollowedWithGet(t *testing.T) {
+	// Test that the next link of a POST search, followed with GET, is
+	// accepted as the same query
+
+	baseTime := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	items := make([]*gostac.Item, 10)
+	for i := range items {
+		items[i] = createTestItem(fmt.Sprintf("item-%03d", 9-i), baseTime.Add(time.Duration(9-i)*time.Hour))
+	}
+
+	mock := &timeWindowBackend{mockBackend{items: items}}
+
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	collections := createTestCollections()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	translator := translate.NewTranslator(cfg, collections, logger)
+
+	handlers := NewHandlers(cfg, mock, translator, collections, logger).WithCursorKeyring(stac.NewRandomCursorKeyring())
+
+	body := `{
+		"collections": ["sentinel-1"],
+		"intersects": {"type": "Polygon", "coordinates": [[[-150, 60], [-140, 60], [-140, 70], [-150, 60]]]},
+		"filter": {"op": "=", "args": [{"property": "sat:orbit_state"}, "ascending"]},
+		"sortby": [{"field": "datetime", "direction": "DESC"}],
+		"limit": 4
+	}`
+	req := httptest.NewRequest("POST", "/search", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handlers.Search(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	var next string
+	for _, l := range response["links"].([]interface{}) {
+		if link := l.(map[string]interface{}); link["rel"] == "next" {
+			next = link["href"].(string)
+		}
+	}
+	if next == "" {
+		t.Fatal("Expected a next link")
+	}
+	u, err := url.Parse(next)
+	if err != nil {
+		t.Fatalf("Invalid next link: %v", err)
+	}
+
+	req = httptest.NewRequest("GET", u.Path+"?"+u.RawQuery, nil)
+	w = httptest.NewRecorder()
+	handlers.Search(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 following the next link, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -5,14 +5,22 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
 )
 
 // RequestIDHeader is the header name for request ID in responses.
 const RequestIDHeader = "X-Request-ID"
 
+// BackendHeader is the header naming the backend a request was routed to.
+const BackendHeader = "X-Backend"
+
 // requestIDKey is the context key for storing request ID.
 type requestIDKey struct{}
 
@@ -41,6 +49,51 @@ func RequestIDResponse(next http.Handler) http.Handler {
 	})
 }
 
+// ActiveBackend adds the X-Backend header naming the backend that served a
+// request: the one a failover backend recorded as serving its calls, or else
+// the one requests are routed to when the response is written.
+func ActiveBackend(b backend.SearchBackend) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, served := backend.WithServedBy(r.Context())
+			next.ServeHTTP(&backendHeaderWriter{ResponseWriter: w, backend: b, served: served}, r.WithContext(ctx))
+		})
+	}
+}
+
+// backendHeaderWriter sets the X-Backend header when the response is
+// written, once the backend serving the request is known
+type backendHeaderWriter struct {
+	http.ResponseWriter
+	backend backend.SearchBackend
+	served  *backend.ServedBy
+	written bool
+}
+
+func (w *backendHeaderWriter) WriteHeader(status int) {
+	if !w.written {
+		w.written = true
+		name := w.served.Name()
+		if name == "" {
+			name = w.backend.Name()
+		}
+		w.Header().Set(BackendHeader, name)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *backendHeaderWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (w *backendHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // RequestLogger creates a middleware that logs HTTP requests using structured logging.
 func RequestLogger(logger *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -59,7 +112,7 @@ func RequestLogger(logger *slog.Logger) func(next http.Handler) http.Handler {
 			// Get request ID from context
 			reqID := GetRequestID(r.Context())
 
-			logger.Info("http request",
+			attrs := []any{
 				slog.String("request_id", reqID),
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
@@ -69,7 +122,75 @@ func RequestLogger(logger *slog.Logger) func(next http.Handler) http.Handler {
 				slog.Int("bytes", ww.BytesWritten()),
 				slog.Duration("duration", duration),
 				slog.String("user_agent", r.UserAgent()),
-			)
+			}
+
+			// Correlate the log line with the request's trace, if traced
+			if sc := tracing.SpanFromContext(r.Context()).Context(); sc.IsValid() {
+				attrs = append(attrs, slog.String("trace_id", sc.TraceID.String()))
+			}
+
+			logger.Info("http request", attrs...)
+		})
+	}
+}
+
+// RequestMetrics creates a middleware that records request counts and
+// latencies by chi route pattern, so that paths with IDs share a series.
+// Requests matching no route are recorded under "unmatched".
+func RequestMetrics(m *metrics.Metrics) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			m.ObserveHTTPRequest(route, r.Method, status, time.Since(start))
+		})
+	}
+}
+
+// Tracing creates a middleware that starts a server span for each request,
+// continuing the caller's trace when a W3C traceparent header is sent. Once
+// the request is routed the span is named after its chi route pattern.
+func Tracing(t *tracing.Tracer) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if parent, ok := tracing.Extract(r.Header); ok {
+				ctx = tracing.ContextWithRemoteParent(ctx, parent)
+			}
+			ctx, span := t.Start(ctx, r.Method, tracing.KindServer)
+			defer span.End()
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.URL.Path)
+			if reqID := GetRequestID(ctx); reqID != "" {
+				span.SetAttribute("request.id", reqID)
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(r.Method + " " + rctx.RoutePattern())
+				span.SetAttribute("http.route", rctx.RoutePattern())
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttribute("http.response.status_code", status)
+			if status >= http.StatusInternalServerError {
+				span.SetError(http.StatusText(status))
+			}
 		})
 	}
 }
@@ -118,3 +239,20 @@ func Recovery(logger *slog.Logger) func(next http.Handler) http.Handler {
 		})
 	}
 }
+
+// Compress creates a middleware compressing responses at the given level,
+// except asset downloads, which stream as is so byte ranges keep their
+// meaning.
+func Compress(level int) func(next http.Handler) http.Handler {
+	compress := middleware.Compress(level)
+	return func(next http.Handler) http.Handler {
+		compressed := compress(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, downloadPathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			compressed.ServeHTTP(w, r)
+		})
+	}
+}
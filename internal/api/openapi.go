@@ -0,0 +1,1178 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	intstac "github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+)
+
+// OpenAPIMediaType is the media type advertised for the service-desc link.
+const OpenAPIMediaType = "application/vnd.oai.openapi+json;version=3.0"
+
+// OpenAPIDocument is the root of an OpenAPI 3.0 document.
+// Schemas are kept as free-form JSON Schema maps, matching how queryables are built.
+type OpenAPIDocument struct {
+	OpenAPI    string                      `json:"openapi"`
+	Info       OpenAPIInfo                 `json:"info"`
+	Servers    []OpenAPIServer             `json:"servers,omitempty"`
+	Tags       []OpenAPITag                `json:"tags,omitempty"`
+	Paths      map[string]*OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents           `json:"components"`
+}
+
+// OpenAPIInfo describes the API.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIServer is a server the API is reachable at.
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+// OpenAPITag groups operations in the rendered documentation.
+type OpenAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIPathItem holds the operations available on a single path.
+type OpenAPIPathItem struct {
+	Get  *OpenAPIOperation `json:"get,omitempty"`
+	Head *OpenAPIOperation `json:"head,omitempty"`
+	Post *OpenAPIOperation `json:"post,omitempty"`
+}
+
+// OpenAPIOperation describes a single API operation.
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter is either an inline parameter or a $ref to a component parameter.
+type OpenAPIParameter struct {
+	Ref         string         `json:"$ref,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	In          string         `json:"in,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Style       string         `json:"style,omitempty"`
+	Explode     *bool          `json:"explode,omitempty"`
+	Schema      map[string]any `json:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody describes a request payload.
+type OpenAPIRequestBody struct {
+	Description string                       `json:"description,omitempty"`
+	Required    bool                         `json:"required,omitempty"`
+	Content     map[string]*OpenAPIMediaItem `json:"content"`
+}
+
+// OpenAPIResponse is either an inline response or a $ref to a component response.
+type OpenAPIResponse struct {
+	Ref         string                       `json:"$ref,omitempty"`
+	Description string                       `json:"description,omitempty"`
+	Content     map[string]*OpenAPIMediaItem `json:"content,omitempty"`
+}
+
+// OpenAPIMediaItem binds a schema to a media type.
+type OpenAPIMediaItem struct {
+	Schema map[string]any `json:"schema"`
+}
+
+// OpenAPIComponents holds reusable schemas, parameters and responses.
+type OpenAPIComponents struct {
+	Schemas    map[string]map[string]any    `json:"schemas"`
+	Parameters map[string]*OpenAPIParameter `json:"parameters"`
+	Responses  map[string]*OpenAPIResponse  `json:"responses"`
+}
+
+// OpenAPI returns the OpenAPI 3.0 service description.
+// GET /api
+func (h *Handlers) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc := h.buildOpenAPIDocument(r.Context())
+
+	w.Header().Set("Content-Type", OpenAPIMediaType)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		h.logger.Error("failed to encode OpenAPI document",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+//go:embed apidocs.html
+var apiDocsHTML string
+
+var apiDocsTemplate = template.Must(template.New("apidocs").Funcs(template.FuncMap{
+	"refName":  refName,
+	"toJSON":   toIndentedJSON,
+	"upper":    strings.ToUpper,
+	"joinTags": func(tags []string) string { return strings.Join(tags, ", ") },
+}).Parse(apiDocsHTML))
+
+// apiDocsOperation is a flattened view of an operation for the HTML renderer.
+type apiDocsOperation struct {
+	Method    string
+	Path      string
+	Operation *OpenAPIOperation
+}
+
+// APIDocs renders human-readable HTML documentation for the OpenAPI document.
+// The page is rendered server-side and has no external script or stylesheet
+// dependencies, so it works on networks without internet access.
+// GET /api.html
+func (h *Handlers) APIDocs(w http.ResponseWriter, r *http.Request) {
+	doc := h.buildOpenAPIDocument(r.Context())
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var operations []apiDocsOperation
+	for _, path := range paths {
+		item := doc.Paths[path]
+		if item.Get != nil {
+			operations = append(operations, apiDocsOperation{Method: "get", Path: path, Operation: item.Get})
+		}
+		if item.Post != nil {
+			operations = append(operations, apiDocsOperation{Method: "post", Path: path, Operation: item.Post})
+		}
+	}
+
+	schemaNames := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		schemaNames = append(schemaNames, name)
+	}
+	sort.Strings(schemaNames)
+
+	data := struct {
+		Doc         *OpenAPIDocument
+		SpecURL     string
+		Operations  []apiDocsOperation
+		SchemaNames []string
+	}{
+		Doc:         doc,
+		SpecURL:     h.cfg.STAC.BaseURL + "/api",
+		Operations:  operations,
+		SchemaNames: schemaNames,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := apiDocsTemplate.Execute(w, data); err != nil {
+		h.logger.Error("failed to render API documentation",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// buildOpenAPIDocument generates the OpenAPI document for the routes registered in NewRouter.
+// Limits and queryables are taken from the live configuration so the document always
+// matches what the server enforces.
+func (h *Handlers) buildOpenAPIDocument(ctx context.Context) *OpenAPIDocument {
+	baseURL := h.cfg.STAC.BaseURL
+
+	doc := &OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:       h.cfg.STAC.Title,
+			Description: h.cfg.STAC.Description,
+			Version:     h.cfg.STAC.Version,
+		},
+		Tags: []OpenAPITag{
+			{Name: "Core", Description: "STAC API core endpoints"},
+			{Name: "Collections", Description: "Collection metadata"},
+			{Name: "Features", Description: "OGC API Features item access"},
+			{Name: "Item Search", Description: "Cross-collection item search"},
+			{Name: "Filter", Description: "CQL2 queryables"},
+			{Name: "Aggregation", Description: "Aggregations over search results"},
+			{Name: "Service", Description: "Operational endpoints"},
+		},
+		Paths:      make(map[string]*OpenAPIPathItem),
+		Components: h.openAPIComponents(ctx),
+	}
+	if baseURL != "" {
+		doc.Servers = []OpenAPIServer{{URL: baseURL}}
+	}
+
+	errorResponses := func(responses map[string]*OpenAPIResponse, codes ...string) map[string]*OpenAPIResponse {
+		for _, code := range codes {
+			switch code {
+			case "400":
+				responses[code] = &OpenAPIResponse{Ref: "#/components/responses/BadRequest"}
+			case "404":
+				responses[code] = &OpenAPIResponse{Ref: "#/components/responses/NotFound"}
+			case "500":
+				responses[code] = &OpenAPIResponse{Ref: "#/components/responses/ServerError"}
+			case "502":
+				responses[code] = &OpenAPIResponse{Ref: "#/components/responses/UpstreamError"}
+			case "503":
+				responses[code] = &OpenAPIResponse{Ref: "#/components/responses/UpstreamUnavailable"}
+			}
+		}
+		return responses
+	}
+
+	jsonResponse := func(description, mediaType, schema string) *OpenAPIResponse {
+		return &OpenAPIResponse{
+			Description: description,
+			Content: map[string]*OpenAPIMediaItem{
+				mediaType: {Schema: schemaRef(schema)},
+			},
+		}
+	}
+
+	searchParams := []OpenAPIParameter{
+		paramRef("bbox"),
+		paramRef("datetime"),
+		paramRef("intersects"),
+		paramRef("ids"),
+		paramRef("limit"),
+		paramRef("cursor"),
+		paramRef("sortby"),
+		paramRef("fields"),
+		paramRef("filter"),
+		paramRef("filter-lang"),
+		paramRef("filter-crs"),
+	}
+
+	doc.Paths["/"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getLandingPage",
+			Summary:     "Landing page",
+			Description: "Root catalog with links to the API's resources.",
+			Tags:        []string{"Core"},
+			Responses: errorResponses(map[string]*OpenAPIResponse{
+				"200": jsonResponse("The landing page", "application/json", "LandingPage"),
+			}, "500"),
+		},
+	}
+
+	doc.Paths["/conformance"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getConformanceDeclaration",
+			Summary:     "Conformance classes",
+			Tags:        []string{"Core"},
+			Responses: errorResponses(map[string]*OpenAPIResponse{
+				"200": jsonResponse("The conformance classes implemented by this server", "application/json", "Conformance"),
+			}, "500"),
+		},
+	}
+
+	doc.Paths["/collections"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getCollections",
+			Summary:     "List collections",
+			Tags:        []string{"Collections"},
+			Responses: errorResponses(map[string]*OpenAPIResponse{
+				"200": jsonResponse("All available collections", "application/json", "Collections"),
+			}, "500"),
+		},
+	}
+
+	doc.Paths["/collections/{collectionId}"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "describeCollection",
+			Summary:     "Describe a collection",
+			Tags:        []string{"Collections"},
+			Parameters:  []OpenAPIParameter{paramRef("collectionId")},
+			Responses: errorResponses(map[string]*OpenAPIResponse{
+				"200": jsonResponse("The collection", "application/json", "Collection"),
+			}, "404", "500"),
+		},
+	}
+
+	doc.Paths["/collections/{collectionId}/items"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getFeatures",
+			Summary:     "Fetch items in a collection",
+			Description: "Returns items from a single collection. Accepts the same filters as GET /search.",
+			Tags:        []string{"Features"},
+			Parameters:  append([]OpenAPIParameter{paramRef("collectionId")}, searchParams...),
+			Responses: errorResponses(map[string]*OpenAPIResponse{
+				"200": jsonResponse("A page of items", "application/geo+json", "ItemCollection"),
+			}, "400", "404", "500", "502", "503"),
+		},
+	}
+
+	doc.Paths["/collections/{collectionId}/items/{itemId}"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getFeature",
+			Summary:     "Fetch a single item",
+			Tags:        []string{"Features"},
+			Parameters:  []OpenAPIParameter{paramRef("collectionId"), paramRef("itemId")},
+			Responses: errorResponses(map[string]*OpenAPIResponse{
+				"200": jsonResponse("The item", "application/geo+json", "Item"),
+			}, "404", "500", "502", "503"),
+		},
+	}
+
+	baselineParam := func(name, unit string) OpenAPIParameter {
+		return OpenAPIParameter{
+			Name:        name,
+			In:          "query",
+			Description: "Range of the " + strings.ReplaceAll(name, "_", " ") + " in " + unit + ", as min/max where \"..\" leaves a bound open",
+			Schema:      map[string]any{"type": "string"},
+		}
+	}
+	doc.Paths["/collections/{collectionId}/items/{itemId}/stack"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getStack",
+			Summary:     "Interferometric stack of an item",
+			Description: "Items forming interferograms with the reference item, ordered by temporal baseline, " +
+				"with their insar:temporal_baseline and insar:perpendicular_baseline relative to it.",
+			Tags: []string{"Features"},
+			Parameters: []OpenAPIParameter{
+				paramRef("collectionId"),
+				paramRef("itemId"),
+				baselineParam("temporal_baseline", "days"),
+				baselineParam("perpendicular_baseline", "meters"),
+			},
+			Responses: errorResponses(map[string]*OpenAPIResponse{
+				"200": jsonResponse("The stack of the item", "application/geo+json", "ItemCollection"),
+				"501": {
+					Description: "The search backend does not support interferometric stacks",
+					Content: map[string]*OpenAPIMediaItem{
+						"application/json": {Schema: schemaRef("STACError")},
+					},
+				},
+			}, "400", "404", "500", "502", "503"),
+		},
+	}
+
+	searchResponses := func() map[string]*OpenAPIResponse {
+		return errorResponses(map[string]*OpenAPIResponse{
+			"200": jsonResponse("A page of items matching the search", "application/geo+json", "ItemCollection"),
+			"501": {
+				Description: "Search is disabled on this server",
+				Content: map[string]*OpenAPIMediaItem{
+					"application/json": {Schema: schemaRef("STACError")},
+				},
+			},
+		}, "400", "404", "500", "502", "503")
+	}
+
+	collectionsParam := paramRef("collections")
+	doc.Paths["/search"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getItemSearch",
+			Summary:     "Search items",
+			Description: "Cross-collection item search using query parameters.",
+			Tags:        []string{"Item Search"},
+			Parameters:  append([]OpenAPIParameter{collectionsParam}, searchParams...),
+			Responses:   searchResponses(),
+		},
+		Post: &OpenAPIOperation{
+			OperationID: "postItemSearch",
+			Summary:     "Search items",
+			Description: "Cross-collection item search using a JSON request body.",
+			Tags:        []string{"Item Search"},
+			RequestBody: &OpenAPIRequestBody{
+				Required: true,
+				Content: map[string]*OpenAPIMediaItem{
+					"application/json": {Schema: schemaRef("SearchBody")},
+				},
+			},
+			Responses: searchResponses(),
+		},
+	}
+
+	aggregateParams := []OpenAPIParameter{
+		paramRef("aggregations"),
+		paramRef("datetime_frequency_interval"),
+		paramRef("centroid_geohash_grid_frequency_precision"),
+		paramRef("centroid_geotile_grid_frequency_precision"),
+		paramRef("bbox"),
+		paramRef("datetime"),
+		paramRef("intersects"),
+		paramRef("ids"),
+		paramRef("filter"),
+		paramRef("filter-lang"),
+		paramRef("filter-crs"),
+	}
+	aggregateResponses := func() map[string]*OpenAPIResponse {
+		return errorResponses(map[string]*OpenAPIResponse{
+			"200": jsonResponse("The aggregations over the matching items", "application/json", "AggregationCollection"),
+		}, "400", "404", "500", "502", "503")
+	}
+	aggregateBody := &OpenAPIRequestBody{
+		Required: true,
+		Content: map[string]*OpenAPIMediaItem{
+			"application/json": {Schema: schemaRef("AggregateBody")},
+		},
+	}
+	aggregationsResponses := func(codes ...string) map[string]*OpenAPIResponse {
+		return errorResponses(map[string]*OpenAPIResponse{
+			"200": jsonResponse("The aggregations available", "application/json", "AggregationCollection"),
+		}, codes...)
+	}
+
+	doc.Paths["/aggregate"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getAggregate",
+			Summary:     "Aggregate search results",
+			Description: "Computes aggregations over the items matching a cross-collection search.",
+			Tags:        []string{"Aggregation"},
+			Parameters:  append([]OpenAPIParameter{collectionsParam}, aggregateParams...),
+			Responses:   aggregateResponses(),
+		},
+		Post: &OpenAPIOperation{
+			OperationID: "postAggregate",
+			Summary:     "Aggregate search results",
+			Description: "Computes aggregations over the items matching a search given as a JSON request body.",
+			Tags:        []string{"Aggregation"},
+			RequestBody: aggregateBody,
+			Responses:   aggregateResponses(),
+		},
+	}
+	doc.Paths["/aggregations"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getAggregations",
+			Summary:     "List aggregations",
+			Tags:        []string{"Aggregation"},
+			Responses:   aggregationsResponses("500"),
+		},
+	}
+	doc.Paths["/collections/{collectionId}/aggregate"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getCollectionAggregate",
+			Summary:     "Aggregate items in a collection",
+			Tags:        []string{"Aggregation"},
+			Parameters:  append([]OpenAPIParameter{paramRef("collectionId")}, aggregateParams...),
+			Responses:   aggregateResponses(),
+		},
+		Post: &OpenAPIOperation{
+			OperationID: "postCollectionAggregate",
+			Summary:     "Aggregate items in a collection",
+			Tags:        []string{"Aggregation"},
+			Parameters:  []OpenAPIParameter{paramRef("collectionId")},
+			RequestBody: aggregateBody,
+			Responses:   aggregateResponses(),
+		},
+	}
+	doc.Paths["/collections/{collectionId}/aggregations"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getCollectionAggregations",
+			Summary:     "List aggregations for a collection",
+			Tags:        []string{"Aggregation"},
+			Parameters:  []OpenAPIParameter{paramRef("collectionId")},
+			Responses:   aggregationsResponses("404", "500"),
+		},
+	}
+
+	pairsParams := []OpenAPIParameter{
+		collectionsParam,
+		{
+			Name:        "max_temporal_baseline",
+			In:          "query",
+			Description: "Longest time between the acquisitions of a pair, in days",
+			Schema:      map[string]any{"type": "integer", "minimum": 1, "default": intstac.DefaultMaxTemporalBaseline},
+		},
+		{
+			Name:        "max_perpendicular_baseline",
+			In:          "query",
+			Description: "Largest perpendicular baseline of a pair, in meters",
+			Schema:      map[string]any{"type": "number", "minimum": 0},
+		},
+		paramRef("bbox"),
+		paramRef("datetime"),
+		paramRef("intersects"),
+		paramRef("ids"),
+		paramRef("limit"),
+		paramRef("cursor"),
+		paramRef("filter"),
+		paramRef("filter-lang"),
+		paramRef("filter-crs"),
+	}
+	pairsResponses := func() map[string]*OpenAPIResponse {
+		return errorResponses(map[string]*OpenAPIResponse{
+			"200": jsonResponse("A page of candidate interferogram pairs", "application/json", "PairCollection"),
+			"501": {
+				Description: "Search is disabled, or the backend cannot bound perpendicular baselines",
+				Content: map[string]*OpenAPIMediaItem{
+					"application/json": {Schema: schemaRef("STACError")},
+				},
+			},
+		}, "400", "404", "500", "502", "503")
+	}
+	pairsDescription := "Pairs the items matching a search that share a collection, relative orbit and frame " +
+		"and whose footprints overlap, within the temporal and perpendicular baseline limits."
+	doc.Paths["/pairs"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getPairs",
+			Summary:     "Find InSAR pairs",
+			Description: pairsDescription,
+			Tags:        []string{"Item Search"},
+			Parameters:  pairsParams,
+			Responses:   pairsResponses(),
+		},
+		Post: &OpenAPIOperation{
+			OperationID: "postPairs",
+			Summary:     "Find InSAR pairs",
+			Description: pairsDescription,
+			Tags:        []string{"Item Search"},
+			RequestBody: &OpenAPIRequestBody{
+				Required: true,
+				Content: map[string]*OpenAPIMediaItem{
+					"application/json": {Schema: schemaRef("PairsBody")},
+				},
+			},
+			Responses: pairsResponses(),
+		},
+	}
+
+	if h.cfg.Features.EnableQueryables {
+		doc.Paths["/queryables"] = &OpenAPIPathItem{
+			Get: &OpenAPIOperation{
+				OperationID: "getQueryables",
+				Summary:     "Queryables for all collections",
+				Description: "JSON Schema of the properties that may be used in CQL2 filter expressions.",
+				Tags:        []string{"Filter"},
+				Responses: errorResponses(map[string]*OpenAPIResponse{
+					"200": jsonResponse("The queryables", "application/schema+json", "Queryables"),
+				}, "500"),
+			},
+		}
+		doc.Paths["/collections/{collectionId}/queryables"] = &OpenAPIPathItem{
+			Get: &OpenAPIOperation{
+				OperationID: "getCollectionQueryables",
+				Summary:     "Queryables for a collection",
+				Tags:        []string{"Filter"},
+				Parameters:  []OpenAPIParameter{paramRef("collectionId")},
+				Responses: errorResponses(map[string]*OpenAPIResponse{
+					"200": jsonResponse("The queryables", "application/schema+json", "Queryables"),
+				}, "404", "500"),
+			},
+		}
+	}
+
+	if h.downloads != nil {
+		downloadOperation := func(operationID, summary string) *OpenAPIOperation {
+			return &OpenAPIOperation{
+				OperationID: operationID,
+				Summary:     summary,
+				Description: "Streams an item asset through Earthdata Login, with the token of the " +
+					h.cfg.Download.TokenHeader + " header or the server's own. Range requests are supported.",
+				Tags: []string{"Features"},
+				Parameters: []OpenAPIParameter{
+					paramRef("collectionId"),
+					paramRef("itemId"),
+					{Name: "asset", In: "path", Description: "Asset key", Required: true, Schema: map[string]any{"type": "string"}},
+				},
+				Responses: errorResponses(map[string]*OpenAPIResponse{
+					"200": {Description: "The asset", Content: map[string]*OpenAPIMediaItem{
+						"application/octet-stream": {Schema: map[string]any{"type": "string", "format": "binary"}},
+					}},
+					"206": {Description: "The requested byte range of the asset"},
+					"401": {Description: "The asset requires an Earthdata Login token", Content: map[string]*OpenAPIMediaItem{
+						"application/json": {Schema: schemaRef("STACError")},
+					}},
+					"416": {Description: "The requested byte range is not satisfiable"},
+				}, "404", "500", "502", "503"),
+			}
+		}
+		doc.Paths["/download/{collectionId}/{itemId}/{asset}"] = &OpenAPIPathItem{
+			Get:  downloadOperation("getAsset", "Download an asset"),
+			Head: downloadOperation("headAsset", "Asset metadata"),
+		}
+	}
+
+	doc.Paths["/health"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getHealth",
+			Summary:     "Health check",
+			Tags:        []string{"Service"},
+			Responses: map[string]*OpenAPIResponse{
+				"200": jsonResponse("The service is healthy", "application/json", "Health"),
+			},
+		},
+	}
+
+	if h.metrics != nil {
+		doc.Paths["/metrics"] = &OpenAPIPathItem{
+			Get: &OpenAPIOperation{
+				OperationID: "getMetrics",
+				Summary:     "Prometheus metrics",
+				Tags:        []string{"Service"},
+				Responses: map[string]*OpenAPIResponse{
+					"200": {
+						Description: "Metrics in the Prometheus text exposition format",
+						Content: map[string]*OpenAPIMediaItem{
+							"text/plain": {Schema: map[string]any{"type": "string"}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	doc.Paths["/api"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getOpenAPI",
+			Summary:     "OpenAPI service description",
+			Tags:        []string{"Service"},
+			Responses: map[string]*OpenAPIResponse{
+				"200": {
+					Description: "This document",
+					Content: map[string]*OpenAPIMediaItem{
+						OpenAPIMediaType: {Schema: map[string]any{"type": "object"}},
+					},
+				},
+			},
+		},
+	}
+
+	doc.Paths["/api.html"] = &OpenAPIPathItem{
+		Get: &OpenAPIOperation{
+			OperationID: "getAPIDocs",
+			Summary:     "HTML API documentation",
+			Tags:        []string{"Service"},
+			Responses: map[string]*OpenAPIResponse{
+				"200": {
+					Description: "Human-readable rendering of this document",
+					Content: map[string]*OpenAPIMediaItem{
+						"text/html": {Schema: map[string]any{"type": "string"}},
+					},
+				},
+			},
+		},
+	}
+
+	return doc
+}
+
+// openAPIComponents builds the reusable schemas, parameters and responses.
+func (h *Handlers) openAPIComponents(ctx context.Context) OpenAPIComponents {
+	limits := h.cfg.Features
+
+	// Queryables are aggregated across the collections the client may access,
+	// the same as GET /queryables
+	queryables := queryableProperties()
+	h.addGlobalEnums(queryables, h.visibleCollections(ctx))
+
+	queryableNames := make([]string, 0, len(queryables))
+	for name := range queryables {
+		queryableNames = append(queryableNames, name)
+	}
+	sort.Strings(queryableNames)
+
+	limitSchema := map[string]any{
+		"type":    "integer",
+		"minimum": 1,
+		"maximum": limits.MaxLimit,
+		"default": limits.DefaultLimit,
+	}
+
+	bboxSchema := map[string]any{
+		"type":     "array",
+		"minItems": 4,
+		"maxItems": 6,
+		"items":    map[string]any{"type": "number"},
+	}
+
+	stringArray := map[string]any{
+		"type":  "array",
+		"items": map[string]any{"type": "string"},
+	}
+
+	linkSchema := map[string]any{
+		"type":     "object",
+		"required": []string{"href", "rel"},
+		"properties": map[string]any{
+			"href":   map[string]any{"type": "string", "format": "uri"},
+			"rel":    map[string]any{"type": "string"},
+			"type":   map[string]any{"type": "string"},
+			"title":  map[string]any{"type": "string"},
+			"method": map[string]any{"type": "string"},
+		},
+	}
+
+	links := map[string]any{
+		"type":  "array",
+		"items": schemaRef("Link"),
+	}
+
+	explodeFalse := false
+
+	aggregationNames := make([]string, 0, len(intstac.SupportedAggregations))
+	for _, def := range intstac.SupportedAggregations {
+		aggregationNames = append(aggregationNames, def.Name)
+	}
+	intervals := []string{intstac.IntervalYear, intstac.IntervalMonth, intstac.IntervalDay, intstac.IntervalHour}
+	geohashPrecision := map[string]any{
+		"type":    "integer",
+		"minimum": 1,
+		"maximum": intstac.MaxGeohashPrecision,
+		"default": intstac.DefaultGeohashPrecision,
+	}
+	geotilePrecision := map[string]any{
+		"type":    "integer",
+		"minimum": 1,
+		"maximum": intstac.MaxGeotilePrecision,
+		"default": intstac.DefaultGeotilePrecision,
+	}
+
+	return OpenAPIComponents{
+		Schemas: map[string]map[string]any{
+			"STACError": {
+				"type":        "object",
+				"description": "Error response returned for all non-2xx statuses.",
+				"required":    []string{"code", "description"},
+				"properties": map[string]any{
+					"code": map[string]any{
+						"type": "string",
+						"enum": []string{
+							ErrCodeBadRequest,
+							ErrCodeNotFound,
+							ErrCodeInvalidParameter,
+							ErrCodeServerError,
+							ErrCodeUpstreamError,
+							"MethodNotAllowed",
+							"NotImplemented",
+						},
+					},
+					"description": map[string]any{"type": "string"},
+					"request_id":  map[string]any{"type": "string"},
+				},
+			},
+			"Link": linkSchema,
+			"LandingPage": {
+				"type":     "object",
+				"required": []string{"type", "id", "description", "stac_version", "links"},
+				"properties": map[string]any{
+					"type":         map[string]any{"type": "string", "enum": []string{"Catalog"}},
+					"id":           map[string]any{"type": "string"},
+					"title":        map[string]any{"type": "string"},
+					"description":  map[string]any{"type": "string"},
+					"stac_version": map[string]any{"type": "string"},
+					"conformsTo":   stringArray,
+					"links":        links,
+				},
+			},
+			"Conformance": {
+				"type":     "object",
+				"required": []string{"conformsTo"},
+				"properties": map[string]any{
+					"conformsTo": stringArray,
+				},
+			},
+			"Collection": {
+				"type":                 "object",
+				"required":             []string{"id", "description", "license", "extent", "links"},
+				"additionalProperties": true,
+				"properties": map[string]any{
+					"id":           map[string]any{"type": "string"},
+					"title":        map[string]any{"type": "string"},
+					"description":  map[string]any{"type": "string"},
+					"license":      map[string]any{"type": "string"},
+					"stac_version": map[string]any{"type": "string"},
+					"extent":       map[string]any{"type": "object"},
+					"summaries":    map[string]any{"type": "object"},
+					"links":        links,
+				},
+			},
+			"Collections": {
+				"type":     "object",
+				"required": []string{"collections", "links"},
+				"properties": map[string]any{
+					"collections": map[string]any{"type": "array", "items": schemaRef("Collection")},
+					"links":       links,
+				},
+			},
+			"Item": {
+				"type":                 "object",
+				"description":          "A STAC Item (GeoJSON Feature).",
+				"required":             []string{"type", "id", "geometry", "properties", "links", "assets"},
+				"additionalProperties": true,
+				"properties": map[string]any{
+					"type":         map[string]any{"type": "string", "enum": []string{"Feature"}},
+					"stac_version": map[string]any{"type": "string"},
+					"id":           map[string]any{"type": "string"},
+					"collection":   map[string]any{"type": "string"},
+					"geometry":     map[string]any{"type": "object", "nullable": true},
+					"bbox":         bboxSchema,
+					"properties":   map[string]any{"type": "object"},
+					"assets":       map[string]any{"type": "object"},
+					"links":        links,
+				},
+			},
+			"ItemCollection": {
+				"type":     "object",
+				"required": []string{"type", "features", "links"},
+				"properties": map[string]any{
+					"type":           map[string]any{"type": "string", "enum": []string{"FeatureCollection"}},
+					"features":       map[string]any{"type": "array", "items": schemaRef("Item")},
+					"links":          links,
+					"numberMatched":  map[string]any{"type": "integer", "minimum": 0},
+					"numberReturned": map[string]any{"type": "integer", "minimum": 0},
+					"context":        map[string]any{"type": "object"},
+				},
+			},
+			"SearchBody": {
+				"type": "object",
+				"properties": map[string]any{
+					"bbox":        bboxSchema,
+					"datetime":    map[string]any{"type": "string"},
+					"intersects":  map[string]any{"type": "object", "description": "GeoJSON geometry"},
+					"ids":         stringArray,
+					"collections": stringArray,
+					"limit":       limitSchema,
+					"cursor":      map[string]any{"type": "string"},
+					"sortby": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type":     "object",
+							"required": []string{"field", "direction"},
+							"properties": map[string]any{
+								"field":     map[string]any{"type": "string"},
+								"direction": map[string]any{"type": "string", "enum": []string{"asc", "desc"}},
+							},
+						},
+					},
+					"fields": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"include": stringArray,
+							"exclude": stringArray,
+						},
+					},
+					"filter": map[string]any{
+						"description": "CQL2-JSON filter object, or a CQL2-Text string when filter-lang is cql2-text",
+						"oneOf":       []any{map[string]any{"type": "object"}, map[string]any{"type": "string"}},
+					},
+					"filter-lang": map[string]any{"type": "string", "enum": []string{"cql2-json", "cql2-text"}},
+					"filter-crs":  map[string]any{"type": "string"},
+				},
+			},
+			"AggregationCollection": {
+				"type":     "object",
+				"required": []string{"type", "aggregations", "links"},
+				"properties": map[string]any{
+					"type": map[string]any{"type": "string", "enum": []string{"AggregationCollection"}},
+					"aggregations": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type":     "object",
+							"required": []string{"name", "data_type"},
+							"properties": map[string]any{
+								"name":      map[string]any{"type": "string"},
+								"data_type": map[string]any{"type": "string"},
+								"value":     map[string]any{},
+								"buckets": map[string]any{
+									"type": "array",
+									"items": map[string]any{
+										"type":     "object",
+										"required": []string{"key", "data_type", "frequency"},
+										"properties": map[string]any{
+											"key":       map[string]any{},
+											"data_type": map[string]any{"type": "string"},
+											"frequency": map[string]any{"type": "integer"},
+										},
+									},
+								},
+							},
+						},
+					},
+					"links": links,
+					"partial": map[string]any{
+						"type":        "boolean",
+						"description": "Set when the aggregations cover only the newest matching items",
+					},
+				},
+			},
+			"AggregateBody": {
+				"type": "object",
+				"properties": map[string]any{
+					"aggregations": map[string]any{
+						"type":  "array",
+						"items": map[string]any{"type": "string", "enum": aggregationNames},
+					},
+					"datetime_frequency_interval":               map[string]any{"type": "string", "enum": intervals},
+					"centroid_geohash_grid_frequency_precision": geohashPrecision,
+					"centroid_geotile_grid_frequency_precision": geotilePrecision,
+					"bbox":        bboxSchema,
+					"datetime":    map[string]any{"type": "string"},
+					"intersects":  map[string]any{"type": "object", "description": "GeoJSON geometry"},
+					"ids":         stringArray,
+					"collections": stringArray,
+					"filter": map[string]any{
+						"description": "CQL2-JSON filter object, or a CQL2-Text string when filter-lang is cql2-text",
+						"oneOf":       []any{map[string]any{"type": "object"}, map[string]any{"type": "string"}},
+					},
+					"filter-lang": map[string]any{"type": "string", "enum": []string{"cql2-json", "cql2-text"}},
+					"filter-crs":  map[string]any{"type": "string"},
+				},
+			},
+			"PairsBody": {
+				"type": "object",
+				"properties": map[string]any{
+					"max_temporal_baseline":      map[string]any{"type": "integer", "minimum": 1, "default": intstac.DefaultMaxTemporalBaseline},
+					"max_perpendicular_baseline": map[string]any{"type": "number", "minimum": 0},
+					"bbox":                       bboxSchema,
+					"datetime":                   map[string]any{"type": "string"},
+					"intersects":                 map[string]any{"type": "object", "description": "GeoJSON geometry"},
+					"ids":                        stringArray,
+					"collections":                stringArray,
+					"limit":                      limitSchema,
+					"cursor":                     map[string]any{"type": "string"},
+					"filter": map[string]any{
+						"description": "CQL2-JSON filter object, or a CQL2-Text string when filter-lang is cql2-text",
+						"oneOf":       []any{map[string]any{"type": "object"}, map[string]any{"type": "string"}},
+					},
+					"filter-lang": map[string]any{"type": "string", "enum": []string{"cql2-json", "cql2-text"}},
+					"filter-crs":  map[string]any{"type": "string"},
+				},
+			},
+			"PairCollection": {
+				"type":     "object",
+				"required": []string{"type", "pairs", "links", "numberReturned"},
+				"properties": map[string]any{
+					"type": map[string]any{"type": "string", "enum": []string{"PairCollection"}},
+					"pairs": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type":     "object",
+							"required": []string{"id", "collection", "reference", "secondary", "insar:temporal_baseline", "links"},
+							"properties": map[string]any{
+								"id":                 map[string]any{"type": "string"},
+								"collection":         map[string]any{"type": "string"},
+								"reference":          map[string]any{"type": "string", "description": "ID of the earlier item"},
+								"secondary":          map[string]any{"type": "string", "description": "ID of the later item"},
+								"reference_datetime": map[string]any{"type": "string", "format": "date-time"},
+								"secondary_datetime": map[string]any{"type": "string", "format": "date-time"},
+								"sat:relative_orbit": map[string]any{},
+								"asf:frame":          map[string]any{},
+								"insar:temporal_baseline": map[string]any{
+									"type":        "integer",
+									"description": "Days between the acquisitions",
+								},
+								"insar:perpendicular_baseline": map[string]any{
+									"type":        "number",
+									"description": "Perpendicular baseline in meters, when bounded by the request",
+								},
+								"links": links,
+							},
+						},
+					},
+					"links": links,
+					"numberMatched": map[string]any{
+						"type":        "integer",
+						"minimum":     0,
+						"description": "Pairs matched in total, on the first page only",
+					},
+					"numberReturned": map[string]any{"type": "integer", "minimum": 0},
+				},
+			},
+			"Queryables": {
+				"type":                 "object",
+				"description":          "Properties usable in CQL2 filter expressions.",
+				"properties":           queryables,
+				"additionalProperties": true,
+			},
+			"Health": {
+				"type": "object",
+				"properties": map[string]any{
+					"status":  map[string]any{"type": "string"},
+					"backend": map[string]any{"type": "string", "description": "Backend requests are currently routed to"},
+					"backend_status": map[string]any{
+						"type":        "object",
+						"description": "Active backend and circuit breaker states, when failover is enabled",
+					},
+					"cache": map[string]any{
+						"type":        "object",
+						"description": "Upstream response cache counters, when the cache is enabled",
+					},
+				},
+			},
+		},
+		Parameters: map[string]*OpenAPIParameter{
+			"collectionId": {
+				Name:        "collectionId",
+				In:          "path",
+				Description: "Collection identifier",
+				Required:    true,
+				Schema:      map[string]any{"type": "string"},
+			},
+			"itemId": {
+				Name:        "itemId",
+				In:          "path",
+				Description: "Item identifier",
+				Required:    true,
+				Schema:      map[string]any{"type": "string"},
+			},
+			"bbox": {
+				Name:        "bbox",
+				In:          "query",
+				Description: "Bounding box as west,south,east,north (or 6 values with elevation)",
+				Style:       "form",
+				Explode:     &explodeFalse,
+				Schema:      bboxSchema,
+			},
+			"datetime": {
+				Name:        "datetime",
+				In:          "query",
+				Description: "RFC 3339 datetime or interval, open ends as '..'",
+				Schema:      map[string]any{"type": "string"},
+			},
+			"intersects": {
+				Name:        "intersects",
+				In:          "query",
+				Description: "URL-encoded GeoJSON geometry",
+				Schema:      map[string]any{"type": "string"},
+			},
+			"ids": {
+				Name:        "ids",
+				In:          "query",
+				Description: "Comma-separated item IDs",
+				Style:       "form",
+				Explode:     &explodeFalse,
+				Schema:      stringArray,
+			},
+			"collections": {
+				Name:        "collections",
+				In:          "query",
+				Description: "Comma-separated collection IDs",
+				Style:       "form",
+				Explode:     &explodeFalse,
+				Schema:      stringArray,
+			},
+			"limit": {
+				Name:        "limit",
+				In:          "query",
+				Description: "Maximum number of items per page. Larger values are clamped to the maximum.",
+				Schema:      limitSchema,
+			},
+			"cursor": {
+				Name:        "cursor",
+				In:          "query",
+				Description: "Opaque pagination cursor taken from a previous response's next link",
+				Schema:      map[string]any{"type": "string"},
+			},
+			"sortby": {
+				Name:        "sortby",
+				In:          "query",
+				Description: "Comma-separated sort fields, prefixed with + (ascending) or - (descending)",
+				Schema:      map[string]any{"type": "string"},
+			},
+			"fields": {
+				Name:        "fields",
+				In:          "query",
+				Description: "Comma-separated dotted field paths to include, or to exclude when prefixed with -",
+				Schema:      map[string]any{"type": "string"},
+			},
+			"filter": {
+				Name:        "filter",
+				In:          "query",
+				Description: "CQL2-Text or CQL2-JSON filter expression. Supported properties: " + strings.Join(queryableNames, ", "),
+				Schema:      map[string]any{"type": "string"},
+			},
+			"filter-lang": {
+				Name:        "filter-lang",
+				In:          "query",
+				Description: "Language of the filter parameter. Detected from the filter when omitted",
+				Schema:      map[string]any{"type": "string", "enum": []string{"cql2-json", "cql2-text"}},
+			},
+			"aggregations": {
+				Name:        "aggregations",
+				In:          "query",
+				Description: "Comma-separated aggregations to compute. All are computed when omitted",
+				Style:       "form",
+				Explode:     &explodeFalse,
+				Schema: map[string]any{
+					"type":  "array",
+					"items": map[string]any{"type": "string", "enum": aggregationNames},
+				},
+			},
+			"datetime_frequency_interval": {
+				Name:        "datetime_frequency_interval",
+				In:          "query",
+				Description: "Interval of the datetime_frequency histogram",
+				Schema:      map[string]any{"type": "string", "enum": intervals, "default": intstac.DefaultDatetimeFrequencyInterval},
+			},
+			"centroid_geohash_grid_frequency_precision": {
+				Name:        "centroid_geohash_grid_frequency_precision",
+				In:          "query",
+				Description: "Geohash length of the centroid_geohash_grid_frequency cells",
+				Schema:      geohashPrecision,
+			},
+			"centroid_geotile_grid_frequency_precision": {
+				Name:        "centroid_geotile_grid_frequency_precision",
+				In:          "query",
+				Description: "Zoom level of the centroid_geotile_grid_frequency tiles",
+				Schema:      geotilePrecision,
+			},
+			"filter-crs": {
+				Name:        "filter-crs",
+				In:          "query",
+				Description: "CRS of geometries in the filter",
+				Schema:      map[string]any{"type": "string"},
+			},
+		},
+		Responses: map[string]*OpenAPIResponse{
+			"BadRequest": {
+				Description: "The request was malformed or a parameter was invalid",
+				Content:     map[string]*OpenAPIMediaItem{"application/json": {Schema: schemaRef("STACError")}},
+			},
+			"NotFound": {
+				Description: "The requested resource does not exist",
+				Content:     map[string]*OpenAPIMediaItem{"application/json": {Schema: schemaRef("STACError")}},
+			},
+			"ServerError": {
+				Description: "An unexpected server error occurred",
+				Content:     map[string]*OpenAPIMediaItem{"application/json": {Schema: schemaRef("STACError")}},
+			},
+			"UpstreamError": {
+				Description: "The upstream search service (ASF or CMR) failed",
+				Content:     map[string]*OpenAPIMediaItem{"application/json": {Schema: schemaRef("STACError")}},
+			},
+			"UpstreamUnavailable": {
+				Description: "The upstream search service is temporarily unavailable; retry after the Retry-After delay when given",
+				Content:     map[string]*OpenAPIMediaItem{"application/json": {Schema: schemaRef("STACError")}},
+			},
+		},
+	}
+}
+
+// schemaRef returns a JSON Schema $ref to a component schema.
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// paramRef returns a $ref to a component parameter.
+func paramRef(name string) OpenAPIParameter {
+	return OpenAPIParameter{Ref: "#/components/parameters/" + name}
+}
+
+// refName returns the last path segment of a $ref, for display.
+func refName(ref string) string {
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		return ref[i+1:]
+	}
+	return ref
+}
+
+// toIndentedJSON renders a value as indented JSON for display.
+func toIndentedJSON(v any) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
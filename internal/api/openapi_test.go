@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func createOpenAPITestHandlers() *Handlers {
+	cfg := createTestConfig()
+	cfg.STAC.Title = "ASF STAC API"
+	cfg.STAC.Version = "1.0.0"
+	cfg.Features.EnableSearch = true
+	cfg.Features.EnableQueryables = true
+	cfg.Features.DefaultLimit = 25
+	cfg.Features.MaxLimit = 500
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewHandlers(cfg, &mockBackend{}, nil, createQueryablesTestCollections(), logger)
+}
+
+func TestOpenAPI_DocumentsAllRoutes(t *testing.T) {
+	h := createOpenAPITestHandlers()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := NewRouter(h, logger)
+
+	doc := h.buildOpenAPIDocument(context.Background())
+
+	err := chi.Walk(router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		path := route
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("route %s %s is not documented", method, route)
+			return nil
+		}
+
+		switch method {
+		case http.MethodGet:
+			if item.Get == nil {
+				t.Errorf("route GET %s has no documented operation", path)
+			}
+		case http.MethodHead:
+			if item.Head == nil {
+				t.Errorf("route HEAD %s has no documented operation", path)
+			}
+		case http.MethodPost:
+			if item.Post == nil {
+				t.Errorf("route POST %s has no documented operation", path)
+			}
+		default:
+			t.Errorf("unexpected method %s on %s", method, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk router: %v", err)
+	}
+}
+
+func TestOpenAPI_ServedWithOpenAPIMediaType(t *testing.T) {
+	h := createOpenAPITestHandlers()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := NewRouter(h, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != OpenAPIMediaType {
+		t.Errorf("Expected Content-Type %q, got %q", OpenAPIMediaType, ct)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to parse OpenAPI document: %v", err)
+	}
+	if doc["openapi"] != "3.0.3" {
+		t.Errorf("Expected openapi 3.0.3, got %v", doc["openapi"])
+	}
+}
+
+func TestOpenAPI_LimitReflectsFeatureConfig(t *testing.T) {
+	h := createOpenAPITestHandlers()
+	doc := h.buildOpenAPIDocument(context.Background())
+
+	limit := doc.Components.Parameters["limit"]
+	if limit == nil {
+		t.Fatal("Expected limit parameter component")
+	}
+	if limit.Schema["maximum"] != 500 {
+		t.Errorf("Expected limit maximum 500, got %v", limit.Schema["maximum"])
+	}
+	if limit.Schema["default"] != 25 {
+		t.Errorf("Expected limit default 25, got %v", limit.Schema["default"])
+	}
+}
+
+func TestOpenAPI_IncludesQueryablesAndErrorSchema(t *testing.T) {
+	h := createOpenAPITestHandlers()
+	doc := h.buildOpenAPIDocument(context.Background())
+
+	queryables, ok := doc.Components.Schemas["Queryables"]["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected Queryables schema with properties")
+	}
+	platform, ok := queryables["platform"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected platform queryable")
+	}
+	if _, ok := platform["enum"]; !ok {
+		t.Error("Expected platform queryable to carry enum values aggregated from collections")
+	}
+
+	stacError, ok := doc.Components.Schemas["STACError"]
+	if !ok {
+		t.Fatal("Expected STACError schema")
+	}
+	props := stacError["properties"].(map[string]interface{})
+	for _, field := range []string{"code", "description", "request_id"} {
+		if _, ok := props[field]; !ok {
+			t.Errorf("Expected STACError schema to have %q", field)
+		}
+	}
+
+	// Every error response must reference STACError
+	for name, resp := range doc.Components.Responses {
+		schema := resp.Content["application/json"].Schema
+		if schema["$ref"] != "#/components/schemas/STACError" {
+			t.Errorf("Expected response %s to reference STACError, got %v", name, schema["$ref"])
+		}
+	}
+}
+
+func TestOpenAPI_QueryablesOmittedWhenDisabled(t *testing.T) {
+	h := createOpenAPITestHandlers()
+	h.cfg.Features.EnableQueryables = false
+	doc := h.buildOpenAPIDocument(context.Background())
+
+	if _, ok := doc.Paths["/queryables"]; ok {
+		t.Error("Expected /queryables to be omitted when queryables are disabled")
+	}
+}
+
+func TestAPIDocs_RendersSelfContainedHTML(t *testing.T) {
+	h := createOpenAPITestHandlers()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := NewRouter(h, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/api.html", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected text/html Content-Type, got %q", ct)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{"/collections/{collectionId}/items", "postItemSearch", "STACError"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected HTML docs to contain %q", want)
+		}
+	}
+
+	// The page must work offline: no external scripts or stylesheets
+	for _, external := range []string{"<script src=", "<link rel=\"stylesheet\"", "cdn."} {
+		if strings.Contains(body, external) {
+			t.Errorf("Expected no external resources, found %q", external)
+		}
+	}
+}
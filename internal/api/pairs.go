@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	intstac "github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/translate"
+)
+
+// maxPairScanPages bounds the pages of MaxLimit items scanned for pairs.
+// Pairs are computed over every matching item, so larger searches must be
+// narrowed rather than answered in part.
+const maxPairScanPages = 10
+
+// maxPairBaselineGroups bounds the tracks and frames whose perpendicular
+// baselines are looked up for one pairs request, each a stack lookup
+const maxPairBaselineGroups = 20
+
+// errPairSearchTooBroad is returned for pair searches beyond the scan bounds
+var errPairSearchTooBroad = errors.New("search matches too many items to pair")
+
+// Pairs finds candidate interferogram pairs among the items matching a
+// search: items of the same collection, relative orbit and frame whose
+// footprints overlap, within max_temporal_baseline days and, when set,
+// max_perpendicular_baseline meters of each other. Perpendicular baselines
+// come from the stack of each track and frame, so bounding them requires a
+// backend with stacks. Pairs are listed in acquisition order and paged by a
+// cursor holding the last pair of the page, so that later pages only scan
+// items from its reference on and are not shifted by new items.
+// GET/POST /pairs
+func (h *Handlers) Pairs(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "Handlers.Pairs")
+	defer span.End()
+
+	if !h.cfg.Features.EnableSearch {
+		WriteError(w, http.StatusNotImplemented, "NotImplemented", "search endpoint is disabled")
+		return
+	}
+
+	var req *intstac.PairsRequest
+	var err error
+	if r.Method == http.MethodPost {
+		req, err = intstac.ParsePairsRequestBody(r.Body)
+		defer r.Body.Close()
+	} else {
+		req, err = intstac.ParsePairsRequest(r)
+	}
+	if err != nil {
+		WriteInvalidParameter(w, fmt.Sprintf("invalid pairs request: %v", err))
+		return
+	}
+
+	// The limit applies to pairs; items are scanned in full pages
+	limit := req.Limit
+	if limit == 0 {
+		limit = h.cfg.Features.DefaultLimit
+	}
+	if limit > h.cfg.Features.MaxLimit {
+		limit = h.cfg.Features.MaxLimit
+	}
+	fingerprint := req.Fingerprint()
+
+	var after *intstac.PairKey
+	if req.Cursor != "" {
+		cursor, err := intstac.DecodeCursorSealed(req.Cursor, h.keyring, h.cursorStore)
+		if err == nil && cursor.Query != fingerprint {
+			err = fmt.Errorf("cursor was issued for a different query")
+		}
+		if err != nil {
+			WriteBadRequest(w, fmt.Sprintf("invalid or expired cursor: %s", err.Error()))
+			return
+		}
+		if cursor.PairAfter == nil {
+			WriteBadRequest(w, "invalid or expired cursor: not a pairs cursor")
+			return
+		}
+		after = cursor.PairAfter
+	}
+
+	var stacker backend.Stacker
+	if req.MaxPerpendicularBaseline != nil {
+		var ok bool
+		if stacker, ok = h.backend.(backend.Stacker); !ok {
+			WriteError(w, http.StatusNotImplemented, "NotImplemented",
+				fmt.Sprintf("the %s backend cannot bound perpendicular baselines", h.backend.Name()))
+			return
+		}
+	}
+
+	for _, collID := range req.Collections {
+		if !h.collectionVisible(ctx, collID) {
+			WriteNotFound(w, fmt.Sprintf("collection %q not found", collID))
+			return
+		}
+	}
+
+	// Pairs are found among all matching items in an order of their own
+	req.Cursor = ""
+	req.Sortby = nil
+	req.Limit = h.cfg.Features.MaxLimit
+
+	backendParams, plan, err := h.buildBackendParams(req.SearchRequest, "")
+	if err != nil {
+		WriteInvalidParameter(w, fmt.Sprintf("invalid filter: %v", err))
+		return
+	}
+	if !h.restrictToVisible(ctx, backendParams, plan) {
+		writeNoVisibleCollections(w, r)
+		return
+	}
+	// The pairs after the cursor are between items acquired from its
+	// reference on, so earlier ones need not be scanned again
+	if after != nil && (backendParams.Start == nil || after.ReferenceDatetime.After(*backendParams.Start)) {
+		start := after.ReferenceDatetime
+		backendParams.Start = &start
+	}
+
+	var items []*intstac.Item
+	complete, _, err := h.scanItems(ctx, backendParams, plan, maxPairScanPages, func(item *intstac.Item) {
+		items = append(items, item)
+	})
+	if err == nil && !complete {
+		err = errPairSearchTooBroad
+	}
+
+	var pairs []*intstac.Pair
+	if err == nil {
+		span.SetAttribute("pairs.items", len(items))
+		groups := 0
+		pairs, err = intstac.FindPairs(items, req, func(group []*intstac.Item) (map[string]float64, error) {
+			if groups++; groups > maxPairBaselineGroups {
+				return nil, errPairSearchTooBroad
+			}
+			return perpendicularBaselines(ctx, stacker, group)
+		})
+	}
+	if err != nil {
+		h.logger.Error("pair search failed",
+			slog.String("backend", h.backend.Name()),
+			slog.String("error", err.Error()),
+		)
+
+		switch {
+		case errors.Is(err, errPairSearchTooBroad):
+			WriteBadRequest(w, fmt.Sprintf("%v: narrow the search to at most %d items and %d tracks and frames",
+				err, maxPairScanPages*h.cfg.Features.MaxLimit, maxPairBaselineGroups))
+		case errors.Is(err, backend.ErrNotSupported):
+			WriteError(w, http.StatusNotImplemented, "NotImplemented",
+				fmt.Sprintf("the %s backend cannot bound perpendicular baselines", h.backend.Name()))
+		case errors.Is(err, translate.ErrCollectionNotFound):
+			WriteNotFound(w, "one or more collections not found")
+		default:
+			WriteUpstreamFailure(w, err, "upstream search service error")
+		}
+		return
+	}
+	// Only the first page scans every item, so only it knows the total
+	var matched *int
+	if after == nil {
+		total := len(pairs)
+		matched = &total
+		span.SetAttribute("pairs.matched", total)
+	} else {
+		pairs = intstac.PairsAfter(pairs, *after)
+	}
+	page := pairs[:min(limit, len(pairs))]
+
+	baseURL := h.cfg.STAC.BaseURL
+	for _, pair := range page {
+		pair.AddLink("reference", itemURL(baseURL, pair.Collection, pair.Reference), "application/geo+json")
+		pair.AddLink("secondary", itemURL(baseURL, pair.Collection, pair.Secondary), "application/geo+json")
+	}
+
+	result := intstac.NewPairCollection(page, matched)
+	pairsURL := baseURL + "/pairs"
+	result.AddLink("self", pairsURL, "application/json")
+	result.AddLink("root", baseURL+"/", "application/json")
+
+	if len(page) < len(pairs) {
+		// For POST requests, the pairing parameters come from the body
+		queryParams := r.URL.Query()
+		if r.Method == http.MethodPost {
+			queryParams = req.ToQueryParams()
+		}
+		last := page[len(page)-1].Key()
+		nextURL, err := h.cursorNextURL(pairsURL, queryParams, &intstac.Cursor{
+			Direction: "next",
+			PairAfter: &last,
+		}, limit, fingerprint)
+		if err != nil {
+			h.logger.Error("failed to encode cursor", slog.String("error", err.Error()))
+		} else {
+			result.AddLink("next", nextURL, "application/json")
+		}
+	}
+
+	_, encodeSpan := tracing.Start(ctx, "encode response")
+	WriteJSON(w, http.StatusOK, result)
+	encodeSpan.End()
+}
+
+// perpendicularBaselines looks up the perpendicular baselines of a track and
+// frame, relative to its first item, from that item's stack
+func perpendicularBaselines(ctx context.Context, stacker backend.Stacker, group []*intstac.Item) (map[string]float64, error) {
+	reference := group[0]
+	result, err := stacker.Stack(ctx, reference.Collection, reference.Id)
+	if errors.Is(err, backend.ErrNoStack) {
+		// Products without baselines pair with nothing
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	baselines := make(map[string]float64, len(result.Items))
+	for _, item := range result.Items {
+		if v, ok := baselineValue(item, perpendicularBaselineProperty); ok {
+			baselines[item.Id] = v
+		}
+	}
+	return baselines, nil
+}
+
+// itemURL returns the URL of an item
+func itemURL(baseURL, collectionID, itemID string) string {
+	return fmt.Sprintf("%s/collections/%s/items/%s", baseURL, url.PathEscape(collectionID), url.PathEscape(itemID))
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	gostac "github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+)
+
+// pairsStackBackend serves the perpendicular baselines of the pairs test
+// items as stacks
+type pairsStackBackend struct {
+	mockBackend
+	stackCalls []string
+}
+
+func (b *pairsStackBackend) Stack(ctx context.Context, collection, itemID string) (*backend.SearchResult, error) {
+	b.stackCalls = append(b.stackCalls, itemID)
+
+	baselines := map[string]float64{"a": 0, "b": 30, "c": 200}
+	var items []*gostac.Item
+	for _, item := range b.items {
+		if v, ok := baselines[item.Id]; ok {
+			stackItem := *item
+			stackItem.Properties = map[string]any{"insar:perpendicular_baseline": v - baselines[itemID]}
+			items = append(items, &stackItem)
+		}
+	}
+	return &backend.SearchResult{Items: items}, nil
+}
+
+// createPairsTestItems returns scenes 12 days apart on track 64, frame 100,
+// with one out of reach of the default temporal baseline, and two scenes on
+// track 137 whose footprints do not overlap
+func createPairsTestItems() []*gostac.Item {
+	reference := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	scenes := []struct {
+		id    string
+		orbit int
+		days  int
+		lon   float64
+	}{
+		{"a", 64, 0, 0},
+		{"b", 64, 12, 0.1},
+		{"c", 64, 24, 0.2},
+		{"d", 64, 96, 0},
+		{"x", 137, 0, 0},
+		{"y", 137, 12, 10},
+	}
+	items := make([]*gostac.Item, len(scenes))
+	for i, scene := range scenes {
+		item := createTestItem(scene.id, reference.AddDate(0, 0, scene.days))
+		item.Geometry = map[string]any{
+			"type": "Polygon",
+			"coordinates": [][][]float64{{
+				{scene.lon, 0}, {scene.lon + 1, 0}, {scene.lon + 1, 1}, {scene.lon, 1}, {scene.lon, 0},
+			}},
+		}
+		item.Properties["sat:relative_orbit"] = scene.orbit
+		item.Properties["asf:frame"] = 100
+		items[i] = item
+	}
+	return items
+}
+
+func newPairsTestRouter(b backend.SearchBackend) http.Handler {
+	cfg := createTestConfig()
+	cfg.Features.EnableSearch = true
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewRouter(NewHandlers(cfg, b, nil, createTestCollections(), logger), logger)
+}
+
+func decodePairs(t *testing.T, w *httptest.ResponseRecorder) *stac.PairCollection {
+	t.Helper()
+	var pc stac.PairCollection
+	if err := json.Unmarshal(w.Body.Bytes(), &pc); err != nil {
+		t.Fatalf("Failed to decode pairs: %v", err)
+	}
+	return &pc
+}
+
+func pairCollectionIDs(pc *stac.PairCollection) []string {
+	ids := make([]string, len(pc.Pairs))
+	for i, p := range pc.Pairs {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func TestPairs(t *testing.T) {
+	router := newPairsTestRouter(&mockBackend{items: createPairsTestItems()})
+
+	w := serveFrom(router, http.MethodGet, "/pairs?collections=sentinel-1", "192.0.2.1:1234", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	pc := decodePairs(t, w)
+
+	want := []string{"a_b", "a_c", "b_c"}
+	if ids := pairCollectionIDs(pc); fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("Expected pairs %v, got %v", want, ids)
+	}
+	if pc.NumberMatched == nil || *pc.NumberMatched != 3 || pc.NumberReturned != 3 {
+		t.Errorf("Expected 3 matched and returned, got %v and %d", pc.NumberMatched, pc.NumberReturned)
+	}
+
+	pair := pc.Pairs[0]
+	if pair.TemporalBaseline != 12 || pair.PerpendicularBaseline != nil {
+		t.Errorf("Unexpected baselines %d, %v", pair.TemporalBaseline, pair.PerpendicularBaseline)
+	}
+	links := map[string]string{}
+	for _, link := range pair.Links {
+		links[link.Rel] = link.Href
+	}
+	if links["reference"] != "http://test.example.com/collections/sentinel-1/items/a" ||
+		links["secondary"] != "http://test.example.com/collections/sentinel-1/items/b" {
+		t.Errorf("Unexpected pair links %v", links)
+	}
+
+	w = serveFrom(router, http.MethodGet, "/pairs?collections=sentinel-1&max_temporal_baseline=12", "192.0.2.1:1234", nil)
+	if ids := pairCollectionIDs(decodePairs(t, w)); fmt.Sprint(ids) != "[a_b b_c]" {
+		t.Errorf("Expected pairs within 12 days, got %v", ids)
+	}
+}
+
+func TestPairs_Pagination(t *testing.T) {
+	mock := &mockBackend{items: createPairsTestItems()}
+	router := newPairsTestRouter(mock)
+	first := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var ids []string
+	target := "/pairs?collections=sentinel-1&limit=1"
+	for page := 0; target != ""; page++ {
+		if page > 3 {
+			t.Fatal("Expected pagination to end")
+		}
+		w := serveFrom(router, http.MethodGet, target, "192.0.2.1:1234", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		pc := decodePairs(t, w)
+		ids = append(ids, pairCollectionIDs(pc)...)
+
+		if page == 0 {
+			// A scene acquired before the first page's pairs and published
+			// since neither shifts nor repeats the pages that follow
+			earlier := createTestItem("0", first.AddDate(0, 0, -12))
+			earlier.Geometry = mock.items[0].Geometry
+			earlier.Properties["sat:relative_orbit"] = 64
+			earlier.Properties["asf:frame"] = 100
+			mock.items = append([]*gostac.Item{earlier}, mock.items...)
+		} else {
+			// Later pages only scan scenes from the last pair's reference on,
+			// and cannot count the pairs before them
+			if start := mock.searchCalls[len(mock.searchCalls)-1].Start; start == nil || !start.Equal(first) {
+				t.Errorf("Page %d: expected the scan to start at %s, got %v", page, first, start)
+			}
+			if pc.NumberMatched != nil {
+				t.Errorf("Page %d: expected no numberMatched, got %d", page, *pc.NumberMatched)
+			}
+		}
+
+		target = ""
+		for _, link := range pc.Links {
+			if link.Rel == "next" {
+				next, _ := url.Parse(link.Href)
+				target = next.RequestURI()
+			}
+		}
+	}
+	if fmt.Sprint(ids) != "[a_b a_c b_c]" {
+		t.Errorf("Expected every pair once across pages, got %v", ids)
+	}
+}
+
+func TestPairs_CursorForOtherQuery(t *testing.T) {
+	router := newPairsTestRouter(&mockBackend{items: createPairsTestItems()})
+
+	w := serveFrom(router, http.MethodGet, "/pairs?collections=sentinel-1&limit=1", "192.0.2.1:1234", nil)
+	var cursor string
+	for _, link := range decodePairs(t, w).Links {
+		if link.Rel == "next" {
+			next, _ := url.Parse(link.Href)
+			cursor = next.Query().Get("cursor")
+		}
+	}
+	if cursor == "" {
+		t.Fatal("Expected a next link")
+	}
+
+	w = serveFrom(router, http.MethodGet, "/pairs?collections=sentinel-1&limit=1&max_temporal_baseline=12&cursor="+url.QueryEscape(cursor), "192.0.2.1:1234", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPairs_Post(t *testing.T) {
+	router := newPairsTestRouter(&mockBackend{items: createPairsTestItems()})
+
+	body := `{"collections": ["sentinel-1"], "max_temporal_baseline": 12, "limit": 1}`
+	req := httptest.NewRequest(http.MethodPost, "/pairs", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	pc := decodePairs(t, w)
+	if ids := pairCollectionIDs(pc); fmt.Sprint(ids) != "[a_b]" {
+		t.Errorf("Expected the first pair, got %v", ids)
+	}
+
+	// The next link carries the body's constraints
+	var next string
+	for _, link := range pc.Links {
+		if link.Rel == "next" {
+			next = link.Href
+		}
+	}
+	if !strings.Contains(next, "max_temporal_baseline=12") {
+		t.Errorf("Expected the next link to keep the temporal baseline, got %q", next)
+	}
+}
+
+func TestPairs_PerpendicularBaseline(t *testing.T) {
+	mock := &pairsStackBackend{mockBackend: mockBackend{items: createPairsTestItems()}}
+	router := newPairsTestRouter(mock)
+
+	w := serveFrom(router, http.MethodGet, "/pairs?collections=sentinel-1&max_perpendicular_baseline=100", "192.0.2.1:1234", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	pc := decodePairs(t, w)
+	if ids := pairCollectionIDs(pc); fmt.Sprint(ids) != "[a_b]" {
+		t.Fatalf("Expected only the short baseline pair, got %v", ids)
+	}
+	if b := pc.Pairs[0].PerpendicularBaseline; b == nil || *b != 30 {
+		t.Errorf("Expected a perpendicular baseline of 30, got %v", b)
+	}
+	// One stack per track and frame, from its earliest scene
+	if fmt.Sprint(mock.stackCalls) != "[x a]" {
+		t.Errorf("Expected stacks of x and a, got %v", mock.stackCalls)
+	}
+}
+
+func TestPairs_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend backend.SearchBackend
+		target  string
+		want    int
+	}{
+		{"perpendicular baseline without stacks", &mockBackend{}, "/pairs?max_perpendicular_baseline=100", http.StatusNotImplemented},
+		{"negative temporal baseline", &mockBackend{}, "/pairs?max_temporal_baseline=-1", http.StatusBadRequest},
+		{"unknown collection", &mockBackend{}, "/pairs?collections=nisar", http.StatusNotFound},
+		{"invalid cursor", &mockBackend{}, "/pairs?cursor=bogus", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveFrom(newPairsTestRouter(tt.backend), http.MethodGet, tt.target, "192.0.2.1:1234", nil)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPairs_SearchDisabled(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{STAC: config.STACConfig{BaseURL: "http://test.example.com"}}
+	router := NewRouter(NewHandlers(cfg, &mockBackend{}, nil, createTestCollections(), logger), logger)
+
+	w := serveFrom(router, http.MethodGet, "/pairs", "192.0.2.1:1234", nil)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d: %s", w.Code, w.Body.String())
+	}
+}
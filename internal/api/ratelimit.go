@@ -0,0 +1,129 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/auth"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/ratelimit"
+)
+
+// Rate limit response headers, following the IETF RateLimit header fields draft.
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// RateLimits holds the token buckets throttling each client, keyed by its
+// authenticated subject, else by IP. Routes may have their own limits; the
+// others share the default ones.
+type RateLimits struct {
+	defaults *ratelimit.Limiter
+	routes   map[string]*ratelimit.Limiter
+}
+
+// NewRateLimits creates limits allowing each client rate requests per second
+// with bursts of up to burst requests.
+func NewRateLimits(rate float64, burst int) *RateLimits {
+	return &RateLimits{
+		defaults: ratelimit.NewLimiter(rate, burst),
+		routes:   make(map[string]*ratelimit.Limiter),
+	}
+}
+
+// WithRoute sets the limits of the route with the given chi pattern, such as
+// "/collections/{collectionId}/items".
+func (l *RateLimits) WithRoute(pattern string, rate float64, burst int) *RateLimits {
+	l.routes[normalizeRoutePattern(pattern)] = ratelimit.NewLimiter(rate, burst)
+	return l
+}
+
+// allow takes a token from the client's bucket for the route
+func (l *RateLimits) allow(r *http.Request, pattern string) ratelimit.Decision {
+	limiter, ok := l.routes[pattern]
+	if !ok {
+		limiter = l.defaults
+	}
+	return limiter.Allow(l.clientKey(r))
+}
+
+// clientKey identifies the client making r. Only credentials the
+// authenticator verified count: keys anonymous clients make up would each get
+// a fresh bucket, so those clients are told apart by IP
+func (l *RateLimits) clientKey(r *http.Request) string {
+	if p := auth.FromContext(r.Context()); p != nil && p.Subject != "" {
+		return p.Method + ":" + p.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RealIP replaces RemoteAddr with a bare IP
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// operationalRoutes are neither authenticated nor throttled, so probes keep
+// working while clients are. /metrics is not one of them: it describes the
+// traffic of every client, so scrapers authenticate like clients
+var operationalRoutes = map[string]bool{
+	"/health": true,
+}
+
+// RateLimit creates a middleware throttling clients with limits. Every
+// response carries the RateLimit-* headers of the client's bucket; requests
+// over the limit get a 429 Too Many Requests with a Retry-After header.
+func RateLimit(limits *RateLimits) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := routePatternOf(r)
+			if operationalRoutes[pattern] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			d := limits.allow(r, pattern)
+			h := w.Header()
+			h.Set(RateLimitLimitHeader, strconv.Itoa(d.Limit))
+			h.Set(RateLimitRemainingHeader, strconv.Itoa(d.Remaining))
+			h.Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(d.Reset)))
+			if !d.Allowed {
+				h.Set("Retry-After", strconv.Itoa(max(ceilSeconds(d.RetryAfter), 1)))
+				WriteErrorWithRequestID(w, http.StatusTooManyRequests, ErrCodeTooManyRequests,
+					"rate limit exceeded, retry later", GetRequestID(r.Context()))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routePatternOf looks up the chi pattern of the route r will be routed to,
+// which is not known yet in a middleware of the top-level router
+func routePatternOf(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return ""
+	}
+	return normalizeRoutePattern(rctx.Routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path))
+}
+
+// normalizeRoutePattern drops the trailing slash of subrouter patterns, so
+// "/search/" is configured as "/search"
+func normalizeRoutePattern(pattern string) string {
+	if pattern != "/" {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
+// ceilSeconds rounds d up to whole seconds, capped to a day for buckets that
+// never refill
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(min(d, 24*time.Hour).Seconds()))
+}
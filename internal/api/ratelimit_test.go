@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gostac "github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/auth"
+)
+
+func newRateLimitedRouter(limits *RateLimits) http.Handler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	items := []*gostac.Item{createTestItem("item-1", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+	h := NewHandlers(createTestConfig(), &mockBackend{items: items}, nil, createTestCollections(), logger).
+		WithRateLimits(limits)
+	return NewRouter(h, logger)
+}
+
+func serveFrom(router http.Handler, method, target, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.RemoteAddr = remoteAddr
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_Throttles(t *testing.T) {
+	// A slow refill keeps the test from racing the clock
+	router := newRateLimitedRouter(NewRateLimits(0.01, 2))
+
+	for i := 0; i < 2; i++ {
+		w := serveFrom(router, http.MethodGet, "/collections", "192.0.2.1:1234", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i+1, w.Code)
+		}
+		if w.Header().Get(RateLimitLimitHeader) != "2" || w.Header().Get(RateLimitRemainingHeader) != []string{"1", "0"}[i] {
+			t.Errorf("request %d: unexpected rate limit headers %v", i+1, w.Header())
+		}
+	}
+
+	w := serveFrom(router, http.MethodGet, "/collections", "192.0.2.1:5678", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "100" || w.Header().Get(RateLimitResetHeader) != "200" {
+		t.Errorf("Unexpected Retry-After %q and reset %q", w.Header().Get("Retry-After"), w.Header().Get(RateLimitResetHeader))
+	}
+	var stacErr STACError
+	if err := json.NewDecoder(w.Body).Decode(&stacErr); err != nil || stacErr.Code != ErrCodeTooManyRequests {
+		t.Errorf("Expected a %s STAC error, got %+v (%v)", ErrCodeTooManyRequests, stacErr, err)
+	}
+
+	// Other clients and exempt routes are unaffected
+	if w := serveFrom(router, http.MethodGet, "/collections", "192.0.2.2:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected another client to get status 200, got %d", w.Code)
+	}
+	if w := serveFrom(router, http.MethodGet, "/health", "192.0.2.1:1234", nil); w.Code != http.StatusOK || w.Header().Get(RateLimitLimitHeader) != "" {
+		t.Errorf("Expected /health to be exempt, got status %d", w.Code)
+	}
+}
+
+func TestRateLimit_PerRoute(t *testing.T) {
+	router := newRateLimitedRouter(NewRateLimits(0.01, 5).
+		WithRoute("/search", 0.01, 1).
+		WithRoute("/collections/{collectionId}/items", 0.01, 3))
+
+	tests := []struct {
+		method string
+		target string
+		limit  string
+	}{
+		{http.MethodGet, "/search", "1"},
+		{http.MethodPost, "/search/", "1"},
+		{http.MethodGet, "/collections/sentinel-1/items", "3"},
+		{http.MethodGet, "/collections/sentinel-1", "5"},
+		{http.MethodGet, "/nowhere", "5"},
+	}
+	for _, tt := range tests {
+		w := serveFrom(router, tt.method, tt.target, "192.0.2.1:1234", nil)
+		if got := w.Header().Get(RateLimitLimitHeader); got != tt.limit {
+			t.Errorf("%s %s: expected limit %s, got %q", tt.method, tt.target, tt.limit, got)
+		}
+	}
+
+	// GET and POST /search share the route's bucket, which is now empty
+	if w := serveFrom(router, http.MethodGet, "/search", "192.0.2.1:1234", nil); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 on /search, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_APIKey(t *testing.T) {
+	// Without an authenticator API keys are unverified, so clients making
+	// up a new key for each request still share the bucket of their IP
+	router := newRateLimitedRouter(NewRateLimits(0.01, 1))
+	if w := serveFrom(router, http.MethodGet, "/", "192.0.2.1:1234", apiKeyHeader("made-up-1")); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w := serveFrom(router, http.MethodGet, "/", "192.0.2.1:1234", apiKeyHeader("made-up-2")); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for an unverified key from the same IP, got %d", w.Code)
+	}
+
+	// Authenticated clients are told apart by subject
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	keys := `[{"subject": "alice", "key": "alice-key"}, {"subject": "bob", "key": "bob-key"}]`
+	if err := os.WriteFile(keysFile, []byte(keys), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	apiKeys, err := auth.LoadAPIKeys(keysFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandlers(createTestConfig(), &mockBackend{}, nil, createTestCollections(), logger).
+		WithAuthenticator(auth.NewAuthenticator("X-API-Key").WithAPIKeys(apiKeys)).
+		WithRateLimits(NewRateLimits(0.01, 1))
+	router = NewRouter(h, logger)
+
+	if w := serveFrom(router, http.MethodGet, "/", "192.0.2.1:1234", apiKeyHeader("alice-key")); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	// Clients behind the same IP are told apart by their key
+	if w := serveFrom(router, http.MethodGet, "/", "192.0.2.1:1234", apiKeyHeader("bob-key")); w.Code != http.StatusOK {
+		t.Errorf("Expected another key to get status 200, got %d", w.Code)
+	}
+	if w := serveFrom(router, http.MethodGet, "/", "192.0.2.1:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected a client without a key to get status 200, got %d", w.Code)
+	}
+	// and a key is limited wherever it comes from
+	if w := serveFrom(router, http.MethodGet, "/", "198.51.100.7:1234", apiKeyHeader("alice-key")); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for a key over its limit, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_RealIP(t *testing.T) {
+	router := newRateLimitedRouter(NewRateLimits(0.01, 1))
+	forwarded := func(ip string) http.Header { return http.Header{"X-Forwarded-For": {ip}} }
+
+	// Clients behind the same proxy are told apart by their forwarded IP
+	if w := serveFrom(router, http.MethodGet, "/", "10.0.0.1:1234", forwarded("192.0.2.1")); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w := serveFrom(router, http.MethodGet, "/", "10.0.0.1:1234", forwarded("192.0.2.2")); w.Code != http.StatusOK {
+		t.Errorf("Expected another forwarded client to get status 200, got %d", w.Code)
+	}
+	if w := serveFrom(router, http.MethodGet, "/", "10.0.0.2:1234", forwarded("192.0.2.1")); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for a forwarded client over its limit, got %d", w.Code)
+	}
+}
@@ -3,8 +3,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/ratelimit"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
 )
 
 // STACError represents a STAC-compliant error response.
@@ -21,6 +28,10 @@ const (
 	ErrCodeInvalidParameter = "InvalidParameterValue"
 	ErrCodeServerError      = "ServerError"
 	ErrCodeUpstreamError    = "UpstreamServiceError"
+	ErrCodeUpstreamBusy     = "UpstreamServiceUnavailable"
+	ErrCodeTooManyRequests  = "TooManyRequests"
+	ErrCodeUnauthorized     = "Unauthorized"
+	ErrCodeForbidden        = "Forbidden"
 )
 
 // WriteJSON writes a JSON response with the given status code and value.
@@ -108,3 +119,31 @@ func WriteInternalErrorWithRequestID(w http.ResponseWriter, message, requestID s
 func WriteUpstreamError(w http.ResponseWriter, message string) {
 	WriteError(w, http.StatusBadGateway, ErrCodeUpstreamError, message)
 }
+
+// WriteUpstreamFailure writes the error response for a failed upstream
+// request. Transient failures that outlasted the retries, and requests no
+// backend could take because every circuit breaker is open, are reported as
+// 503 Service Unavailable, with the upstream Retry-After when it sent one.
+// Requests that found every upstream call slot taken are a 429 Too Many
+// Requests; other failures are a 502 Bad Gateway.
+func WriteUpstreamFailure(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, ratelimit.ErrSaturated) {
+		w.Header().Set("Retry-After", "1")
+		WriteError(w, http.StatusTooManyRequests, ErrCodeTooManyRequests, message)
+		return
+	}
+	if errors.Is(err, backend.ErrNoBackendAvailable) {
+		WriteError(w, http.StatusServiceUnavailable, ErrCodeUpstreamBusy, message)
+		return
+	}
+	var upstreamErr *upstream.Error
+	if !errors.As(err, &upstreamErr) || !upstreamErr.Retryable {
+		WriteUpstreamError(w, message)
+		return
+	}
+	if upstreamErr.RetryAfter > 0 {
+		seconds := int(math.Ceil(upstreamErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	WriteError(w, http.StatusServiceUnavailable, ErrCodeUpstreamBusy, message)
+}
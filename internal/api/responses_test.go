@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/ratelimit"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
+)
+
+func TestWriteUpstreamFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter string
+	}{
+		{"terminal", fmt.Errorf("ASF search failed: %w", &upstream.Error{Service: "ASF", StatusCode: 400}), http.StatusBadGateway, ""},
+		{"unstructured", errors.New("boom"), http.StatusBadGateway, ""},
+		{"retries exhausted", fmt.Errorf("CMR search failed: %w", &upstream.Error{Service: "CMR", StatusCode: 503, Retryable: true}), http.StatusServiceUnavailable, ""},
+		{"rate limited", &upstream.Error{Service: "ASF", StatusCode: 429, Retryable: true, RetryAfter: 1500 * time.Millisecond}, http.StatusServiceUnavailable, "2"},
+		{"concurrency cap", fmt.Errorf("ASF search failed: ASF: %w", ratelimit.ErrSaturated), http.StatusTooManyRequests, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			WriteUpstreamFailure(w, tt.err, "upstream search service error")
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Expected Retry-After %q, got %q", tt.retryAfter, got)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
 )
 
 // NewRouter creates and configures the HTTP router with all routes and middleware.
@@ -16,25 +17,59 @@ func NewRouter(h *Handlers, logger *slog.Logger) chi.Router {
 	// Add middleware stack
 	r.Use(middleware.RequestID)
 	r.Use(RequestIDResponse) // Add X-Request-ID to response headers
+	r.Use(ActiveBackend(h.backend))
 	r.Use(middleware.RealIP)
+	if h.tracer != nil {
+		r.Use(Tracing(h.tracer))
+	}
 	r.Use(RequestLogger(logger))
+	if h.metrics != nil {
+		r.Use(RequestMetrics(h.metrics))
+	}
 	r.Use(Recovery(logger))
-	r.Use(middleware.Compress(5)) // Gzip compression
+	r.Use(Compress(5)) // Gzip compression, except for asset downloads
 	r.Use(ContentTypeJSON)
 
 	// CORS configuration
+	allowedHeaders := []string{"Accept", "Content-Type", "Content-Length", "traceparent", "tracestate"}
+	if h.auth != nil {
+		allowedHeaders = append(allowedHeaders, h.auth.CredentialHeaders()...)
+	}
+	exposedHeaders := []string{"Link", "X-Request-ID", "X-Backend", "Retry-After", RateLimitLimitHeader, RateLimitRemainingHeader, RateLimitResetHeader}
+	if h.downloads != nil {
+		allowedHeaders = append(allowedHeaders, "Range", "If-Range", h.cfg.Download.TokenHeader)
+		exposedHeaders = append(exposedHeaders, "Content-Range", "Accept-Ranges", "Content-Disposition")
+	}
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"}, // Allow all origins for STAC API
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Content-Type", "Content-Length"},
-		ExposedHeaders:   []string{"Link", "X-Request-ID"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "OPTIONS"},
+		AllowedHeaders:   allowedHeaders,
+		ExposedHeaders:   exposedHeaders,
 		AllowCredentials: false,
 		MaxAge:           300, // 5 minutes
 	}))
 
-	// Health check endpoint (before any other middleware)
+	// Authentication (if configured), after CORS so preflights need no credentials
+	if h.auth != nil {
+		r.Use(Authenticate(h.auth, logger))
+	}
+
+	// Per-client rate limits (if enabled), after CORS so preflights are free,
+	// and after authentication so clients are limited by subject
+	if h.rateLimits != nil {
+		r.Use(RateLimit(h.rateLimits))
+	}
+
+	// Health check endpoint, behind the middleware above but exempt from
+	// authentication and rate limits as an operational route
 	r.Get("/health", h.Health)
 
+	// Prometheus metrics (if enabled), authenticated and throttled like the
+	// STAC routes
+	if h.metrics != nil {
+		r.Method(http.MethodGet, "/metrics", h.metrics.Handler())
+	}
+
 	// STAC API routes
 
 	// Landing page
@@ -43,6 +78,10 @@ func NewRouter(h *Handlers, logger *slog.Logger) chi.Router {
 	// Conformance
 	r.Get("/conformance", h.Conformance)
 
+	// OpenAPI service description (service-desc) and HTML docs (service-doc)
+	r.Get("/api", h.OpenAPI)
+	r.Get("/api.html", h.APIDocs)
+
 	// Collections
 	r.Get("/collections", h.Collections)
 	r.Get("/collections/{collectionId}", h.Collection)
@@ -50,6 +89,7 @@ func NewRouter(h *Handlers, logger *slog.Logger) chi.Router {
 	// Items
 	r.Get("/collections/{collectionId}/items", h.Items)
 	r.Get("/collections/{collectionId}/items/{itemId}", h.Item)
+	r.Get("/collections/{collectionId}/items/{itemId}/stack", h.Stack)
 
 	// Search endpoint
 	r.Route("/search", func(r chi.Router) {
@@ -57,12 +97,34 @@ func NewRouter(h *Handlers, logger *slog.Logger) chi.Router {
 		r.Post("/", h.Search)
 	})
 
+	// InSAR pair discovery
+	r.Route("/pairs", func(r chi.Router) {
+		r.Get("/", h.Pairs)
+		r.Post("/", h.Pairs)
+	})
+
+	// Aggregation
+	r.Get("/aggregations", h.Aggregations)
+	r.Route("/aggregate", func(r chi.Router) {
+		r.Get("/", h.Aggregate)
+		r.Post("/", h.Aggregate)
+	})
+	r.Get("/collections/{collectionId}/aggregations", h.Aggregations)
+	r.Get("/collections/{collectionId}/aggregate", h.Aggregate)
+	r.Post("/collections/{collectionId}/aggregate", h.Aggregate)
+
 	// Queryables (if enabled)
 	if h.cfg.Features.EnableQueryables {
 		r.Get("/queryables", h.Queryables)
 		r.Get("/collections/{collectionId}/queryables", h.Queryables)
 	}
 
+	// Asset downloads through Earthdata Login (if enabled)
+	if h.downloads != nil {
+		r.Get("/download/{collectionId}/{itemId}/{asset}", h.Download)
+		r.Head("/download/{collectionId}/{itemId}/{asset}", h.Download)
+	}
+
 	// 404 handler
 	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
 		WriteNotFound(w, "endpoint not found")
@@ -86,7 +148,7 @@ func (h *Handlers) Queryables(w http.ResponseWriter, r *http.Request) {
 	id := h.cfg.STAC.BaseURL + "/queryables"
 
 	if collectionID != "" {
-		if !h.collections.Has(collectionID) {
+		if !h.collectionVisible(r.Context(), collectionID) {
 			WriteNotFound(w, "collection not found")
 			return
 		}
@@ -95,7 +157,48 @@ func (h *Handlers) Queryables(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build properties map with all supported queryables
-	properties := map[string]interface{}{
+	properties := queryableProperties()
+
+	// Add enum values based on whether this is global or collection-specific
+	if collectionID != "" {
+		// Collection-specific: use that collection's summaries
+		coll := h.collections.Get(collectionID)
+		if coll != nil && coll.Summaries != nil {
+			addEnumFromSummary(properties, coll.Summaries, "platform")
+			addEnumFromSummary(properties, coll.Summaries, "sar:instrument_mode")
+			addEnumFromSummary(properties, coll.Summaries, "sar:frequency_band")
+			addEnumFromSummary(properties, coll.Summaries, "sar:product_type")
+			addEnumFromSummary(properties, coll.Summaries, "sat:orbit_state")
+			addEnumFromSummary(properties, coll.Summaries, "constellation")
+			addEnumFromSummary(properties, coll.Summaries, "processing:level")
+			addEnumFromSummary(properties, coll.Summaries, "s1:subswath")
+			// Handle polarizations specially - flatten to channels as enum on items
+			addPolarizationChannelsEnum(properties, coll.Summaries)
+			// Handle instruments - set as enum on items
+			addArrayItemsEnumFromSummary(properties, coll.Summaries, "instruments")
+		}
+	} else {
+		// Global: aggregate enum values from the collections the client may access
+		h.addGlobalEnums(properties, h.visibleCollections(r.Context()))
+	}
+
+	queryables := map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2019-09/schema",
+		"$id":                  id,
+		"type":                 "object",
+		"title":                title,
+		"description":          "Queryable properties for STAC API search",
+		"properties":           properties,
+		"additionalProperties": true,
+	}
+
+	WriteJSON(w, http.StatusOK, queryables)
+}
+
+// queryableProperties returns the JSON Schema properties for all supported queryables.
+// A fresh map is returned on every call so callers can attach enums without sharing state.
+func queryableProperties() map[string]interface{} {
+	return map[string]interface{}{
 		// Core STAC queryables
 		"datetime": map[string]interface{}{
 			"description": "Datetime or datetime range",
@@ -157,6 +260,33 @@ func (h *Handlers) Queryables(w http.ResponseWriter, r *http.Request) {
 			"type":        "integer",
 		},
 
+		// ASF track and frame grid queryables
+		"asf:frame": map[string]interface{}{
+			"description": "ASF frame number along the track (e.g., ALOS PALSAR frames)",
+			"type":        "integer",
+		},
+		"asf:path": map[string]interface{}{
+			"description": "Path number of the platform's track grid",
+			"type":        "integer",
+		},
+
+		// View extension queryables
+		"view:off_nadir": map[string]interface{}{
+			"description": "Off-nadir angle of the sensor, in degrees",
+			"type":        "number",
+		},
+
+		// Sentinel-1 burst queryables
+		"s1:burst_id": map[string]interface{}{
+			"description": "Full Sentinel-1 burst ID: relative orbit, burst ID and subswath (e.g., 064_136231_IW2)",
+			"type":        "string",
+			"pattern":     "^[0-9]{3}_[0-9]{6}_(IW|EW)[1-5]$",
+		},
+		"s1:subswath": map[string]interface{}{
+			"description": "Sentinel-1 subswath of a burst (e.g., IW1, IW2, IW3)",
+			"type":        "string",
+		},
+
 		// Processing extension queryables
 		"processing:level": map[string]interface{}{
 			"description": "Processing level (e.g., L0, L1, L2)",
@@ -178,40 +308,6 @@ func (h *Handlers) Queryables(w http.ResponseWriter, r *http.Request) {
 			"items":       map[string]interface{}{"type": "string"},
 		},
 	}
-
-	// Add enum values based on whether this is global or collection-specific
-	if collectionID != "" {
-		// Collection-specific: use that collection's summaries
-		coll := h.collections.Get(collectionID)
-		if coll != nil && coll.Summaries != nil {
-			addEnumFromSummary(properties, coll.Summaries, "platform")
-			addEnumFromSummary(properties, coll.Summaries, "sar:instrument_mode")
-			addEnumFromSummary(properties, coll.Summaries, "sar:frequency_band")
-			addEnumFromSummary(properties, coll.Summaries, "sar:product_type")
-			addEnumFromSummary(properties, coll.Summaries, "sat:orbit_state")
-			addEnumFromSummary(properties, coll.Summaries, "constellation")
-			addEnumFromSummary(properties, coll.Summaries, "processing:level")
-			// Handle polarizations specially - flatten to channels as enum on items
-			addPolarizationChannelsEnum(properties, coll.Summaries)
-			// Handle instruments - set as enum on items
-			addArrayItemsEnumFromSummary(properties, coll.Summaries, "instruments")
-		}
-	} else {
-		// Global: aggregate enum values from all collections
-		h.addGlobalEnums(properties)
-	}
-
-	queryables := map[string]interface{}{
-		"$schema":              "https://json-schema.org/draft/2019-09/schema",
-		"$id":                  id,
-		"type":                 "object",
-		"title":                title,
-		"description":          "Queryable properties for STAC API search",
-		"properties":           properties,
-		"additionalProperties": true,
-	}
-
-	WriteJSON(w, http.StatusOK, queryables)
 }
 
 // addEnumFromSummary adds enum values to a property from collection summaries
@@ -277,8 +373,8 @@ func addArrayItemsEnumFromSummary(properties map[string]interface{}, summaries m
 	}
 }
 
-// addGlobalEnums aggregates enum values from all collections for global queryables
-func (h *Handlers) addGlobalEnums(properties map[string]interface{}) {
+// addGlobalEnums aggregates enum values from the given collections for global queryables
+func (h *Handlers) addGlobalEnums(properties map[string]interface{}, collections []*config.CollectionConfig) {
 	// Fields to aggregate
 	stringFields := []string{
 		"platform",
@@ -288,11 +384,12 @@ func (h *Handlers) addGlobalEnums(properties map[string]interface{}) {
 		"sat:orbit_state",
 		"constellation",
 		"processing:level",
+		"s1:subswath",
 	}
 
 	// Aggregate string fields
 	for _, field := range stringFields {
-		values := h.aggregateStringEnums(field)
+		values := aggregateStringEnums(collections, field)
 		if len(values) > 0 {
 			if prop, ok := properties[field].(map[string]interface{}); ok {
 				prop["enum"] = values
@@ -301,7 +398,7 @@ func (h *Handlers) addGlobalEnums(properties map[string]interface{}) {
 	}
 
 	// Handle sar:polarizations - flatten to unique channels and set as enum on items
-	if polarizations := h.aggregatePolarizationChannels(); len(polarizations) > 0 {
+	if polarizations := aggregatePolarizationChannels(collections); len(polarizations) > 0 {
 		if prop, ok := properties["sar:polarizations"].(map[string]interface{}); ok {
 			if items, ok := prop["items"].(map[string]interface{}); ok {
 				items["enum"] = polarizations
@@ -310,7 +407,7 @@ func (h *Handlers) addGlobalEnums(properties map[string]interface{}) {
 	}
 
 	// Handle instruments - set as enum on items
-	if instruments := h.aggregateStringEnums("instruments"); len(instruments) > 0 {
+	if instruments := aggregateStringEnums(collections, "instruments"); len(instruments) > 0 {
 		if prop, ok := properties["instruments"].(map[string]interface{}); ok {
 			if items, ok := prop["items"].(map[string]interface{}); ok {
 				items["enum"] = instruments
@@ -319,12 +416,12 @@ func (h *Handlers) addGlobalEnums(properties map[string]interface{}) {
 	}
 }
 
-// aggregateStringEnums collects unique string values from a field across all collections
-func (h *Handlers) aggregateStringEnums(field string) []string {
+// aggregateStringEnums collects unique string values from a field across collections
+func aggregateStringEnums(collections []*config.CollectionConfig, field string) []string {
 	seen := make(map[string]bool)
 	var result []string
 
-	for _, coll := range h.collections.All() {
+	for _, coll := range collections {
 		if coll.Summaries == nil {
 			continue
 		}
@@ -341,12 +438,12 @@ func (h *Handlers) aggregateStringEnums(field string) []string {
 	return result
 }
 
-// aggregatePolarizationChannels flattens all polarization combinations into unique channels
-func (h *Handlers) aggregatePolarizationChannels() []string {
+// aggregatePolarizationChannels flattens the polarization combinations of collections into unique channels
+func aggregatePolarizationChannels(collections []*config.CollectionConfig) []string {
 	seen := make(map[string]bool)
 	var result []string
 
-	for _, coll := range h.collections.All() {
+	for _, coll := range collections {
 		if coll.Summaries == nil {
 			continue
 		}
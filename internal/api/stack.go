@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	intstac "github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
+)
+
+// Properties holding the baselines of stack items, relative to the
+// reference item
+const (
+	temporalBaselineProperty      = "insar:temporal_baseline"
+	perpendicularBaselineProperty = "insar:perpendicular_baseline"
+)
+
+// baselineRange bounds a baseline. Both ends are inclusive and either may
+// be open.
+type baselineRange struct {
+	min, max *float64
+}
+
+// parseBaselineRange parses a range written "min/max" like a datetime
+// interval, where ".." or an empty bound leaves that end open.
+func parseBaselineRange(value string) (*baselineRange, error) {
+	lower, upper, ok := strings.Cut(value, "/")
+	if !ok {
+		return nil, fmt.Errorf("expected a range as min/max, got %q", value)
+	}
+	var r baselineRange
+	for _, bound := range []struct {
+		text string
+		dst  **float64
+	}{{lower, &r.min}, {upper, &r.max}} {
+		text := strings.TrimSpace(bound.text)
+		if text == "" || text == ".." {
+			continue
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bound %q", text)
+		}
+		*bound.dst = &v
+	}
+	if r.min == nil && r.max == nil {
+		return nil, fmt.Errorf("range %q has no bounds", value)
+	}
+	if r.min != nil && r.max != nil && *r.min > *r.max {
+		return nil, fmt.Errorf("range %q has its minimum above its maximum", value)
+	}
+	return &r, nil
+}
+
+// contains reports whether an item's property lies in the range. Items
+// without the property are outside every range.
+func (r *baselineRange) contains(item *stac.Item, property string) bool {
+	v, ok := baselineValue(item, property)
+	if !ok {
+		return false
+	}
+	return (r.min == nil || v >= *r.min) && (r.max == nil || v <= *r.max)
+}
+
+// baselineValue returns a baseline property of an item
+func baselineValue(item *stac.Item, property string) (float64, bool) {
+	switch v := item.Properties[property].(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// Stack returns the interferometric stack of an item: the items it forms
+// interferograms with, including itself, each with its temporal baseline in
+// days and perpendicular baseline in meters relative to the item. The
+// temporal_baseline and perpendicular_baseline parameters narrow the stack
+// to baselines in a min/max range.
+// GET /collections/{collectionId}/items/{itemId}/stack
+func (h *Handlers) Stack(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "Handlers.Stack")
+	defer span.End()
+
+	collectionID := chi.URLParam(r, "collectionId")
+	itemID := chi.URLParam(r, "itemId")
+
+	// Verify collection exists and is visible to the client
+	if !h.collectionVisible(ctx, collectionID) {
+		WriteNotFound(w, fmt.Sprintf("collection %q not found", collectionID))
+		return
+	}
+
+	filters := make(map[string]*baselineRange)
+	for param, property := range map[string]string{
+		"temporal_baseline":      temporalBaselineProperty,
+		"perpendicular_baseline": perpendicularBaselineProperty,
+	} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		rng, err := parseBaselineRange(value)
+		if err != nil {
+			WriteInvalidParameter(w, fmt.Sprintf("invalid %s: %s", param, err))
+			return
+		}
+		filters[property] = rng
+	}
+
+	unsupported := fmt.Sprintf("the %s backend does not support interferometric stacks", h.backend.Name())
+	stacker, ok := h.backend.(backend.Stacker)
+	if !ok {
+		WriteError(w, http.StatusNotImplemented, "NotImplemented", unsupported)
+		return
+	}
+
+	result, err := stacker.Stack(ctx, collectionID, itemID)
+	if err != nil {
+		h.logger.Error("failed to fetch stack",
+			slog.String("collection_id", collectionID),
+			slog.String("item_id", itemID),
+			slog.String("backend", h.backend.Name()),
+			slog.String("error", err.Error()),
+		)
+
+		switch {
+		case errors.Is(err, backend.ErrNotSupported):
+			WriteError(w, http.StatusNotImplemented, "NotImplemented", unsupported)
+		case errors.Is(err, backend.ErrNoStack):
+			WriteBadRequest(w, fmt.Sprintf("item %q has no interferometric stack", itemID))
+		case strings.Contains(err.Error(), "not found"):
+			WriteNotFound(w, fmt.Sprintf("item %q not found", itemID))
+		default:
+			WriteUpstreamFailure(w, err, "upstream service error")
+		}
+		return
+	}
+	// A stack of another collection, possibly one hidden from the client,
+	// is not found in this one
+	if slices.ContainsFunc(result.Items, func(item *stac.Item) bool { return item.Collection != collectionID }) {
+		WriteNotFound(w, fmt.Sprintf("item %q not found", itemID))
+		return
+	}
+
+	items := make([]*stac.Item, 0, len(result.Items))
+	for _, item := range result.Items {
+		inRange := true
+		for property, rng := range filters {
+			if !rng.contains(item, property) {
+				inRange = false
+				break
+			}
+		}
+		if inRange {
+			items = append(items, item)
+		}
+	}
+
+	// Order the stack in time around the reference
+	intstac.SortItems(items, []intstac.SortbyItem{
+		{Field: temporalBaselineProperty, Direction: string(intstac.SortAsc)},
+		{Field: "id", Direction: string(intstac.SortAsc)},
+	})
+	span.SetAttribute("stack.items", len(items))
+
+	h.rewriteAssetHrefs(items...)
+
+	itemCollection := intstac.NewItemCollection(items)
+	matched := len(items)
+	itemCollection.SetContext(len(items), len(items), &matched)
+
+	baseURL := h.cfg.STAC.BaseURL
+	itemURL := fmt.Sprintf("%s/collections/%s/items/%s", baseURL, collectionID, itemID)
+	selfURL := itemURL + "/stack"
+	if r.URL.RawQuery != "" {
+		selfURL += "?" + r.URL.RawQuery
+	}
+	itemCollection.AddLink("self", selfURL, "application/geo+json")
+	itemCollection.AddLink("root", baseURL+"/", "application/json")
+	itemCollection.AddLink("parent", itemURL, "application/geo+json")
+	itemCollection.AddLink("collection", fmt.Sprintf("%s/collections/%s", baseURL, collectionID), "application/json")
+
+	_, encodeSpan := tracing.Start(ctx, "encode response")
+	WriteGeoJSON(w, http.StatusOK, itemCollection)
+	encodeSpan.End()
+}
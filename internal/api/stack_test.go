@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	gostac "github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+)
+
+// stackBackend serves the stack of "reference", the only item with one
+type stackBackend struct {
+	mockBackend
+}
+
+func (b *stackBackend) Stack(ctx context.Context, collection, itemID string) (*backend.SearchResult, error) {
+	switch itemID {
+	case "reference":
+	case "grd":
+		return nil, fmt.Errorf("%w: %s", backend.ErrNoStack, itemID)
+	default:
+		return nil, fmt.Errorf("failed to fetch granule: granule not found: %s", itemID)
+	}
+
+	reference := time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC)
+	scenes := []struct {
+		id            string
+		temporal      int
+		perpendicular float64
+	}{
+		{"later", 12, 35.2},
+		{"reference", 0, 0},
+		{"earlier", -12, -140.5},
+		{"distant", 24, 210},
+	}
+	items := make([]*gostac.Item, 0, len(scenes))
+	for _, scene := range scenes {
+		item := createTestItem(scene.id, reference.AddDate(0, 0, scene.temporal))
+		item.Properties["insar:temporal_baseline"] = scene.temporal
+		item.Properties["insar:perpendicular_baseline"] = scene.perpendicular
+		items = append(items, item)
+	}
+	return &backend.SearchResult{Items: items}, nil
+}
+
+func newStackTestRouter(b backend.SearchBackend) http.Handler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewRouter(NewHandlers(createTestConfig(), b, nil, createTestCollections(), logger), logger)
+}
+
+func stackItemIDs(t *testing.T, body []byte) []string {
+	t.Helper()
+	var fc struct {
+		Features      []gostac.Item `json:"features"`
+		NumberMatched int           `json:"numberMatched"`
+	}
+	if err := json.Unmarshal(body, &fc); err != nil {
+		t.Fatalf("Failed to decode stack: %v", err)
+	}
+	if fc.NumberMatched != len(fc.Features) {
+		t.Errorf("Expected numberMatched %d, got %d", len(fc.Features), fc.NumberMatched)
+	}
+	ids := make([]string, 0, len(fc.Features))
+	for _, item := range fc.Features {
+		ids = append(ids, item.Id)
+	}
+	return ids
+}
+
+func TestStack(t *testing.T) {
+	router := newStackTestRouter(&stackBackend{})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"full stack ordered by temporal baseline", "", []string{"earlier", "reference", "later", "distant"}},
+		{"temporal baseline range", "?temporal_baseline=0/12", []string{"reference", "later"}},
+		{"open temporal baseline range", "?temporal_baseline=../0", []string{"earlier", "reference"}},
+		{"perpendicular baseline range", "?perpendicular_baseline=-150/150", []string{"earlier", "reference", "later"}},
+		{"both ranges", "?temporal_baseline=1/..&perpendicular_baseline=/100", []string{"later"}},
+		{"empty range", "?temporal_baseline=100/200", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveFrom(router, http.MethodGet, "/collections/sentinel-1/items/reference/stack"+tt.query, "192.0.2.1:1234", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if ids := stackItemIDs(t, w.Body.Bytes()); fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestStack_Errors(t *testing.T) {
+	router := newStackTestRouter(&stackBackend{})
+
+	tests := []struct {
+		name   string
+		target string
+		want   int
+	}{
+		{"unknown collection", "/collections/nisar/items/reference/stack", http.StatusNotFound},
+		{"unknown item", "/collections/sentinel-1/items/missing/stack", http.StatusNotFound},
+		{"item without stack", "/collections/sentinel-1/items/grd/stack", http.StatusBadRequest},
+		{"range without separator", "/collections/sentinel-1/items/reference/stack?temporal_baseline=12", http.StatusBadRequest},
+		{"range without bounds", "/collections/sentinel-1/items/reference/stack?temporal_baseline=../..", http.StatusBadRequest},
+		{"invalid bound", "/collections/sentinel-1/items/reference/stack?perpendicular_baseline=low/100", http.StatusBadRequest},
+		{"inverted range", "/collections/sentinel-1/items/reference/stack?perpendicular_baseline=100/-100", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveFrom(router, http.MethodGet, tt.target, "192.0.2.1:1234", nil)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestStack_UnsupportedBackend(t *testing.T) {
+	router := newStackTestRouter(&mockBackend{})
+
+	w := serveFrom(router, http.MethodGet, "/collections/sentinel-1/items/reference/stack", "192.0.2.1:1234", nil)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,46 @@
+package asf
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// BurstDataset is the ASF dataset of Sentinel-1 burst products
+const BurstDataset = "SENTINEL-1 BURSTS"
+
+// burstNamePattern matches burst product names such as
+// S1_136231_IW2_20200604T022312_VV_7C85-BURST: the relative burst ID,
+// subswath, burst start time, polarization and a product checksum.
+var burstNamePattern = regexp.MustCompile(`^S1_(\d{6})_((?:IW|EW)\d)_(\d{8}T\d{6})_([HV]{2})_[0-9A-F]{4}-BURST$`)
+
+// BurstName is a parsed Sentinel-1 burst product name
+type BurstName struct {
+	RelativeBurstID int
+	Subswath        string
+	Start           time.Time
+	Polarization    string
+}
+
+// ParseBurstName parses a Sentinel-1 burst product name. It reports false
+// for names of any other product.
+func ParseBurstName(name string) (*BurstName, bool) {
+	m := burstNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return nil, false
+	}
+	id, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil, false
+	}
+	start, err := time.Parse("20060102T150405", m[3])
+	if err != nil {
+		return nil, false
+	}
+	return &BurstName{
+		RelativeBurstID: id,
+		Subswath:        m[2],
+		Start:           start,
+		Polarization:    m[4],
+	}, true
+}
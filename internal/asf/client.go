@@ -4,50 +4,119 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cache"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/ratelimit"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
 )
 
 // Client handles communication with the ASF Search API
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
-	logger     *slog.Logger
+	baseURL   string
+	upstream  *upstream.Client
+	logger    *slog.Logger
+	cache     *cache.ReadThrough
+	cacheTTLs cache.TTLs
 }
 
 // NewClient creates a new ASF API client
 func NewClient(baseURL string, timeout time.Duration) *Client {
 	return &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: timeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 100,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
-		logger: slog.Default(),
+		baseURL:  baseURL,
+		upstream: upstream.NewClient("ASF", timeout),
+		logger:   slog.Default(),
 	}
 }
 
 // WithLogger sets a custom logger for the client
 func (c *Client) WithLogger(logger *slog.Logger) *Client {
 	c.logger = logger
+	c.upstream.WithLogger(logger)
+	return c
+}
+
+// WithMetrics records the client's upstream requests
+func (c *Client) WithMetrics(m *metrics.Metrics) *Client {
+	c.upstream.WithMetrics(m)
+	return c
+}
+
+// WithConcurrencyLimit shares a cap on the upstream calls in flight with
+// the other clients
+func (c *Client) WithConcurrencyLimit(slots *ratelimit.Semaphore) *Client {
+	c.upstream.WithConcurrencyLimit(slots)
+	return c
+}
+
+// WithRetryPolicy sets how failed requests are retried
+func (c *Client) WithRetryPolicy(policy upstream.RetryPolicy) *Client {
+	c.upstream.WithRetryPolicy(policy)
+	return c
+}
+
+// WithCache serves repeated queries from a read-through cache, keeping
+// search results and granule lookups for their respective TTLs
+func (c *Client) WithCache(rt *cache.ReadThrough, ttls cache.TTLs) *Client {
+	c.cache = rt
+	c.cacheTTLs = ttls
 	return c
 }
 
 // Search performs a search against the ASF API
 func (c *Client) Search(ctx context.Context, params SearchParams) (*ASFGeoJSONResponse, error) {
+	ctx, span := tracing.Start(ctx, "asf.Client.Search")
+	defer span.End()
+
+	result, err := c.search(ctx, params, c.cacheTTLs.Search)
+	span.RecordError(err)
+	return result, err
+}
+
+// Baseline runs a baseline search for the interferometric stack of a
+// reference scene, with the temporal and perpendicular baselines of each
+// scene relative to it. processingLevel selects the products of the stack,
+// such as SLC for Sentinel-1; when empty, ASF picks the platform default.
+func (c *Client) Baseline(ctx context.Context, reference, processingLevel string) (*ASFGeoJSONResponse, error) {
+	ctx, span := tracing.Start(ctx, "asf.Client.Baseline")
+	defer span.End()
+	span.SetAttribute("reference", reference)
+
+	query := url.Values{}
+	query.Set("reference", reference)
+	if processingLevel != "" {
+		query.Set("processingLevel", processingLevel)
+	}
+	query.Set("output", "geojson")
+
+	baselineURL, err := c.buildURL("/services/search/baseline", query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build baseline URL: %w", err)
+	}
+
+	result, err := c.fetch(ctx, baselineURL, c.cacheTTLs.Search)
+	span.RecordError(err)
+	return result, err
+}
+
+// search runs a search, serving it from the cache for ttl when one is set
+func (c *Client) search(ctx context.Context, params SearchParams, ttl time.Duration) (*ASFGeoJSONResponse, error) {
 	// Build the search URL
 	searchURL, err := c.buildSearchURL(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build search URL: %w", err)
 	}
+	return c.fetch(ctx, searchURL, ttl)
+}
 
+// fetch requests a GeoJSON response from searchURL, serving it from the
+// cache for ttl when one is set
+func (c *Client) fetch(ctx context.Context, searchURL string, ttl time.Duration) (*ASFGeoJSONResponse, error) {
 	c.logger.DebugContext(ctx, "executing ASF search",
 		slog.String("url", searchURL),
 	)
@@ -62,35 +131,22 @@ func (c *Client) Search(ctx context.Context, params SearchParams) (*ASFGeoJSONRe
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", "asf-stac-proxy/1.0")
 
-	// Execute the request
-	resp, err := c.httpClient.Do(req)
+	// Execute the request, or reuse a cached response to the same query
+	resp, err := c.cache.Fetch(ctx, cache.Key(req.URL, req.Header), ttl, func(ctx context.Context) (*cache.Response, error) {
+		return c.do(req.WithContext(ctx))
+	})
 	if err != nil {
-		c.logger.ErrorContext(ctx, "ASF API request failed",
-			slog.String("error", err.Error()),
-			slog.String("url", searchURL),
-		)
-		return nil, fmt.Errorf("ASF API request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check for non-200 status codes
-	if resp.StatusCode != http.StatusOK {
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			c.logger.WarnContext(ctx, "failed to read error response body",
-				slog.String("error", readErr.Error()),
-			)
-		}
-		c.logger.ErrorContext(ctx, "ASF API returned non-200 status",
-			slog.Int("status_code", resp.StatusCode),
-			slog.String("response_body", string(body)),
-		)
-		return nil, fmt.Errorf("ASF API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
 	// Parse the response
+	_, decodeSpan := tracing.Start(ctx, "decode ASF response")
+	decodeSpan.SetAttribute("response.bytes", len(resp.Body))
 	var result ASFGeoJSONResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	err = json.Unmarshal(resp.Body, &result)
+	decodeSpan.RecordError(err)
+	decodeSpan.End()
+	if err != nil {
 		c.logger.ErrorContext(ctx, "failed to decode ASF response",
 			slog.String("error", err.Error()),
 		)
@@ -104,14 +160,36 @@ func (c *Client) Search(ctx context.Context, params SearchParams) (*ASFGeoJSONRe
 	return &result, nil
 }
 
+// do executes a request through the shared upstream layer, which retries
+// transient failures
+func (c *Client) do(req *http.Request) (*cache.Response, error) {
+	resp, err := c.upstream.Do(req)
+	if err != nil {
+		c.logger.ErrorContext(req.Context(), "ASF API request failed",
+			slog.String("error", err.Error()),
+			slog.String("url", req.URL.String()),
+		)
+		return nil, err
+	}
+	return &cache.Response{Body: resp.Body, Header: resp.Header}, nil
+}
+
 // GetGranule retrieves a single granule by name or fileID.
 // The itemID can be either a scene name or a fileID (e.g., "sceneName-SLC").
 // ASF may return multiple products per scene, so we filter by fileID if provided.
 func (c *Client) GetGranule(ctx context.Context, itemID string) (*ASFFeature, error) {
+	ctx, span := tracing.Start(ctx, "asf.Client.GetGranule")
+	defer span.End()
+	span.SetAttribute("item.id", itemID)
+
 	c.logger.DebugContext(ctx, "fetching granule",
 		slog.String("item_id", itemID),
 	)
 
+	if burst, ok := ParseBurstName(itemID); ok {
+		return c.getBurst(ctx, itemID, burst)
+	}
+
 	// Create search params - granule_list matches scene name patterns
 	// Note: ASF doesn't allow maxResults with granule_list
 	params := SearchParams{
@@ -119,8 +197,8 @@ func (c *Client) GetGranule(ctx context.Context, itemID string) (*ASFFeature, er
 		Output:      "geojson",
 	}
 
-	// Execute the search
-	result, err := c.Search(ctx, params)
+	// Execute the search. Granules rarely change, so lookups are cached longer
+	result, err := c.search(ctx, params, c.cacheTTLs.Item)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search for granule: %w", err)
 	}
@@ -159,19 +237,55 @@ func (c *Client) GetGranule(ctx context.Context, itemID string) (*ASFFeature, er
 	return &result.Features[0], nil
 }
 
+// getBurst retrieves a burst product by the burst ID, subswath, time and
+// polarization in its name. granule_list only matches frame-level scenes.
+func (c *Client) getBurst(ctx context.Context, itemID string, burst *BurstName) (*ASFFeature, error) {
+	// Burst names carry the start time to the second
+	start := burst.Start.Add(-time.Second)
+	end := burst.Start.Add(2 * time.Second)
+	params := SearchParams{
+		Dataset:         []string{BurstDataset},
+		RelativeBurstID: []int{burst.RelativeBurstID},
+		Polarization:    []string{burst.Polarization},
+		Start:           &start,
+		End:             &end,
+		Output:          "geojson",
+	}
+
+	result, err := c.search(ctx, params, c.cacheTTLs.Item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for burst: %w", err)
+	}
+
+	for i := range result.Features {
+		props := &result.Features[i].Properties
+		if props.FileID == itemID || props.SceneName == itemID {
+			return &result.Features[i], nil
+		}
+	}
+
+	c.logger.WarnContext(ctx, "granule not found",
+		slog.String("item_id", itemID),
+		slog.Int("burst_candidates", len(result.Features)),
+	)
+	return nil, fmt.Errorf("granule not found: %s", itemID)
+}
+
 // buildSearchURL constructs the full search URL with query parameters
 func (c *Client) buildSearchURL(params SearchParams) (string, error) {
+	return c.buildURL("/services/search/param", params.ToQueryString())
+}
+
+// buildURL constructs the URL of an ASF API endpoint
+func (c *Client) buildURL(path, rawQuery string) (string, error) {
 	// Parse the base URL
 	base, err := url.Parse(c.baseURL)
 	if err != nil {
 		return "", fmt.Errorf("invalid base URL: %w", err)
 	}
 
-	// Add the search path
-	base.Path = "/services/search/param"
-
-	// Add query parameters
-	base.RawQuery = params.ToQueryString()
+	base.Path = path
+	base.RawQuery = rawQuery
 
 	return base.String(), nil
 }
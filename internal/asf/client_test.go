@@ -8,8 +8,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cache"
 )
 
 func TestClient_Search_Success(t *testing.T) {
@@ -345,6 +348,99 @@ func TestClient_GetGranule_MultipleResults_MatchesByFileID(t *testing.T) {
 	}
 }
 
+func TestClient_Baseline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/search/baseline" {
+			t.Errorf("Expected the baseline endpoint, got %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("reference") != "S1A_IW_SLC__1SDV_20240101T000000" || query.Get("processingLevel") != "SLC" || query.Get("output") != "geojson" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"sceneName": "S1A_IW_SLC__1SDV_20240101T000000", "temporalBaseline": 0, "perpendicularBaseline": 0}},
+			{"type": "Feature", "properties": {"sceneName": "S1A_IW_SLC__1SDV_20240113T000000", "temporalBaseline": 12, "perpendicularBaseline": -41.7}}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	result, err := client.Baseline(context.Background(), "S1A_IW_SLC__1SDV_20240101T000000", "SLC")
+	if err != nil {
+		t.Fatalf("Baseline failed: %v", err)
+	}
+	if len(result.Features) != 2 {
+		t.Fatalf("Expected 2 features, got %d", len(result.Features))
+	}
+	props := result.Features[1].Properties
+	if props.TemporalBaseline == nil || *props.TemporalBaseline != 12 {
+		t.Errorf("Expected temporal baseline 12, got %v", props.TemporalBaseline)
+	}
+	if props.PerpendicularBaseline == nil || *props.PerpendicularBaseline != -41.7 {
+		t.Errorf("Expected perpendicular baseline -41.7, got %v", props.PerpendicularBaseline)
+	}
+}
+
+func TestClient_GetGranule_Burst(t *testing.T) {
+	const burstName = "S1_136231_IW2_20200604T022312_VV_7C85-BURST"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("granule_list") != "" {
+			t.Errorf("Expected a burst search rather than a granule list, got %s", r.URL.RawQuery)
+		}
+		if query.Get("dataset") != BurstDataset || query.Get("relativeBurstID") != "136231" || query.Get("polarization") != "VV" ||
+			query.Get("start") != "2020-06-04T02:23:11Z" || query.Get("end") != "2020-06-04T02:23:14Z" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		// The burst ID is shared by the subswaths of the burst
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"fileID": "S1_136231_IW1_20200604T022311_VV_2A1F-BURST", "burst": {"fullBurstID": "064_136231_IW1", "subswath": "IW1"}}},
+			{"type": "Feature", "properties": {"fileID": "S1_136231_IW2_20200604T022312_VV_7C85-BURST", "burst": {"fullBurstID": "064_136231_IW2", "subswath": "IW2", "burstIndex": 7}}}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	result, err := client.GetGranule(context.Background(), burstName)
+	if err != nil {
+		t.Fatalf("GetGranule failed: %v", err)
+	}
+	burst := result.Properties.Burst
+	if result.Properties.FileID != burstName || burst == nil || burst.FullBurstID != "064_136231_IW2" || burst.BurstIndex == nil || *burst.BurstIndex != 7 {
+		t.Errorf("Unexpected burst %+v", result.Properties)
+	}
+
+	if _, err := client.GetGranule(context.Background(), "S1_136231_IW3_20200604T022312_VV_0000-BURST"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error for a burst missing from the results, got %v", err)
+	}
+}
+
+func TestParseBurstName(t *testing.T) {
+	burst, ok := ParseBurstName("S1_136231_IW2_20200604T022312_VV_7C85-BURST")
+	if !ok {
+		t.Fatal("Expected a burst name")
+	}
+	want := BurstName{RelativeBurstID: 136231, Subswath: "IW2", Start: time.Date(2020, 6, 4, 2, 23, 12, 0, time.UTC), Polarization: "VV"}
+	if *burst != want {
+		t.Errorf("Expected %+v, got %+v", want, *burst)
+	}
+
+	for _, name := range []string{
+		"S1A_IW_SLC__1SDV_20240101T000000-SLC",
+		"S1_136231_IW2_20200604T022312_VV_7C85",
+		"S1_136231_XX2_20200604T022312_VV_7C85-BURST",
+	} {
+		if _, ok := ParseBurstName(name); ok {
+			t.Errorf("Expected %s not to parse as a burst", name)
+		}
+	}
+}
+
 func TestClient_WithLogger(t *testing.T) {
 	client := NewClient("http://example.com", 30*time.Second)
 
@@ -356,6 +452,52 @@ func TestClient_WithLogger(t *testing.T) {
 	}
 }
 
+func TestClient_WithCache(t *testing.T) {
+	// Test that repeated queries are served from the cache, whatever the
+	// order of their multi-valued parameters, and that failures are not cached
+	var requests atomic.Int32
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if fail.Load() {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ASFGeoJSONResponse{Type: "FeatureCollection", Features: []ASFFeature{{Type: "Feature"}}})
+	}))
+	defer server.Close()
+
+	rt := cache.NewReadThrough(cache.NewLRU(1<<20), 0)
+	client := NewClient(server.URL, 30*time.Second).WithCache(rt, cache.TTLs{Search: time.Minute, Item: time.Hour})
+
+	for _, beamModes := range [][]string{{"IW", "EW"}, {"EW", "IW"}} {
+		result, err := client.Search(context.Background(), SearchParams{Dataset: []string{"SENTINEL-1"}, BeamMode: beamModes})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(result.Features) != 1 {
+			t.Errorf("Expected 1 feature, got %d", len(result.Features))
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", n)
+	}
+	if stats := rt.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Unexpected cache stats %+v", stats)
+	}
+
+	fail.Store(true)
+	for i := 0; i < 2; i++ {
+		if _, err := client.Search(context.Background(), SearchParams{Dataset: []string{"ALOS"}}); err == nil {
+			t.Fatal("Expected an error from the failing upstream")
+		}
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected failed searches to reach upstream each time, got %d requests", n)
+	}
+}
+
 func TestSearchParams_ToQueryString(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -424,6 +566,27 @@ func TestSearchParams_ToQueryString(t *testing.T) {
 			},
 			expectedParams: []string{"granule_list=SCENE1%2CSCENE2"},
 		},
+		{
+			name: "burst IDs",
+			params: SearchParams{
+				FullBurstID:     []string{"064_136231_IW2", "064_136232_IW2"},
+				RelativeBurstID: []int{136231, 136232},
+				Output:          "geojson",
+			},
+			// Burst IDs use comma-separated values
+			expectedParams: []string{"fullBurstID=064_136231_IW2%2C064_136232_IW2", "relativeBurstID=136231%2C136232"},
+		},
+		{
+			name: "frame, path and off-nadir ranges",
+			params: SearchParams{
+				Frame:         []Range{{Min: 100, Max: 150}},
+				Path:          []Range{{Min: 422, Max: 422}, {Min: 430, Max: 432}},
+				OffNadirAngle: []Range{{Min: 21.5, Max: 21.5}, {Min: 34.3, Max: 34.3}},
+				Output:        "geojson",
+			},
+			// Ranges use min-max syntax in comma-separated values
+			expectedParams: []string{"frame=100-150", "relativeOrbit=422%2C430-432", "offNadirAngle=21.5%2C34.3"},
+		},
 		{
 			name: "intersects with WKT",
 			params: SearchParams{
@@ -88,4 +88,18 @@ type ASFProperties struct {
 	InsarBaseline         *float64 `json:"insarBaseline"`
 	TemporalBaseline      *int     `json:"temporalBaseline"`
 	PerpendicularBaseline *float64 `json:"perpendicularBaseline"`
+
+	// Burst metadata, set on SENTINEL-1 BURSTS products
+	Burst *ASFBurst `json:"burst"`
+}
+
+// ASFBurst identifies a Sentinel-1 IW or EW burst on ESA's burst ID map
+type ASFBurst struct {
+	FullBurstID     string `json:"fullBurstID"` // Relative orbit, burst ID and subswath (e.g., "064_136231_IW2")
+	RelativeBurstID *int   `json:"relativeBurstID"`
+	AbsoluteBurstID *int   `json:"absoluteBurstID"`
+	BurstIndex      *int   `json:"burstIndex"` // Position of the burst in its subswath of the source SLC
+	Subswath        string `json:"subswath"`
+	AzimuthTime     string `json:"azimuthTime"`
+	SamplesPerBurst *int   `json:"samplesPerBurst"`
 }
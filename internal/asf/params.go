@@ -1,7 +1,6 @@
 package asf
 
 import (
-	"fmt"
 	"net/url"
 	"strconv"
 	"strings"
@@ -36,8 +35,14 @@ type SearchParams struct {
 	// Processing filters
 	ProcessingLevel []string // Processing levels (e.g., "SLC", "GRD")
 
-	// Geometric filters
-	OffNadirAngle []float64 // Off-nadir angle values
+	// Sentinel-1 burst filters
+	FullBurstID     []string // Full burst IDs (e.g., "064_136231_IW2")
+	RelativeBurstID []int    // Relative burst IDs, shared by the subswaths of a burst
+
+	// Grid and geometric filters, each a list of alternative values or ranges
+	Frame         []Range // Frame numbers
+	Path          []Range // Path numbers, sent as relativeOrbit alongside RelativeOrbit, so set at most one
+	OffNadirAngle []Range // Off-nadir angles in degrees
 
 	// Sorting
 	// Note: ASF API does not support sort direction (sortDir). Results are always sorted in descending order.
@@ -130,11 +135,27 @@ func (p *SearchParams) ToURLValues() url.Values {
 		values.Set("processingLevel", strings.Join(p.ProcessingLevel, ","))
 	}
 
-	// Off-nadir angle
-	if len(p.OffNadirAngle) > 0 {
-		for _, angle := range p.OffNadirAngle {
-			values.Add("offNadirAngle", fmt.Sprintf("%.2f", angle))
+	// Burst IDs (comma-separated)
+	if len(p.FullBurstID) > 0 {
+		values.Set("fullBurstID", strings.Join(p.FullBurstID, ","))
+	}
+	if len(p.RelativeBurstID) > 0 {
+		ids := make([]string, len(p.RelativeBurstID))
+		for i, id := range p.RelativeBurstID {
+			ids[i] = strconv.Itoa(id)
 		}
+		values.Set("relativeBurstID", strings.Join(ids, ","))
+	}
+
+	// Frame, path and off-nadir angle (comma-separated values and ranges)
+	if len(p.Frame) > 0 {
+		values.Set("frame", joinRanges(p.Frame))
+	}
+	if len(p.Path) > 0 {
+		values.Add("relativeOrbit", joinRanges(p.Path))
+	}
+	if len(p.OffNadirAngle) > 0 {
+		values.Set("offNadirAngle", joinRanges(p.OffNadirAngle))
 	}
 
 	// Sort (ASF API doesn't support sort direction)
@@ -157,6 +178,30 @@ func (p *SearchParams) ToURLValues() url.Values {
 	return values
 }
 
+// Range is an inclusive range of values, written "min-max" in ASF queries.
+// A range whose Min equals its Max is a single value.
+type Range struct {
+	Min, Max float64
+}
+
+// String formats the range in ASF syntax, such as "100-150" or "100"
+func (r Range) String() string {
+	low := strconv.FormatFloat(r.Min, 'f', -1, 64)
+	if r.Max == r.Min {
+		return low
+	}
+	return low + "-" + strconv.FormatFloat(r.Max, 'f', -1, 64)
+}
+
+// joinRanges formats a list of alternative values and ranges
+func joinRanges(ranges []Range) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
 // formatASFTime formats a time.Time for ASF API queries
 // ASF expects ISO 8601 format: YYYY-MM-DDTHH:MM:SSZ
 func formatASFTime(t *time.Time) string {
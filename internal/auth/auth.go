@@ -0,0 +1,189 @@
+// Package auth authenticates API clients, with static API keys or JWT bearer
+// tokens, and maps them to the collections they may access.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrInvalidCredentials is returned when a request carries an unknown API key
+// or a bearer token that does not verify.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal is an authenticated client.
+type Principal struct {
+	// Subject identifies the client: the name of its API key or the sub
+	// claim of its token
+	Subject string
+	// Method is how the client authenticated, "api_key" or "jwt"
+	Method string
+	// Collections are the restricted collections the client may access, as
+	// IDs or path.Match patterns; "*" grants them all
+	Collections []string
+}
+
+// Allows reports whether the principal may access the restricted collection
+// with the given ID. A nil principal, an anonymous client, is allowed none.
+func (p *Principal) Allows(collectionID string) bool {
+	if p == nil {
+		return false
+	}
+	for _, grant := range p.Collections {
+		if grant == collectionID {
+			return true
+		}
+		if ok, _ := path.Match(grant, collectionID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a context carrying the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of a request, or nil for an anonymous one.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Authenticator authenticates requests by the API key in a header or the
+// bearer token in the Authorization header.
+type Authenticator struct {
+	keyHeader string
+	keys      map[[sha256.Size]byte]*Principal
+	jwt       *JWTVerifier
+	required  bool
+}
+
+// NewAuthenticator creates an authenticator reading API keys from keyHeader.
+// It accepts no credentials until API keys or a JWT verifier are added.
+func NewAuthenticator(keyHeader string) *Authenticator {
+	return &Authenticator{
+		keyHeader: keyHeader,
+		keys:      make(map[[sha256.Size]byte]*Principal),
+	}
+}
+
+// WithAPIKeys adds the API keys of a key file.
+func (a *Authenticator) WithAPIKeys(keys []APIKey) *Authenticator {
+	for _, key := range keys {
+		a.keys[key.hash] = &Principal{Subject: key.Subject, Method: "api_key", Collections: key.Collections}
+	}
+	return a
+}
+
+// WithJWT accepts bearer tokens verified by v.
+func (a *Authenticator) WithJWT(v *JWTVerifier) *Authenticator {
+	a.jwt = v
+	return a
+}
+
+// WithRequired rejects requests without credentials, instead of serving them
+// anonymously.
+func (a *Authenticator) WithRequired(required bool) *Authenticator {
+	a.required = required
+	return a
+}
+
+// Required reports whether requests without credentials are rejected.
+func (a *Authenticator) Required() bool {
+	return a.required
+}
+
+// CredentialHeaders returns the request headers carrying credentials, which
+// browsers must be allowed to send cross-origin.
+func (a *Authenticator) CredentialHeaders() []string {
+	if a.keyHeader == "" {
+		return []string{"Authorization"}
+	}
+	return []string{"Authorization", a.keyHeader}
+}
+
+// Authenticate returns the principal of a request, or nil when the request
+// carries no credentials. It fails with ErrInvalidCredentials when the
+// credentials are not valid.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(a.keyHeader); a.keyHeader != "" && key != "" {
+		p, ok := a.keys[sha256.Sum256([]byte(key))]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+		}
+		return p, nil
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+	if a.jwt == nil {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", ErrInvalidCredentials)
+	}
+	return a.jwt.Verify(r.Context(), strings.TrimSpace(token))
+}
+
+// APIKey is an entry of an API key file.
+type APIKey struct {
+	Subject     string
+	Collections []string
+	hash        [sha256.Size]byte
+}
+
+// apiKeyEntry is the JSON form of an API key. The key is given either as is
+// or, to keep it out of the file, as the hex SHA-256 of the key
+type apiKeyEntry struct {
+	Subject     string   `json:"subject"`
+	Key         string   `json:"key,omitempty"`
+	KeySHA256   string   `json:"key_sha256,omitempty"`
+	Collections []string `json:"collections"`
+}
+
+// LoadAPIKeys reads an API key file, a JSON array of entries such as
+//
+//	{"subject": "partner-a", "key_sha256": "9f86d0…", "collections": ["nisar-*"]}
+func LoadAPIKeys(filename string) ([]APIKey, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API key file: %w", err)
+	}
+	var entries []apiKeyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse API key file %s: %w", filename, err)
+	}
+
+	keys := make([]APIKey, 0, len(entries))
+	for i, entry := range entries {
+		key := APIKey{Subject: entry.Subject, Collections: entry.Collections}
+		if key.Subject == "" {
+			return nil, fmt.Errorf("API key %d has no subject", i+1)
+		}
+		switch {
+		case entry.Key != "" && entry.KeySHA256 == "":
+			key.hash = sha256.Sum256([]byte(entry.Key))
+		case entry.KeySHA256 != "" && entry.Key == "":
+			sum, err := hex.DecodeString(entry.KeySHA256)
+			if err != nil || len(sum) != sha256.Size {
+				return nil, fmt.Errorf("API key %q has an invalid key_sha256", key.Subject)
+			}
+			copy(key.hash[:], sum)
+		default:
+			return nil, fmt.Errorf("API key %q must have exactly one of key and key_sha256", key.Subject)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
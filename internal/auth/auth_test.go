@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestPrincipal_Allows(t *testing.T) {
+	p := &Principal{Subject: "partner", Collections: []string{"nisar-l2", "opera-*"}}
+
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"nisar-l2", true},
+		{"nisar-l1", false},
+		{"opera-rtc", true},
+		{"opera", false},
+		{"sentinel-1", false},
+	}
+	for _, tt := range tests {
+		if got := p.Allows(tt.id); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+
+	if (&Principal{Collections: []string{"*"}}).Allows("anything") != true {
+		t.Error("Expected * to grant every collection")
+	}
+	var anonymous *Principal
+	if anonymous.Allows("nisar-l2") {
+		t.Error("Expected an anonymous client to be allowed no restricted collection")
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	sum := sha256.Sum256([]byte("hashed-secret"))
+	filename := writeFile(t, "keys.json", `[
+		{"subject": "plain", "key": "plain-secret", "collections": ["nisar-*"]},
+		{"subject": "hashed", "key_sha256": "`+hex.EncodeToString(sum[:])+`", "collections": ["*"]}
+	]`)
+
+	keys, err := LoadAPIKeys(filename)
+	if err != nil {
+		t.Fatalf("LoadAPIKeys: %v", err)
+	}
+	a := NewAuthenticator("X-API-Key").WithAPIKeys(keys)
+
+	tests := []struct {
+		key     string
+		subject string
+		wantErr bool
+	}{
+		{"plain-secret", "plain", false},
+		{"hashed-secret", "hashed", false},
+		{"wrong-secret", "", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/collections", nil)
+		req.Header.Set("X-API-Key", tt.key)
+		p, err := a.Authenticate(req)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("key %q: expected ErrInvalidCredentials, got %v", tt.key, err)
+			}
+			continue
+		}
+		if err != nil || p == nil || p.Subject != tt.subject || p.Method != "api_key" {
+			t.Errorf("key %q: unexpected principal %+v (%v)", tt.key, p, err)
+		}
+	}
+}
+
+func TestLoadAPIKeys_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"not json", `{`},
+		{"no subject", `[{"key": "secret"}]`},
+		{"no key", `[{"subject": "a"}]`},
+		{"both keys", `[{"subject": "a", "key": "secret", "key_sha256": "00"}]`},
+		{"short hash", `[{"subject": "a", "key_sha256": "abcd"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadAPIKeys(writeFile(t, "keys.json", tt.content)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if _, err := LoadAPIKeys(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestAuthenticator_Anonymous(t *testing.T) {
+	a := NewAuthenticator("X-API-Key")
+
+	req := httptest.NewRequest("GET", "/collections", nil)
+	if p, err := a.Authenticate(req); p != nil || err != nil {
+		t.Errorf("Expected an anonymous request, got %+v (%v)", p, err)
+	}
+
+	// Other schemes are not credentials of this API
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	if p, err := a.Authenticate(req); p != nil || err != nil {
+		t.Errorf("Expected Basic credentials to be ignored, got %+v (%v)", p, err)
+	}
+
+	// Bearer tokens are rejected without a verifier
+	req.Header.Set("Authorization", "Bearer abc.def.ghi")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+}
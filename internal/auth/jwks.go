@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// minJWKSRefetch bounds how often a token signed with an unknown key can make
+// the key set be fetched again
+const minJWKSRefetch = 30 * time.Second
+
+// JWK is a public signing key of a JSON Web Key Set.
+type JWK struct {
+	Kid string
+	// Alg restricts the key to one algorithm when set
+	Alg    string
+	Public crypto.PublicKey
+}
+
+// KeySet is a JSON Web Key Set read from a file or URL. It is loaded on first
+// use and reloaded every refresh interval, or sooner when a token names a key
+// it does not hold, so keys can be rotated at the identity provider. It is
+// safe for concurrent use.
+type KeySet struct {
+	source  string
+	fetch   func(ctx context.Context) ([]byte, error)
+	refresh time.Duration
+	now     func() time.Time
+
+	mu          sync.Mutex
+	keys        []JWK
+	loaded      time.Time // when keys were last loaded
+	lastAttempt time.Time
+}
+
+// NewFileKeySet creates a key set read from a JWKS file.
+func NewFileKeySet(filename string, refresh time.Duration) *KeySet {
+	return newKeySet(filename, refresh, func(context.Context) ([]byte, error) {
+		return os.ReadFile(filename)
+	})
+}
+
+// NewURLKeySet creates a key set fetched from a JWKS URL, such as the
+// jwks_uri of an OpenID Connect provider.
+func NewURLKeySet(url string, client *http.Client, refresh time.Duration) *KeySet {
+	return newKeySet(url, refresh, func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	})
+}
+
+func newKeySet(source string, refresh time.Duration, fetch func(context.Context) ([]byte, error)) *KeySet {
+	return &KeySet{source: source, fetch: fetch, refresh: refresh, now: time.Now}
+}
+
+// Load fetches the key set, replacing the keys held on success.
+func (s *KeySet) Load(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(ctx)
+}
+
+func (s *KeySet) load(ctx context.Context) error {
+	s.lastAttempt = s.now()
+	data, err := s.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS from %s: %w", s.source, err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse JWKS from %s: %w", s.source, err)
+	}
+	s.keys = keys
+	s.loaded = s.lastAttempt
+	return nil
+}
+
+// Lookup returns the keys with the given key ID, or all keys when kid is
+// empty, loading the key set first when it is due.
+func (s *KeySet) Lookup(ctx context.Context, kid string) ([]JWK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	canFetch := now.Sub(s.lastAttempt) >= minJWKSRefetch
+	var loadErr error
+	if (s.loaded.IsZero() || (s.refresh > 0 && now.Sub(s.loaded) >= s.refresh)) && canFetch {
+		// Keep the current keys if a refresh fails
+		loadErr = s.load(ctx)
+		canFetch = false
+	}
+
+	matching := s.matching(kid)
+	if len(matching) == 0 && kid != "" && canFetch {
+		loadErr = s.load(ctx)
+		matching = s.matching(kid)
+	}
+	if len(matching) == 0 {
+		if s.loaded.IsZero() && loadErr != nil {
+			return nil, loadErr
+		}
+		return nil, fmt.Errorf("no signing key %q", kid)
+	}
+	return matching, nil
+}
+
+func (s *KeySet) matching(kid string) []JWK {
+	if kid == "" {
+		return s.keys
+	}
+	var keys []JWK
+	for _, key := range s.keys {
+		if key.Kid == kid {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// jwkJSON is the JSON form of a JWK, with the members of RSA, EC and OKP keys
+type jwkJSON struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses the signing keys of a JSON Web Key Set. Encryption keys
+// and key types other than RSA, EC and OKP (Ed25519) are skipped.
+func ParseJWKS(data []byte) ([]JWK, error) {
+	var set struct {
+		Keys []jwkJSON `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	var keys []JWK
+	for i, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		public, err := raw.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %d (%q): %w", i+1, raw.Kid, err)
+		}
+		if public != nil {
+			keys = append(keys, JWK{Kid: raw.Kid, Alg: raw.Alg, Public: public})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key, returning nil for unsupported key types
+func (k *jwkJSON) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil || len(n) == 0 {
+			return nil, errors.New("invalid RSA modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		var validate ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, validate = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, validate = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, validate = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC point")
+		}
+		// Reject points off the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := validate.NewPublicKey(point); err != nil {
+			return nil, errors.New("invalid EC point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers crypto.SHA256
+	_ "crypto/sha512" // registers crypto.SHA384 and crypto.SHA512
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWTConfig contains the claims a bearer token must carry.
+type JWTConfig struct {
+	// Issuer, when set, must equal the iss claim
+	Issuer string
+	// Audience, when set, must be one of the aud claim
+	Audience string
+	// CollectionsClaim names the claim listing the restricted collections
+	// a token grants, as an array of strings or a space-separated string
+	// such as a scope
+	CollectionsClaim string
+	// ClaimPrefix, when set, selects the values of CollectionsClaim that
+	// start with it, such as "stac:" in "openid stac:nisar-*", and strips it
+	ClaimPrefix string
+	// ClockSkew is the leeway allowed when checking exp and nbf
+	ClockSkew time.Duration
+}
+
+// JWTVerifier verifies JWT bearer tokens signed with a key of a JWKS.
+type JWTVerifier struct {
+	keys *KeySet
+	cfg  JWTConfig
+	now  func() time.Time
+}
+
+// NewJWTVerifier creates a verifier of tokens signed with the keys of keys.
+func NewJWTVerifier(keys *KeySet, cfg JWTConfig) *JWTVerifier {
+	if cfg.CollectionsClaim == "" {
+		cfg.CollectionsClaim = "collections"
+	}
+	return &JWTVerifier{keys: keys, cfg: cfg, now: time.Now}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the signature and claims of a compact JWT and returns the
+// principal it identifies. It fails with ErrInvalidCredentials.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed token header", ErrInvalidCredentials)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token signature", ErrInvalidCredentials)
+	}
+	alg, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidCredentials, header.Alg)
+	}
+
+	keys, err := v.keys.Lookup(ctx, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if (key.Alg == "" || key.Alg == header.Alg) && alg(key.Public, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidCredentials)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims", ErrInvalidCredentials)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	sub, _ := claims["sub"].(string)
+	return &Principal{Subject: sub, Method: "jwt", Collections: v.grants(claims)}, nil
+}
+
+// checkClaims validates the registered claims of a token
+func (v *JWTVerifier) checkClaims(claims map[string]any) error {
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.cfg.ClockSkew)) {
+		return fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.cfg.ClockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("token not yet valid")
+	}
+	if v.cfg.Issuer != "" && claims["iss"] != v.cfg.Issuer {
+		return fmt.Errorf("unexpected issuer")
+	}
+	if v.cfg.Audience != "" && !slices.Contains(stringsOf(claims["aud"]), v.cfg.Audience) {
+		return fmt.Errorf("unexpected audience")
+	}
+	return nil
+}
+
+// grants extracts the collections granted by the claims of a token
+func (v *JWTVerifier) grants(claims map[string]any) []string {
+	var grants []string
+	for _, value := range stringsOf(claims[v.cfg.CollectionsClaim]) {
+		if v.cfg.ClaimPrefix != "" {
+			var ok bool
+			if value, ok = strings.CutPrefix(value, v.cfg.ClaimPrefix); !ok {
+				continue
+			}
+		}
+		if value != "" {
+			grants = append(grants, value)
+		}
+	}
+	return grants
+}
+
+// stringsOf reads a claim holding a string array or a space-separated string
+func stringsOf(claim any) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(c)
+	case []any:
+		values := make([]string, 0, len(c))
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// jwtAlgorithm verifies the signatures of a JWS algorithm
+type jwtAlgorithm func(key crypto.PublicKey, signed, signature []byte) bool
+
+// jwtAlgorithms are the accepted asymmetric algorithms. Symmetric HS* and
+// "none" are deliberately absent, so a public key can never be used as an
+// HMAC secret.
+var jwtAlgorithms = map[string]jwtAlgorithm{
+	"RS256": rsaPKCS1(crypto.SHA256),
+	"RS384": rsaPKCS1(crypto.SHA384),
+	"RS512": rsaPKCS1(crypto.SHA512),
+	"PS256": rsaPSS(crypto.SHA256),
+	"PS384": rsaPSS(crypto.SHA384),
+	"PS512": rsaPSS(crypto.SHA512),
+	"ES256": ecdsaP(crypto.SHA256, 32),
+	"ES384": ecdsaP(crypto.SHA384, 48),
+	"ES512": ecdsaP(crypto.SHA512, 66),
+	"EdDSA": func(key crypto.PublicKey, signed, signature []byte) bool {
+		pub, ok := key.(ed25519.PublicKey)
+		return ok && ed25519.Verify(pub, signed, signature)
+	},
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func rsaPKCS1(hash crypto.Hash) jwtAlgorithm {
+	return func(key crypto.PublicKey, signed, signature []byte) bool {
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, hash, digest(hash, signed), signature) == nil
+	}
+}
+
+func rsaPSS(hash crypto.Hash) jwtAlgorithm {
+	return func(key crypto.PublicKey, signed, signature []byte) bool {
+		pub, ok := key.(*rsa.PublicKey)
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		return ok && rsa.VerifyPSS(pub, hash, digest(hash, signed), signature, opts) == nil
+	}
+}
+
+// ecdsaP verifies the fixed-size r || s signatures of JWS, on the curve whose
+// coordinates are size bytes long
+func ecdsaP(hash crypto.Hash, size int) jwtAlgorithm {
+	return func(key crypto.PublicKey, signed, signature []byte) bool {
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || (pub.Curve.Params().BitSize+7)/8 != size || len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(pub, digest(hash, signed), r, s)
+	}
+}
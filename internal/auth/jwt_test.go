@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// testKey is a signing key of the tests and its JWK
+type testKey struct {
+	kid     string
+	alg     string
+	private crypto.Signer
+}
+
+func newRSAKey(t *testing.T, kid string) *testKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKey{kid: kid, alg: "RS256", private: key}
+}
+
+func newECKey(t *testing.T, kid string) *testKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKey{kid: kid, alg: "ES256", private: key}
+}
+
+func newEdKey(t *testing.T, kid string) *testKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKey{kid: kid, alg: "EdDSA", private: key}
+}
+
+func (k *testKey) jwk() map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": k.kid, "use": "sig",
+			"n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": k.kid, "crv": "P-256",
+			"x": b64(pub.X.FillBytes(make([]byte, 32))), "y": b64(pub.Y.FillBytes(make([]byte, 32)))}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": k.kid, "crv": "Ed25519", "x": b64(pub)}
+	}
+	return nil
+}
+
+func jwksOf(keys ...*testKey) string {
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	for _, k := range keys {
+		set.Keys = append(set.Keys, k.jwk())
+	}
+	data, _ := json.Marshal(set)
+	return string(data)
+}
+
+// sign creates a compact JWT of the claims
+func (k *testKey) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": k.alg, "kid": k.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	var err error
+	switch key := k.private.(type) {
+	case *rsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	case *ecdsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key, sum[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signed))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":         "user-1",
+		"iss":         "https://idp.example.com",
+		"aud":         []string{"stac", "other"},
+		"exp":         testNow.Add(time.Hour).Unix(),
+		"collections": []string{"nisar-*"},
+	}
+}
+
+func newTestVerifier(keys *KeySet, cfg JWTConfig) *JWTVerifier {
+	keys.now = func() time.Time { return testNow }
+	v := NewJWTVerifier(keys, cfg)
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func TestJWTVerifier_Algorithms(t *testing.T) {
+	keys := []*testKey{newRSAKey(t, "rsa"), newECKey(t, "ec"), newEdKey(t, "ed")}
+	v := newTestVerifier(NewFileKeySet(writeFile(t, "jwks.json", jwksOf(keys...)), 0), JWTConfig{})
+
+	for _, key := range keys {
+		t.Run(key.alg, func(t *testing.T) {
+			p, err := v.Verify(context.Background(), key.sign(t, validClaims()))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			want := &Principal{Subject: "user-1", Method: "jwt", Collections: []string{"nisar-*"}}
+			if !reflect.DeepEqual(p, want) {
+				t.Errorf("Expected %+v, got %+v", want, p)
+			}
+		})
+	}
+}
+
+func TestJWTVerifier_Rejects(t *testing.T) {
+	key := newRSAKey(t, "rsa")
+	other := newRSAKey(t, "rsa")
+	v := newTestVerifier(NewFileKeySet(writeFile(t, "jwks.json", jwksOf(key)), 0), JWTConfig{
+		Issuer:    "https://idp.example.com",
+		Audience:  "stac",
+		ClockSkew: time.Minute,
+	})
+
+	with := func(name string, value any) map[string]any {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	tamper := func(token string) string {
+		return token[:len(token)-4] + "AAAA"
+	}
+	unsigned := func(token string) string {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`))
+		_, rest, _ := strings.Cut(token, ".")
+		return header + "." + rest
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not-a-token"},
+		{"expired", key.sign(t, with("exp", testNow.Add(-2*time.Minute).Unix()))},
+		{"no expiry", key.sign(t, with("exp", nil))},
+		{"not yet valid", key.sign(t, with("nbf", testNow.Add(2*time.Minute).Unix()))},
+		{"wrong issuer", key.sign(t, with("iss", "https://evil.example.com"))},
+		{"wrong audience", key.sign(t, with("aud", "other"))},
+		{"wrong key", other.sign(t, validClaims())},
+		{"tampered", tamper(key.sign(t, validClaims()))},
+		{"alg none", unsigned(key.sign(t, validClaims()))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(context.Background(), tt.token); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+			}
+		})
+	}
+
+	// Within the clock skew, expiry and nbf are tolerated
+	if _, err := v.Verify(context.Background(), key.sign(t, with("exp", testNow.Add(-30*time.Second).Unix()))); err != nil {
+		t.Errorf("Expected a token expired within the skew to verify, got %v", err)
+	}
+}
+
+func TestJWTVerifier_ClaimPrefix(t *testing.T) {
+	key := newEdKey(t, "ed")
+	v := newTestVerifier(NewFileKeySet(writeFile(t, "jwks.json", jwksOf(key)), 0), JWTConfig{
+		CollectionsClaim: "scope",
+		ClaimPrefix:      "stac:",
+	})
+
+	claims := validClaims()
+	claims["scope"] = "openid profile stac:nisar-* stac:opera-rtc"
+	p, err := v.Verify(context.Background(), key.sign(t, claims))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if want := []string{"nisar-*", "opera-rtc"}; !reflect.DeepEqual(p.Collections, want) {
+		t.Errorf("Expected grants %v, got %v", want, p.Collections)
+	}
+}
+
+func TestKeySet_URLRotation(t *testing.T) {
+	oldKey := newECKey(t, "2024-01")
+	newKey := newECKey(t, "2024-06")
+	var jwks atomic.Value
+	jwks.Store(jwksOf(oldKey))
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(jwks.Load().(string)))
+	}))
+	defer srv.Close()
+
+	keys := NewURLKeySet(srv.URL, srv.Client(), time.Hour)
+	now := testNow
+	v := newTestVerifier(keys, JWTConfig{})
+	keys.now = func() time.Time { return now }
+
+	if _, err := v.Verify(context.Background(), oldKey.sign(t, validClaims())); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// A token of a key published since is verified after a refetch
+	jwks.Store(jwksOf(oldKey, newKey))
+	now = now.Add(time.Minute)
+	if _, err := v.Verify(context.Background(), newKey.sign(t, validClaims())); err != nil {
+		t.Fatalf("Expected the rotated key to be fetched, got %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected 2 fetches, got %d", got)
+	}
+
+	// Unknown key IDs do not make every request refetch the key set
+	unknown := newECKey(t, "unknown")
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(context.Background(), unknown.sign(t, validClaims())); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected no more fetches, got %d", got)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantLen int
+		wantErr bool
+	}{
+		{"encryption keys skipped", `{"keys":[{"kty":"RSA","use":"enc","n":"AQAB","e":"AQAB"},{"kty":"OKP","crv":"Ed25519","x":"` +
+			base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"}]}`, 1, false},
+		{"unknown key types skipped", `{"keys":[{"kty":"oct","k":"c2VjcmV0"},{"kty":"RSA","n":"AQAB","e":"AQAB"}]}`, 1, false},
+		{"no signing keys", `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`, 0, true},
+		{"point off the curve", `{"keys":[{"kty":"EC","crv":"P-256","x":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) +
+			`","y":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"}]}`, 0, true},
+		{"invalid json", `{`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseJWKS([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJWKS error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(keys) != tt.wantLen {
+				t.Errorf("Expected %d keys, got %d", tt.wantLen, len(keys))
+			}
+		})
+	}
+}
@@ -3,13 +3,19 @@ package backend
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"slices"
 
 	"github.com/robert-malhotra/asf-stac-proxy/internal/asf"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/translate"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
 )
 
 // ASFBackend implements SearchBackend for the ASF Search API.
@@ -19,6 +25,7 @@ type ASFBackend struct {
 	translator  *translate.Translator
 	cfg         *config.Config
 	logger      *slog.Logger
+	metrics     *metrics.Metrics
 }
 
 // NewASFBackend creates a new ASF backend.
@@ -38,6 +45,12 @@ func NewASFBackend(
 	}
 }
 
+// WithMetrics records the items the backend fails to translate.
+func (b *ASFBackend) WithMetrics(m *metrics.Metrics) *ASFBackend {
+	b.metrics = m
+	return b
+}
+
 // Name returns the backend name.
 func (b *ASFBackend) Name() string {
 	return "asf"
@@ -48,8 +61,16 @@ func (b *ASFBackend) SupportsPagination() bool {
 	return false
 }
 
+// FilterCapabilities returns the CQL2 equality predicates ASF applies upstream.
+func (b *ASFBackend) FilterCapabilities() FilterCapabilities {
+	return DefaultFilterCapabilities()
+}
+
 // Search executes a search against the ASF API.
 func (b *ASFBackend) Search(ctx context.Context, params *SearchParams) (*SearchResult, error) {
+	ctx, span := tracing.Start(ctx, "ASFBackend.Search")
+	defer span.End()
+
 	// Convert backend params to ASF params
 	asfParams, err := b.toASFParams(params)
 	if err != nil {
@@ -59,16 +80,76 @@ func (b *ASFBackend) Search(ctx context.Context, params *SearchParams) (*SearchR
 	// Execute search
 	resp, err := b.client.Search(ctx, *asfParams)
 	if err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("ASF search failed: %w", err)
 	}
 
-	// Convert ASF features to STAC items
-	items := make([]*stac.Item, 0, len(resp.Features))
-	for _, feature := range resp.Features {
-		// Determine collection ID from feature
-		collectionID := b.determineCollection(&feature)
-		item, err := translate.TranslateASFFeatureToItem(&feature, collectionID, b.cfg.STAC.BaseURL, b.cfg.STAC.Version)
+	// Convert ASF features to STAC items, determining the collection of each
+	items := b.translateFeatures(ctx, resp.Features, b.determineCollection)
+
+	return &SearchResult{
+		Items:      items,
+		TotalCount: resp.TotalCount,
+		// NextCursor is handled by the pagination layer, not the backend
+	}, nil
+}
+
+// Stack returns the interferometric stack of an item, found by an ASF
+// baseline search with the item's scene as reference. The stack is made of
+// products of the same processing level, so its items are served from the
+// collection of the reference.
+func (b *ASFBackend) Stack(ctx context.Context, collection, itemID string) (*SearchResult, error) {
+	ctx, span := tracing.Start(ctx, "ASFBackend.Stack")
+	defer span.End()
+	span.SetAttribute("collection.id", collection)
+	span.SetAttribute("item.id", itemID)
+
+	// Verify collection exists
+	if !b.collections.Has(collection) {
+		return nil, fmt.Errorf("collection %q not found", collection)
+	}
+
+	// The baseline search takes a scene name, so resolve the item first
+	reference, err := b.client.GetGranule(ctx, itemID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to fetch granule: %w", err)
+	}
+	if !featureInCollection(b.collections.Get(collection), reference) {
+		return nil, fmt.Errorf("granule not found in collection %q: %s", collection, itemID)
+	}
+
+	resp, err := b.client.Baseline(ctx, reference.Properties.SceneName, reference.Properties.ProcessingLevel)
+	if err != nil {
+		span.RecordError(err)
+		// ASF rejects references it has no baselines for
+		var upstreamErr *upstream.Error
+		if errors.As(err, &upstreamErr) && upstreamErr.StatusCode == http.StatusBadRequest {
+			return nil, fmt.Errorf("%w: %s", ErrNoStack, itemID)
+		}
+		return nil, fmt.Errorf("ASF baseline search failed: %w", err)
+	}
+	if len(resp.Features) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoStack, itemID)
+	}
+
+	items := b.translateFeatures(ctx, resp.Features, func(*asf.ASFFeature) string { return collection })
+	span.SetAttribute("stack.items", len(items))
+
+	return &SearchResult{Items: items}, nil
+}
+
+// translateFeatures converts ASF features to STAC items in the collections
+// given by collectionOf, skipping the features that fail to translate
+func (b *ASFBackend) translateFeatures(ctx context.Context, features []asf.ASFFeature, collectionOf func(*asf.ASFFeature) string) []*stac.Item {
+	_, translateSpan := tracing.Start(ctx, "translate ASF features")
+	defer translateSpan.End()
+
+	items := make([]*stac.Item, 0, len(features))
+	for _, feature := range features {
+		item, err := translate.TranslateASFFeatureToItem(&feature, collectionOf(&feature), b.cfg.STAC.BaseURL, b.cfg.STAC.Version, b.collections)
 		if err != nil {
+			b.metrics.TranslationFailure("asf")
 			b.logger.Warn("failed to translate ASF feature",
 				slog.String("feature_id", feature.Properties.FileID),
 				slog.String("error", err.Error()),
@@ -77,16 +158,19 @@ func (b *ASFBackend) Search(ctx context.Context, params *SearchParams) (*SearchR
 		}
 		items = append(items, item)
 	}
+	translateSpan.SetAttribute("translate.items", len(items))
+	translateSpan.SetAttribute("translate.failures", len(features)-len(items))
 
-	return &SearchResult{
-		Items:      items,
-		TotalCount: resp.TotalCount,
-		// NextCursor is handled by the pagination layer, not the backend
-	}, nil
+	return items
 }
 
 // GetItem retrieves a single item from ASF.
 func (b *ASFBackend) GetItem(ctx context.Context, collection, itemID string) (*stac.Item, error) {
+	ctx, span := tracing.Start(ctx, "ASFBackend.GetItem")
+	defer span.End()
+	span.SetAttribute("collection.id", collection)
+	span.SetAttribute("item.id", itemID)
+
 	// Verify collection exists
 	if !b.collections.Has(collection) {
 		return nil, fmt.Errorf("collection %q not found", collection)
@@ -95,12 +179,23 @@ func (b *ASFBackend) GetItem(ctx context.Context, collection, itemID string) (*s
 	// Fetch from ASF
 	feature, err := b.client.GetGranule(ctx, itemID)
 	if err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("failed to fetch granule: %w", err)
 	}
 
+	// Granules are looked up by name alone, so one of another collection,
+	// possibly a restricted one, is not found in this one
+	if !featureInCollection(b.collections.Get(collection), feature) {
+		return nil, fmt.Errorf("granule not found in collection %q: %s", collection, itemID)
+	}
+
 	// Convert to STAC item
-	item, err := translate.TranslateASFFeatureToItem(feature, collection, b.cfg.STAC.BaseURL, b.cfg.STAC.Version)
+	_, translateSpan := tracing.Start(ctx, "translate ASF feature")
+	item, err := translate.TranslateASFFeatureToItem(feature, collection, b.cfg.STAC.BaseURL, b.cfg.STAC.Version, b.collections)
+	translateSpan.RecordError(err)
+	translateSpan.End()
 	if err != nil {
+		b.metrics.TranslationFailure("asf")
 		return nil, fmt.Errorf("failed to translate feature: %w", err)
 	}
 
@@ -163,6 +258,15 @@ func (b *ASFBackend) toASFParams(params *SearchParams) (*asf.SearchParams, error
 	asfParams.AbsoluteOrbit = params.AbsoluteOrbit
 	asfParams.Platform = params.Platform
 
+	// ASF has no subswath parameter, so Subswath is never pushed down to it;
+	// full burst IDs name their subswath
+	asfParams.FullBurstID = params.BurstID
+
+	// Map grid and look angle filters, which ASF takes as ranges
+	asfParams.Frame = asfRanges(params.Frame)
+	asfParams.Path = asfRanges(params.Path)
+	asfParams.OffNadirAngle = asfRanges(params.OffNadirAngle)
+
 	// Apply processing level filter
 	// If user specified processing levels, use those
 	// Otherwise, use collection-configured level (each collection now has at most one)
@@ -200,30 +304,24 @@ func (b *ASFBackend) toASFParams(params *SearchParams) (*asf.SearchParams, error
 	return asfParams, nil
 }
 
+// asfRanges converts ranges to their ASF form
+func asfRanges(ranges []Range) []asf.Range {
+	if len(ranges) == 0 {
+		return nil
+	}
+	out := make([]asf.Range, len(ranges))
+	for i, r := range ranges {
+		out[i] = asf.Range{Min: r.Min, Max: r.Max}
+	}
+	return out
+}
+
 // determineCollection determines the STAC collection ID for an ASF feature.
 func (b *ASFBackend) determineCollection(feature *asf.ASFFeature) string {
-	platform := feature.Properties.Platform
-	processingLevel := feature.Properties.ProcessingLevel
-
 	// Find collection matching platform AND processing level
 	for _, coll := range b.collections.All() {
-		platformMatch := false
-		for _, p := range coll.ASFPlatforms {
-			if p == platform {
-				platformMatch = true
-				break
-			}
-		}
-		if platformMatch {
-			// If collection has a specific processing level, it must match
-			if coll.ASFProcessingLevel != "" {
-				if coll.ASFProcessingLevel == processingLevel {
-					return coll.ID
-				}
-			} else {
-				// Collection has no processing level filter (like UAVSAR), accept any
-				return coll.ID
-			}
+		if featureInCollection(coll, feature) {
+			return coll.ID
 		}
 	}
 
@@ -231,3 +329,14 @@ func (b *ASFBackend) determineCollection(feature *asf.ASFFeature) string {
 	return ""
 }
 
+// featureInCollection reports whether an ASF feature is an item of a
+// collection: from one of its platforms and, if it has one, at its
+// processing level.
+func featureInCollection(coll *config.CollectionConfig, feature *asf.ASFFeature) bool {
+	if coll == nil || !slices.Contains(coll.ASFPlatforms, feature.Properties.Platform) {
+		return false
+	}
+	// Collections with no processing level filter (like UAVSAR) accept any
+	return coll.ASFProcessingLevel == "" || coll.ASFProcessingLevel == feature.Properties.ProcessingLevel
+}
+
@@ -1,10 +1,17 @@
 package backend
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/robert-malhotra/asf-stac-proxy/internal/asf"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
 )
 
@@ -155,3 +162,146 @@ func TestASFBackend_toASFParams_MultipleIDs(t *testing.T) {
 		t.Errorf("MaxResults should not be set when IDs are provided, got %d", asfParams.MaxResults)
 	}
 }
+
+func TestASFBackend_Stack(t *testing.T) {
+	const reference = "S1A_IW_SLC__1SDV_20240101T000000_20240101T000027_051000_062000_ABCD"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/services/search/param":
+			if r.URL.Query().Get("granule_list") != reference+"-SLC" {
+				w.Write([]byte(`{"type": "FeatureCollection", "features": []}`))
+				return
+			}
+			w.Write([]byte(`{"type": "FeatureCollection", "features": [
+				{"type": "Feature", "properties": {"sceneName": "` + reference + `", "fileID": "` + reference + `-SLC", "platform": "Sentinel-1A", "processingLevel": "SLC"}}
+			]}`))
+		case "/services/search/baseline":
+			switch r.URL.Query().Get("reference") {
+			case reference:
+				if r.URL.Query().Get("processingLevel") != "SLC" {
+					t.Errorf("Expected the reference's processing level, got %s", r.URL.RawQuery)
+				}
+				w.Write([]byte(`{"type": "FeatureCollection", "features": [
+					{"type": "Feature", "properties": {"sceneName": "` + reference + `", "fileID": "` + reference + `-SLC", "platform": "Sentinel-1A", "processingLevel": "SLC", "temporalBaseline": 0, "perpendicularBaseline": 0}},
+					{"type": "Feature", "properties": {"sceneName": "S1A_IW_SLC__1SDV_20240113", "fileID": "S1A_IW_SLC__1SDV_20240113-SLC", "platform": "Sentinel-1A", "processingLevel": "SLC", "temporalBaseline": 12, "perpendicularBaseline": 35.2}}
+				]}`))
+			default:
+				http.Error(w, `{"error": "Requested reference scene has no baseline"}`, http.StatusBadRequest)
+			}
+		}
+	}))
+	defer server.Close()
+
+	b := createTestASFBackend()
+	b.client = asf.NewClient(server.URL, 5*time.Second)
+
+	result, err := b.Stack(context.Background(), "sentinel-1-slc", reference+"-SLC")
+	if err != nil {
+		t.Fatalf("Stack() error = %v", err)
+	}
+	if len(result.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(result.Items))
+	}
+	secondary := result.Items[1]
+	if secondary.Collection != "sentinel-1-slc" || secondary.Properties["insar:temporal_baseline"] != 12 ||
+		secondary.Properties["insar:perpendicular_baseline"] != 35.2 {
+		t.Errorf("Unexpected stack item %+v", secondary)
+	}
+
+	if _, err := b.Stack(context.Background(), "sentinel-1-slc", "S1A_UNKNOWN-SLC"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	if _, err := b.Stack(context.Background(), "unknown", reference+"-SLC"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error for the collection, got %v", err)
+	}
+}
+
+func TestASFBackend_Stack_NoBaseline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/search/baseline" {
+			http.Error(w, `{"error": "Requested reference scene has no baseline"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"sceneName": "S1A_IW_SLC__1SDV_20240101", "fileID": "S1A_IW_SLC__1SDV_20240101-SLC", "platform": "Sentinel-1A", "processingLevel": "SLC"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	b := createTestASFBackend()
+	b.client = asf.NewClient(server.URL, 5*time.Second)
+
+	if _, err := b.Stack(context.Background(), "sentinel-1-slc", "S1A_IW_SLC__1SDV_20240101-SLC"); !errors.Is(err, ErrNoStack) {
+		t.Errorf("Expected ErrNoStack, got %v", err)
+	}
+}
+
+func TestASFBackend_GetItem_Burst(t *testing.T) {
+	const burstName = "S1_136231_IW2_20200604T022312_VV_7C85-BURST"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("relativeBurstID") != "136231" {
+			w.Write([]byte(`{"type": "FeatureCollection", "features": []}`))
+			return
+		}
+		w.Write([]byte(`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"fileID": "` + burstName + `", "platform": "Sentinel-1A", "processingLevel": "BURST",
+				"burst": {"fullBurstID": "064_136231_IW2", "subswath": "IW2", "burstIndex": 7, "relativeBurstID": 136231}}}
+		]}`))
+	}))
+	defer server.Close()
+
+	b := createTestASFBackend()
+	b.client = asf.NewClient(server.URL, 5*time.Second)
+	_ = b.collections.Add(&config.CollectionConfig{
+		ID:                 "sentinel-1-bursts",
+		Title:              "Sentinel-1 Bursts",
+		Description:        "Test collection",
+		License:            "proprietary",
+		ASFDatasets:        []string{asf.BurstDataset},
+		ASFPlatforms:       []string{"Sentinel-1A", "Sentinel-1B"},
+		ASFProcessingLevel: "BURST",
+		Extent: config.Extent{
+			Spatial: config.SpatialExtent{
+				BBox: [][]float64{{-180, -90, 180, 90}},
+			},
+		},
+	})
+
+	item, err := b.GetItem(context.Background(), "sentinel-1-bursts", burstName)
+	if err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+	if item.Id != burstName || item.Properties["s1:burst_id"] != "064_136231_IW2" || item.Properties["s1:subswath"] != "IW2" {
+		t.Errorf("Unexpected burst item %s: %v", item.Id, item.Properties)
+	}
+
+	if _, err := b.GetItem(context.Background(), "sentinel-1-bursts", "S1_999999_IW1_20200604T022312_VV_0000-BURST"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+
+	// Granules are found by name alone, but not through another collection
+	if _, err := b.GetItem(context.Background(), "sentinel-1-slc", burstName); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error for another collection's granule, got %v", err)
+	}
+	if _, err := b.Stack(context.Background(), "sentinel-1-slc", burstName); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error for another collection's stack, got %v", err)
+	}
+}
+
+func TestASFBackend_toASFParams_BurstID(t *testing.T) {
+	b := createTestASFBackend()
+
+	asfParams, err := b.toASFParams(&SearchParams{
+		Collections: []string{"sentinel-1-slc"},
+		BurstID:     []string{"064_136231_IW2"},
+	})
+	if err != nil {
+		t.Fatalf("toASFParams() error = %v", err)
+	}
+	if len(asfParams.FullBurstID) != 1 || asfParams.FullBurstID[0] != "064_136231_IW2" {
+		t.Errorf("Expected the burst ID as fullBurstID, got %v", asfParams.FullBurstID)
+	}
+}
@@ -3,6 +3,7 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
@@ -56,11 +57,26 @@ type SearchParams struct {
 	ProcessingLevel []string
 	Platform        []string // Platform names (e.g., "Sentinel-1A", "Sentinel-1B")
 
+	// Sentinel-1 burst filters
+	BurstID  []string // Full burst IDs (e.g., "064_136231_IW2")
+	Subswath []string // Subswaths (e.g., "IW1")
+
+	// Grid and look angle filters, each a list of alternative values or ranges
+	Frame         []Range // Frame numbers
+	Path          []Range // Path numbers, which ASF filters as relative orbits
+	OffNadirAngle []Range // Off-nadir angles in degrees
+
 	// Sorting
 	SortField     string // Field to sort by
 	SortDirection string // "asc" or "desc"
 }
 
+// Range is an inclusive range of values. A range whose Min equals its Max
+// is a single value.
+type Range struct {
+	Min, Max float64
+}
+
 // SearchResult contains the results of a search query.
 type SearchResult struct {
 	// Items are the STAC items returned by the search
@@ -75,6 +91,103 @@ type SearchResult struct {
 	TotalCount *int
 }
 
+// PushdownMode describes how a backend can apply an equality filter on a
+// STAC property upstream. For the properties SearchParams holds as ranges,
+// a CQL2 "between" range counts as one value.
+type PushdownMode int
+
+const (
+	// PushdownNone means the property cannot be filtered upstream.
+	PushdownNone PushdownMode = iota
+	// PushdownSingle means a single value can be filtered upstream exactly.
+	PushdownSingle
+	// PushdownMulti means a list of alternative values (CQL2 "in") can be
+	// filtered upstream exactly.
+	PushdownMulti
+)
+
+// FilterCapabilities maps STAC property names to how a backend applies
+// equality predicates on them through SearchParams.
+type FilterCapabilities map[string]PushdownMode
+
+// FilterPushdown is implemented by backends that declare which CQL2 predicates
+// they can apply upstream with exactly the semantics of in-proxy evaluation.
+// Predicates outside these capabilities are evaluated by the proxy.
+type FilterPushdown interface {
+	FilterCapabilities() FilterCapabilities
+}
+
+// DefaultFilterCapabilities returns the capabilities assumed for backends that
+// do not implement FilterPushdown. These match the ASF Search API, which
+// accepts comma-separated value lists for each of these parameters.
+func DefaultFilterCapabilities() FilterCapabilities {
+	return FilterCapabilities{
+		"platform":            PushdownMulti,
+		"sar:instrument_mode": PushdownMulti,
+		"sar:polarizations":   PushdownMulti,
+		"sar:product_type":    PushdownMulti,
+		"sat:orbit_state":     PushdownSingle,
+		"sat:relative_orbit":  PushdownMulti,
+		"sat:absolute_orbit":  PushdownMulti,
+		"s1:burst_id":         PushdownMulti,
+		"asf:frame":           PushdownMulti,
+		"asf:path":            PushdownMulti,
+		"view:off_nadir":      PushdownMulti,
+	}
+}
+
+// CapabilitiesOf returns the filter capabilities of a backend.
+func CapabilitiesOf(b SearchBackend) FilterCapabilities {
+	if fp, ok := b.(FilterPushdown); ok {
+		return fp.FilterCapabilities()
+	}
+	return DefaultFilterCapabilities()
+}
+
+// AscendingSort is implemented by backends that can return results ordered
+// by ascending start time. Backends without it always return the newest
+// results first and the proxy establishes ascending order itself.
+type AscendingSort interface {
+	SupportsAscendingSort() bool
+}
+
+// SupportsAscendingSort reports whether a backend can order results by
+// ascending start time upstream.
+func SupportsAscendingSort(b SearchBackend) bool {
+	if as, ok := b.(AscendingSort); ok {
+		return as.SupportsAscendingSort()
+	}
+	return false
+}
+
+// Counter is implemented by backends that can count the items matching a
+// search without returning them. Aggregations are computed from these counts
+// where possible instead of scanning results.
+type Counter interface {
+	Count(ctx context.Context, params *SearchParams) (int, error)
+}
+
+// StatusReporter is implemented by backends with internal state worth
+// reporting in /health, such as the circuit breakers of a Failover.
+type StatusReporter interface {
+	Status() any
+}
+
+// ErrNotSupported is returned by backends asked for an operation they
+// cannot serve, such as a Failover whose active backend lacks it.
+var ErrNotSupported = errors.New("operation not supported by the search backend")
+
+// ErrNoStack is returned for items that have no interferometric stack, such
+// as products that were not processed for interferometry.
+var ErrNoStack = errors.New("item has no interferometric stack")
+
+// Stacker is implemented by backends that can find the interferometric stack
+// of a reference item: the items it forms interferograms with, each carrying
+// its temporal and perpendicular baselines relative to the reference.
+type Stacker interface {
+	Stack(ctx context.Context, collection, itemID string) (*SearchResult, error)
+}
+
 // DatetimeRange represents a temporal range for filtering.
 type DatetimeRange struct {
 	Start *time.Time
@@ -0,0 +1,197 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call through while tracking failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects calls until the open timeout has passed.
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe call through; its outcome closes
+	// or reopens the breaker.
+	BreakerHalfOpen
+)
+
+// String returns the state name reported in /health.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig configures when a circuit breaker trips and recovers.
+type BreakerConfig struct {
+	// Window is the number of recent calls the failure rate is computed over
+	Window int
+	// MinRequests is the number of calls in the window needed before the
+	// breaker can trip
+	MinRequests int
+	// FailureRate is the fraction of failed calls in the window, between 0
+	// and 1, at which the breaker trips
+	FailureRate float64
+	// OpenTimeout is how long the breaker stays open before probing the
+	// upstream again
+	OpenTimeout time.Duration
+}
+
+// DefaultBreakerConfig returns the breaker settings used unless configured.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		Window:      20,
+		MinRequests: 10,
+		FailureRate: 0.5,
+		OpenTimeout: 30 * time.Second,
+	}
+}
+
+// Outcome is the result of a call guarded by a circuit breaker.
+type Outcome int
+
+const (
+	// OutcomeSuccess is a call the upstream answered.
+	OutcomeSuccess Outcome = iota
+	// OutcomeFailure is a call the upstream failed.
+	OutcomeFailure
+	// OutcomeIgnored is a call that says nothing about the upstream's
+	// health, such as an invalid request or one the client abandoned.
+	OutcomeIgnored
+)
+
+// Breaker is a circuit breaker tracking the failure rate of an upstream over
+// a window of recent calls. It is safe for concurrent use.
+type Breaker struct {
+	mu       sync.Mutex
+	cfg      BreakerConfig
+	state    BreakerState
+	outcomes []bool // ring buffer of recent calls, true for a failure
+	next     int
+	count    int
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// NewBreaker creates a closed circuit breaker.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.Window < 1 {
+		cfg.Window = 1
+	}
+	return &Breaker{
+		cfg:      cfg,
+		outcomes: make([]bool, cfg.Window),
+		now:      time.Now,
+	}
+}
+
+// Allow reports whether a call may go to the upstream. Every allowed call
+// must be followed by a Record of its outcome.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record records the outcome of an allowed call.
+func (b *Breaker) Record(outcome Outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probing = false
+		switch outcome {
+		case OutcomeSuccess:
+			b.state = BreakerClosed
+			b.reset()
+		case OutcomeFailure:
+			b.trip()
+		}
+	case BreakerClosed:
+		if outcome == OutcomeIgnored {
+			return
+		}
+		failed := outcome == OutcomeFailure
+		if b.count == len(b.outcomes) {
+			if b.outcomes[b.next] {
+				b.failures--
+			}
+		} else {
+			b.count++
+		}
+		b.outcomes[b.next] = failed
+		b.next = (b.next + 1) % len(b.outcomes)
+		if failed {
+			b.failures++
+		}
+		if b.count >= b.cfg.MinRequests && float64(b.failures) >= b.cfg.FailureRate*float64(b.count) {
+			b.trip()
+		}
+	}
+}
+
+// trip opens the breaker; the caller holds the lock
+func (b *Breaker) trip() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.reset()
+}
+
+// reset clears the window; the caller holds the lock
+func (b *Breaker) reset() {
+	b.next, b.count, b.failures = 0, 0, 0
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// BreakerStats describes a circuit breaker in /health.
+type BreakerStats struct {
+	State string `json:"state"`
+	// Requests and Failures count the calls in the current window
+	Requests int `json:"requests"`
+	Failures int `json:"failures"`
+}
+
+// Stats returns the breaker's state and current window.
+func (b *Breaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BreakerStats{
+		State:    b.state.String(),
+		Requests: b.count,
+		Failures: b.failures,
+	}
+}
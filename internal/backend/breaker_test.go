@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker_TripsOnFailureRate(t *testing.T) {
+	b := NewBreaker(BreakerConfig{Window: 4, MinRequests: 4, FailureRate: 0.5, OpenTimeout: time.Minute})
+
+	// Below the minimum number of requests the breaker stays closed
+	for i := 0; i < 3; i++ {
+		if !b.Allow() {
+			t.Fatal("Expected a closed breaker to allow calls")
+		}
+		b.Record(OutcomeFailure)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("Expected the breaker to stay closed below MinRequests, got %s", b.State())
+	}
+
+	// Ignored outcomes do not count
+	b.Record(OutcomeIgnored)
+	if stats := b.Stats(); stats.Requests != 3 || stats.Failures != 3 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	b.Record(OutcomeSuccess)
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected 3 failures in 4 calls to trip the breaker, got %s", b.State())
+	}
+	if b.Allow() {
+		t.Error("Expected an open breaker to reject calls")
+	}
+}
+
+func TestBreaker_SlidingWindow(t *testing.T) {
+	b := NewBreaker(BreakerConfig{Window: 4, MinRequests: 4, FailureRate: 0.75, OpenTimeout: time.Minute})
+
+	// Old failures leave the window as new calls succeed
+	for _, outcome := range []Outcome{OutcomeFailure, OutcomeFailure, OutcomeSuccess, OutcomeSuccess, OutcomeSuccess, OutcomeFailure, OutcomeFailure} {
+		b.Record(outcome)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("Expected the breaker to stay closed, got %s", b.State())
+	}
+	if stats := b.Stats(); stats.Requests != 4 || stats.Failures != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	b.Record(OutcomeFailure)
+	if b.State() != BreakerOpen {
+		t.Errorf("Expected 3 failures in the last 4 calls to trip the breaker, got %s", b.State())
+	}
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(BreakerConfig{Window: 1, MinRequests: 1, FailureRate: 1, OpenTimeout: 30 * time.Second})
+	b.now = func() time.Time { return now }
+
+	b.Record(OutcomeFailure)
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected an open breaker, got %s", b.State())
+	}
+
+	now = now.Add(30 * time.Second)
+	if !b.Allow() {
+		t.Fatal("Expected a probe once the open timeout has passed")
+	}
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("Expected a half-open breaker, got %s", b.State())
+	}
+	if b.Allow() {
+		t.Error("Expected a single probe at a time")
+	}
+
+	// An ignored probe lets another through
+	b.Record(OutcomeIgnored)
+	if !b.Allow() {
+		t.Fatal("Expected another probe after an ignored one")
+	}
+
+	// A failed probe reopens the breaker
+	b.Record(OutcomeFailure)
+	if b.State() != BreakerOpen || b.Allow() {
+		t.Fatalf("Expected a failed probe to reopen the breaker, got %s", b.State())
+	}
+
+	// A successful probe closes it
+	now = now.Add(30 * time.Second)
+	if !b.Allow() {
+		t.Fatal("Expected a probe")
+	}
+	b.Record(OutcomeSuccess)
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Errorf("Expected a successful probe to close the breaker, got %s", b.State())
+	}
+}
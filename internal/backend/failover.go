@@ -0,0 +1,223 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
+)
+
+// ErrNoBackendAvailable is returned when the circuit breakers of every
+// backend of a Failover are open.
+var ErrNoBackendAvailable = errors.New("no search backend available: all circuit breakers are open")
+
+// Failover is a SearchBackend that sends calls to a primary backend and
+// fails over to a secondary one, each guarded by its own circuit breaker.
+// While the primary's breaker is open every call goes to the secondary; a
+// call that fails upstream on the primary is also repeated on the secondary.
+//
+// Pages may be served by either backend, so Failover only offers what both
+// support: native pagination only when both page natively (otherwise the
+// time-window cursors shared by both are used, which stay valid across a
+// switch since ASF and CMR identify granules by the same name), ascending
+// sort only when both sort upstream, and for each property the weaker of the
+// two pushdown modes.
+type Failover struct {
+	backends [2]SearchBackend
+	breakers [2]*Breaker
+	logger   *slog.Logger
+}
+
+// NewFailover creates a failover backend from a primary and a secondary
+// backend, with a circuit breaker for each configured by cfg.
+func NewFailover(primary, secondary SearchBackend, cfg BreakerConfig, logger *slog.Logger) *Failover {
+	return &Failover{
+		backends: [2]SearchBackend{primary, secondary},
+		breakers: [2]*Breaker{NewBreaker(cfg), NewBreaker(cfg)},
+		logger:   logger,
+	}
+}
+
+// ServedBy records which backend of a Failover served the calls made with a
+// context, which may differ from the one requests were routed to when they
+// arrived if the primary fails mid-request.
+type ServedBy struct {
+	mu   sync.Mutex
+	name string
+}
+
+type servedByKey struct{}
+
+// WithServedBy returns a context whose Failover calls are recorded in the
+// returned ServedBy.
+func WithServedBy(ctx context.Context) (context.Context, *ServedBy) {
+	s := &ServedBy{}
+	return context.WithValue(ctx, servedByKey{}, s), s
+}
+
+// Name returns the name of the backend that served the last call, or "" if
+// none has.
+func (s *ServedBy) Name() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.name
+}
+
+// recordServedBy records the backend that served a call made with ctx
+func recordServedBy(ctx context.Context, name string) {
+	if s, ok := ctx.Value(servedByKey{}).(*ServedBy); ok {
+		s.mu.Lock()
+		s.name = name
+		s.mu.Unlock()
+	}
+}
+
+// Name returns the name of the backend calls are currently routed to: the
+// primary unless its circuit breaker is open.
+func (f *Failover) Name() string {
+	return f.backends[f.active()].Name()
+}
+
+// active returns the index of the backend calls are currently routed to
+func (f *Failover) active() int {
+	if f.breakers[0].State() == BreakerOpen && f.breakers[1].State() != BreakerOpen {
+		return 1
+	}
+	return 0
+}
+
+// SupportsPagination reports whether both backends paginate natively.
+func (f *Failover) SupportsPagination() bool {
+	return f.backends[0].SupportsPagination() && f.backends[1].SupportsPagination()
+}
+
+// SupportsAscendingSort reports whether both backends sort ascending upstream.
+func (f *Failover) SupportsAscendingSort() bool {
+	return SupportsAscendingSort(f.backends[0]) && SupportsAscendingSort(f.backends[1])
+}
+
+// FilterCapabilities returns the pushdowns both backends apply exactly.
+func (f *Failover) FilterCapabilities() FilterCapabilities {
+	secondary := CapabilitiesOf(f.backends[1])
+	capabilities := FilterCapabilities{}
+	for property, mode := range CapabilitiesOf(f.backends[0]) {
+		if other, ok := secondary[property]; ok {
+			capabilities[property] = min(mode, other)
+		}
+	}
+	return capabilities
+}
+
+// Search executes a search on the first backend available.
+func (f *Failover) Search(ctx context.Context, params *SearchParams) (*SearchResult, error) {
+	return failoverCall(ctx, f, "search", func(b SearchBackend) (*SearchResult, error) {
+		// Each backend gets its own copy, so nothing one sets leaks to the other
+		p := *params
+		return b.Search(ctx, &p)
+	})
+}
+
+// GetItem retrieves an item from the first backend available.
+func (f *Failover) GetItem(ctx context.Context, collection, itemID string) (*stac.Item, error) {
+	return failoverCall(ctx, f, "get item", func(b SearchBackend) (*stac.Item, error) {
+		return b.GetItem(ctx, collection, itemID)
+	})
+}
+
+// Stack retrieves the interferometric stack of an item from the first
+// backend available, failing with ErrNotSupported on backends without stacks.
+func (f *Failover) Stack(ctx context.Context, collection, itemID string) (*SearchResult, error) {
+	return failoverCall(ctx, f, "stack", func(b SearchBackend) (*SearchResult, error) {
+		stacker, ok := b.(Stacker)
+		if !ok {
+			return nil, ErrNotSupported
+		}
+		return stacker.Stack(ctx, collection, itemID)
+	})
+}
+
+// Count counts the items matching a search on the first backend available,
+// failing with ErrNotSupported on backends that cannot count.
+func (f *Failover) Count(ctx context.Context, params *SearchParams) (int, error) {
+	return failoverCall(ctx, f, "count", func(b SearchBackend) (int, error) {
+		counter, ok := b.(Counter)
+		if !ok {
+			return 0, ErrNotSupported
+		}
+		p := *params
+		return counter.Count(ctx, &p)
+	})
+}
+
+// failoverCall runs call on each backend in turn whose breaker allows it,
+// until one succeeds or fails for a reason other than an upstream failure
+func failoverCall[T any](ctx context.Context, f *Failover, op string, call func(SearchBackend) (T, error)) (T, error) {
+	var zero T
+	err := ErrNoBackendAvailable
+	for i, b := range f.backends {
+		if !f.breakers[i].Allow() {
+			continue
+		}
+
+		var result T
+		result, err = call(b)
+		outcome := outcomeOf(ctx, err)
+		f.breakers[i].Record(outcome)
+		if outcome != OutcomeFailure {
+			recordServedBy(ctx, b.Name())
+			return result, err
+		}
+
+		if i == 0 {
+			f.logger.WarnContext(ctx, "primary backend failed, failing over",
+				slog.String("operation", op),
+				slog.String("primary", b.Name()),
+				slog.String("secondary", f.backends[1].Name()),
+				slog.String("breaker", f.breakers[i].State().String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	return zero, err
+}
+
+// outcomeOf classifies the result of a backend call for its breaker. Only
+// failures of the upstream service itself count against it: transport
+// errors, retryable statuses and server errors.
+func outcomeOf(ctx context.Context, err error) Outcome {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	if ctx.Err() != nil {
+		return OutcomeIgnored
+	}
+	var upstreamErr *upstream.Error
+	if errors.As(err, &upstreamErr) &&
+		(upstreamErr.Retryable || upstreamErr.StatusCode == 0 || upstreamErr.StatusCode >= 500) {
+		return OutcomeFailure
+	}
+	return OutcomeIgnored
+}
+
+// FailoverStatus describes a failover backend in /health.
+type FailoverStatus struct {
+	// Active is the backend calls are currently routed to
+	Active string `json:"active"`
+	// Breakers are the circuit breakers by backend name
+	Breakers map[string]BreakerStats `json:"breakers"`
+}
+
+// Status returns the active backend and the state of each circuit breaker.
+func (f *Failover) Status() any {
+	status := FailoverStatus{
+		Active:   f.Name(),
+		Breakers: make(map[string]BreakerStats, len(f.backends)),
+	}
+	for i, b := range f.backends {
+		status.Breakers[b.Name()] = f.breakers[i].Stats()
+	}
+	return status
+}
@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
+)
+
+// stubBackend returns a fixed error, or one item named after the backend
+type stubBackend struct {
+	name         string
+	err          error
+	pagination   bool
+	capabilities FilterCapabilities
+	calls        int
+}
+
+func (s *stubBackend) Search(ctx context.Context, params *SearchParams) (*SearchResult, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &SearchResult{Items: []*stac.Item{{Id: s.name}}}, nil
+}
+
+func (s *stubBackend) GetItem(ctx context.Context, collection, itemID string) (*stac.Item, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &stac.Item{Id: s.name}, nil
+}
+
+func (s *stubBackend) Name() string                           { return s.name }
+func (s *stubBackend) SupportsPagination() bool               { return s.pagination }
+func (s *stubBackend) FilterCapabilities() FilterCapabilities { return s.capabilities }
+
+func newTestFailover(primary, secondary *stubBackend) *Failover {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	return NewFailover(primary, secondary, BreakerConfig{
+		Window:      4,
+		MinRequests: 2,
+		FailureRate: 0.5,
+		OpenTimeout: time.Minute,
+	}, logger)
+}
+
+func TestFailover_FailsOverOnUpstreamFailure(t *testing.T) {
+	unavailable := fmt.Errorf("ASF search failed: %w", &upstream.Error{Service: "ASF", StatusCode: 503, Retryable: true})
+	primary := &stubBackend{name: "asf", err: unavailable}
+	secondary := &stubBackend{name: "cmr"}
+	f := newTestFailover(primary, secondary)
+
+	ctx, served := WithServedBy(context.Background())
+	result, err := f.Search(ctx, &SearchParams{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if result.Items[0].Id != "cmr" {
+		t.Errorf("Expected the secondary to answer, got %s", result.Items[0].Id)
+	}
+	if served.Name() != "cmr" {
+		t.Errorf("Expected the call to be recorded as served by the secondary, got %q", served.Name())
+	}
+	if f.Name() != "asf" {
+		t.Errorf("Expected the primary to stay active after one failure, got %s", f.Name())
+	}
+
+	// A second failure trips the primary's breaker, after which it is skipped
+	if _, err := f.GetItem(context.Background(), "sentinel-1", "S1A"); err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+	if f.Name() != "cmr" {
+		t.Errorf("Expected the secondary to be active, got %s", f.Name())
+	}
+	f.Search(context.Background(), &SearchParams{})
+	if primary.calls != 2 || secondary.calls != 3 {
+		t.Errorf("Expected 2 primary and 3 secondary calls, got %d and %d", primary.calls, secondary.calls)
+	}
+
+	status := f.Status().(FailoverStatus)
+	if status.Active != "cmr" || status.Breakers["asf"].State != "open" || status.Breakers["cmr"].State != "closed" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+func TestFailover_DoesNotFailOverOnRequestErrors(t *testing.T) {
+	for name, err := range map[string]error{
+		"bad request": &upstream.Error{Service: "ASF", StatusCode: 400},
+		"not found":   errors.New("failed to fetch granule: granule not found: S1A"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			primary := &stubBackend{name: "asf", err: err}
+			secondary := &stubBackend{name: "cmr"}
+			f := newTestFailover(primary, secondary)
+
+			for i := 0; i < 3; i++ {
+				if _, got := f.GetItem(context.Background(), "sentinel-1", "S1A"); got != err {
+					t.Errorf("Expected the primary's error, got %v", got)
+				}
+			}
+			if secondary.calls != 0 || f.Name() != "asf" {
+				t.Errorf("Expected no failover, got %d secondary calls and %s active", secondary.calls, f.Name())
+			}
+		})
+	}
+}
+
+func TestFailover_NoBackendAvailable(t *testing.T) {
+	down := &upstream.Error{Service: "ASF", Err: errors.New("connection reset"), Retryable: true}
+	f := newTestFailover(&stubBackend{name: "asf", err: down}, &stubBackend{name: "cmr", err: down})
+
+	for i := 0; i < 2; i++ {
+		if _, err := f.Search(context.Background(), &SearchParams{}); !upstream.IsRetryable(err) {
+			t.Errorf("Expected the upstream failure, got %v", err)
+		}
+	}
+	if _, err := f.Search(context.Background(), &SearchParams{}); !errors.Is(err, ErrNoBackendAvailable) {
+		t.Errorf("Expected ErrNoBackendAvailable, got %v", err)
+	}
+}
+
+func TestFailover_Capabilities(t *testing.T) {
+	primary := &stubBackend{name: "asf", capabilities: DefaultFilterCapabilities()}
+	secondary := &stubBackend{name: "cmr", pagination: true, capabilities: FilterCapabilities{
+		"sar:instrument_mode": PushdownSingle,
+		"sat:orbit_state":     PushdownSingle,
+		"sat:frame":           PushdownMulti,
+	}}
+	f := newTestFailover(primary, secondary)
+
+	// Cursors must work on both backends, so time windows are used
+	if f.SupportsPagination() {
+		t.Error("Expected no native pagination unless both backends page natively")
+	}
+	if SupportsAscendingSort(f) {
+		t.Error("Expected no ascending sort unless both backends sort ascending")
+	}
+
+	want := FilterCapabilities{"sar:instrument_mode": PushdownSingle, "sat:orbit_state": PushdownSingle}
+	got := CapabilitiesOf(f)
+	if len(got) != len(want) {
+		t.Fatalf("FilterCapabilities() = %v, want %v", got, want)
+	}
+	for property, mode := range want {
+		if got[property] != mode {
+			t.Errorf("FilterCapabilities()[%s] = %v, want %v", property, got[property], mode)
+		}
+	}
+}
+
+// stackingBackend is a stubBackend that also finds stacks
+type stackingBackend struct {
+	stubBackend
+}
+
+func (s *stackingBackend) Stack(ctx context.Context, collection, itemID string) (*SearchResult, error) {
+	return s.Search(ctx, &SearchParams{})
+}
+
+func TestFailover_Stack(t *testing.T) {
+	unavailable := &upstream.Error{Service: "ASF", StatusCode: 503, Retryable: true}
+	primary := &stackingBackend{stubBackend{name: "asf"}}
+	secondary := &stubBackend{name: "cmr"}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	f := NewFailover(primary, secondary, BreakerConfig{Window: 4, MinRequests: 2, FailureRate: 0.5, OpenTimeout: time.Minute}, logger)
+
+	result, err := f.Stack(context.Background(), "sentinel-1", "S1A")
+	if err != nil || result.Items[0].Id != "asf" {
+		t.Fatalf("Expected the primary's stack, got %v (%v)", result, err)
+	}
+
+	// The secondary cannot find stacks, so failing over reports that
+	primary.err = unavailable
+	if _, err := f.Stack(context.Background(), "sentinel-1", "S1A"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("Expected no calls to the secondary, got %d", secondary.calls)
+	}
+}
+
+// countingBackend is a stubBackend that also counts items
+type countingBackend struct {
+	stubBackend
+	count int
+}
+
+func (c *countingBackend) Count(ctx context.Context, params *SearchParams) (int, error) {
+	c.calls++
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.count, nil
+}
+
+func TestFailover_Count(t *testing.T) {
+	unavailable := &upstream.Error{Service: "ASF", StatusCode: 503, Retryable: true}
+	primary := &countingBackend{stubBackend: stubBackend{name: "asf"}, count: 42}
+	secondary := &countingBackend{stubBackend: stubBackend{name: "cmr"}, count: 41}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	f := NewFailover(primary, secondary, BreakerConfig{Window: 4, MinRequests: 2, FailureRate: 0.5, OpenTimeout: time.Minute}, logger)
+
+	var _ Counter = f
+	if count, err := f.Count(context.Background(), &SearchParams{}); err != nil || count != 42 {
+		t.Fatalf("Expected the primary's count, got %d (%v)", count, err)
+	}
+
+	primary.err = unavailable
+	if count, err := f.Count(context.Background(), &SearchParams{}); err != nil || count != 41 {
+		t.Errorf("Expected the secondary's count, got %d (%v)", count, err)
+	}
+
+	// A backend that cannot count reports that
+	g := NewFailover(&stubBackend{name: "asf"}, secondary, BreakerConfig{Window: 4, MinRequests: 2, FailureRate: 0.5, OpenTimeout: time.Minute}, logger)
+	if _, err := g.Count(context.Background(), &SearchParams{}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Package cache provides a read-through cache for upstream ASF and CMR responses.
+package cache
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cache stores upstream responses. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the response stored under key, if present and not expired
+	Get(key string) (*Response, bool)
+
+	// Set stores a response under key for ttl
+	Set(key string, response *Response, ttl time.Duration)
+}
+
+// Response is an upstream response body along with the response headers the
+// clients read. Cached responses are shared and must not be modified.
+type Response struct {
+	Body   []byte
+	Header http.Header
+}
+
+// size approximates the memory held by a response
+func (r *Response) size() int64 {
+	n := int64(len(r.Body))
+	for name, values := range r.Header {
+		n += int64(len(name))
+		for _, v := range values {
+			n += int64(len(v))
+		}
+	}
+	return n
+}
+
+// TTLs sets how long responses are cached by kind of upstream request. A
+// zero TTL disables caching for that kind.
+type TTLs struct {
+	// Search applies to item searches, whose results change as new
+	// acquisitions are ingested
+	Search time.Duration
+	// Item applies to single item lookups, which rarely change
+	Item time.Duration
+}
+
+// Key returns the cache key of a GET request: the URL with its query
+// parameters sorted by name and value, followed by the given request headers
+// that select the response (such as CMR-Search-After). Parameter order does
+// not change what the upstream services return, so equivalent queries share
+// an entry.
+func Key(u *url.URL, header http.Header, names ...string) string {
+	query := u.Query()
+	for _, values := range query {
+		sort.Strings(values)
+	}
+
+	normalized := *u
+	normalized.RawQuery = query.Encode()
+	normalized.Fragment = ""
+
+	var key strings.Builder
+	key.WriteString(normalized.String())
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			key.WriteString("\n")
+			key.WriteString(http.CanonicalHeaderKey(name))
+			key.WriteString(": ")
+			key.WriteString(value)
+		}
+	}
+	return key.String()
+}
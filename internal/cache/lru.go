@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruEntry is a cached response in the recency list
+type lruEntry struct {
+	key       string
+	response  *Response
+	size      int64
+	expiresAt time.Time
+}
+
+// LRU is an in-memory Cache bounded by the total size of the responses it
+// holds. When a new response does not fit, the least recently used entries
+// are evicted. Expired entries are dropped when they are next read, or
+// evicted like any other.
+type LRU struct {
+	mu        sync.Mutex
+	maxBytes  int64
+	bytes     int64
+	entries   map[string]*list.Element
+	recency   *list.List // front is most recently used
+	evictions int64
+	now       func() time.Time
+}
+
+// NewLRU creates an LRU cache holding at most maxBytes of responses.
+func NewLRU(maxBytes int64) *LRU {
+	return &LRU{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		recency:  list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns the response stored under key, if present and not expired.
+func (c *LRU) Get(key string) (*Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.recency.MoveToFront(elem)
+	return entry.response, true
+}
+
+// Set stores a response under key for ttl. Responses larger than the whole
+// cache are not stored.
+func (c *LRU) Set(key string, response *Response, ttl time.Duration) {
+	size := response.size() + int64(len(key))
+	if ttl <= 0 || size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	for c.bytes+size > c.maxBytes {
+		c.remove(c.recency.Back())
+		c.evictions++
+	}
+
+	c.entries[key] = c.recency.PushFront(&lruEntry{
+		key:       key,
+		response:  response,
+		size:      size,
+		expiresAt: c.now().Add(ttl),
+	})
+	c.bytes += size
+}
+
+// remove deletes an entry; the caller holds the lock
+func (c *LRU) remove(elem *list.Element) {
+	entry := c.recency.Remove(elem).(*lruEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+}
+
+// Usage returns the number of entries held, their total size in bytes, and
+// the number of entries evicted to make room so far.
+func (c *LRU) Usage() (entries int, bytes int64, evictions int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), c.bytes, c.evictions
+}
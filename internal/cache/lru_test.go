@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(30)
+	body := func(n int) *Response { return &Response{Body: []byte(strings.Repeat("x", n))} }
+
+	c.Set("a", body(9), time.Minute)
+	c.Set("b", body(9), time.Minute)
+	c.Set("c", body(9), time.Minute)
+
+	// Reading a makes b the least recently used entry
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	c.Set("d", body(9), time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Expected %s to be cached", key)
+		}
+	}
+
+	entries, bytes, evictions := c.Usage()
+	if entries != 3 || bytes != 30 || evictions != 1 {
+		t.Errorf("Usage() = %d, %d, %d, want 3, 30, 1", entries, bytes, evictions)
+	}
+
+	// A response larger than the whole cache is not stored
+	c.Set("e", body(40), time.Minute)
+	if _, ok := c.Get("e"); ok {
+		t.Error("Expected an oversized response not to be cached")
+	}
+	if entries, _, _ := c.Usage(); entries != 3 {
+		t.Errorf("Expected the oversized response to evict nothing, got %d entries", entries)
+	}
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(1 << 10)
+	c.now = func() time.Time { return now }
+
+	c.Set("search", &Response{Body: []byte("{}")}, 30*time.Second)
+	c.Set("item", &Response{Body: []byte("{}")}, 10*time.Minute)
+	c.Set("disabled", &Response{Body: []byte("{}")}, 0)
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("search"); ok {
+		t.Error("Expected the search response to have expired")
+	}
+	if _, ok := c.Get("item"); !ok {
+		t.Error("Expected the item response to be cached")
+	}
+	if _, ok := c.Get("disabled"); ok {
+		t.Error("Expected a zero TTL not to be cached")
+	}
+	if entries, _, _ := c.Usage(); entries != 1 {
+		t.Errorf("Expected the expired entry to be dropped, got %d entries", entries)
+	}
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Loader fetches a response from upstream on a cache miss
+type Loader func(ctx context.Context) (*Response, error)
+
+// inflight is a load shared by every request for the same key
+type inflight struct {
+	done     chan struct{}
+	response *Response
+	err      error
+}
+
+// ReadThrough serves responses from a Cache and loads them from upstream on
+// a miss. Concurrent misses for the same key wait for a single upstream
+// request. Failed loads are not cached.
+type ReadThrough struct {
+	cache Cache
+	// maxLoad bounds shared loads started by requests without a deadline
+	maxLoad time.Duration
+
+	mu    sync.Mutex
+	loads map[string]*inflight
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	shared atomic.Int64
+}
+
+// NewReadThrough creates a read-through cache backed by c. Shared loads
+// started by requests without a deadline are cancelled after maxLoad; zero
+// leaves them unbounded.
+func NewReadThrough(c Cache, maxLoad time.Duration) *ReadThrough {
+	return &ReadThrough{
+		cache:   c,
+		maxLoad: maxLoad,
+		loads:   make(map[string]*inflight),
+	}
+}
+
+// Fetch returns the response cached under key, or calls load and caches its
+// response for ttl. A nil ReadThrough or a zero ttl calls load directly.
+//
+// The shared load runs detached from the cancellation of the request that
+// started it, so that a client going away does not fail the others waiting
+// on it; each caller still returns as soon as its own context is done. The
+// load keeps that request's deadline, or maxLoad without one, so upstream
+// retries stay bounded.
+func (r *ReadThrough) Fetch(ctx context.Context, key string, ttl time.Duration, load Loader) (*Response, error) {
+	if r == nil || ttl <= 0 {
+		return load(ctx)
+	}
+
+	if response, ok := r.cache.Get(key); ok {
+		r.hits.Add(1)
+		return response, nil
+	}
+
+	r.mu.Lock()
+	call, ok := r.loads[key]
+	if ok {
+		r.shared.Add(1)
+	} else {
+		r.misses.Add(1)
+		call = &inflight{done: make(chan struct{})}
+		r.loads[key] = call
+		loadCtx, cancel := r.detach(ctx)
+		go r.load(loadCtx, cancel, key, ttl, load, call)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.response, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// detach returns a context for a shared load: without the cancellation of
+// ctx, but with its deadline or, if it has none, maxLoad from now
+func (r *ReadThrough) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	if r.maxLoad > 0 {
+		return context.WithTimeout(detached, r.maxLoad)
+	}
+	return context.WithCancel(detached)
+}
+
+// load runs a shared load and publishes its result
+func (r *ReadThrough) load(ctx context.Context, cancel context.CancelFunc, key string, ttl time.Duration, load Loader, call *inflight) {
+	defer cancel()
+	call.response, call.err = load(ctx)
+	if call.err == nil {
+		r.cache.Set(key, call.response, ttl)
+	}
+
+	r.mu.Lock()
+	delete(r.loads, key)
+	r.mu.Unlock()
+	close(call.done)
+}
+
+// Stats are the counters of a read-through cache
+type Stats struct {
+	// Hits are requests answered from the cache
+	Hits int64 `json:"hits"`
+	// Misses are requests that loaded from upstream
+	Misses int64 `json:"misses"`
+	// Shared are requests that waited on another request's load
+	Shared int64 `json:"shared"`
+	// Entries, Bytes and Evictions describe the cache contents, when the
+	// cache reports them
+	Entries   int   `json:"entries"`
+	Bytes     int64 `json:"bytes"`
+	Evictions int64 `json:"evictions"`
+}
+
+// usageReporter is implemented by caches that report their contents, such as LRU
+type usageReporter interface {
+	Usage() (entries int, bytes int64, evictions int64)
+}
+
+// Stats returns the current counters.
+func (r *ReadThrough) Stats() Stats {
+	stats := Stats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+		Shared: r.shared.Load(),
+	}
+	if usage, ok := r.cache.(usageReporter); ok {
+		stats.Entries, stats.Bytes, stats.Evictions = usage.Usage()
+	}
+	return stats
+}
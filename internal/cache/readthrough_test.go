@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadThrough_CollapsesConcurrentMisses(t *testing.T) {
+	rt := NewReadThrough(NewLRU(1<<10), 0)
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (*Response, error) {
+		loads.Add(1)
+		<-release
+		return &Response{Body: []byte("ok")}, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			resp, err := rt.Fetch(context.Background(), "key", time.Minute, load)
+			if err != nil || string(resp.Body) != "ok" {
+				t.Errorf("Fetch() = %v, %v", resp, err)
+			}
+		}()
+	}
+	// Hold the load until every caller has joined it
+	for rt.Stats().Misses+rt.Stats().Shared < callers {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("Expected 1 upstream load, got %d", n)
+	}
+
+	// Later requests are served from the cache
+	if _, err := rt.Fetch(context.Background(), "key", time.Minute, load); err != nil {
+		t.Fatal(err)
+	}
+	stats := rt.Stats()
+	if stats.Misses != 1 || stats.Shared != callers-1 || stats.Hits != 1 || stats.Entries != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestReadThrough_DoesNotCacheErrors(t *testing.T) {
+	rt := NewReadThrough(NewLRU(1<<10), 0)
+
+	calls := 0
+	load := func(ctx context.Context) (*Response, error) {
+		calls++
+		return nil, errors.New("upstream unavailable")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := rt.Fetch(context.Background(), "key", time.Minute, load); err == nil {
+			t.Fatal("Expected an error")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected each request to reach upstream, got %d loads", calls)
+	}
+}
+
+func TestReadThrough_WaiterCancellation(t *testing.T) {
+	// A caller giving up does not fail the shared load for the others
+	rt := NewReadThrough(NewLRU(1<<10), 0)
+
+	release := make(chan struct{})
+	load := func(ctx context.Context) (*Response, error) {
+		select {
+		case <-release:
+			return &Response{Body: []byte("ok")}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := rt.Fetch(ctx, "key", time.Minute, load)
+		errc <- err
+	}()
+	for rt.Stats().Misses == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	close(release)
+	resp, err := rt.Fetch(context.Background(), "key", time.Minute, load)
+	if err != nil || string(resp.Body) != "ok" {
+		t.Errorf("Fetch() = %v, %v", resp, err)
+	}
+}
+
+func TestReadThrough_LoadDeadline(t *testing.T) {
+	// A detached load still stops at the deadline of the request that
+	// started it, or after the maximum load time without one
+	block := func(ctx context.Context) (*Response, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	tests := []struct {
+		name    string
+		maxLoad time.Duration
+		timeout time.Duration
+	}{
+		{"caller deadline", time.Hour, 20 * time.Millisecond},
+		{"maximum load time", 20 * time.Millisecond, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := NewReadThrough(NewLRU(1<<10), tt.maxLoad)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			// A second caller without a deadline sees the load end too
+			errc := make(chan error, 1)
+			go func() {
+				for rt.Stats().Misses == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				_, err := rt.Fetch(context.Background(), "key", time.Minute, block)
+				errc <- err
+			}()
+
+			if _, err := rt.Fetch(ctx, "key", time.Minute, block); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+			}
+			select {
+			case err := <-errc:
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Expected the shared load to time out, got %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Shared load did not stop at the deadline")
+			}
+		})
+	}
+}
+
+func TestReadThrough_NilAndZeroTTL(t *testing.T) {
+	calls := 0
+	load := func(ctx context.Context) (*Response, error) {
+		calls++
+		return &Response{}, nil
+	}
+
+	var disabled *ReadThrough
+	disabled.Fetch(context.Background(), "key", time.Minute, load)
+	disabled.Fetch(context.Background(), "key", time.Minute, load)
+
+	rt := NewReadThrough(NewLRU(1<<10), 0)
+	rt.Fetch(context.Background(), "key", 0, load)
+	rt.Fetch(context.Background(), "key", 0, load)
+
+	if calls != 4 {
+		t.Errorf("Expected every request to load, got %d loads", calls)
+	}
+	if stats := rt.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Expected uncached requests not to be counted, got %+v", stats)
+	}
+}
+
+func TestKey(t *testing.T) {
+	a, _ := url.Parse("https://cmr.example.com/granules.umm_json?short_name=B&short_name=A&provider=ASF")
+	b, _ := url.Parse("https://cmr.example.com/granules.umm_json?provider=ASF&short_name=A&short_name=B")
+	if Key(a, nil) != Key(b, nil) {
+		t.Errorf("Expected equivalent queries to share a key: %q != %q", Key(a, nil), Key(b, nil))
+	}
+
+	page2 := http.Header{"Cmr-Search-After": []string{`["a",1]`}}
+	if Key(a, nil, "CMR-Search-After") == Key(a, page2, "CMR-Search-After") {
+		t.Error("Expected the search-after header to select a different entry")
+	}
+	if Key(a, page2) != Key(a, nil) {
+		t.Error("Expected unselected headers to be ignored")
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 
 	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
 )
 
 // CMRBackend implements backend.SearchBackend for NASA's CMR API.
@@ -18,6 +21,7 @@ type CMRBackend struct {
 	collections *config.CollectionRegistry
 	cfg         *config.Config
 	logger      *slog.Logger
+	metrics     *metrics.Metrics
 }
 
 // NewCMRBackend creates a new CMR backend.
@@ -35,20 +39,53 @@ func NewCMRBackend(
 	}
 }
 
+// WithMetrics records the items the backend fails to translate.
+func (b *CMRBackend) WithMetrics(m *metrics.Metrics) *CMRBackend {
+	b.metrics = m
+	return b
+}
+
 // Name returns the backend name.
 func (b *CMRBackend) Name() string {
 	return "cmr"
 }
 
-// SupportsPagination returns false to use unified cursor-based pagination.
-// While CMR supports native pagination via CMR-Search-After header, we use
-// the same client-side cursor-based pagination as ASF for consistency.
+// SupportsPagination reports whether native CMR-Search-After pagination is
+// enabled. By default CMR uses the same client-side cursor-based pagination
+// as ASF for consistency.
 func (b *CMRBackend) SupportsPagination() bool {
-	return false
+	return b.cfg.CMR.NativePagination
+}
+
+// SupportsAscendingSort returns true since CMR sorts by start_date in
+// either direction.
+func (b *CMRBackend) SupportsAscendingSort() bool {
+	return true
+}
+
+// FilterCapabilities returns the CQL2 equality predicates CMR applies upstream.
+// Each is sent as an additional attribute, and CMR ANDs repeated attribute
+// parameters, so only single values can be pushed down.
+func (b *CMRBackend) FilterCapabilities() backend.FilterCapabilities {
+	return backend.FilterCapabilities{
+		"sar:instrument_mode": backend.PushdownSingle,
+		"sar:polarizations":   backend.PushdownSingle,
+		"sar:product_type":    backend.PushdownSingle,
+		"sat:orbit_state":     backend.PushdownSingle,
+		"sat:relative_orbit":  backend.PushdownSingle,
+		"s1:burst_id":         backend.PushdownSingle,
+		"s1:subswath":         backend.PushdownSingle,
+		"asf:frame":           backend.PushdownSingle,
+		"asf:path":            backend.PushdownSingle,
+		"view:off_nadir":      backend.PushdownSingle,
+	}
 }
 
 // Search executes a search against the CMR API.
 func (b *CMRBackend) Search(ctx context.Context, params *backend.SearchParams) (*backend.SearchResult, error) {
+	ctx, span := tracing.Start(ctx, "CMRBackend.Search")
+	defer span.End()
+
 	// Convert backend params to CMR params
 	cmrParams, err := b.toCMRParams(params)
 	if err != nil {
@@ -58,16 +95,19 @@ func (b *CMRBackend) Search(ctx context.Context, params *backend.SearchParams) (
 	// Execute search
 	result, err := b.client.Search(ctx, cmrParams)
 	if err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("CMR search failed: %w", err)
 	}
 
 	// Convert CMR granules to STAC items
+	_, translateSpan := tracing.Start(ctx, "translate CMR granules")
 	items := make([]*stac.Item, 0, len(result.Granules))
 	for _, granule := range result.Granules {
 		// Determine collection ID from granule
 		collectionID := b.determineCollection(&granule)
-		item, err := TranslateGranuleToItem(&granule, collectionID, b.cfg.STAC.BaseURL, b.cfg.STAC.Version)
+		item, err := TranslateGranuleToItem(&granule, collectionID, b.cfg.STAC.BaseURL, b.cfg.STAC.Version, b.collections)
 		if err != nil {
+			b.metrics.TranslationFailure("cmr")
 			b.logger.Warn("failed to translate CMR granule",
 				slog.String("granule_ur", granule.GranuleUR),
 				slog.String("error", err.Error()),
@@ -76,16 +116,46 @@ func (b *CMRBackend) Search(ctx context.Context, params *backend.SearchParams) (
 		}
 		items = append(items, item)
 	}
+	translateSpan.SetAttribute("translate.items", len(items))
+	translateSpan.SetAttribute("translate.failures", len(result.Granules)-len(items))
+	translateSpan.End()
 
-	return &backend.SearchResult{
+	searchResult := &backend.SearchResult{
 		Items:      items,
-		// NextCursor is not set - we use unified client-side cursor pagination
 		TotalCount: &result.Hits,
-	}, nil
+	}
+	// Without native pagination NextCursor is not set - we use unified
+	// client-side cursor pagination
+	if b.SupportsPagination() {
+		searchResult.NextCursor = result.SearchAfter
+	}
+	return searchResult, nil
+}
+
+// Count returns the number of granules matching a search from the CMR hits
+// count, without fetching any granules.
+func (b *CMRBackend) Count(ctx context.Context, params *backend.SearchParams) (int, error) {
+	cmrParams, err := b.toCMRParams(params)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert search params: %w", err)
+	}
+	cmrParams.HitsOnly = true
+	cmrParams.SearchAfter = ""
+
+	result, err := b.client.Search(ctx, cmrParams)
+	if err != nil {
+		return 0, fmt.Errorf("CMR search failed: %w", err)
+	}
+	return result.Hits, nil
 }
 
 // GetItem retrieves a single item from CMR.
 func (b *CMRBackend) GetItem(ctx context.Context, collection, itemID string) (*stac.Item, error) {
+	ctx, span := tracing.Start(ctx, "CMRBackend.GetItem")
+	defer span.End()
+	span.SetAttribute("collection.id", collection)
+	span.SetAttribute("item.id", itemID)
+
 	// Verify collection exists
 	if !b.collections.Has(collection) {
 		return nil, fmt.Errorf("collection %q not found", collection)
@@ -94,12 +164,23 @@ func (b *CMRBackend) GetItem(ctx context.Context, collection, itemID string) (*s
 	// Fetch from CMR
 	granule, err := b.client.GetGranule(ctx, itemID)
 	if err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("failed to fetch granule: %w", err)
 	}
 
+	// Granules are looked up by name alone, so one of another collection,
+	// possibly a restricted one, is not found in this one
+	if !granuleInCollection(b.collections.Get(collection), granule) {
+		return nil, fmt.Errorf("granule not found in collection %q: %s", collection, itemID)
+	}
+
 	// Convert to STAC item
-	item, err := TranslateGranuleToItem(granule, collection, b.cfg.STAC.BaseURL, b.cfg.STAC.Version)
+	_, translateSpan := tracing.Start(ctx, "translate CMR granule")
+	item, err := TranslateGranuleToItem(granule, collection, b.cfg.STAC.BaseURL, b.cfg.STAC.Version, b.collections)
+	translateSpan.RecordError(err)
+	translateSpan.End()
 	if err != nil {
+		b.metrics.TranslationFailure("cmr")
 		return nil, fmt.Errorf("failed to translate granule: %w", err)
 	}
 
@@ -172,13 +253,22 @@ func (b *CMRBackend) toCMRParams(params *backend.SearchParams) (*SearchParams, e
 	cmrParams.FlightDirection = params.FlightDirection
 	cmrParams.RelativeOrbit = params.RelativeOrbit
 	cmrParams.ProcessingLevel = params.ProcessingLevel
+	cmrParams.BurstID = params.BurstID
+	cmrParams.Subswath = params.Subswath
+	cmrParams.Frame = cmrRanges(params.Frame)
+	cmrParams.Path = cmrRanges(params.Path)
+	cmrParams.OffNadirAngle = cmrRanges(params.OffNadirAngle)
 
 	// Map pagination
 	if params.Limit > 0 {
 		cmrParams.PageSize = params.Limit
 	}
-	// Note: We don't pass params.Cursor to CMR - we use unified client-side
-	// cursor pagination that works the same way as ASF backend
+	// With native pagination the cursor is the CMR-Search-After token;
+	// otherwise we use unified client-side cursor pagination that works
+	// the same way as ASF backend
+	if b.SupportsPagination() {
+		cmrParams.SearchAfter = params.Cursor
+	}
 
 	// Map sort
 	if params.SortField != "" {
@@ -192,17 +282,24 @@ func (b *CMRBackend) toCMRParams(params *backend.SearchParams) (*SearchParams, e
 	return cmrParams, nil
 }
 
+// cmrRanges converts ranges to their CMR form
+func cmrRanges(ranges []backend.Range) []Range {
+	if len(ranges) == 0 {
+		return nil
+	}
+	out := make([]Range, len(ranges))
+	for i, r := range ranges {
+		out[i] = Range{Min: r.Min, Max: r.Max}
+	}
+	return out
+}
+
 // determineCollection determines the STAC collection ID for a CMR granule.
 func (b *CMRBackend) determineCollection(granule *UMMGranule) string {
 	shortName := granule.CollectionReference.ShortName
 
 	// Get processing level from granule (may be empty)
-	processingLevel := ""
-	if levels := granule.GetAdditionalAttribute("PROCESSING_LEVEL"); len(levels) > 0 {
-		processingLevel = levels[0]
-	} else if levels := granule.GetAdditionalAttribute("PROCESSING_TYPE"); len(levels) > 0 {
-		processingLevel = levels[0]
-	}
+	processingLevel := granuleProcessingLevel(granule)
 
 	// Check all collections for matching CMR short name AND processing level
 	for _, coll := range b.collections.All() {
@@ -266,6 +363,35 @@ func (b *CMRBackend) determineCollection(granule *UMMGranule) string {
 	return ""
 }
 
+// granuleInCollection reports whether a CMR granule is an item of a
+// collection: one of its CMR short names or, at its processing level if it
+// has one, one of its ASF datasets.
+func granuleInCollection(coll *config.CollectionConfig, granule *UMMGranule) bool {
+	if coll == nil {
+		return false
+	}
+	shortName := granule.CollectionReference.ShortName
+	if coll.CMR != nil && slices.Contains(coll.CMR.ShortNames, shortName) {
+		return true
+	}
+	if !slices.Contains(coll.ASFDatasets, shortName) {
+		return false
+	}
+	return coll.ASFProcessingLevel == "" || coll.ASFProcessingLevel == granuleProcessingLevel(granule)
+}
+
+// granuleProcessingLevel returns the processing level of a CMR granule, or
+// "" if its metadata has none.
+func granuleProcessingLevel(granule *UMMGranule) string {
+	if levels := granule.GetAdditionalAttribute("PROCESSING_LEVEL"); len(levels) > 0 {
+		return levels[0]
+	}
+	if levels := granule.GetAdditionalAttribute("PROCESSING_TYPE"); len(levels) > 0 {
+		return levels[0]
+	}
+	return ""
+}
+
 
 // geojsonToPolygon converts GeoJSON geometry to CMR polygon format.
 func geojsonToPolygon(geojsonBytes []byte) (string, error) {
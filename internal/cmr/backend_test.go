@@ -0,0 +1,180 @@
+package cmr
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
+)
+
+func TestCMRBackend_Search_Pagination(t *testing.T) {
+	tests := []struct {
+		name             string
+		nativePagination bool
+		cursor           string
+		wantHeader       string
+		wantNextCursor   string
+	}{
+		{"client-side pagination", false, "ignored-token", "", ""},
+		{"native first page", true, "", "", "token-2"},
+		{"native next page", true, "token-1", "token-1", "token-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotHeader string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHeader = r.Header.Get(CMRSearchAfterHeader)
+				w.Header().Set(CMRSearchAfterHeader, "token-2")
+				w.Header().Set("Content-Type", "application/vnd.nasa.cmr.umm_results+json")
+				json.NewEncoder(w).Encode(UMMSearchResponse{Hits: 42})
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{}
+			cfg.CMR.NativePagination = tt.nativePagination
+			collections := config.NewCollectionRegistry()
+			_ = collections.Add(&config.CollectionConfig{ID: "sentinel-1", ASFDatasets: []string{"SENTINEL-1"}})
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+			b := NewCMRBackend(NewClient(server.URL, "ASF", 30*time.Second), collections, cfg, logger)
+
+			if b.SupportsPagination() != tt.nativePagination {
+				t.Errorf("SupportsPagination() = %v, want %v", b.SupportsPagination(), tt.nativePagination)
+			}
+
+			result, err := b.Search(context.Background(), &backend.SearchParams{
+				Collections: []string{"sentinel-1"},
+				Limit:       10,
+				Cursor:      tt.cursor,
+			})
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+
+			if gotHeader != tt.wantHeader {
+				t.Errorf("%s header = %q, want %q", CMRSearchAfterHeader, gotHeader, tt.wantHeader)
+			}
+			if result.NextCursor != tt.wantNextCursor {
+				t.Errorf("Search() NextCursor = %q, want %q", result.NextCursor, tt.wantNextCursor)
+			}
+			if result.TotalCount == nil || *result.TotalCount != 42 {
+				t.Errorf("Search() TotalCount = %v, want 42", result.TotalCount)
+			}
+		})
+	}
+}
+
+func TestCMRBackend_Count(t *testing.T) {
+	var gotPageSize string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPageSize = r.URL.Query().Get("page_size")
+		w.Header().Set("Content-Type", "application/vnd.nasa.cmr.umm_results+json")
+		json.NewEncoder(w).Encode(UMMSearchResponse{Hits: 1234})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{}
+	collections := config.NewCollectionRegistry()
+	_ = collections.Add(&config.CollectionConfig{ID: "sentinel-1", ASFDatasets: []string{"SENTINEL-1"}})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	b := NewCMRBackend(NewClient(server.URL, "ASF", 30*time.Second), collections, cfg, logger)
+
+	count, err := b.Count(context.Background(), &backend.SearchParams{
+		Collections: []string{"sentinel-1"},
+		Limit:       10,
+	})
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count != 1234 {
+		t.Errorf("Count() = %d, want 1234", count)
+	}
+	if gotPageSize != "0" {
+		t.Errorf("page_size = %q, want 0", gotPageSize)
+	}
+}
+
+func TestCMRBackend_Search_Tracing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.nasa.cmr.umm_results+json")
+		json.NewEncoder(w).Encode(UMMSearchResponse{Hits: 0})
+	}))
+	defer server.Close()
+
+	collections := config.NewCollectionRegistry()
+	_ = collections.Add(&config.CollectionConfig{ID: "sentinel-1", ASFDatasets: []string{"SENTINEL-1"}})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	b := NewCMRBackend(NewClient(server.URL, "ASF", 30*time.Second), collections, &config.Config{}, logger)
+
+	recorder := tracing.NewRecorder()
+	ctx, root := tracing.NewTracer("test", recorder).Start(context.Background(), "root", tracing.KindServer)
+	if _, err := b.Search(ctx, &backend.SearchParams{Collections: []string{"sentinel-1"}, Limit: 10}); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	root.End()
+
+	// The client call, its HTTP round-trip, decoding and translation all nest
+	// under the backend span
+	parents := map[string]string{
+		"CMRBackend.Search":      "root",
+		"cmr.Client.Search":      "CMRBackend.Search",
+		"GET":                    "cmr.Client.Search",
+		"decode CMR response":    "cmr.Client.Search",
+		"translate CMR granules": "CMRBackend.Search",
+	}
+	for name, parentName := range parents {
+		span, ok := recorder.Find(name)
+		parent, _ := recorder.Find(parentName)
+		if !ok || span.Parent != parent.Context.SpanID {
+			t.Errorf("Expected span %q under %q, got %+v", name, parentName, span)
+		}
+	}
+}
+
+func TestCMRBackend_GetItem_Collection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.nasa.cmr.umm_results+json")
+		json.NewEncoder(w).Encode(UMMSearchResponse{Hits: 1, Items: []UMMResultItem{{
+			UMM: UMMGranule{
+				GranuleUR:           r.URL.Query().Get("granule_ur"),
+				CollectionReference: CollectionReference{ShortName: "NISAR_L2_GCOV_BETA_V1"},
+			},
+		}}})
+	}))
+	defer server.Close()
+
+	collections := config.NewCollectionRegistry()
+	_ = collections.Add(&config.CollectionConfig{ID: "sentinel-1", ASFDatasets: []string{"SENTINEL-1"}})
+	_ = collections.Add(&config.CollectionConfig{
+		ID:          "nisar",
+		ASFDatasets: []string{"NISAR"},
+		CMR:         &config.CMRMapping{ShortNames: []string{"NISAR_L2_GCOV_BETA_V1"}},
+		Restricted:  true,
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	b := NewCMRBackend(NewClient(server.URL, "ASF", 30*time.Second), collections, &config.Config{}, logger)
+
+	item, err := b.GetItem(context.Background(), "nisar", "NISAR_GCOV_001")
+	if err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+	if item.Collection != "nisar" {
+		t.Errorf("Expected the item in nisar, got %q", item.Collection)
+	}
+
+	// Granules are found by name alone, but not through another collection
+	if _, err := b.GetItem(context.Background(), "sentinel-1", "NISAR_GCOV_001"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error for another collection's granule, got %v", err)
+	}
+}
@@ -5,12 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cache"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/ratelimit"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
 )
 
 const (
@@ -32,10 +38,12 @@ const (
 
 // Client handles communication with the CMR API.
 type Client struct {
-	baseURL    string
-	provider   string
-	httpClient *http.Client
-	logger     *slog.Logger
+	baseURL   string
+	provider  string
+	upstream  *upstream.Client
+	logger    *slog.Logger
+	cache     *cache.ReadThrough
+	cacheTTLs cache.TTLs
 }
 
 // NewClient creates a new CMR API client.
@@ -50,21 +58,34 @@ func NewClient(baseURL, provider string, timeout time.Duration) *Client {
 	return &Client{
 		baseURL:  strings.TrimSuffix(baseURL, "/"),
 		provider: provider,
-		httpClient: &http.Client{
-			Timeout: timeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 100,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
-		logger: slog.Default(),
+		upstream: upstream.NewClient("CMR", timeout),
+		logger:   slog.Default(),
 	}
 }
 
 // WithLogger sets a custom logger for the client.
 func (c *Client) WithLogger(logger *slog.Logger) *Client {
 	c.logger = logger
+	c.upstream.WithLogger(logger)
+	return c
+}
+
+// WithMetrics records the client's upstream requests.
+func (c *Client) WithMetrics(m *metrics.Metrics) *Client {
+	c.upstream.WithMetrics(m)
+	return c
+}
+
+// WithConcurrencyLimit shares a cap on the upstream calls in flight with
+// the other clients.
+func (c *Client) WithConcurrencyLimit(slots *ratelimit.Semaphore) *Client {
+	c.upstream.WithConcurrencyLimit(slots)
+	return c
+}
+
+// WithRetryPolicy sets how failed requests are retried.
+func (c *Client) WithRetryPolicy(policy upstream.RetryPolicy) *Client {
+	c.upstream.WithRetryPolicy(policy)
 	return c
 }
 
@@ -76,8 +97,26 @@ type SearchResult struct {
 	TookMs          int
 }
 
+// WithCache serves repeated queries from a read-through cache, keeping
+// search results and granule lookups for their respective TTLs.
+func (c *Client) WithCache(rt *cache.ReadThrough, ttls cache.TTLs) *Client {
+	c.cache = rt
+	c.cacheTTLs = ttls
+	return c
+}
+
 // Search performs a granule search against CMR.
 func (c *Client) Search(ctx context.Context, params *SearchParams) (*SearchResult, error) {
+	ctx, span := tracing.Start(ctx, "cmr.Client.Search")
+	defer span.End()
+
+	result, err := c.search(ctx, params, c.cacheTTLs.Search)
+	span.RecordError(err)
+	return result, err
+}
+
+// search runs a granule search, serving it from the cache for ttl when one is set.
+func (c *Client) search(ctx context.Context, params *SearchParams, ttl time.Duration) (*SearchResult, error) {
 	// Build the search URL
 	searchURL := c.baseURL + "/granules.umm_json"
 
@@ -105,34 +144,24 @@ func (c *Client) Search(ctx context.Context, params *SearchParams) (*SearchResul
 		req.Header.Set(CMRSearchAfterHeader, params.SearchAfter)
 	}
 
-	// Execute the request
-	resp, err := c.httpClient.Do(req)
+	// Execute the request, or reuse a cached response to the same query.
+	// The search-after cursor selects the page, so it is part of the key.
+	key := cache.Key(req.URL, req.Header, CMRSearchAfterHeader)
+	resp, err := c.cache.Fetch(ctx, key, ttl, func(ctx context.Context) (*cache.Response, error) {
+		return c.do(req.WithContext(ctx))
+	})
 	if err != nil {
-		c.logger.ErrorContext(ctx, "CMR API request failed",
-			slog.String("error", err.Error()),
-		)
-		return nil, fmt.Errorf("CMR API request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check for non-200 status codes
-	if resp.StatusCode != http.StatusOK {
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			c.logger.WarnContext(ctx, "failed to read error response body",
-				slog.String("error", readErr.Error()),
-			)
-		}
-		c.logger.ErrorContext(ctx, "CMR API returned non-200 status",
-			slog.Int("status_code", resp.StatusCode),
-			slog.String("response_body", string(body)),
-		)
-		return nil, fmt.Errorf("CMR API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
 	// Parse the response
+	_, decodeSpan := tracing.Start(ctx, "decode CMR response")
+	decodeSpan.SetAttribute("response.bytes", len(resp.Body))
 	var cmrResp UMMSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&cmrResp); err != nil {
+	err = json.Unmarshal(resp.Body, &cmrResp)
+	decodeSpan.RecordError(err)
+	decodeSpan.End()
+	if err != nil {
 		c.logger.ErrorContext(ctx, "failed to decode CMR response",
 			slog.String("error", err.Error()),
 		)
@@ -162,8 +191,25 @@ func (c *Client) Search(ctx context.Context, params *SearchParams) (*SearchResul
 	}, nil
 }
 
+// do executes a request through the shared upstream layer, which retries
+// transient failures.
+func (c *Client) do(req *http.Request) (*cache.Response, error) {
+	resp, err := c.upstream.Do(req)
+	if err != nil {
+		c.logger.ErrorContext(req.Context(), "CMR API request failed",
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return &cache.Response{Body: resp.Body, Header: resp.Header}, nil
+}
+
 // GetGranule retrieves a single granule by its granule UR (unique reference).
 func (c *Client) GetGranule(ctx context.Context, granuleUR string) (*UMMGranule, error) {
+	ctx, span := tracing.Start(ctx, "cmr.Client.GetGranule")
+	defer span.End()
+	span.SetAttribute("item.id", granuleUR)
+
 	c.logger.DebugContext(ctx, "fetching granule",
 		slog.String("granule_ur", granuleUR),
 	)
@@ -174,7 +220,8 @@ func (c *Client) GetGranule(ctx context.Context, granuleUR string) (*UMMGranule,
 		PageSize:  1,
 	}
 
-	result, err := c.Search(ctx, params)
+	// Granules rarely change, so lookups are cached longer than searches
+	result, err := c.search(ctx, params, c.cacheTTLs.Item)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search for granule: %w", err)
 	}
@@ -209,10 +256,16 @@ type SearchParams struct {
 	FlightDirection string
 	RelativeOrbit   []int
 	ProcessingLevel []string
+	BurstID         []string
+	Subswath        []string
+	Frame           []Range
+	Path            []Range
+	OffNadirAngle   []Range
 
 	// Pagination
 	PageSize    int
 	SearchAfter string // CMR-Search-After cursor
+	HitsOnly    bool   // Request only the hit count (page_size=0)
 
 	// Sorting
 	SortKey string // CMR sort key (e.g., "-start_date" for descending)
@@ -276,9 +329,26 @@ func (p *SearchParams) ToURLValues() url.Values {
 			values.Add("attribute[]", fmt.Sprintf("string,PROCESSING_TYPE,%s", pl))
 		}
 	}
+	for _, id := range p.BurstID {
+		values.Add("attribute[]", fmt.Sprintf("string,BURST_ID_FULL,%s", id))
+	}
+	for _, sw := range p.Subswath {
+		values.Add("attribute[]", fmt.Sprintf("string,SUBSWATH_NAME,%s", sw))
+	}
+	for _, r := range p.Frame {
+		values.Add("attribute[]", r.attribute("int", "FRAME_NUMBER"))
+	}
+	for _, r := range p.Path {
+		values.Add("attribute[]", r.attribute("int", "PATH_NUMBER"))
+	}
+	for _, r := range p.OffNadirAngle {
+		values.Add("attribute[]", r.attribute("float", "OFF_NADIR_ANGLE"))
+	}
 
 	// Pagination
-	if p.PageSize > 0 {
+	if p.HitsOnly {
+		values.Set("page_size", "0")
+	} else if p.PageSize > 0 {
 		values.Set("page_size", fmt.Sprintf("%d", p.PageSize))
 	} else {
 		values.Set("page_size", fmt.Sprintf("%d", DefaultPageSize))
@@ -294,3 +364,19 @@ func (p *SearchParams) ToURLValues() url.Values {
 
 	return values
 }
+
+// Range is an inclusive range of additional attribute values. A range whose
+// Min equals its Max is a single value.
+type Range struct {
+	Min, Max float64
+}
+
+// attribute formats the range as an attribute[] filter, "type,NAME,value"
+// or "type,NAME,min,max"
+func (r Range) attribute(kind, name string) string {
+	low := strconv.FormatFloat(r.Min, 'f', -1, 64)
+	if r.Max == r.Min {
+		return fmt.Sprintf("%s,%s,%s", kind, name, low)
+	}
+	return fmt.Sprintf("%s,%s,%s,%s", kind, name, low, strconv.FormatFloat(r.Max, 'f', -1, 64))
+}
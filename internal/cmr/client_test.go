@@ -58,6 +58,20 @@ func TestSearchParams_ToURLValues(t *testing.T) {
 				"attribute%5B%5D=string%2CBEAM_MODE%2CIW",
 			},
 		},
+		{
+			name: "range attribute filters",
+			params: &SearchParams{
+				Frame:         []Range{{Min: 100, Max: 150}},
+				Path:          []Range{{Min: 422, Max: 422}},
+				OffNadirAngle: []Range{{Min: 21.5, Max: 34.3}},
+				PageSize:      250,
+			},
+			contains: []string{
+				"attribute%5B%5D=int%2CFRAME_NUMBER%2C100%2C150",
+				"attribute%5B%5D=int%2CPATH_NUMBER%2C422",
+				"attribute%5B%5D=float%2COFF_NADIR_ANGLE%2C21.5%2C34.3",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -3,10 +3,12 @@ package cmr
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	gostac "github.com/planetlabs/go-stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
 )
 
@@ -15,7 +17,9 @@ import (
 // not string URIs in the Extensions field.
 
 // TranslateGranuleToItem converts a CMR UMM-G granule to a STAC Item.
-func TranslateGranuleToItem(granule *UMMGranule, collectionID, baseURL, stacVersion string) (*stac.Item, error) {
+// Constellation, radar band and processing level come from the platform
+// definitions and processing level maps of collections, as for ASF features.
+func TranslateGranuleToItem(granule *UMMGranule, collectionID, baseURL, stacVersion string, collections *config.CollectionRegistry) (*stac.Item, error) {
 	// Use GranuleUR as the item ID
 	itemID := granule.GranuleUR
 	if itemID == "" {
@@ -56,14 +60,33 @@ func TranslateGranuleToItem(granule *UMMGranule, collectionID, baseURL, stacVers
 	if len(granule.Platforms) > 0 {
 		platform := granule.Platforms[0]
 		item.Properties["platform"] = strings.ToLower(platform.ShortName)
-		item.Properties["constellation"] = getConstellation(platform.ShortName)
+		definition := collections.Platform(collectionID, platform.ShortName)
 
+		instrument := ""
 		if len(platform.Instruments) > 0 {
 			instruments := make([]string, len(platform.Instruments))
 			for i, inst := range platform.Instruments {
 				instruments[i] = strings.ToLower(inst.ShortName)
 			}
 			item.Properties["instruments"] = instruments
+			instrument = platform.Instruments[0].ShortName
+		} else if definition != nil && definition.Instrument != "" {
+			item.Properties["instruments"] = []string{strings.ToLower(definition.Instrument)}
+			instrument = definition.Instrument
+		}
+
+		if definition != nil {
+			if definition.Constellation != "" {
+				item.Properties["constellation"] = definition.Constellation
+			}
+			// Frequency band and center frequency of the platform's radar
+			band, frequency := definition.Band(instrument)
+			if band != "" {
+				item.Properties["sar:frequency_band"] = band
+			}
+			if frequency > 0 {
+				item.Properties["sar:center_frequency"] = frequency
+			}
 		}
 	}
 
@@ -73,8 +96,11 @@ func TranslateGranuleToItem(granule *UMMGranule, collectionID, baseURL, stacVers
 	// Set satellite extension properties
 	setSatelliteProperties(granule, item)
 
+	// Set Sentinel-1 burst properties
+	setBurstProperties(granule, item)
+
 	// Set processing extension properties
-	setProcessingProperties(granule, item)
+	setProcessingProperties(granule, item, collections, collectionID)
 
 	// Add assets
 	addAssets(granule, item)
@@ -128,28 +154,6 @@ func setSARProperties(granule *UMMGranule, item *stac.Item) {
 		item.Properties["sar:instrument_mode"] = modes[0]
 	}
 
-	// Frequency band (SAR sensors)
-	if len(granule.Platforms) > 0 {
-		platform := strings.ToLower(granule.Platforms[0].ShortName)
-		switch {
-		case strings.Contains(platform, "sentinel-1"):
-			item.Properties["sar:frequency_band"] = "C"
-			item.Properties["sar:center_frequency"] = 5.405
-		case strings.Contains(platform, "alos"):
-			item.Properties["sar:frequency_band"] = "L"
-			item.Properties["sar:center_frequency"] = 1.27
-		case strings.Contains(platform, "ers"):
-			item.Properties["sar:frequency_band"] = "C"
-			item.Properties["sar:center_frequency"] = 5.3
-		case strings.Contains(platform, "radarsat"):
-			item.Properties["sar:frequency_band"] = "C"
-			item.Properties["sar:center_frequency"] = 5.405
-		case strings.Contains(platform, "uavsar"):
-			item.Properties["sar:frequency_band"] = "L"
-			item.Properties["sar:center_frequency"] = 1.2575
-		}
-	}
-
 	// Look direction
 	if dirs := granule.GetAdditionalAttribute("LOOK_DIRECTION"); len(dirs) > 0 {
 		item.Properties["sar:looks_range"] = dirs[0]
@@ -187,9 +191,23 @@ func setSatelliteProperties(granule *UMMGranule, item *stac.Item) {
 
 	// Relative orbit (path number)
 	if paths := granule.GetAdditionalAttribute("PATH_NUMBER"); len(paths) > 0 {
-		// Try to parse as int
-		if len(paths[0]) > 0 {
-			item.Properties["sat:relative_orbit"] = paths[0]
+		if path, err := strconv.Atoi(paths[0]); err == nil {
+			item.Properties["sat:relative_orbit"] = path
+			item.Properties["asf:path"] = path
+		}
+	}
+
+	// Frame number on the track
+	if frames := granule.GetAdditionalAttribute("FRAME_NUMBER"); len(frames) > 0 {
+		if frame, err := strconv.Atoi(frames[0]); err == nil {
+			item.Properties["asf:frame"] = frame
+		}
+	}
+
+	// Off-nadir angle
+	if angles := granule.GetAdditionalAttribute("OFF_NADIR_ANGLE"); len(angles) > 0 {
+		if angle, err := strconv.ParseFloat(angles[0], 64); err == nil {
+			item.Properties["view:off_nadir"] = angle
 		}
 	}
 
@@ -207,13 +225,30 @@ func setSatelliteProperties(granule *UMMGranule, item *stac.Item) {
 	}
 }
 
-// setProcessingProperties sets processing extension properties.
-func setProcessingProperties(granule *UMMGranule, item *stac.Item) {
+// setBurstProperties sets the burst ID, subswath and burst index of
+// Sentinel-1 burst products.
+func setBurstProperties(granule *UMMGranule, item *stac.Item) {
+	if ids := granule.GetAdditionalAttribute("BURST_ID_FULL"); len(ids) > 0 && ids[0] != "" {
+		item.Properties["s1:burst_id"] = ids[0]
+	}
+	if subswaths := granule.GetAdditionalAttribute("SUBSWATH_NAME"); len(subswaths) > 0 && subswaths[0] != "" {
+		item.Properties["s1:subswath"] = subswaths[0]
+	}
+	if indexes := granule.GetAdditionalAttribute("BURST_INDEX"); len(indexes) > 0 {
+		if index, err := strconv.Atoi(indexes[0]); err == nil {
+			item.Properties["s1:burst_index"] = index
+		}
+	}
+}
+
+// setProcessingProperties sets processing extension properties, mapping
+// processing levels through the processing level maps of collections.
+func setProcessingProperties(granule *UMMGranule, item *stac.Item, collections *config.CollectionRegistry, collectionID string) {
 	// Processing level
 	if levels := granule.GetAdditionalAttribute("PROCESSING_LEVEL"); len(levels) > 0 {
-		item.Properties["processing:level"] = levels[0]
+		item.Properties["processing:level"] = collections.ProcessingLevel(collectionID, levels[0])
 	} else if levels := granule.GetAdditionalAttribute("PROCESSING_TYPE"); len(levels) > 0 {
-		item.Properties["processing:level"] = levels[0]
+		item.Properties["processing:level"] = collections.ProcessingLevel(collectionID, levels[0])
 	}
 
 	// Processing datetime
@@ -277,25 +312,6 @@ func addAssets(granule *UMMGranule, item *stac.Item) {
 	}
 }
 
-// getConstellation returns the constellation name for a platform.
-func getConstellation(platform string) string {
-	platform = strings.ToLower(platform)
-	switch {
-	case strings.HasPrefix(platform, "sentinel-1"):
-		return "sentinel-1"
-	case strings.HasPrefix(platform, "sentinel-2"):
-		return "sentinel-2"
-	case strings.HasPrefix(platform, "alos"):
-		return "alos"
-	case strings.HasPrefix(platform, "ers"):
-		return "ers"
-	case strings.HasPrefix(platform, "radarsat"):
-		return "radarsat"
-	default:
-		return platform
-	}
-}
-
 // calculateBbox calculates a bounding box from GeoJSON geometry.
 func calculateBbox(geom json.RawMessage) []float64 {
 	var g struct {
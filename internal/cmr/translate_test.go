@@ -4,8 +4,21 @@ import (
 	"encoding/json"
 	"testing"
 	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
 )
 
+// shippedCollections loads the collections shipped in collections/, whose
+// platform definitions and processing level maps items are translated with
+func shippedCollections(t *testing.T) *config.CollectionRegistry {
+	t.Helper()
+	collections, err := config.LoadCollections("../../collections")
+	if err != nil {
+		t.Fatalf("Failed to load collections: %v", err)
+	}
+	return collections
+}
+
 func TestTranslateGranuleToItem(t *testing.T) {
 	granule := &UMMGranule{
 		GranuleUR: "S1A_IW_SLC__1SDV_20200101T120000_20200101T120100_030000_037000_ABCD",
@@ -45,6 +58,9 @@ func TestTranslateGranuleToItem(t *testing.T) {
 			{Name: "POLARIZATION", Values: []string{"VV", "VH"}},
 			{Name: "BEAM_MODE", Values: []string{"IW"}},
 			{Name: "ASCENDING_DESCENDING", Values: []string{"ASCENDING"}},
+			{Name: "FRAME_NUMBER", Values: []string{"1168"}},
+			{Name: "PATH_NUMBER", Values: []string{"64"}},
+			{Name: "OFF_NADIR_ANGLE", Values: []string{"34.3"}},
 		},
 		RelatedUrls: []RelatedURL{
 			{
@@ -58,7 +74,7 @@ func TestTranslateGranuleToItem(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateGranuleToItem(granule, "sentinel-1", "https://stac.example.com", "1.0.0")
+	item, err := TranslateGranuleToItem(granule, "sentinel-1", "https://stac.example.com", "1.0.0", shippedCollections(t))
 	if err != nil {
 		t.Fatalf("TranslateGranuleToItem() error = %v", err)
 	}
@@ -86,11 +102,40 @@ func TestTranslateGranuleToItem(t *testing.T) {
 		t.Errorf("Item sar:instrument_mode = %v, want IW", item.Properties["sar:instrument_mode"])
 	}
 
+	// Check platform definition properties
+	if constellation, ok := item.Properties["constellation"].(string); !ok || constellation != "sentinel-1" {
+		t.Errorf("Item constellation = %v, want sentinel-1", item.Properties["constellation"])
+	}
+
+	if band, ok := item.Properties["sar:frequency_band"].(string); !ok || band != "C" {
+		t.Errorf("Item sar:frequency_band = %v, want C", item.Properties["sar:frequency_band"])
+	}
+
+	if frequency, ok := item.Properties["sar:center_frequency"].(float64); !ok || frequency != 5.405 {
+		t.Errorf("Item sar:center_frequency = %v, want 5.405", item.Properties["sar:center_frequency"])
+	}
+
 	// Check satellite properties
 	if orbitState, ok := item.Properties["sat:orbit_state"].(string); !ok || orbitState != "ascending" {
 		t.Errorf("Item sat:orbit_state = %v, want ascending", item.Properties["sat:orbit_state"])
 	}
 
+	if frame, ok := item.Properties["asf:frame"].(int); !ok || frame != 1168 {
+		t.Errorf("Item asf:frame = %v, want 1168", item.Properties["asf:frame"])
+	}
+
+	if orbit, ok := item.Properties["sat:relative_orbit"].(int); !ok || orbit != 64 {
+		t.Errorf("Item sat:relative_orbit = %#v, want int 64", item.Properties["sat:relative_orbit"])
+	}
+
+	if path, ok := item.Properties["asf:path"].(int); !ok || path != 64 {
+		t.Errorf("Item asf:path = %v, want 64", item.Properties["asf:path"])
+	}
+
+	if angle, ok := item.Properties["view:off_nadir"].(float64); !ok || angle != 34.3 {
+		t.Errorf("Item view:off_nadir = %v, want 34.3", item.Properties["view:off_nadir"])
+	}
+
 	// Check assets
 	if dataAsset, ok := item.Assets["data"]; !ok {
 		t.Error("Item missing data asset")
@@ -115,6 +160,35 @@ func TestTranslateGranuleToItem(t *testing.T) {
 	}
 }
 
+func TestTranslateGranuleToItem_Burst(t *testing.T) {
+	granule := &UMMGranule{
+		GranuleUR: "S1_136231_IW2_20200604T022312_VV_7C85-BURST",
+		CollectionReference: CollectionReference{
+			ShortName: "SENTINEL-1_BURSTS",
+		},
+		AdditionalAttributes: []AdditionalAttribute{
+			{Name: "BURST_ID_FULL", Values: []string{"064_136231_IW2"}},
+			{Name: "SUBSWATH_NAME", Values: []string{"IW2"}},
+			{Name: "BURST_INDEX", Values: []string{"7"}},
+		},
+	}
+
+	item, err := TranslateGranuleToItem(granule, "sentinel-1-bursts", "https://stac.example.com", "1.0.0", shippedCollections(t))
+	if err != nil {
+		t.Fatalf("TranslateGranuleToItem() error = %v", err)
+	}
+
+	if item.Properties["s1:burst_id"] != "064_136231_IW2" {
+		t.Errorf("Item s1:burst_id = %v, want 064_136231_IW2", item.Properties["s1:burst_id"])
+	}
+	if item.Properties["s1:subswath"] != "IW2" {
+		t.Errorf("Item s1:subswath = %v, want IW2", item.Properties["s1:subswath"])
+	}
+	if index, ok := item.Properties["s1:burst_index"].(int); !ok || index != 7 {
+		t.Errorf("Item s1:burst_index = %v, want 7", item.Properties["s1:burst_index"])
+	}
+}
+
 func TestUMMGranule_GetStartTime(t *testing.T) {
 	tests := []struct {
 		name    string
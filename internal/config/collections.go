@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // CollectionConfig represents a STAC collection configuration that maps
@@ -24,6 +25,13 @@ type CollectionConfig struct {
 	Extent              Extent                 `json:"extent"`
 	Summaries           map[string]interface{} `json:"summaries,omitempty"`
 	Extensions          []string               `json:"stac_extensions,omitempty"`
+	// Restricted collections are only visible to clients granted them
+	Restricted          bool                   `json:"restricted,omitempty"`
+	// Platforms describe the platforms of the collection's items
+	Platforms           []PlatformConfig       `json:"platforms,omitempty"`
+	// ProcessingLevels map the ASF processing levels and product types of
+	// the collection's items to STAC processing levels
+	ProcessingLevels    map[string]string      `json:"processing_levels,omitempty"`
 }
 
 // CMRMapping contains CMR-specific configuration for a collection.
@@ -61,7 +69,10 @@ type TemporalExtent struct {
 }
 
 // CollectionRegistry holds all loaded collection configurations indexed by ID.
+// It is safe for concurrent use, and Replace swaps in a new set of
+// collections while requests are being served.
 type CollectionRegistry struct {
+	mu          sync.RWMutex
 	collections map[string]*CollectionConfig
 }
 
@@ -188,6 +199,12 @@ func validateCollection(c *CollectionConfig) error {
 		}
 	}
 
+	for i := range c.Platforms {
+		if err := c.Platforms[i].validate(); err != nil {
+			return fmt.Errorf("platforms[%d]: %w", i, err)
+		}
+	}
+
 	return nil
 }
 
@@ -198,6 +215,8 @@ func (r *CollectionRegistry) Add(collection *CollectionConfig) error {
 		return fmt.Errorf("cannot add nil collection")
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _, exists := r.collections[collection.ID]; exists {
 		return fmt.Errorf("collection with ID %q already exists", collection.ID)
 	}
@@ -206,20 +225,39 @@ func (r *CollectionRegistry) Add(collection *CollectionConfig) error {
 	return nil
 }
 
+// Replace atomically swaps the collections of the registry for those of
+// other, which should no longer be modified. Readers see either the old or
+// the new set, never a mix.
+func (r *CollectionRegistry) Replace(other *CollectionRegistry) {
+	other.mu.RLock()
+	collections := other.collections
+	other.mu.RUnlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collections = collections
+}
+
 // Get retrieves a collection by ID.
 // Returns nil if the collection does not exist.
 func (r *CollectionRegistry) Get(id string) *CollectionConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.collections[id]
 }
 
 // Has checks if a collection with the given ID exists in the registry.
 func (r *CollectionRegistry) Has(id string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	_, exists := r.collections[id]
 	return exists
 }
 
 // All returns all collections in the registry.
 func (r *CollectionRegistry) All() []*CollectionConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	collections := make([]*CollectionConfig, 0, len(r.collections))
 	for _, collection := range r.collections {
 		collections = append(collections, collection)
@@ -229,6 +267,8 @@ func (r *CollectionRegistry) All() []*CollectionConfig {
 
 // IDs returns all collection IDs in the registry.
 func (r *CollectionRegistry) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	ids := make([]string, 0, len(r.collections))
 	for id := range r.collections {
 		ids = append(ids, id)
@@ -238,6 +278,8 @@ func (r *CollectionRegistry) IDs() []string {
 
 // Count returns the number of collections in the registry.
 func (r *CollectionRegistry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return len(r.collections)
 }
 
@@ -263,6 +305,8 @@ func (r *CollectionRegistry) GetASFProcessingLevel(collectionID string) string {
 
 // FindByASFDataset returns all collections that include the specified ASF dataset.
 func (r *CollectionRegistry) FindByASFDataset(dataset string) []*CollectionConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	var matches []*CollectionConfig
 	for _, collection := range r.collections {
 		for _, ds := range collection.ASFDatasets {
@@ -278,6 +322,8 @@ func (r *CollectionRegistry) FindByASFDataset(dataset string) []*CollectionConfi
 // FindByASFDatasetAndLevel returns the collection that matches both the ASF dataset and processing level.
 // Returns nil if no matching collection is found.
 func (r *CollectionRegistry) FindByASFDatasetAndLevel(dataset, level string) *CollectionConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	for _, collection := range r.collections {
 		if collection.ASFProcessingLevel == level {
 			for _, ds := range collection.ASFDatasets {
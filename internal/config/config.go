@@ -3,6 +3,8 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v10"
@@ -14,9 +16,27 @@ type Config struct {
 	Backend  BackendConfig  `envPrefix:"BACKEND_"`
 	ASF      ASFConfig      `envPrefix:"ASF_"`
 	CMR      CMRConfig      `envPrefix:"CMR_"`
+	Cache    CacheConfig    `envPrefix:"CACHE_"`
+	Retry    RetryConfig    `envPrefix:"RETRY_"`
+	Cursor   CursorConfig   `envPrefix:"CURSOR_"`
 	STAC     STACConfig     `envPrefix:"STAC_"`
 	Features FeatureConfig  `envPrefix:"FEATURE_"`
 	Logging  LoggingConfig  `envPrefix:"LOG_"`
+	Tracing  TracingConfig  `envPrefix:"OTEL_"`
+
+	Collections CollectionsConfig `envPrefix:"COLLECTIONS_"`
+	RateLimit   RateLimitConfig   `envPrefix:"RATE_LIMIT_"`
+	Auth        AuthConfig        `envPrefix:"AUTH_"`
+	Download    DownloadConfig    `envPrefix:"DOWNLOAD_"`
+}
+
+// CollectionsConfig contains where collection definitions are loaded from
+// and how changes to them are picked up.
+type CollectionsConfig struct {
+	Dir string `env:"DIR" envDefault:"./collections"`
+	// WatchInterval is how often Dir is checked for changes, which are then
+	// reloaded; 0 disables watching, leaving reloads to SIGHUP
+	WatchInterval time.Duration `env:"WATCH_INTERVAL" envDefault:"30s"`
 }
 
 // ServerConfig contains HTTP server configuration.
@@ -32,6 +52,19 @@ type ServerConfig struct {
 type BackendConfig struct {
 	// Type specifies which backend to use: "asf" or "cmr"
 	Type string `env:"TYPE" envDefault:"asf"`
+	// Failover optionally names a secondary backend, "asf" or "cmr", that
+	// takes over while the circuit breaker of the primary is open
+	Failover string        `env:"FAILOVER" envDefault:""`
+	Breaker  BreakerConfig `envPrefix:"BREAKER_"`
+}
+
+// BreakerConfig contains the circuit breaker settings used with a failover backend.
+type BreakerConfig struct {
+	// Window is the number of recent requests the failure rate is computed over
+	Window      int           `env:"WINDOW" envDefault:"20"`
+	MinRequests int           `env:"MIN_REQUESTS" envDefault:"10"`
+	FailureRate float64       `env:"FAILURE_RATE" envDefault:"0.5"`
+	OpenTimeout time.Duration `env:"OPEN_TIMEOUT" envDefault:"30s"`
 }
 
 // ASFConfig contains ASF API client configuration.
@@ -45,6 +78,153 @@ type CMRConfig struct {
 	BaseURL  string        `env:"BASE_URL" envDefault:"https://cmr.earthdata.nasa.gov/search"`
 	Provider string        `env:"PROVIDER" envDefault:"ASF"`
 	Timeout  time.Duration `env:"TIMEOUT" envDefault:"30s"`
+	// NativePagination pages with CMR-Search-After tokens instead of the
+	// start-time cursor shared with the ASF backend
+	NativePagination bool `env:"NATIVE_PAGINATION" envDefault:"false"`
+}
+
+// CacheConfig contains upstream response cache configuration.
+type CacheConfig struct {
+	Enabled  bool  `env:"ENABLED" envDefault:"false"`
+	MaxBytes int64 `env:"MAX_BYTES" envDefault:"67108864"` // 64 MiB
+	// SearchTTL applies to searches and ItemTTL to single item lookups,
+	// which change far less often. A zero TTL disables caching for that kind.
+	SearchTTL time.Duration `env:"SEARCH_TTL" envDefault:"30s"`
+	ItemTTL   time.Duration `env:"ITEM_TTL" envDefault:"10m"`
+	// MaxLoadTime bounds an upstream load shared by several requests when
+	// the request that started it has no deadline of its own
+	MaxLoadTime time.Duration `env:"MAX_LOAD_TIME" envDefault:"2m"`
+}
+
+// RetryConfig contains the retry policy for upstream ASF and CMR requests.
+type RetryConfig struct {
+	// MaxAttempts counts the first request; 0 or 1 disables retries
+	MaxAttempts int           `env:"MAX_ATTEMPTS" envDefault:"3"`
+	BaseDelay   time.Duration `env:"BASE_DELAY" envDefault:"250ms"`
+	MaxDelay    time.Duration `env:"MAX_DELAY" envDefault:"10s"`
+}
+
+// CursorConfig contains the storage of pagination cursors too large to inline.
+type CursorConfig struct {
+	// Store is "memory" (this instance only), "signed" (no store: cursors
+	// stay inline, sealed with Secret) or "redis" (shared through Redis)
+	Store string `env:"STORE" envDefault:"memory"`
+	// TTL bounds the age of every cursor, inline or stored
+	TTL time.Duration `env:"TTL" envDefault:"1h"`
+	// MaxBytes bounds a compressed cursor in Redis or, with no store to take
+	// it, inline
+	MaxBytes int `env:"MAX_BYTES" envDefault:"8192"`
+	// Secret seals the inline cursors handed to clients; every replica needs
+	// the same one. A random key is used when unset
+	Secret string `env:"SECRET" envDefault:""`
+	// PreviousSecrets are rotated-out secrets whose cursors are still
+	// accepted for KeyGrace after KeyRotatedAt, the time of the rotation,
+	// required with them so every replica ends the grace window together
+	PreviousSecrets []string      `env:"PREVIOUS_SECRETS" envDefault:""`
+	KeyRotatedAt    time.Time     `env:"KEY_ROTATED_AT" envDefault:""`
+	KeyGrace        time.Duration `env:"KEY_GRACE" envDefault:"1h"`
+	Redis           RedisConfig   `envPrefix:"REDIS_"`
+}
+
+// RedisConfig contains the Redis connection of the redis cursor store.
+type RedisConfig struct {
+	Addr     string `env:"ADDR" envDefault:"localhost:6379"`
+	Password string `env:"PASSWORD" envDefault:""`
+	DB       int    `env:"DB" envDefault:"0"`
+	// KeyPrefix namespaces the cursor keys
+	KeyPrefix string `env:"KEY_PREFIX" envDefault:"asf-stac:cursor:"`
+}
+
+// RateLimitConfig contains the limits protecting the upstream APIs from
+// being saturated by a few clients.
+type RateLimitConfig struct {
+	// Enabled throttles each client to Rate requests per second, with bursts
+	// of up to Burst requests
+	Enabled bool    `env:"ENABLED" envDefault:"false"`
+	Rate    float64 `env:"RATE" envDefault:"5"`
+	Burst   int     `env:"BURST" envDefault:"20"`
+	// Routes overrides Rate and Burst for chi route patterns, as a list of
+	// pattern=rate:burst such as "/search=1:5"
+	Routes map[string]string `env:"ROUTES" envKeyValSeparator:"=" envDefault:""`
+	// UpstreamConcurrency caps the upstream calls in flight across all
+	// clients, 0 for no cap; calls wait up to UpstreamWait for a free slot
+	UpstreamConcurrency int           `env:"UPSTREAM_CONCURRENCY" envDefault:"0"`
+	UpstreamWait        time.Duration `env:"UPSTREAM_WAIT" envDefault:"5s"`
+}
+
+// RouteLimit is the rate limit of a route.
+type RouteLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RouteLimits parses Routes.
+func (c *RateLimitConfig) RouteLimits() (map[string]RouteLimit, error) {
+	limits := make(map[string]RouteLimit, len(c.Routes))
+	for pattern, value := range c.Routes {
+		rate, burst, ok := strings.Cut(value, ":")
+		if !strings.HasPrefix(pattern, "/") || !ok {
+			return nil, fmt.Errorf("invalid rate limit route %q, must be /pattern=rate:burst", pattern+"="+value)
+		}
+		var limit RouteLimit
+		var err error
+		if limit.Rate, err = strconv.ParseFloat(rate, 64); err != nil || limit.Rate <= 0 {
+			return nil, fmt.Errorf("invalid rate limit rate %q for route %q", rate, pattern)
+		}
+		if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst < 1 {
+			return nil, fmt.Errorf("invalid rate limit burst %q for route %q", burst, pattern)
+		}
+		limits[pattern] = limit
+	}
+	return limits, nil
+}
+
+// AuthConfig contains client authentication, with static API keys or JWT
+// bearer tokens, and how clients are granted restricted collections.
+type AuthConfig struct {
+	// Required rejects requests without credentials; otherwise anonymous
+	// clients see the collections that are not restricted
+	Required bool `env:"REQUIRED" envDefault:"false"`
+	// APIKeysFile is a JSON file of API keys and the collections they grant
+	APIKeysFile  string `env:"API_KEYS_FILE" envDefault:""`
+	APIKeyHeader string `env:"API_KEY_HEADER" envDefault:"X-API-Key"`
+	// JWKSFile or JWKSURL hold the keys bearer tokens are signed with; the
+	// set is reloaded every JWKSRefresh and when a token names an unknown key
+	JWKSFile    string        `env:"JWKS_FILE" envDefault:""`
+	JWKSURL     string        `env:"JWKS_URL" envDefault:""`
+	JWKSRefresh time.Duration `env:"JWKS_REFRESH" envDefault:"15m"`
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string `env:"JWT_ISSUER" envDefault:""`
+	Audience string `env:"JWT_AUDIENCE" envDefault:""`
+	// CollectionsClaim lists the collections a token grants, as an array or
+	// a space-separated string; with ClaimPrefix set, only the values with
+	// that prefix count, such as "stac:nisar-*" in a scope
+	CollectionsClaim string        `env:"JWT_COLLECTIONS_CLAIM" envDefault:"collections"`
+	ClaimPrefix      string        `env:"JWT_CLAIM_PREFIX" envDefault:""`
+	ClockSkew        time.Duration `env:"JWT_CLOCK_SKEW" envDefault:"1m"`
+}
+
+// Enabled reports whether any authentication method is configured.
+func (c *AuthConfig) Enabled() bool {
+	return c.APIKeysFile != "" || c.JWKSFile != "" || c.JWKSURL != ""
+}
+
+// DownloadConfig contains the asset download proxy, which streams assets
+// behind Earthdata Login to clients that cannot follow its redirects.
+type DownloadConfig struct {
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+	// EarthdataToken is the Earthdata Login bearer token used for clients
+	// that do not send their own in TokenHeader
+	EarthdataToken string `env:"EARTHDATA_TOKEN" envDefault:""`
+	TokenHeader    string `env:"TOKEN_HEADER" envDefault:"X-Earthdata-Token"`
+	// TokenHosts are the domains tokens are sent to, subdomains included;
+	// the final redirect to a signed storage URL never carries the token
+	TokenHosts []string `env:"TOKEN_HOSTS" envDefault:"asf.alaska.edu,earthdata.nasa.gov"`
+	LoginHost  string   `env:"LOGIN_HOST" envDefault:"urs.earthdata.nasa.gov"`
+	// RewriteHrefs points the asset hrefs of items at the download route
+	RewriteHrefs bool `env:"REWRITE_HREFS" envDefault:"false"`
+	// Timeout bounds the wait for the upstream response headers
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"30s"`
 }
 
 // STACConfig contains STAC API metadata configuration.
@@ -59,6 +239,7 @@ type STACConfig struct {
 type FeatureConfig struct {
 	EnableSearch     bool `env:"ENABLE_SEARCH" envDefault:"true"`
 	EnableQueryables bool `env:"ENABLE_QUERYABLES" envDefault:"true"`
+	EnableMetrics    bool `env:"ENABLE_METRICS" envDefault:"true"`
 	DefaultLimit     int  `env:"DEFAULT_LIMIT" envDefault:"10"`
 	MaxLimit         int  `env:"MAX_LIMIT" envDefault:"250"`
 }
@@ -69,6 +250,20 @@ type LoggingConfig struct {
 	Format string `env:"FORMAT" envDefault:"json"`
 }
 
+// TracingConfig contains OpenTelemetry tracing configuration, read from the
+// standard OpenTelemetry SDK variables.
+type TracingConfig struct {
+	// Exporter is "none", "stdout" (JSON lines) or "otlp" (OTLP/HTTP)
+	Exporter string `env:"TRACES_EXPORTER" envDefault:"none"`
+	// Endpoint is the collector base URL; spans are posted to /v1/traces
+	Endpoint string            `env:"EXPORTER_OTLP_ENDPOINT" envDefault:"http://localhost:4318"`
+	Headers  map[string]string `env:"EXPORTER_OTLP_HEADERS" envKeyValSeparator:"=" envDefault:""`
+	// SampleRatio is the fraction of new traces recorded; traces continued
+	// from an inbound traceparent follow its sampled flag
+	SampleRatio float64 `env:"TRACES_SAMPLER_ARG" envDefault:"1"`
+	ServiceName string  `env:"SERVICE_NAME" envDefault:"asf-stac-proxy"`
+}
+
 // Load parses configuration from environment variables.
 // It returns an error if required fields are missing or invalid.
 func Load() (*Config, error) {
@@ -114,6 +309,28 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("backend type must be 'asf' or 'cmr', got %q", c.Backend.Type)
 	}
 
+	if c.Backend.Failover != "" {
+		if c.Backend.Failover != "asf" && c.Backend.Failover != "cmr" {
+			return fmt.Errorf("failover backend must be 'asf' or 'cmr', got %q", c.Backend.Failover)
+		}
+
+		if c.Backend.Failover == c.Backend.Type {
+			return fmt.Errorf("failover backend must differ from the backend type %q", c.Backend.Type)
+		}
+
+		if c.Backend.Breaker.Window < 1 || c.Backend.Breaker.MinRequests < 1 || c.Backend.Breaker.MinRequests > c.Backend.Breaker.Window {
+			return fmt.Errorf("breaker min requests (%d) must be between 1 and the window (%d)", c.Backend.Breaker.MinRequests, c.Backend.Breaker.Window)
+		}
+
+		if c.Backend.Breaker.FailureRate <= 0 || c.Backend.Breaker.FailureRate > 1 {
+			return fmt.Errorf("breaker failure rate must be in (0, 1], got %v", c.Backend.Breaker.FailureRate)
+		}
+
+		if c.Backend.Breaker.OpenTimeout <= 0 {
+			return fmt.Errorf("breaker open timeout must be positive, got %s", c.Backend.Breaker.OpenTimeout)
+		}
+	}
+
 	// Validate ASF config
 	if c.ASF.BaseURL == "" {
 		return fmt.Errorf("ASF base URL is required")
@@ -132,6 +349,112 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("CMR timeout must be positive, got %s", c.CMR.Timeout)
 	}
 
+	// Validate cache config
+	if c.Cache.Enabled {
+		if c.Cache.MaxBytes < 1 {
+			return fmt.Errorf("cache max bytes must be positive, got %d", c.Cache.MaxBytes)
+		}
+
+		if c.Cache.SearchTTL < 0 || c.Cache.ItemTTL < 0 {
+			return fmt.Errorf("cache TTLs must not be negative, got search %s and item %s", c.Cache.SearchTTL, c.Cache.ItemTTL)
+		}
+
+		if c.Cache.MaxLoadTime < 0 {
+			return fmt.Errorf("cache max load time must not be negative, got %s", c.Cache.MaxLoadTime)
+		}
+	}
+
+	// Validate retry config
+	if c.Retry.MaxAttempts < 0 {
+		return fmt.Errorf("retry max attempts must not be negative, got %d", c.Retry.MaxAttempts)
+	}
+
+	if c.Retry.BaseDelay < 0 || c.Retry.MaxDelay < c.Retry.BaseDelay {
+		return fmt.Errorf("retry delays must satisfy 0 <= base (%s) <= max (%s)", c.Retry.BaseDelay, c.Retry.MaxDelay)
+	}
+
+	// Validate cursor config
+	switch c.Cursor.Store {
+	case "", "memory":
+	case "signed":
+		if c.Cursor.Secret == "" {
+			return fmt.Errorf("the signed cursor store requires a cursor secret")
+		}
+	case "redis":
+		if c.Cursor.Redis.Addr == "" {
+			return fmt.Errorf("Redis address is required with the redis cursor store")
+		}
+	default:
+		return fmt.Errorf("invalid cursor store %q, must be one of: memory, signed, redis", c.Cursor.Store)
+	}
+
+	if c.Cursor.Secret != "" && len(c.Cursor.Secret) < 32 {
+		return fmt.Errorf("cursor secret must be at least 32 bytes")
+	}
+	for _, secret := range c.Cursor.PreviousSecrets {
+		if len(secret) < 32 {
+			return fmt.Errorf("previous cursor secrets must be at least 32 bytes")
+		}
+	}
+	if len(c.Cursor.PreviousSecrets) > 0 && c.Cursor.Secret == "" {
+		return fmt.Errorf("previous cursor secrets require a current cursor secret")
+	}
+	if len(c.Cursor.PreviousSecrets) > 0 && c.Cursor.KeyRotatedAt.IsZero() {
+		return fmt.Errorf("previous cursor secrets require the cursor key rotation time")
+	}
+	if c.Cursor.KeyGrace < 0 {
+		return fmt.Errorf("cursor key grace must not be negative, got %s", c.Cursor.KeyGrace)
+	}
+
+	if c.Cursor.Store != "" && (c.Cursor.TTL <= 0 || c.Cursor.MaxBytes < 0) {
+		return fmt.Errorf("cursor TTL must be positive and max bytes not negative, got %s and %d", c.Cursor.TTL, c.Cursor.MaxBytes)
+	}
+
+	if c.Collections.WatchInterval < 0 {
+		return fmt.Errorf("collections watch interval must not be negative, got %s", c.Collections.WatchInterval)
+	}
+
+	// Validate rate limit config
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Rate <= 0 || c.RateLimit.Burst < 1 {
+			return fmt.Errorf("rate limit rate must be positive and burst at least 1, got %v and %d", c.RateLimit.Rate, c.RateLimit.Burst)
+		}
+
+		if _, err := c.RateLimit.RouteLimits(); err != nil {
+			return err
+		}
+	}
+
+	if c.RateLimit.UpstreamConcurrency < 0 || c.RateLimit.UpstreamWait < 0 {
+		return fmt.Errorf("upstream concurrency and wait must not be negative, got %d and %s", c.RateLimit.UpstreamConcurrency, c.RateLimit.UpstreamWait)
+	}
+
+	// Validate auth config
+	if c.Auth.JWKSFile != "" && c.Auth.JWKSURL != "" {
+		return fmt.Errorf("only one of JWKS file and JWKS URL can be set")
+	}
+
+	if c.Auth.Required && !c.Auth.Enabled() {
+		return fmt.Errorf("required authentication needs an API key file or a JWKS")
+	}
+
+	if c.Auth.APIKeysFile != "" && c.Auth.APIKeyHeader == "" {
+		return fmt.Errorf("API key header is required with an API key file")
+	}
+
+	if (c.Auth.JWKSFile != "" || c.Auth.JWKSURL != "") && (c.Auth.CollectionsClaim == "" || c.Auth.ClockSkew < 0 || c.Auth.JWKSRefresh < 0) {
+		return fmt.Errorf("JWT collections claim is required, and clock skew and JWKS refresh must not be negative")
+	}
+
+	// Validate download config
+	if c.Download.RewriteHrefs && !c.Download.Enabled {
+		return fmt.Errorf("rewriting asset hrefs requires the download proxy")
+	}
+
+	if c.Download.Enabled && (c.Download.TokenHeader == "" || c.Download.Timeout <= 0) {
+		return fmt.Errorf("download token header is required and timeout must be positive, got %s", c.Download.Timeout)
+	}
+
 	// Validate STAC config
 	if c.STAC.BaseURL == "" {
 		return fmt.Errorf("STAC base URL is required")
@@ -169,6 +492,21 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log format %q, must be one of: json, text", c.Logging.Format)
 	}
 
+	// Validate tracing config
+	switch c.Tracing.Exporter {
+	case "", "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			return fmt.Errorf("OTLP endpoint is required with the otlp trace exporter")
+		}
+	default:
+		return fmt.Errorf("invalid trace exporter %q, must be one of: none, stdout, otlp", c.Tracing.Exporter)
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
 	return nil
 }
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -328,6 +329,638 @@ func TestValidate(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "enabled cache without a size",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				Cache: CacheConfig{
+					Enabled:   true,
+					SearchTTL: 30 * time.Second,
+					ItemTTL:   10 * time.Minute,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "retry max delay below base delay",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				Retry: RetryConfig{
+					MaxAttempts: 3,
+					BaseDelay:   time.Second,
+					MaxDelay:    100 * time.Millisecond,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "failover to the same backend",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type:     "asf",
+					Failover: "asf",
+					Breaker: BreakerConfig{
+						Window:      20,
+						MinRequests: 10,
+						FailureRate: 0.5,
+						OpenTimeout: 30 * time.Second,
+					},
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "invalid trace exporter",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				Tracing: TracingConfig{
+					Exporter:    "jaeger",
+					SampleRatio: 1,
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "signed cursor store with short secret",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				Cursor: CursorConfig{
+					Store:    "signed",
+					TTL:      time.Hour,
+					MaxBytes: 8192,
+					Secret:   "too-short",
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "rate limits per route",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				RateLimit: RateLimitConfig{
+					Enabled: true,
+					Rate:    5,
+					Burst:   20,
+					Routes:  map[string]string{"/search": "1:5", "/collections/{collectionId}/items": "2.5:10"},
+				},
+			},
+			wantError: false,
+		},
+		{
+			name: "rate limit route without a burst",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				RateLimit: RateLimitConfig{
+					Enabled: true,
+					Rate:    5,
+					Burst:   20,
+					Routes:  map[string]string{"/search": "1"},
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "negative upstream concurrency",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				RateLimit: RateLimitConfig{
+					UpstreamConcurrency: -1,
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "API keys and JWKS URL",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				Auth: AuthConfig{
+					Required:         true,
+					APIKeysFile:      "/etc/stac/keys.json",
+					APIKeyHeader:     "X-API-Key",
+					JWKSURL:          "https://idp.example.com/jwks",
+					JWKSRefresh:      15 * time.Minute,
+					CollectionsClaim: "collections",
+					ClockSkew:        time.Minute,
+				},
+			},
+			wantError: false,
+		},
+		{
+			name: "JWKS file and URL",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				Auth: AuthConfig{
+					JWKSFile:         "/etc/stac/jwks.json",
+					JWKSURL:          "https://idp.example.com/jwks",
+					CollectionsClaim: "collections",
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "auth required without a method",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				Auth: AuthConfig{
+					Required: true,
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "API key file without header",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				Auth: AuthConfig{
+					APIKeysFile: "/etc/stac/keys.json",
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "JWKS without collections claim",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				Auth: AuthConfig{
+					JWKSURL: "https://idp.example.com/jwks",
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "downloads with href rewriting",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				Download: DownloadConfig{
+					Enabled:      true,
+					RewriteHrefs: true,
+					TokenHeader:  "X-Earthdata-Token",
+					TokenHosts:   []string{"asf.alaska.edu"},
+					Timeout:      30 * time.Second,
+				},
+			},
+			wantError: false,
+		},
+		{
+			name: "href rewriting without downloads",
+			cfg: &Config{
+				Server: ServerConfig{
+					Host:            "0.0.0.0",
+					Port:            8080,
+					ReadTimeout:     30 * time.Second,
+					WriteTimeout:    60 * time.Second,
+					ShutdownTimeout: 10 * time.Second,
+				},
+				Backend: BackendConfig{
+					Type: "asf",
+				},
+				ASF: ASFConfig{
+					BaseURL: "https://api.daac.asf.alaska.edu",
+					Timeout: 30 * time.Second,
+				},
+				CMR: CMRConfig{
+					BaseURL:  "https://cmr.earthdata.nasa.gov/search",
+					Provider: "ASF",
+					Timeout:  30 * time.Second,
+				},
+				STAC: STACConfig{
+					Version: "1.0.0",
+					BaseURL: "https://stac.example.com",
+				},
+				Features: FeatureConfig{
+					DefaultLimit: 10,
+					MaxLimit:     250,
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+				},
+				Download: DownloadConfig{
+					RewriteHrefs: true,
+					TokenHeader:  "X-Earthdata-Token",
+					Timeout:      30 * time.Second,
+				},
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -340,6 +973,177 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestLoadTracing(t *testing.T) {
+	os.Setenv("STAC_BASE_URL", "https://example.com")
+	os.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+	os.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "authorization=Bearer token,x-tenant=asf")
+	os.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.25")
+	defer func() {
+		os.Unsetenv("STAC_BASE_URL")
+		os.Unsetenv("OTEL_TRACES_EXPORTER")
+		os.Unsetenv("OTEL_EXPORTER_OTLP_HEADERS")
+		os.Unsetenv("OTEL_TRACES_SAMPLER_ARG")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Tracing.Exporter != "otlp" || cfg.Tracing.Endpoint != "http://localhost:4318" || cfg.Tracing.SampleRatio != 0.25 {
+		t.Errorf("unexpected tracing config %+v", cfg.Tracing)
+	}
+
+	if cfg.Tracing.Headers["authorization"] != "Bearer token" || cfg.Tracing.Headers["x-tenant"] != "asf" {
+		t.Errorf("unexpected OTLP headers %v", cfg.Tracing.Headers)
+	}
+}
+
+func TestLoadCursor(t *testing.T) {
+	os.Setenv("STAC_BASE_URL", "https://example.com")
+	os.Setenv("CURSOR_STORE", "redis")
+	os.Setenv("CURSOR_TTL", "30m")
+	os.Setenv("CURSOR_REDIS_ADDR", "redis.internal:6380")
+	os.Setenv("CURSOR_REDIS_DB", "2")
+	os.Setenv("CURSOR_SECRET", "current-secret-at-least-32-bytes-long")
+	os.Setenv("CURSOR_PREVIOUS_SECRETS", "previous-secret-at-least-32-bytes-long,older-secret-at-least-32-bytes-long")
+	os.Setenv("CURSOR_KEY_ROTATED_AT", "2024-01-15T12:00:00Z")
+	defer func() {
+		os.Unsetenv("STAC_BASE_URL")
+		os.Unsetenv("CURSOR_STORE")
+		os.Unsetenv("CURSOR_TTL")
+		os.Unsetenv("CURSOR_REDIS_ADDR")
+		os.Unsetenv("CURSOR_REDIS_DB")
+		os.Unsetenv("CURSOR_SECRET")
+		os.Unsetenv("CURSOR_PREVIOUS_SECRETS")
+		os.Unsetenv("CURSOR_KEY_ROTATED_AT")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Cursor.Store != "redis" || cfg.Cursor.TTL != 30*time.Minute || cfg.Cursor.MaxBytes != 8192 {
+		t.Errorf("unexpected cursor config %+v", cfg.Cursor)
+	}
+	if cfg.Cursor.Redis.Addr != "redis.internal:6380" || cfg.Cursor.Redis.DB != 2 || cfg.Cursor.Redis.KeyPrefix != "asf-stac:cursor:" {
+		t.Errorf("unexpected Redis config %+v", cfg.Cursor.Redis)
+	}
+	rotated := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	if len(cfg.Cursor.PreviousSecrets) != 2 || !cfg.Cursor.KeyRotatedAt.Equal(rotated) || cfg.Cursor.KeyGrace != time.Hour {
+		t.Errorf("unexpected cursor key rotation config %v, %s, %s", cfg.Cursor.PreviousSecrets, cfg.Cursor.KeyRotatedAt, cfg.Cursor.KeyGrace)
+	}
+
+	// Without the rotation time the grace window has no fixed end
+	os.Unsetenv("CURSOR_KEY_ROTATED_AT")
+	if _, err := Load(); err == nil {
+		t.Error("expected an error for previous secrets without a rotation time")
+	}
+}
+
+func TestLoadRateLimit(t *testing.T) {
+	os.Setenv("STAC_BASE_URL", "https://example.com")
+	os.Setenv("RATE_LIMIT_ENABLED", "true")
+	os.Setenv("RATE_LIMIT_ROUTES", "/search=1:5,/collections/{collectionId}/items=0.5:2")
+	os.Setenv("RATE_LIMIT_UPSTREAM_CONCURRENCY", "16")
+	defer func() {
+		os.Unsetenv("STAC_BASE_URL")
+		os.Unsetenv("RATE_LIMIT_ENABLED")
+		os.Unsetenv("RATE_LIMIT_ROUTES")
+		os.Unsetenv("RATE_LIMIT_UPSTREAM_CONCURRENCY")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if !cfg.RateLimit.Enabled || cfg.RateLimit.Rate != 5 || cfg.RateLimit.Burst != 20 {
+		t.Errorf("unexpected rate limit config %+v", cfg.RateLimit)
+	}
+	if cfg.RateLimit.UpstreamConcurrency != 16 || cfg.RateLimit.UpstreamWait != 5*time.Second {
+		t.Errorf("unexpected upstream concurrency %d, wait %s", cfg.RateLimit.UpstreamConcurrency, cfg.RateLimit.UpstreamWait)
+	}
+
+	routes, err := cfg.RateLimit.RouteLimits()
+	if err != nil {
+		t.Fatalf("RouteLimits() failed: %v", err)
+	}
+	want := map[string]RouteLimit{
+		"/search":                           {Rate: 1, Burst: 5},
+		"/collections/{collectionId}/items": {Rate: 0.5, Burst: 2},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("RouteLimits() = %v, want %v", routes, want)
+	}
+}
+
+func TestLoadAuth(t *testing.T) {
+	os.Setenv("STAC_BASE_URL", "https://example.com")
+	os.Setenv("AUTH_REQUIRED", "true")
+	os.Setenv("AUTH_API_KEYS_FILE", "/etc/stac/keys.json")
+	os.Setenv("AUTH_JWKS_URL", "https://idp.example.com/jwks")
+	os.Setenv("AUTH_JWT_CLAIM_PREFIX", "stac:")
+	defer func() {
+		os.Unsetenv("STAC_BASE_URL")
+		os.Unsetenv("AUTH_REQUIRED")
+		os.Unsetenv("AUTH_API_KEYS_FILE")
+		os.Unsetenv("AUTH_JWKS_URL")
+		os.Unsetenv("AUTH_JWT_CLAIM_PREFIX")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	want := AuthConfig{
+		Required:         true,
+		APIKeysFile:      "/etc/stac/keys.json",
+		APIKeyHeader:     "X-API-Key",
+		JWKSURL:          "https://idp.example.com/jwks",
+		JWKSRefresh:      15 * time.Minute,
+		CollectionsClaim: "collections",
+		ClaimPrefix:      "stac:",
+		ClockSkew:        time.Minute,
+	}
+	if cfg.Auth != want {
+		t.Errorf("unexpected auth config %+v", cfg.Auth)
+	}
+	if !cfg.Auth.Enabled() {
+		t.Error("expected auth to be enabled")
+	}
+}
+
+func TestLoadDownload(t *testing.T) {
+	os.Setenv("STAC_BASE_URL", "https://example.com")
+	os.Setenv("DOWNLOAD_ENABLED", "true")
+	os.Setenv("DOWNLOAD_EARTHDATA_TOKEN", "edl-token")
+	defer func() {
+		os.Unsetenv("STAC_BASE_URL")
+		os.Unsetenv("DOWNLOAD_ENABLED")
+		os.Unsetenv("DOWNLOAD_EARTHDATA_TOKEN")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	want := DownloadConfig{
+		Enabled:        true,
+		EarthdataToken: "edl-token",
+		TokenHeader:    "X-Earthdata-Token",
+		TokenHosts:     []string{"asf.alaska.edu", "earthdata.nasa.gov"},
+		LoginHost:      "urs.earthdata.nasa.gov",
+		Timeout:        30 * time.Second,
+	}
+	if !reflect.DeepEqual(cfg.Download, want) {
+		t.Errorf("unexpected download config %+v", cfg.Download)
+	}
+}
+
 func TestServerConfigAddress(t *testing.T) {
 	cfg := ServerConfig{
 		Host: "localhost",
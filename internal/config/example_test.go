@@ -54,7 +54,7 @@ func ExampleLoadCollections() {
 	// Collection ID: sentinel-1-slc
 	// Title: Sentinel-1 SAR - Single Look Complex (SLC)
 	// ASF Datasets: [SENTINEL-1]
-	// Total collections: 14
+	// Total collections: 23
 }
 
 func ExampleCollectionRegistry_FindByASFDataset() {
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// frequencyBands are the SAR frequency bands of the STAC SAR extension
+var frequencyBands = []string{"P", "L", "S", "C", "X", "Ku", "K", "Ka"}
+
+// PlatformConfig describes a platform whose items a collection serves, so
+// items translated from its ASF and CMR metadata carry its constellation,
+// instrument and radar band.
+type PlatformConfig struct {
+	// Names are the names of the platform in ASF and CMR metadata, such as
+	// Sentinel-1A, matched without regard to case
+	Names []string `json:"names"`
+	// Constellation is the STAC constellation of the platform
+	Constellation string `json:"constellation,omitempty"`
+	// Instrument is the platform's instrument, for metadata that names none
+	Instrument string `json:"instrument,omitempty"`
+	// FrequencyBand is the SAR frequency band of the platform's radar
+	FrequencyBand string `json:"frequency_band,omitempty"`
+	// CenterFrequency is the center frequency of the radar, in GHz
+	CenterFrequency float64 `json:"center_frequency,omitempty"`
+	// Instruments override the band and center frequency for platforms
+	// carrying more than one radar, by instrument name
+	Instruments map[string]InstrumentConfig `json:"instruments,omitempty"`
+}
+
+// InstrumentConfig describes one radar of a platform.
+type InstrumentConfig struct {
+	FrequencyBand   string  `json:"frequency_band,omitempty"`
+	CenterFrequency float64 `json:"center_frequency,omitempty"`
+}
+
+// Band returns the frequency band and center frequency of an instrument of
+// the platform, those of the platform unless the instrument overrides them.
+func (p *PlatformConfig) Band(instrument string) (string, float64) {
+	for name, inst := range p.Instruments {
+		if strings.EqualFold(name, instrument) {
+			return inst.FrequencyBand, inst.CenterFrequency
+		}
+	}
+	return p.FrequencyBand, p.CenterFrequency
+}
+
+// validate checks that a platform definition is valid.
+func (p *PlatformConfig) validate() error {
+	if len(p.Names) == 0 {
+		return fmt.Errorf("platform must have at least one name")
+	}
+	if err := validateBand(p.FrequencyBand, p.CenterFrequency); err != nil {
+		return fmt.Errorf("platform %s: %w", p.Names[0], err)
+	}
+	for name, inst := range p.Instruments {
+		if err := validateBand(inst.FrequencyBand, inst.CenterFrequency); err != nil {
+			return fmt.Errorf("platform %s instrument %s: %w", p.Names[0], name, err)
+		}
+	}
+	return nil
+}
+
+// validateBand checks a frequency band and center frequency
+func validateBand(band string, frequency float64) error {
+	if band != "" && !slices.Contains(frequencyBands, band) {
+		return fmt.Errorf("unknown frequency band %q", band)
+	}
+	if frequency < 0 {
+		return fmt.Errorf("center frequency must not be negative, got %g", frequency)
+	}
+	return nil
+}
+
+// Platform returns the collection's definition of the named platform, or
+// nil if it defines none.
+func (c *CollectionConfig) Platform(name string) *PlatformConfig {
+	for i := range c.Platforms {
+		for _, n := range c.Platforms[i].Names {
+			if strings.EqualFold(n, name) {
+				return &c.Platforms[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ProcessingLevel maps an ASF processing level or product type to the STAC
+// processing level of the collection's items. It reports false for levels
+// the collection does not map.
+func (c *CollectionConfig) ProcessingLevel(level string) (string, bool) {
+	for from, to := range c.ProcessingLevels {
+		if strings.EqualFold(from, level) {
+			return to, true
+		}
+	}
+	return "", false
+}
+
+// Platform returns the definition of a platform for an item of a
+// collection: the collection's own or, for items of no known collection,
+// that of the first collection by ID that defines the platform. It returns
+// nil for platforms not defined there and for a nil registry.
+func (r *CollectionRegistry) Platform(collectionID, name string) *PlatformConfig {
+	if r == nil || name == "" {
+		return nil
+	}
+	if collection := r.Get(collectionID); collection != nil {
+		return collection.Platform(name)
+	}
+	for _, collection := range r.sorted() {
+		if p := collection.Platform(name); p != nil {
+			return p
+		}
+	}
+	return nil
+}
+
+// ProcessingLevel maps an ASF processing level or product type of an item
+// of a collection to its STAC processing level, resolved like Platform.
+// Levels not mapped there are returned in upper case.
+func (r *CollectionRegistry) ProcessingLevel(collectionID, level string) string {
+	if r != nil {
+		if collection := r.Get(collectionID); collection != nil {
+			if mapped, ok := collection.ProcessingLevel(level); ok {
+				return mapped
+			}
+			return strings.ToUpper(level)
+		}
+		for _, collection := range r.sorted() {
+			if mapped, ok := collection.ProcessingLevel(level); ok {
+				return mapped
+			}
+		}
+	}
+	return strings.ToUpper(level)
+}
+
+// sorted returns all collections in the registry ordered by ID.
+func (r *CollectionRegistry) sorted() []*CollectionConfig {
+	collections := r.All()
+	slices.SortFunc(collections, func(a, b *CollectionConfig) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return collections
+}
@@ -0,0 +1,138 @@
+package config
+
+import "testing"
+
+func testPlatformRegistry(t *testing.T) *CollectionRegistry {
+	t.Helper()
+	registry := NewCollectionRegistry()
+	collections := []*CollectionConfig{
+		{
+			ID: "nisar",
+			Platforms: []PlatformConfig{{
+				Names:           []string{"NISAR"},
+				Constellation:   "nisar",
+				FrequencyBand:   "L",
+				CenterFrequency: 1.257,
+				Instruments: map[string]InstrumentConfig{
+					"S-SAR": {FrequencyBand: "S", CenterFrequency: 3.2},
+				},
+			}},
+			ProcessingLevels: map[string]string{"GCOV": "L2"},
+		},
+		{
+			ID:               "b-slc",
+			Platforms:        []PlatformConfig{{Names: []string{"Sentinel-1A"}, Constellation: "b"}},
+			ProcessingLevels: map[string]string{"SLC": "L1"},
+		},
+		{
+			ID:               "a-slc",
+			Platforms:        []PlatformConfig{{Names: []string{"Sentinel-1A"}, Constellation: "a"}},
+			ProcessingLevels: map[string]string{"SLC": "L1A"},
+		},
+	}
+	for _, c := range collections {
+		if err := registry.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return registry
+}
+
+func TestCollectionRegistryPlatform(t *testing.T) {
+	registry := testPlatformRegistry(t)
+
+	tests := []struct {
+		name       string
+		collection string
+		platform   string
+		want       string
+	}{
+		{"own definition", "b-slc", "Sentinel-1A", "b"},
+		{"names match without regard to case", "b-slc", "SENTINEL-1A", "b"},
+		{"no collection uses the first by ID", "", "sentinel-1a", "a"},
+		{"other collection's definition ignored", "nisar", "Sentinel-1A", ""},
+		{"unknown collection uses the first by ID", "c-slc", "Sentinel-1A", "a"},
+		{"unknown platform", "b-slc", "ALOS", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if p := registry.Platform(tt.collection, tt.platform); p != nil {
+				got = p.Constellation
+			}
+			if got != tt.want {
+				t.Errorf("Platform(%q, %q) constellation = %q, want %q", tt.collection, tt.platform, got, tt.want)
+			}
+		})
+	}
+
+	var nilRegistry *CollectionRegistry
+	if p := nilRegistry.Platform("nisar", "NISAR"); p != nil {
+		t.Errorf("expected no platform from a nil registry, got %+v", p)
+	}
+}
+
+func TestPlatformConfigBand(t *testing.T) {
+	p := testPlatformRegistry(t).Platform("nisar", "NISAR")
+
+	if band, frequency := p.Band("L-SAR"); band != "L" || frequency != 1.257 {
+		t.Errorf("Band(L-SAR) = %s, %g, want L, 1.257", band, frequency)
+	}
+	if band, frequency := p.Band("s-sar"); band != "S" || frequency != 3.2 {
+		t.Errorf("Band(s-sar) = %s, %g, want S, 3.2", band, frequency)
+	}
+}
+
+func TestCollectionRegistryProcessingLevel(t *testing.T) {
+	registry := testPlatformRegistry(t)
+
+	tests := []struct {
+		collection string
+		level      string
+		want       string
+	}{
+		{"b-slc", "SLC", "L1"},
+		{"b-slc", "slc", "L1"},
+		{"", "SLC", "L1A"},
+		{"b-slc", "GCOV", "GCOV"},
+		{"c-slc", "GCOV", "L2"},
+		{"b-slc", "grd_hd", "GRD_HD"},
+	}
+	for _, tt := range tests {
+		if got := registry.ProcessingLevel(tt.collection, tt.level); got != tt.want {
+			t.Errorf("ProcessingLevel(%q, %q) = %q, want %q", tt.collection, tt.level, got, tt.want)
+		}
+	}
+
+	var nilRegistry *CollectionRegistry
+	if got := nilRegistry.ProcessingLevel("b-slc", "slc"); got != "SLC" {
+		t.Errorf("expected levels passed through by a nil registry, got %q", got)
+	}
+}
+
+func TestPlatformConfigValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		platform  PlatformConfig
+		wantError bool
+	}{
+		{"valid", PlatformConfig{Names: []string{"ALOS"}, FrequencyBand: "L", CenterFrequency: 1.27}, false},
+		{"band only", PlatformConfig{Names: []string{"ALOS"}, FrequencyBand: "Ku"}, false},
+		{"missing names", PlatformConfig{FrequencyBand: "L"}, true},
+		{"unknown band", PlatformConfig{Names: []string{"ALOS"}, FrequencyBand: "l"}, true},
+		{"negative frequency", PlatformConfig{Names: []string{"ALOS"}, CenterFrequency: -1}, true},
+		{
+			"unknown instrument band",
+			PlatformConfig{Names: []string{"NISAR"}, Instruments: map[string]InstrumentConfig{"S-SAR": {FrequencyBand: "Z"}}},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.platform.validate()
+			if (err != nil) != tt.wantError {
+				t.Errorf("validate() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
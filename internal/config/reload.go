@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CollectionReloader reloads a CollectionRegistry from its directory, on
+// demand or when the directory changes. A new set of collections replaces the
+// current one only once every file in it has loaded and validated; otherwise
+// the current set stays active.
+type CollectionReloader struct {
+	registry *CollectionRegistry
+	dir      string
+	logger   *slog.Logger
+
+	mu       sync.Mutex // serializes reloads
+	snapshot string     // the directory's state at the last reload attempt
+}
+
+// NewCollectionReloader creates a reloader loading dir into registry.
+func NewCollectionReloader(registry *CollectionRegistry, dir string, logger *slog.Logger) *CollectionReloader {
+	r := &CollectionReloader{registry: registry, dir: dir, logger: logger}
+	r.snapshot, _ = snapshotDir(dir)
+	return r
+}
+
+// Reload loads the collections in the directory and, if they are all valid,
+// swaps them into the registry. On error the registry is left unchanged.
+func (r *CollectionReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snapshot, _ = snapshotDir(r.dir)
+	return r.reload()
+}
+
+func (r *CollectionReloader) reload() error {
+	loaded, err := LoadCollections(r.dir)
+	if err != nil {
+		r.logger.Error("failed to reload collections, keeping the current set",
+			slog.String("dir", r.dir),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	before := r.registry.IDs()
+	r.registry.Replace(loaded)
+	after := loaded.IDs()
+
+	var added, removed []string
+	for _, id := range after {
+		if !slices.Contains(before, id) {
+			added = append(added, id)
+		}
+	}
+	for _, id := range before {
+		if !slices.Contains(after, id) {
+			removed = append(removed, id)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+
+	r.logger.Info("reloaded collections",
+		slog.String("dir", r.dir),
+		slog.Int("count", len(after)),
+		slog.Any("added", added),
+		slog.Any("removed", removed),
+	)
+	return nil
+}
+
+// Watch checks the directory every interval until ctx is done, reloading
+// when a collection file was added, removed or modified. A set that fails to
+// load is not retried until the directory changes again.
+func (r *CollectionReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		snapshot, err := snapshotDir(r.dir)
+		if err != nil {
+			r.logger.Warn("failed to check collections directory",
+				slog.String("dir", r.dir),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		r.mu.Lock()
+		if snapshot != r.snapshot {
+			r.snapshot = snapshot
+			r.logger.Info("collections directory changed, reloading", slog.String("dir", r.dir))
+			r.reload()
+		}
+		r.mu.Unlock()
+	}
+}
+
+// snapshotDir summarizes the name, size and modification time of the
+// collection files in dir, to detect changes
+func snapshotDir(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".json") {
+			continue
+		}
+		// Follow symlinks, as in Kubernetes ConfigMap volumes
+		info, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s\x00%d\x00%d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
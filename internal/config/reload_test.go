@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeCollectionFile writes a valid collection definition with the given ID
+func writeCollectionFile(t *testing.T, dir, id string) {
+	t.Helper()
+	collection := CollectionConfig{
+		ID:          id,
+		Title:       "Collection " + id,
+		Description: "A test collection",
+		ASFDatasets: []string{"TEST-DATASET"},
+		License:     "proprietary",
+		Extent: Extent{
+			Spatial:  SpatialExtent{BBox: [][]float64{{-180, -90, 180, 90}}},
+			Temporal: TemporalExtent{Interval: [][]interface{}{{"2020-01-01T00:00:00Z", nil}}},
+		},
+	}
+	data, err := json.Marshal(collection)
+	if err != nil {
+		t.Fatalf("failed to marshal collection: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, id+".json"), data, 0644); err != nil {
+		t.Fatalf("failed to write collection: %v", err)
+	}
+}
+
+// syncBuffer is a log destination safe for concurrent writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newReloaderForTest(t *testing.T, ids ...string) (*CollectionReloader, *CollectionRegistry, string, *syncBuffer) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, id := range ids {
+		writeCollectionFile(t, dir, id)
+	}
+	registry, err := LoadCollections(dir)
+	if err != nil {
+		t.Fatalf("LoadCollections() failed: %v", err)
+	}
+	logs := &syncBuffer{}
+	logger := slog.New(slog.NewTextHandler(logs, nil))
+	return NewCollectionReloader(registry, dir, logger), registry, dir, logs
+}
+
+func sortedIDs(registry *CollectionRegistry) []string {
+	ids := registry.IDs()
+	slices.Sort(ids)
+	return ids
+}
+
+func TestCollectionReloader_Reload(t *testing.T) {
+	reloader, registry, dir, logs := newReloaderForTest(t, "sentinel-1", "alos-palsar")
+
+	writeCollectionFile(t, dir, "opera-rtc")
+	os.Remove(filepath.Join(dir, "alos-palsar.json"))
+
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if ids := sortedIDs(registry); !slices.Equal(ids, []string{"opera-rtc", "sentinel-1"}) {
+		t.Errorf("collections after reload = %v", ids)
+	}
+	if !strings.Contains(logs.String(), "reloaded collections") || !strings.Contains(logs.String(), "added=[opera-rtc]") {
+		t.Errorf("expected the reload to be logged with its changes, got %s", logs.String())
+	}
+}
+
+func TestCollectionReloader_InvalidSetKeepsCurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, dir string)
+	}{
+		{"malformed JSON", func(t *testing.T, dir string) {
+			os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644)
+		}},
+		{"invalid collection", func(t *testing.T, dir string) {
+			os.WriteFile(filepath.Join(dir, "untitled.json"), []byte(`{"id": "untitled"}`), 0644)
+		}},
+		{"duplicate ID", func(t *testing.T, dir string) {
+			data, _ := os.ReadFile(filepath.Join(dir, "sentinel-1.json"))
+			os.WriteFile(filepath.Join(dir, "copy.json"), data, 0644)
+		}},
+		{"no collections left", func(t *testing.T, dir string) {
+			os.Remove(filepath.Join(dir, "sentinel-1.json"))
+			os.Remove(filepath.Join(dir, "opera-rtc.json"))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader, registry, dir, logs := newReloaderForTest(t, "sentinel-1")
+			// A valid addition alongside the bad file must not be applied either
+			writeCollectionFile(t, dir, "opera-rtc")
+			tt.corrupt(t, dir)
+
+			if err := reloader.Reload(); err == nil {
+				t.Fatal("Reload() succeeded with an invalid collection set")
+			}
+			if ids := sortedIDs(registry); !slices.Equal(ids, []string{"sentinel-1"}) {
+				t.Errorf("collections after failed reload = %v, want the previous set", ids)
+			}
+			if !strings.Contains(logs.String(), "keeping the current set") {
+				t.Errorf("expected the failure to be logged, got %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestCollectionReloader_Watch(t *testing.T) {
+	reloader, registry, dir, _ := newReloaderForTest(t, "sentinel-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 10*time.Millisecond)
+
+	writeCollectionFile(t, dir, "nisar")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !registry.Has("nisar") {
+		if time.Now().After(deadline) {
+			t.Fatal("the new collection file was not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !registry.Has("sentinel-1") {
+		t.Error("existing collection lost on reload")
+	}
+}
+
+func TestCollectionRegistry_ConcurrentReplace(t *testing.T) {
+	registry := NewCollectionRegistry()
+	registry.Add(&CollectionConfig{ID: "a"})
+
+	next := NewCollectionRegistry()
+	next.Add(&CollectionConfig{ID: "a"})
+	next.Add(&CollectionConfig{ID: "b"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				// Every reader sees a complete set: a is in both
+				if !registry.Has("a") || registry.Get("a") == nil {
+					t.Error("collection a missing during replace")
+					return
+				}
+				registry.All()
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			registry.Replace(next)
+		} else {
+			old := NewCollectionRegistry()
+			old.Add(&CollectionConfig{ID: "a"})
+			registry.Replace(old)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,564 @@
+package cql2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PropertyGetter returns the value of a named property of the item being
+// evaluated, and whether the property is present.
+type PropertyGetter func(name string) (any, bool)
+
+// Predicate is a compiled CQL2 boolean expression. It reports whether the
+// expression holds for the item whose properties are exposed by get.
+type Predicate func(get PropertyGetter) bool
+
+// UnsupportedError is returned by Compile for well-formed expressions that use
+// operators or functions the in-proxy evaluator does not implement.
+type UnsupportedError struct {
+	Op string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("operator %q is not supported", e.Op)
+}
+
+// truth is a three-valued logic result. Comparisons involving a missing
+// property are unknown, so NOT (platform = 'x') does not match items that
+// have no platform at all, as in SQL.
+type truth int
+
+const (
+	truthFalse truth = iota
+	truthTrue
+	truthUnknown
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return truthTrue
+	}
+	return truthFalse
+}
+
+type boolExpr func(get PropertyGetter) truth
+
+// scalarExpr evaluates to a value, or ok=false when the value is unknown
+type scalarExpr func(get PropertyGetter) (v any, ok bool)
+
+// Compile converts a CQL2-JSON expression into a Predicate. Errors describe
+// malformed expressions or unsupported operators.
+func Compile(expr any) (Predicate, error) {
+	b, err := compileBool(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(get PropertyGetter) bool {
+		return b(get) == truthTrue
+	}, nil
+}
+
+// Conjuncts splits an expression into the operands of its top-level AND.
+// Any other expression is returned as a single conjunct.
+func Conjuncts(expr any) []any {
+	opName, args, ok := opArgs(expr)
+	if !ok || opName != "and" {
+		return []any{expr}
+	}
+	var out []any
+	for _, arg := range args {
+		out = append(out, Conjuncts(arg)...)
+	}
+	return out
+}
+
+// And combines expressions with a logical AND, returning nil for no
+// expressions and the expression itself for one.
+func And(exprs ...any) any {
+	switch len(exprs) {
+	case 0:
+		return nil
+	case 1:
+		return exprs[0]
+	}
+	return op("and", exprs...)
+}
+
+// opArgs returns the operator name and arguments of a CQL2-JSON operator node
+func opArgs(expr any) (string, []any, bool) {
+	m, ok := expr.(map[string]any)
+	if !ok {
+		return "", nil, false
+	}
+	name, ok := m["op"].(string)
+	if !ok {
+		return "", nil, false
+	}
+	args, ok := m["args"].([]any)
+	if !ok {
+		return "", nil, false
+	}
+	return name, args, true
+}
+
+// PropertyName returns the property name if expr is a property reference.
+func PropertyName(expr any) (string, bool) {
+	m, ok := expr.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	name, ok := m["property"].(string)
+	return name, ok
+}
+
+// Op returns the lower-cased operator name and arguments if expr is an
+// operator node.
+func Op(expr any) (string, []any, bool) {
+	name, args, ok := opArgs(expr)
+	if !ok {
+		return "", nil, false
+	}
+	return strings.ToLower(name), args, true
+}
+
+func compileBool(expr any) (boolExpr, error) {
+	if b, ok := expr.(bool); ok {
+		t := truthOf(b)
+		return func(PropertyGetter) truth { return t }, nil
+	}
+
+	name, args, ok := Op(expr)
+	if !ok {
+		return nil, fmt.Errorf("expected a boolean expression, got %s", describe(expr))
+	}
+
+	switch name {
+	case "and", "or":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%q requires at least 2 arguments", name)
+		}
+		operands := make([]boolExpr, len(args))
+		for i, arg := range args {
+			b, err := compileBool(arg)
+			if err != nil {
+				return nil, err
+			}
+			operands[i] = b
+		}
+		if name == "and" {
+			return func(get PropertyGetter) truth {
+				result := truthTrue
+				for _, operand := range operands {
+					switch operand(get) {
+					case truthFalse:
+						return truthFalse
+					case truthUnknown:
+						result = truthUnknown
+					}
+				}
+				return result
+			}, nil
+		}
+		return func(get PropertyGetter) truth {
+			result := truthFalse
+			for _, operand := range operands {
+				switch operand(get) {
+				case truthTrue:
+					return truthTrue
+				case truthUnknown:
+					result = truthUnknown
+				}
+			}
+			return result
+		}, nil
+
+	case "not":
+		if len(args) != 1 {
+			return nil, fmt.Errorf(`"not" requires exactly 1 argument`)
+		}
+		inner, err := compileBool(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(get PropertyGetter) truth {
+			switch inner(get) {
+			case truthTrue:
+				return truthFalse
+			case truthFalse:
+				return truthTrue
+			}
+			return truthUnknown
+		}, nil
+
+	case "=", "<>", "<", "<=", ">", ">=":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%q requires exactly 2 arguments", name)
+		}
+		left, err := compileScalar(args[0])
+		if err != nil {
+			return nil, err
+		}
+		right, err := compileScalar(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return func(get PropertyGetter) truth {
+			l, lok := left(get)
+			r, rok := right(get)
+			if !lok || !rok {
+				return truthUnknown
+			}
+			cmp, ok := compareValues(l, r)
+			if !ok {
+				return truthUnknown
+			}
+			return truthOf(applyComparison(name, cmp))
+		}, nil
+
+	case "between":
+		if len(args) != 3 {
+			return nil, fmt.Errorf(`"between" requires exactly 3 arguments`)
+		}
+		operands := make([]scalarExpr, 3)
+		for i, arg := range args {
+			s, err := compileScalar(arg)
+			if err != nil {
+				return nil, err
+			}
+			operands[i] = s
+		}
+		return func(get PropertyGetter) truth {
+			v, ok := operands[0](get)
+			low, lok := operands[1](get)
+			high, hok := operands[2](get)
+			if !ok || !lok || !hok {
+				return truthUnknown
+			}
+			cmpLow, ok1 := compareValues(v, low)
+			cmpHigh, ok2 := compareValues(v, high)
+			if !ok1 || !ok2 {
+				return truthUnknown
+			}
+			return truthOf(cmpLow >= 0 && cmpHigh <= 0)
+		}, nil
+
+	case "in":
+		if len(args) != 2 {
+			return nil, fmt.Errorf(`"in" requires exactly 2 arguments`)
+		}
+		item, err := compileScalar(args[0])
+		if err != nil {
+			return nil, err
+		}
+		list, ok := args[1].([]any)
+		if !ok {
+			return nil, fmt.Errorf(`second argument of "in" must be a list`)
+		}
+		values := make([]scalarExpr, len(list))
+		for i, v := range list {
+			s, err := compileScalar(v)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = s
+		}
+		return func(get PropertyGetter) truth {
+			v, ok := item(get)
+			if !ok {
+				return truthUnknown
+			}
+			result := truthFalse
+			for _, candidate := range values {
+				c, cok := candidate(get)
+				if !cok {
+					result = truthUnknown
+					continue
+				}
+				if cmp, ok := compareValues(v, c); ok && cmp == 0 {
+					return truthTrue
+				}
+			}
+			return result
+		}, nil
+
+	case "like":
+		if len(args) != 2 {
+			return nil, fmt.Errorf(`"like" requires exactly 2 arguments`)
+		}
+		value, err := compileScalar(args[0])
+		if err != nil {
+			return nil, err
+		}
+		pattern, caseInsensitive, err := likePattern(args[1])
+		if err != nil {
+			return nil, err
+		}
+		re, err := compileLike(pattern, caseInsensitive)
+		if err != nil {
+			return nil, err
+		}
+		return func(get PropertyGetter) truth {
+			v, ok := value(get)
+			if !ok {
+				return truthUnknown
+			}
+			s, ok := v.(string)
+			if !ok {
+				return truthUnknown
+			}
+			return truthOf(re.MatchString(s))
+		}, nil
+
+	case "isnull":
+		if len(args) != 1 {
+			return nil, fmt.Errorf(`"isNull" requires exactly 1 argument`)
+		}
+		value, err := compileScalar(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(get PropertyGetter) truth {
+			_, ok := value(get)
+			return truthOf(!ok)
+		}, nil
+
+	case "s_intersects", "s_disjoint", "s_within", "s_contains":
+		return compileSpatial(name, args)
+	}
+
+	if IsTemporalOp(name) {
+		return compileTemporal(name, args)
+	}
+
+	return nil, &UnsupportedError{Op: name}
+}
+
+func applyComparison(name string, cmp int) bool {
+	switch name {
+	case "=":
+		return cmp == 0
+	case "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// likePattern extracts the pattern string from the second argument of "like",
+// which may be wrapped in casei().
+func likePattern(arg any) (string, bool, error) {
+	if s, ok := arg.(string); ok {
+		return s, false, nil
+	}
+	if name, args, ok := Op(arg); ok && name == "casei" && len(args) == 1 {
+		if s, ok := args[0].(string); ok {
+			return s, true, nil
+		}
+	}
+	return "", false, fmt.Errorf(`pattern of "like" must be a string literal`)
+}
+
+// compileLike translates a CQL2 LIKE pattern into an anchored regular
+// expression. '%' matches any sequence, '_' a single character, and '\'
+// escapes the next character.
+func compileLike(pattern string, caseInsensitive bool) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	if caseInsensitive {
+		sb.WriteString("(?i)")
+	}
+	sb.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			sb.WriteString("(?s:.*)")
+		case r == '_':
+			sb.WriteString("(?s:.)")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		sb.WriteString(regexp.QuoteMeta(`\`))
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+func compileScalar(expr any) (scalarExpr, error) {
+	switch v := expr.(type) {
+	case nil:
+		return func(PropertyGetter) (any, bool) { return nil, false }, nil
+	case string, bool:
+		return func(PropertyGetter) (any, bool) { return v, true }, nil
+	case float64:
+		return func(PropertyGetter) (any, bool) { return v, true }, nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", v)
+		}
+		return func(PropertyGetter) (any, bool) { return f, true }, nil
+	case map[string]any:
+		if name, ok := PropertyName(v); ok {
+			return func(get PropertyGetter) (any, bool) {
+				value, ok := get(name)
+				if !ok || value == nil {
+					return nil, false
+				}
+				return value, true
+			}, nil
+		}
+		if ts, ok := v["timestamp"].(string); ok {
+			t, err := time.Parse(time.RFC3339Nano, ts)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp %q", ts)
+			}
+			return func(PropertyGetter) (any, bool) { return t, true }, nil
+		}
+		if d, ok := v["date"].(string); ok {
+			t, err := time.Parse("2006-01-02", d)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date %q", d)
+			}
+			return func(PropertyGetter) (any, bool) { return t, true }, nil
+		}
+		if name, args, ok := Op(v); ok && name == "casei" {
+			if len(args) != 1 {
+				return nil, fmt.Errorf(`"casei" requires exactly 1 argument`)
+			}
+			inner, err := compileScalar(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return func(get PropertyGetter) (any, bool) {
+				value, ok := inner(get)
+				if !ok {
+					return nil, false
+				}
+				s, ok := value.(string)
+				if !ok {
+					return nil, false
+				}
+				return strings.ToLower(s), true
+			}, nil
+		}
+		if name, _, ok := Op(v); ok {
+			return nil, &UnsupportedError{Op: name}
+		}
+	}
+	return nil, fmt.Errorf("unsupported value %s", describe(expr))
+}
+
+// compareValues orders two values. Numbers compare numerically, including
+// numeric strings such as the orbit numbers CMR reports as text; instants
+// compare chronologically; strings lexically; booleans only for equality.
+// ok is false when the values are not comparable.
+func compareValues(a, b any) (int, bool) {
+	if at, ok := toTime(a); ok {
+		if bt, ok := toTime(b); ok {
+			return at.Compare(bt), true
+		}
+	}
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			switch {
+			case af < bf:
+				return -1, true
+			case af > bf:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok {
+			return strings.Compare(as, bs), true
+		}
+	}
+	if ab, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			if ab == bb {
+				return 0, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+// toFloat converts numeric values, and strings that hold a number, to float64
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// toTime converts time values and RFC 3339 strings to time.Time. Plain
+// strings only convert when they parse fully as a timestamp, so ordinary
+// string properties keep lexical comparison.
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, false
+		}
+		return *t, true
+	case string:
+		if len(t) < len("2006-01-02T15:04:05") || t[4] != '-' {
+			return time.Time{}, false
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return parsed, true
+	}
+	return time.Time{}, false
+}
+
+func describe(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -0,0 +1,419 @@
+package cql2
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/pkg/geojson"
+)
+
+func testProperties() map[string]any {
+	return map[string]any{
+		"platform":            "sentinel-1a",
+		"sar:instrument_mode": "IW",
+		"sar:product_type":    "GRD_HD",
+		"sat:orbit_state":     "ascending",
+		"sat:relative_orbit":  42,
+		"sat:absolute_orbit":  "51234", // CMR reports some numbers as text
+		"view:off_nadir":      33.5,
+		"start_datetime":      time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+		"end_datetime":        "2023-06-01T12:00:25Z",
+		"processing:level":    nil,
+	}
+}
+
+func getter(props map[string]any) PropertyGetter {
+	return func(name string) (any, bool) {
+		v, ok := props[name]
+		return v, ok && v != nil
+	}
+}
+
+func TestCompile_Evaluate(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		// Equality and inequality
+		{"platform = 'sentinel-1a'", true},
+		{"platform <> 'sentinel-1a'", false},
+		{"platform <> 'sentinel-1b'", true},
+		{"'IW' = sar:instrument_mode", true},
+
+		// NOT is a real negation, not a flattened equality
+		{"NOT (platform = 'sentinel-1a')", false},
+		{"NOT (platform = 'sentinel-1b')", true},
+
+		// Ordering comparisons on numbers, numeric strings and strings
+		{"sat:relative_orbit > 40", true},
+		{"sat:relative_orbit < 40", false},
+		{"sat:relative_orbit >= 42 AND sat:relative_orbit <= 42", true},
+		{"sat:absolute_orbit > 50000", true},
+		{"sat:absolute_orbit = 51234", true},
+		{"view:off_nadir < 33.6", true},
+		{"sar:product_type > 'GRD'", true},
+
+		// BETWEEN
+		{"sat:relative_orbit BETWEEN 40 AND 50", true},
+		{"sat:relative_orbit BETWEEN 43 AND 50", false},
+		{"sat:relative_orbit NOT BETWEEN 43 AND 50", true},
+
+		// IN
+		{"sar:instrument_mode IN ('EW', 'IW')", true},
+		{"sar:instrument_mode NOT IN ('EW', 'SM')", true},
+		{"sat:relative_orbit IN (1, 42)", true},
+
+		// LIKE
+		{"sar:product_type LIKE 'GRD%'", true},
+		{"sar:product_type LIKE 'GRD_'", false},
+		{"sar:product_type LIKE 'GRD___'", true},
+		{"sar:product_type LIKE 'grd%'", false},
+		{"sar:product_type LIKE casei('grd%')", true},
+		{"sar:product_type NOT LIKE 'SLC%'", true},
+
+		// IS NULL, for missing and null-valued properties
+		{"processing:level IS NULL", true},
+		{"sar:polarizations IS NULL", true},
+		{"platform IS NULL", false},
+		{"platform IS NOT NULL", true},
+
+		// OR across different properties
+		{"platform = 'sentinel-1b' OR sar:instrument_mode = 'IW'", true},
+		{"platform = 'sentinel-1b' OR sar:instrument_mode = 'EW'", false},
+
+		// casei
+		{"casei(platform) = casei('SENTINEL-1A')", true},
+
+		// Instants compare chronologically against time values and RFC 3339 strings
+		{"start_datetime > TIMESTAMP('2023-01-01T00:00:00Z')", true},
+		{"start_datetime < DATE('2023-06-01')", false},
+		{"end_datetime >= TIMESTAMP('2023-06-01T12:00:25Z')", true},
+		{"start_datetime BETWEEN TIMESTAMP('2023-06-01T00:00:00Z') AND TIMESTAMP('2023-06-02T00:00:00Z')", true},
+
+		// Boolean literals
+		{"TRUE", true},
+		{"FALSE", false},
+	}
+
+	props := getter(testProperties())
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			expr, err := ParseText(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseText error: %v", err)
+			}
+			pred, err := Compile(expr)
+			if err != nil {
+				t.Fatalf("Compile error: %v", err)
+			}
+			if got := pred(props); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_MissingPropertiesAreUnknown(t *testing.T) {
+	// Comparisons against a missing property are unknown, and so is their
+	// negation: neither the predicate nor NOT of it matches.
+	props := getter(map[string]any{"platform": "sentinel-1a"})
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"sat:orbit_state = 'ascending'", false},
+		{"NOT (sat:orbit_state = 'ascending')", false},
+		{"sat:orbit_state <> 'ascending'", false},
+		{"sat:orbit_state = 'ascending' OR platform = 'sentinel-1a'", true},
+		{"NOT (sat:orbit_state = 'ascending' AND platform = 'sentinel-1b')", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			expr, err := ParseText(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseText error: %v", err)
+			}
+			pred, err := Compile(expr)
+			if err != nil {
+				t.Fatalf("Compile error: %v", err)
+			}
+			if got := pred(props); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		expr        any
+		unsupported bool
+	}{
+		{
+			name: "not an expression",
+			expr: "platform",
+		},
+		{
+			name: "wrong arity",
+			expr: map[string]any{"op": "=", "args": []any{map[string]any{"property": "platform"}}},
+		},
+		{
+			name: "in without list",
+			expr: map[string]any{"op": "in", "args": []any{map[string]any{"property": "platform"}, "x"}},
+		},
+		{
+			name: "non-literal like pattern",
+			expr: map[string]any{"op": "like", "args": []any{map[string]any{"property": "id"}, map[string]any{"property": "x"}}},
+		},
+		{
+			name:        "unknown operator",
+			expr:        map[string]any{"op": "regex", "args": []any{map[string]any{"property": "id"}, "x"}},
+			unsupported: true,
+		},
+		{
+			name:        "unknown function as value",
+			expr:        map[string]any{"op": "=", "args": []any{map[string]any{"op": "upper", "args": []any{"x"}}, "X"}},
+			unsupported: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			var unsupported *UnsupportedError
+			if errors.As(err, &unsupported) != tt.unsupported {
+				t.Errorf("expected UnsupportedError=%v, got %T: %v", tt.unsupported, err, err)
+			}
+		})
+	}
+}
+
+func TestConjuncts(t *testing.T) {
+	expr, err := ParseText("a = 1 AND (b = 2 AND c = 3) AND (d = 4 OR e = 5)")
+	if err != nil {
+		t.Fatalf("ParseText error: %v", err)
+	}
+
+	conjuncts := Conjuncts(expr)
+	if len(conjuncts) != 4 {
+		t.Fatalf("expected 4 conjuncts, got %d: %v", len(conjuncts), conjuncts)
+	}
+	if name, _, _ := Op(conjuncts[3]); name != "or" {
+		t.Errorf("expected last conjunct to be the OR, got %v", conjuncts[3])
+	}
+
+	if And() != nil {
+		t.Error("expected And() to be nil")
+	}
+	if got := And(conjuncts[0]); got == nil {
+		t.Error("expected And of one expression to return it")
+	}
+}
+
+func TestCompile_Spatial(t *testing.T) {
+	// A footprint covering 0..10 in both directions, in each of the forms
+	// items carry geometries
+	footprints := map[string]any{
+		"decoded map": map[string]any{
+			"type":        "Polygon",
+			"coordinates": []any{[]any{[]any{0.0, 0.0}, []any{10.0, 0.0}, []any{10.0, 10.0}, []any{0.0, 10.0}, []any{0.0, 0.0}}},
+		},
+		"raw json": json.RawMessage(`{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}`),
+		"geometry": &geojson.Geometry{
+			Type:        "Polygon",
+			Coordinates: json.RawMessage(`[[[0,0],[10,0],[10,10],[0,10],[0,0]]]`),
+		},
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"S_INTERSECTS(geometry, POLYGON((5 5, 15 5, 15 15, 5 15, 5 5)))", true},
+		{"S_INTERSECTS(geometry, POINT(20 20))", false},
+		{"S_DISJOINT(geometry, POINT(20 20))", true},
+		{"S_CONTAINS(geometry, BBOX(1, 1, 9, 9))", true},
+		{"S_CONTAINS(geometry, BBOX(5, 5, 15, 15))", false},
+		{"S_WITHIN(geometry, BBOX(-1, -1, 11, 11))", true},
+		{"S_WITHIN(geometry, BBOX(1, 1, 9, 9))", false},
+		{"S_WITHIN(POINT(5 5), geometry)", true},
+		{"NOT S_INTERSECTS(geometry, LINESTRING(20 0, 20 10))", true},
+	}
+
+	for form, footprint := range footprints {
+		props := getter(map[string]any{"geometry": footprint})
+		for _, tt := range tests {
+			t.Run(form+"/"+tt.filter, func(t *testing.T) {
+				expr, err := ParseText(tt.filter)
+				if err != nil {
+					t.Fatalf("ParseText error: %v", err)
+				}
+				pred, err := Compile(expr)
+				if err != nil {
+					t.Fatalf("Compile error: %v", err)
+				}
+				if got := pred(props); got != tt.want {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+
+	// Items without a geometry never match, nor does the negation
+	expr, _ := ParseText("NOT S_INTERSECTS(geometry, POINT(20 20))")
+	pred, err := Compile(expr)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	if pred(getter(map[string]any{})) {
+		t.Error("expected item without geometry not to match")
+	}
+}
+
+func TestCompile_SpatialErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		expr        string
+		unsupported bool
+	}{
+		{name: "unsupported spatial function", expr: "S_TOUCHES(geometry, POINT(0 0))", unsupported: true},
+		{name: "geometry collection", expr: "S_INTERSECTS(geometry, GEOMETRYCOLLECTION(POINT(0 0)))"},
+		{name: "not a geometry", expr: "S_INTERSECTS(geometry, 'POINT(0 0)')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseText(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseText error: %v", err)
+			}
+			_, err = Compile(expr)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			var unsupported *UnsupportedError
+			if errors.As(err, &unsupported) != tt.unsupported {
+				t.Errorf("expected UnsupportedError=%v, got %T: %v", tt.unsupported, err, err)
+			}
+		})
+	}
+}
+
+func TestCompile_Temporal(t *testing.T) {
+	// An acquisition from 12:00:00 to 12:00:25 on 2023-06-01
+	scene := getter(map[string]any{
+		"datetime":       nil,
+		"start_datetime": time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+		"end_datetime":   "2023-06-01T12:00:25Z",
+	})
+	// An item with only an instant
+	instant := getter(map[string]any{"datetime": "2023-06-01T12:00:00Z"})
+
+	tests := []struct {
+		filter string
+		props  PropertyGetter
+		want   bool
+	}{
+		{"T_INTERSECTS(datetime, INTERVAL('2023-06-01T12:00:20Z', '2023-06-01T13:00:00Z'))", scene, true},
+		{"T_INTERSECTS(datetime, INTERVAL('2023-06-01T12:00:26Z', '..'))", scene, false},
+		{"T_INTERSECTS(datetime, TIMESTAMP('2023-06-01T12:00:25Z'))", scene, true},
+		{"T_INTERSECTS(datetime, DATE('2023-06-01'))", scene, true},
+		{"T_DURING(datetime, INTERVAL('2023-06-01', '2023-06-01'))", scene, true},
+		{"T_DURING(datetime, INTERVAL('2023-06-01T12:00:00Z', '2023-06-01T13:00:00Z'))", scene, false},
+		{"T_DURING(datetime, INTERVAL('..', '..'))", scene, true},
+		{"T_BEFORE(datetime, TIMESTAMP('2023-06-01T12:00:26Z'))", scene, true},
+		{"T_BEFORE(datetime, TIMESTAMP('2023-06-01T12:00:25Z'))", scene, false},
+		{"T_AFTER(datetime, DATE('2023-05-31'))", scene, true},
+		{"T_AFTER(datetime, TIMESTAMP('2023-06-01T12:00:00Z'))", scene, false},
+		{"T_CONTAINS(datetime, TIMESTAMP('2023-06-01T12:00:10Z'))", scene, true},
+		{"T_DISJOINT(datetime, INTERVAL('2023-01-01', '2023-05-31'))", scene, true},
+		{"T_EQUALS(datetime, INTERVAL('2023-06-01T12:00:00Z', '2023-06-01T12:00:25Z'))", scene, true},
+		{"T_STARTS(datetime, INTERVAL('2023-06-01T12:00:00Z', '2023-06-01T13:00:00Z'))", scene, true},
+		{"T_STARTEDBY(datetime, TIMESTAMP('2023-06-01T12:00:00Z'))", scene, true},
+		{"T_FINISHES(datetime, INTERVAL('2023-06-01T11:00:00Z', '2023-06-01T12:00:25Z'))", scene, true},
+		{"T_FINISHEDBY(datetime, TIMESTAMP('2023-06-01T12:00:25Z'))", scene, true},
+		{"T_MEETS(datetime, INTERVAL('2023-06-01T12:00:25Z', '..'))", scene, true},
+		{"T_METBY(datetime, INTERVAL('..', '2023-06-01T12:00:00Z'))", scene, true},
+		{"T_OVERLAPS(datetime, INTERVAL('2023-06-01T12:00:10Z', '2023-06-01T13:00:00Z'))", scene, true},
+		{"T_OVERLAPPEDBY(datetime, INTERVAL('2023-06-01T11:00:00Z', '2023-06-01T12:00:10Z'))", scene, true},
+		{"T_OVERLAPS(datetime, INTERVAL('2023-06-01T11:00:00Z', '2023-06-01T12:00:10Z'))", scene, false},
+
+		// Operands in either order, and intervals built from properties
+		{"T_AFTER(TIMESTAMP('2023-06-02T00:00:00Z'), datetime)", scene, true},
+		{"T_DURING(INTERVAL(start_datetime, end_datetime), INTERVAL('2023-06-01', '2023-06-02'))", scene, true},
+		{"T_BEFORE(start_datetime, end_datetime)", scene, true},
+
+		// Items without a period use their datetime instant
+		{"T_DURING(datetime, INTERVAL('2023-06-01', '2023-06-01'))", instant, true},
+		{"T_AFTER(datetime, TIMESTAMP('2023-06-01T12:00:00Z'))", instant, false},
+		{"NOT T_AFTER(datetime, TIMESTAMP('2023-06-01T12:00:00Z'))", getter(map[string]any{}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			expr, err := ParseText(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseText error: %v", err)
+			}
+			pred, err := Compile(expr)
+			if err != nil {
+				t.Fatalf("Compile error: %v", err)
+			}
+			if got := pred(tt.props); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemporalLiteral(t *testing.T) {
+	day := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr      string
+		wantStart *time.Time
+		wantEnd   *time.Time
+		wantErr   bool
+	}{
+		{expr: `{"timestamp":"2023-06-01T00:00:00Z"}`, wantStart: &day, wantEnd: &day},
+		{expr: `{"interval":["2023-06-01T00:00:00Z",".."]}`, wantStart: &day},
+		{expr: `{"interval":["..","2023-05-31"]}`, wantEnd: func() *time.Time { t := day.Add(-time.Nanosecond); return &t }()},
+		{expr: `{"interval":[{"property":"start_datetime"},".."]}`, wantErr: true},
+		{expr: `{"property":"datetime"}`, wantErr: true},
+		{expr: `{"timestamp":"yesterday"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			var expr any
+			if err := json.Unmarshal([]byte(tt.expr), &expr); err != nil {
+				t.Fatal(err)
+			}
+			start, end, err := TemporalLiteral(expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TemporalLiteral() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !timePtrEqual(start, tt.wantStart) || !timePtrEqual(end, tt.wantEnd) {
+				t.Errorf("TemporalLiteral() = %v, %v, want %v, %v", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func timePtrEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
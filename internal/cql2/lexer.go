@@ -0,0 +1,195 @@
+package cql2
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind identifies the lexical class of a token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenOperator
+)
+
+// token is a single lexical unit of a CQL2-Text expression
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 1-based character offset of the token start
+}
+
+// lexer splits CQL2-Text input into tokens
+type lexer struct {
+	input string
+	pos   int // current byte offset
+}
+
+// tokenize returns all tokens in the input, terminated by a tokenEOF token.
+func tokenize(input string) ([]token, error) {
+	l := &lexer{input: input}
+	var tokens []token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+// charPos converts a byte offset to a 1-based character position for error messages
+func (l *lexer) charPos(offset int) int {
+	return utf8.RuneCountInString(l.input[:offset]) + 1
+}
+
+func (l *lexer) next() (token, error) {
+	// Skip whitespace
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return token{kind: tokenEOF, pos: l.charPos(start)}, nil
+	}
+
+	r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+	switch {
+	case r == '(':
+		l.pos += size
+		return token{kind: tokenLParen, text: "(", pos: l.charPos(start)}, nil
+	case r == ')':
+		l.pos += size
+		return token{kind: tokenRParen, text: ")", pos: l.charPos(start)}, nil
+	case r == ',':
+		l.pos += size
+		return token{kind: tokenComma, text: ",", pos: l.charPos(start)}, nil
+	case r == '\'':
+		return l.quoted('\'', tokenString)
+	case r == '"':
+		return l.quoted('"', tokenQuotedIdent)
+	case r == '=' || r == '+' || r == '-' || r == '*' || r == '/':
+		l.pos += size
+		return token{kind: tokenOperator, text: string(r), pos: l.charPos(start)}, nil
+	case r == '<' || r == '>':
+		l.pos += size
+		if l.pos < len(l.input) {
+			next := l.input[l.pos]
+			if next == '=' || (r == '<' && next == '>') {
+				l.pos++
+			}
+		}
+		return token{kind: tokenOperator, text: l.input[start:l.pos], pos: l.charPos(start)}, nil
+	case r == '.' || (r >= '0' && r <= '9'):
+		return l.number()
+	case isIdentStart(r):
+		for l.pos < len(l.input) {
+			r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+			if !isIdentPart(r) {
+				break
+			}
+			l.pos += size
+		}
+		return token{kind: tokenIdent, text: l.input[start:l.pos], pos: l.charPos(start)}, nil
+	default:
+		return token{}, &SyntaxError{Pos: l.charPos(start), Msg: "unexpected character " + strconv.QuoteRune(r)}
+	}
+}
+
+// quoted scans a quoted string or identifier. A doubled quote character
+// inside the literal represents a single literal quote.
+func (l *lexer) quoted(quote byte, kind tokenKind) (token, error) {
+	start := l.pos
+	l.pos++ // opening quote
+
+	var sb strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		if c == quote {
+			if l.pos+1 < len(l.input) && l.input[l.pos+1] == quote {
+				sb.WriteByte(quote)
+				l.pos += 2
+				continue
+			}
+			l.pos++
+			return token{kind: kind, text: sb.String(), pos: l.charPos(start)}, nil
+		}
+		sb.WriteByte(c)
+		l.pos++
+	}
+
+	what := "string literal"
+	if kind == tokenQuotedIdent {
+		what = "quoted identifier"
+	}
+	return token{}, &SyntaxError{Pos: l.charPos(start), Msg: "unterminated " + what}
+}
+
+// number scans an unsigned numeric literal with optional fraction and exponent.
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	digits := 0
+	for l.pos < len(l.input) && isDigit(l.input[l.pos]) {
+		l.pos++
+		digits++
+	}
+	if l.pos < len(l.input) && l.input[l.pos] == '.' {
+		l.pos++
+		for l.pos < len(l.input) && isDigit(l.input[l.pos]) {
+			l.pos++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return token{}, &SyntaxError{Pos: l.charPos(start), Msg: "invalid number"}
+	}
+	if l.pos < len(l.input) && (l.input[l.pos] == 'e' || l.input[l.pos] == 'E') {
+		l.pos++
+		if l.pos < len(l.input) && (l.input[l.pos] == '+' || l.input[l.pos] == '-') {
+			l.pos++
+		}
+		expDigits := 0
+		for l.pos < len(l.input) && isDigit(l.input[l.pos]) {
+			l.pos++
+			expDigits++
+		}
+		if expDigits == 0 {
+			return token{}, &SyntaxError{Pos: l.charPos(start), Msg: "invalid number exponent"}
+		}
+	}
+	return token{kind: tokenNumber, text: l.input[start:l.pos], pos: l.charPos(start)}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// isIdentPart reports whether r may appear after the first character of an
+// identifier. Colons and dots are allowed so that extension properties such
+// as sar:instrument_mode and nested names such as assets.data can be written
+// without quoting.
+func isIdentPart(r rune) bool {
+	return r == '_' || r == ':' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,140 @@
+package cql2
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/robert-malhotra/asf-stac-proxy/pkg/geojson"
+)
+
+// geometryExpr evaluates to a geometry, or ok=false when it is unknown
+type geometryExpr func(get PropertyGetter) (*geojson.Geometry, bool)
+
+// spatialOps maps the supported CQL2 spatial functions to their predicates
+var spatialOps = map[string]func(a, b *geojson.Geometry) (bool, error){
+	"s_intersects": geojson.Intersects,
+	"s_disjoint":   geojson.Disjoint,
+	"s_within":     geojson.Within,
+	"s_contains":   geojson.Contains,
+}
+
+// compileSpatial compiles a spatial function. Geometries that fail to decode
+// make the result unknown rather than failing the whole search.
+func compileSpatial(name string, args []any) (boolExpr, error) {
+	predicate, ok := spatialOps[name]
+	if !ok {
+		return nil, &UnsupportedError{Op: name}
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%q requires exactly 2 arguments", name)
+	}
+	left, err := compileGeometry(args[0])
+	if err != nil {
+		return nil, err
+	}
+	right, err := compileGeometry(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return func(get PropertyGetter) truth {
+		a, aok := left(get)
+		b, bok := right(get)
+		if !aok || !bok {
+			return truthUnknown
+		}
+		result, err := predicate(a, b)
+		if err != nil {
+			return truthUnknown
+		}
+		return truthOf(result)
+	}, nil
+}
+
+func compileGeometry(expr any) (geometryExpr, error) {
+	if name, ok := PropertyName(expr); ok {
+		return func(get PropertyGetter) (*geojson.Geometry, bool) {
+			value, ok := get(name)
+			if !ok {
+				return nil, false
+			}
+			return toGeometry(value)
+		}, nil
+	}
+
+	g, err := GeometryLiteral(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(PropertyGetter) (*geojson.Geometry, bool) { return g, true }, nil
+}
+
+// GeometryLiteral converts a CQL2-JSON geometry literal, either a GeoJSON
+// geometry or a {"bbox": [...]} object, to a Geometry.
+func GeometryLiteral(expr any) (*geojson.Geometry, error) {
+	m, ok := expr.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a geometry, got %s", describe(expr))
+	}
+
+	if raw, ok := m["bbox"]; ok {
+		values, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid bbox %s", describe(raw))
+		}
+		bbox := make([]float64, 0, len(values))
+		for _, v := range values {
+			f, ok := toFloat(v)
+			if !ok {
+				return nil, fmt.Errorf("invalid bbox %s", describe(raw))
+			}
+			bbox = append(bbox, f)
+		}
+		// A 3D bbox is [west, south, min z, east, north, max z]
+		if len(bbox) == 6 {
+			bbox = []float64{bbox[0], bbox[1], bbox[3], bbox[4]}
+		}
+		return geojson.NewPolygonFromBBox(bbox)
+	}
+
+	if _, ok := m["type"].(string); !ok {
+		return nil, fmt.Errorf("expected a geometry, got %s", describe(expr))
+	}
+	g, ok := toGeometry(m)
+	if !ok {
+		return nil, fmt.Errorf("invalid geometry %s", describe(expr))
+	}
+	if _, err := geojson.ComputeBBox(g); err != nil {
+		return nil, fmt.Errorf("invalid geometry: %w", err)
+	}
+	return g, nil
+}
+
+// toGeometry converts the geometry representations found on items - decoded
+// GeoJSON, raw JSON, or a Geometry - to a Geometry
+func toGeometry(v any) (*geojson.Geometry, bool) {
+	switch g := v.(type) {
+	case *geojson.Geometry:
+		return g, g != nil
+	case geojson.Geometry:
+		return &g, true
+	case json.RawMessage:
+		return unmarshalGeometry(g)
+	case []byte:
+		return unmarshalGeometry(g)
+	case map[string]any:
+		data, err := json.Marshal(g)
+		if err != nil {
+			return nil, false
+		}
+		return unmarshalGeometry(data)
+	}
+	return nil, false
+}
+
+func unmarshalGeometry(data []byte) (*geojson.Geometry, bool) {
+	var g geojson.Geometry
+	if err := json.Unmarshal(data, &g); err != nil || g.Type == "" {
+		return nil, false
+	}
+	return &g, true
+}
@@ -0,0 +1,319 @@
+package cql2
+
+import (
+	"fmt"
+	"time"
+)
+
+// bound is one end of a period. inf is -1 or +1 for an open start or end,
+// in which case t is unused.
+type bound struct {
+	t   time.Time
+	inf int
+}
+
+func (b bound) compare(other bound) int {
+	if b.inf != 0 || other.inf != 0 {
+		switch {
+		case b.inf < other.inf:
+			return -1
+		case b.inf > other.inf:
+			return 1
+		}
+		return 0
+	}
+	return b.t.Compare(other.t)
+}
+
+// period is a closed time interval; an instant has equal start and end
+type period struct {
+	start, end bound
+}
+
+// periodExpr evaluates to a period, or ok=false when it is unknown
+type periodExpr func(get PropertyGetter) (period, bool)
+
+// temporalOps implements the CQL2 temporal functions as relations between the
+// periods a and b, following the definitions in the CQL2 specification.
+// Names are lower-cased as returned by Op.
+var temporalOps = map[string]func(a, b period) bool{
+	"t_after":  func(a, b period) bool { return a.start.compare(b.end) > 0 },
+	"t_before": func(a, b period) bool { return a.end.compare(b.start) < 0 },
+	"t_contains": func(a, b period) bool {
+		return a.start.compare(b.start) < 0 && a.end.compare(b.end) > 0
+	},
+	"t_disjoint": func(a, b period) bool {
+		return a.end.compare(b.start) < 0 || a.start.compare(b.end) > 0
+	},
+	"t_during": func(a, b period) bool {
+		return a.start.compare(b.start) > 0 && a.end.compare(b.end) < 0
+	},
+	"t_equals": func(a, b period) bool {
+		return a.start.compare(b.start) == 0 && a.end.compare(b.end) == 0
+	},
+	"t_finishedby": func(a, b period) bool {
+		return a.start.compare(b.start) < 0 && a.end.compare(b.end) == 0
+	},
+	"t_finishes": func(a, b period) bool {
+		return a.start.compare(b.start) > 0 && a.end.compare(b.end) == 0
+	},
+	"t_intersects": func(a, b period) bool {
+		return a.start.compare(b.end) <= 0 && a.end.compare(b.start) >= 0
+	},
+	"t_meets": func(a, b period) bool { return a.end.compare(b.start) == 0 },
+	"t_metby": func(a, b period) bool { return a.start.compare(b.end) == 0 },
+	"t_overlappedby": func(a, b period) bool {
+		return a.start.compare(b.start) > 0 && a.start.compare(b.end) < 0 && a.end.compare(b.end) > 0
+	},
+	"t_overlaps": func(a, b period) bool {
+		return a.start.compare(b.start) < 0 && a.end.compare(b.start) > 0 && a.end.compare(b.end) < 0
+	},
+	"t_startedby": func(a, b period) bool {
+		return a.start.compare(b.start) == 0 && a.end.compare(b.end) > 0
+	},
+	"t_starts": func(a, b period) bool {
+		return a.start.compare(b.start) == 0 && a.end.compare(b.end) < 0
+	},
+}
+
+// IsTemporalOp reports whether name, as returned by Op, is a CQL2 temporal
+// function.
+func IsTemporalOp(name string) bool {
+	_, ok := temporalOps[name]
+	return ok
+}
+
+// ConverseTemporalOp returns the temporal function that holds for (b, a)
+// exactly when name holds for (a, b).
+func ConverseTemporalOp(name string) string {
+	switch name {
+	case "t_after":
+		return "t_before"
+	case "t_before":
+		return "t_after"
+	case "t_contains":
+		return "t_during"
+	case "t_during":
+		return "t_contains"
+	case "t_finishedby":
+		return "t_finishes"
+	case "t_finishes":
+		return "t_finishedby"
+	case "t_meets":
+		return "t_metby"
+	case "t_metby":
+		return "t_meets"
+	case "t_overlappedby":
+		return "t_overlaps"
+	case "t_overlaps":
+		return "t_overlappedby"
+	case "t_startedby":
+		return "t_starts"
+	case "t_starts":
+		return "t_startedby"
+	}
+	return name
+}
+
+func compileTemporal(name string, args []any) (boolExpr, error) {
+	relation, ok := temporalOps[name]
+	if !ok {
+		return nil, &UnsupportedError{Op: name}
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%q requires exactly 2 arguments", name)
+	}
+	left, err := compilePeriod(args[0])
+	if err != nil {
+		return nil, err
+	}
+	right, err := compilePeriod(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return func(get PropertyGetter) truth {
+		a, aok := left(get)
+		b, bok := right(get)
+		if !aok || !bok {
+			return truthUnknown
+		}
+		return truthOf(relation(a, b))
+	}, nil
+}
+
+// compilePeriod compiles an operand of a temporal function: a property, a
+// TIMESTAMP or DATE literal, or an INTERVAL. The datetime property refers to
+// the item's acquisition period, start_datetime to end_datetime, when the
+// item has one, and to its datetime instant otherwise.
+func compilePeriod(expr any) (periodExpr, error) {
+	if name, ok := PropertyName(expr); ok {
+		if name == "datetime" {
+			return func(get PropertyGetter) (period, bool) {
+				start, sok := propertyInstant(get, "start_datetime")
+				end, eok := propertyInstant(get, "end_datetime")
+				if sok && eok {
+					return period{start: start, end: end}, true
+				}
+				instant, ok := propertyInstant(get, "datetime")
+				return period{start: instant, end: instant}, ok
+			}, nil
+		}
+		return func(get PropertyGetter) (period, bool) {
+			instant, ok := propertyInstant(get, name)
+			return period{start: instant, end: instant}, ok
+		}, nil
+	}
+
+	m, ok := expr.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a temporal value, got %s", describe(expr))
+	}
+
+	if raw, ok := m["interval"]; ok {
+		bounds, ok := raw.([]any)
+		if !ok || len(bounds) != 2 {
+			return nil, fmt.Errorf("interval must have exactly 2 bounds")
+		}
+		start, err := compileBound(bounds[0], false)
+		if err != nil {
+			return nil, err
+		}
+		end, err := compileBound(bounds[1], true)
+		if err != nil {
+			return nil, err
+		}
+		return func(get PropertyGetter) (period, bool) {
+			s, sok := start(get)
+			e, eok := end(get)
+			return period{start: s, end: e}, sok && eok
+		}, nil
+	}
+
+	p, err := literalPeriod(m)
+	if err != nil {
+		return nil, err
+	}
+	return func(PropertyGetter) (period, bool) { return p, true }, nil
+}
+
+// compileBound compiles one bound of an interval: an instant string, '..', a
+// TIMESTAMP or DATE literal, or a property
+func compileBound(expr any, isEnd bool) (func(get PropertyGetter) (bound, bool), error) {
+	if name, ok := PropertyName(expr); ok {
+		return func(get PropertyGetter) (bound, bool) {
+			return propertyInstant(get, name)
+		}, nil
+	}
+
+	var b bound
+	switch v := expr.(type) {
+	case string:
+		if v == ".." {
+			b = bound{inf: -1}
+			if isEnd {
+				b.inf = 1
+			}
+			break
+		}
+		p, err := parseInstant(v)
+		if err != nil {
+			return nil, err
+		}
+		b = p.start
+		if isEnd {
+			b = p.end
+		}
+	case map[string]any:
+		p, err := literalPeriod(v)
+		if err != nil {
+			return nil, err
+		}
+		b = p.start
+		if isEnd {
+			b = p.end
+		}
+	default:
+		return nil, fmt.Errorf("invalid interval bound %s", describe(expr))
+	}
+	return func(PropertyGetter) (bound, bool) { return b, true }, nil
+}
+
+// literalPeriod converts a {"timestamp": ...} or {"date": ...} literal
+func literalPeriod(m map[string]any) (period, error) {
+	if ts, ok := m["timestamp"].(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return period{}, fmt.Errorf("invalid timestamp %q", ts)
+		}
+		return period{start: bound{t: t}, end: bound{t: t}}, nil
+	}
+	if d, ok := m["date"].(string); ok {
+		return parseDate(d)
+	}
+	if name, _, ok := Op(m); ok {
+		return period{}, &UnsupportedError{Op: name}
+	}
+	return period{}, fmt.Errorf("expected a temporal value, got %s", describe(m))
+}
+
+// parseInstant parses an interval bound written as a plain string, which is
+// either a timestamp or a date
+func parseInstant(s string) (period, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return period{start: bound{t: t}, end: bound{t: t}}, nil
+	}
+	return parseDate(s)
+}
+
+// parseDate returns a date as the period covering the whole UTC day, so an
+// interval ending on a date includes items acquired on that day
+func parseDate(s string) (period, error) {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return period{}, fmt.Errorf("invalid date %q", s)
+	}
+	return period{
+		start: bound{t: t},
+		end:   bound{t: t.AddDate(0, 0, 1).Add(-time.Nanosecond)},
+	}, nil
+}
+
+// propertyInstant reads a property holding an instant, as a time value or an
+// RFC 3339 string
+func propertyInstant(get PropertyGetter, name string) (bound, bool) {
+	value, ok := get(name)
+	if !ok {
+		return bound{}, false
+	}
+	t, ok := toTime(value)
+	if !ok {
+		return bound{}, false
+	}
+	return bound{t: t}, true
+}
+
+// TemporalLiteral returns the bounds of a TIMESTAMP, DATE or INTERVAL literal.
+// Open bounds are returned as nil. Intervals with property bounds are not
+// literals and return an error.
+func TemporalLiteral(expr any) (start, end *time.Time, err error) {
+	if _, ok := PropertyName(expr); ok {
+		return nil, nil, fmt.Errorf("expected a temporal literal, got %s", describe(expr))
+	}
+	compiled, err := compilePeriod(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, ok := compiled(func(string) (any, bool) { return nil, false })
+	if !ok {
+		return nil, nil, fmt.Errorf("expected a temporal literal, got %s", describe(expr))
+	}
+	if p.start.inf == 0 {
+		t := p.start.t
+		start = &t
+	}
+	if p.end.inf == 0 {
+		t := p.end.t
+		end = &t
+	}
+	return start, end, nil
+}
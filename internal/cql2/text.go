@@ -0,0 +1,739 @@
+// Package cql2 implements parsing and evaluation of OGC CQL2 filter expressions.
+//
+// CQL2-Text input is converted to the equivalent CQL2-JSON document so that the
+// rest of the proxy only ever deals with a single filter encoding. The output of
+// ParseText can be marshaled and handed to any CQL2-JSON consumer, and yields the
+// same expression tree as the JSON form of the filter. Compile turns that tree
+// into a Predicate for the parts of a filter the upstream APIs cannot apply.
+package cql2
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter language identifiers accepted by the filter-lang parameter.
+const (
+	LangJSON = "cql2-json"
+	LangText = "cql2-text"
+)
+
+// maxDepth bounds expression nesting so hostile input cannot exhaust the stack.
+const maxDepth = 64
+
+// SyntaxError describes a malformed CQL2-Text expression.
+type SyntaxError struct {
+	Pos int    // 1-based character position of the offending token
+	Msg string // human-readable description
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// standardFunctions are the CQL2 operators written with function-call syntax.
+// Their names are keywords in CQL2-Text and therefore case-insensitive; they
+// are normalized to the lower-case form used by CQL2-JSON.
+var standardFunctions = map[string]bool{
+	"s_intersects": true, "s_equals": true, "s_disjoint": true, "s_touches": true,
+	"s_within": true, "s_overlaps": true, "s_crosses": true, "s_contains": true,
+	"t_after": true, "t_before": true, "t_contains": true, "t_disjoint": true,
+	"t_during": true, "t_equals": true, "t_finishedby": true, "t_finishes": true,
+	"t_intersects": true, "t_meets": true, "t_metby": true, "t_overlappedby": true,
+	"t_overlaps": true, "t_startedby": true, "t_starts": true,
+	"a_equals": true, "a_contains": true, "a_containedby": true, "a_overlaps": true,
+	"casei": true, "accenti": true,
+}
+
+// ParseText parses a CQL2-Text expression and returns its CQL2-JSON equivalent.
+// The result is a map[string]any for predicates, or a bool for the literals
+// TRUE and FALSE. Malformed input yields a *SyntaxError.
+func ParseText(text string) (any, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: "empty filter expression"}
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.unexpected(tok, "end of expression")
+	}
+	return expr, nil
+}
+
+// parser is a recursive-descent parser over a token stream
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isKeyword reports whether tok is the given (upper-case) keyword
+func isKeyword(tok token, keyword string) bool {
+	return tok.kind == tokenIdent && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	if isKeyword(p.peek(), keyword) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if tok := p.peek(); !isKeyword(tok, keyword) {
+		return p.unexpected(tok, keyword)
+	}
+	p.advance()
+	return nil
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.peek()
+	if tok.kind != kind {
+		return tok, p.unexpected(tok, what)
+	}
+	return p.advance(), nil
+}
+
+func (p *parser) unexpected(tok token, expected string) error {
+	if tok.kind == tokenEOF {
+		return &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected end of expression, expected %s", expected)}
+	}
+	text := tok.text
+	switch tok.kind {
+	case tokenString:
+		text = "'" + text + "'"
+	case tokenQuotedIdent:
+		text = `"` + text + `"`
+	}
+	return &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s, expected %s", text, expected)}
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return &SyntaxError{Pos: p.peek().pos, Msg: fmt.Sprintf("expression nesting exceeds %d levels", maxDepth)}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// parseOr parses: andExpr { OR andExpr }
+func (p *parser) parseOr() (any, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	args := []any{first}
+	for p.acceptKeyword("OR") {
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, next)
+	}
+	if len(args) == 1 {
+		return first, nil
+	}
+	return op("or", args...), nil
+}
+
+// parseAnd parses: notExpr { AND notExpr }
+func (p *parser) parseAnd() (any, error) {
+	first, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	args := []any{first}
+	for p.acceptKeyword("AND") {
+		next, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, next)
+	}
+	if len(args) == 1 {
+		return first, nil
+	}
+	return op("and", args...), nil
+}
+
+// parseNot parses: [ NOT ] primary
+func (p *parser) parseNot() (any, error) {
+	if p.acceptKeyword("NOT") {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return op("not", inner), nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a parenthesized boolean expression or a predicate.
+func (p *parser) parsePrimary() (any, error) {
+	if p.peek().kind == tokenLParen {
+		p.advance()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return p.parsePredicate()
+}
+
+// parsePredicate parses a comparison, LIKE, BETWEEN, IN or IS NULL predicate,
+// or a standalone boolean-valued function call or literal.
+func (p *parser) parsePredicate() (any, error) {
+	left, err := p.parseScalar()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+
+	if tok.kind == tokenOperator {
+		switch tok.text {
+		case "=", "<>", "<", "<=", ">", ">=":
+			p.advance()
+			right, err := p.parseScalar()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.rejectArithmetic(); err != nil {
+				return nil, err
+			}
+			return op(tok.text, left, right), nil
+		}
+		if err := p.rejectArithmetic(); err != nil {
+			return nil, err
+		}
+	}
+
+	negate := false
+	if isKeyword(tok, "NOT") {
+		next := p.peekAt(1)
+		if isKeyword(next, "LIKE") || isKeyword(next, "BETWEEN") || isKeyword(next, "IN") {
+			p.advance()
+			negate = true
+			tok = p.peek()
+		}
+	}
+
+	var pred any
+	switch {
+	case isKeyword(tok, "LIKE"):
+		p.advance()
+		pattern, err := p.parseScalar()
+		if err != nil {
+			return nil, err
+		}
+		pred = op("like", left, pattern)
+
+	case isKeyword(tok, "BETWEEN"):
+		p.advance()
+		low, err := p.parseScalar()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseScalar()
+		if err != nil {
+			return nil, err
+		}
+		pred = op("between", left, low, high)
+
+	case isKeyword(tok, "IN"):
+		p.advance()
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		pred = op("in", left, list)
+
+	case isKeyword(tok, "IS"):
+		p.advance()
+		isNot := p.acceptKeyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		pred = op("isNull", left)
+		if isNot {
+			pred = op("not", pred)
+		}
+
+	default:
+		// Function calls (s_intersects, t_before, ...) and boolean literals
+		// are predicates in their own right.
+		if isBooleanValued(left) {
+			return left, nil
+		}
+		return nil, p.unexpected(tok, "comparison operator, LIKE, BETWEEN, IN or IS NULL")
+	}
+
+	if negate {
+		pred = op("not", pred)
+	}
+	return pred, nil
+}
+
+// rejectArithmetic reports a clear error when an arithmetic operator follows
+// an operand, instead of a generic unexpected-token message.
+func (p *parser) rejectArithmetic() error {
+	tok := p.peek()
+	if tok.kind == tokenOperator {
+		switch tok.text {
+		case "+", "-", "*", "/":
+			return &SyntaxError{Pos: tok.pos, Msg: "arithmetic expressions are not supported"}
+		}
+	}
+	return nil
+}
+
+// isBooleanValued reports whether a scalar can stand alone as a predicate
+func isBooleanValued(v any) bool {
+	switch val := v.(type) {
+	case bool:
+		return true
+	case map[string]any:
+		_, isOp := val["op"]
+		return isOp
+	}
+	return false
+}
+
+// parseList parses a parenthesized, comma-separated list of scalars, as used
+// by IN lists, array literals and function arguments.
+func (p *parser) parseList() ([]any, error) {
+	if _, err := p.expect(tokenLParen, "'('"); err != nil {
+		return nil, err
+	}
+	list := []any{}
+	if p.peek().kind == tokenRParen {
+		p.advance()
+		return list, nil
+	}
+	for {
+		item, err := p.parseScalar()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+		if p.peek().kind == tokenComma {
+			p.advance()
+			continue
+		}
+		if _, err := p.expect(tokenRParen, "',' or ')'"); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+}
+
+// parseScalar parses a literal, property reference, function call or
+// geometry literal.
+func (p *parser) parseScalar() (any, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	tok := p.peek()
+	switch tok.kind {
+	case tokenString:
+		p.advance()
+		return tok.text, nil
+
+	case tokenNumber:
+		p.advance()
+		return parseNumber(tok)
+
+	case tokenOperator:
+		if tok.text == "-" || tok.text == "+" {
+			p.advance()
+			numTok, err := p.expect(tokenNumber, "number")
+			if err != nil {
+				return nil, err
+			}
+			n, err := parseNumber(numTok)
+			if err != nil {
+				return nil, err
+			}
+			if tok.text == "-" {
+				return -n, nil
+			}
+			return n, nil
+		}
+
+	case tokenQuotedIdent:
+		p.advance()
+		return map[string]any{"property": tok.text}, nil
+
+	case tokenLParen:
+		// Array literal, e.g. the second argument of a_contains
+		return p.parseList()
+
+	case tokenIdent:
+		return p.parseIdentScalar()
+	}
+
+	return nil, p.unexpected(tok, "value or property name")
+}
+
+func parseNumber(tok token) (float64, error) {
+	n, err := strconv.ParseFloat(tok.text, 64)
+	if err != nil {
+		return 0, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("invalid number %q", tok.text)}
+	}
+	return n, nil
+}
+
+// parseIdentScalar parses scalars that begin with an identifier: boolean
+// literals, temporal and geometry literals, function calls and property names.
+func (p *parser) parseIdentScalar() (any, error) {
+	tok := p.advance()
+	upper := strings.ToUpper(tok.text)
+	followedByParen := p.peek().kind == tokenLParen
+
+	switch upper {
+	case "TRUE":
+		return true, nil
+	case "FALSE":
+		return false, nil
+	}
+
+	if !followedByParen {
+		// Reserved words cannot be used as bare property names
+		switch upper {
+		case "AND", "OR", "NOT", "LIKE", "BETWEEN", "IN", "IS", "NULL":
+			return nil, p.unexpected(tok, "value or property name")
+		}
+		return map[string]any{"property": tok.text}, nil
+	}
+
+	switch upper {
+	case "TIMESTAMP", "DATE":
+		value, err := p.parseInstantArg()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{strings.ToLower(upper): value}, nil
+
+	case "INTERVAL":
+		return p.parseInterval()
+
+	case "BBOX":
+		return p.parseBBox()
+
+	case "POINT", "LINESTRING", "POLYGON", "MULTIPOINT", "MULTILINESTRING", "MULTIPOLYGON", "GEOMETRYCOLLECTION":
+		return p.parseGeometryBody(upper)
+	}
+
+	args, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	name := tok.text
+	if lower := strings.ToLower(name); standardFunctions[lower] {
+		name = lower
+	}
+	return op(name, args...), nil
+}
+
+// parseInstantArg parses the single string argument of TIMESTAMP(...) or DATE(...)
+func (p *parser) parseInstantArg() (string, error) {
+	if _, err := p.expect(tokenLParen, "'('"); err != nil {
+		return "", err
+	}
+	value, err := p.expect(tokenString, "quoted date or timestamp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := p.expect(tokenRParen, "')'"); err != nil {
+		return "", err
+	}
+	return value.text, nil
+}
+
+// parseInterval parses INTERVAL(start, end). Bounds may be quoted instants,
+// '..' for an open end, TIMESTAMP/DATE literals or property references.
+func (p *parser) parseInterval() (any, error) {
+	open, err := p.expect(tokenLParen, "'('")
+	if err != nil {
+		return nil, err
+	}
+	bounds := make([]any, 0, 2)
+	for len(bounds) < 2 {
+		if len(bounds) == 1 {
+			if _, err := p.expect(tokenComma, "','"); err != nil {
+				return nil, err
+			}
+		}
+		bound, err := p.parseScalar()
+		if err != nil {
+			return nil, err
+		}
+		// Instant literals are written as plain strings inside an interval
+		if m, ok := bound.(map[string]any); ok {
+			if ts, ok := m["timestamp"]; ok {
+				bound = ts
+			} else if d, ok := m["date"]; ok {
+				bound = d
+			}
+		}
+		switch bound.(type) {
+		case string, map[string]any:
+		default:
+			return nil, &SyntaxError{Pos: open.pos, Msg: "interval bounds must be instants, '..' or properties"}
+		}
+		bounds = append(bounds, bound)
+	}
+	if _, err := p.expect(tokenRParen, "')'"); err != nil {
+		return nil, err
+	}
+	return map[string]any{"interval": bounds}, nil
+}
+
+// parseBBox parses BBOX(minx, miny, maxx, maxy) or its 3D form
+func (p *parser) parseBBox() (any, error) {
+	open := p.peek()
+	args, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 4 && len(args) != 6 {
+		return nil, &SyntaxError{Pos: open.pos, Msg: fmt.Sprintf("BBOX requires 4 or 6 coordinates, got %d", len(args))}
+	}
+	bbox := make([]any, len(args))
+	for i, arg := range args {
+		n, ok := arg.(float64)
+		if !ok {
+			return nil, &SyntaxError{Pos: open.pos, Msg: "BBOX coordinates must be numbers"}
+		}
+		bbox[i] = n
+	}
+	return map[string]any{"bbox": bbox}, nil
+}
+
+// parseGeometryBody parses the parenthesized body of a WKT geometry literal
+// and returns it as a GeoJSON geometry object.
+func (p *parser) parseGeometryBody(kind string) (any, error) {
+	switch kind {
+	case "POINT":
+		if _, err := p.expect(tokenLParen, "'('"); err != nil {
+			return nil, err
+		}
+		pos, err := p.parsePosition()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return geometry("Point", pos), nil
+
+	case "LINESTRING":
+		line, err := p.parsePositionList()
+		if err != nil {
+			return nil, err
+		}
+		return geometry("LineString", line), nil
+
+	case "POLYGON":
+		rings, err := p.parseRings()
+		if err != nil {
+			return nil, err
+		}
+		return geometry("Polygon", rings), nil
+
+	case "MULTIPOINT":
+		points, err := p.parseMultiPoint()
+		if err != nil {
+			return nil, err
+		}
+		return geometry("MultiPoint", points), nil
+
+	case "MULTILINESTRING":
+		lines, err := p.parseRings()
+		if err != nil {
+			return nil, err
+		}
+		return geometry("MultiLineString", lines), nil
+
+	case "MULTIPOLYGON":
+		polygons, err := p.parseNested(func() (any, error) { return p.parseRings() })
+		if err != nil {
+			return nil, err
+		}
+		return geometry("MultiPolygon", polygons), nil
+
+	case "GEOMETRYCOLLECTION":
+		if _, err := p.expect(tokenLParen, "'('"); err != nil {
+			return nil, err
+		}
+		var geoms []any
+		for {
+			tok, err := p.expect(tokenIdent, "geometry type")
+			if err != nil {
+				return nil, err
+			}
+			upper := strings.ToUpper(tok.text)
+			switch upper {
+			case "POINT", "LINESTRING", "POLYGON", "MULTIPOINT", "MULTILINESTRING", "MULTIPOLYGON":
+			default:
+				return nil, p.unexpected(tok, "geometry type")
+			}
+			g, err := p.parseGeometryBody(upper)
+			if err != nil {
+				return nil, err
+			}
+			geoms = append(geoms, g)
+			if p.peek().kind == tokenComma {
+				p.advance()
+				continue
+			}
+			if _, err := p.expect(tokenRParen, "',' or ')'"); err != nil {
+				return nil, err
+			}
+			return map[string]any{"type": "GeometryCollection", "geometries": geoms}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported geometry type %s", kind)
+}
+
+func geometry(kind string, coordinates any) map[string]any {
+	return map[string]any{"type": kind, "coordinates": coordinates}
+}
+
+// parsePosition parses a coordinate tuple such as "10 20" or "10 20 5"
+func (p *parser) parsePosition() ([]any, error) {
+	var pos []any
+	for {
+		tok := p.peek()
+		if tok.kind != tokenNumber && !(tok.kind == tokenOperator && (tok.text == "-" || tok.text == "+")) {
+			break
+		}
+		n, err := p.parseScalar()
+		if err != nil {
+			return nil, err
+		}
+		pos = append(pos, n)
+	}
+	if len(pos) < 2 || len(pos) > 3 {
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: fmt.Sprintf("coordinate must have 2 or 3 values, got %d", len(pos))}
+	}
+	return pos, nil
+}
+
+// parsePositionList parses "(x y, x y, ...)"
+func (p *parser) parsePositionList() ([]any, error) {
+	return p.parseNested(func() (any, error) { return p.parsePosition() })
+}
+
+// parseRings parses "((x y, ...), (x y, ...))"
+func (p *parser) parseRings() ([]any, error) {
+	return p.parseNested(func() (any, error) { return p.parsePositionList() })
+}
+
+// parseMultiPoint accepts both "(x y, x y)" and "((x y), (x y))"
+func (p *parser) parseMultiPoint() ([]any, error) {
+	return p.parseNested(func() (any, error) {
+		if p.peek().kind == tokenLParen {
+			p.advance()
+			pos, err := p.parsePosition()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(tokenRParen, "')'"); err != nil {
+				return nil, err
+			}
+			return pos, nil
+		}
+		return p.parsePosition()
+	})
+}
+
+// parseNested parses a parenthesized, comma-separated list of elements
+func (p *parser) parseNested(element func() (any, error)) ([]any, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	if _, err := p.expect(tokenLParen, "'('"); err != nil {
+		return nil, err
+	}
+	var items []any
+	for {
+		item, err := element()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.peek().kind == tokenComma {
+			p.advance()
+			continue
+		}
+		if _, err := p.expect(tokenRParen, "',' or ')'"); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+}
+
+// op builds a CQL2-JSON operator node
+func op(name string, args ...any) map[string]any {
+	if args == nil {
+		args = []any{}
+	}
+	return map[string]any{"op": name, "args": args}
+}
@@ -0,0 +1,245 @@
+package cql2
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustJSON decodes a CQL2-JSON document the same way request bodies are decoded
+func mustJSON(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid test JSON %q: %v", s, err)
+	}
+	return v
+}
+
+func TestParseText_MatchesJSONForm(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		json string
+	}{
+		{
+			name: "equality",
+			text: "sar:instrument_mode = 'IW'",
+			json: `{"op":"=","args":[{"property":"sar:instrument_mode"},"IW"]}`,
+		},
+		{
+			name: "and of two equalities",
+			text: "sar:instrument_mode='IW' AND sat:orbit_state='ascending'",
+			json: `{"op":"and","args":[
+				{"op":"=","args":[{"property":"sar:instrument_mode"},"IW"]},
+				{"op":"=","args":[{"property":"sat:orbit_state"},"ascending"]}]}`,
+		},
+		{
+			name: "and is n-ary",
+			text: "a = 1 and b = 2 and c = 3",
+			json: `{"op":"and","args":[
+				{"op":"=","args":[{"property":"a"},1]},
+				{"op":"=","args":[{"property":"b"},2]},
+				{"op":"=","args":[{"property":"c"},3]}]}`,
+		},
+		{
+			name: "and binds tighter than or",
+			text: "a = 1 OR b = 2 AND c = 3",
+			json: `{"op":"or","args":[
+				{"op":"=","args":[{"property":"a"},1]},
+				{"op":"and","args":[
+					{"op":"=","args":[{"property":"b"},2]},
+					{"op":"=","args":[{"property":"c"},3]}]}]}`,
+		},
+		{
+			name: "parentheses override precedence",
+			text: "(a = 1 OR b = 2) AND c = 3",
+			json: `{"op":"and","args":[
+				{"op":"or","args":[
+					{"op":"=","args":[{"property":"a"},1]},
+					{"op":"=","args":[{"property":"b"},2]}]},
+				{"op":"=","args":[{"property":"c"},3]}]}`,
+		},
+		{
+			name: "not",
+			text: "NOT (platform = 'sentinel-1a')",
+			json: `{"op":"not","args":[{"op":"=","args":[{"property":"platform"},"sentinel-1a"]}]}`,
+		},
+		{
+			name: "comparison operators",
+			text: "sat:relative_orbit >= 10 AND sat:relative_orbit < 20 AND view:off_nadir <> -1.5",
+			json: `{"op":"and","args":[
+				{"op":">=","args":[{"property":"sat:relative_orbit"},10]},
+				{"op":"<","args":[{"property":"sat:relative_orbit"},20]},
+				{"op":"<>","args":[{"property":"view:off_nadir"},-1.5]}]}`,
+		},
+		{
+			name: "in list",
+			text: "platform IN ('sentinel-1a', 'sentinel-1b')",
+			json: `{"op":"in","args":[{"property":"platform"},["sentinel-1a","sentinel-1b"]]}`,
+		},
+		{
+			name: "not in",
+			text: "platform NOT IN ('sentinel-1a')",
+			json: `{"op":"not","args":[{"op":"in","args":[{"property":"platform"},["sentinel-1a"]]}]}`,
+		},
+		{
+			name: "between",
+			text: "sat:absolute_orbit BETWEEN 1000 AND 2000",
+			json: `{"op":"between","args":[{"property":"sat:absolute_orbit"},1000,2000]}`,
+		},
+		{
+			name: "like and not like",
+			text: "id LIKE 'S1A_IW%' AND id NOT LIKE '%_RAW_%'",
+			json: `{"op":"and","args":[
+				{"op":"like","args":[{"property":"id"},"S1A_IW%"]},
+				{"op":"not","args":[{"op":"like","args":[{"property":"id"},"%_RAW_%"]}]}]}`,
+		},
+		{
+			name: "is null and is not null",
+			text: "processing:level IS NULL OR sar:product_type IS NOT NULL",
+			json: `{"op":"or","args":[
+				{"op":"isNull","args":[{"property":"processing:level"}]},
+				{"op":"not","args":[{"op":"isNull","args":[{"property":"sar:product_type"}]}]}]}`,
+		},
+		{
+			name: "keywords are case-insensitive",
+			text: "platform in ('sentinel-1a') and not sar:instrument_mode = 'EW'",
+			json: `{"op":"and","args":[
+				{"op":"in","args":[{"property":"platform"},["sentinel-1a"]]},
+				{"op":"not","args":[{"op":"=","args":[{"property":"sar:instrument_mode"},"EW"]}]}]}`,
+		},
+		{
+			name: "quoted identifier and escaped quote",
+			text: `"my property" = 'it''s'`,
+			json: `{"op":"=","args":[{"property":"my property"},"it's"]}`,
+		},
+		{
+			name: "boolean literal",
+			text: "flag = TRUE",
+			json: `{"op":"=","args":[{"property":"flag"},true]}`,
+		},
+		{
+			name: "spatial with polygon",
+			text: "S_INTERSECTS(geometry, POLYGON((0 0, 10 0, 10 10, 0 10, 0 0)))",
+			json: `{"op":"s_intersects","args":[{"property":"geometry"},
+				{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}]}`,
+		},
+		{
+			name: "spatial with point and bbox",
+			text: "s_within(geometry, BBOX(-10, -5, 10, 5)) OR s_contains(geometry, POINT(-1.5 2))",
+			json: `{"op":"or","args":[
+				{"op":"s_within","args":[{"property":"geometry"},{"bbox":[-10,-5,10,5]}]},
+				{"op":"s_contains","args":[{"property":"geometry"},{"type":"Point","coordinates":[-1.5,2]}]}]}`,
+		},
+		{
+			name: "multipolygon",
+			text: "s_intersects(geometry, MULTIPOLYGON(((0 0, 1 0, 1 1, 0 0)), ((5 5, 6 5, 6 6, 5 5))))",
+			json: `{"op":"s_intersects","args":[{"property":"geometry"},
+				{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,5]]]]}]}`,
+		},
+		{
+			name: "temporal with timestamp and interval",
+			text: "T_AFTER(datetime, TIMESTAMP('2023-01-01T00:00:00Z')) AND t_during(datetime, INTERVAL('2023-01-01', '..'))",
+			json: `{"op":"and","args":[
+				{"op":"t_after","args":[{"property":"datetime"},{"timestamp":"2023-01-01T00:00:00Z"}]},
+				{"op":"t_during","args":[{"property":"datetime"},{"interval":["2023-01-01",".."]}]}]}`,
+		},
+		{
+			name: "date literal comparison",
+			text: "datetime > DATE('2020-06-01')",
+			json: `{"op":">","args":[{"property":"datetime"},{"date":"2020-06-01"}]}`,
+		},
+		{
+			name: "casei function",
+			text: "CASEI(platform) = casei('SENTINEL-1A')",
+			json: `{"op":"=","args":[{"op":"casei","args":[{"property":"platform"}]},{"op":"casei","args":["SENTINEL-1A"]}]}`,
+		},
+		{
+			name: "array function",
+			text: "a_contains(sar:polarizations, ('VV', 'VH'))",
+			json: `{"op":"a_contains","args":[{"property":"sar:polarizations"},["VV","VH"]]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseText(tt.text)
+			if err != nil {
+				t.Fatalf("ParseText(%q) error: %v", tt.text, err)
+			}
+
+			// Round-trip through JSON so numeric and container types match the
+			// decoded JSON form exactly.
+			gotBytes, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("failed to marshal result: %v", err)
+			}
+			want := mustJSON(t, tt.json)
+			if !reflect.DeepEqual(mustJSON(t, string(gotBytes)), want) {
+				wantBytes, _ := json.Marshal(want)
+				t.Errorf("ParseText(%q)\n got: %s\nwant: %s", tt.text, gotBytes, wantBytes)
+			}
+		})
+	}
+}
+
+func TestParseText_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		text    string
+		pos     int
+		message string
+	}{
+		{text: "", pos: 1, message: "empty filter expression"},
+		{text: "platform = ", pos: 12, message: "unexpected end of expression"},
+		{text: "platform = 'sentinel-1a", pos: 12, message: "unterminated string literal"},
+		{text: "platform == 'x'", pos: 11, message: "unexpected ="},
+		{text: "platform 'x'", pos: 10, message: "expected comparison operator"},
+		{text: "(platform = 'x'", pos: 16, message: "expected ')'"},
+		{text: "platform = 'x' AND", pos: 19, message: "unexpected end of expression"},
+		{text: "a BETWEEN 1 OR 2", pos: 13, message: "expected AND"},
+		{text: "platform IN 'x'", pos: 13, message: "expected '('"},
+		{text: "platform = 'x' extra", pos: 16, message: "expected end of expression"},
+		{text: "a = 1 + 2", pos: 7, message: "arithmetic"},
+		{text: "a IS NOT 'x'", pos: 10, message: "expected NULL"},
+		{text: "a = 1 ; DROP", pos: 7, message: "unexpected character ';'"},
+		{text: "s_intersects(geometry, BBOX(1, 2, 3))", pos: 28, message: "BBOX requires 4 or 6 coordinates"},
+		{text: "s_intersects(geometry, POINT(1))", pos: 31, message: "coordinate must have 2 or 3 values"},
+		{text: "AND = 1", pos: 1, message: "unexpected AND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := ParseText(tt.text)
+			if err == nil {
+				t.Fatalf("ParseText(%q) expected error, got nil", tt.text)
+			}
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected *SyntaxError, got %T: %v", err, err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("expected position %d, got %d (%v)", tt.pos, syntaxErr.Pos, err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error containing %q, got %q", tt.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseText_NestingLimit(t *testing.T) {
+	text := strings.Repeat("(", maxDepth+1) + "a = 1" + strings.Repeat(")", maxDepth+1)
+	_, err := ParseText(text)
+	if err == nil || !strings.Contains(err.Error(), "nesting") {
+		t.Fatalf("expected nesting error, got %v", err)
+	}
+
+	text = strings.Repeat("NOT ", maxDepth+1) + "a = 1"
+	_, err = ParseText(text)
+	if err == nil || !strings.Contains(err.Error(), "nesting") {
+		t.Fatalf("expected nesting error for NOT chain, got %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+// Package download streams item assets from the ASF distribution endpoints,
+// which sit behind Earthdata Login (URS). Requests carry an Earthdata bearer
+// token through the redirect chain, from the datapool to URS and back, and
+// the token is dropped before the final redirect to a signed storage URL.
+package download
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
+)
+
+// DefaultLoginHost is the Earthdata Login host. A download that ends there
+// was sent to the login form, so its credentials were missing or rejected.
+const DefaultLoginHost = "urs.earthdata.nasa.gov"
+
+// DefaultTokenHosts are the domains trusted with Earthdata tokens.
+var DefaultTokenHosts = []string{"asf.alaska.edu", "earthdata.nasa.gov"}
+
+// maxRedirects bounds the redirect chain, which takes four hops to reach
+// the storage URL when a session cookie has to be set up
+const maxRedirects = 10
+
+var (
+	// ErrUnauthorized is returned when the asset requires Earthdata Login
+	// and the token was missing or rejected.
+	ErrUnauthorized = errors.New("asset requires valid Earthdata Login credentials")
+	// ErrNotFound is returned when the asset does not exist upstream.
+	ErrNotFound = errors.New("asset not found")
+)
+
+// forwardedHeaders are the request headers passed on to the upstream, so
+// clients can resume downloads and revalidate what they have
+var forwardedHeaders = []string{"Range", "If-Range", "If-None-Match", "If-Modified-Since"}
+
+// Proxy fetches assets on behalf of clients. It is safe for concurrent use.
+type Proxy struct {
+	client     *http.Client
+	token      string
+	tokenHosts []string
+	loginHost  string
+	logger     *slog.Logger
+}
+
+// NewProxy creates a proxy. timeout bounds the wait for response headers;
+// the body streams for as long as the client keeps reading.
+func NewProxy(timeout time.Duration) *Proxy {
+	p := &Proxy{
+		tokenHosts: DefaultTokenHosts,
+		loginHost:  DefaultLoginHost,
+		logger:     slog.Default(),
+	}
+	p.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConnsPerHost:   16,
+			IdleConnTimeout:       90 * time.Second,
+			// Pass bodies through as served, so byte ranges and lengths hold
+			DisableCompression: true,
+		},
+		CheckRedirect: p.checkRedirect,
+	}
+	return p
+}
+
+// WithToken sets the server-side Earthdata bearer token, used for clients
+// that do not supply their own.
+func (p *Proxy) WithToken(token string) *Proxy {
+	p.token = token
+	return p
+}
+
+// WithTokenHosts sets the domains trusted with tokens. A host matches a
+// domain it equals or is a subdomain of; other hosts never see the token.
+func (p *Proxy) WithTokenHosts(hosts []string) *Proxy {
+	p.tokenHosts = hosts
+	return p
+}
+
+// WithLoginHost sets the Earthdata Login host, as host or host:port.
+func (p *Proxy) WithLoginHost(host string) *Proxy {
+	p.loginHost = host
+	return p
+}
+
+// WithTLSConfig sets the TLS configuration of asset requests, such as the
+// root CAs to trust.
+func (p *Proxy) WithTLSConfig(cfg *tls.Config) *Proxy {
+	p.client.Transport.(*http.Transport).TLSClientConfig = cfg
+	return p
+}
+
+// WithLogger sets the logger.
+func (p *Proxy) WithLogger(logger *slog.Logger) *Proxy {
+	p.logger = logger
+	return p
+}
+
+// HasToken reports whether a server-side token is configured.
+func (p *Proxy) HasToken() bool {
+	return p.token != ""
+}
+
+type tokenKey struct{}
+
+// Fetch requests an asset with method GET or HEAD, authenticating with
+// token, or the server-side token when empty. The conditional and Range
+// headers of header are forwarded. It returns the upstream response, with
+// status 200, 206, 304 or 416, for the caller to stream and close; it fails
+// with ErrUnauthorized, ErrNotFound or an *upstream.Error.
+func (p *Proxy) Fetch(ctx context.Context, method, href string, token string, header http.Header) (*http.Response, error) {
+	target, err := url.Parse(href)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("invalid asset href %q", href)
+	}
+	if token == "" {
+		token = p.token
+	}
+
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, tokenKey{}, token), method, href, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range forwardedHeaders {
+		if value := header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	p.authorize(req)
+
+	// Each download gets its own cookie jar, so the URS session cookie set
+	// during the redirect chain is neither lost nor shared between clients
+	jar, _ := cookiejar.New(nil)
+	client := *p.client
+	client.Jar = jar
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &upstream.Error{Service: target.Host, Attempts: 1, Retryable: ctx.Err() == nil, Err: err}
+	}
+
+	final := resp.Request.URL
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden,
+		p.loginHost != "" && final.Host == p.loginHost:
+		resp.Body.Close()
+		return nil, ErrUnauthorized
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	case resp.StatusCode == http.StatusOK, resp.StatusCode == http.StatusPartialContent,
+		resp.StatusCode == http.StatusNotModified, resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		return resp, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	return nil, &upstream.Error{
+		Service:    final.Host,
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Attempts:   1,
+		Retryable:  resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+	}
+}
+
+// checkRedirect carries the token across the redirects between trusted
+// hosts, which net/http would strip between different domains, and removes
+// it on the way to any other host
+func (p *Proxy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	p.logger.Debug("following asset redirect", slog.String("host", req.URL.Host))
+	p.authorize(req)
+	return nil
+}
+
+// authorize sets the bearer token of a request to a trusted host over
+// HTTPS; plain HTTP would expose the token on the wire
+func (p *Proxy) authorize(req *http.Request) {
+	token, _ := req.Context().Value(tokenKey{}).(string)
+	if token != "" && req.URL.Scheme == "https" && p.trusts(req.URL.Hostname()) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.Header.Del("Authorization")
+	}
+}
+
+func (p *Proxy) trusts(host string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range p.tokenHosts {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
+)
+
+const testToken = "edl-token"
+
+var testContent = []byte("0123456789abcdefghij")
+
+// ursChain stands in for the Earthdata Login redirect chain: the datapool
+// sends clients without a session to URS, URS sends clients with a valid
+// bearer token back with a code, the datapool exchanges it for a session
+// cookie, and then redirects to a signed storage URL. The datapool and URS
+// serve HTTPS, as tokens are only sent over TLS.
+type ursChain struct {
+	datapool *httptest.Server
+	urs      *httptest.Server
+	storage  *httptest.Server
+	// storageURL reaches storage by another host name, which is not trusted
+	// with tokens
+	storageURL string
+	// tokensAtStorage counts the requests that leaked a token to storage
+	tokensAtStorage atomic.Int32
+}
+
+func newURSChain(t *testing.T) *ursChain {
+	t.Helper()
+	c := &ursChain{}
+
+	c.storage = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			// Signed URLs reject requests with a second authentication method
+			c.tokensAtStorage.Add(1)
+			http.Error(w, "only one auth mechanism allowed", http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("signature") != "valid" {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "granule.zip", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), bytes.NewReader(testContent))
+	}))
+	t.Cleanup(c.storage.Close)
+	c.storageURL = strings.Replace(c.storage.URL, "127.0.0.1", "localhost", 1)
+
+	c.urs = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/authorize":
+			if r.Header.Get("Authorization") != "Bearer "+testToken {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			back := r.URL.Query().Get("redirect_uri") + "?code=granted&state=" + url.QueryEscape(r.URL.Query().Get("state"))
+			http.Redirect(w, r, back, http.StatusFound)
+		case "/login":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>Earthdata Login</html>"))
+		}
+	}))
+	t.Cleanup(c.urs.Close)
+
+	c.datapool = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.URL.Query().Get("code") != "granted" {
+				http.Error(w, "bad code", http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "asf-urs", Value: "session", Path: "/"})
+			http.Redirect(w, r, r.URL.Query().Get("state"), http.StatusFound)
+		case "/broken.zip":
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		case "/granule.zip":
+			if cookie, err := r.Cookie("asf-urs"); err != nil || cookie.Value != "session" {
+				authorize := c.urs.URL + "/oauth/authorize?redirect_uri=" + url.QueryEscape(c.datapool.URL+"/login") +
+					"&state=" + url.QueryEscape(r.URL.Path)
+				http.Redirect(w, r, authorize, http.StatusFound)
+				return
+			}
+			http.Redirect(w, r, c.storageURL+"/bucket/granule.zip?signature=valid", http.StatusTemporaryRedirect)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(c.datapool.Close)
+	return c
+}
+
+func (c *ursChain) proxy() *Proxy {
+	// The TLS test servers share a certificate, which the proxy must trust
+	return NewProxy(5 * time.Second).
+		WithTokenHosts([]string{"127.0.0.1"}).
+		WithLoginHost(strings.TrimPrefix(c.urs.URL, "https://")).
+		WithTLSConfig(c.datapool.Client().Transport.(*http.Transport).TLSClientConfig)
+}
+
+func TestProxy_FollowsEarthdataLogin(t *testing.T) {
+	chain := newURSChain(t)
+
+	tests := []struct {
+		name        string
+		serverToken string
+		callerToken string
+	}{
+		{"server token", testToken, ""},
+		{"caller token", "", testToken},
+		{"caller token overrides server token", "expired", testToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := chain.proxy().WithToken(tt.serverToken)
+			resp, err := p.Fetch(context.Background(), http.MethodGet, chain.datapool.URL+"/granule.zip", tt.callerToken, http.Header{})
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK || !bytes.Equal(body, testContent) {
+				t.Errorf("Expected the asset, got status %d and %q", resp.StatusCode, body)
+			}
+		})
+	}
+	if n := chain.tokensAtStorage.Load(); n != 0 {
+		t.Errorf("Expected the token to never reach storage, got %d requests", n)
+	}
+}
+
+func TestProxy_Range(t *testing.T) {
+	chain := newURSChain(t)
+	p := chain.proxy().WithToken(testToken)
+
+	header := http.Header{"Range": []string{"bytes=4-9"}, "Accept": []string{"*/*"}}
+	resp, err := p.Fetch(context.Background(), http.MethodGet, chain.datapool.URL+"/granule.zip", "", header)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "456789" {
+		t.Errorf("Expected bytes 4-9, got status %d and %q", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Range"); got != "bytes 4-9/20" {
+		t.Errorf("Unexpected Content-Range %q", got)
+	}
+
+	// Revalidation and unsatisfiable ranges are passed back as is
+	resp, err = p.Fetch(context.Background(), http.MethodGet, chain.datapool.URL+"/granule.zip", "", http.Header{"If-None-Match": []string{`"v1"`}})
+	if err != nil || resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("Expected 304, got %v (%v)", resp, err)
+	}
+	resp.Body.Close()
+	resp, err = p.Fetch(context.Background(), http.MethodGet, chain.datapool.URL+"/granule.zip", "", http.Header{"Range": []string{"bytes=100-"}})
+	if err != nil || resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("Expected 416, got %v (%v)", resp, err)
+	}
+	resp.Body.Close()
+}
+
+func TestProxy_Errors(t *testing.T) {
+	chain := newURSChain(t)
+
+	tests := []struct {
+		name  string
+		token string
+		path  string
+		check func(error) bool
+	}{
+		{"no token", "", "/granule.zip", func(err error) bool { return errors.Is(err, ErrUnauthorized) }},
+		{"rejected token", "wrong", "/granule.zip", func(err error) bool { return errors.Is(err, ErrUnauthorized) }},
+		{"missing asset", testToken, "/missing.zip", func(err error) bool { return errors.Is(err, ErrNotFound) }},
+		{"upstream failure", testToken, "/broken.zip", func(err error) bool {
+			var upstreamErr *upstream.Error
+			return errors.As(err, &upstreamErr) && upstreamErr.StatusCode == http.StatusInternalServerError && upstreamErr.Retryable
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := chain.proxy().Fetch(context.Background(), http.MethodGet, chain.datapool.URL+tt.path, tt.token, http.Header{})
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if !tt.check(err) {
+				t.Errorf("Unexpected error %v", err)
+			}
+		})
+	}
+
+	if _, err := chain.proxy().Fetch(context.Background(), http.MethodGet, "file:///etc/passwd", "", http.Header{}); err == nil {
+		t.Error("Expected an error for a non-HTTP href")
+	}
+}
+
+func TestProxy_TrustsTokenHosts(t *testing.T) {
+	p := NewProxy(time.Second)
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"datapool.asf.alaska.edu", true},
+		{"asf.alaska.edu", true},
+		{"urs.earthdata.nasa.gov", true},
+		{"DATAPOOL.ASF.ALASKA.EDU", true},
+		{"evilasf.alaska.edu.example.com", false},
+		{"notasf.alaska.edu.evil", false},
+		{"sentinel1.s3.amazonaws.com", false},
+	}
+	for _, tt := range tests {
+		if got := p.trusts(tt.host); got != tt.want {
+			t.Errorf("trusts(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestProxy_NoTokenOverHTTP(t *testing.T) {
+	var got atomic.Value
+	got.Store("")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get("Authorization"))
+		w.Write(testContent)
+	}))
+	defer server.Close()
+
+	// The host is trusted, but the token must not travel in the clear
+	p := NewProxy(5 * time.Second).WithTokenHosts([]string{"127.0.0.1"}).WithToken(testToken)
+	resp, err := p.Fetch(context.Background(), http.MethodGet, server.URL+"/granule.zip", "", http.Header{})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	resp.Body.Close()
+	if auth := got.Load().(string); auth != "" {
+		t.Errorf("Expected no Authorization header over HTTP, got %q", auth)
+	}
+}
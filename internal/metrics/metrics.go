@@ -0,0 +1,152 @@
+// Package metrics exposes the proxy's Prometheus metrics: HTTP requests,
+// upstream ASF and CMR calls, page sizes, translation failures and the cursor
+// store.
+//
+// Every method of Metrics is safe to call on a nil *Metrics, which records
+// nothing, so components can be instrumented unconditionally.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// namespace prefixes every metric name
+const namespace = "asf_stac_"
+
+var (
+	// latencyBuckets are the histogram bounds for request latencies, in seconds
+	latencyBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+	// pageBuckets are the histogram bounds for the number of items on a page
+	pageBuckets = []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000}
+)
+
+// Metrics records the proxy's metrics.
+type Metrics struct {
+	registry *Registry
+
+	httpRequests *CounterVec
+	httpDuration *HistogramVec
+
+	upstreamRequests *CounterVec
+	upstreamDuration *HistogramVec
+	upstreamRetries  *CounterVec
+	upstreamErrors   *CounterVec
+
+	pageItems           *HistogramVec
+	translationFailures *CounterVec
+}
+
+// New creates the proxy's metrics in a new registry.
+func New() *Metrics {
+	r := NewRegistry()
+	return &Metrics{
+		registry: r,
+		httpRequests: r.NewCounterVec(namespace+"http_requests_total",
+			"HTTP requests by route pattern, method and status.", "route", "method", "status"),
+		httpDuration: r.NewHistogramVec(namespace+"http_request_duration_seconds",
+			"HTTP request latency by route pattern and method.", latencyBuckets, "route", "method"),
+		upstreamRequests: r.NewCounterVec(namespace+"upstream_requests_total",
+			"Upstream HTTP attempts by backend and status; status is \"error\" when no response was received.", "backend", "status"),
+		upstreamDuration: r.NewHistogramVec(namespace+"upstream_request_duration_seconds",
+			"Upstream HTTP attempt latency by backend.", latencyBuckets, "backend"),
+		upstreamRetries: r.NewCounterVec(namespace+"upstream_retries_total",
+			"Upstream HTTP requests retried after a transient failure, by backend.", "backend"),
+		upstreamErrors: r.NewCounterVec(namespace+"upstream_errors_total",
+			"Upstream HTTP requests that failed after any retries, by backend and whether the last failure was retryable or terminal.", "backend", "kind"),
+		pageItems: r.NewHistogramVec(namespace+"page_items",
+			"Items returned per page by endpoint.", pageBuckets, "endpoint"),
+		translationFailures: r.NewCounterVec(namespace+"translation_failures_total",
+			"Upstream granules dropped because they could not be translated to STAC items, by backend.", "backend"),
+	}
+}
+
+// Registry returns the registry holding the metrics.
+func (m *Metrics) Registry() *Registry {
+	return m.registry
+}
+
+// Handler returns the /metrics HTTP handler.
+func (m *Metrics) Handler() http.Handler {
+	return m.registry.Handler()
+}
+
+// ObserveHTTPRequest records a served HTTP request.
+func (m *Metrics) ObserveHTTPRequest(route, method string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.httpRequests.Inc(route, method, strconv.Itoa(status))
+	m.httpDuration.Observe(duration.Seconds(), route, method)
+}
+
+// ObserveUpstreamAttempt records one upstream HTTP attempt. status is 0 when
+// no response was received.
+func (m *Metrics) ObserveUpstreamAttempt(backend string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	m.upstreamRequests.Inc(backend, label)
+	m.upstreamDuration.Observe(duration.Seconds(), backend)
+}
+
+// UpstreamRetry records that an upstream request is being retried.
+func (m *Metrics) UpstreamRetry(backend string) {
+	if m == nil {
+		return
+	}
+	m.upstreamRetries.Inc(backend)
+}
+
+// UpstreamError records an upstream request that failed for good.
+func (m *Metrics) UpstreamError(backend string, retryable bool) {
+	if m == nil {
+		return
+	}
+	kind := "terminal"
+	if retryable {
+		kind = "retryable"
+	}
+	m.upstreamErrors.Inc(backend, kind)
+}
+
+// ObservePage records the number of items returned on a page.
+func (m *Metrics) ObservePage(endpoint string, items int) {
+	if m == nil {
+		return
+	}
+	m.pageItems.Observe(float64(items), endpoint)
+}
+
+// TranslationFailure records an upstream granule that could not be
+// translated to a STAC item.
+func (m *Metrics) TranslationFailure(backend string) {
+	if m == nil {
+		return
+	}
+	m.translationFailures.Inc(backend)
+}
+
+// RegisterCursorStore reports the size of a cursor store and the age of its
+// oldest cursor, as returned by stats when metrics are scraped.
+func (m *Metrics) RegisterCursorStore(stats func() (count int, oldestAge time.Duration)) {
+	if m == nil {
+		return
+	}
+	m.registry.NewGaugeFunc(namespace+"cursor_store_cursors",
+		"Cursors held in the cursor store.", func() float64 {
+			count, _ := stats()
+			return float64(count)
+		})
+	m.registry.NewGaugeFunc(namespace+"cursor_store_oldest_cursor_age_seconds",
+		"Age of the oldest cursor in the cursor store.", func() float64 {
+			_, age := stats()
+			return age.Seconds()
+		})
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("requests_total", "Requests.", "route", "status")
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	r.NewGaugeFunc("open", "Open things.", func() float64 { return 3 })
+
+	requests.Inc("/b", "200")
+	requests.Add(2, "/a", "500")
+	requests.Inc(`/"q"`, "200")
+	latency.Observe(0.05, "/a")
+	latency.Observe(0.5, "/a")
+	latency.Observe(5, "/a")
+
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/\"q\"",status="200"} 1
+requests_total{route="/a",status="500"} 2
+requests_total{route="/b",status="200"} 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 1
+latency_seconds_bucket{route="/a",le="1"} 2
+latency_seconds_bucket{route="/a",le="+Inf"} 3
+latency_seconds_sum{route="/a"} 5.55
+latency_seconds_count{route="/a"} 3
+# HELP open Open things.
+# TYPE open gauge
+open 3
+`
+	if b.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", b.String(), want)
+	}
+	if got := requests.Value("/a", "500"); got != 2 {
+		t.Errorf("Value() = %v, want 2", got)
+	}
+	if got := latency.Count("/a"); got != 3 {
+		t.Errorf("Count() = %d, want 3", got)
+	}
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+	m.ObserveHTTPRequest("/search", "GET", 200, time.Second)
+	m.ObserveUpstreamAttempt("asf", 200, time.Second)
+	m.UpstreamRetry("asf")
+	m.UpstreamError("asf", true)
+	m.ObservePage("search", 10)
+	m.TranslationFailure("cmr")
+	m.RegisterCursorStore(func() (int, time.Duration) { return 0, 0 })
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.ObserveUpstreamAttempt("asf", 0, 10*time.Millisecond)
+	m.RegisterCursorStore(func() (int, time.Duration) { return 4, 90 * time.Second })
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		`asf_stac_upstream_requests_total{backend="asf",status="error"} 1`,
+		`asf_stac_cursor_store_cursors 4`,
+		`asf_stac_cursor_store_oldest_cursor_age_seconds 90`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, body)
+		}
+	}
+}
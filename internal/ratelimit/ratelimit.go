@@ -0,0 +1,155 @@
+// Package ratelimit protects the upstream APIs from being saturated by a few
+// clients: token buckets limit the request rate of each client, and a
+// semaphore caps the number of upstream calls in flight across all of them.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// Decision is the outcome of a rate limit check.
+type Decision struct {
+	// Allowed reports whether the request may proceed
+	Allowed bool
+	// Limit is the bucket size, the most requests a client can make at once
+	Limit int
+	// Remaining is the number of requests the client can still make at once
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, 0 when
+	// Allowed
+	RetryAfter time.Duration
+}
+
+// Limiter is a set of token buckets, one per key, each holding up to burst
+// tokens and refilled at rate tokens per second. It is safe for concurrent
+// use.
+type Limiter struct {
+	rate  float64
+	burst int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter allowing each key rate requests per second,
+// with bursts of up to burst requests.
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   max(burst, 1),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of key, if one is available.
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		l.sweep(now)
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+	}
+
+	d := Decision{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.refill(1 - b.tokens)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = l.refill(float64(l.burst) - b.tokens)
+	return d
+}
+
+// refill returns the time needed to refill the given number of tokens
+func (l *Limiter) refill(tokens float64) time.Duration {
+	if l.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep forgets the buckets that have refilled completely, which behave
+// like new ones, at most once per refill period
+func (l *Limiter) sweep(now time.Time) {
+	full := l.refill(float64(l.burst))
+	if now.Sub(l.lastSweep) < full {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ErrSaturated is returned when no upstream call slot became free in time.
+var ErrSaturated = errors.New("too many concurrent upstream requests")
+
+// Semaphore caps the number of upstream calls in flight. Callers wait for a
+// free slot for up to a maximum time. It is safe for concurrent use.
+type Semaphore struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewSemaphore creates a semaphore allowing n concurrent calls, whose callers
+// wait up to maxWait for a slot.
+func NewSemaphore(n int, maxWait time.Duration) *Semaphore {
+	return &Semaphore{slots: make(chan struct{}, n), maxWait: maxWait}
+}
+
+// Acquire takes a slot, waiting until one is free, maxWait has passed or ctx
+// is done. It returns ErrSaturated or the context's error when no slot was
+// taken. Every successful Acquire must be followed by a Release.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(s.maxWait)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrSaturated
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (s *Semaphore) Release() {
+	<-s.slots
+}
+
+// InFlight returns the number of slots taken.
+func (s *Semaphore) InFlight() int {
+	return len(s.slots)
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Refill(t *testing.T) {
+	l := NewLimiter(2, 3)
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		d := l.Allow("client")
+		if !d.Allowed {
+			t.Fatalf("request %d denied within the burst", i+1)
+		}
+		if d.Limit != 3 || d.Remaining != 2-i {
+			t.Errorf("request %d: limit %d remaining %d, want 3 and %d", i+1, d.Limit, d.Remaining, 2-i)
+		}
+	}
+
+	d := l.Allow("client")
+	if d.Allowed {
+		t.Fatal("request over the burst allowed")
+	}
+	if d.RetryAfter != 500*time.Millisecond || d.Reset != 1500*time.Millisecond {
+		t.Errorf("retry after %s reset %s, want 500ms and 1.5s", d.RetryAfter, d.Reset)
+	}
+
+	// Half a second refills one token at 2 per second
+	now = now.Add(500 * time.Millisecond)
+	if d := l.Allow("client"); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("after refill: allowed %v remaining %d, want allowed with 0 left", d.Allowed, d.Remaining)
+	}
+}
+
+func TestLimiter_KeysAreIsolated(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.now = func() time.Time { return time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC) }
+
+	if !l.Allow("ip:192.0.2.1").Allowed {
+		t.Fatal("first request denied")
+	}
+	if l.Allow("ip:192.0.2.1").Allowed {
+		t.Error("second request from the same client allowed")
+	}
+	if !l.Allow("ip:192.0.2.2").Allowed {
+		t.Error("request from another client denied")
+	}
+}
+
+func TestLimiter_SweepsFullBuckets(t *testing.T) {
+	l := NewLimiter(1, 2)
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+	now = now.Add(time.Minute)
+	l.Allow("c")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buckets) != 1 {
+		t.Errorf("%d buckets left, want only the new one", len(l.buckets))
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	s := NewSemaphore(2, 20*time.Millisecond)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := s.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire() %d error = %v", i+1, err)
+		}
+	}
+	if s.InFlight() != 2 {
+		t.Errorf("InFlight() = %d, want 2", s.InFlight())
+	}
+
+	if err := s.Acquire(ctx); !errors.Is(err, ErrSaturated) {
+		t.Errorf("Acquire() with every slot taken error = %v, want ErrSaturated", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.Acquire(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire() with a cancelled context error = %v, want context.Canceled", err)
+	}
+
+	// A slot released while a caller waits goes to it
+	waiting := NewSemaphore(1, time.Second)
+	waiting.Acquire(ctx)
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		waiting.Release()
+	}()
+	if err := waiting.Acquire(ctx); err != nil {
+		t.Errorf("Acquire() while a slot is released error = %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a Redis stand-in speaking just enough RESP2 for the client:
+// AUTH, SELECT, GET, SET with PX and DEL.
+type fakeServer struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	ttls     map[string]string
+	commands []string
+	dials    int
+}
+
+func newFakeServer(t *testing.T, password string) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeServer{ln: ln, password: password, values: make(map[string]string), ttls: make(map[string]string)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.dials++
+			s.mu.Unlock()
+			go s.serve(nc)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	authed := s.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(args, " "))
+		var reply string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			if args[1] == s.password {
+				authed = true
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT":
+			reply = "+OK\r\n"
+		case cmd == "GET":
+			if v, ok := s.values[args[1]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case cmd == "SET":
+			s.values[args[1]] = args[2]
+			s.ttls[args[1]] = strings.Join(args[3:], " ")
+			reply = "+OK\r\n"
+		case cmd == "DEL":
+			_, ok := s.values[args[1]]
+			delete(s.values, args[1])
+			reply = ":" + map[bool]string{true: "1", false: "0"}[ok] + "\r\n"
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		s.mu.Unlock()
+		if _, err := io.WriteString(nc, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	reply, err := readReply(r)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items) == 0 {
+		return nil, errors.New("not a command")
+	}
+	args := make([]string, len(items))
+	for i, item := range items {
+		args[i] = string(item.([]byte))
+	}
+	return args, nil
+}
+
+func TestClient_GetSetDelete(t *testing.T) {
+	server := newFakeServer(t, "")
+	client := NewClient(server.Addr())
+	defer client.Close()
+	ctx := context.Background()
+
+	if _, found, err := client.Get(ctx, "missing"); err != nil || found {
+		t.Fatalf("Get(missing) = found %v, err %v; want not found", found, err)
+	}
+
+	value := []byte("binary\r\nvalue\x00")
+	if err := client.Set(ctx, "key", value, 90*time.Second); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if ttl := server.ttls["key"]; ttl != "PX 90000" {
+		t.Errorf("SET expiry = %q, want \"PX 90000\"", ttl)
+	}
+
+	got, found, err := client.Get(ctx, "key")
+	if err != nil || !found {
+		t.Fatalf("Get(key) = found %v, err %v", found, err)
+	}
+	if string(got) != string(value) {
+		t.Errorf("Get(key) = %q, want %q", got, value)
+	}
+
+	if err := client.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, found, _ := client.Get(ctx, "key"); found {
+		t.Error("key still present after Delete")
+	}
+
+	// Commands reuse the pooled connection
+	if server.dials != 1 {
+		t.Errorf("dialed %d connections, want 1", server.dials)
+	}
+}
+
+func TestClient_AuthAndSelect(t *testing.T) {
+	server := newFakeServer(t, "hunter2")
+
+	client := NewClient(server.Addr()).WithPassword("hunter2").WithDB(3)
+	defer client.Close()
+	if err := client.Set(context.Background(), "key", []byte("v"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	server.mu.Lock()
+	commands := strings.Join(server.commands[:2], "; ")
+	server.mu.Unlock()
+	if commands != "AUTH hunter2; SELECT "+strconv.Itoa(3) {
+		t.Errorf("connection setup = %q, want AUTH then SELECT", commands)
+	}
+
+	wrong := NewClient(server.Addr()).WithPassword("wrong")
+	defer wrong.Close()
+	_, _, err := wrong.Get(context.Background(), "key")
+	var replyErr Error
+	if !errors.As(err, &replyErr) || !strings.HasPrefix(string(replyErr), "WRONGPASS") {
+		t.Errorf("Get() with a wrong password error = %v, want WRONGPASS", err)
+	}
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	client := NewClient(addr).WithTimeout(time.Second)
+	if _, _, err := client.Get(context.Background(), "key"); err == nil {
+		t.Error("Get() succeeded against a closed port")
+	}
+}
+
+func TestClient_Closed(t *testing.T) {
+	server := newFakeServer(t, "")
+	client := NewClient(server.Addr())
+	client.Close()
+	if _, _, err := client.Get(context.Background(), "key"); err == nil {
+		t.Error("Get() succeeded on a closed client")
+	}
+}
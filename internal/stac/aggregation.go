@@ -0,0 +1,470 @@
+package stac
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/pkg/geojson"
+)
+
+// Aggregation names (STAC API Aggregation extension)
+const (
+	AggregationTotalCount              = "total_count"
+	AggregationDatetimeFrequency       = "datetime_frequency"
+	AggregationPlatformFrequency       = "platform_frequency"
+	AggregationInstrumentModeFrequency = "sar_instrument_mode_frequency"
+	AggregationRelativeOrbitFrequency  = "sat_relative_orbit_frequency"
+	AggregationOrbitStateFrequency     = "sat_orbit_state_frequency"
+	AggregationGeohashGridFrequency    = "centroid_geohash_grid_frequency"
+	AggregationGeotileGridFrequency    = "centroid_geotile_grid_frequency"
+)
+
+// Aggregation data types
+const (
+	AggregationDataInteger               = "integer"
+	AggregationDataFrequencyDistribution = "frequency_distribution"
+	BucketDataString                     = "string"
+	BucketDataNumeric                    = "numeric"
+	BucketDataDatetime                   = "datetime"
+)
+
+// Datetime histogram intervals
+const (
+	IntervalYear  = "year"
+	IntervalMonth = "month"
+	IntervalDay   = "day"
+	IntervalHour  = "hour"
+)
+
+// Aggregation parameter defaults
+const (
+	DefaultDatetimeFrequencyInterval = IntervalMonth
+	DefaultGeohashPrecision          = 3
+	DefaultGeotilePrecision          = 6
+	MaxGeohashPrecision              = 12
+	MaxGeotilePrecision              = 29
+)
+
+// AggregationKind says how an aggregation buckets items
+type AggregationKind int
+
+const (
+	// AggregationCount counts the matching items
+	AggregationCount AggregationKind = iota
+	// AggregationTerms counts items by the value of a property
+	AggregationTerms
+	// AggregationDatetime counts items by acquisition start interval
+	AggregationDatetime
+	// AggregationGeohash counts items by the geohash cell of their centroid
+	AggregationGeohash
+	// AggregationGeotile counts items by the map tile of their centroid
+	AggregationGeotile
+)
+
+// AggregationDefinition describes an aggregation offered by the proxy.
+type AggregationDefinition struct {
+	Name     string          `json:"name"`
+	DataType string          `json:"data_type"`
+	Kind     AggregationKind `json:"-"`
+	// Property is the item property counted by a terms aggregation
+	Property string `json:"-"`
+}
+
+// SupportedAggregations lists the aggregations the proxy computes, in the
+// order they are listed and computed by default.
+var SupportedAggregations = []AggregationDefinition{
+	{Name: AggregationTotalCount, DataType: AggregationDataInteger, Kind: AggregationCount},
+	{Name: AggregationDatetimeFrequency, DataType: AggregationDataFrequencyDistribution, Kind: AggregationDatetime},
+	{Name: AggregationPlatformFrequency, DataType: AggregationDataFrequencyDistribution, Kind: AggregationTerms, Property: "platform"},
+	{Name: AggregationInstrumentModeFrequency, DataType: AggregationDataFrequencyDistribution, Kind: AggregationTerms, Property: "sar:instrument_mode"},
+	{Name: AggregationRelativeOrbitFrequency, DataType: AggregationDataFrequencyDistribution, Kind: AggregationTerms, Property: "sat:relative_orbit"},
+	{Name: AggregationOrbitStateFrequency, DataType: AggregationDataFrequencyDistribution, Kind: AggregationTerms, Property: "sat:orbit_state"},
+	{Name: AggregationGeohashGridFrequency, DataType: AggregationDataFrequencyDistribution, Kind: AggregationGeohash},
+	{Name: AggregationGeotileGridFrequency, DataType: AggregationDataFrequencyDistribution, Kind: AggregationGeotile},
+}
+
+// LookupAggregation returns the definition of a supported aggregation
+func LookupAggregation(name string) (AggregationDefinition, bool) {
+	for _, def := range SupportedAggregations {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return AggregationDefinition{}, false
+}
+
+// Bucket is one entry of a frequency distribution
+type Bucket struct {
+	Key       any    `json:"key"`
+	DataType  string `json:"data_type"`
+	Frequency int    `json:"frequency"`
+}
+
+// Aggregation is the result of one aggregation
+type Aggregation struct {
+	Name     string    `json:"name"`
+	DataType string    `json:"data_type"`
+	Buckets  []*Bucket `json:"buckets,omitempty"`
+	Value    any       `json:"value,omitempty"`
+}
+
+// AggregationCollection is the response of /aggregate and /aggregations
+type AggregationCollection struct {
+	Type         string         `json:"type"`
+	Aggregations []*Aggregation `json:"aggregations"`
+	Links        []*Link        `json:"links"`
+	// Partial is set when the aggregations were computed from a bounded
+	// scan that stopped before the end of the matching items
+	Partial bool `json:"partial,omitempty"`
+}
+
+// NewAggregationCollection creates an empty AggregationCollection
+func NewAggregationCollection() *AggregationCollection {
+	return &AggregationCollection{
+		Type:         "AggregationCollection",
+		Aggregations: []*Aggregation{},
+		Links:        []*Link{},
+	}
+}
+
+// AddLink adds a link to the aggregation collection
+func (ac *AggregationCollection) AddLink(rel, href, mediaType string) {
+	ac.Links = append(ac.Links, &Link{
+		Rel:  rel,
+		Href: href,
+		Type: mediaType,
+	})
+}
+
+// AggregationRequest is a search request with the aggregations to compute
+type AggregationRequest struct {
+	*SearchRequest
+
+	Aggregations              []string `json:"aggregations,omitempty"`
+	DatetimeFrequencyInterval string   `json:"datetime_frequency_interval,omitempty"`
+	GeohashPrecision          int      `json:"centroid_geohash_grid_frequency_precision,omitempty"`
+	GeotilePrecision          int      `json:"centroid_geotile_grid_frequency_precision,omitempty"`
+}
+
+// ParseAggregationRequest parses an aggregation request from GET query parameters
+func ParseAggregationRequest(r *http.Request) (*AggregationRequest, error) {
+	search, err := ParseSearchRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	req := &AggregationRequest{SearchRequest: search}
+
+	query := r.URL.Query()
+	if aggregations := query.Get("aggregations"); aggregations != "" {
+		for _, name := range strings.Split(aggregations, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				req.Aggregations = append(req.Aggregations, name)
+			}
+		}
+	}
+	req.DatetimeFrequencyInterval = query.Get("datetime_frequency_interval")
+	for param, target := range map[string]*int{
+		"centroid_geohash_grid_frequency_precision": &req.GeohashPrecision,
+		"centroid_geotile_grid_frequency_precision": &req.GeotilePrecision,
+	} {
+		if value := query.Get(param); value != "" {
+			precision, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s parameter: %w", param, err)
+			}
+			*target = precision
+		}
+	}
+
+	return req, req.normalize()
+}
+
+// ParseAggregationRequestBody parses an aggregation request from a POST JSON body
+func ParseAggregationRequestBody(body io.Reader) (*AggregationRequest, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aggregation request body: %w", err)
+	}
+
+	search, err := ParseSearchRequestBody(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var req AggregationRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse aggregation request body: %w", err)
+	}
+	req.SearchRequest = search
+
+	return &req, req.normalize()
+}
+
+// normalize applies defaults and validates the aggregation parameters
+func (req *AggregationRequest) normalize() error {
+	if len(req.Aggregations) == 0 {
+		for _, def := range SupportedAggregations {
+			req.Aggregations = append(req.Aggregations, def.Name)
+		}
+	}
+	for _, name := range req.Aggregations {
+		if _, ok := LookupAggregation(name); !ok {
+			return fmt.Errorf("unsupported aggregation %q", name)
+		}
+	}
+
+	switch req.DatetimeFrequencyInterval {
+	case "":
+		req.DatetimeFrequencyInterval = DefaultDatetimeFrequencyInterval
+	case IntervalYear, IntervalMonth, IntervalDay, IntervalHour:
+	default:
+		return fmt.Errorf("unsupported datetime_frequency_interval %q", req.DatetimeFrequencyInterval)
+	}
+
+	if req.GeohashPrecision == 0 {
+		req.GeohashPrecision = DefaultGeohashPrecision
+	}
+	if req.GeohashPrecision < 1 || req.GeohashPrecision > MaxGeohashPrecision {
+		return fmt.Errorf("centroid_geohash_grid_frequency_precision must be between 1 and %d, got %d",
+			MaxGeohashPrecision, req.GeohashPrecision)
+	}
+	if req.GeotilePrecision == 0 {
+		req.GeotilePrecision = DefaultGeotilePrecision
+	}
+	if req.GeotilePrecision < 1 || req.GeotilePrecision > MaxGeotilePrecision {
+		return fmt.Errorf("centroid_geotile_grid_frequency_precision must be between 1 and %d, got %d",
+			MaxGeotilePrecision, req.GeotilePrecision)
+	}
+
+	return nil
+}
+
+// TruncateToInterval returns the start of the histogram interval holding t
+func TruncateToInterval(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case IntervalYear:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case IntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
+
+// NextInterval returns the start of the histogram interval after the one
+// starting at t
+func NextInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalYear:
+		return t.AddDate(1, 0, 0)
+	case IntervalMonth:
+		return t.AddDate(0, 1, 0)
+	case IntervalDay:
+		return t.AddDate(0, 0, 1)
+	}
+	return t.Add(time.Hour)
+}
+
+// Aggregator accumulates items into frequency distributions
+type Aggregator struct {
+	req    *AggregationRequest
+	total  int
+	counts map[string]map[any]int
+}
+
+// NewAggregator creates an Aggregator for the aggregations of req
+func NewAggregator(req *AggregationRequest) *Aggregator {
+	a := &Aggregator{req: req, counts: make(map[string]map[any]int)}
+	for _, name := range req.Aggregations {
+		a.counts[name] = make(map[any]int)
+	}
+	return a
+}
+
+// Add counts an item in every aggregation. Items lacking the aggregated
+// value are left out of that distribution.
+func (a *Aggregator) Add(item *Item) {
+	a.total++
+	for name, counts := range a.counts {
+		def, _ := LookupAggregation(name)
+		if key, ok := a.bucketKey(def, item); ok {
+			counts[key]++
+		}
+	}
+}
+
+// Total returns the number of items added
+func (a *Aggregator) Total() int {
+	return a.total
+}
+
+// Result returns the accumulated aggregation with the given name
+func (a *Aggregator) Result(name string) *Aggregation {
+	def, _ := LookupAggregation(name)
+	if def.Kind == AggregationCount {
+		return CountAggregation(def, a.total)
+	}
+
+	dataType := BucketDataString
+	switch def.Kind {
+	case AggregationDatetime:
+		dataType = BucketDataDatetime
+	case AggregationTerms:
+		if def.Property == "sat:relative_orbit" {
+			dataType = BucketDataNumeric
+		}
+	}
+	buckets := make([]*Bucket, 0, len(a.counts[name]))
+	for key, frequency := range a.counts[name] {
+		buckets = append(buckets, &Bucket{Key: key, DataType: dataType, Frequency: frequency})
+	}
+	return FrequencyAggregation(def, buckets)
+}
+
+// CountAggregation returns an integer aggregation
+func CountAggregation(def AggregationDefinition, count int) *Aggregation {
+	return &Aggregation{Name: def.Name, DataType: def.DataType, Value: count}
+}
+
+// FrequencyAggregation returns a frequency distribution. Datetime buckets are
+// ordered chronologically and all others by descending frequency, ties by key.
+func FrequencyAggregation(def AggregationDefinition, buckets []*Bucket) *Aggregation {
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if def.Kind != AggregationDatetime && buckets[i].Frequency != buckets[j].Frequency {
+			return buckets[i].Frequency > buckets[j].Frequency
+		}
+		return compareSortValues(buckets[i].Key, buckets[j].Key) < 0
+	})
+	return &Aggregation{Name: def.Name, DataType: def.DataType, Buckets: buckets}
+}
+
+// bucketKey returns the key of the bucket counting item
+func (a *Aggregator) bucketKey(def AggregationDefinition, item *Item) (any, bool) {
+	switch def.Kind {
+	case AggregationTerms:
+		value, ok := item.Properties[def.Property]
+		if !ok || value == nil {
+			return nil, false
+		}
+		if n, ok := sortNumber(value); ok {
+			return n, true
+		}
+		return fmt.Sprint(value), true
+	case AggregationDatetime:
+		t, ok := sortTime(sortValue(item, "datetime"))
+		if !ok {
+			return nil, false
+		}
+		return TruncateToInterval(t, a.req.DatetimeFrequencyInterval).Format(time.RFC3339), true
+	case AggregationGeohash:
+		lon, lat, ok := itemCentroid(item)
+		if !ok {
+			return nil, false
+		}
+		return EncodeGeohash(lon, lat, a.req.GeohashPrecision), true
+	case AggregationGeotile:
+		lon, lat, ok := itemCentroid(item)
+		if !ok {
+			return nil, false
+		}
+		return EncodeGeotile(lon, lat, a.req.GeotilePrecision), true
+	}
+	return nil, false
+}
+
+// itemCentroid returns the center of an item's bounding box, computed from
+// its geometry when the item has no bbox. Boxes crossing the antimeridian
+// (west > east) are centered across it.
+func itemCentroid(item *Item) (lon, lat float64, ok bool) {
+	bbox := item.Bbox
+	if len(bbox) < 4 && item.Geometry != nil {
+		data, err := json.Marshal(item.Geometry)
+		if err != nil {
+			return 0, 0, false
+		}
+		var g geojson.Geometry
+		if err := json.Unmarshal(data, &g); err != nil {
+			return 0, 0, false
+		}
+		if bbox, err = geojson.ComputeBBox(&g); err != nil {
+			return 0, 0, false
+		}
+	}
+	if len(bbox) < 4 {
+		return 0, 0, false
+	}
+
+	west, south, east, north := bbox[0], bbox[1], bbox[2], bbox[3]
+	if len(bbox) == 6 {
+		east, north = bbox[3], bbox[4]
+	}
+	if west > east {
+		east += 360
+	}
+	lon = (west + east) / 2
+	if lon > 180 {
+		lon -= 360
+	}
+	return lon, (south + north) / 2, true
+}
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// EncodeGeohash returns the geohash of a point with the given number of characters
+func EncodeGeohash(lon, lat float64, precision int) string {
+	lonRange := [2]float64{-180, 180}
+	latRange := [2]float64{-90, 90}
+
+	var hash strings.Builder
+	bits, ch := 0, 0
+	even := true
+	for hash.Len() < precision {
+		r, v := &latRange, lat
+		if even {
+			r, v = &lonRange, lon
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+
+		if bits++; bits == 5 {
+			hash.WriteByte(geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// maxMercatorLatitude is the latitude limit of the web mercator projection
+const maxMercatorLatitude = 85.05112878
+
+// EncodeGeotile returns the "zoom/x/y" web mercator tile holding a point
+func EncodeGeotile(lon, lat float64, zoom int) string {
+	lat = math.Max(-maxMercatorLatitude, math.Min(maxMercatorLatitude, lat))
+	n := math.Exp2(float64(zoom))
+
+	x := int(math.Floor((lon + 180) / 360 * n))
+	rad := lat * math.Pi / 180
+	y := int(math.Floor((1 - math.Log(math.Tan(rad)+1/math.Cos(rad))/math.Pi) / 2 * n))
+
+	tiles := int(n)
+	x = max(0, min(x, tiles-1))
+	y = max(0, min(y, tiles-1))
+	return fmt.Sprintf("%d/%d/%d", zoom, x, y)
+}
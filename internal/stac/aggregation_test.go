@@ -0,0 +1,156 @@
+package stac
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeGeohash(t *testing.T) {
+	tests := []struct {
+		lon, lat  float64
+		precision int
+		want      string
+	}{
+		{-5.6, 42.6, 5, "ezs42"},
+		{10.40744, 57.64911, 11, "u4pruydqqvj"},
+		{0, 0, 1, "s"},
+		{-180, -90, 3, "000"},
+	}
+
+	for _, tt := range tests {
+		if got := EncodeGeohash(tt.lon, tt.lat, tt.precision); got != tt.want {
+			t.Errorf("EncodeGeohash(%v, %v, %d) = %q, want %q", tt.lon, tt.lat, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeGeotile(t *testing.T) {
+	tests := []struct {
+		lon, lat float64
+		zoom     int
+		want     string
+	}{
+		{0.1, 0.1, 1, "1/1/0"},
+		{-0.1, -0.1, 1, "1/0/1"},
+		{180, -90, 2, "2/3/3"},
+		{-122.4194, 37.7749, 10, "10/163/395"},
+	}
+
+	for _, tt := range tests {
+		if got := EncodeGeotile(tt.lon, tt.lat, tt.zoom); got != tt.want {
+			t.Errorf("EncodeGeotile(%v, %v, %d) = %q, want %q", tt.lon, tt.lat, tt.zoom, got, tt.want)
+		}
+	}
+}
+
+func TestParseAggregationRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/aggregate?collections=sentinel-1&aggregations=total_count,+platform_frequency"+
+		"&datetime_frequency_interval=year&centroid_geotile_grid_frequency_precision=4", nil)
+	req, err := ParseAggregationRequest(r)
+	if err != nil {
+		t.Fatalf("ParseAggregationRequest() error = %v", err)
+	}
+	if strings.Join(req.Aggregations, ",") != "total_count,platform_frequency" {
+		t.Errorf("Aggregations = %v", req.Aggregations)
+	}
+	if req.DatetimeFrequencyInterval != IntervalYear {
+		t.Errorf("DatetimeFrequencyInterval = %q, want year", req.DatetimeFrequencyInterval)
+	}
+	if req.GeotilePrecision != 4 || req.GeohashPrecision != DefaultGeohashPrecision {
+		t.Errorf("precisions = %d/%d, want 4/%d", req.GeotilePrecision, req.GeohashPrecision, DefaultGeohashPrecision)
+	}
+	if len(req.Collections) != 1 || req.Collections[0] != "sentinel-1" {
+		t.Errorf("Collections = %v", req.Collections)
+	}
+
+	// Every aggregation is computed by default
+	req, err = ParseAggregationRequestBody(strings.NewReader(`{"filter": "platform = 'sentinel-1a'"}`))
+	if err != nil {
+		t.Fatalf("ParseAggregationRequestBody() error = %v", err)
+	}
+	if len(req.Aggregations) != len(SupportedAggregations) {
+		t.Errorf("Aggregations = %v, want all", req.Aggregations)
+	}
+	if req.DatetimeFrequencyInterval != DefaultDatetimeFrequencyInterval {
+		t.Errorf("DatetimeFrequencyInterval = %q", req.DatetimeFrequencyInterval)
+	}
+	if _, ok := req.Filter.(map[string]any); !ok {
+		t.Errorf("Filter = %v, want CQL2-JSON", req.Filter)
+	}
+
+	for _, body := range []string{
+		`{"aggregations": ["unknown"]}`,
+		`{"datetime_frequency_interval": "week"}`,
+		`{"centroid_geotile_grid_frequency_precision": 30}`,
+	} {
+		if _, err := ParseAggregationRequestBody(strings.NewReader(body)); err == nil {
+			t.Errorf("ParseAggregationRequestBody(%s) succeeded, want error", body)
+		}
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	req := &AggregationRequest{
+		SearchRequest: &SearchRequest{},
+		Aggregations:  []string{AggregationTotalCount, AggregationDatetimeFrequency, AggregationOrbitStateFrequency, AggregationGeohashGridFrequency},
+	}
+	if err := req.normalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	item := func(start string, orbitState string, bbox []float64) *Item {
+		return &Item{
+			Id:   start,
+			Bbox: bbox,
+			Properties: map[string]any{
+				"datetime":        start,
+				"start_datetime":  start,
+				"sat:orbit_state": orbitState,
+			},
+		}
+	}
+
+	a := NewAggregator(req)
+	a.Add(item("2024-01-31T23:59:59Z", "ascending", []float64{-5.7, 42.5, -5.5, 42.7}))
+	a.Add(item("2024-02-01T00:00:00Z", "ascending", []float64{-5.7, 42.5, -5.5, 42.7}))
+	a.Add(item("2024-02-15T00:00:00Z", "descending", []float64{179, 0, -179, 2}))
+	a.Add(&Item{Id: "bare", Properties: map[string]any{}})
+
+	if got := a.Result(AggregationTotalCount).Value; got != 4 {
+		t.Errorf("total_count = %v, want 4", got)
+	}
+
+	datetimes := a.Result(AggregationDatetimeFrequency).Buckets
+	if len(datetimes) != 2 || datetimes[0].Key != "2024-01-01T00:00:00Z" || datetimes[1].Key != "2024-02-01T00:00:00Z" ||
+		datetimes[1].Frequency != 2 {
+		t.Errorf("datetime buckets = %+v", datetimes)
+	}
+
+	states := a.Result(AggregationOrbitStateFrequency).Buckets
+	if len(states) != 2 || states[0].Key != "ascending" || states[0].Frequency != 2 {
+		t.Errorf("orbit state buckets = %+v", states)
+	}
+
+	// The antimeridian-crossing box is centered on 180 degrees
+	cells := a.Result(AggregationGeohashGridFrequency).Buckets
+	if len(cells) != 2 || cells[0].Key != "ezs" || cells[0].Frequency != 2 || cells[1].Key != EncodeGeohash(180, 1, 3) {
+		t.Errorf("geohash buckets = %+v", cells)
+	}
+}
+
+func TestTruncateToInterval(t *testing.T) {
+	ts := time.Date(2024, 5, 17, 13, 45, 10, 0, time.UTC)
+	tests := map[string]string{
+		IntervalYear:  "2024-01-01T00:00:00Z",
+		IntervalMonth: "2024-05-01T00:00:00Z",
+		IntervalDay:   "2024-05-17T00:00:00Z",
+		IntervalHour:  "2024-05-17T13:00:00Z",
+	}
+	for interval, want := range tests {
+		if got := TruncateToInterval(ts, interval).Format(time.RFC3339); got != want {
+			t.Errorf("TruncateToInterval(%s) = %s, want %s", interval, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package stac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// MinCursorSecretBytes is the minimum length of a cursor secret.
+const MinCursorSecretBytes = 32
+
+// sealedCursorVersion is the first byte of a sealed cursor
+const sealedCursorVersion = 2
+
+// sealedHeaderSize is the size of the version, key ID and issue time that
+// precede the nonce; they are authenticated along with the cursor
+const sealedHeaderSize = 1 + 4 + 8
+
+// CursorKeyring seals the cursors handed to clients with AES-256-GCM, so they
+// can be neither read nor altered. Cursors sealed with a previous key are
+// still accepted for a grace window after the key was rotated out, so secrets
+// can be rotated without breaking pagination in progress. The rotation time
+// is configured rather than taken from the clock, so the window ends at the
+// same instant on every replica however often they restart.
+type CursorKeyring struct {
+	keys  []cursorKey // the current key first
+	grace time.Duration
+	// ttl bounds the age of the cursors opened, maxBytes the compressed
+	// cursors kept inline for lack of a store; zero disables either
+	ttl      time.Duration
+	maxBytes int
+	now      func() time.Time
+}
+
+type cursorKey struct {
+	id   [4]byte
+	aead cipher.AEAD
+	// rotated is when a previous key was rotated out; zero for the current
+	rotated time.Time
+}
+
+// NewCursorKeyring creates a keyring sealing with the current secret and
+// opening cursors sealed with it or, for grace after the rotation time, with
+// one of the previous secrets.
+func NewCursorKeyring(current []byte, previous [][]byte, rotated time.Time, grace time.Duration) (*CursorKeyring, error) {
+	return newCursorKeyring(current, previous, rotated, grace, time.Now)
+}
+
+// newCursorKeyring creates a keyring reading the time from now
+func newCursorKeyring(current []byte, previous [][]byte, rotated time.Time, grace time.Duration, now func() time.Time) (*CursorKeyring, error) {
+	k := &CursorKeyring{grace: grace, now: now}
+	for i, secret := range append([][]byte{current}, previous...) {
+		key, err := newCursorKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			key.rotated = rotated
+		}
+		k.keys = append(k.keys, key)
+	}
+	return k, nil
+}
+
+// NewRandomCursorKeyring creates a keyring with a random key, for an instance
+// without a configured secret. Its cursors are only valid on this instance
+// and until it restarts.
+func NewRandomCursorKeyring() *CursorKeyring {
+	secret := make([]byte, MinCursorSecretBytes)
+	rand.Read(secret)
+	k, _ := NewCursorKeyring(secret, nil, time.Time{}, 0)
+	return k
+}
+
+// WithLimits makes the keyring reject cursors issued more than ttl ago and
+// compressed cursors larger than maxBytes that are kept inline. Zero disables
+// either limit.
+func (k *CursorKeyring) WithLimits(ttl time.Duration, maxBytes int) *CursorKeyring {
+	k.ttl = ttl
+	k.maxBytes = maxBytes
+	return k
+}
+
+// newCursorKey derives an AES-256 key and its ID from a secret
+func newCursorKey(secret []byte) (cursorKey, error) {
+	if len(secret) < MinCursorSecretBytes {
+		return cursorKey{}, fmt.Errorf("cursor secret must be at least %d bytes", MinCursorSecretBytes)
+	}
+	derived, err := hkdf.Key(sha256.New, secret, nil, "asf-stac-proxy cursor", 32)
+	if err != nil {
+		return cursorKey{}, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return cursorKey{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return cursorKey{}, err
+	}
+
+	key := cursorKey{aead: aead}
+	sum := sha256.Sum256(derived)
+	copy(key.id[:], sum[:])
+	return key, nil
+}
+
+// Seal encrypts and authenticates plaintext with the current key.
+func (k *CursorKeyring) Seal(plaintext []byte) []byte {
+	key := k.keys[0]
+
+	// version | key ID | issued (unix seconds) | nonce | ciphertext and tag
+	out := make([]byte, sealedHeaderSize, sealedHeaderSize+key.aead.NonceSize()+len(plaintext)+key.aead.Overhead())
+	out[0] = sealedCursorVersion
+	copy(out[1:5], key.id[:])
+	binary.BigEndian.PutUint64(out[5:sealedHeaderSize], uint64(k.now().Unix()))
+	nonce := make([]byte, key.aead.NonceSize())
+	rand.Read(nonce)
+	out = append(out, nonce...)
+	return key.aead.Seal(out, nonce, plaintext, out[:sealedHeaderSize])
+}
+
+// Open verifies and decrypts sealed, returning the plaintext and when it was
+// sealed. It fails with ErrCursorInvalid when sealed was altered or not sealed
+// by a key of the keyring, and with ErrCursorExpired when it was issued more
+// than the keyring's TTL ago or its key was rotated out more than the grace
+// window ago.
+func (k *CursorKeyring) Open(sealed []byte) ([]byte, time.Time, error) {
+	if len(sealed) < sealedHeaderSize || sealed[0] != sealedCursorVersion {
+		return nil, time.Time{}, ErrCursorInvalid
+	}
+	header, body := sealed[:sealedHeaderSize], sealed[sealedHeaderSize:]
+	issued := time.Unix(int64(binary.BigEndian.Uint64(header[5:])), 0)
+
+	for i, key := range k.keys {
+		if !bytes.Equal(key.id[:], header[1:5]) {
+			continue
+		}
+		n := key.aead.NonceSize()
+		if len(body) < n {
+			return nil, time.Time{}, ErrCursorInvalid
+		}
+		plaintext, err := key.aead.Open(nil, body[:n], body[n:], header)
+		if err != nil {
+			return nil, time.Time{}, ErrCursorInvalid
+		}
+		if i > 0 && k.now().Sub(key.rotated) > k.grace {
+			return nil, time.Time{}, ErrCursorExpired
+		}
+		if k.ttl > 0 && k.now().Sub(issued) > k.ttl {
+			return nil, time.Time{}, ErrCursorExpired
+		}
+		return plaintext, issued, nil
+	}
+	return nil, time.Time{}, ErrCursorInvalid
+}
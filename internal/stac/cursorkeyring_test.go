@@ -0,0 +1,243 @@
+package stac
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorKeyring_SealOpen(t *testing.T) {
+	keyring := testKeyring(t, testSecret)
+	plaintext := []byte(`{"st":"2024-01-15T12:00:00Z","d":"next"}`)
+
+	sealed := keyring.Seal(plaintext)
+	if bytes.Contains(sealed, plaintext) {
+		t.Error("sealed cursor contains the plaintext")
+	}
+	if bytes.Equal(sealed, keyring.Seal(plaintext)) {
+		t.Error("sealing twice gave the same output, nonce not random")
+	}
+
+	got, _, err := keyring.Open(sealed)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("Open() = %s, want %s", got, plaintext)
+	}
+
+	// Any altered byte, header included, is rejected
+	for i := range sealed {
+		altered := bytes.Clone(sealed)
+		altered[i] ^= 0x80
+		if _, _, err := keyring.Open(altered); !errors.Is(err, ErrCursorInvalid) {
+			t.Fatalf("Open() with byte %d altered error = %v, want ErrCursorInvalid", i, err)
+		}
+	}
+}
+
+func TestCursorKeyring_Rotation(t *testing.T) {
+	oldSecret := []byte("the secret before the rotation, 32+ bytes")
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	before := testKeyring(t, oldSecret)
+	before.now = func() time.Time { return now }
+	sealedOld := before.Seal([]byte("old"))
+
+	// The secret is rotated well after the old cursor was issued; the grace
+	// window starts from the configured rotation time
+	rotated := now.Add(3 * time.Hour)
+	now = rotated
+	after, err := newCursorKeyring(testSecret, [][]byte{oldSecret}, rotated, time.Hour, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("NewCursorKeyring() error = %v", err)
+	}
+
+	// New cursors are sealed with the current key, which the old keyring
+	// does not know
+	sealedNew := after.Seal([]byte("new"))
+	if _, _, err := before.Open(sealedNew); !errors.Is(err, ErrCursorInvalid) {
+		t.Errorf("old keyring Open() of a new cursor error = %v, want ErrCursorInvalid", err)
+	}
+
+	now = now.Add(59 * time.Minute)
+	if got, _, err := after.Open(sealedOld); err != nil || string(got) != "old" {
+		t.Errorf("Open() of an old cursor within the grace window = %q, %v", got, err)
+	}
+
+	// A replica started later, or a restart, does not extend the window
+	restarted, err := newCursorKeyring(testSecret, [][]byte{oldSecret}, rotated, time.Hour, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("NewCursorKeyring() error = %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	for name, keyring := range map[string]*CursorKeyring{"first": after, "restarted": restarted} {
+		if _, _, err := keyring.Open(sealedOld); !errors.Is(err, ErrCursorExpired) {
+			t.Errorf("%s keyring Open() of an old cursor after the grace window error = %v, want ErrCursorExpired", name, err)
+		}
+	}
+	if got, _, err := after.Open(sealedNew); err != nil || string(got) != "new" {
+		t.Errorf("Open() of a current cursor = %q, %v", got, err)
+	}
+
+	// Once the old secret is dropped its cursors are unknown
+	dropped := testKeyring(t, testSecret)
+	if _, _, err := dropped.Open(sealedOld); !errors.Is(err, ErrCursorInvalid) {
+		t.Errorf("Open() with the old secret dropped error = %v, want ErrCursorInvalid", err)
+	}
+}
+
+func TestNewCursorKeyring_ShortSecret(t *testing.T) {
+	if _, err := NewCursorKeyring([]byte("short"), nil, time.Time{}, 0); err == nil {
+		t.Error("expected an error for a short current secret")
+	}
+	if _, err := NewCursorKeyring(testSecret, [][]byte{[]byte("short")}, time.Time{}, 0); err == nil {
+		t.Error("expected an error for a short previous secret")
+	}
+}
+
+func TestEncodeCursorSealed(t *testing.T) {
+	keyring := testKeyring(t, testSecret)
+	cursor := &Cursor{StartTime: "2024-01-15T12:00:00Z", Direction: "next", SeenIDs: []string{"a", "b"}, Query: "abc"}
+
+	encoded, err := EncodeCursorSealed(cursor, keyring, nil)
+	if err != nil {
+		t.Fatalf("EncodeCursorSealed() error = %v", err)
+	}
+	got, err := DecodeCursorSealed(encoded, keyring, nil)
+	if err != nil {
+		t.Fatalf("DecodeCursorSealed() error = %v", err)
+	}
+	if got.StartTime != cursor.StartTime || got.Query != cursor.Query || len(got.SeenIDs) != 2 {
+		t.Errorf("DecodeCursorSealed() = %+v, want %+v", got, cursor)
+	}
+
+	// A plain cursor is refused when cursors are sealed
+	if _, err := DecodeCursorSealed(EncodeCursor(cursor), keyring, nil); !errors.Is(err, ErrCursorInvalid) {
+		t.Errorf("DecodeCursorSealed() of a plain cursor error = %v, want ErrCursorInvalid", err)
+	}
+
+	// Large cursors stay inline and sealed without a store, as with the
+	// signed store setting, and go to the store otherwise. IDs that do not
+	// compress well make the inline form too long
+	large := &Cursor{StartTime: "2024-01-15T12:00:00Z", Direction: "next"}
+	for i := 0; i < 200; i++ {
+		sum := sha256.Sum256([]byte{byte(i)})
+		large.SeenIDs = append(large.SeenIDs, hex.EncodeToString(sum[:8]))
+	}
+	encoded, err = EncodeCursorSealed(large, keyring, nil)
+	if err != nil {
+		t.Fatalf("EncodeCursorSealed() error = %v", err)
+	}
+	if IsServerSideCursor(encoded) || len(encoded) <= MaxInlineCursorSize {
+		t.Fatalf("expected a long inline cursor without a store, got %d bytes", len(encoded))
+	}
+	// A second replica sharing the secret decodes it
+	if got, err := DecodeCursorSealed(encoded, testKeyring(t, testSecret), nil); err != nil || len(got.SeenIDs) != 200 {
+		t.Errorf("DecodeCursorSealed() of a long inline cursor = %v, %v", got, err)
+	}
+
+	store := NewMemoryCursorStore(time.Hour, time.Minute)
+	defer store.Stop()
+	encoded, err = EncodeCursorSealed(large, keyring, store)
+	if err != nil {
+		t.Fatalf("EncodeCursorSealed() error = %v", err)
+	}
+	if !IsServerSideCursor(encoded) {
+		t.Fatalf("expected a server-side cursor for %d seen IDs", len(large.SeenIDs))
+	}
+	if got, err := DecodeCursorSealed(encoded, keyring, store); err != nil || len(got.SeenIDs) != 200 {
+		t.Errorf("DecodeCursorSealed() of a stored cursor = %v, %v", got, err)
+	}
+}
+
+func TestDecodeCursorSealed_Expired(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	keyring := testKeyring(t, testSecret).WithLimits(time.Hour, 0)
+	keyring.now = func() time.Time { return now }
+
+	encoded, err := EncodeCursorSealed(testCursor(5), keyring, nil)
+	if err != nil {
+		t.Fatalf("EncodeCursorSealed() error = %v", err)
+	}
+
+	now = now.Add(59 * time.Minute)
+	if _, err := DecodeCursorSealed(encoded, keyring, nil); err != nil {
+		t.Errorf("DecodeCursorSealed() within the TTL error = %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := DecodeCursorSealed(encoded, keyring, nil); !errors.Is(err, ErrCursorExpired) {
+		t.Errorf("DecodeCursorSealed() after the TTL error = %v, want ErrCursorExpired", err)
+	}
+}
+
+func TestEncodeCursorSealed_SizeLimit(t *testing.T) {
+	// IDs that do not compress well make the inline form too long
+	large := &Cursor{StartTime: "2024-01-15T12:00:00Z", Direction: "next"}
+	for i := 0; i < 200; i++ {
+		sum := sha256.Sum256([]byte{byte(i)})
+		large.SeenIDs = append(large.SeenIDs, hex.EncodeToString(sum[:8]))
+	}
+	limited := testKeyring(t, testSecret).WithLimits(0, 1024)
+
+	// Without a store a cursor over the limit is refused rather than inlined
+	if _, err := EncodeCursorSealed(large, limited, nil); !errors.Is(err, ErrCursorTooLarge) {
+		t.Errorf("EncodeCursorSealed() over the size limit error = %v, want ErrCursorTooLarge", err)
+	}
+
+	// A store still takes it
+	store := NewMemoryCursorStore(time.Hour, time.Minute)
+	defer store.Stop()
+	if encoded, err := EncodeCursorSealed(large, limited, store); err != nil || !IsServerSideCursor(encoded) {
+		t.Errorf("EncodeCursorSealed() with a store = %q, %v, want a server-side cursor", encoded, err)
+	}
+
+	// An oversized inline cursor, sealed by a replica without the limit, is
+	// rejected when decoded
+	encoded, err := EncodeCursorSealed(large, testKeyring(t, testSecret), nil)
+	if err != nil {
+		t.Fatalf("EncodeCursorSealed() error = %v", err)
+	}
+	if _, err := DecodeCursorSealed(encoded, limited, nil); !errors.Is(err, ErrCursorTooLarge) {
+		t.Errorf("DecodeCursorSealed() over the size limit error = %v, want ErrCursorTooLarge", err)
+	}
+}
+
+func TestDecodeCursorSealed_RejectsTampering(t *testing.T) {
+	keyring := testKeyring(t, testSecret)
+	encoded, err := EncodeCursorSealed(testCursor(5), keyring, nil)
+	if err != nil {
+		t.Fatalf("EncodeCursorSealed() error = %v", err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(encoded)
+
+	flipped := append([]byte(nil), raw...)
+	flipped[len(flipped)-1] ^= 0x01
+
+	other, err := EncodeCursorSealed(testCursor(5), testKeyring(t, []byte("another secret of at least 32 bytes")), nil)
+	if err != nil {
+		t.Fatalf("EncodeCursorSealed() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"flipped payload bit", base64.RawURLEncoding.EncodeToString(flipped)},
+		{"truncated", base64.RawURLEncoding.EncodeToString(raw[:20])},
+		{"not base64", "!!!"},
+		{"other secret", other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursorSealed(tt.encoded, keyring, nil); !errors.Is(err, ErrCursorInvalid) {
+				t.Errorf("DecodeCursorSealed() error = %v, want ErrCursorInvalid", err)
+			}
+		})
+	}
+}
@@ -28,7 +28,7 @@ type cursorEntry struct {
 
 // MemoryCursorStore implements CursorStore using in-memory storage with TTL.
 // This is suitable for single-instance deployments. For distributed deployments,
-// use Redis or another shared storage backend.
+// share a cursor secret and use no store, or a KVCursorStore backed by Redis.
 type MemoryCursorStore struct {
 	mu       sync.RWMutex
 	cursors  map[string]cursorEntry
@@ -0,0 +1,214 @@
+package stac
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Fields selects the item attributes returned by a search (STAC API Fields
+// extension). Paths are dotted, e.g. "properties.datetime" or
+// "assets.thumbnail.href".
+type Fields struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// DefaultFieldsInclude are the attributes returned when fields are requested
+// without an include list, keeping the result a valid STAC Item.
+var DefaultFieldsInclude = []string{
+	"type",
+	"stac_version",
+	"id",
+	"geometry",
+	"bbox",
+	"links",
+	"assets",
+	"collection",
+	"properties.datetime",
+}
+
+// parseFieldsParam parses the fields query parameter
+// Format: fields=id,properties.datetime,-assets (- excludes, + or no prefix includes)
+func parseFieldsParam(fieldsStr string) (*Fields, error) {
+	fields := &Fields{}
+	for _, field := range strings.Split(fieldsStr, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if strings.HasPrefix(field, "-") {
+			fields.Exclude = append(fields.Exclude, field[1:])
+		} else {
+			fields.Include = append(fields.Include, strings.TrimPrefix(field, "+"))
+		}
+	}
+	return fields, fields.validate()
+}
+
+// validate checks that every path is a well-formed dotted path
+func (f *Fields) validate() error {
+	for _, path := range append(append([]string{}, f.Include...), f.Exclude...) {
+		for _, segment := range strings.Split(path, ".") {
+			if segment == "" {
+				return fmt.Errorf("invalid field path %q", path)
+			}
+		}
+	}
+	return nil
+}
+
+// queryValue encodes the fields in the GET parameter form
+func (f *Fields) queryValue() string {
+	parts := make([]string, 0, len(f.Include)+len(f.Exclude))
+	parts = append(parts, f.Include...)
+	for _, path := range f.Exclude {
+		parts = append(parts, "-"+path)
+	}
+	return strings.Join(parts, ",")
+}
+
+// fieldRule is an include or exclude path split into segments
+type fieldRule struct {
+	path    []string
+	include bool
+}
+
+// fieldSelector decides which attributes of a serialized item are kept.
+//
+// Following the Fields extension, a path is governed by the most specific
+// include or exclude rule that covers it, and a path that is both included
+// and excluded is included. Paths no rule covers are kept when only
+// exclusions were given, and otherwise kept only if DefaultFieldsInclude
+// covers them.
+type fieldSelector struct {
+	rules    []fieldRule
+	defaults []fieldRule
+	keepRest bool
+}
+
+func newFieldSelector(f *Fields) *fieldSelector {
+	s := &fieldSelector{keepRest: len(f.Include) == 0 && len(f.Exclude) > 0}
+	for _, path := range f.Exclude {
+		s.rules = append(s.rules, fieldRule{path: strings.Split(path, ".")})
+	}
+	for _, path := range f.Include {
+		s.rules = append(s.rules, fieldRule{path: strings.Split(path, "."), include: true})
+	}
+	if !s.keepRest {
+		for _, path := range DefaultFieldsInclude {
+			s.defaults = append(s.defaults, fieldRule{path: strings.Split(path, "."), include: true})
+		}
+	}
+	return s
+}
+
+// keep reports whether the attribute at path is kept as a whole
+func (s *fieldSelector) keep(path []string) bool {
+	depth := -1
+	keep := false
+	for _, rule := range s.rules {
+		if !hasPathPrefix(path, rule.path) {
+			continue
+		}
+		if len(rule.path) > depth || (len(rule.path) == depth && rule.include) {
+			depth = len(rule.path)
+			keep = rule.include
+		}
+	}
+	if depth >= 0 {
+		return keep
+	}
+	if s.keepRest {
+		return true
+	}
+	for _, rule := range s.defaults {
+		if hasPathPrefix(path, rule.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// refined reports whether any rule selects attributes below path
+func (s *fieldSelector) refined(path []string) bool {
+	for _, rules := range [][]fieldRule{s.rules, s.defaults} {
+		for _, rule := range rules {
+			if len(rule.path) > len(path) && hasPathPrefix(rule.path, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// apply returns the selected attributes of the object at path, and whether
+// anything was selected
+func (s *fieldSelector) apply(object map[string]any, path []string) (map[string]any, bool) {
+	result := make(map[string]any)
+	for key, value := range object {
+		child := append(path[:len(path):len(path)], key)
+		if nested, ok := value.(map[string]any); ok && s.refined(child) {
+			selected, found := s.apply(nested, child)
+			if found || s.keep(child) {
+				result[key] = selected
+			}
+			continue
+		}
+		if s.keep(child) {
+			result[key] = value
+		}
+	}
+	return result, len(result) > 0
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Select returns the serialized form of v reduced to the selected fields.
+func (f *Fields) Select(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	selected, _ := newFieldSelector(f).apply(object, nil)
+	return selected, nil
+}
+
+// fieldsItemCollection is an ItemCollection whose features were reduced by
+// the Fields extension
+type fieldsItemCollection struct {
+	*ItemCollection
+	Features []map[string]any `json:"features"`
+}
+
+// WithFields returns the item collection with every feature reduced to the
+// selected fields, for serialization. A nil Fields returns the collection
+// unchanged.
+func (ic *ItemCollection) WithFields(f *Fields) (any, error) {
+	if f == nil {
+		return ic, nil
+	}
+	features := make([]map[string]any, 0, len(ic.Features))
+	for _, item := range ic.Features {
+		selected, err := f.Select(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select fields of item %s: %w", item.Id, err)
+		}
+		features = append(features, selected)
+	}
+	return &fieldsItemCollection{ItemCollection: ic, Features: features}, nil
+}
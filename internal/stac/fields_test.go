@@ -0,0 +1,146 @@
+package stac
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const fieldsTestItem = `{
+	"type": "Feature",
+	"stac_version": "1.0.0",
+	"id": "S1A_IW_SLC",
+	"collection": "sentinel-1",
+	"geometry": {"type": "Point", "coordinates": [0, 0]},
+	"bbox": [-1, -1, 1, 1],
+	"properties": {
+		"datetime": "2024-01-15T12:00:00Z",
+		"platform": "sentinel-1a",
+		"sar:instrument_mode": "IW",
+		"sat:relative_orbit": 64
+	},
+	"assets": {
+		"data": {"href": "https://example.com/data.zip", "type": "application/zip"},
+		"thumbnail": {"href": "https://example.com/thumb.png", "type": "image/png"}
+	},
+	"links": [{"rel": "self", "href": "https://example.com/item"}]
+}`
+
+// fieldsTestPaths lists the leaf paths of a selected item, with objects
+// reduced to their keys
+func fieldsTestPaths(v map[string]any, prefix string) []string {
+	var paths []string
+	for key, value := range v {
+		path := prefix + key
+		if nested, ok := value.(map[string]any); ok && key != "geometry" {
+			paths = append(paths, fieldsTestPaths(nested, path+".")...)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestFields_Select(t *testing.T) {
+	var item map[string]any
+	if err := json.Unmarshal([]byte(fieldsTestItem), &item); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		fields Fields
+		want   []string
+	}{
+		{
+			name:   "empty selects the defaults",
+			fields: Fields{},
+			want: []string{"type", "stac_version", "id", "collection", "geometry", "bbox", "properties.datetime",
+				"assets.data.href", "assets.data.type", "assets.thumbnail.href", "assets.thumbnail.type", "links"},
+		},
+		{
+			name:   "include adds to the defaults",
+			fields: Fields{Include: []string{"properties.platform"}},
+			want: []string{"type", "stac_version", "id", "collection", "geometry", "bbox", "properties.datetime", "properties.platform",
+				"assets.data.href", "assets.data.type", "assets.thumbnail.href", "assets.thumbnail.type", "links"},
+		},
+		{
+			name:   "footprint only",
+			fields: Fields{Include: []string{"id", "geometry", "properties.datetime"}, Exclude: []string{"type", "stac_version", "collection", "bbox", "assets", "links"}},
+			want:   []string{"id", "geometry", "properties.datetime"},
+		},
+		{
+			name:   "exclude only removes from the full item",
+			fields: Fields{Exclude: []string{"assets", "links", "properties.sat:relative_orbit"}},
+			want:   []string{"type", "stac_version", "id", "collection", "geometry", "bbox", "properties.datetime", "properties.platform", "properties.sar:instrument_mode"},
+		},
+		{
+			name:   "excluded sub-field of an included field",
+			fields: Fields{Include: []string{"properties"}, Exclude: []string{"properties.platform", "assets", "links"}},
+			want:   []string{"type", "stac_version", "id", "collection", "geometry", "bbox", "properties.datetime", "properties.sar:instrument_mode", "properties.sat:relative_orbit"},
+		},
+		{
+			name:   "included sub-field of an excluded field",
+			fields: Fields{Include: []string{"assets.thumbnail.href"}, Exclude: []string{"assets"}},
+			want:   []string{"type", "stac_version", "id", "collection", "geometry", "bbox", "properties.datetime", "assets.thumbnail.href", "links"},
+		},
+		{
+			name:   "include wins over the same exclude",
+			fields: Fields{Include: []string{"properties.platform"}, Exclude: []string{"properties.platform", "properties.datetime"}},
+			want: []string{"type", "stac_version", "id", "collection", "geometry", "bbox", "properties.platform",
+				"assets.data.href", "assets.data.type", "assets.thumbnail.href", "assets.thumbnail.type", "links"},
+		},
+		{
+			name:   "unknown paths are ignored",
+			fields: Fields{Include: []string{"id", "properties.eo:cloud_cover"}, Exclude: []string{"type", "stac_version", "collection", "geometry", "bbox", "assets", "links", "properties.datetime"}},
+			want:   []string{"id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := tt.fields.Select(item)
+			if err != nil {
+				t.Fatalf("Select() error: %v", err)
+			}
+			got := fieldsTestPaths(selected, "")
+			want := append([]string{}, tt.want...)
+			sort.Strings(got)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Select() paths = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseSearchRequest_Fields(t *testing.T) {
+	r := httptest.NewRequest("GET", "/search?fields="+url.QueryEscape("id, +geometry,-assets,properties.datetime"), nil)
+	req, err := ParseSearchRequest(r)
+	if err != nil {
+		t.Fatalf("ParseSearchRequest() error: %v", err)
+	}
+	want := &Fields{Include: []string{"id", "geometry", "properties.datetime"}, Exclude: []string{"assets"}}
+	if !reflect.DeepEqual(req.Fields, want) {
+		t.Errorf("Fields = %+v, want %+v", req.Fields, want)
+	}
+
+	// Preserved for pagination links of POST searches
+	if got := req.ToQueryParams().Get("fields"); got != "id,geometry,properties.datetime,-assets" {
+		t.Errorf("ToQueryParams() fields = %q", got)
+	}
+
+	r = httptest.NewRequest("GET", "/search?fields=properties..datetime", nil)
+	if _, err := ParseSearchRequest(r); err == nil {
+		t.Error("Expected an error for an empty path segment")
+	}
+
+	body := `{"fields": {"include": ["id"], "exclude": ["properties."]}}`
+	if _, err := ParseSearchRequestBody(strings.NewReader(body)); err == nil {
+		t.Error("Expected an error for an empty path segment in a POST body")
+	}
+}
@@ -160,8 +160,13 @@ const (
 	ConformanceOGCFeatures   = "https://api.stacspec.org/v1.0.0/ogcapi-features"
 	ConformanceItemSearch    = "https://api.stacspec.org/v1.0.0/item-search"
 	ConformanceFilter        = "https://api.stacspec.org/v1.0.0/item-search#filter"
+	ConformanceSort          = "https://api.stacspec.org/v1.0.0/item-search#sort"
+	ConformanceFields        = "https://api.stacspec.org/v1.0.0/item-search#fields"
+	ConformanceFeaturesFields = "https://api.stacspec.org/v1.0.0/ogcapi-features#fields"
+	ConformanceAggregation   = "https://api.stacspec.org/v0.3.0/aggregation"
 	ConformanceOGCFeatCore   = "http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/core"
 	ConformanceOGCFeatGeoJSON = "http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/geojson"
+	ConformanceOGCFeatOAS30   = "http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/oas30"
 )
 
 // DefaultConformance returns the default conformance classes for the proxy.
@@ -170,8 +175,13 @@ func DefaultConformance() []string {
 		ConformanceCore,
 		ConformanceOGCFeatures,
 		ConformanceItemSearch,
+		ConformanceSort,
+		ConformanceFields,
+		ConformanceFeaturesFields,
+		ConformanceAggregation,
 		ConformanceOGCFeatCore,
 		ConformanceOGCFeatGeoJSON,
+		ConformanceOGCFeatOAS30,
 	}
 }
 
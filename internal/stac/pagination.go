@@ -26,9 +26,21 @@ type Cursor struct {
 	StartTime string `json:"st"`
 	// Direction: "next" for forward pagination, "prev" for backward
 	Direction string `json:"d"`
+	// Ascending is true when results are ordered oldest first. The next page
+	// then holds items with startTime >= StartTime instead.
+	Ascending bool `json:"asc,omitempty"`
 	// SeenIDs contains IDs of items at the boundary timestamp that have already been returned.
 	// This prevents duplicates when multiple items share the same start_datetime.
 	SeenIDs []string `json:"seen,omitempty"`
+	// SearchAfter is the upstream pagination token for backends with native
+	// pagination (CMR-Search-After). When set, the time-window fields are unused.
+	SearchAfter string `json:"sa,omitempty"`
+	// PairAfter is the last InSAR pair of a page; the next page holds the
+	// pairs after it. The time-window fields are then unused.
+	PairAfter *PairKey `json:"pa,omitempty"`
+	// Query is the fingerprint of the search the cursor pages through, see
+	// SearchRequest.Fingerprint. A cursor may not be used with another search.
+	Query string `json:"q,omitempty"`
 }
 
 // EncodeCursor encodes a cursor to a URL-safe string.
@@ -49,16 +61,36 @@ func EncodeCursor(cursor *Cursor) string {
 // EncodeCursorWithStore encodes a cursor, using server-side storage if the cursor is too large.
 // Returns the encoded cursor string (either base64 or "ref:<token>").
 func EncodeCursorWithStore(cursor *Cursor, store CursorStore) (string, error) {
+	return EncodeCursorSealed(cursor, nil, store)
+}
+
+// EncodeCursorSealed encodes a cursor like EncodeCursorWithStore, sealing an
+// inline cursor with keyring so clients can neither read nor alter it. Without
+// a keyring an inline cursor is plain base64 JSON. A long cursor that cannot
+// be stored is kept inline, and fails with ErrCursorTooLarge when it exceeds
+// the keyring's size limit.
+func EncodeCursorSealed(cursor *Cursor, keyring *CursorKeyring, store CursorStore) (string, error) {
 	if cursor == nil {
 		return "", nil
 	}
 
 	// First, try inline encoding
-	data, err := json.Marshal(cursor)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal cursor: %w", err)
+	var encoded string
+	var payload []byte
+	if keyring != nil {
+		var err error
+		payload, err = compressCursor(cursor)
+		if err != nil {
+			return "", err
+		}
+		encoded = base64.RawURLEncoding.EncodeToString(keyring.Seal(payload))
+	} else {
+		data, err := json.Marshal(cursor)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal cursor: %w", err)
+		}
+		encoded = base64.URLEncoding.EncodeToString(data)
 	}
-	encoded := base64.URLEncoding.EncodeToString(data)
 
 	// If small enough, use inline encoding
 	if len(encoded) <= MaxInlineCursorSize {
@@ -66,18 +98,18 @@ func EncodeCursorWithStore(cursor *Cursor, store CursorStore) (string, error) {
 	}
 
 	// Cursor is too large - store server-side
-	if store == nil {
-		// No store available, fall back to inline (may cause issues with very long URLs)
-		return encoded, nil
+	if store != nil {
+		if token, err := store.Store(cursor); err == nil {
+			return ServerSideCursorPrefix + token, nil
+		}
 	}
 
-	token, err := store.Store(cursor)
-	if err != nil {
-		// Fall back to inline on store error
-		return encoded, nil
+	// No store available, or it failed: fall back to inline up to the size
+	// limit, so URLs do not grow without bound
+	if keyring != nil && keyring.maxBytes > 0 && len(payload) > keyring.maxBytes {
+		return "", fmt.Errorf("%w: %d compressed bytes, limit %d", ErrCursorTooLarge, len(payload), keyring.maxBytes)
 	}
-
-	return ServerSideCursorPrefix + token, nil
+	return encoded, nil
 }
 
 // DecodeCursor decodes a cursor from a URL-safe string
@@ -98,6 +130,14 @@ func DecodeCursor(encoded string) (*Cursor, error) {
 
 // DecodeCursorWithStore decodes a cursor, retrieving from server-side storage if needed.
 func DecodeCursorWithStore(encoded string, store CursorStore) (*Cursor, error) {
+	return DecodeCursorSealed(encoded, nil, store)
+}
+
+// DecodeCursorSealed decodes a cursor encoded by EncodeCursorSealed. With a
+// keyring, an inline cursor that was not sealed by it is rejected with
+// ErrCursorInvalid, one issued more than its TTL ago with ErrCursorExpired and
+// one over its size limit with ErrCursorTooLarge.
+func DecodeCursorSealed(encoded string, keyring *CursorKeyring, store CursorStore) (*Cursor, error) {
 	if encoded == "" {
 		return nil, nil
 	}
@@ -112,7 +152,21 @@ func DecodeCursorWithStore(encoded string, store CursorStore) (*Cursor, error) {
 	}
 
 	// Inline cursor - decode normally
-	return DecodeCursor(encoded)
+	if keyring == nil {
+		return DecodeCursor(encoded)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrCursorInvalid
+	}
+	payload, _, err := keyring.Open(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if keyring.maxBytes > 0 && len(payload) > keyring.maxBytes {
+		return nil, ErrCursorTooLarge
+	}
+	return decompressCursor(payload)
 }
 
 // IsServerSideCursor returns true if the cursor string references server-side storage.
@@ -140,9 +194,15 @@ type CursorPaginationInfo struct {
 	// CursorStore is used for server-side cursor storage when cursors are too large.
 	// If nil, cursors are always encoded inline (may cause URL length issues).
 	CursorStore CursorStore
+	// Keyring seals inline cursors. If nil, they are plain base64 JSON.
+	Keyring *CursorKeyring
+	// Query is the fingerprint of the search, recorded in the next cursor
+	Query string
 	// BackendHasMoreData indicates the backend returned a full page of results,
 	// suggesting more data exists. Used for pagination decisions after filtering.
 	BackendHasMoreData bool
+	// Ascending is true when results are ordered oldest first
+	Ascending bool
 }
 
 // BuildCursorPaginationLinks generates next/prev links using cursor-based pagination
@@ -154,58 +214,11 @@ func BuildCursorPaginationLinks(info CursorPaginationInfo) []*Link {
 	// 2. We returned a full page after filtering (fallback for backward compatibility)
 	hasMoreData := info.BackendHasMoreData || info.ReturnedCount >= info.Limit
 	if hasMoreData && len(info.Items) > 0 {
-		// Find the MINIMUM startTime from all items on this page
-		// This is important because ASF's ordering may not be strictly by startTime
-		minTime := info.Items[0].StartTime
-		for _, item := range info.Items {
-			if item.StartTime.Before(minTime) {
-				minTime = item.StartTime
-			}
-		}
-
-		// Collect all item IDs at the minimum timestamp (items with the same start_datetime)
-		// These need to be tracked in the cursor to avoid returning them again
-		var boundaryIDs []string
-		for _, item := range info.Items {
-			if item.StartTime.Equal(minTime) {
-				boundaryIDs = append(boundaryIDs, item.ID)
-			}
-		}
-
-		// IMPORTANT: If the cursor timestamp hasn't changed, we need to ACCUMULATE
-		// the SeenIDs from the previous cursor. This handles the case where more items
-		// share the same timestamp than fit on a single page.
-		// Example: 300 items at T1, page size 250
-		//   - Page 1: returns 250, cursor has seen=[250 IDs], st=T1
-		//   - Page 2: returns 50 (filtered from 300), must keep all 300 IDs in cursor
-		if info.CurrentCursor != nil && info.CurrentCursor.StartTime != "" {
-			prevCursorTime, err := time.Parse(time.RFC3339, info.CurrentCursor.StartTime)
-			if err == nil && prevCursorTime.Equal(minTime) {
-				// Same timestamp - accumulate SeenIDs from previous cursor
-				// Use a map to deduplicate
-				seenSet := make(map[string]bool)
-				for _, id := range info.CurrentCursor.SeenIDs {
-					seenSet[id] = true
-				}
-				for _, id := range boundaryIDs {
-					seenSet[id] = true
-				}
-				// Rebuild boundaryIDs with all accumulated IDs
-				boundaryIDs = make([]string, 0, len(seenSet))
-				for id := range seenSet {
-					boundaryIDs = append(boundaryIDs, id)
-				}
-			}
-		}
-
-		cursor := &Cursor{
-			StartTime: minTime.Format(time.RFC3339),
-			Direction: "next",
-			SeenIDs:   boundaryIDs,
-		}
+		cursor := NextCursorInOrder(info.Items, info.CurrentCursor, info.Ascending)
+		cursor.Query = info.Query
 
 		// Build URL with cursor (may use server-side storage for large cursors)
-		nextURL := buildCursorURLWithStore(info.BaseURL, info.QueryParams, cursor, info.Limit, info.CursorStore)
+		nextURL := buildCursorURLWithStore(info.BaseURL, info.QueryParams, cursor, info.Limit, info.Keyring, info.CursorStore)
 		links = append(links, &Link{
 			Rel:  "next",
 			Href: nextURL,
@@ -220,14 +233,85 @@ func BuildCursorPaginationLinks(info CursorPaginationInfo) []*Link {
 	return links
 }
 
+// NextCursor builds the cursor that continues after the given items in
+// descending order. It returns nil when items is empty.
+func NextCursor(items []ItemTimeInfo, current *Cursor) *Cursor {
+	return NextCursorInOrder(items, current, false)
+}
+
+// NextCursorInOrder builds the cursor that continues after the given items.
+// In descending order the next page starts at the oldest item, in ascending
+// order at the newest. It returns nil when items is empty.
+func NextCursorInOrder(items []ItemTimeInfo, current *Cursor, ascending bool) *Cursor {
+	if len(items) == 0 {
+		return nil
+	}
+
+	// Find the boundary startTime from all items on this page: the MINIMUM
+	// for descending order, the MAXIMUM for ascending order
+	// This is important because ASF's ordering may not be strictly by startTime
+	boundary := items[0].StartTime
+	for _, item := range items {
+		if (ascending && item.StartTime.After(boundary)) || (!ascending && item.StartTime.Before(boundary)) {
+			boundary = item.StartTime
+		}
+	}
+
+	// The cursor has one-second precision, so the next page includes every
+	// item within the boundary second. Collect the IDs of all items on this page
+	// in that second (items with the same start_datetime); these need to be
+	// tracked in the cursor to avoid returning them again
+	boundary = boundary.Truncate(time.Second)
+	var boundaryIDs []string
+	for _, item := range items {
+		if item.StartTime.Truncate(time.Second).Equal(boundary) {
+			boundaryIDs = append(boundaryIDs, item.ID)
+		}
+	}
+
+	// IMPORTANT: If the cursor timestamp hasn't changed, we need to ACCUMULATE
+	// the SeenIDs from the previous cursor. This handles the case where more items
+	// share the same timestamp than fit on a single page.
+	// Example: 300 items at T1, page size 250
+	//   - Page 1: returns 250, cursor has seen=[250 IDs], st=T1
+	//   - Page 2: returns 50 (filtered from 300), must keep all 300 IDs in cursor
+	if current != nil && current.StartTime != "" {
+		prevCursorTime, err := time.Parse(time.RFC3339, current.StartTime)
+		if err == nil && prevCursorTime.Equal(boundary) {
+			// Same timestamp - accumulate SeenIDs from previous cursor
+			// Use a map to deduplicate
+			seenSet := make(map[string]bool)
+			for _, id := range current.SeenIDs {
+				seenSet[id] = true
+			}
+			for _, id := range boundaryIDs {
+				seenSet[id] = true
+			}
+			// Rebuild boundaryIDs with all accumulated IDs
+			boundaryIDs = make([]string, 0, len(seenSet))
+			for id := range seenSet {
+				boundaryIDs = append(boundaryIDs, id)
+			}
+		}
+	}
+
+	return &Cursor{
+		StartTime: boundary.Format(time.RFC3339),
+		Direction: "next",
+		SeenIDs:   boundaryIDs,
+		Ascending: ascending,
+	}
+}
+
 // buildCursorURL constructs a URL with the cursor parameter (always inline).
 func buildCursorURL(baseURL string, params url.Values, cursor *Cursor, limit int) string {
-	return buildCursorURLWithStore(baseURL, params, cursor, limit, nil)
+	return buildCursorURLWithStore(baseURL, params, cursor, limit, nil, nil)
 }
 
-// buildCursorURLWithStore constructs a URL with the cursor parameter.
-// If the cursor is large and a store is provided, it will use server-side storage.
-func buildCursorURLWithStore(baseURL string, params url.Values, cursor *Cursor, limit int, store CursorStore) string {
+// buildCursorURLWithStore constructs a URL with the cursor parameter, sealed
+// with keyring if one is provided. If the cursor is large and a store is
+// provided, it will use server-side storage.
+func buildCursorURLWithStore(baseURL string, params url.Values, cursor *Cursor, limit int, keyring *CursorKeyring, store CursorStore) string {
 	// Clone the params to avoid modifying the original
 	newParams := url.Values{}
 	for key, values := range params {
@@ -242,7 +326,7 @@ func buildCursorURLWithStore(baseURL string, params url.Values, cursor *Cursor,
 
 	// Add the cursor (may use server-side storage for large cursors)
 	if cursor != nil {
-		encoded, err := EncodeCursorWithStore(cursor, store)
+		encoded, err := EncodeCursorSealed(cursor, keyring, store)
 		if err == nil && encoded != "" {
 			newParams.Set("cursor", encoded)
 		}
@@ -288,6 +372,26 @@ func ApplyCursorToDatetime(cursor *Cursor, existingEnd *time.Time) *time.Time {
 	return existingEnd
 }
 
+// ApplyCursorToStart modifies the datetime range for an ascending cursor.
+// Returns the new start time constraint for the query: the later of
+// existingStart and the cursor time. Items at the cursor timestamp are
+// included; SeenIDs will filter duplicates.
+func ApplyCursorToStart(cursor *Cursor, existingStart *time.Time) *time.Time {
+	if cursor == nil || cursor.StartTime == "" {
+		return existingStart
+	}
+
+	cursorTime, err := time.Parse(time.RFC3339, cursor.StartTime)
+	if err != nil {
+		return existingStart
+	}
+
+	if existingStart == nil || cursorTime.After(*existingStart) {
+		return &cursorTime
+	}
+	return existingStart
+}
+
 // FilterSeenItems removes items that are in the cursor's SeenIDs list
 // This is called after fetching results to eliminate duplicates from the previous page
 func FilterSeenItems[T any](items []T, getID func(T) string, cursor *Cursor) []T {
@@ -552,3 +552,51 @@ func TestBuildCursorPaginationLinks_LastPageAfterFiltering(t *testing.T) {
 		t.Errorf("Expected no links on last page, got %d", len(links))
 	}
 }
+
+func TestNextCursorInOrder_Ascending(t *testing.T) {
+	baseTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	items := []ItemTimeInfo{
+		{ID: "a", StartTime: baseTime},
+		{ID: "b", StartTime: baseTime.Add(time.Minute)},
+		{ID: "c", StartTime: baseTime.Add(time.Minute + 500*time.Millisecond)},
+		{ID: "d", StartTime: baseTime.Add(30 * time.Second)},
+	}
+
+	cursor := NextCursorInOrder(items, nil, true)
+	if !cursor.Ascending {
+		t.Error("Expected an ascending cursor")
+	}
+	if cursor.StartTime != "2024-01-15T12:01:00Z" {
+		t.Errorf("Expected cursor at the newest item, got %s", cursor.StartTime)
+	}
+	// Both items within the boundary second are seen, since the next page
+	// starts at the whole second
+	if len(cursor.SeenIDs) != 2 {
+		t.Errorf("Expected SeenIDs [b c], got %v", cursor.SeenIDs)
+	}
+
+	descending := NextCursor(items, nil)
+	if descending.Ascending || descending.StartTime != "2024-01-15T12:00:00Z" {
+		t.Errorf("Expected a descending cursor at the oldest item, got %+v", descending)
+	}
+}
+
+func TestApplyCursorToStart(t *testing.T) {
+	cursor := &Cursor{StartTime: "2024-01-15T12:00:00Z", Ascending: true}
+	cursorTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	earlier := cursorTime.Add(-time.Hour)
+	later := cursorTime.Add(time.Hour)
+
+	if got := ApplyCursorToStart(cursor, nil); got == nil || !got.Equal(cursorTime) {
+		t.Errorf("ApplyCursorToStart(nil) = %v, want %v", got, cursorTime)
+	}
+	if got := ApplyCursorToStart(cursor, &earlier); !got.Equal(cursorTime) {
+		t.Errorf("ApplyCursorToStart(earlier) = %v, want %v", got, cursorTime)
+	}
+	if got := ApplyCursorToStart(cursor, &later); !got.Equal(later) {
+		t.Errorf("ApplyCursorToStart(later) = %v, want %v", got, later)
+	}
+	if got := ApplyCursorToStart(nil, &earlier); got != &earlier {
+		t.Errorf("ApplyCursorToStart(nil cursor) = %v, want %v", got, earlier)
+	}
+}
@@ -0,0 +1,356 @@
+package stac
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/pkg/geojson"
+)
+
+// DefaultMaxTemporalBaseline is the temporal baseline limit, in days, of
+// pair searches that do not set one
+const DefaultMaxTemporalBaseline = 48
+
+// Properties identifying the track and frame of an item, whose items share
+// an imaging geometry and can form interferograms
+const (
+	RelativeOrbitProperty = "sat:relative_orbit"
+	FrameProperty         = "asf:frame"
+)
+
+// PairsRequest is a search request with the constraints that candidate
+// interferogram pairs of the matching items must meet
+type PairsRequest struct {
+	*SearchRequest
+
+	// MaxTemporalBaseline is the longest time between the acquisitions of a
+	// pair, in days
+	MaxTemporalBaseline int `json:"max_temporal_baseline,omitempty"`
+	// MaxPerpendicularBaseline is the largest perpendicular baseline of a
+	// pair, in meters. Unset, perpendicular baselines are not checked.
+	MaxPerpendicularBaseline *float64 `json:"max_perpendicular_baseline,omitempty"`
+}
+
+// ParsePairsRequest parses a pairs request from GET query parameters
+func ParsePairsRequest(r *http.Request) (*PairsRequest, error) {
+	search, err := ParseSearchRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	req := &PairsRequest{SearchRequest: search}
+
+	query := r.URL.Query()
+	if value := query.Get("max_temporal_baseline"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_temporal_baseline parameter: %w", err)
+		}
+		req.MaxTemporalBaseline = days
+	}
+	if value := query.Get("max_perpendicular_baseline"); value != "" {
+		meters, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_perpendicular_baseline parameter: %w", err)
+		}
+		req.MaxPerpendicularBaseline = &meters
+	}
+
+	return req, req.normalize()
+}
+
+// ParsePairsRequestBody parses a pairs request from a POST JSON body
+func ParsePairsRequestBody(body io.Reader) (*PairsRequest, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pairs request body: %w", err)
+	}
+
+	search, err := ParseSearchRequestBody(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var req PairsRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse pairs request body: %w", err)
+	}
+	req.SearchRequest = search
+
+	return &req, req.normalize()
+}
+
+// normalize applies defaults and validates the pairing constraints
+func (req *PairsRequest) normalize() error {
+	if req.MaxTemporalBaseline == 0 {
+		req.MaxTemporalBaseline = DefaultMaxTemporalBaseline
+	}
+	if req.MaxTemporalBaseline < 0 {
+		return fmt.Errorf("max_temporal_baseline must be positive, got %d", req.MaxTemporalBaseline)
+	}
+	if req.MaxPerpendicularBaseline != nil && *req.MaxPerpendicularBaseline < 0 {
+		return fmt.Errorf("max_perpendicular_baseline must not be negative, got %g", *req.MaxPerpendicularBaseline)
+	}
+	return nil
+}
+
+// Fingerprint identifies the pairs a request finds, like
+// SearchRequest.Fingerprint, so cursors stay bound to their request.
+func (req *PairsRequest) Fingerprint() string {
+	data, _ := json.Marshal(struct {
+		Search        string   `json:"search"`
+		Temporal      int      `json:"temporal"`
+		Perpendicular *float64 `json:"perpendicular,omitempty"`
+	}{req.SearchRequest.Fingerprint(""), req.MaxTemporalBaseline, req.MaxPerpendicularBaseline})
+
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// ToQueryParams converts a PairsRequest to URL query parameters, for the
+// pagination links of POST requests.
+func (req *PairsRequest) ToQueryParams() url.Values {
+	params := req.SearchRequest.ToQueryParams()
+	params.Set("max_temporal_baseline", strconv.Itoa(req.MaxTemporalBaseline))
+	if req.MaxPerpendicularBaseline != nil {
+		params.Set("max_perpendicular_baseline", strconv.FormatFloat(*req.MaxPerpendicularBaseline, 'f', -1, 64))
+	}
+	return params
+}
+
+// Pair is a candidate interferogram: a reference item and a later secondary
+// item of the same track and frame whose footprints overlap
+type Pair struct {
+	ID                string    `json:"id"`
+	Collection        string    `json:"collection"`
+	Reference         string    `json:"reference"`
+	Secondary         string    `json:"secondary"`
+	ReferenceDatetime time.Time `json:"reference_datetime"`
+	SecondaryDatetime time.Time `json:"secondary_datetime"`
+	RelativeOrbit     any       `json:"sat:relative_orbit"`
+	Frame             any       `json:"asf:frame"`
+	// TemporalBaseline is the time between the acquisitions, in days
+	TemporalBaseline int `json:"insar:temporal_baseline"`
+	// PerpendicularBaseline is set when perpendicular baselines were checked
+	PerpendicularBaseline *float64 `json:"insar:perpendicular_baseline,omitempty"`
+	Links                 []*Link  `json:"links"`
+}
+
+// PairKey is the position of a pair in the order of FindPairs, after which
+// a page of pairs resumes
+type PairKey struct {
+	ReferenceDatetime time.Time `json:"r"`
+	SecondaryDatetime time.Time `json:"s"`
+	ID                string    `json:"id"`
+}
+
+// Key returns the position of the pair in the order of FindPairs.
+func (p *Pair) Key() PairKey {
+	return PairKey{ReferenceDatetime: p.ReferenceDatetime, SecondaryDatetime: p.SecondaryDatetime, ID: p.ID}
+}
+
+// Before reports whether k comes before other in the order of FindPairs.
+func (k PairKey) Before(other PairKey) bool {
+	if !k.ReferenceDatetime.Equal(other.ReferenceDatetime) {
+		return k.ReferenceDatetime.Before(other.ReferenceDatetime)
+	}
+	if !k.SecondaryDatetime.Equal(other.SecondaryDatetime) {
+		return k.SecondaryDatetime.Before(other.SecondaryDatetime)
+	}
+	return k.ID < other.ID
+}
+
+// PairsAfter returns the pairs, ordered as by FindPairs, that come after key.
+// Pairs of items added since key was taken are included if they come after
+// it, and no pair before it is repeated.
+func PairsAfter(pairs []*Pair, key PairKey) []*Pair {
+	i := sort.Search(len(pairs), func(i int) bool { return key.Before(pairs[i].Key()) })
+	return pairs[i:]
+}
+
+// AddLink adds a link to the pair
+func (p *Pair) AddLink(rel, href, mediaType string) {
+	p.Links = append(p.Links, &Link{
+		Rel:  rel,
+		Href: href,
+		Type: mediaType,
+	})
+}
+
+// PairCollection is the response of /pairs
+type PairCollection struct {
+	Type           string  `json:"type"`
+	Pairs          []*Pair `json:"pairs"`
+	Links          []*Link `json:"links"`
+	NumberMatched  *int    `json:"numberMatched,omitempty"`
+	NumberReturned int     `json:"numberReturned"`
+}
+
+// NewPairCollection creates a PairCollection with one page of pairs out of
+// matched in total, if known
+func NewPairCollection(pairs []*Pair, matched *int) *PairCollection {
+	return &PairCollection{
+		Type:           "PairCollection",
+		Pairs:          pairs,
+		Links:          []*Link{},
+		NumberMatched:  matched,
+		NumberReturned: len(pairs),
+	}
+}
+
+// AddLink adds a link to the pair collection
+func (pc *PairCollection) AddLink(rel, href, mediaType string) {
+	pc.Links = append(pc.Links, &Link{
+		Rel:  rel,
+		Href: href,
+		Type: mediaType,
+	})
+}
+
+// PerpendicularBaselines returns the perpendicular baselines in meters of
+// the items of one track and frame, by item ID, relative to any common
+// reference. Items without a baseline are left out.
+type PerpendicularBaselines func(group []*Item) (map[string]float64, error)
+
+// pairScene is an item with the values pairing needs
+type pairScene struct {
+	item     *Item
+	start    time.Time
+	geometry *geojson.Geometry
+}
+
+// FindPairs returns the pairs of items that meet the constraints of req,
+// ordered by reference and secondary acquisition and then ID. Items are
+// grouped by collection, relative orbit and frame; items missing any of
+// them, a start time or a geometry are left out. When req bounds the
+// perpendicular baseline, baselines is called once per group.
+func FindPairs(items []*Item, req *PairsRequest, baselines PerpendicularBaselines) ([]*Pair, error) {
+	groups := make(map[string][]*pairScene)
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		if seen[item.Id] {
+			continue
+		}
+		seen[item.Id] = true
+
+		orbit, frame := item.Properties[RelativeOrbitProperty], item.Properties[FrameProperty]
+		if item.Collection == "" || orbit == nil || frame == nil {
+			continue
+		}
+		start, ok := sortTime(item.Properties["start_datetime"])
+		if !ok {
+			if start, ok = sortTime(item.Properties["datetime"]); !ok {
+				continue
+			}
+		}
+		geometry, err := itemGeometry(item)
+		if err != nil {
+			continue
+		}
+		key := fmt.Sprintf("%s/%v/%v", item.Collection, orbit, frame)
+		groups[key] = append(groups[key], &pairScene{item: item, start: start, geometry: geometry})
+	}
+
+	// Visit groups in a fixed order, so baselines are requested in one
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []*Pair
+	for _, key := range keys {
+		scenes := groups[key]
+		sort.Slice(scenes, func(i, j int) bool {
+			if !scenes[i].start.Equal(scenes[j].start) {
+				return scenes[i].start.Before(scenes[j].start)
+			}
+			return scenes[i].item.Id < scenes[j].item.Id
+		})
+
+		var perpendicular map[string]float64
+		if req.MaxPerpendicularBaseline != nil {
+			group := make([]*Item, len(scenes))
+			for i, scene := range scenes {
+				group[i] = scene.item
+			}
+			var err error
+			if perpendicular, err = baselines(group); err != nil {
+				return nil, err
+			}
+		}
+
+		for i, reference := range scenes {
+			for _, secondary := range scenes[i+1:] {
+				if !secondary.start.After(reference.start) {
+					continue
+				}
+				days := int(math.Round(secondary.start.Sub(reference.start).Hours() / 24))
+				if days > req.MaxTemporalBaseline {
+					// Later scenes are further apart still
+					break
+				}
+
+				pair := &Pair{
+					ID:                reference.item.Id + "_" + secondary.item.Id,
+					Collection:        reference.item.Collection,
+					Reference:         reference.item.Id,
+					Secondary:         secondary.item.Id,
+					ReferenceDatetime: reference.start.UTC(),
+					SecondaryDatetime: secondary.start.UTC(),
+					RelativeOrbit:     reference.item.Properties[RelativeOrbitProperty],
+					Frame:             reference.item.Properties[FrameProperty],
+					TemporalBaseline:  days,
+					Links:             []*Link{},
+				}
+				if req.MaxPerpendicularBaseline != nil {
+					a, okA := perpendicular[reference.item.Id]
+					b, okB := perpendicular[secondary.item.Id]
+					if !okA || !okB {
+						continue
+					}
+					baseline := math.Abs(b - a)
+					if baseline > *req.MaxPerpendicularBaseline {
+						continue
+					}
+					pair.PerpendicularBaseline = &baseline
+				}
+
+				if overlap, err := geojson.Intersects(reference.geometry, secondary.geometry); err != nil || !overlap {
+					continue
+				}
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Key().Before(pairs[j].Key())
+	})
+	return pairs, nil
+}
+
+// itemGeometry decodes the geometry of an item
+func itemGeometry(item *Item) (*geojson.Geometry, error) {
+	if item.Geometry == nil {
+		return nil, fmt.Errorf("item %s has no geometry", item.Id)
+	}
+	data, err := json.Marshal(item.Geometry)
+	if err != nil {
+		return nil, err
+	}
+	var g geojson.Geometry
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
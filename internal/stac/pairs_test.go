@@ -0,0 +1,181 @@
+package stac
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pairItem creates an item on a track and frame, acquired days after
+// 2024-01-01 with a one degree footprint whose west edge is at lon
+func pairItem(id string, orbit, frame, days int, lon float64) *Item {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+	return &Item{
+		Id:         id,
+		Collection: "sentinel-1",
+		Geometry: map[string]any{
+			"type": "Polygon",
+			"coordinates": [][][]float64{{
+				{lon, 0}, {lon + 1, 0}, {lon + 1, 1}, {lon, 1}, {lon, 0},
+			}},
+		},
+		Properties: map[string]any{
+			"start_datetime":      start.Format(time.RFC3339),
+			RelativeOrbitProperty: orbit,
+			FrameProperty:         frame,
+		},
+	}
+}
+
+func pairIDs(pairs []*Pair) []string {
+	ids := make([]string, len(pairs))
+	for i, p := range pairs {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func TestFindPairs(t *testing.T) {
+	items := []*Item{
+		pairItem("c", 64, 100, 24, 0.5),
+		pairItem("a", 64, 100, 0, 0),
+		pairItem("b", 64, 100, 12, 0.2),
+		pairItem("a", 64, 100, 0, 0), // repeated by the scan
+		pairItem("far", 64, 100, 36, 5),
+		pairItem("other-frame", 64, 101, 12, 0),
+		pairItem("other-orbit", 137, 100, 12, 0),
+		pairItem("x", 137, 100, 0, 0),
+	}
+
+	pairs, err := FindPairs(items, &PairsRequest{SearchRequest: &SearchRequest{}, MaxTemporalBaseline: 24}, nil)
+	if err != nil {
+		t.Fatalf("FindPairs() error = %v", err)
+	}
+	want := []string{"a_b", "x_other-orbit", "a_c", "b_c"}
+	if got := pairIDs(pairs); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected pairs %v, got %v", want, got)
+	}
+
+	p := pairs[0]
+	if p.Reference != "a" || p.Secondary != "b" || p.TemporalBaseline != 12 || p.RelativeOrbit != 64 || p.Frame != 100 {
+		t.Errorf("Unexpected pair %+v", p)
+	}
+	if p.PerpendicularBaseline != nil {
+		t.Errorf("Expected no perpendicular baseline, got %v", *p.PerpendicularBaseline)
+	}
+
+	// Pages resume after the key of their last pair, which need not be
+	// among the pairs found anymore
+	if got := pairIDs(PairsAfter(pairs, pairs[1].Key())); fmt.Sprint(got) != "[a_c b_c]" {
+		t.Errorf("Expected the pairs after x_other-orbit, got %v", got)
+	}
+	gone := PairKey{ReferenceDatetime: p.ReferenceDatetime, SecondaryDatetime: p.SecondaryDatetime, ID: "a_a"}
+	if got := pairIDs(PairsAfter(pairs, gone)); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected every pair after a removed one, got %v", got)
+	}
+
+	// A longer limit reaches further, but not to scenes that do not overlap
+	pairs, _ = FindPairs(items, &PairsRequest{SearchRequest: &SearchRequest{}, MaxTemporalBaseline: 48}, nil)
+	if got := pairIDs(pairs); strings.Contains(fmt.Sprint(got), "far") {
+		t.Errorf("Expected no pairs with the distant footprint, got %v", got)
+	}
+}
+
+func TestFindPairs_PerpendicularBaselines(t *testing.T) {
+	items := []*Item{
+		pairItem("a", 64, 100, 0, 0),
+		pairItem("b", 64, 100, 12, 0),
+		pairItem("c", 64, 100, 24, 0),
+		pairItem("d", 64, 100, 36, 0),
+	}
+	maxPerpendicular := 100.0
+	req := &PairsRequest{SearchRequest: &SearchRequest{}, MaxTemporalBaseline: 24, MaxPerpendicularBaseline: &maxPerpendicular}
+
+	var groups [][]*Item
+	pairs, err := FindPairs(items, req, func(group []*Item) (map[string]float64, error) {
+		groups = append(groups, group)
+		// d has no baseline
+		return map[string]float64{"a": 0, "b": -80, "c": 50}, nil
+	})
+	if err != nil {
+		t.Fatalf("FindPairs() error = %v", err)
+	}
+	if len(groups) != 1 || groups[0][0].Id != "a" {
+		t.Errorf("Expected one lookup with the earliest item first, got %v", groups)
+	}
+	want := []string{"a_b", "a_c"}
+	if got := pairIDs(pairs); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected pairs %v, got %v", want, got)
+	}
+	if b := pairs[0].PerpendicularBaseline; b == nil || *b != 80 {
+		t.Errorf("Expected a perpendicular baseline of 80, got %v", b)
+	}
+
+	lookupErr := errors.New("stack lookup failed")
+	if _, err := FindPairs(items, req, func([]*Item) (map[string]float64, error) { return nil, lookupErr }); !errors.Is(err, lookupErr) {
+		t.Errorf("Expected the lookup error, got %v", err)
+	}
+}
+
+func TestParsePairsRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/pairs?collections=sentinel-1&max_temporal_baseline=24&max_perpendicular_baseline=150.5", nil)
+	req, err := ParsePairsRequest(r)
+	if err != nil {
+		t.Fatalf("ParsePairsRequest() error = %v", err)
+	}
+	if req.MaxTemporalBaseline != 24 || req.MaxPerpendicularBaseline == nil || *req.MaxPerpendicularBaseline != 150.5 {
+		t.Errorf("Unexpected constraints %d, %v", req.MaxTemporalBaseline, req.MaxPerpendicularBaseline)
+	}
+	if len(req.Collections) != 1 || req.Collections[0] != "sentinel-1" {
+		t.Errorf("Expected the search fields to be parsed, got %v", req.Collections)
+	}
+
+	req, err = ParsePairsRequestBody(strings.NewReader(`{"collections": ["sentinel-1"], "max_perpendicular_baseline": 100}`))
+	if err != nil {
+		t.Fatalf("ParsePairsRequestBody() error = %v", err)
+	}
+	if req.MaxTemporalBaseline != DefaultMaxTemporalBaseline || *req.MaxPerpendicularBaseline != 100 {
+		t.Errorf("Unexpected constraints %d, %v", req.MaxTemporalBaseline, *req.MaxPerpendicularBaseline)
+	}
+
+	// The constraints round-trip through the query parameters of next links
+	r = httptest.NewRequest("GET", "/pairs?"+req.ToQueryParams().Encode(), nil)
+	again, err := ParsePairsRequest(r)
+	if err != nil || again.Fingerprint() != req.Fingerprint() {
+		t.Errorf("Expected the same request from its query parameters, got %+v (%v)", again, err)
+	}
+
+	for _, query := range []string{
+		"max_temporal_baseline=-12",
+		"max_temporal_baseline=twelve",
+		"max_perpendicular_baseline=-1",
+	} {
+		if _, err := ParsePairsRequest(httptest.NewRequest("GET", "/pairs?"+query, nil)); err == nil {
+			t.Errorf("Expected an error for %s", query)
+		}
+	}
+}
+
+func TestPairsRequest_Fingerprint(t *testing.T) {
+	parse := func(query string) string {
+		req, err := ParsePairsRequest(httptest.NewRequest("GET", "/pairs?"+query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req.Fingerprint()
+	}
+
+	base := parse("collections=sentinel-1")
+	if parse("collections=sentinel-1&limit=5") != base {
+		t.Error("Expected the limit not to change the fingerprint")
+	}
+	if parse("collections=sentinel-1&max_temporal_baseline=12") == base {
+		t.Error("Expected the temporal baseline to change the fingerprint")
+	}
+	if parse("collections=sentinel-1&max_perpendicular_baseline=100") == base {
+		t.Error("Expected the perpendicular baseline to change the fingerprint")
+	}
+}
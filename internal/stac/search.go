@@ -1,6 +1,8 @@
 package stac
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,6 +10,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cql2"
 )
 
 // SortbyItem represents a single sort criterion
@@ -34,7 +38,11 @@ type SearchRequest struct {
 	// Sortby extension
 	Sortby []SortbyItem `json:"sortby,omitempty"`
 
-	// Filter extension - use CQL2-JSON for extension properties like sar:*, sat:*
+	// Fields extension
+	Fields *Fields `json:"fields,omitempty"`
+
+	// Filter extension. CQL2-Text input is converted on parse, so Filter always
+	// holds the CQL2-JSON form regardless of the encoding used by the client.
 	Filter     any    `json:"filter,omitempty"`
 	FilterLang string `json:"filter-lang,omitempty"`
 	FilterCRS  string `json:"filter-crs,omitempty"`
@@ -112,31 +120,36 @@ func ParseSearchRequest(r *http.Request) (*SearchRequest, error) {
 	// Parse sortby parameter
 	if sortbyStr := query.Get("sortby"); sortbyStr != "" {
 		sortbyItems, err := parseSortbyParam(sortbyStr)
+		if err == nil {
+			err = ValidateSortby(sortbyItems)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("invalid sortby parameter: %w", err)
 		}
 		req.Sortby = sortbyItems
 	}
 
-	// Parse filter parameters
+	// Parse fields parameter
+	if fieldsStr := query.Get("fields"); fieldsStr != "" {
+		fields, err := parseFieldsParam(fieldsStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fields parameter: %w", err)
+		}
+		req.Fields = fields
+	}
+
+	// Parse filter parameters. Both CQL2-JSON and CQL2-Text are accepted; the
+	// filter is always held in CQL2-JSON form once parsed.
+	filterLang := query.Get("filter-lang")
 	if filter := query.Get("filter"); filter != "" {
-		// Try to parse as JSON (CQL2-JSON format) if filter-lang is cql2-json or auto-detect
-		filterLang := query.Get("filter-lang")
-		if filterLang == "cql2-json" || (filterLang == "" && strings.HasPrefix(strings.TrimSpace(filter), "{")) {
-			// Parse as JSON
-			var filterObj interface{}
-			if err := json.Unmarshal([]byte(filter), &filterObj); err == nil {
-				req.Filter = filterObj
-			} else {
-				// Fall back to storing as string if JSON parsing fails
-				req.Filter = filter
-			}
-		} else {
-			// Store as-is for CQL2-Text format
-			req.Filter = filter
+		parsed, err := parseFilterString(filter, filterLang)
+		if err != nil {
+			return nil, err
 		}
+		req.Filter = parsed
+		filterLang = cql2.LangJSON
 	}
-	if filterLang := query.Get("filter-lang"); filterLang != "" {
+	if filterLang != "" {
 		req.FilterLang = filterLang
 	}
 	if filterCRS := query.Get("filter-crs"); filterCRS != "" {
@@ -199,9 +212,103 @@ func ParseSearchRequestBody(body io.Reader) (*SearchRequest, error) {
 		return nil, fmt.Errorf("failed to parse search request body: %w", err)
 	}
 
+	// A string filter in a JSON body is CQL2-Text unless declared otherwise
+	if filter, ok := req.Filter.(string); ok {
+		parsed, err := parseFilterString(filter, req.FilterLang)
+		if err != nil {
+			return nil, err
+		}
+		req.Filter = parsed
+		req.FilterLang = cql2.LangJSON
+	} else if req.Filter != nil && req.FilterLang == cql2.LangText {
+		return nil, fmt.Errorf("filter-lang %q requires the filter to be a string", cql2.LangText)
+	} else if req.Filter != nil && req.FilterLang != "" && req.FilterLang != cql2.LangJSON {
+		return nil, fmt.Errorf("unsupported filter-lang %q", req.FilterLang)
+	}
+
+	// Directions in a JSON body are case-insensitive and default to ascending,
+	// as for the GET form
+	for i := range req.Sortby {
+		req.Sortby[i].Direction = strings.ToLower(req.Sortby[i].Direction)
+		if req.Sortby[i].Direction == "" {
+			req.Sortby[i].Direction = string(SortAsc)
+		}
+	}
+	if err := ValidateSortby(req.Sortby); err != nil {
+		return nil, fmt.Errorf("invalid sortby: %w", err)
+	}
+
+	if req.Fields != nil {
+		if err := req.Fields.validate(); err != nil {
+			return nil, fmt.Errorf("invalid fields: %w", err)
+		}
+	}
+
 	return &req, nil
 }
 
+// parseFilterString parses a filter supplied as a string according to filterLang.
+// When filterLang is empty the encoding is detected: input starting with '{' is
+// treated as CQL2-JSON and anything else as CQL2-Text.
+func parseFilterString(filter, filterLang string) (any, error) {
+	switch filterLang {
+	case cql2.LangJSON:
+	case cql2.LangText:
+	case "":
+		if strings.HasPrefix(strings.TrimSpace(filter), "{") {
+			filterLang = cql2.LangJSON
+		} else {
+			filterLang = cql2.LangText
+		}
+	default:
+		return nil, fmt.Errorf("unsupported filter-lang %q", filterLang)
+	}
+
+	if filterLang == cql2.LangJSON {
+		var filterObj any
+		if err := json.Unmarshal([]byte(filter), &filterObj); err != nil {
+			return nil, fmt.Errorf("invalid cql2-json filter: %w", err)
+		}
+		return filterObj, nil
+	}
+
+	filterObj, err := cql2.ParseText(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cql2-text filter: %w", err)
+	}
+	return filterObj, nil
+}
+
+// Fingerprint identifies the items a search selects, in their order, for
+// the given collection (empty for a cross-collection search). It covers the
+// spatial, temporal, ID, collection, filter and sort parameters but not the
+// limit or fields, which only shape each page, and it is the same for the
+// GET and POST forms of a search.
+func (req *SearchRequest) Fingerprint(collectionID string) string {
+	// Normalize the geometry so formatting differences do not matter
+	var intersects any
+	if len(req.Intersects) > 0 {
+		if err := json.Unmarshal(req.Intersects, &intersects); err != nil {
+			intersects = string(req.Intersects)
+		}
+	}
+
+	data, _ := json.Marshal(struct {
+		Collection  string       `json:"collection,omitempty"`
+		BBox        []float64    `json:"bbox,omitempty"`
+		DateTime    string       `json:"datetime,omitempty"`
+		Intersects  any          `json:"intersects,omitempty"`
+		IDs         []string     `json:"ids,omitempty"`
+		Collections []string     `json:"collections,omitempty"`
+		Sortby      []SortbyItem `json:"sortby,omitempty"`
+		Filter      any          `json:"filter,omitempty"`
+		FilterCRS   string       `json:"filter-crs,omitempty"`
+	}{collectionID, req.BBox, req.DateTime, intersects, req.IDs, req.Collections, req.Sortby, req.Filter, req.FilterCRS})
+
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
 // ToQueryParams converts a SearchRequest to URL query parameters.
 // This is used to preserve search parameters in pagination links for POST requests.
 func (req *SearchRequest) ToQueryParams() url.Values {
@@ -251,6 +358,13 @@ func (req *SearchRequest) ToQueryParams() url.Values {
 		params.Set("sortby", strings.Join(sortbyStrs, ","))
 	}
 
+	// Fields
+	if req.Fields != nil {
+		if value := req.Fields.queryValue(); value != "" {
+			params.Set("fields", value)
+		}
+	}
+
 	// Filter - convert to JSON string for query param
 	if req.Filter != nil {
 		filterBytes, err := json.Marshal(req.Filter)
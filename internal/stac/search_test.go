@@ -0,0 +1,59 @@
+package stac
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestSearchRequest_Fingerprint(t *testing.T) {
+	parseGET := func(query string) *SearchRequest {
+		t.Helper()
+		req, err := ParseSearchRequest(httptest.NewRequest("GET", "/search?"+query, nil))
+		if err != nil {
+			t.Fatalf("ParseSearchRequest(%q) error = %v", query, err)
+		}
+		return req
+	}
+
+	base := parseGET("collections=sentinel-1&bbox=-150,60,-140,70&datetime=2024-01-01T00:00:00Z/..")
+
+	// Page-shaping parameters do not change the fingerprint
+	same := parseGET("collections=sentinel-1&bbox=-150,60,-140,70&datetime=2024-01-01T00:00:00Z/..&limit=50&fields=id")
+	same.Cursor = "abc"
+	if base.Fingerprint("") != same.Fingerprint("") {
+		t.Error("limit, fields or cursor changed the fingerprint")
+	}
+
+	for _, query := range []string{
+		"collections=sentinel-1&bbox=-150,60,-140,71&datetime=2024-01-01T00:00:00Z/..",
+		"collections=sentinel-1&bbox=-150,60,-140,70&datetime=2024-02-01T00:00:00Z/..",
+		"collections=sentinel-1&bbox=-150,60,-140,70&datetime=2024-01-01T00:00:00Z/..&sortby=%2Bdatetime",
+		"collections=sentinel-1&bbox=-150,60,-140,70&datetime=2024-01-01T00:00:00Z/..&filter=" + url.QueryEscape("platform = 'sentinel-1a'"),
+		"collections=sentinel-1&bbox=-150,60,-140,70&datetime=2024-01-01T00:00:00Z/..&ids=a,b",
+	} {
+		if parseGET(query).Fingerprint("") == base.Fingerprint("") {
+			t.Errorf("fingerprint of %q matches the base query", query)
+		}
+	}
+
+	if base.Fingerprint("sentinel-1") == base.Fingerprint("") {
+		t.Error("collection ID did not change the fingerprint")
+	}
+
+	// The POST form and the GET form of its next link match
+	post, err := ParseSearchRequestBody(bytes.NewBufferString(`{
+		"collections": ["sentinel-1"],
+		"intersects": { "type": "Point", "coordinates": [-147.5, 64.8] },
+		"filter": "platform = 'sentinel-1a' AND sat:relative_orbit > 10",
+		"sortby": [{"field": "datetime", "direction": "DESC"}]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseSearchRequestBody() error = %v", err)
+	}
+	get := parseGET(post.ToQueryParams().Encode())
+	if post.Fingerprint("") != get.Fingerprint("") {
+		t.Errorf("POST fingerprint %s differs from its GET form %s", post.Fingerprint(""), get.Fingerprint(""))
+	}
+}
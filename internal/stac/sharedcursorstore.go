@@ -0,0 +1,145 @@
+package stac
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Cursor stores for deployments with several replicas, where the next page
+// may be served by a different instance than the one that issued the cursor.
+// Replicas sharing a cursor secret can also do without a store, as sealed
+// inline cursors are verified by any of them.
+
+// DefaultMaxCursorBytes bounds the encoded size of a stored cursor.
+const DefaultMaxCursorBytes = 8192
+
+// maxInflatedCursorBytes bounds the JSON a stored cursor may inflate to
+const maxInflatedCursorBytes = 1 << 20
+
+// Sentinel errors for shared cursor stores
+var (
+	ErrCursorInvalid  = cursorStoreError("cursor is invalid or was tampered with")
+	ErrCursorTooLarge = cursorStoreError("cursor exceeds the size limit")
+)
+
+// CursorStoreStats is implemented by cursor stores that can report how many
+// cursors they hold and the age of the oldest.
+type CursorStoreStats interface {
+	Stats() (count int, oldestAge time.Duration)
+}
+
+// compressCursor serializes a cursor as deflated JSON
+func compressCursor(cursor *Cursor) ([]byte, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cursor: %w", err)
+	}
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressCursor reverses compressCursor, refusing to inflate a cursor
+// beyond maxInflatedCursorBytes of JSON
+func decompressCursor(data []byte) (*Cursor, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	raw, err := io.ReadAll(io.LimitReader(r, maxInflatedCursorBytes+1))
+	if err != nil {
+		return nil, ErrCursorInvalid
+	}
+	if len(raw) > maxInflatedCursorBytes {
+		return nil, ErrCursorTooLarge
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, ErrCursorInvalid
+	}
+	return &cursor, nil
+}
+
+// KV is a key-value store shared by all replicas whose entries expire, such
+// as Redis.
+type KV interface {
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// KVCursorStore is a CursorStore keeping compressed cursors in a shared
+// key-value store under a random token, expiring after the TTL.
+type KVCursorStore struct {
+	kv       KV
+	prefix   string
+	ttl      time.Duration
+	maxBytes int
+	timeout  time.Duration
+}
+
+// NewKVCursorStore creates a store keeping cursors in kv under keys starting
+// with prefix. Cursors whose compressed size exceeds maxBytes are refused; 0
+// means DefaultMaxCursorBytes.
+func NewKVCursorStore(kv KV, prefix string, ttl time.Duration, maxBytes int) *KVCursorStore {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxCursorBytes
+	}
+	return &KVCursorStore{kv: kv, prefix: prefix, ttl: ttl, maxBytes: maxBytes, timeout: 2 * time.Second}
+}
+
+// Store saves a cursor and returns a short token.
+func (s *KVCursorStore) Store(cursor *Cursor) (string, error) {
+	value, err := compressCursor(cursor)
+	if err != nil {
+		return "", err
+	}
+	if len(value) > s.maxBytes {
+		return "", ErrCursorTooLarge
+	}
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	if err := s.kv.Set(ctx, s.prefix+token, value, s.ttl); err != nil {
+		return "", fmt.Errorf("failed to store cursor: %w", err)
+	}
+	return token, nil
+}
+
+// Retrieve gets a cursor by its token. Expired cursors have been evicted by
+// the store, so they are reported as not found.
+func (s *KVCursorStore) Retrieve(token string) (*Cursor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	value, found, err := s.kv.Get(ctx, s.prefix+token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve cursor: %w", err)
+	}
+	if !found {
+		return nil, ErrCursorNotFound
+	}
+	return decompressCursor(value)
+}
+
+// Delete removes a cursor by its token.
+func (s *KVCursorStore) Delete(token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	return s.kv.Delete(ctx, s.prefix+token)
+}
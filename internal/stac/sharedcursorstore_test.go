@@ -0,0 +1,156 @@
+package stac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testKeyring(t *testing.T, secret []byte) *CursorKeyring {
+	t.Helper()
+	keyring, err := NewCursorKeyring(secret, nil, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("NewCursorKeyring() error = %v", err)
+	}
+	return keyring
+}
+
+func testCursor(seen int) *Cursor {
+	cursor := &Cursor{StartTime: "2024-01-15T12:00:00Z", Direction: "next"}
+	for i := 0; i < seen; i++ {
+		cursor.SeenIDs = append(cursor.SeenIDs, fmt.Sprintf("S1A_IW_SLC__1SDV_20240115T120000_%06d", i))
+	}
+	return cursor
+}
+
+func mustStore(t *testing.T, store CursorStore) string {
+	t.Helper()
+	token, err := store.Store(testCursor(5))
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	return token
+}
+
+// memoryKV is an in-memory KV for tests
+type memoryKV struct {
+	mu      sync.Mutex
+	now     time.Time
+	values  map[string][]byte
+	expires map[string]time.Time
+	ttls    map[string]time.Duration
+	err     error
+}
+
+func newMemoryKV() *memoryKV {
+	return &memoryKV{
+		now:     time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		values:  make(map[string][]byte),
+		expires: make(map[string]time.Time),
+		ttls:    make(map[string]time.Duration),
+	}
+}
+
+func (kv *memoryKV) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.err != nil {
+		return nil, false, kv.err
+	}
+	value, ok := kv.values[key]
+	if !ok || !kv.now.Before(kv.expires[key]) {
+		return nil, false, nil
+	}
+	return value, true, nil
+}
+
+func (kv *memoryKV) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.err != nil {
+		return kv.err
+	}
+	kv.values[key] = value
+	kv.expires[key] = kv.now.Add(ttl)
+	kv.ttls[key] = ttl
+	return nil
+}
+
+func (kv *memoryKV) Delete(ctx context.Context, key string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	delete(kv.values, key)
+	return nil
+}
+
+func TestKVCursorStore(t *testing.T) {
+	kv := newMemoryKV()
+	store := NewKVCursorStore(kv, "cursor:", 30*time.Minute, 0)
+	cursor := testCursor(200)
+
+	token, err := store.Store(cursor)
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if ttl := kv.ttls["cursor:"+token]; ttl != 30*time.Minute {
+		t.Errorf("stored with TTL %v, want 30m", ttl)
+	}
+
+	// A second replica sharing the KV finds the cursor
+	other := NewKVCursorStore(kv, "cursor:", 30*time.Minute, 0)
+	got, err := other.Retrieve(token)
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, cursor) {
+		t.Errorf("Retrieve() = %+v, want %+v", got, cursor)
+	}
+
+	if err := store.Delete(token); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Retrieve(token); !errors.Is(err, ErrCursorNotFound) {
+		t.Errorf("Retrieve() after Delete error = %v, want ErrCursorNotFound", err)
+	}
+}
+
+func TestKVCursorStore_Expired(t *testing.T) {
+	kv := newMemoryKV()
+	store := NewKVCursorStore(kv, "cursor:", time.Minute, 0)
+	token := mustStore(t, store)
+
+	kv.now = kv.now.Add(2 * time.Minute)
+	if _, err := store.Retrieve(token); !errors.Is(err, ErrCursorNotFound) {
+		t.Errorf("Retrieve() error = %v, want ErrCursorNotFound", err)
+	}
+}
+
+func TestKVCursorStore_Errors(t *testing.T) {
+	kv := newMemoryKV()
+
+	small := NewKVCursorStore(kv, "cursor:", time.Hour, 64)
+	if _, err := small.Store(testCursor(500)); !errors.Is(err, ErrCursorTooLarge) {
+		t.Errorf("Store() error = %v, want ErrCursorTooLarge", err)
+	}
+
+	store := NewKVCursorStore(kv, "cursor:", time.Hour, 0)
+	kv.values["cursor:garbage"] = []byte("not deflate")
+	kv.expires["cursor:garbage"] = kv.now.Add(time.Hour)
+	if _, err := store.Retrieve("garbage"); !errors.Is(err, ErrCursorInvalid) {
+		t.Errorf("Retrieve() of a corrupt value error = %v, want ErrCursorInvalid", err)
+	}
+
+	kv.err = errors.New("connection refused")
+	if _, err := store.Store(testCursor(1)); err == nil {
+		t.Error("Store() succeeded with the KV down")
+	}
+	if _, err := store.Retrieve("token"); err == nil {
+		t.Error("Retrieve() succeeded with the KV down")
+	}
+}
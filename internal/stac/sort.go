@@ -1,6 +1,11 @@
 package stac
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
 
 // SortDirection represents the sort direction.
 type SortDirection string
@@ -12,6 +17,160 @@ const (
 	SortDesc SortDirection = "desc"
 )
 
+// sortableFields are the fields results can be ordered by. The first sort key
+// must be a start time field, since pagination cursors track start times;
+// later keys are applied by the proxy within each page.
+var sortableFields = map[string]bool{
+	"id":                  true,
+	"collection":          true,
+	"datetime":            true,
+	"start_datetime":      true,
+	"end_datetime":        true,
+	"platform":            true,
+	"constellation":       true,
+	"sar:instrument_mode": true,
+	"sar:frequency_band":  true,
+	"sar:product_type":    true,
+	"sat:orbit_state":     true,
+	"sat:relative_orbit":  true,
+	"sat:absolute_orbit":  true,
+	"processing:level":    true,
+}
+
+// NormalizeSortField strips the optional "properties." prefix from a sort field.
+func NormalizeSortField(field string) string {
+	return strings.TrimPrefix(field, "properties.")
+}
+
+// isStartTimeField reports whether field orders items by acquisition start
+func isStartTimeField(field string) bool {
+	switch NormalizeSortField(field) {
+	case "datetime", "start_datetime":
+		return true
+	}
+	return false
+}
+
+// ValidateSortby checks that every sort key names a sortable field with a
+// valid direction, and that the first key orders by start time.
+func ValidateSortby(sortby []SortbyItem) error {
+	for i, item := range sortby {
+		field := NormalizeSortField(item.Field)
+		if !sortableFields[field] {
+			return fmt.Errorf("unsupported sort field: %s", item.Field)
+		}
+		if item.Direction != string(SortAsc) && item.Direction != string(SortDesc) {
+			return fmt.Errorf("sort direction for %s must be %q or %q, got %q", item.Field, SortAsc, SortDesc, item.Direction)
+		}
+		if i == 0 && !isStartTimeField(field) {
+			return fmt.Errorf("the first sort field must be datetime or start_datetime, got %s", item.Field)
+		}
+	}
+	return nil
+}
+
+// SortsAscending reports whether results are requested oldest first. Without
+// sortby, results are returned newest first.
+func SortsAscending(sortby []SortbyItem) bool {
+	return len(sortby) > 0 && sortby[0].Direction == string(SortAsc)
+}
+
+// SortItems orders items by the sort keys. The sort is stable, so items that
+// compare equal on every key keep their upstream order. Items missing a
+// field sort after those that have it, in either direction.
+func SortItems(items []*Item, sortby []SortbyItem) {
+	if len(sortby) == 0 {
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, key := range sortby {
+			a, b := sortValue(items[i], key.Field), sortValue(items[j], key.Field)
+			if a == nil || b == nil {
+				if a == nil && b == nil {
+					continue
+				}
+				return b == nil
+			}
+			c := compareSortValues(a, b)
+			if c == 0 {
+				continue
+			}
+			if key.Direction == string(SortDesc) {
+				c = -c
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// sortValue returns the value of a sort field on an item, or nil when unset
+func sortValue(item *Item, field string) any {
+	field = NormalizeSortField(field)
+	switch field {
+	case "id":
+		return item.Id
+	case "collection":
+		return item.Collection
+	case "datetime":
+		// Ordered by acquisition start, like the upstream services
+		if v, ok := item.Properties["start_datetime"]; ok && v != nil {
+			return v
+		}
+	}
+	return item.Properties[field]
+}
+
+// compareSortValues compares two property values, returning -1, 0 or 1.
+// Numbers compare numerically, times and timestamps chronologically and
+// everything else as strings.
+func compareSortValues(a, b any) int {
+	if x, ok := sortNumber(a); ok {
+		if y, ok := sortNumber(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	if x, ok := sortTime(a); ok {
+		if y, ok := sortTime(b); ok {
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func sortNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func sortTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return parsed, err == nil
+	}
+	return time.Time{}, false
+}
+
 // MapSTACFieldToASFSort maps STAC field names to ASF sort parameters.
 // ASF supports: startTime, stopTime, dataset, platform, frame, orbit
 func MapSTACFieldToASFSort(stacField string) (string, error) {
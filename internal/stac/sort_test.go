@@ -155,3 +155,64 @@ func TestMapSTACFieldToCMRSort(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSortby(t *testing.T) {
+	tests := []struct {
+		name    string
+		sortby  []SortbyItem
+		wantErr bool
+	}{
+		{"empty", nil, false},
+		{"datetime descending", []SortbyItem{{"datetime", "desc"}}, false},
+		{"datetime ascending", []SortbyItem{{"properties.datetime", "asc"}}, false},
+		{"start_datetime with secondary keys", []SortbyItem{{"start_datetime", "asc"}, {"sat:relative_orbit", "desc"}, {"id", "asc"}}, false},
+		{"secondary key first", []SortbyItem{{"sat:relative_orbit", "desc"}, {"datetime", "asc"}}, true},
+		{"end_datetime first", []SortbyItem{{"end_datetime", "asc"}}, true},
+		{"unknown field", []SortbyItem{{"datetime", "asc"}, {"eo:cloud_cover", "asc"}}, true},
+		{"array field", []SortbyItem{{"datetime", "asc"}, {"sar:polarizations", "asc"}}, true},
+		{"invalid direction", []SortbyItem{{"datetime", "up"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSortby(tt.sortby)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSortby() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSortItems(t *testing.T) {
+	item := func(id string, props map[string]any) *Item {
+		return &Item{Id: id, Properties: props}
+	}
+	items := []*Item{
+		item("a", map[string]any{"start_datetime": "2024-01-02T00:00:00Z", "sat:relative_orbit": 10.0}),
+		item("b", map[string]any{"start_datetime": "2024-01-01T00:00:00Z", "sat:relative_orbit": 5.0}),
+		item("c", map[string]any{"start_datetime": "2024-01-02T00:00:00Z", "sat:relative_orbit": 99.0}),
+		item("d", map[string]any{"start_datetime": "2024-01-02T00:00:00Z"}),
+		item("e", map[string]any{"start_datetime": "2024-01-02T00:00:00Z", "sat:relative_orbit": 99.0}),
+		item("f", map[string]any{"datetime": "2023-12-31T00:00:00Z", "sat:relative_orbit": 1.0}),
+	}
+
+	SortItems(items, []SortbyItem{{"datetime", "asc"}, {"properties.sat:relative_orbit", "desc"}})
+
+	// Numbers compare numerically, equal items keep their order and items
+	// without the field come last even in descending order
+	want := []string{"f", "b", "c", "e", "a", "d"}
+	for i, it := range items {
+		if it.Id != want[i] {
+			t.Errorf("SortItems() order = %v, want %v", itemIDs(items), want)
+			break
+		}
+	}
+}
+
+func itemIDs(items []*Item) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.Id
+	}
+	return ids
+}
@@ -0,0 +1,369 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter receives finished, sampled spans.
+type Exporter interface {
+	// ExportSpan hands over a finished span. It must not block for long.
+	ExportSpan(span SpanData)
+	// Shutdown flushes buffered spans and releases the exporter.
+	Shutdown(ctx context.Context) error
+}
+
+// NewExporter creates the exporter named by kind: "otlp" posts batches to a
+// collector's OTLP/HTTP endpoint, "stdout" writes JSON lines, and "none"
+// returns a nil exporter, meaning tracing is disabled.
+func NewExporter(kind, endpoint string, headers map[string]string, service string, logger *slog.Logger) (Exporter, error) {
+	switch kind {
+	case "none", "":
+		return nil, nil
+	case "stdout":
+		return NewWriterExporter(os.Stdout), nil
+	case "otlp":
+		return NewOTLPExporter(endpoint, service).WithHeaders(headers).WithLogger(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", kind)
+	}
+}
+
+// Recorder is an Exporter that keeps spans in memory, for tests.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewRecorder creates an empty recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// ExportSpan records a span.
+func (r *Recorder) ExportSpan(span SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// Shutdown does nothing.
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Spans returns the recorded spans in the order they ended.
+func (r *Recorder) Spans() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.spans)
+}
+
+// Find returns the first recorded span with the given name.
+func (r *Recorder) Find(name string) (SpanData, bool) {
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return SpanData{}, false
+}
+
+// WriterExporter writes each span as a line of JSON.
+type WriterExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterExporter creates an exporter writing to w.
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+// ExportSpan writes a span.
+func (e *WriterExporter) ExportSpan(span SpanData) {
+	line, err := json.Marshal(otlpSpanOf(span))
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w.Write(append(line, '\n'))
+}
+
+// Shutdown does nothing.
+func (e *WriterExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// OTLP batching limits
+const (
+	otlpBatchSize     = 512
+	otlpQueueSize     = 2048
+	otlpFlushInterval = 5 * time.Second
+)
+
+// OTLPExporter posts spans in batches to an OTLP/HTTP collector using the
+// JSON encoding. Spans arriving while the queue is full are dropped rather
+// than slowing down requests.
+type OTLPExporter struct {
+	url        string
+	service    string
+	headers    map[string]string
+	httpClient *http.Client
+	logger     *slog.Logger
+
+	mu      sync.Mutex
+	queue   []SpanData
+	dropped int
+	flush   chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewOTLPExporter creates an exporter for the collector at endpoint, the
+// base URL to which "/v1/traces" is appended, and starts its background
+// flushing.
+func NewOTLPExporter(endpoint, service string) *OTLPExporter {
+	e := &OTLPExporter{
+		url:        strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service:    service,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     slog.Default(),
+		flush:      make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// WithHeaders sets headers sent with every export, such as credentials.
+func (e *OTLPExporter) WithHeaders(headers map[string]string) *OTLPExporter {
+	e.headers = headers
+	return e
+}
+
+// WithLogger sets the logger export failures are reported to.
+func (e *OTLPExporter) WithLogger(logger *slog.Logger) *OTLPExporter {
+	if logger != nil {
+		e.logger = logger
+	}
+	return e
+}
+
+// ExportSpan queues a span for the next batch.
+func (e *OTLPExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.queue) >= otlpQueueSize {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, span)
+	if len(e.queue) >= otlpBatchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Shutdown exports the queued spans and stops the background flushing.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.once.Do(func() { close(e.stop) })
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return e.export(ctx)
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.flush:
+		case <-e.stop:
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), e.httpClient.Timeout)
+		if err := e.export(ctx); err != nil {
+			e.logger.Warn("failed to export trace spans", slog.String("error", err.Error()))
+		}
+		cancel()
+	}
+}
+
+// export posts the queued spans, in batches
+func (e *OTLPExporter) export(ctx context.Context) error {
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), otlpBatchSize)
+		batch := slices.Clone(e.queue[:n])
+		e.queue = e.queue[n:]
+		if dropped := e.dropped; dropped > 0 {
+			e.dropped = 0
+			e.logger.Warn("dropped trace spans, export queue full", slog.Int("count", dropped))
+		}
+		e.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := e.post(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *OTLPExporter) post(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP/JSON request body, see opentelemetry-proto trace/v1
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	converted := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		converted[i] = otlpSpanOf(span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: otlpAttributes(map[string]any{"service.name": e.service})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/robert-malhotra/asf-stac-proxy"},
+			Spans: converted,
+		}},
+	}}}
+}
+
+func otlpSpanOf(span SpanData) otlpSpan {
+	s := otlpSpan{
+		TraceID:           span.Context.TraceID.String(),
+		SpanID:            span.Context.SpanID.String(),
+		TraceState:        span.Context.TraceState,
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        otlpAttributes(span.Attributes),
+		Status:            otlpStatus{Code: span.Status, Message: span.StatusMessage},
+	}
+	if !span.Parent.IsZero() {
+		s.ParentSpanID = span.Parent.String()
+	}
+	return s
+}
+
+// otlpAttributes converts attributes to OTLP key-values, sorted by key
+func otlpAttributes(attributes map[string]any) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	converted := make([]otlpAttribute, 0, len(keys))
+	for _, k := range keys {
+		var value map[string]any
+		switch v := attributes[k].(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		converted = append(converted, otlpAttribute{Key: k, Value: value})
+	}
+	return converted
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		body   map[string]any
+		header http.Header
+		path   string
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, header = r.URL.Path, r.Header
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL+"/", "asf-stac-proxy").WithHeaders(map[string]string{"Authorization": "Bearer token"})
+	tracer := NewTracer("asf-stac-proxy", exporter)
+	ctx, root := tracer.Start(context.Background(), "GET /search", KindServer)
+	_, child := Start(ctx, "translate ASF features")
+	child.SetAttribute("translate.items", 10)
+	child.End()
+	root.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if path != "/v1/traces" || header.Get("Content-Type") != "application/json" || header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Unexpected export request to %s with headers %v", path, header)
+	}
+
+	resourceSpans := body["resourceSpans"].([]any)[0].(map[string]any)
+	resource, _ := json.Marshal(resourceSpans["resource"])
+	if !strings.Contains(string(resource), `{"key":"service.name","value":{"stringValue":"asf-stac-proxy"}}`) {
+		t.Errorf("Unexpected resource %s", resource)
+	}
+	spans := resourceSpans["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	exported := spans[0].(map[string]any)
+	if exported["name"] != "translate ASF features" || exported["traceId"] != root.Context().TraceID.String() ||
+		exported["parentSpanId"] != root.Context().SpanID.String() || exported["kind"] != float64(KindInternal) {
+		t.Errorf("Unexpected span %v", exported)
+	}
+	attributes, _ := json.Marshal(exported["attributes"])
+	if string(attributes) != `[{"key":"translate.items","value":{"intValue":"10"}}]` {
+		t.Errorf("Unexpected attributes %s", attributes)
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	if exporter, err := NewExporter("none", "", nil, "test", nil); exporter != nil || err != nil {
+		t.Errorf("NewExporter(none) = %v, %v; want tracing disabled", exporter, err)
+	}
+	if _, err := NewExporter("jaeger", "", nil, "test", nil); err == nil {
+		t.Error("Expected an error for an unknown exporter")
+	}
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		// Later versions may append fields
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		sc, ok := ParseTraceparent(tt.value)
+		if ok != tt.valid || sc.Sampled != tt.sampled {
+			t.Errorf("ParseTraceparent(%q) = %+v, %v; want valid %v, sampled %v", tt.value, sc, ok, tt.valid, tt.sampled)
+		}
+		if ok && tt.value[:2] == "00" && sc.Traceparent() != tt.value {
+			t.Errorf("Traceparent() = %q, want %q", sc.Traceparent(), tt.value)
+		}
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	tracer := NewTracer("test", NewRecorder())
+
+	inbound := http.Header{}
+	inbound.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	inbound.Set(TracestateHeader, "vendor=value")
+	remote, ok := Extract(inbound)
+	if !ok {
+		t.Fatal("Extract() found no span context")
+	}
+
+	ctx, span := tracer.Start(ContextWithRemoteParent(context.Background(), remote), "server", KindServer)
+	defer span.End()
+
+	outbound := http.Header{}
+	Inject(ctx, outbound)
+	sc, ok := ParseTraceparent(outbound.Get(TraceparentHeader))
+	if !ok {
+		t.Fatalf("Inject() set an invalid traceparent %q", outbound.Get(TraceparentHeader))
+	}
+	if sc.TraceID != remote.TraceID || sc.SpanID != span.Context().SpanID || !sc.Sampled {
+		t.Errorf("Injected %+v, want trace %s and span %s", sc, remote.TraceID, span.Context().SpanID)
+	}
+	if got := outbound.Get(TracestateHeader); got != "vendor=value" {
+		t.Errorf("tracestate = %q, want it passed on", got)
+	}
+
+	// Without a span nothing is injected
+	empty := http.Header{}
+	Inject(context.Background(), empty)
+	if len(empty) != 0 {
+		t.Errorf("Expected no headers, got %v", empty)
+	}
+}
@@ -0,0 +1,265 @@
+// Package tracing records OpenTelemetry-compatible trace spans for the proxy:
+// inbound requests, handlers, backend calls, upstream HTTP round-trips,
+// response decoding, translation and encoding.
+//
+// Span context is propagated in and out with the W3C traceparent and
+// tracestate headers. Finished spans are handed to an Exporter: OTLP/HTTP for
+// a collector, JSON lines on stdout, or an in-memory Recorder in tests.
+//
+// A span is only started under a parent found in the context, so components
+// call Start unconditionally; without a Tracer at the root of the request
+// every span is nil, and every method of a nil *Span does nothing.
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"maps"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// SpanKind describes the relationship of a span to the rest of the trace.
+// The values match OTLP.
+type SpanKind int
+
+const (
+	// KindInternal is an operation within the proxy.
+	KindInternal SpanKind = 1
+	// KindServer is an inbound HTTP request.
+	KindServer SpanKind = 2
+	// KindClient is an outbound HTTP request.
+	KindClient SpanKind = 3
+)
+
+// StatusCode is the status of a finished span. The values match OTLP.
+type StatusCode int
+
+const (
+	// StatusUnset is a span that did not fail.
+	StatusUnset StatusCode = 0
+	// StatusError is a span whose operation failed.
+	StatusError StatusCode = 2
+)
+
+// SpanData is the immutable record of a finished span.
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	Context       SpanContext
+	Parent        SpanID // zero for a root span
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]any
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Duration returns how long the span lasted.
+func (d SpanData) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// Tracer starts root spans and hands finished spans to its exporter. It is
+// safe for concurrent use.
+type Tracer struct {
+	service  string
+	exporter Exporter
+	ratio    float64
+}
+
+// NewTracer creates a tracer for the named service that samples every trace.
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter, ratio: 1}
+}
+
+// WithSampleRatio sets the fraction of new traces, between 0 and 1, that are
+// recorded. Traces continued from an inbound traceparent follow its sampled
+// flag instead.
+func (t *Tracer) WithSampleRatio(ratio float64) *Tracer {
+	t.ratio = min(max(ratio, 0), 1)
+	return t
+}
+
+// Service returns the name of the traced service.
+func (t *Tracer) Service() string {
+	return t.service
+}
+
+// Shutdown exports any buffered spans and stops the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+// Start starts a span of the given kind under the span in ctx, or under the
+// remote parent set by ContextWithRemoteParent, or as the root of a new
+// trace. The returned context carries the span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if parent := SpanFromContext(ctx); parent != nil {
+		return parent.tracer.child(ctx, parent.data.Context, name, kind)
+	}
+	if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok && remote.IsValid() {
+		return t.child(ctx, remote, name, kind)
+	}
+
+	sc := SpanContext{
+		TraceID: newTraceID(),
+		SpanID:  newSpanID(),
+		Sampled: t.ratio >= 1 || rand.Float64() < t.ratio,
+	}
+	return t.start(ctx, sc, SpanID{}, name, kind)
+}
+
+// child starts a span under parent, inheriting its trace and sampling
+func (t *Tracer) child(ctx context.Context, parent SpanContext, name string, kind SpanKind) (context.Context, *Span) {
+	sc := parent
+	sc.SpanID = newSpanID()
+	return t.start(ctx, sc, parent.SpanID, name, kind)
+}
+
+func (t *Tracer) start(ctx context.Context, sc SpanContext, parent SpanID, name string, kind SpanKind) (context.Context, *Span) {
+	s := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:       name,
+			Kind:       kind,
+			Context:    sc,
+			Parent:     parent,
+			Start:      time.Now(),
+			Attributes: make(map[string]any),
+		},
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Start starts an internal span under the span in ctx. Without one it
+// returns ctx unchanged and a nil span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal)
+}
+
+// StartKind starts a span of the given kind under the span in ctx. Without
+// one it returns ctx unchanged and a nil span.
+func StartKind(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.child(ctx, parent.data.Context, name, kind)
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// SpanFromContext returns the span carried by ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithRemoteParent returns a context whose next root span continues
+// the trace of a span in another process.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Span is an operation being traced. It is safe for concurrent use, and
+// every method is a no-op on a nil *Span.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// Context returns the span's context, or the zero SpanContext for a nil span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetName renames the span, for names only known once it is under way.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttribute sets an attribute of the span. Values should be strings,
+// bools, integers or floats.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+// RecordError marks the span as failed with err, which may be nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetError(err.Error())
+}
+
+// SetError marks the span as failed with the given description.
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = StatusError
+	s.data.StatusMessage = message
+}
+
+// End finishes the span and, if its trace is sampled, exports it. Only the
+// first call has any effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	data.Attributes = maps.Clone(s.data.Attributes)
+	s.mu.Unlock()
+
+	if data.Context.Sampled {
+		s.tracer.exporter.ExportSpan(data)
+	}
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for id.IsZero() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for id.IsZero() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStart_WithoutParentIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "orphan")
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatal("Expected no span without a parent")
+	}
+	// Every method is safe on the nil span
+	span.SetName("renamed")
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("failed"))
+	span.End()
+}
+
+func TestTracer_ParentChild(t *testing.T) {
+	recorder := NewRecorder()
+	tracer := NewTracer("test", recorder)
+
+	ctx, root := tracer.Start(context.Background(), "root", KindServer)
+	childCtx, child := Start(ctx, "child")
+	_, grandchild := StartKind(childCtx, "grandchild", KindClient)
+	grandchild.SetAttribute("http.response.status_code", 503)
+	grandchild.RecordError(errors.New("unavailable"))
+	grandchild.End()
+	child.End()
+	root.End()
+	root.End() // ending twice exports once
+
+	spans := recorder.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	g, c, r := spans[0], spans[1], spans[2]
+	if r.Name != "root" || !r.Parent.IsZero() || r.Kind != KindServer {
+		t.Errorf("Unexpected root %+v", r)
+	}
+	if c.Parent != r.Context.SpanID || g.Parent != c.Context.SpanID {
+		t.Errorf("Expected a parent chain, got %s <- %s <- %s", r.Context.SpanID, c.Parent, g.Parent)
+	}
+	for _, s := range spans {
+		if s.Context.TraceID != r.Context.TraceID {
+			t.Errorf("Span %q has trace %s, want %s", s.Name, s.Context.TraceID, r.Context.TraceID)
+		}
+		if s.End.Before(s.Start) {
+			t.Errorf("Span %q ends before it starts", s.Name)
+		}
+	}
+	if g.Status != StatusError || g.StatusMessage != "unavailable" || g.Attributes["http.response.status_code"] != 503 {
+		t.Errorf("Unexpected grandchild %+v", g)
+	}
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	recorder := NewRecorder()
+	tracer := NewTracer("test", recorder).WithSampleRatio(0)
+
+	// Unsampled traces still propagate, but nothing is exported
+	ctx, root := tracer.Start(context.Background(), "root", KindServer)
+	_, child := Start(ctx, "child")
+	if !child.Context().IsValid() || child.Context().Sampled {
+		t.Errorf("Expected a valid, unsampled child, got %+v", child.Context())
+	}
+	child.End()
+	root.End()
+	if n := len(recorder.Spans()); n != 0 {
+		t.Errorf("Expected no spans exported, got %d", n)
+	}
+
+	// A sampled remote parent overrides the ratio
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span := tracer.Start(ContextWithRemoteParent(context.Background(), remote), "server", KindServer)
+	span.End()
+	spans := recorder.Spans()
+	if len(spans) != 1 || spans[0].Context.TraceID != remote.TraceID || spans[0].Parent != remote.SpanID {
+		t.Errorf("Expected the remote trace to be continued, got %+v", spans)
+	}
+}
@@ -24,6 +24,10 @@ import (
 //   - sat:absolute_orbit   -> absoluteOrbit
 //   - processing:level     -> processingLevel
 //   - platform             -> platform
+//   - s1:burst_id          -> fullBurstID
+//   - asf:frame            -> frame
+//   - asf:path             -> relativeOrbit
+//   - view:off_nadir       -> offNadirAngle
 func TranslateCQL2Filter(filter any, params *asf.SearchParams) error {
 	if filter == nil {
 		return nil
@@ -36,7 +40,16 @@ func TranslateCQL2Filter(filter any, params *asf.SearchParams) error {
 	}
 
 	// Process the filter expression recursively
-	return processFilterExpression(filterMap, params)
+	if err := processFilterExpression(filterMap, params); err != nil {
+		return err
+	}
+
+	// Relative orbits and paths are both sent as relativeOrbit, whose values
+	// ASF treats as alternatives, so together they would widen the search
+	if len(params.RelativeOrbit) > 0 && len(params.Path) > 0 {
+		return fmt.Errorf("%w: sat:relative_orbit and asf:path cannot be combined", ErrUnsupportedFilter)
+	}
+	return nil
 }
 
 // processFilterExpression processes a single filter expression node
@@ -215,6 +228,14 @@ func applyPropertyFilter(propName string, value any, params *asf.SearchParams) e
 		return applyProcessingLevelFilter(value, params)
 	case "platform":
 		return applyPlatformFilter(value, params)
+	case "s1:burst_id":
+		return applyBurstIDFilter(value, params)
+	case "asf:frame":
+		return applyRangeFilter(propName, value, &params.Frame)
+	case "asf:path":
+		return applyRangeFilter(propName, value, &params.Path)
+	case "view:off_nadir":
+		return applyRangeFilter(propName, value, &params.OffNadirAngle)
 	default:
 		return fmt.Errorf("%w: property '%s' not supported for filtering", ErrUnsupportedFilter, propName)
 	}
@@ -329,3 +350,33 @@ func applyPlatformFilter(value any, params *asf.SearchParams) error {
 	params.Platform = append(params.Platform, strValue)
 	return nil
 }
+
+// applyBurstIDFilter applies a burst ID filter (s1:burst_id -> fullBurstID)
+func applyBurstIDFilter(value any, params *asf.SearchParams) error {
+	strValue, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%w: s1:burst_id value must be a string", ErrUnsupportedFilter)
+	}
+
+	// Add to burst ID list (ASF accepts multiple values as OR)
+	params.FullBurstID = append(params.FullBurstID, strValue)
+	return nil
+}
+
+// applyRangeFilter applies a numeric filter (asf:frame -> frame, asf:path ->
+// relativeOrbit, view:off_nadir -> offNadirAngle) as a single value range
+func applyRangeFilter(propName string, value any, ranges *[]asf.Range) error {
+	var number float64
+	switch v := value.(type) {
+	case float64:
+		number = v
+	case int:
+		number = float64(v)
+	default:
+		return fmt.Errorf("%w: %s value must be a number", ErrUnsupportedFilter, propName)
+	}
+
+	// Add to range list (ASF accepts multiple values as OR)
+	*ranges = append(*ranges, asf.Range{Min: number, Max: number})
+	return nil
+}
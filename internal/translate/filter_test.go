@@ -1,6 +1,7 @@
 package translate
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/robert-malhotra/asf-stac-proxy/internal/asf"
@@ -393,6 +394,75 @@ func TestTranslateCQL2Filter_RelativeOrbit(t *testing.T) {
 	}
 }
 
+func TestTranslateCQL2Filter_FramePathOffNadir(t *testing.T) {
+	filter := map[string]any{
+		"op": "and",
+		"args": []any{
+			map[string]any{
+				"op": "in",
+				"args": []any{
+					map[string]any{"property": "asf:frame"},
+					[]any{float64(100), float64(150)},
+				},
+			},
+			map[string]any{
+				"op": "=",
+				"args": []any{
+					map[string]any{"property": "asf:path"},
+					float64(422),
+				},
+			},
+			map[string]any{
+				"op": "=",
+				"args": []any{
+					map[string]any{"property": "view:off_nadir"},
+					34.3,
+				},
+			},
+		},
+	}
+
+	params := &asf.SearchParams{}
+	if err := TranslateCQL2Filter(filter, params); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(params.Frame) != 2 || params.Frame[0] != (asf.Range{Min: 100, Max: 100}) || params.Frame[1] != (asf.Range{Min: 150, Max: 150}) {
+		t.Errorf("Expected frames 100 and 150, got %v", params.Frame)
+	}
+	if len(params.Path) != 1 || params.Path[0] != (asf.Range{Min: 422, Max: 422}) {
+		t.Errorf("Expected path 422, got %v", params.Path)
+	}
+	if len(params.OffNadirAngle) != 1 || params.OffNadirAngle[0] != (asf.Range{Min: 34.3, Max: 34.3}) {
+		t.Errorf("Expected off-nadir angle 34.3, got %v", params.OffNadirAngle)
+	}
+}
+
+func TestTranslateCQL2Filter_RelativeOrbitWithPath(t *testing.T) {
+	relativeOrbit := map[string]any{
+		"op": "=",
+		"args": []any{
+			map[string]any{"property": "sat:relative_orbit"},
+			float64(64),
+		},
+	}
+	path := map[string]any{
+		"op": "=",
+		"args": []any{
+			map[string]any{"property": "asf:path"},
+			float64(422),
+		},
+	}
+
+	for _, args := range [][]any{{relativeOrbit, path}, {path, relativeOrbit}} {
+		params := &asf.SearchParams{}
+		err := TranslateCQL2Filter(map[string]any{"op": "and", "args": args}, params)
+		if !errors.Is(err, ErrUnsupportedFilter) {
+			t.Errorf("Expected ErrUnsupportedFilter for relative orbit with path, got %v", err)
+		}
+	}
+}
+
 func TestTranslateCQL2Filter_AbsoluteOrbit(t *testing.T) {
 	filter := map[string]any{
 		"op": "=",
@@ -7,12 +7,16 @@ import (
 
 	"github.com/planetlabs/go-stac"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/asf"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
 	"github.com/robert-malhotra/asf-stac-proxy/pkg/geojson"
 )
 
 // TranslateASFFeatureToItem converts an ASF feature to a STAC Item.
 // Implements property mapping as defined in design doc section 3.3.
-func TranslateASFFeatureToItem(feature *asf.ASFFeature, collectionID, baseURL, stacVersion string) (*stac.Item, error) {
+// Constellation, radar band and processing level come from the platform
+// definitions and processing level maps of collections; with a nil registry
+// they are left out and processing levels passed through.
+func TranslateASFFeatureToItem(feature *asf.ASFFeature, collectionID, baseURL, stacVersion string, collections *config.CollectionRegistry) (*stac.Item, error) {
 	if feature == nil {
 		return nil, fmt.Errorf("feature is nil")
 	}
@@ -82,16 +86,21 @@ func TranslateASFFeatureToItem(feature *asf.ASFFeature, collectionID, baseURL, s
 		item.Properties["platform"] = strings.ToLower(props.Platform)
 	}
 
-	// Map instrument
-	if props.Instrument != "" {
+	// Look up the platform's definition
+	platform := collections.Platform(collectionID, props.Platform)
+
+	// Map instrument, falling back to the platform's
+	instrument := props.Instrument
+	if instrument == "" && platform != nil {
+		instrument = platform.Instrument
+	}
+	if instrument != "" {
 		// Convert to lowercase and create array
-		instrument := strings.ToLower(props.Instrument)
-		item.Properties["instruments"] = []string{instrument}
+		item.Properties["instruments"] = []string{strings.ToLower(instrument)}
 	}
 
-	// Determine constellation from platform
-	if constellation := getConstellation(props.Platform); constellation != "" {
-		item.Properties["constellation"] = constellation
+	if platform != nil && platform.Constellation != "" {
+		item.Properties["constellation"] = platform.Constellation
 	}
 
 	// SAR Extension properties (https://stac-extensions.github.io/sar/v1.0.0/schema.json)
@@ -100,9 +109,15 @@ func TranslateASFFeatureToItem(feature *asf.ASFFeature, collectionID, baseURL, s
 		item.Properties["sar:instrument_mode"] = props.BeamModeType
 	}
 
-	// Determine frequency band from platform/instrument
-	if band := getFrequencyBand(props.Platform, props.Instrument); band != "" {
-		item.Properties["sar:frequency_band"] = band
+	// Frequency band and center frequency of the platform's radar
+	if platform != nil {
+		band, frequency := platform.Band(instrument)
+		if band != "" {
+			item.Properties["sar:frequency_band"] = band
+		}
+		if frequency > 0 {
+			item.Properties["sar:center_frequency"] = frequency
+		}
 	}
 
 	// Parse polarizations (e.g., "VV+VH" -> ["VV", "VH"])
@@ -137,15 +152,54 @@ func TranslateASFFeatureToItem(feature *asf.ASFFeature, collectionID, baseURL, s
 		item.Properties["sat:absolute_orbit"] = *props.AbsoluteOrbit
 	}
 
+	// ASF frame number, which with the relative orbit locates a scene on the
+	// platform's track and frame grid
+	if props.FrameNumber != nil {
+		item.Properties["asf:frame"] = *props.FrameNumber
+	}
+
+	// ASF path number, the relative orbit on the platform's path grid
+	if props.PathNumber != nil {
+		item.Properties["asf:path"] = *props.PathNumber
+	}
+
+	// Sentinel-1 burst properties: the full burst ID locates the burst on
+	// ESA's burst ID map, and the index its position in the source SLC
+	if burst := props.Burst; burst != nil {
+		if burst.FullBurstID != "" {
+			item.Properties["s1:burst_id"] = burst.FullBurstID
+		}
+		if burst.Subswath != "" {
+			item.Properties["s1:subswath"] = burst.Subswath
+		}
+		if burst.BurstIndex != nil {
+			item.Properties["s1:burst_index"] = *burst.BurstIndex
+		}
+	}
+
 	// View Extension properties (https://stac-extensions.github.io/view/v1.0.0/schema.json)
 	if props.OffNadirAngle != nil {
 		item.Properties["view:off_nadir"] = *props.OffNadirAngle
 	}
 
+	// InSAR stack properties. Baselines are only set by baseline searches,
+	// relative to the reference scene of the stack.
+	if props.InsarStackID != nil && *props.InsarStackID != "" && *props.InsarStackID != "NA" {
+		item.Properties["insar:stack_id"] = *props.InsarStackID
+	}
+
+	if props.TemporalBaseline != nil {
+		item.Properties["insar:temporal_baseline"] = *props.TemporalBaseline
+	}
+
+	if props.PerpendicularBaseline != nil {
+		item.Properties["insar:perpendicular_baseline"] = *props.PerpendicularBaseline
+	}
+
 	// Processing Extension (https://stac-extensions.github.io/processing/v1.0.0/schema.json)
 	if props.ProcessingLevel != "" {
 		// Map ASF processing level to STAC processing level
-		item.Properties["processing:level"] = mapProcessingLevel(props.ProcessingLevel)
+		item.Properties["processing:level"] = collections.ProcessingLevel(collectionID, props.ProcessingLevel)
 	}
 
 	// Note: STAC extensions are handled by go-stac during JSON marshaling
@@ -199,76 +253,6 @@ func parsePolarizations(pol string) []string {
 	return result
 }
 
-// getConstellation determines the constellation from the platform name
-func getConstellation(platform string) string {
-	platform = strings.ToLower(platform)
-	switch {
-	case strings.HasPrefix(platform, "sentinel-1"):
-		return "sentinel-1"
-	case strings.HasPrefix(platform, "alos"):
-		return "alos"
-	case strings.HasPrefix(platform, "radarsat"):
-		return "radarsat"
-	case strings.HasPrefix(platform, "ers"):
-		return "ers"
-	default:
-		return ""
-	}
-}
-
-// getFrequencyBand determines the SAR frequency band from platform/instrument
-func getFrequencyBand(platform, instrument string) string {
-	platform = strings.ToLower(platform)
-	instrument = strings.ToLower(instrument)
-
-	// Most SAR satellites use C-band
-	switch {
-	case strings.HasPrefix(platform, "sentinel-1"):
-		return "C"
-	case strings.HasPrefix(platform, "radarsat"):
-		return "C"
-	case strings.HasPrefix(platform, "ers"):
-		return "C"
-	case strings.Contains(platform, "alos") && strings.Contains(instrument, "palsar"):
-		return "L"
-	case strings.Contains(platform, "seasat"):
-		return "L"
-	case strings.Contains(platform, "jers"):
-		return "L"
-	case strings.Contains(platform, "uavsar"):
-		return "L"
-	case strings.Contains(platform, "airsar"):
-		return "C" // AIRSAR can be multiple bands, C is most common
-	case strings.Contains(platform, "sir-c"):
-		return "C" // SIR-C has both C and L band
-	default:
-		return ""
-	}
-}
-
-// mapProcessingLevel maps ASF processing level to STAC processing level
-// ASF uses levels like "SLC", "GRD", "RAW", "L0", "L1", etc.
-// STAC processing extension uses levels like "L0", "L1", "L2", etc.
-func mapProcessingLevel(asfLevel string) string {
-	asfLevel = strings.ToUpper(asfLevel)
-
-	switch asfLevel {
-	case "RAW", "L0":
-		return "L0"
-	case "SLC", "GRD", "L1", "GRD_HS", "GRD_HD", "GRD_MS", "GRD_MD":
-		return "L1"
-	case "L2", "RTC", "GUNW":
-		return "L2"
-	case "L3":
-		return "L3"
-	case "L4":
-		return "L4"
-	default:
-		// Return as-is if we don't recognize it
-		return asfLevel
-	}
-}
-
 // addAssets adds assets (data, thumbnail, browse) to the STAC item
 func addAssets(item *stac.Item, props *asf.ASFProperties) error {
 	// Main data asset
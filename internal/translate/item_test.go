@@ -2,13 +2,27 @@ package translate
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/robert-malhotra/asf-stac-proxy/internal/asf"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
 )
 
+// shippedCollections loads the collections shipped in collections/, whose
+// platform definitions and processing level maps items are translated with
+func shippedCollections(t *testing.T) *config.CollectionRegistry {
+	t.Helper()
+	collections, err := config.LoadCollections("../../collections")
+	if err != nil {
+		t.Fatalf("Failed to load collections: %v", err)
+	}
+	return collections
+}
+
 func TestTranslateASFFeatureToItem_NilFeature(t *testing.T) {
-	_, err := TranslateASFFeatureToItem(nil, "sentinel-1", "https://example.com", "1.0.0")
+	_, err := TranslateASFFeatureToItem(nil, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err == nil {
 		t.Fatal("Expected error for nil feature, got nil")
 	}
@@ -20,7 +34,7 @@ func TestTranslateASFFeatureToItem_MissingID(t *testing.T) {
 		Properties: asf.ASFProperties{},
 	}
 
-	_, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	_, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err == nil {
 		t.Fatal("Expected error for missing ID, got nil")
 	}
@@ -47,7 +61,7 @@ func TestTranslateASFFeatureToItem_Basic(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -82,7 +96,7 @@ func TestTranslateASFFeatureToItem_PlatformMapping(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1-slc", "https://example.com", "1.0.0", shippedCollections(t))
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -107,7 +121,7 @@ func TestTranslateASFFeatureToItem_InstrumentMapping(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -134,7 +148,7 @@ func TestTranslateASFFeatureToItem_SARExtension(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1-slc", "https://example.com", "1.0.0", shippedCollections(t))
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -168,6 +182,8 @@ func TestTranslateASFFeatureToItem_SARExtension(t *testing.T) {
 func TestTranslateASFFeatureToItem_SatelliteExtension(t *testing.T) {
 	relOrbit := 42
 	absOrbit := 12345
+	frame := 1168
+	path := 422
 
 	feature := &asf.ASFFeature{
 		Type: "Feature",
@@ -176,10 +192,12 @@ func TestTranslateASFFeatureToItem_SatelliteExtension(t *testing.T) {
 			FlightDirection: "ASCENDING",
 			RelativeOrbit:   &relOrbit,
 			AbsoluteOrbit:   &absOrbit,
+			FrameNumber:     &frame,
+			PathNumber:      &path,
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -196,6 +214,14 @@ func TestTranslateASFFeatureToItem_SatelliteExtension(t *testing.T) {
 	if item.Properties["sat:absolute_orbit"] != 12345 {
 		t.Errorf("Expected sat:absolute_orbit 12345, got %v", item.Properties["sat:absolute_orbit"])
 	}
+
+	if item.Properties["asf:frame"] != 1168 {
+		t.Errorf("Expected asf:frame 1168, got %v", item.Properties["asf:frame"])
+	}
+
+	if item.Properties["asf:path"] != 422 {
+		t.Errorf("Expected asf:path 422, got %v", item.Properties["asf:path"])
+	}
 }
 
 func TestTranslateASFFeatureToItem_ViewExtension(t *testing.T) {
@@ -209,7 +235,7 @@ func TestTranslateASFFeatureToItem_ViewExtension(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -219,6 +245,86 @@ func TestTranslateASFFeatureToItem_ViewExtension(t *testing.T) {
 	}
 }
 
+func TestTranslateASFFeatureToItem_BurstProperties(t *testing.T) {
+	burstIndex := 7
+
+	feature := &asf.ASFFeature{
+		Type: "Feature",
+		Properties: asf.ASFProperties{
+			FileID:          "S1_136231_IW2_20200604T022312_VV_7C85-BURST",
+			ProcessingLevel: "BURST",
+			Burst: &asf.ASFBurst{
+				FullBurstID: "064_136231_IW2",
+				Subswath:    "IW2",
+				BurstIndex:  &burstIndex,
+			},
+		},
+	}
+
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1-bursts", "https://example.com", "1.0.0", shippedCollections(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if item.Properties["s1:burst_id"] != "064_136231_IW2" {
+		t.Errorf("Expected s1:burst_id 064_136231_IW2, got %v", item.Properties["s1:burst_id"])
+	}
+	if item.Properties["s1:subswath"] != "IW2" {
+		t.Errorf("Expected s1:subswath IW2, got %v", item.Properties["s1:subswath"])
+	}
+	if item.Properties["s1:burst_index"] != 7 {
+		t.Errorf("Expected s1:burst_index 7, got %v", item.Properties["s1:burst_index"])
+	}
+	if item.Properties["processing:level"] != "L1" {
+		t.Errorf("Expected processing:level L1, got %v", item.Properties["processing:level"])
+	}
+}
+
+func TestTranslateASFFeatureToItem_InSARProperties(t *testing.T) {
+	stackID := "1234"
+	temporal := -24
+	perpendicular := 87.5
+
+	feature := &asf.ASFFeature{
+		Type: "Feature",
+		Properties: asf.ASFProperties{
+			FileID:                "test-id",
+			InsarStackID:          &stackID,
+			TemporalBaseline:      &temporal,
+			PerpendicularBaseline: &perpendicular,
+		},
+	}
+
+	item, err := TranslateASFFeatureToItem(feature, "alos-palsar-l1-5", "https://example.com", "1.0.0", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if item.Properties["insar:stack_id"] != "1234" {
+		t.Errorf("Expected insar:stack_id 1234, got %v", item.Properties["insar:stack_id"])
+	}
+	if item.Properties["insar:temporal_baseline"] != -24 {
+		t.Errorf("Expected insar:temporal_baseline -24, got %v", item.Properties["insar:temporal_baseline"])
+	}
+	if item.Properties["insar:perpendicular_baseline"] != 87.5 {
+		t.Errorf("Expected insar:perpendicular_baseline 87.5, got %v", item.Properties["insar:perpendicular_baseline"])
+	}
+
+	// Outside baseline searches the baselines are unset, and ASF reports
+	// scenes outside any stack as "NA"
+	notApplicable := "NA"
+	feature.Properties = asf.ASFProperties{FileID: "test-id", InsarStackID: &notApplicable}
+	item, err = TranslateASFFeatureToItem(feature, "alos-palsar-l1-5", "https://example.com", "1.0.0", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, key := range []string{"insar:stack_id", "insar:temporal_baseline", "insar:perpendicular_baseline"} {
+		if v, ok := item.Properties[key]; ok {
+			t.Errorf("Expected no %s, got %v", key, v)
+		}
+	}
+}
+
 func TestTranslateASFFeatureToItem_TemporalProperties(t *testing.T) {
 	feature := &asf.ASFFeature{
 		Type: "Feature",
@@ -229,7 +335,7 @@ func TestTranslateASFFeatureToItem_TemporalProperties(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -256,7 +362,7 @@ func TestTranslateASFFeatureToItem_Links(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -290,7 +396,7 @@ func TestTranslateASFFeatureToItem_Assets(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -338,7 +444,7 @@ func TestTranslateASFFeatureToItem_FallbackToSceneName(t *testing.T) {
 		},
 	}
 
-	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0")
+	item, err := TranslateASFFeatureToItem(feature, "sentinel-1", "https://example.com", "1.0.0", nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -414,83 +520,175 @@ func TestParsePolarizations(t *testing.T) {
 	}
 }
 
-func TestGetConstellation(t *testing.T) {
+func TestTranslateASFFeatureToItem_PlatformDefinitions(t *testing.T) {
+	collections := shippedCollections(t)
+
 	tests := []struct {
-		platform     string
-		expectedConst string
+		platform          string
+		instrument        string
+		wantConstellation any
+		wantBand          any
+		wantFrequency     any
 	}{
-		{"Sentinel-1A", "sentinel-1"},
-		{"Sentinel-1B", "sentinel-1"},
-		{"ALOS", "alos"},
-		{"ALOS-2", "alos"},
-		{"RADARSAT-1", "radarsat"},
-		{"RADARSAT-2", "radarsat"},
-		{"ERS-1", "ers"},
-		{"ERS-2", "ers"},
-		{"Unknown", ""},
+		{"Sentinel-1A", "C-SAR", "sentinel-1", "C", 5.405},
+		{"Sentinel-1B", "", "sentinel-1", "C", 5.405},
+		{"ALOS", "PALSAR", "alos", "L", 1.27},
+		{"RADARSAT-1", "", "radarsat", "C", 5.3},
+		{"ERS-1", "", "ers", "C", 5.3},
+		{"ERS-2", "", "ers", "C", 5.3},
+		{"SEASAT 1", "", "seasat", "L", 1.275},
+		{"JERS-1", "", "jers", "L", 1.275},
+		{"G-III", "", nil, "L", 1.2575},
+		{"NISAR", "L-SAR", "nisar", "L", 1.257},
+		{"NISAR", "S-SAR", "nisar", "S", 3.2},
+		{"Unknown", "", nil, nil, nil},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.platform, func(t *testing.T) {
-			result := getConstellation(tt.platform)
-			if result != tt.expectedConst {
-				t.Errorf("Expected constellation %s for platform %s, got %s", tt.expectedConst, tt.platform, result)
+		t.Run(tt.platform+"/"+tt.instrument, func(t *testing.T) {
+			feature := &asf.ASFFeature{
+				Type: "Feature",
+				Properties: asf.ASFProperties{
+					FileID:     "test-id",
+					Platform:   tt.platform,
+					Instrument: tt.instrument,
+				},
+			}
+
+			// Items of no known collection use any collection's definition
+			item, err := TranslateASFFeatureToItem(feature, "", "https://example.com", "1.0.0", collections)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got := item.Properties["constellation"]; got != tt.wantConstellation {
+				t.Errorf("Expected constellation %v, got %v", tt.wantConstellation, got)
+			}
+			if got := item.Properties["sar:frequency_band"]; got != tt.wantBand {
+				t.Errorf("Expected sar:frequency_band %v, got %v", tt.wantBand, got)
+			}
+			if got := item.Properties["sar:center_frequency"]; got != tt.wantFrequency {
+				t.Errorf("Expected sar:center_frequency %v, got %v", tt.wantFrequency, got)
 			}
 		})
 	}
 }
 
-func TestGetFrequencyBand(t *testing.T) {
+func TestTranslateASFFeatureToItem_ProcessingLevels(t *testing.T) {
+	collections := shippedCollections(t)
+
 	tests := []struct {
-		platform   string
-		instrument string
+		collection string
+		input      string
 		expected   string
 	}{
-		{"Sentinel-1A", "C-SAR", "C"},
-		{"RADARSAT-1", "", "C"},
-		{"ERS-1", "", "C"},
-		{"ALOS", "PALSAR", "L"},
-		{"SEASAT", "", "L"},
-		{"JERS-1", "", "L"},
-		{"UAVSAR", "", "L"},
-		{"Unknown", "", ""},
+		{"sentinel-1-raw", "RAW", "L0"},
+		{"sentinel-1-slc", "SLC", "L1"},
+		{"sentinel-1-grd-hd", "GRD_HS", "L1"},
+		{"sentinel-1-grd-hd", "GRD_HD", "L1"},
+		{"sentinel-1-ocn", "OCN", "L2"},
+		{"alos-palsar-l1-5", "L1.5", "L1"},
+		{"ers-l0", "L0", "L0"},
+		{"opera-rtc-s1", "RTC", "L2"},
+		{"opera-disp-s1", "DISP-S1", "L3"},
+		{"nisar", "GUNW", "L2"},
+		// Levels a collection does not map are not taken from other
+		// collections, unless the item is of no known collection
+		{"sentinel-1-slc", "GCOV", "GCOV"},
+		{"", "SLC", "L1"},
+		{"sentinel-1", "GCOV", "L2"},
+		{"", "l4", "L4"},
+		{"", "UNKNOWN", "UNKNOWN"},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.platform, func(t *testing.T) {
-			result := getFrequencyBand(tt.platform, tt.instrument)
-			if result != tt.expected {
-				t.Errorf("Expected band %s for platform %s, got %s", tt.expected, tt.platform, result)
+		t.Run(tt.collection+"/"+tt.input, func(t *testing.T) {
+			feature := &asf.ASFFeature{
+				Type: "Feature",
+				Properties: asf.ASFProperties{
+					FileID:          "test-id",
+					ProcessingLevel: tt.input,
+				},
+			}
+
+			item, err := TranslateASFFeatureToItem(feature, tt.collection, "https://example.com", "1.0.0", collections)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := item.Properties["processing:level"]; got != tt.expected {
+				t.Errorf("Expected %s for input %s, got %v", tt.expected, tt.input, got)
 			}
 		})
 	}
 }
 
-func TestMapProcessingLevel(t *testing.T) {
+func TestTranslateASFFeatureToItem_Fixtures(t *testing.T) {
+	collections := shippedCollections(t)
+
+	// Recorded ASF search responses of the collections, by collection ID
 	tests := []struct {
-		input    string
-		expected string
+		collection    string
+		platform      string
+		constellation any
+		instrument    string
+		band          string
+		frequency     float64
+		level         string
 	}{
-		{"RAW", "L0"},
-		{"L0", "L0"},
-		{"SLC", "L1"},
-		{"GRD", "L1"},
-		{"GRD_HS", "L1"},
-		{"GRD_HD", "L1"},
-		{"L1", "L1"},
-		{"RTC", "L2"},
-		{"GUNW", "L2"},
-		{"L2", "L2"},
-		{"L3", "L3"},
-		{"L4", "L4"},
-		{"UNKNOWN", "UNKNOWN"},
+		{"opera-rtc-s1", "sentinel-1a", "sentinel-1", "c-sar", "C", 5.405, "L2"},
+		{"opera-cslc-s1", "sentinel-1a", "sentinel-1", "c-sar", "C", 5.405, "L2"},
+		{"opera-disp-s1", "sentinel-1a", "sentinel-1", "c-sar", "C", 5.405, "L3"},
+		{"nisar", "nisar", "nisar", "l-sar", "L", 1.257, "L2"},
+		{"jers-1", "jers-1", "jers", "sar", "L", 1.275, "L1"},
+		{"seasat", "seasat 1", "seasat", "sar", "L", 1.275, "L1"},
+		{"sir-c", "sir-c", "sir-c", "sir-c", "C", 5.298, "L1"},
+		{"airsar", "dc-8", nil, "airsar", "C", 5.31, "L1"},
+		{"uavsar", "g-iii", nil, "uavsar", "L", 1.2575, "L1"},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			result := mapProcessingLevel(tt.input)
-			if result != tt.expected {
-				t.Errorf("Expected %s for input %s, got %s", tt.expected, tt.input, result)
+		t.Run(tt.collection, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "asf", tt.collection+".json"))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+			var resp asf.ASFGeoJSONResponse
+			if err := json.Unmarshal(data, &resp); err != nil {
+				t.Fatalf("Failed to parse fixture: %v", err)
+			}
+			if len(resp.Features) != 1 {
+				t.Fatalf("Expected one feature, got %d", len(resp.Features))
+			}
+
+			item, err := TranslateASFFeatureToItem(&resp.Features[0], tt.collection, "https://example.com", "1.0.0", collections)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			props := item.Properties
+			if props["platform"] != tt.platform {
+				t.Errorf("Expected platform %s, got %v", tt.platform, props["platform"])
+			}
+			if props["constellation"] != tt.constellation {
+				t.Errorf("Expected constellation %v, got %v", tt.constellation, props["constellation"])
+			}
+			if instruments, ok := props["instruments"].([]string); !ok || len(instruments) != 1 || instruments[0] != tt.instrument {
+				t.Errorf("Expected instruments [%s], got %v", tt.instrument, props["instruments"])
+			}
+			if props["sar:frequency_band"] != tt.band {
+				t.Errorf("Expected sar:frequency_band %s, got %v", tt.band, props["sar:frequency_band"])
+			}
+			if props["sar:center_frequency"] != tt.frequency {
+				t.Errorf("Expected sar:center_frequency %v, got %v", tt.frequency, props["sar:center_frequency"])
+			}
+			if props["processing:level"] != tt.level {
+				t.Errorf("Expected processing:level %s, got %v", tt.level, props["processing:level"])
+			}
+			if item.Geometry == nil || len(item.Bbox) != 4 {
+				t.Errorf("Expected a geometry and bbox, got %v and %v", item.Geometry, item.Bbox)
+			}
+			if _, ok := item.Assets["data"]; !ok {
+				t.Error("Expected a data asset")
 			}
 		})
 	}
@@ -121,7 +121,9 @@ func (t *Translator) TranslateSTACSearchToASF(req *stac.SearchRequest, collectio
 	// Pagination is handled by the proxy via next/prev links.
 
 	// Map sortby to ASF sort parameters
-	// Note: ASF API doesn't support sort direction, results are always descending
+	// Note: ASF API doesn't support sort direction, results are always descending.
+	// Ascending order and secondary sort keys are established by the proxy,
+	// which walks forward in time windows and sorts within each page.
 	if len(req.Sortby) > 0 {
 		// ASF only supports single field sorting, use the first one
 		sortby := req.Sortby[0]
@@ -130,10 +132,6 @@ func (t *Translator) TranslateSTACSearchToASF(req *stac.SearchRequest, collectio
 			t.logger.Warn("unsupported sort field, ignoring", "field", sortby.Field)
 		} else {
 			params.Sort = asfSort
-			// ASF doesn't support sort direction, log if user requested ascending
-			if sortby.Direction == "asc" {
-				t.logger.Warn("ASF API doesn't support ascending sort, using default descending order")
-			}
 		}
 	}
 
@@ -153,7 +151,7 @@ func (t *Translator) TranslateSTACSearchToASF(req *stac.SearchRequest, collectio
 // TranslateASFFeatureToSTACItem converts an ASF feature to a STAC item.
 func (t *Translator) TranslateASFFeatureToSTACItem(feature *asf.ASFFeature, collectionID string) (*stac.Item, error) {
 	// Use the detailed translation function from item.go
-	return TranslateASFFeatureToItem(feature, collectionID, t.cfg.STAC.BaseURL, t.cfg.STAC.Version)
+	return TranslateASFFeatureToItem(feature, collectionID, t.cfg.STAC.BaseURL, t.cfg.STAC.Version, t.collections)
 }
 
 // TranslateASFResponseToItemCollection converts an ASF response to a STAC ItemCollection.
@@ -0,0 +1,237 @@
+// Package upstream provides the HTTP layer shared by the ASF and CMR clients:
+// connection pooling, retries of idempotent requests with exponential backoff
+// and jitter, Retry-After handling, and structured errors.
+package upstream
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/ratelimit"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
+)
+
+// RetryPolicy controls how failed idempotent requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of requests made, including the
+	// first. 1 disables retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry; it doubles on each
+	// further retry
+	BaseDelay time.Duration
+	// MaxDelay caps any single delay. A Retry-After longer than MaxDelay
+	// ends the retries.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used unless one is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   250 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+// Response is a successful upstream response with its body read.
+type Response struct {
+	Header http.Header
+	Body   []byte
+}
+
+// Client executes requests against an upstream service.
+type Client struct {
+	service    string
+	httpClient *http.Client
+	policy     RetryPolicy
+	logger     *slog.Logger
+	metrics    *metrics.Metrics
+	slots      *ratelimit.Semaphore
+	jitter     func() float64
+	now        func() time.Time
+}
+
+// NewClient creates a client for the named upstream service. timeout bounds
+// each attempt; the caller's context bounds the request as a whole,
+// retries included.
+func NewClient(service string, timeout time.Duration) *Client {
+	return &Client{
+		service: service,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		policy: DefaultRetryPolicy(),
+		logger: slog.Default(),
+		jitter: rand.Float64,
+		now:    time.Now,
+	}
+}
+
+// WithLogger sets a custom logger for the client.
+func (c *Client) WithLogger(logger *slog.Logger) *Client {
+	c.logger = logger
+	return c
+}
+
+// WithMetrics records the client's attempts, retries and failures.
+func (c *Client) WithMetrics(m *metrics.Metrics) *Client {
+	c.metrics = m
+	return c
+}
+
+// WithConcurrencyLimit makes each attempt take a slot of slots, which may be
+// shared with other clients to cap the upstream calls in flight across them.
+func (c *Client) WithConcurrencyLimit(slots *ratelimit.Semaphore) *Client {
+	c.slots = slots
+	return c
+}
+
+// WithRetryPolicy sets the retry policy for the client.
+func (c *Client) WithRetryPolicy(policy RetryPolicy) *Client {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	c.policy = policy
+	return c
+}
+
+// Do executes a request and returns its response when the status is 200 OK.
+// GET and HEAD requests that fail transiently are retried according to the
+// retry policy, as long as the context deadline leaves room for the wait.
+// Any failure is returned as an *Error, except when no slot of the
+// concurrency limit could be taken: the error then wraps
+// ratelimit.ErrSaturated or the context's error.
+func (c *Client) Do(req *http.Request) (*Response, error) {
+	ctx := req.Context()
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	for attempt := 1; ; attempt++ {
+		if c.slots != nil {
+			if err := c.slots.Acquire(ctx); err != nil {
+				return nil, fmt.Errorf("%s: %w", c.service, err)
+			}
+		}
+		resp, err := c.attempt(req, attempt)
+		if c.slots != nil {
+			c.slots.Release()
+		}
+		if err == nil {
+			return resp, nil
+		}
+		err.Attempts = attempt
+
+		if !err.Retryable || !idempotent || attempt >= c.policy.MaxAttempts {
+			return nil, c.fail(err)
+		}
+
+		delay := c.backoff(attempt)
+		if err.RetryAfter > 0 {
+			if err.RetryAfter > c.policy.MaxDelay {
+				return nil, c.fail(err)
+			}
+			delay = err.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && c.now().Add(delay).After(deadline) {
+			// The retry could not complete before the caller gives up
+			return nil, c.fail(err)
+		}
+
+		c.logger.WarnContext(ctx, "retrying upstream request",
+			slog.String("service", c.service),
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.String("error", err.Error()),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			c.metrics.UpstreamRetry(c.backend())
+		case <-ctx.Done():
+			timer.Stop()
+			err.Retryable = false
+			err.Err = ctx.Err()
+			return nil, c.fail(err)
+		}
+	}
+}
+
+// fail records a request that failed for good
+func (c *Client) fail(err *Error) *Error {
+	c.metrics.UpstreamError(c.backend(), err.Retryable)
+	return err
+}
+
+// backend returns the backend label of the client's metrics
+func (c *Client) backend() string {
+	return strings.ToLower(c.service)
+}
+
+// attempt makes a single request, traced as a client span whose context is
+// propagated to the upstream
+func (c *Client) attempt(req *http.Request, attempt int) (*Response, *Error) {
+	ctx, span := tracing.StartKind(req.Context(), req.Method, tracing.KindClient)
+	defer span.End()
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("server.address", req.URL.Host)
+	span.SetAttribute("url.full", req.URL.String())
+	span.SetAttribute("upstream.service", c.service)
+	if attempt > 1 {
+		span.SetAttribute("http.request.resend_count", attempt-1)
+	}
+
+	out := req.Clone(ctx)
+	tracing.Inject(ctx, out.Header)
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(out)
+	if err != nil {
+		c.metrics.ObserveUpstreamAttempt(c.backend(), 0, time.Since(start))
+		span.RecordError(err)
+		// A transport failure is transient unless the caller gave up
+		return nil, &Error{Service: c.service, Err: err, Retryable: ctx.Err() == nil}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	c.metrics.ObserveUpstreamAttempt(c.backend(), resp.StatusCode, time.Since(start))
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		span.SetError(http.StatusText(resp.StatusCode))
+		return nil, &Error{
+			Service:    c.service,
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			Retryable:  retryableStatus(resp.StatusCode),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), c.now()),
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, &Error{Service: c.service, Err: err, Retryable: ctx.Err() == nil}
+	}
+	return &Response{Header: resp.Header, Body: body}, nil
+}
+
+// backoff returns the delay before the retry following the given attempt:
+// BaseDelay doubled for each earlier retry, capped at MaxDelay, and
+// jittered down by up to half so that clients do not retry in lockstep
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.policy.BaseDelay
+	for i := 1; i < attempt && delay < c.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, c.policy.MaxDelay)
+	return delay/2 + time.Duration(c.jitter()*float64(delay/2))
+}
@@ -0,0 +1,325 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/ratelimit"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
+)
+
+// newTestClient returns a client with short, deterministic backoff
+func newTestClient() *Client {
+	c := NewClient("ASF", 5*time.Second).WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    50 * time.Millisecond,
+	})
+	c.jitter = func() float64 { return 0 }
+	return c
+}
+
+func get(t *testing.T, ctx context.Context, c *Client, url string) (*Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Do(req)
+}
+
+func TestClient_RetriesTransientFailures(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			// Drop the connection without a response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer server.Close()
+
+	resp, err := get(t, context.Background(), newTestClient(), server.URL)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if string(resp.Body) != `{"ok":true}` {
+		t.Errorf("Body = %s", resp.Body)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected 3 requests, got %d", n)
+	}
+}
+
+func TestClient_TerminalFailureIsNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "bad polygon", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	_, err := get(t, context.Background(), newTestClient(), server.URL)
+
+	var upstreamErr *Error
+	if !errors.As(err, &upstreamErr) {
+		t.Fatalf("Expected an *Error, got %v", err)
+	}
+	if upstreamErr.StatusCode != http.StatusBadRequest || upstreamErr.Retryable || upstreamErr.Attempts != 1 {
+		t.Errorf("Unexpected error %+v", upstreamErr)
+	}
+	if upstreamErr.Error() != "ASF API returned status 400: bad polygon\n" {
+		t.Errorf("Error() = %q", upstreamErr.Error())
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+}
+
+func TestClient_RetriesExhausted(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, err := get(t, context.Background(), newTestClient(), server.URL)
+	if !IsRetryable(err) {
+		t.Errorf("Expected a retryable error, got %v", err)
+	}
+	var upstreamErr *Error
+	if errors.As(err, &upstreamErr) && upstreamErr.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", upstreamErr.Attempts)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected 3 requests, got %d", n)
+	}
+}
+
+func TestClient_RetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// A Retry-After longer than the maximum delay ends the retries
+	_, err := get(t, context.Background(), newTestClient(), server.URL)
+	var upstreamErr *Error
+	if !errors.As(err, &upstreamErr) || upstreamErr.RetryAfter != time.Second || !upstreamErr.Retryable {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+
+	// So does a wait that would outlast the caller's deadline
+	requests.Store(0)
+	client := newTestClient().WithRetryPolicy(RetryPolicy{MaxAttempts: 3, MaxDelay: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := get(t, ctx, client, server.URL); !IsRetryable(err) {
+		t.Errorf("Expected a retryable error, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Expected to give up without waiting, took %s", elapsed)
+	}
+}
+
+func TestClient_DoesNotRetryAfterCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		cancel()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := get(t, ctx, newTestClient().WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Second}), server.URL)
+	if err == nil || IsRetryable(err) {
+		t.Errorf("Expected a terminal error, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+}
+
+func TestClient_Backoff(t *testing.T) {
+	c := NewClient("CMR", time.Second).WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+	})
+
+	c.jitter = func() float64 { return 0.999999 }
+	for attempt, want := range map[int]time.Duration{1: 100, 2: 200, 3: 400, 4: 800, 5: 1000, 60: 1000} {
+		if got := c.backoff(attempt).Round(time.Millisecond); got != want*time.Millisecond {
+			t.Errorf("backoff(%d) = %s, want %s", attempt, got, want*time.Millisecond)
+		}
+	}
+
+	// Jitter shortens a delay by up to half
+	c.jitter = func() float64 { return 0 }
+	if got := c.backoff(2); got != 100*time.Millisecond {
+		t.Errorf("backoff(2) with no jitter = %s, want 100ms", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2024 00:00:30 GMT": 30 * time.Second,
+		"Sun, 31 Dec 2023 23:59:00 GMT": 0,
+	}
+	for value, want := range tests {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestClient_Metrics(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		http.Error(w, "bad polygon", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	m := metrics.New()
+	get(t, context.Background(), newTestClient().WithMetrics(m), server.URL)
+
+	var b strings.Builder
+	m.Registry().Write(&b)
+	for _, line := range []string{
+		`asf_stac_upstream_requests_total{backend="asf",status="400"} 1`,
+		`asf_stac_upstream_requests_total{backend="asf",status="502"} 1`,
+		`asf_stac_upstream_retries_total{backend="asf"} 1`,
+		`asf_stac_upstream_errors_total{backend="asf",kind="terminal"} 1`,
+		`asf_stac_upstream_request_duration_seconds_count{backend="asf"} 2`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestClient_Tracing(t *testing.T) {
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get(tracing.TraceparentHeader))
+		if len(traceparents) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	recorder := tracing.NewRecorder()
+	ctx, root := tracing.NewTracer("test", recorder).Start(context.Background(), "root", tracing.KindServer)
+	if _, err := get(t, ctx, newTestClient(), server.URL); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	root.End()
+
+	// Each attempt is a client span, whose context is sent upstream
+	spans := recorder.Spans()
+	if len(spans) != 3 || len(traceparents) != 2 {
+		t.Fatalf("Expected 2 attempt spans and 2 requests, got %d spans and %d requests", len(spans)-1, len(traceparents))
+	}
+	for i, span := range spans[:2] {
+		if span.Kind != tracing.KindClient || span.Parent != root.Context().SpanID {
+			t.Errorf("Unexpected attempt span %+v", span)
+		}
+		if traceparents[i] != span.Context.Traceparent() {
+			t.Errorf("Attempt %d sent traceparent %q, want %q", i+1, traceparents[i], span.Context.Traceparent())
+		}
+	}
+	if spans[0].Status != tracing.StatusError || spans[0].Attributes["http.response.status_code"] != http.StatusServiceUnavailable {
+		t.Errorf("Unexpected failed attempt %+v", spans[0])
+	}
+	if spans[1].Attributes["http.request.resend_count"] != 1 || spans[1].Status != tracing.StatusUnset {
+		t.Errorf("Unexpected retry %+v", spans[1])
+	}
+}
+
+func TestClient_ConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	// Two clients share two slots
+	slots := ratelimit.NewSemaphore(2, 50*time.Millisecond)
+	asf := newTestClient().WithConcurrencyLimit(slots)
+	cmr := newTestClient().WithConcurrencyLimit(slots)
+
+	errs := make(chan error, 2)
+	for _, c := range []*Client{asf, cmr} {
+		go func() {
+			_, err := get(t, context.Background(), c, server.URL)
+			errs <- err
+		}()
+	}
+	for slots.InFlight() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := get(t, context.Background(), asf, server.URL)
+	if !errors.Is(err, ratelimit.ErrSaturated) {
+		t.Errorf("Do() with every slot taken error = %v, want ErrSaturated", err)
+	}
+	var upstreamErr *Error
+	if errors.As(err, &upstreamErr) {
+		t.Error("saturation reported as an upstream failure, which would trip the circuit breaker")
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Do() error = %v", err)
+		}
+	}
+	if peak.Load() != 2 || slots.InFlight() != 0 {
+		t.Errorf("peak %d concurrent requests with %d slots still taken, want 2 and 0", peak.Load(), slots.InFlight())
+	}
+}
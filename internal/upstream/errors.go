@@ -0,0 +1,88 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error is a failed upstream request. Retryable failures are transient (a
+// dropped connection, a 429 or a 503) and may succeed if the request is
+// repeated later; terminal failures will not.
+type Error struct {
+	// Service names the upstream service, such as "ASF" or "CMR"
+	Service string
+	// StatusCode is the HTTP status of the last attempt, or 0 when no
+	// response was received
+	StatusCode int
+	// Body is the response body of the last attempt, if any
+	Body string
+	// Attempts is the number of requests made
+	Attempts int
+	// Retryable reports whether the last failure was transient. A retryable
+	// error is returned once the retry budget is spent.
+	Retryable bool
+	// RetryAfter is the delay requested by the last response's Retry-After
+	// header, if any
+	RetryAfter time.Duration
+	// Err is the transport error of the last attempt, if any
+	Err error
+}
+
+func (e *Error) Error() string {
+	var msg string
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("%s API returned status %d: %s", e.Service, e.StatusCode, e.Body)
+	} else {
+		msg = fmt.Sprintf("%s API request failed: %v", e.Service, e.Err)
+	}
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" (after %d attempts)", e.Attempts)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether err is a transient upstream failure.
+func IsRetryable(err error) bool {
+	var upstreamErr *Error
+	return errors.As(err, &upstreamErr) && upstreamErr.Retryable
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date. It returns 0 when the header is absent or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -44,6 +44,32 @@ func (g *Geometry) LineString() ([][]float64, error) {
 	return coords, nil
 }
 
+// MultiPoint returns the coordinates as a MultiPoint [][lon, lat].
+// Returns error if geometry is not a MultiPoint.
+func (g *Geometry) MultiPoint() ([][]float64, error) {
+	if g.Type != "MultiPoint" {
+		return nil, fmt.Errorf("geometry is not a MultiPoint, got %s", g.Type)
+	}
+	var coords [][]float64
+	if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal MultiPoint coordinates: %w", err)
+	}
+	return coords, nil
+}
+
+// MultiLineString returns the coordinates as a MultiLineString [][][lon, lat].
+// Returns error if geometry is not a MultiLineString.
+func (g *Geometry) MultiLineString() ([][][]float64, error) {
+	if g.Type != "MultiLineString" {
+		return nil, fmt.Errorf("geometry is not a MultiLineString, got %s", g.Type)
+	}
+	var coords [][][]float64
+	if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal MultiLineString coordinates: %w", err)
+	}
+	return coords, nil
+}
+
 // Polygon returns the coordinates as a Polygon [][][lon, lat].
 // Returns error if geometry is not a Polygon.
 func (g *Geometry) Polygon() ([][][]float64, error) {
@@ -94,8 +120,14 @@ func ComputeBBox(g *Geometry) ([]float64, error) {
 		}
 		return []float64{coords[0], coords[1], coords[0], coords[1]}, nil
 
-	case "LineString":
-		coords, err := g.LineString()
+	case "LineString", "MultiPoint":
+		var coords [][]float64
+		var err error
+		if g.Type == "LineString" {
+			coords, err = g.LineString()
+		} else {
+			coords, err = g.MultiPoint()
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -109,8 +141,14 @@ func ComputeBBox(g *Geometry) ([]float64, error) {
 			maxLat = math.Max(maxLat, point[1])
 		}
 
-	case "Polygon":
-		coords, err := g.Polygon()
+	case "Polygon", "MultiLineString":
+		var coords [][][]float64
+		var err error
+		if g.Type == "Polygon" {
+			coords, err = g.Polygon()
+		} else {
+			coords, err = g.MultiLineString()
+		}
 		if err != nil {
 			return nil, err
 		}
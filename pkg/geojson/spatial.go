@@ -0,0 +1,519 @@
+package geojson
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Spatial predicates operate on planar longitude/latitude coordinates with
+// exact floating point arithmetic. Boundaries are inclusive: a point on the
+// edge of a polygon is inside it, and a polygon contains itself. Geometries
+// crossing the antimeridian are not split.
+
+// location is the position of a point relative to a geometry
+type location int
+
+const (
+	outside location = iota
+	onBoundary
+	inside
+)
+
+// shape is a geometry decomposed into its primitive parts
+type shape struct {
+	points   [][]float64
+	lines    [][][]float64
+	polygons [][][][]float64
+}
+
+// Intersects reports whether two geometries share at least one point.
+func Intersects(a, b *Geometry) (bool, error) {
+	sa, err := decompose(a)
+	if err != nil {
+		return false, err
+	}
+	sb, err := decompose(b)
+	if err != nil {
+		return false, err
+	}
+	return sa.intersects(sb), nil
+}
+
+// Disjoint reports whether two geometries share no point.
+func Disjoint(a, b *Geometry) (bool, error) {
+	intersects, err := Intersects(a, b)
+	if err != nil {
+		return false, err
+	}
+	return !intersects, nil
+}
+
+// Contains reports whether no point of b lies outside a. Points of b may lie
+// on the boundary of a. An empty b is not contained by anything.
+//
+// A MultiPolygon contains b when its parts cover b together, so b may span
+// polygons that share an edge.
+func Contains(a, b *Geometry) (bool, error) {
+	sa, err := decompose(a)
+	if err != nil {
+		return false, err
+	}
+	sb, err := decompose(b)
+	if err != nil {
+		return false, err
+	}
+	return sa.contains(sb), nil
+}
+
+// Within reports whether no point of a lies outside b.
+func Within(a, b *Geometry) (bool, error) {
+	return Contains(b, a)
+}
+
+// PointInPolygon reports whether a point lies inside a polygon or on its
+// boundary. The first ring is the exterior; further rings are holes.
+func PointInPolygon(point []float64, polygon [][][]float64) bool {
+	return polygonLocation(point, polygon) != outside
+}
+
+// SegmentsIntersect reports whether the closed segments p1-p2 and q1-q2 share
+// at least one point, including touching endpoints and collinear overlap.
+func SegmentsIntersect(p1, p2, q1, q2 []float64) bool {
+	o1 := orientation(p1, p2, q1)
+	o2 := orientation(p1, p2, q2)
+	o3 := orientation(q1, q2, p1)
+	o4 := orientation(q1, q2, p2)
+
+	if o1 != o2 && o3 != o4 {
+		return true
+	}
+
+	// Collinear cases: an endpoint of one segment lies on the other
+	return (o1 == 0 && onSegment(q1, p1, p2)) ||
+		(o2 == 0 && onSegment(q2, p1, p2)) ||
+		(o3 == 0 && onSegment(p1, q1, q2)) ||
+		(o4 == 0 && onSegment(p2, q1, q2))
+}
+
+// decompose splits a geometry into points, lines and polygons, validating
+// that every position has at least two values
+func decompose(g *Geometry) (*shape, error) {
+	if g == nil {
+		return nil, fmt.Errorf("geometry is nil")
+	}
+
+	s := &shape{}
+	switch g.Type {
+	case "Point":
+		coords, err := g.Point()
+		if err != nil {
+			return nil, err
+		}
+		s.points = [][]float64{coords}
+	case "MultiPoint":
+		coords, err := g.MultiPoint()
+		if err != nil {
+			return nil, err
+		}
+		s.points = coords
+	case "LineString":
+		coords, err := g.LineString()
+		if err != nil {
+			return nil, err
+		}
+		s.lines = [][][]float64{coords}
+	case "MultiLineString":
+		coords, err := g.MultiLineString()
+		if err != nil {
+			return nil, err
+		}
+		s.lines = coords
+	case "Polygon":
+		coords, err := g.Polygon()
+		if err != nil {
+			return nil, err
+		}
+		s.polygons = [][][][]float64{coords}
+	case "MultiPolygon":
+		coords, err := g.MultiPolygon()
+		if err != nil {
+			return nil, err
+		}
+		s.polygons = coords
+	default:
+		return nil, fmt.Errorf("unsupported geometry type: %s", g.Type)
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", g.Type, err)
+	}
+	return s, nil
+}
+
+func (s *shape) validate() error {
+	for _, p := range s.points {
+		if len(p) < 2 {
+			return fmt.Errorf("position must have at least 2 values")
+		}
+	}
+	for _, line := range s.lines {
+		if len(line) < 2 {
+			return fmt.Errorf("line must have at least 2 positions")
+		}
+		for _, p := range line {
+			if len(p) < 2 {
+				return fmt.Errorf("position must have at least 2 values")
+			}
+		}
+	}
+	for _, polygon := range s.polygons {
+		if len(polygon) == 0 {
+			return fmt.Errorf("polygon must have an exterior ring")
+		}
+		for _, ring := range polygon {
+			if len(ring) < 3 {
+				return fmt.Errorf("ring must have at least 3 positions")
+			}
+			for _, p := range ring {
+				if len(p) < 2 {
+					return fmt.Errorf("position must have at least 2 values")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *shape) empty() bool {
+	return len(s.points) == 0 && len(s.lines) == 0 && len(s.polygons) == 0
+}
+
+// bbox returns [west, south, east, north], or ok=false for an empty shape
+func (s *shape) bbox() ([]float64, bool) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	add := func(p []float64) {
+		minX = math.Min(minX, p[0])
+		maxX = math.Max(maxX, p[0])
+		minY = math.Min(minY, p[1])
+		maxY = math.Max(maxY, p[1])
+	}
+	for _, p := range s.points {
+		add(p)
+	}
+	for _, line := range s.lines {
+		for _, p := range line {
+			add(p)
+		}
+	}
+	for _, polygon := range s.polygons {
+		for _, p := range polygon[0] {
+			add(p)
+		}
+	}
+	if math.IsInf(minX, 0) {
+		return nil, false
+	}
+	return []float64{minX, minY, maxX, maxY}, true
+}
+
+// segments returns every line segment and polygon ring edge of the shape
+func (s *shape) segments() [][2][]float64 {
+	var out [][2][]float64
+	for _, line := range s.lines {
+		for i := 1; i < len(line); i++ {
+			out = append(out, [2][]float64{line[i-1], line[i]})
+		}
+	}
+	for _, polygon := range s.polygons {
+		for _, ring := range polygon {
+			out = append(out, ringSegments(ring)...)
+		}
+	}
+	return out
+}
+
+// vertices returns one position from every line and polygon of the shape.
+// When no segments of two shapes cross, each line and polygon lies entirely
+// inside or outside the other shape, so one position per part decides it.
+func (s *shape) vertices() [][]float64 {
+	var out [][]float64
+	for _, line := range s.lines {
+		out = append(out, line[0])
+	}
+	for _, polygon := range s.polygons {
+		out = append(out, polygon[0][0])
+	}
+	return out
+}
+
+// locate returns the position of a point relative to the shape
+func (s *shape) locate(p []float64) location {
+	result := outside
+	for _, q := range s.points {
+		if p[0] == q[0] && p[1] == q[1] {
+			result = onBoundary
+		}
+	}
+	for _, line := range s.lines {
+		for i := 1; i < len(line); i++ {
+			if onSegment(p, line[i-1], line[i]) {
+				result = onBoundary
+			}
+		}
+	}
+	for _, polygon := range s.polygons {
+		switch polygonLocation(p, polygon) {
+		case inside:
+			return inside
+		case onBoundary:
+			result = onBoundary
+		}
+	}
+	return result
+}
+
+func (s *shape) intersects(other *shape) bool {
+	if s.empty() || other.empty() {
+		return false
+	}
+	a, _ := s.bbox()
+	b, _ := other.bbox()
+	if a[0] > b[2] || b[0] > a[2] || a[1] > b[3] || b[1] > a[3] {
+		return false
+	}
+
+	for _, p := range s.points {
+		if other.locate(p) != outside {
+			return true
+		}
+	}
+	for _, p := range other.points {
+		if s.locate(p) != outside {
+			return true
+		}
+	}
+
+	otherSegments := other.segments()
+	for _, seg := range s.segments() {
+		for _, o := range otherSegments {
+			if SegmentsIntersect(seg[0], seg[1], o[0], o[1]) {
+				return true
+			}
+		}
+	}
+
+	// No boundaries cross, so the only remaining case is one part lying
+	// wholly inside a polygon of the other shape
+	for _, p := range s.vertices() {
+		if other.locate(p) != outside {
+			return true
+		}
+	}
+	for _, p := range other.vertices() {
+		if s.locate(p) != outside {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *shape) contains(other *shape) bool {
+	if s.empty() || other.empty() {
+		return false
+	}
+
+	for _, p := range other.points {
+		if s.locate(p) == outside {
+			return false
+		}
+	}
+
+	// Every segment of other must stay inside s along its whole length. The
+	// segment is split wherever it meets the boundary of s; each piece is then
+	// either entirely inside or entirely outside, which its midpoint decides.
+	boundary := s.segments()
+	for _, line := range other.lines {
+		for i := 1; i < len(line); i++ {
+			if !segmentCovered(line[i-1], line[i], s, boundary) {
+				return false
+			}
+		}
+	}
+	for _, polygon := range other.polygons {
+		for _, seg := range ringSegments(polygon[0]) {
+			if !segmentCovered(seg[0], seg[1], s, boundary) {
+				return false
+			}
+		}
+		// The exterior ring of other can sit inside s while surrounding one
+		// of its holes, which other then covers but s does not
+		for _, container := range s.polygons {
+			for _, hole := range container[1:] {
+				if holeInside(hole, polygon) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// segmentCovered reports whether every point of the segment p-q lies inside s
+// or on its boundary
+func segmentCovered(p, q []float64, s *shape, boundary [][2][]float64) bool {
+	if s.locate(p) == outside || s.locate(q) == outside {
+		return false
+	}
+
+	params := []float64{0, 1}
+	for _, seg := range boundary {
+		params = append(params, splitParams(p, q, seg[0], seg[1])...)
+	}
+	sort.Float64s(params)
+
+	for i := 1; i < len(params); i++ {
+		if params[i] == params[i-1] {
+			continue
+		}
+		t := (params[i-1] + params[i]) / 2
+		mid := []float64{p[0] + t*(q[0]-p[0]), p[1] + t*(q[1]-p[1])}
+		if s.locate(mid) == outside {
+			return false
+		}
+	}
+	return true
+}
+
+// splitParams returns the positions along p-q, as fractions in [0, 1], where
+// the segment meets r-s
+func splitParams(p, q, r, s []float64) []float64 {
+	dx, dy := q[0]-p[0], q[1]-p[1]
+	ex, ey := s[0]-r[0], s[1]-r[1]
+	fx, fy := r[0]-p[0], r[1]-p[1]
+
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return nil
+	}
+
+	denom := dx*ey - dy*ex
+	if denom != 0 {
+		t := (fx*ey - fy*ex) / denom
+		u := (fx*dy - fy*dx) / denom
+		if t >= 0 && t <= 1 && u >= 0 && u <= 1 {
+			return []float64{t}
+		}
+		return nil
+	}
+
+	// Parallel segments only meet when collinear; split at the endpoints of
+	// r-s that fall within p-q
+	if fx*dy-fy*dx != 0 {
+		return nil
+	}
+	var out []float64
+	for _, e := range [][]float64{r, s} {
+		t := ((e[0]-p[0])*dx + (e[1]-p[1])*dy) / length
+		if t > 0 && t < 1 {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// holeInside reports whether any part of a hole lies strictly inside polygon
+func holeInside(hole [][]float64, polygon [][][]float64) bool {
+	for _, p := range hole {
+		if polygonLocation(p, polygon) == inside {
+			return true
+		}
+	}
+	for _, seg := range ringSegments(hole) {
+		mid := []float64{(seg[0][0] + seg[1][0]) / 2, (seg[0][1] + seg[1][1]) / 2}
+		if polygonLocation(mid, polygon) == inside {
+			return true
+		}
+	}
+	return false
+}
+
+// polygonLocation returns the position of a point relative to a polygon
+// with holes
+func polygonLocation(p []float64, polygon [][][]float64) location {
+	if len(polygon) == 0 {
+		return outside
+	}
+	loc := ringLocation(p, polygon[0])
+	if loc != inside {
+		return loc
+	}
+	for _, hole := range polygon[1:] {
+		switch ringLocation(p, hole) {
+		case inside:
+			return outside
+		case onBoundary:
+			return onBoundary
+		}
+	}
+	return inside
+}
+
+// ringLocation returns the position of a point relative to a ring, using ray
+// casting for the interior test. Rings may be open or closed.
+func ringLocation(p []float64, ring [][]float64) location {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[j], ring[i]
+		if onSegment(p, a, b) {
+			return onBoundary
+		}
+		if (a[1] > p[1]) != (b[1] > p[1]) {
+			x := a[0] + (p[1]-a[1])*(b[0]-a[0])/(b[1]-a[1])
+			if p[0] < x {
+				in = !in
+			}
+		}
+	}
+	if in {
+		return inside
+	}
+	return outside
+}
+
+// ringSegments returns the edges of a ring, closing it if necessary
+func ringSegments(ring [][]float64) [][2][]float64 {
+	out := make([][2][]float64, 0, len(ring))
+	for i := 1; i < len(ring); i++ {
+		out = append(out, [2][]float64{ring[i-1], ring[i]})
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		out = append(out, [2][]float64{last, first})
+	}
+	return out
+}
+
+// orientation returns the sign of the cross product (b-a) x (c-a): positive
+// when a, b, c turn counter-clockwise, negative when clockwise, zero when
+// collinear
+func orientation(a, b, c []float64) int {
+	cross := (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	switch {
+	case cross > 0:
+		return 1
+	case cross < 0:
+		return -1
+	}
+	return 0
+}
+
+// onSegment reports whether p lies on the closed segment a-b
+func onSegment(p, a, b []float64) bool {
+	if orientation(a, b, p) != 0 {
+		return false
+	}
+	return p[0] >= math.Min(a[0], b[0]) && p[0] <= math.Max(a[0], b[0]) &&
+		p[1] >= math.Min(a[1], b[1]) && p[1] <= math.Max(a[1], b[1])
+}
@@ -0,0 +1,317 @@
+package geojson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mustGeometry(t *testing.T, s string) *Geometry {
+	t.Helper()
+	var g Geometry
+	if err := json.Unmarshal([]byte(s), &g); err != nil {
+		t.Fatalf("invalid test geometry %q: %v", s, err)
+	}
+	return &g
+}
+
+const (
+	// 10x10 square at the origin
+	square = `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}`
+	// 10x10 square with a 4x4 hole in the middle
+	squareWithHole = `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[3,3],[7,3],[7,7],[3,7],[3,3]]]}`
+	// U shape: the square minus the notch 4<x<6, y>2
+	uShape = `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[6,10],[6,2],[4,2],[4,10],[0,10],[0,0]]]}`
+)
+
+func TestPointInPolygon(t *testing.T) {
+	var polygon [][][]float64
+	if err := json.Unmarshal([]byte(`[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[3,3],[7,3],[7,7],[3,7],[3,3]]]`), &polygon); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		point []float64
+		want  bool
+	}{
+		{"interior", []float64{1, 1}, true},
+		{"outside", []float64{11, 5}, false},
+		{"on exterior edge", []float64{10, 5}, true},
+		{"on exterior vertex", []float64{0, 0}, true},
+		{"inside hole", []float64{5, 5}, false},
+		{"on hole edge", []float64{3, 5}, true},
+		{"level with a vertex", []float64{-1, 0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PointInPolygon(tt.point, polygon); got != tt.want {
+				t.Errorf("PointInPolygon(%v) = %v, want %v", tt.point, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmentsIntersect(t *testing.T) {
+	tests := []struct {
+		name           string
+		p1, p2, q1, q2 []float64
+		want           bool
+	}{
+		{"crossing", []float64{0, 0}, []float64{2, 2}, []float64{0, 2}, []float64{2, 0}, true},
+		{"parallel", []float64{0, 0}, []float64{2, 0}, []float64{0, 1}, []float64{2, 1}, false},
+		{"touching endpoint", []float64{0, 0}, []float64{1, 1}, []float64{1, 1}, []float64{2, 0}, true},
+		{"t junction", []float64{0, 0}, []float64{2, 0}, []float64{1, 0}, []float64{1, 5}, true},
+		{"collinear overlap", []float64{0, 0}, []float64{2, 0}, []float64{1, 0}, []float64{3, 0}, true},
+		{"collinear apart", []float64{0, 0}, []float64{1, 0}, []float64{2, 0}, []float64{3, 0}, false},
+		{"would cross if extended", []float64{0, 0}, []float64{1, 1}, []float64{3, 0}, []float64{2, 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SegmentsIntersect(tt.p1, tt.p2, tt.q1, tt.q2); got != tt.want {
+				t.Errorf("SegmentsIntersect() = %v, want %v", got, tt.want)
+			}
+			// The result does not depend on argument order
+			if got := SegmentsIntersect(tt.q2, tt.q1, tt.p1, tt.p2); got != tt.want {
+				t.Errorf("SegmentsIntersect() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntersects(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{
+			name: "overlapping polygons",
+			a:    square,
+			b:    `{"type":"Polygon","coordinates":[[[5,5],[15,5],[15,15],[5,15],[5,5]]]}`,
+			want: true,
+		},
+		{
+			name: "polygon inside polygon, no edges cross",
+			a:    square,
+			b:    `{"type":"Polygon","coordinates":[[[1,1],[2,1],[2,2],[1,2],[1,1]]]}`,
+			want: true,
+		},
+		{
+			name: "polygons sharing an edge",
+			a:    square,
+			b:    `{"type":"Polygon","coordinates":[[[10,0],[20,0],[20,10],[10,10],[10,0]]]}`,
+			want: true,
+		},
+		{
+			name: "bounding boxes overlap but polygons do not",
+			a:    `{"type":"Polygon","coordinates":[[[0,0],[10,0],[0,10],[0,0]]]}`,
+			b:    `{"type":"Polygon","coordinates":[[[10,10],[10,6],[6,10],[10,10]]]}`,
+			want: false,
+		},
+		{
+			name: "polygon inside a hole",
+			a:    squareWithHole,
+			b:    `{"type":"Polygon","coordinates":[[[4,4],[6,4],[6,6],[4,6],[4,4]]]}`,
+			want: false,
+		},
+		{
+			name: "polygon inside the notch of a concave polygon",
+			a:    uShape,
+			b:    `{"type":"Polygon","coordinates":[[[4.5,5],[5.5,5],[5.5,8],[4.5,8],[4.5,5]]]}`,
+			want: false,
+		},
+		{
+			name: "point inside polygon",
+			a:    square,
+			b:    `{"type":"Point","coordinates":[5,5]}`,
+			want: true,
+		},
+		{
+			name: "point in hole",
+			a:    squareWithHole,
+			b:    `{"type":"Point","coordinates":[5,5]}`,
+			want: false,
+		},
+		{
+			name: "line crossing polygon",
+			a:    square,
+			b:    `{"type":"LineString","coordinates":[[-5,5],[15,5]]}`,
+			want: true,
+		},
+		{
+			name: "multipolygon with one part overlapping",
+			a:    `{"type":"MultiPolygon","coordinates":[[[[20,20],[21,20],[21,21],[20,20]]],[[[1,1],[2,1],[2,2],[1,1]]]]}`,
+			b:    square,
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := mustGeometry(t, tt.a), mustGeometry(t, tt.b)
+			for _, order := range [][2]*Geometry{{a, b}, {b, a}} {
+				got, err := Intersects(order[0], order[1])
+				if err != nil {
+					t.Fatalf("Intersects() error: %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("Intersects() = %v, want %v", got, tt.want)
+				}
+				disjoint, err := Disjoint(order[0], order[1])
+				if err != nil {
+					t.Fatalf("Disjoint() error: %v", err)
+				}
+				if disjoint == tt.want {
+					t.Errorf("Disjoint() = %v, want %v", disjoint, !tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{
+			name: "polygon contains smaller polygon",
+			a:    square,
+			b:    `{"type":"Polygon","coordinates":[[[1,1],[9,1],[9,9],[1,9],[1,1]]]}`,
+			want: true,
+		},
+		{
+			name: "polygon contains itself",
+			a:    square,
+			b:    square,
+			want: true,
+		},
+		{
+			name: "overlapping polygon is not contained",
+			a:    square,
+			b:    `{"type":"Polygon","coordinates":[[[5,5],[15,5],[15,15],[5,15],[5,5]]]}`,
+			want: false,
+		},
+		{
+			name: "larger polygon is not contained",
+			a:    `{"type":"Polygon","coordinates":[[[1,1],[9,1],[9,9],[1,9],[1,1]]]}`,
+			b:    square,
+			want: false,
+		},
+		{
+			name: "polygon spanning the notch of a concave polygon",
+			a:    uShape,
+			b:    `{"type":"Polygon","coordinates":[[[1,1],[9,1],[9,9],[1,9],[1,1]]]}`,
+			want: false,
+		},
+		{
+			name: "segment bridging the notch with vertices inside",
+			a:    uShape,
+			b:    `{"type":"LineString","coordinates":[[2,5],[8,5]]}`,
+			want: false,
+		},
+		{
+			name: "polygon below the notch",
+			a:    uShape,
+			b:    `{"type":"Polygon","coordinates":[[[1,0.5],[9,0.5],[9,2],[1,2],[1,0.5]]]}`,
+			want: true,
+		},
+		{
+			name: "polygon surrounding a hole",
+			a:    squareWithHole,
+			b:    `{"type":"Polygon","coordinates":[[[1,1],[9,1],[9,9],[1,9],[1,1]]]}`,
+			want: false,
+		},
+		{
+			name: "polygon beside a hole",
+			a:    squareWithHole,
+			b:    `{"type":"Polygon","coordinates":[[[1,1],[2,1],[2,9],[1,9],[1,1]]]}`,
+			want: true,
+		},
+		{
+			name: "polygon across adjacent multipolygon parts",
+			a:    `{"type":"MultiPolygon","coordinates":[[[[0,0],[5,0],[5,10],[0,10],[0,0]]],[[[5,0],[10,0],[10,10],[5,10],[5,0]]]]}`,
+			b:    `{"type":"Polygon","coordinates":[[[1,1],[9,1],[9,9],[1,9],[1,1]]]}`,
+			want: true,
+		},
+		{
+			name: "point on boundary",
+			a:    square,
+			b:    `{"type":"Point","coordinates":[10,5]}`,
+			want: true,
+		},
+		{
+			name: "multipoint partly outside",
+			a:    square,
+			b:    `{"type":"MultiPoint","coordinates":[[5,5],[11,5]]}`,
+			want: false,
+		},
+		{
+			name: "line contains point on it",
+			a:    `{"type":"LineString","coordinates":[[0,0],[10,10]]}`,
+			b:    `{"type":"Point","coordinates":[5,5]}`,
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := mustGeometry(t, tt.a), mustGeometry(t, tt.b)
+			got, err := Contains(a, b)
+			if err != nil {
+				t.Fatalf("Contains() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+			within, err := Within(b, a)
+			if err != nil {
+				t.Fatalf("Within() error: %v", err)
+			}
+			if within != tt.want {
+				t.Errorf("Within() = %v, want %v", within, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpatialPredicates_InvalidGeometry(t *testing.T) {
+	valid := mustGeometry(t, square)
+	invalid := []*Geometry{
+		nil,
+		mustGeometry(t, `{"type":"GeometryCollection","coordinates":[]}`),
+		mustGeometry(t, `{"type":"Polygon","coordinates":[[[0,0],[1,1]]]}`),
+		mustGeometry(t, `{"type":"LineString","coordinates":[[0,0]]}`),
+		mustGeometry(t, `{"type":"Point","coordinates":[1]}`),
+	}
+
+	for _, g := range invalid {
+		if _, err := Intersects(valid, g); err == nil {
+			t.Errorf("Intersects() expected error for %+v", g)
+		}
+		if _, err := Contains(g, valid); err == nil {
+			t.Errorf("Contains() expected error for %+v", g)
+		}
+	}
+}
+
+func TestComputeBBox_MultiPointAndMultiLineString(t *testing.T) {
+	bbox, err := ComputeBBox(mustGeometry(t, `{"type":"MultiPoint","coordinates":[[1,2],[-3,4]]}`))
+	if err != nil {
+		t.Fatalf("ComputeBBox() error: %v", err)
+	}
+	if !floatSlicesEqual(bbox, []float64{-3, 2, 1, 4}) {
+		t.Errorf("ComputeBBox() = %v", bbox)
+	}
+
+	bbox, err = ComputeBBox(mustGeometry(t, `{"type":"MultiLineString","coordinates":[[[0,0],[1,1]],[[5,-2],[6,0]]]}`))
+	if err != nil {
+		t.Fatalf("ComputeBBox() error: %v", err)
+	}
+	if !floatSlicesEqual(bbox, []float64{0, -2, 6, 1}) {
+		t.Errorf("ComputeBBox() = %v", bbox)
+	}
+}
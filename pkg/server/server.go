@@ -2,17 +2,28 @@
 package server
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/api"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/asf"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/auth"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/backend"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/cache"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/cmr"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/config"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/download"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/metrics"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/ratelimit"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/redis"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/stac"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/tracing"
 	"github.com/robert-malhotra/asf-stac-proxy/internal/translate"
+	"github.com/robert-malhotra/asf-stac-proxy/internal/upstream"
 )
 
 // BackendType specifies which upstream data source to use.
@@ -35,6 +46,12 @@ type Options struct {
 	// Default: BackendASF
 	Backend BackendType
 
+	// Failover optionally names a secondary backend that serves requests
+	// while the circuit breaker of Backend is open. It must differ from
+	// Backend.
+	// Default: "" (no failover)
+	Failover BackendType
+
 	// ASFBaseURL is the ASF Search API base URL.
 	// Default: "https://api.daac.asf.alaska.edu"
 	ASFBaseURL string
@@ -47,6 +64,144 @@ type Options struct {
 	// Default: "ASF"
 	CMRProvider string
 
+	// CMRNativePagination pages through CMR results with CMR-Search-After
+	// tokens instead of start-time cursors.
+	// Default: false
+	CMRNativePagination bool
+
+	// Cache enables an in-memory cache of upstream responses.
+	// Default: false
+	Cache bool
+
+	// CacheMaxBytes bounds the size of the cached responses.
+	// Default: 64 MiB
+	CacheMaxBytes int64
+
+	// CacheSearchTTL is how long search responses are cached.
+	// Default: 30s
+	CacheSearchTTL time.Duration
+
+	// CacheItemTTL is how long single item lookups are cached.
+	// Default: 10m
+	CacheItemTTL time.Duration
+
+	// RetryMaxAttempts is the number of attempts made for an upstream
+	// request, including the first. 1 disables retries.
+	// Default: 3
+	RetryMaxAttempts int
+
+	// RetryBaseDelay is the backoff before the first retry, doubled on each
+	// further retry.
+	// Default: 250ms
+	RetryBaseDelay time.Duration
+
+	// RetryMaxDelay caps the backoff between retries.
+	// Default: 10s
+	RetryMaxDelay time.Duration
+
+	// RateLimit is the sustained requests per second allowed to each client,
+	// identified by its authenticated subject or else by IP.
+	// Default: 0 (no rate limiting)
+	RateLimit float64
+
+	// RateLimitBurst is the most requests a client can make at once.
+	// Default: 20
+	RateLimitBurst int
+
+	// RateLimitRoutes overrides the rate and burst of chi route patterns, as
+	// "rate:burst" values such as {"/search": "1:5"}.
+	// Default: nil
+	RateLimitRoutes map[string]string
+
+	// UpstreamConcurrency caps the upstream calls in flight across all
+	// clients; calls wait up to UpstreamWait for a free slot.
+	// Default: 0 (no cap)
+	UpstreamConcurrency int
+
+	// UpstreamWait is how long an upstream call waits for a free slot
+	// before the request fails with 429 Too Many Requests.
+	// Default: 5s
+	UpstreamWait time.Duration
+
+	// AuthRequired rejects requests without an API key or bearer token.
+	// Default: false (anonymous clients see unrestricted collections)
+	AuthRequired bool
+
+	// APIKeysFile is a JSON file of API keys and the restricted collections
+	// each grants.
+	// Default: "" (no API keys)
+	APIKeysFile string
+
+	// APIKeyHeader names the header carrying API keys.
+	// Default: "X-API-Key"
+	APIKeyHeader string
+
+	// JWKSFile is a JSON Web Key Set file of the keys signing bearer tokens.
+	// Default: "" (no bearer tokens)
+	JWKSFile string
+
+	// JWKSURL is the URL of the JSON Web Key Set signing bearer tokens, such
+	// as an OpenID Connect jwks_uri. Exclusive with JWKSFile.
+	// Default: "" (no bearer tokens)
+	JWKSURL string
+
+	// JWKSRefresh is how often the key set is reloaded.
+	// Default: 15m
+	JWKSRefresh time.Duration
+
+	// JWTIssuer, when set, must equal the iss claim of bearer tokens.
+	// Default: ""
+	JWTIssuer string
+
+	// JWTAudience, when set, must be in the aud claim of bearer tokens.
+	// Default: ""
+	JWTAudience string
+
+	// JWTCollectionsClaim names the claim listing the restricted collections
+	// a bearer token grants.
+	// Default: "collections"
+	JWTCollectionsClaim string
+
+	// JWTClaimPrefix, when set, selects the values of JWTCollectionsClaim
+	// starting with it, such as "stac:" in a scope, and strips it.
+	// Default: ""
+	JWTClaimPrefix string
+
+	// JWTClockSkew is the leeway allowed when checking token expiry.
+	// Default: 1m
+	JWTClockSkew time.Duration
+
+	// DownloadEnabled serves item assets on /download/{collection}/{item}/{asset},
+	// streamed through Earthdata Login.
+	// Default: false
+	DownloadEnabled bool
+
+	// EarthdataToken is the Earthdata Login bearer token used for clients
+	// that do not send their own in DownloadTokenHeader.
+	// Default: "" (clients must send a token for protected assets)
+	EarthdataToken string
+
+	// DownloadTokenHeader names the header carrying a client's Earthdata token.
+	// Default: "X-Earthdata-Token"
+	DownloadTokenHeader string
+
+	// DownloadTokenHosts are the domains Earthdata tokens are sent to,
+	// subdomains included.
+	// Default: ["asf.alaska.edu", "earthdata.nasa.gov"]
+	DownloadTokenHosts []string
+
+	// DownloadLoginHost is the Earthdata Login host.
+	// Default: "urs.earthdata.nasa.gov"
+	DownloadLoginHost string
+
+	// RewriteAssetHrefs points the asset hrefs of items at the download route.
+	// Default: false
+	RewriteAssetHrefs bool
+
+	// DownloadTimeout bounds the wait for upstream response headers.
+	// Default: 30s
+	DownloadTimeout time.Duration
+
 	// Timeout is the upstream request timeout.
 	// Default: 30s
 	Timeout time.Duration
@@ -75,10 +230,93 @@ type Options struct {
 	// Default: true
 	EnableQueryables bool
 
+	// EnableMetrics serves Prometheus metrics on /metrics.
+	// Default: false
+	EnableMetrics bool
+
+	// CursorStore keeps pagination cursors too large to inline: "memory"
+	// (this instance only), "signed" (no store: they stay inline, sealed
+	// with CursorSecret) or "redis" (shared through the Redis at RedisAddr).
+	// Default: "memory"
+	CursorStore string
+
+	// CursorTTL is how long a stored cursor remains valid.
+	// Default: 1h
+	CursorTTL time.Duration
+
+	// CursorMaxBytes bounds a compressed cursor in Redis.
+	// Default: 8192
+	CursorMaxBytes int
+
+	// CursorSecret seals the inline cursors handed to clients, so they can
+	// be neither read nor altered. At least 32 bytes; every replica needs
+	// the same secret.
+	// Default: "" (a random key valid only on this instance; required with
+	// the signed store)
+	CursorSecret string
+
+	// CursorPreviousSecrets are rotated-out secrets whose cursors are still
+	// accepted for CursorKeyGrace after CursorKeyRotatedAt.
+	// Default: nil
+	CursorPreviousSecrets []string
+
+	// CursorKeyRotatedAt is when the previous secrets were rotated out, the
+	// same on every replica; required with CursorPreviousSecrets.
+	// Default: zero
+	CursorKeyRotatedAt time.Time
+
+	// CursorKeyGrace is how long cursors sealed with a previous secret stay
+	// valid after the secret was rotated out.
+	// Default: 1h
+	CursorKeyGrace time.Duration
+
+	// RedisAddr is the "host:port" of the Redis server of the redis store.
+	// Default: "localhost:6379"
+	RedisAddr string
+
+	// RedisPassword authenticates to Redis.
+	// Default: ""
+	RedisPassword string
+
+	// RedisDB is the Redis database number.
+	// Default: 0
+	RedisDB int
+
+	// RedisKeyPrefix namespaces the cursor keys in Redis.
+	// Default: "asf-stac:cursor:"
+	RedisKeyPrefix string
+
+	// TraceExporter sends trace spans to "otlp" (an OTLP/HTTP collector at
+	// OTLPEndpoint) or "stdout" (JSON lines).
+	// Default: "" (tracing disabled)
+	TraceExporter string
+
+	// OTLPEndpoint is the base URL of the OTLP/HTTP collector.
+	// Default: "http://localhost:4318"
+	OTLPEndpoint string
+
+	// OTLPHeaders are sent with every span export, such as credentials.
+	// Default: nil
+	OTLPHeaders map[string]string
+
+	// TraceSampleRatio is the fraction of new traces recorded. Traces
+	// continued from an inbound traceparent follow its sampled flag.
+	// Default: 1
+	TraceSampleRatio float64
+
+	// ServiceName names the service in exported traces.
+	// Default: "asf-stac-proxy"
+	ServiceName string
+
 	// CollectionsDir is the path to collection definition JSON files.
 	// Default: "" (uses built-in defaults)
 	CollectionsDir string
 
+	// CollectionsWatchInterval is how often CollectionsDir is checked for
+	// changes, which are then reloaded. ReloadCollections reloads on demand.
+	// Default: 0 (no watching)
+	CollectionsWatchInterval time.Duration
+
 	// Logger is the slog logger to use.
 	// Default: slog.Default()
 	Logger *slog.Logger
@@ -86,8 +324,12 @@ type Options struct {
 
 // Server is an ASF STAC proxy server that can be embedded in another application.
 type Server struct {
-	router      chi.Router
-	cursorStore *stac.MemoryCursorStore
+	router       chi.Router
+	cursorStore  stac.CursorStore
+	redis        *redis.Client
+	tracer       *tracing.Tracer
+	reloader     *config.CollectionReloader
+	stopWatching context.CancelFunc
 }
 
 // New creates a new ASF STAC server with the given options.
@@ -96,6 +338,9 @@ func New(opts Options) (*Server, error) {
 	if opts.Backend == "" {
 		opts.Backend = BackendASF
 	}
+	if opts.Failover != "" && opts.Failover == opts.Backend {
+		return nil, fmt.Errorf("failover backend must differ from the backend %q", opts.Backend)
+	}
 	if opts.ASFBaseURL == "" {
 		opts.ASFBaseURL = "https://api.daac.asf.alaska.edu"
 	}
@@ -108,6 +353,84 @@ func New(opts Options) (*Server, error) {
 	if opts.Timeout == 0 {
 		opts.Timeout = 30 * time.Second
 	}
+	if opts.CacheMaxBytes == 0 {
+		opts.CacheMaxBytes = 64 << 20
+	}
+	if opts.CacheSearchTTL == 0 {
+		opts.CacheSearchTTL = 30 * time.Second
+	}
+	if opts.CacheItemTTL == 0 {
+		opts.CacheItemTTL = 10 * time.Minute
+	}
+	if opts.RetryMaxAttempts == 0 {
+		opts.RetryMaxAttempts = 3
+	}
+	if opts.RetryBaseDelay == 0 {
+		opts.RetryBaseDelay = 250 * time.Millisecond
+	}
+	if opts.RetryMaxDelay == 0 {
+		opts.RetryMaxDelay = 10 * time.Second
+	}
+	if opts.RateLimitBurst == 0 {
+		opts.RateLimitBurst = 20
+	}
+	if opts.UpstreamWait == 0 {
+		opts.UpstreamWait = 5 * time.Second
+	}
+	if opts.APIKeyHeader == "" {
+		opts.APIKeyHeader = "X-API-Key"
+	}
+	if opts.JWKSRefresh == 0 {
+		opts.JWKSRefresh = 15 * time.Minute
+	}
+	if opts.JWTCollectionsClaim == "" {
+		opts.JWTCollectionsClaim = "collections"
+	}
+	if opts.JWTClockSkew == 0 {
+		opts.JWTClockSkew = time.Minute
+	}
+	if opts.DownloadTokenHeader == "" {
+		opts.DownloadTokenHeader = "X-Earthdata-Token"
+	}
+	if opts.DownloadTokenHosts == nil {
+		opts.DownloadTokenHosts = download.DefaultTokenHosts
+	}
+	if opts.DownloadLoginHost == "" {
+		opts.DownloadLoginHost = download.DefaultLoginHost
+	}
+	if opts.DownloadTimeout == 0 {
+		opts.DownloadTimeout = 30 * time.Second
+	}
+	if opts.CursorStore == "" {
+		opts.CursorStore = "memory"
+	}
+	if opts.CursorStore == "signed" && opts.CursorSecret == "" {
+		return nil, fmt.Errorf("the signed cursor store requires a cursor secret")
+	}
+	if opts.CursorKeyGrace == 0 {
+		opts.CursorKeyGrace = time.Hour
+	}
+	if opts.CursorTTL == 0 {
+		opts.CursorTTL = time.Hour
+	}
+	if opts.CursorMaxBytes == 0 {
+		opts.CursorMaxBytes = stac.DefaultMaxCursorBytes
+	}
+	if opts.RedisAddr == "" {
+		opts.RedisAddr = "localhost:6379"
+	}
+	if opts.RedisKeyPrefix == "" {
+		opts.RedisKeyPrefix = "asf-stac:cursor:"
+	}
+	if opts.OTLPEndpoint == "" {
+		opts.OTLPEndpoint = "http://localhost:4318"
+	}
+	if opts.TraceSampleRatio == 0 {
+		opts.TraceSampleRatio = 1
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = "asf-stac-proxy"
+	}
 	if opts.Title == "" {
 		opts.Title = "ASF STAC API"
 	}
@@ -125,18 +448,83 @@ func New(opts Options) (*Server, error) {
 	}
 
 	// Build internal config
+	breakerDefaults := backend.DefaultBreakerConfig()
 	cfg := &config.Config{
 		Backend: config.BackendConfig{
-			Type: string(opts.Backend),
+			Type:     string(opts.Backend),
+			Failover: string(opts.Failover),
+			Breaker: config.BreakerConfig{
+				Window:      breakerDefaults.Window,
+				MinRequests: breakerDefaults.MinRequests,
+				FailureRate: breakerDefaults.FailureRate,
+				OpenTimeout: breakerDefaults.OpenTimeout,
+			},
 		},
 		ASF: config.ASFConfig{
 			BaseURL: opts.ASFBaseURL,
 			Timeout: opts.Timeout,
 		},
 		CMR: config.CMRConfig{
-			BaseURL:  opts.CMRBaseURL,
-			Provider: opts.CMRProvider,
-			Timeout:  opts.Timeout,
+			BaseURL:          opts.CMRBaseURL,
+			Provider:         opts.CMRProvider,
+			Timeout:          opts.Timeout,
+			NativePagination: opts.CMRNativePagination,
+		},
+		Cache: config.CacheConfig{
+			Enabled:   opts.Cache,
+			MaxBytes:  opts.CacheMaxBytes,
+			SearchTTL: opts.CacheSearchTTL,
+			ItemTTL:   opts.CacheItemTTL,
+		},
+		Retry: config.RetryConfig{
+			MaxAttempts: opts.RetryMaxAttempts,
+			BaseDelay:   opts.RetryBaseDelay,
+			MaxDelay:    opts.RetryMaxDelay,
+		},
+		RateLimit: config.RateLimitConfig{
+			Enabled:             opts.RateLimit > 0,
+			Rate:                opts.RateLimit,
+			Burst:               opts.RateLimitBurst,
+			Routes:              opts.RateLimitRoutes,
+			UpstreamConcurrency: opts.UpstreamConcurrency,
+			UpstreamWait:        opts.UpstreamWait,
+		},
+		Auth: config.AuthConfig{
+			Required:         opts.AuthRequired,
+			APIKeysFile:      opts.APIKeysFile,
+			APIKeyHeader:     opts.APIKeyHeader,
+			JWKSFile:         opts.JWKSFile,
+			JWKSURL:          opts.JWKSURL,
+			JWKSRefresh:      opts.JWKSRefresh,
+			Issuer:           opts.JWTIssuer,
+			Audience:         opts.JWTAudience,
+			CollectionsClaim: opts.JWTCollectionsClaim,
+			ClaimPrefix:      opts.JWTClaimPrefix,
+			ClockSkew:        opts.JWTClockSkew,
+		},
+		Download: config.DownloadConfig{
+			Enabled:        opts.DownloadEnabled,
+			EarthdataToken: opts.EarthdataToken,
+			TokenHeader:    opts.DownloadTokenHeader,
+			TokenHosts:     opts.DownloadTokenHosts,
+			LoginHost:      opts.DownloadLoginHost,
+			RewriteHrefs:   opts.RewriteAssetHrefs,
+			Timeout:        opts.DownloadTimeout,
+		},
+		Cursor: config.CursorConfig{
+			Store:           opts.CursorStore,
+			TTL:             opts.CursorTTL,
+			MaxBytes:        opts.CursorMaxBytes,
+			Secret:          opts.CursorSecret,
+			PreviousSecrets: opts.CursorPreviousSecrets,
+			KeyRotatedAt:    opts.CursorKeyRotatedAt,
+			KeyGrace:        opts.CursorKeyGrace,
+			Redis: config.RedisConfig{
+				Addr:      opts.RedisAddr,
+				Password:  opts.RedisPassword,
+				DB:        opts.RedisDB,
+				KeyPrefix: opts.RedisKeyPrefix,
+			},
 		},
 		STAC: config.STACConfig{
 			Version:     "1.0.0",
@@ -147,9 +535,17 @@ func New(opts Options) (*Server, error) {
 		Features: config.FeatureConfig{
 			EnableSearch:     opts.EnableSearch,
 			EnableQueryables: opts.EnableQueryables,
+			EnableMetrics:    opts.EnableMetrics,
 			DefaultLimit:     opts.DefaultLimit,
 			MaxLimit:         opts.MaxLimit,
 		},
+		Tracing: config.TracingConfig{
+			Exporter:    opts.TraceExporter,
+			Endpoint:    opts.OTLPEndpoint,
+			Headers:     opts.OTLPHeaders,
+			SampleRatio: opts.TraceSampleRatio,
+			ServiceName: opts.ServiceName,
+		},
 	}
 
 	// Load collections
@@ -171,33 +567,209 @@ func New(opts Options) (*Server, error) {
 	// Create translator
 	translator := translate.NewTranslator(cfg, collections, opts.Logger)
 
-	// Create cursor store
-	cursorStore := stac.NewMemoryCursorStore(1*time.Hour, 5*time.Minute)
+	// Create the keyring sealing pagination cursors
+	var keyring *stac.CursorKeyring
+	if cfg.Cursor.Secret != "" {
+		previous := make([][]byte, len(cfg.Cursor.PreviousSecrets))
+		for i, secret := range cfg.Cursor.PreviousSecrets {
+			previous[i] = []byte(secret)
+		}
+		keyring, err = stac.NewCursorKeyring([]byte(cfg.Cursor.Secret), previous, cfg.Cursor.KeyRotatedAt, cfg.Cursor.KeyGrace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cursor keyring: %w", err)
+		}
+	} else {
+		keyring = stac.NewRandomCursorKeyring()
+	}
+	// Cursors expire after the TTL, and those kept inline are size-bounded
+	keyring.WithLimits(cfg.Cursor.TTL, cfg.Cursor.MaxBytes)
 
-	// Create search backend
-	var searchBackend backend.SearchBackend
-	switch opts.Backend {
-	case BackendCMR:
-		cmrClient := cmr.NewClient(cfg.CMR.BaseURL, cfg.CMR.Provider, cfg.CMR.Timeout).WithLogger(opts.Logger)
-		searchBackend = cmr.NewCMRBackend(cmrClient, collections, cfg, opts.Logger)
-		opts.Logger.Info("using CMR backend", "base_url", cfg.CMR.BaseURL, "provider", cfg.CMR.Provider)
+	// Create cursor store. Replicas behind a load balancer need the signed or
+	// redis store to share cursors; signed uses none, keeping long cursors
+	// inline and sealed by the keyring
+	var cursorStore stac.CursorStore
+	var redisClient *redis.Client
+	switch cfg.Cursor.Store {
+	case "signed":
+	case "redis":
+		redisClient = redis.NewClient(cfg.Cursor.Redis.Addr).
+			WithPassword(cfg.Cursor.Redis.Password).
+			WithDB(cfg.Cursor.Redis.DB)
+		cursorStore = stac.NewKVCursorStore(redisClient, cfg.Cursor.Redis.KeyPrefix, cfg.Cursor.TTL, cfg.Cursor.MaxBytes)
 	default:
-		asfClient := asf.NewClient(cfg.ASF.BaseURL, cfg.ASF.Timeout).WithLogger(opts.Logger)
-		searchBackend = backend.NewASFBackend(asfClient, collections, translator, cfg, opts.Logger)
-		opts.Logger.Info("using ASF backend", "base_url", cfg.ASF.BaseURL)
+		cursorStore = stac.NewMemoryCursorStore(cfg.Cursor.TTL, 5*time.Minute)
+	}
+
+	// Create Prometheus metrics, served on /metrics
+	var appMetrics *metrics.Metrics
+	if cfg.Features.EnableMetrics {
+		appMetrics = metrics.New()
+		if stats, ok := cursorStore.(stac.CursorStoreStats); ok {
+			appMetrics.RegisterCursorStore(stats.Stats)
+		}
+	}
+
+	// Create the tracer, if a trace exporter is configured
+	var tracer *tracing.Tracer
+	exporter, err := tracing.NewExporter(cfg.Tracing.Exporter, cfg.Tracing.Endpoint, cfg.Tracing.Headers, cfg.Tracing.ServiceName, opts.Logger)
+	if err != nil {
+		(&Server{cursorStore: cursorStore, redis: redisClient}).Close()
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	if exporter != nil {
+		tracer = tracing.NewTracer(cfg.Tracing.ServiceName, exporter).WithSampleRatio(cfg.Tracing.SampleRatio)
+	}
+
+	// Create the upstream response cache, shared by the ASF and CMR clients
+	var responseCache *cache.ReadThrough
+	cacheTTLs := cache.TTLs{Search: cfg.Cache.SearchTTL, Item: cfg.Cache.ItemTTL}
+	if cfg.Cache.Enabled {
+		responseCache = cache.NewReadThrough(cache.NewLRU(cfg.Cache.MaxBytes), cfg.Cache.MaxLoadTime)
+		opts.Logger.Info("initialized response cache",
+			"max_bytes", cfg.Cache.MaxBytes,
+			"search_ttl", cfg.Cache.SearchTTL,
+			"item_ttl", cfg.Cache.ItemTTL,
+		)
+	}
+
+	// Transient upstream failures are retried with backoff
+	retryPolicy := upstream.RetryPolicy{
+		MaxAttempts: cfg.Retry.MaxAttempts,
+		BaseDelay:   cfg.Retry.BaseDelay,
+		MaxDelay:    cfg.Retry.MaxDelay,
+	}
+
+	// Cap the upstream calls in flight across the ASF and CMR clients
+	var upstreamSlots *ratelimit.Semaphore
+	if cfg.RateLimit.UpstreamConcurrency > 0 {
+		upstreamSlots = ratelimit.NewSemaphore(cfg.RateLimit.UpstreamConcurrency, cfg.RateLimit.UpstreamWait)
+	}
+
+	// Create search backends based on configuration
+	newBackend := func(backendType BackendType) backend.SearchBackend {
+		switch backendType {
+		case BackendCMR:
+			cmrClient := cmr.NewClient(cfg.CMR.BaseURL, cfg.CMR.Provider, cfg.CMR.Timeout).WithLogger(opts.Logger).
+				WithRetryPolicy(retryPolicy).
+				WithMetrics(appMetrics).
+				WithCache(responseCache, cacheTTLs).
+				WithConcurrencyLimit(upstreamSlots)
+			opts.Logger.Info("using CMR backend", "base_url", cfg.CMR.BaseURL, "provider", cfg.CMR.Provider)
+			return cmr.NewCMRBackend(cmrClient, collections, cfg, opts.Logger).WithMetrics(appMetrics)
+		default:
+			asfClient := asf.NewClient(cfg.ASF.BaseURL, cfg.ASF.Timeout).WithLogger(opts.Logger).
+				WithRetryPolicy(retryPolicy).
+				WithMetrics(appMetrics).
+				WithCache(responseCache, cacheTTLs).
+				WithConcurrencyLimit(upstreamSlots)
+			opts.Logger.Info("using ASF backend", "base_url", cfg.ASF.BaseURL)
+			return backend.NewASFBackend(asfClient, collections, translator, cfg, opts.Logger).WithMetrics(appMetrics)
+		}
+	}
+
+	searchBackend := newBackend(opts.Backend)
+	if opts.Failover != "" {
+		// Route requests to the secondary while the primary's breaker is open
+		breakerCfg := backend.BreakerConfig{
+			Window:      cfg.Backend.Breaker.Window,
+			MinRequests: cfg.Backend.Breaker.MinRequests,
+			FailureRate: cfg.Backend.Breaker.FailureRate,
+			OpenTimeout: cfg.Backend.Breaker.OpenTimeout,
+		}
+		searchBackend = backend.NewFailover(searchBackend, newBackend(opts.Failover), breakerCfg, opts.Logger)
+		opts.Logger.Info("enabled backend failover", "primary", opts.Backend, "secondary", opts.Failover)
 	}
 
 	// Create handlers
 	handlers := api.NewHandlers(cfg, searchBackend, translator, collections, opts.Logger).
-		WithCursorStore(cursorStore)
+		WithCursorStore(cursorStore).
+		WithCursorKeyring(keyring).
+		WithCache(responseCache).
+		WithMetrics(appMetrics).
+		WithTracer(tracer)
+
+	// Authenticate clients, if API keys or a JWKS are configured
+	if cfg.Auth.Enabled() {
+		authenticator := auth.NewAuthenticator(cfg.Auth.APIKeyHeader).WithRequired(cfg.Auth.Required)
+		if cfg.Auth.APIKeysFile != "" {
+			keys, err := auth.LoadAPIKeys(cfg.Auth.APIKeysFile)
+			if err != nil {
+				(&Server{cursorStore: cursorStore, redis: redisClient, tracer: tracer}).Close()
+				return nil, err
+			}
+			authenticator.WithAPIKeys(keys)
+		}
+		var keySet *auth.KeySet
+		if cfg.Auth.JWKSFile != "" {
+			keySet = auth.NewFileKeySet(cfg.Auth.JWKSFile, cfg.Auth.JWKSRefresh)
+			if err := keySet.Load(context.Background()); err != nil {
+				(&Server{cursorStore: cursorStore, redis: redisClient, tracer: tracer}).Close()
+				return nil, err
+			}
+		} else if cfg.Auth.JWKSURL != "" {
+			keySet = auth.NewURLKeySet(cfg.Auth.JWKSURL, &http.Client{Timeout: 10 * time.Second}, cfg.Auth.JWKSRefresh)
+			if err := keySet.Load(context.Background()); err != nil {
+				// The identity provider may be back by the first token
+				opts.Logger.Warn("failed to load JWKS, retrying on demand", "error", err)
+			}
+		}
+		if keySet != nil {
+			authenticator.WithJWT(auth.NewJWTVerifier(keySet, auth.JWTConfig{
+				Issuer:           cfg.Auth.Issuer,
+				Audience:         cfg.Auth.Audience,
+				CollectionsClaim: cfg.Auth.CollectionsClaim,
+				ClaimPrefix:      cfg.Auth.ClaimPrefix,
+				ClockSkew:        cfg.Auth.ClockSkew,
+			}))
+		}
+		handlers.WithAuthenticator(authenticator)
+	}
+
+	// Stream assets through Earthdata Login, if enabled
+	if cfg.Download.Enabled {
+		downloads := download.NewProxy(cfg.Download.Timeout).
+			WithToken(cfg.Download.EarthdataToken).
+			WithTokenHosts(cfg.Download.TokenHosts).
+			WithLoginHost(cfg.Download.LoginHost).
+			WithLogger(opts.Logger)
+		handlers.WithDownloads(downloads)
+	}
+
+	// Throttle each client, if enabled
+	if cfg.RateLimit.Enabled {
+		routeLimits, err := cfg.RateLimit.RouteLimits()
+		if err != nil {
+			(&Server{cursorStore: cursorStore, redis: redisClient, tracer: tracer}).Close()
+			return nil, err
+		}
+		rateLimits := api.NewRateLimits(cfg.RateLimit.Rate, cfg.RateLimit.Burst)
+		for pattern, limit := range routeLimits {
+			rateLimits.WithRoute(pattern, limit.Rate, limit.Burst)
+		}
+		handlers.WithRateLimits(rateLimits)
+	}
 
 	// Create router
 	router := api.NewRouter(handlers, opts.Logger)
 
-	return &Server{
+	srv := &Server{
 		router:      router,
 		cursorStore: cursorStore,
-	}, nil
+		redis:       redisClient,
+		tracer:      tracer,
+	}
+
+	// Reload collection definitions when the directory changes
+	if opts.CollectionsDir != "" {
+		srv.reloader = config.NewCollectionReloader(collections, opts.CollectionsDir, opts.Logger)
+		if opts.CollectionsWatchInterval > 0 {
+			var watchCtx context.Context
+			watchCtx, srv.stopWatching = context.WithCancel(context.Background())
+			go srv.reloader.Watch(watchCtx, opts.CollectionsWatchInterval)
+		}
+	}
+
+	return srv, nil
 }
 
 // Router returns the chi.Router for mounting in another application.
@@ -205,9 +777,29 @@ func (s *Server) Router() chi.Router {
 	return s.router
 }
 
-// Close stops background goroutines (cursor cleanup).
+// ReloadCollections reloads the collection definitions from CollectionsDir.
+// The new set replaces the current one only if every file in it is valid;
+// otherwise the current set stays active and the error is returned.
+func (s *Server) ReloadCollections() error {
+	if s.reloader == nil {
+		return fmt.Errorf("no collections directory configured")
+	}
+	return s.reloader.Reload()
+}
+
+// Close stops background goroutines (cursor cleanup, collection watching),
+// closes the Redis connections and flushes buffered trace spans.
 func (s *Server) Close() {
-	if s.cursorStore != nil {
-		s.cursorStore.Stop()
+	if s.stopWatching != nil {
+		s.stopWatching()
+	}
+	if memoryStore, ok := s.cursorStore.(*stac.MemoryCursorStore); ok {
+		memoryStore.Stop()
+	}
+	if s.redis != nil {
+		s.redis.Close()
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.tracer.Shutdown(ctx)
 }